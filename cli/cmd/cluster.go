@@ -16,7 +16,10 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/master"
@@ -39,6 +42,12 @@ func (cmd *ChubaoFSCmd) newClusterCmd(client *master.MasterClient) *cobra.Comman
 		newClusterFreezeCmd(client),
 		newClusterSetThresholdCmd(client),
 		newClusterDeleteParasCmd(client),
+		newClusterRebalanceCmd(client),
+		newClusterAutoDecommissionCmd(client),
+		newClusterAuditCmd(client),
+		newClusterBackupCmd(client),
+		newClusterExportCmd(client),
+		newClusterApplyCmd(client),
 	)
 	return clusterCmd
 }
@@ -49,10 +58,17 @@ const (
 	cmdClusterFreezeShort    = "Freeze cluster"
 	cmdClusterThresholdShort = "Set memory threshold of metanodes"
 	cmdClusterDelParaShort   = "Set delete parameters"
+	cmdClusterRebalanceShort = "Manage the rebalancer of data and meta partitions"
+	cmdClusterAutoDecShort   = "Manage the automatic decommission of the bad disks"
+	cmdClusterAuditShort     = "List the audit records of the operations on the cluster"
+	cmdClusterBackupShort    = "Back up the metadata of the cluster to a file"
+	cmdClusterExportShort    = "Export the configuration of the cluster as a JSON document"
+	cmdClusterApplyShort     = "Apply the configuration of the cluster in a JSON document"
 	nodeDeleteBatchCountKey  = "batchCount"
 	nodeMarkDeleteRateKey    = "markDeleteRate"
 	nodeDeleteWorkerSleepMs  = "deleteWorkerSleepMs"
 	nodeAutoRepairRateKey    = "autoRepairRate"
+	nodeScrubRateKey         = "scrubRate"
 )
 
 func newClusterInfoCmd(client *master.MasterClient) *cobra.Command {
@@ -75,6 +91,7 @@ func newClusterInfoCmd(client *master.MasterClient) *cobra.Command {
 			stdout(fmt.Sprintf("  MarkDeleteRate     : %v\n", delPara[nodeMarkDeleteRateKey]))
 			stdout(fmt.Sprintf("  DeleteWorkerSleepMs: %v\n", delPara[nodeDeleteWorkerSleepMs]))
 			stdout(fmt.Sprintf("  AutoRepairRate     : %v\n", delPara[nodeAutoRepairRateKey]))
+			stdout(fmt.Sprintf("  ScrubRate          : %v\n", delPara[nodeScrubRateKey]))
 			stdout("\n")
 		},
 	}
@@ -208,3 +225,253 @@ func newClusterDeleteParasCmd(client *master.MasterClient) *cobra.Command {
 
 	return cmd
 }
+
+func newClusterRebalanceCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpRebalance + " [COMMAND]",
+		Short: cmdClusterRebalanceShort,
+		Long: `The rebalancer moves the replicas of the partitions from the most loaded nodes to the least loaded ones
+in the same node set or zone, once the usage ratios, or the partition counts to the average, of the nodes
+differ more than the threshold.`,
+	}
+	cmd.AddCommand(
+		newClusterRebalanceInfoCmd(client),
+		newClusterRebalanceSwitchCmd(client, CliOpPause, false),
+		newClusterRebalanceSwitchCmd(client, CliOpResume, true),
+		newClusterRebalanceSetCmd(client),
+	)
+	return cmd
+}
+
+func newClusterRebalanceInfoCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo,
+		Short: "Show the imbalance of the nodes and the migrations of the rebalancer",
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				view *proto.RebalanceView
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if view, err = client.AdminAPI().GetRebalance(); err != nil {
+				return
+			}
+			stdout("[Rebalance]\n")
+			stdout(formatRebalanceView(view))
+			stdout("\n[Imbalance]\n")
+			stdout(rebalanceImbalanceTableHeader)
+			for _, imbalance := range view.Imbalances {
+				stdout(formatRebalanceImbalance(imbalance))
+			}
+			stdout("\n[Running migrations]\n")
+			stdout(rebalanceMigrationTableHeader)
+			for _, m := range view.Running {
+				stdout(formatRebalanceMigration(m))
+			}
+			stdout("\n[Finished migrations]\n")
+			stdout(rebalanceMigrationTableHeader)
+			for _, m := range view.History {
+				stdout(formatRebalanceMigration(m))
+			}
+		},
+	}
+	return cmd
+}
+
+func newClusterRebalanceSwitchCmd(client *master.MasterClient, op string, enable bool) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   op,
+		Short: fmt.Sprintf("%v the rebalancer", strings.Title(op)),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := client.AdminAPI().SetRebalance(strconv.FormatBool(enable), "", ""); err != nil {
+				errout("Error: %v", err)
+			}
+			stdout("Rebalancer is %v!\n", formatEnabledDisabled(enable))
+		},
+	}
+	return cmd
+}
+
+func newClusterRebalanceSetCmd(client *master.MasterClient) *cobra.Command {
+	var optThreshold, optMaxConcurrency string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet,
+		Short: "Set the threshold of the imbalance and the max concurrent migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := client.AdminAPI().SetRebalance("", optThreshold, optMaxConcurrency); err != nil {
+				errout("Error: %v", err)
+			}
+			stdout("Rebalance parameters has been set successfully. \n")
+		},
+	}
+	cmd.Flags().StringVar(&optThreshold, CliFlagThreshold, "", "Spread of the usage ratios, or of the partition counts to the average, in (0, 1)")
+	cmd.Flags().StringVar(&optMaxConcurrency, CliFlagMaxConcurrency, "", "Max partitions migrating at the same time")
+	return cmd
+}
+
+func newClusterAutoDecommissionCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpAutoDecommission + " [COMMAND]",
+		Short: cmdClusterAutoDecShort,
+		Long: `The master decommissions the disks reported unavailable, or whose I/O errors reach the threshold,
+at most max-concurrency disks at the same time. It is suspended while more than bad-disk-limit disks are bad.`,
+	}
+	cmd.AddCommand(
+		newClusterAutoDecommissionInfoCmd(client),
+		newClusterAutoDecommissionSwitchCmd(client, CliOpEnable, true),
+		newClusterAutoDecommissionSwitchCmd(client, CliOpDisable, false),
+		newClusterAutoDecommissionSetCmd(client),
+	)
+	return cmd
+}
+
+func newClusterAutoDecommissionInfoCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo,
+		Short: "Show the bad disks and the events of the automatic disk decommission",
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				view *proto.AutoDecommissionView
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if view, err = client.AdminAPI().GetAutoDecommission(); err != nil {
+				return
+			}
+			stdout("[Auto decommission]\n")
+			stdout(formatAutoDecommissionView(view))
+			stdout("\n[Bad disks]\n")
+			stdout(badDiskTableHeader)
+			for _, disk := range view.BadDisks {
+				stdout(formatBadDisk(disk))
+			}
+			stdout("\n[Events]\n")
+			stdout(diskFailureEventTableHeader)
+			for _, event := range view.Events {
+				stdout(formatDiskFailureEvent(event))
+			}
+		},
+	}
+	return cmd
+}
+
+func newClusterAutoDecommissionSwitchCmd(client *master.MasterClient, op string, enable bool) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   op,
+		Short: fmt.Sprintf("%v the automatic disk decommission", strings.Title(op)),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := client.AdminAPI().SetAutoDecommission(strconv.FormatBool(enable), "", "", ""); err != nil {
+				errout("Error: %v", err)
+			}
+			stdout("Auto decommission is %v!\n", formatEnabledDisabled(enable))
+		},
+	}
+	return cmd
+}
+
+func newClusterAutoDecommissionSetCmd(client *master.MasterClient) *cobra.Command {
+	var optErrorThreshold, optMaxConcurrency, optBadDiskLimit string
+	var cmd = &cobra.Command{
+		Use:   CliOpSet,
+		Short: "Set the error threshold, the max concurrent disks and the limit of the bad disks",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := client.AdminAPI().SetAutoDecommission("", optErrorThreshold, optMaxConcurrency, optBadDiskLimit); err != nil {
+				errout("Error: %v", err)
+			}
+			stdout("Auto decommission parameters has been set successfully. \n")
+		},
+	}
+	cmd.Flags().StringVar(&optErrorThreshold, CliFlagErrorThreshold, "", "Read and write errors of a disk to decommission it, 0 for the unavailable disks only")
+	cmd.Flags().StringVar(&optMaxConcurrency, CliFlagMaxConcurrency, "", "Max disks decommissioned at the same time")
+	cmd.Flags().StringVar(&optBadDiskLimit, CliFlagBadDiskLimit, "", "Bad disks beyond which the automatic decommission is suspended")
+	return cmd
+}
+
+func newClusterAuditCmd(client *master.MasterClient) *cobra.Command {
+	var (
+		optUser  string
+		optOp    string
+		optSince time.Duration
+		optLimit int
+	)
+	var cmd = &cobra.Command{
+		Use:   CliOpAudit,
+		Short: cmdClusterAuditShort,
+		Long: `List the recent records of the mutating APIs called on the leader master, the full history
+is in the audit logs of the masters.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err     error
+				since   int64
+				records []*proto.AuditRecord
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optSince > 0 {
+				since = time.Now().Add(-optSince).Unix()
+			}
+			if records, err = client.AdminAPI().ListAuditRecords(optUser, optOp, since, optLimit); err != nil {
+				return
+			}
+			stdout(auditRecordTableHeader)
+			for _, record := range records {
+				stdout(formatAuditRecord(record))
+			}
+		},
+	}
+	cmd.Flags().StringVar(&optUser, CliFlagOnwer, "", "List the records of the user only")
+	cmd.Flags().StringVar(&optOp, CliFlagOp, "", "List the records of the operations with the prefix only, e.g. /vol")
+	cmd.Flags().DurationVar(&optSince, CliFlagSince, 0, "List the records within the duration only, e.g. 24h")
+	cmd.Flags().IntVar(&optLimit, CliFlagLimit, 100, "Max records listed")
+	return cmd
+}
+
+func newClusterBackupCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpBackup + " [FILE]",
+		Short: cmdClusterBackupShort,
+		Long: `Back up a consistent snapshot of the metadata of the masters, including the volumes, the partitions,
+the nodes, the users and the tokens. A new master group is restored from the backup by "cfs-server -c master.json -restore FILE"
+on each master, with the store and the raft logs of the master empty.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				file *os.File
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			path := args[0]
+			if file, err = os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600); err != nil {
+				return
+			}
+			if err = client.AdminAPI().BackupMetadata(file); err != nil {
+				file.Close()
+				os.Remove(file.Name())
+				return
+			}
+			if err = file.Close(); err != nil {
+				return
+			}
+			if err = os.Rename(file.Name(), path); err != nil {
+				return
+			}
+			stdout("Metadata has been backed up to %v\n", path)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,441 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+func newClusterExportCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpExport + " [FILE]",
+		Short: cmdClusterExportShort,
+		Long: `Export the settings of the cluster, the zones, the volumes and the users as a JSON document, to the file
+or the standard output if not specified. The keys and the passwords of the users are not exported.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				spec *proto.ClusterSpec
+				data []byte
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if spec, err = exportClusterSpec(client); err != nil {
+				return
+			}
+			if data, err = json.MarshalIndent(spec, "", "  "); err != nil {
+				return
+			}
+			data = append(data, '\n')
+			if len(args) == 0 {
+				stdout("%s", data)
+				return
+			}
+			if err = ioutil.WriteFile(args[0], data, 0600); err != nil {
+				return
+			}
+			stdout("Configuration has been exported to %v\n", args[0])
+		},
+	}
+	return cmd
+}
+
+func newClusterApplyCmd(client *master.MasterClient) *cobra.Command {
+	var optDryRun bool
+	var cmd = &cobra.Command{
+		Use:   CliOpApply + " [FILE]",
+		Short: cmdClusterApplyShort,
+		Long: `Apply the configuration exported or written by hand to the cluster. The changes between the document and
+the cluster are listed and made one by one, so applying a document again changes nothing. The sections omitted are
+left as they are, and the volumes and the users not in the document are neither changed nor deleted.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err     error
+				data    []byte
+				current *proto.ClusterSpec
+				changes []*specChange
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if data, err = ioutil.ReadFile(args[0]); err != nil {
+				return
+			}
+			desired := &proto.ClusterSpec{}
+			if err = json.Unmarshal(data, desired); err != nil {
+				err = fmt.Errorf("parse %v failed: %v", args[0], err)
+				return
+			}
+			if current, err = exportClusterSpec(client); err != nil {
+				return
+			}
+			if changes, err = diffClusterSpec(client, current, desired); err != nil {
+				return
+			}
+			if len(changes) == 0 {
+				stdout("No changes\n")
+				return
+			}
+			for _, change := range changes {
+				if optDryRun {
+					stdout("%v\n", change.desc)
+					continue
+				}
+				if err = change.apply(); err != nil {
+					err = fmt.Errorf("%v: %v", change.desc, err)
+					return
+				}
+				stdout("%v ... done\n", change.desc)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&optDryRun, CliFlagDryRun, false, "List the changes without making them")
+	return cmd
+}
+
+// specChange is a call to the master making a part of the cluster match the document.
+type specChange struct {
+	desc  string
+	apply func() error
+}
+
+func exportClusterSpec(client *master.MasterClient) (spec *proto.ClusterSpec, err error) {
+	var (
+		cv         *proto.ClusterView
+		nodeInfo   map[string]string
+		rebalance  *proto.RebalanceView
+		autoDec    *proto.AutoDecommissionView
+		zones      []*proto.ZoneView
+		vols       []*proto.VolInfo
+		users      []*proto.UserInfo
+		volView    *proto.SimpleVolView
+		paramValue uint64
+	)
+	if cv, err = client.AdminAPI().GetCluster(); err != nil {
+		return
+	}
+	if nodeInfo, err = client.AdminAPI().GetDeleteParas(); err != nil {
+		return
+	}
+	if rebalance, err = client.AdminAPI().GetRebalance(); err != nil {
+		return
+	}
+	if autoDec, err = client.AdminAPI().GetAutoDecommission(); err != nil {
+		return
+	}
+	settings := &proto.ClusterSettingsSpec{
+		DisableAutoAlloc:  &cv.DisableAutoAlloc,
+		MetaNodeThreshold: &cv.MetaNodeThreshold,
+		NodeInfo:          make(map[string]uint64),
+		Rebalance: &proto.RebalanceSpec{
+			Enable:         rebalance.Enable,
+			Threshold:      rebalance.Threshold,
+			MaxConcurrency: rebalance.MaxConcurrency,
+		},
+		AutoDecommission: &proto.AutoDecommissionSpec{
+			Enable:         autoDec.Enable,
+			ErrorThreshold: autoDec.ErrorThreshold,
+			MaxConcurrency: autoDec.MaxConcurrency,
+			BadDiskLimit:   autoDec.BadDiskLimit,
+		},
+	}
+	for key, value := range nodeInfo {
+		if paramValue, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+		settings.NodeInfo[key] = paramValue
+	}
+	spec = &proto.ClusterSpec{
+		Cluster: settings,
+		Zones:   make([]*proto.ZoneSpec, 0),
+		Vols:    make([]*proto.VolSpec, 0),
+		Users:   make([]*proto.UserSpec, 0),
+	}
+
+	if zones, err = client.AdminAPI().ListZones(); err != nil {
+		return
+	}
+	for _, zone := range zones {
+		spec.Zones = append(spec.Zones, &proto.ZoneSpec{Name: zone.Name, Available: zone.Status == "available"})
+	}
+	sort.Slice(spec.Zones, func(i, j int) bool { return spec.Zones[i].Name < spec.Zones[j].Name })
+
+	if vols, err = client.AdminAPI().ListVols(""); err != nil {
+		return
+	}
+	for _, vol := range vols {
+		if volView, err = client.AdminAPI().GetVolumeSimpleInfo(vol.Name); err != nil {
+			return
+		}
+		spec.Vols = append(spec.Vols, &proto.VolSpec{
+			Name:            volView.Name,
+			Owner:           volView.Owner,
+			Capacity:        volView.Capacity,
+			ZoneName:        volView.ZoneName,
+			Description:     volView.Description,
+			FollowerRead:    volView.FollowerRead,
+			Authenticate:    volView.Authenticate,
+			EnableToken:     volView.EnableToken,
+			IopsLimit:       volView.IopsLimit,
+			BandwidthLimit:  volView.BandwidthLimit,
+			Compression:     volView.Compression,
+			Checksum:        volView.Checksum,
+			QuorumWrite:     volView.QuorumWrite,
+			PlacementPolicy: volView.PlacementPolicy,
+		})
+	}
+	sort.Slice(spec.Vols, func(i, j int) bool { return spec.Vols[i].Name < spec.Vols[j].Name })
+
+	if users, err = client.UserAPI().ListUsers(""); err != nil {
+		return
+	}
+	for _, user := range users {
+		userSpec := &proto.UserSpec{
+			ID:             user.UserID,
+			Type:           user.UserType.String(),
+			Description:    user.Description,
+			Quota:          user.Quota,
+			AuthorizedVols: make(map[string][]string),
+		}
+		if user.Policy != nil {
+			for vol, actions := range user.Policy.AuthorizedVols {
+				userSpec.AuthorizedVols[vol] = actions
+			}
+		}
+		spec.Users = append(spec.Users, userSpec)
+	}
+	sort.Slice(spec.Users, func(i, j int) bool { return spec.Users[i].ID < spec.Users[j].ID })
+	return
+}
+
+// diffClusterSpec lists the changes making the cluster match the desired document. The users are created before
+// the volumes owned by them, and the volumes before the users authorized to them.
+func diffClusterSpec(client *master.MasterClient, current, desired *proto.ClusterSpec) (changes []*specChange, err error) {
+	changes = make([]*specChange, 0)
+	add := func(apply func() error, format string, a ...interface{}) {
+		changes = append(changes, &specChange{desc: fmt.Sprintf(format, a...), apply: apply})
+	}
+
+	if s := desired.Cluster; s != nil {
+		cur := current.Cluster
+		if s.DisableAutoAlloc != nil && *s.DisableAutoAlloc != *cur.DisableAutoAlloc {
+			disable := *s.DisableAutoAlloc
+			add(func() error { return client.AdminAPI().IsFreezeCluster(disable) },
+				"set cluster disableAutoAlloc: %v -> %v", *cur.DisableAutoAlloc, disable)
+		}
+		if s.MetaNodeThreshold != nil && *s.MetaNodeThreshold != *cur.MetaNodeThreshold {
+			threshold := *s.MetaNodeThreshold
+			add(func() error { return client.AdminAPI().SetMetaNodeThreshold(float64(threshold)) },
+				"set cluster metaNodeThreshold: %v -> %v", *cur.MetaNodeThreshold, threshold)
+		}
+		nodeInfo := make(map[string]uint64)
+		for key, value := range s.NodeInfo {
+			curValue, ok := cur.NodeInfo[key]
+			if !ok {
+				err = fmt.Errorf("unknown node info parameter [%v]", key)
+				return
+			}
+			if value != curValue {
+				nodeInfo[key] = value
+			}
+		}
+		if len(nodeInfo) > 0 {
+			add(func() error { return client.AdminAPI().SetNodeInfo(nodeInfo) }, "set cluster nodeInfo: %v", nodeInfo)
+		}
+		if r := s.Rebalance; r != nil && *r != *cur.Rebalance {
+			add(func() error {
+				return client.AdminAPI().SetRebalance(strconv.FormatBool(r.Enable),
+					strconv.FormatFloat(r.Threshold, 'f', -1, 64), strconv.FormatUint(r.MaxConcurrency, 10))
+			}, "set cluster rebalance: %v", changedFields(*cur.Rebalance, *r))
+		}
+		if d := s.AutoDecommission; d != nil && *d != *cur.AutoDecommission {
+			add(func() error {
+				return client.AdminAPI().SetAutoDecommission(strconv.FormatBool(d.Enable),
+					strconv.FormatUint(d.ErrorThreshold, 10), strconv.FormatUint(d.MaxConcurrency, 10),
+					strconv.FormatUint(d.BadDiskLimit, 10))
+			}, "set cluster autoDecommission: %v", changedFields(*cur.AutoDecommission, *d))
+		}
+	}
+
+	curZones := make(map[string]*proto.ZoneSpec)
+	for _, zone := range current.Zones {
+		curZones[zone.Name] = zone
+	}
+	for _, zone := range desired.Zones {
+		cur, ok := curZones[zone.Name]
+		if !ok {
+			err = fmt.Errorf("zone [%v] not found, the zones are created by the nodes registered", zone.Name)
+			return
+		}
+		if zone.Available != cur.Available {
+			name, available := zone.Name, zone.Available
+			add(func() error { return client.AdminAPI().UpdateZone(name, available) },
+				"set zone [%v] available: %v -> %v", name, cur.Available, available)
+		}
+	}
+
+	curUsers := make(map[string]*proto.UserSpec)
+	for _, user := range current.Users {
+		curUsers[user.ID] = user
+	}
+	for _, user := range desired.Users {
+		if user.ID == "" {
+			err = fmt.Errorf("user without id")
+			return
+		}
+		userType := proto.UserTypeFromString(user.Type)
+		if !userType.Valid() {
+			err = fmt.Errorf("user [%v] type [%v] invalid", user.ID, user.Type)
+			return
+		}
+		cur, ok := curUsers[user.ID]
+		if !ok {
+			param := &proto.UserCreateParam{ID: user.ID, Type: userType, Description: user.Description}
+			add(func() (err error) { _, err = client.UserAPI().CreateUser(param); return },
+				"create user [%v] type [%v]", user.ID, user.Type)
+			cur = &proto.UserSpec{ID: user.ID, Type: user.Type, Description: user.Description}
+		}
+		if user.Type != cur.Type || (user.Description != "" && user.Description != cur.Description) {
+			param := &proto.UserUpdateParam{UserID: user.ID, Type: userType, Description: user.Description}
+			add(func() (err error) { _, err = client.UserAPI().UpdateUser(param); return },
+				"update user [%v] type [%v] description [%v]", user.ID, user.Type, user.Description)
+		}
+		if user.Quota != nil && (cur.Quota == nil || *user.Quota != *cur.Quota) {
+			param := &proto.UserQuotaUpdateParam{UserID: user.ID, Quota: *user.Quota}
+			add(func() (err error) { _, err = client.UserAPI().UpdateQuota(param); return },
+				"set user [%v] quota: %+v", user.ID, *user.Quota)
+		}
+	}
+
+	curVols := make(map[string]*proto.VolSpec)
+	for _, vol := range current.Vols {
+		curVols[vol.Name] = vol
+	}
+	for _, vol := range desired.Vols {
+		if vol.Name == "" || vol.Owner == "" || vol.Capacity == 0 {
+			err = fmt.Errorf("vol [%v] requires the name, the owner and the capacity", vol.Name)
+			return
+		}
+		spec := *vol
+		cur, ok := curVols[vol.Name]
+		if !ok {
+			if spec.ZoneName == "" {
+				spec.ZoneName = cmdVolDefaultZoneName
+			}
+			add(func() (err error) {
+				if err = client.AdminAPI().CreateVolume(spec.Name, spec.Owner, cmdVolDefaultMPCount, cmdVolDefaultDPSize,
+					spec.Capacity, cmdVolDefaultReplicas, spec.FollowerRead, spec.ZoneName); err != nil {
+					return
+				}
+				return client.AdminAPI().UpdateVolumeSpec(&spec, calcAuthKey(spec.Owner))
+			}, "create vol [%v] owner [%v] capacity [%v]GB", spec.Name, spec.Owner, spec.Capacity)
+			continue
+		}
+		if spec.ZoneName == "" {
+			spec.ZoneName = cur.ZoneName
+		}
+		if spec.Checksum == "" {
+			spec.Checksum = cur.Checksum
+		}
+		if spec.Owner != cur.Owner {
+			param := &proto.UserTransferVolParam{Volume: spec.Name, UserSrc: cur.Owner, UserDst: spec.Owner}
+			add(func() (err error) { _, err = client.UserAPI().TransferVol(param); return },
+				"transfer vol [%v] owner: %v -> %v", spec.Name, cur.Owner, spec.Owner)
+		}
+		curSpec := *cur
+		curSpec.Owner = spec.Owner
+		if spec != curSpec {
+			add(func() error { return client.AdminAPI().UpdateVolumeSpec(&spec, calcAuthKey(spec.Owner)) },
+				"update vol [%v]: %v", spec.Name, changedFields(curSpec, spec))
+		}
+	}
+
+	for _, user := range desired.Users {
+		if user.AuthorizedVols == nil {
+			continue
+		}
+		curAuthorized := make(map[string][]string)
+		if cur, ok := curUsers[user.ID]; ok {
+			curAuthorized = cur.AuthorizedVols
+		}
+		vols := make([]string, 0, len(user.AuthorizedVols))
+		for vol := range user.AuthorizedVols {
+			vols = append(vols, vol)
+		}
+		sort.Strings(vols)
+		for _, vol := range vols {
+			actions := user.AuthorizedVols[vol]
+			if curActions, ok := curAuthorized[vol]; ok && sameStrings(actions, curActions) {
+				continue
+			}
+			param := &proto.UserPermUpdateParam{UserID: user.ID, Volume: vol, Policy: actions}
+			add(func() (err error) { _, err = client.UserAPI().UpdatePolicy(param); return },
+				"authorize user [%v] vol [%v]: %v", user.ID, vol, actions)
+		}
+		vols = vols[:0]
+		for vol := range curAuthorized {
+			if _, ok := user.AuthorizedVols[vol]; !ok {
+				vols = append(vols, vol)
+			}
+		}
+		sort.Strings(vols)
+		for _, vol := range vols {
+			param := &proto.UserPermRemoveParam{UserID: user.ID, Volume: vol}
+			add(func() (err error) { _, err = client.UserAPI().RemovePolicy(param); return },
+				"unauthorize user [%v] vol [%v]", user.ID, vol)
+		}
+	}
+	return
+}
+
+// changedFields lists the fields of the two structs of the same type that differ, by their JSON names.
+func changedFields(from, to interface{}) string {
+	fromValue, toValue := reflect.ValueOf(from), reflect.ValueOf(to)
+	changes := make([]string, 0)
+	for i := 0; i < fromValue.NumField(); i++ {
+		if reflect.DeepEqual(fromValue.Field(i).Interface(), toValue.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(fromValue.Type().Field(i).Tag.Get("json"), ",")[0]
+		changes = append(changes, fmt.Sprintf("%v %q -> %q", name,
+			fmt.Sprint(fromValue.Field(i).Interface()), fmt.Sprint(toValue.Field(i).Interface())))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// sameStrings returns if the two lists have the same strings regardless of the order.
+func sameStrings(a, b []string) bool {
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return reflect.DeepEqual(sortedA, sortedB)
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+)
+
+func TestDiffClusterSpec(t *testing.T) {
+	disableAutoAlloc := false
+	readOnly := []string{"perm:builtin:ReadOnly"}
+	current := &proto.ClusterSpec{
+		Cluster: &proto.ClusterSettingsSpec{
+			DisableAutoAlloc: &disableAutoAlloc,
+			Rebalance:        &proto.RebalanceSpec{Enable: true, Threshold: 0.2, MaxConcurrency: 2},
+		},
+		Zones: []*proto.ZoneSpec{{Name: "default", Available: true}},
+		Vols:  []*proto.VolSpec{{Name: "vol1", Owner: "user1", Capacity: 10, ZoneName: "default", Checksum: "crc32"}},
+		Users: []*proto.UserSpec{
+			{ID: "user1", Type: "normal", AuthorizedVols: map[string][]string{}},
+			{ID: "user2", Type: "normal", AuthorizedVols: map[string][]string{"vol1": readOnly}},
+		},
+	}
+	tests := []struct {
+		name    string
+		desired *proto.ClusterSpec
+		changes []string
+	}{
+		{
+			name:    "re-apply",
+			desired: current,
+			changes: []string{},
+		},
+		{
+			name: "user created before the vol owned",
+			desired: &proto.ClusterSpec{
+				Vols:  []*proto.VolSpec{{Name: "vol2", Owner: "user3", Capacity: 20}},
+				Users: []*proto.UserSpec{{ID: "user3", Type: "normal"}},
+			},
+			changes: []string{
+				"create user [user3] type [normal]",
+				"create vol [vol2] owner [user3] capacity [20]GB",
+			},
+		},
+		{
+			name: "policy added and removed",
+			desired: &proto.ClusterSpec{
+				Users: []*proto.UserSpec{{ID: "user2", Type: "normal", AuthorizedVols: map[string][]string{"vol2": readOnly}}},
+			},
+			changes: []string{
+				"authorize user [user2] vol [vol2]: [perm:builtin:ReadOnly]",
+				"unauthorize user [user2] vol [vol1]",
+			},
+		},
+	}
+	client := master.NewMasterClient(nil, false)
+	for _, tt := range tests {
+		changes, err := diffClusterSpec(client, current, tt.desired)
+		if err != nil {
+			t.Errorf("name[%v] err[%v]", tt.name, err)
+			continue
+		}
+		descs := make([]string, 0, len(changes))
+		for _, change := range changes {
+			descs = append(descs, change.desc)
+		}
+		if !reflect.DeepEqual(descs, tt.changes) {
+			t.Errorf("name[%v] changes[%q] expected[%q]", tt.name, descs, tt.changes)
+		}
+	}
+}
@@ -46,6 +46,8 @@ var (
 type Config struct {
 	MasterAddr []string `json:"masterAddr"`
 	Timeout    uint16   `json:"timeout"`
+	AccessKey  string   `json:"accessKey,omitempty"` // credential signing the requests to the admin APIs
+	SecretKey  string   `json:"secretKey,omitempty"`
 }
 
 func newConfigCmd() *cobra.Command {
@@ -66,6 +68,7 @@ const (
 func newConfigSetCmd() *cobra.Command {
 	var optMasterHost string
 	var optTimeout uint16
+	var optAccessKey, optSecretKey string
 	var cmd = &cobra.Command{
 		Use:   CliOpSet,
 		Short: cmdConfigSetShort,
@@ -80,14 +83,14 @@ func newConfigSetCmd() *cobra.Command {
 					errout("Error: %v", err)
 				}
 			}()
-			if optMasterHost == "" && optTimeout == 0 {
+			if optMasterHost == "" && optTimeout == 0 && optAccessKey == "" && optSecretKey == "" {
 				stdout(fmt.Sprintf("No change. Input 'cfs-cli config set -h' for help.\n"))
 				return
 			}
 			if len(optMasterHost) != 0 {
 				masterHosts = append(masterHosts, optMasterHost)
 			}
-			if err = setConfig(masterHosts, optTimeout); err != nil {
+			if err = setConfig(masterHosts, optTimeout, optAccessKey, optSecretKey); err != nil {
 				return
 			}
 			stdout(fmt.Sprintf("Config has been set successfully!\n"))
@@ -95,6 +98,8 @@ func newConfigSetCmd() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&optMasterHost, "addr", "", "Specify master address [{HOST}:{PORT}]")
 	cmd.Flags().Uint16Var(&optTimeout, "timeout", 0, "Specify timeout for requests [Unit: s]")
+	cmd.Flags().StringVar(&optAccessKey, "accessKey", "", "Specify access key of the user signing the requests")
+	cmd.Flags().StringVar(&optSecretKey, "secretKey", "", "Specify secret key of the user signing the requests")
 	return cmd
 }
 func newConfigInfoCmd() *cobra.Command {
@@ -121,9 +126,10 @@ func printConfigInfo(config *Config) {
 	stdout("Config info:\n")
 	stdout("  Master  Address    : %v\n", config.MasterAddr)
 	stdout("  Request Timeout [s]: %v\n", config.Timeout)
+	stdout("  Access  Key        : %v\n", config.AccessKey)
 }
 
-func setConfig(masterHosts []string, timeout uint16) (err error) {
+func setConfig(masterHosts []string, timeout uint16, accessKey, secretKey string) (err error) {
 	var config *Config
 	if config, err = LoadConfig(); err != nil {
 		return
@@ -134,6 +140,12 @@ func setConfig(masterHosts []string, timeout uint16) (err error) {
 	if timeout != 0 {
 		config.Timeout = timeout
 	}
+	if accessKey != "" {
+		config.AccessKey = accessKey
+	}
+	if secretKey != "" {
+		config.SecretKey = secretKey
+	}
 	var configData []byte
 	if configData, err = json.Marshal(config); err != nil {
 		return
@@ -36,6 +36,17 @@ const (
 	CliOpDelReplica        = "del-replica"
 	CliOpExpand              = "expand"
 	CliOpShrink              = "shrink"
+	CliOpRebalance           = "rebalance"
+	CliOpPause               = "pause"
+	CliOpResume              = "resume"
+	CliOpCancel              = "cancel"
+	CliOpAutoDecommission    = "auto-decommission"
+	CliOpEnable              = "enable"
+	CliOpDisable             = "disable"
+	CliOpAudit               = "audit"
+	CliOpBackup              = "backup"
+	CliOpExport              = "export"
+	CliOpApply               = "apply"
 
 	//Shorthand format of operation name
 	CliOpDecommissionShortHand = "dec"
@@ -73,6 +84,13 @@ const (
 	CliFlagDelBatchCount      = "delete-batch-count"
 	CliFlagDelWorkerSleepMs   = "delete-worker-sleep-ms"
 	CliFlagMarkDelRate        = "mark-delete-rate"
+	CliFlagMaxConcurrency     = "max-concurrency"
+	CliFlagErrorThreshold     = "error-threshold"
+	CliFlagBadDiskLimit       = "bad-disk-limit"
+	CliFlagOp                 = "op"
+	CliFlagSince              = "since"
+	CliFlagLimit              = "limit"
+	CliFlagDryRun             = "dry-run"
 
 	//CliFlagSetDataPartitionCount	= "count" use dp-count instead
 
@@ -136,7 +136,7 @@ func newDataNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 			if err = client.NodeAPI().DataNodeDecommission(nodeAddr); err != nil {
 				return
 			}
-			stdout("Decommission data node successfully\n")
+			stdout("Decommission data node started, see the progress by 'job list'\n")
 
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -46,6 +47,9 @@ var (
 	zoneStatInfoTablePattern = "    %-10v   %-10v  %-15v    %-15v    %-15v    %-15v    %-10v    %-10v\n"
 	zoneStatInfoTableHeader  = fmt.Sprintf(zoneStatInfoTablePattern,
 		"ZONE NAME", "ROLE", "TOTAL/GB", "USED/GB", "AVAILABLE/GB ", "USED RATIO", "TOTAL NODES", "WRITEBLE NODES")
+	forecastTablePattern = "    %-8v    %-24v    %-12v    %-12v    %-14v    %-12v\n"
+	forecastTableHeader  = fmt.Sprintf(forecastTablePattern,
+		"SCOPE", "NAME", "TOTAL/GB", "USED/GB", "GROWTH/GB/DAY", "DAYS TO FULL")
 )
 
 func formatClusterStat(cs *proto.ClusterStatInfo) string {
@@ -65,9 +69,126 @@ func formatClusterStat(cs *proto.ClusterStatInfo) string {
 		sb.WriteString(fmt.Sprintf(zoneStatInfoTablePattern, zoneName, "DATANODE", zoneStat.DataNodeStat.Total, zoneStat.DataNodeStat.Used, zoneStat.DataNodeStat.Avail, zoneStat.DataNodeStat.UsedRatio, zoneStat.DataNodeStat.TotalNodes, zoneStat.DataNodeStat.WritableNodes))
 		sb.WriteString(fmt.Sprintf(zoneStatInfoTablePattern, "", "METANODE", zoneStat.MetaNodeStat.Total, zoneStat.MetaNodeStat.Used, zoneStat.MetaNodeStat.Avail, zoneStat.MetaNodeStat.UsedRatio, zoneStat.MetaNodeStat.TotalNodes, zoneStat.MetaNodeStat.WritableNodes))
 	}
+	if len(cs.Forecasts) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("Capacity Forecast:\n"))
+		sb.WriteString(forecastTableHeader)
+		for _, f := range cs.Forecasts {
+			daysToFull := "N/A"
+			if f.DaysToFull >= 0 {
+				daysToFull = fmt.Sprintf("%.2f", f.DaysToFull)
+			}
+			sb.WriteString(fmt.Sprintf(forecastTablePattern, f.Scope, f.Name, f.TotalGB, f.UsedGB, f.GrowthGBPerDay, daysToFull))
+		}
+	}
+	return sb.String()
+}
+
+func formatRebalanceView(view *proto.RebalanceView) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Rebalance          : %v\n", formatEnabledDisabled(view.Enable)))
+	sb.WriteString(fmt.Sprintf("  Threshold          : %v\n", view.Threshold))
+	sb.WriteString(fmt.Sprintf("  Max concurrency    : %v\n", view.MaxConcurrency))
 	return sb.String()
 }
 
+var (
+	rebalanceImbalanceTablePattern = "%-8v    %-16v    %-12v    %-10v    %-12v    %-10v\n"
+	rebalanceImbalanceTableHeader  = fmt.Sprintf(rebalanceImbalanceTablePattern,
+		"SCOPE", "NAME", "DATA USAGE", "DATA DPS", "META MEMORY", "META MPS")
+	rebalanceMigrationTablePattern = "%-4v    %-8v    %-12v    %-18v    %-18v    %-10v    %-19v    %v\n"
+	rebalanceMigrationTableHeader  = fmt.Sprintf(rebalanceMigrationTablePattern,
+		"KIND", "ID", "VOLUME", "SOURCE", "TARGET", "STATUS", "START TIME", "REASON")
+)
+
+func formatRebalanceImbalance(imbalance *proto.RebalanceImbalance) string {
+	return fmt.Sprintf(rebalanceImbalanceTablePattern, imbalance.Scope, imbalance.Name,
+		fmt.Sprintf("%.2f%%", imbalance.DataUsageSpread*100), imbalance.DataPartitionSpread,
+		fmt.Sprintf("%.2f%%", imbalance.MetaMemSpread*100), imbalance.MetaPartitionSpread)
+}
+
+func formatRebalanceMigration(m *proto.RebalanceMigration) string {
+	reason := m.Reason
+	if m.Msg != "" {
+		reason = fmt.Sprintf("%v: %v", m.Reason, m.Msg)
+	}
+	return fmt.Sprintf(rebalanceMigrationTablePattern, m.Kind, m.PartitionID, m.VolName, m.Src, m.Target,
+		m.Status, formatTime(m.StartTime), reason)
+}
+
+func formatAutoDecommissionView(view *proto.AutoDecommissionView) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Auto decommission  : %v\n", formatEnabledDisabled(view.Enable)))
+	sb.WriteString(fmt.Sprintf("  Suspended          : %v\n", view.Suspended))
+	sb.WriteString(fmt.Sprintf("  Error threshold    : %v\n", view.ErrorThreshold))
+	sb.WriteString(fmt.Sprintf("  Max concurrency    : %v\n", view.MaxConcurrency))
+	sb.WriteString(fmt.Sprintf("  Bad disk limit     : %v\n", view.BadDiskLimit))
+	return sb.String()
+}
+
+var (
+	badDiskTablePattern = "%-18v    %-24v    %-10v    %v\n"
+	badDiskTableHeader  = fmt.Sprintf(badDiskTablePattern, "ADDRESS", "DISK", "PARTITIONS", "REASON")
+	diskFailureEventTablePattern = "%-19v    %-12v    %-18v    %-24v    %-8v    %v\n"
+	diskFailureEventTableHeader  = fmt.Sprintf(diskFailureEventTablePattern,
+		"TIME", "ACTION", "ADDRESS", "DISK", "JOB", "REASON")
+)
+
+func formatBadDisk(disk *proto.BadDisk) string {
+	return fmt.Sprintf(badDiskTablePattern, disk.Addr, disk.DiskPath, disk.Partitions, disk.Reason)
+}
+
+func formatDiskFailureEvent(event *proto.DiskFailureEvent) string {
+	return fmt.Sprintf(diskFailureEventTablePattern, formatTime(event.Time), event.Action, event.Addr, event.DiskPath,
+		event.JobID, event.Reason)
+}
+
+var (
+	auditRecordTablePattern = "%-19v    %-12v    %-4v    %-21v    %-28v    %-6v    %v\n"
+	auditRecordTableHeader  = fmt.Sprintf(auditRecordTablePattern, "TIME", "USER", "AUTH", "REMOTE", "OP", "CODE", "PARAMS / MSG")
+)
+
+func formatAuditRecord(record *proto.AuditRecord) string {
+	keys := make([]string, 0, len(record.Params))
+	for key := range record.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		params = append(params, fmt.Sprintf("%v=%v", key, record.Params[key]))
+	}
+	detail := strings.Join(params, "&")
+	if record.Msg != "" {
+		detail += " / " + record.Msg
+	}
+	return fmt.Sprintf(auditRecordTablePattern, formatTime(record.Time), record.User, formatYesNo(record.Authenticated),
+		record.RemoteAddr, record.Op, record.Code, detail)
+}
+
+var (
+	jobTablePattern = "%-8v    %-8v    %-18v    %-12v    %-9v    %-22v    %-19v    %v\n"
+	jobTableHeader  = fmt.Sprintf(jobTablePattern,
+		"ID", "TYPE", "ADDRESS", "DISK", "STATUS", "TOTAL/PEND/MIG/DONE/FAIL", "UPDATE TIME", "MESSAGE")
+	jobPartitionTablePattern = "%-8v    %-12v    %-9v    %-7v    %-19v    %v\n"
+	jobPartitionTableHeader  = fmt.Sprintf(jobPartitionTablePattern,
+		"ID", "VOLUME", "STATUS", "RETRIES", "NEXT RETRY", "MESSAGE")
+)
+
+func formatDecommissionJobView(view *proto.DecommissionJobView) string {
+	progress := fmt.Sprintf("%v/%v/%v/%v/%v", view.Total, view.Pending, view.Migrating, view.Done, view.Failed)
+	return fmt.Sprintf(jobTablePattern, view.ID, view.Type, view.Addr, view.DiskPath, view.Status, progress,
+		formatTime(view.UpdateTime), view.Msg)
+}
+
+func formatDecommissionPartition(p *proto.DecommissionPartition) string {
+	var nextRetry string
+	if p.Status == proto.PartitionPending && p.NextRetryTime > 0 {
+		nextRetry = formatTime(p.NextRetryTime)
+	}
+	return fmt.Sprintf(jobPartitionTablePattern, p.PartitionID, p.VolName, p.Status, p.Retries, nextRetry, p.Msg)
+}
+
 var nodeViewTableRowPattern = "%-6v    %-18v    %-8v    %-8v"
 
 func formatNodeViewTableHeader() string {
@@ -441,6 +562,7 @@ func formatDataNodeDetail(dn *proto.DataNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Available           : %v\n", formatSize(dn.AvailableSpace)))
 	sb.WriteString(fmt.Sprintf("  Total               : %v\n", formatSize(dn.Total)))
 	sb.WriteString(fmt.Sprintf("  Zone                : %v\n", dn.ZoneName))
+	sb.WriteString(fmt.Sprintf("  Labels              : %v\n", dn.Labels))
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(dn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(dn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", dn.DataPartitionCount))
@@ -468,6 +590,7 @@ func formatMetaNodeDetail(mn *proto.MetaNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Used                : %v\n", formatSize(mn.Used)))
 	sb.WriteString(fmt.Sprintf("  Total               : %v\n", formatSize(mn.Total)))
 	sb.WriteString(fmt.Sprintf("  Zone                : %v\n", mn.ZoneName))
+	sb.WriteString(fmt.Sprintf("  Labels              : %v\n", mn.Labels))
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(mn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(mn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", mn.MetaPartitionCount))
@@ -0,0 +1,144 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdJobUse         = "job [COMMAND]"
+	cmdJobShort       = "Manage decommission jobs"
+	cmdJobListShort   = "List decommission jobs and their progress"
+	cmdJobInfoShort   = "Show decommission job and its partitions"
+	cmdJobCancelShort = "Cancel decommission job"
+)
+
+func newJobCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdJobUse,
+		Short: cmdJobShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newJobListCmd(client),
+		newJobInfoCmd(client),
+		newJobCancelCmd(client),
+	)
+	return cmd
+}
+
+func newJobListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpList,
+		Short:   cmdJobListShort,
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				jobs []*proto.DecommissionJobView
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if jobs, err = client.AdminAPI().ListDecommissionJobs(); err != nil {
+				return
+			}
+			stdout(jobTableHeader)
+			for _, job := range jobs {
+				stdout(formatDecommissionJobView(job))
+			}
+		},
+	}
+	return cmd
+}
+
+func newJobInfoCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpInfo + " [ID]",
+		Short: cmdJobInfoShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err error
+				id  uint64
+				job *proto.DecommissionJob
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if id, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				err = fmt.Errorf("Parse job ID fail: %v\n", err)
+				return
+			}
+			if job, err = client.AdminAPI().GetDecommissionJob(id); err != nil {
+				return
+			}
+			stdout("[Job]\n")
+			stdout("  ID          : %v\n", job.ID)
+			stdout("  Type        : %v\n", job.Type)
+			stdout("  Address     : %v\n", job.Addr)
+			stdout("  Disk        : %v\n", job.DiskPath)
+			stdout("  Status      : %v\n", job.Status)
+			stdout("  Message     : %v\n", job.Msg)
+			stdout("  Create time : %v\n", formatTime(job.CreateTime))
+			stdout("  Update time : %v\n", formatTime(job.UpdateTime))
+			stdout("\n[Partitions]\n")
+			stdout(jobPartitionTableHeader)
+			for _, p := range job.Partitions {
+				stdout(formatDecommissionPartition(p))
+			}
+		},
+	}
+	return cmd
+}
+
+func newJobCancelCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpCancel + " [ID]",
+		Short: cmdJobCancelShort,
+		Long:  `Stop moving the partitions out of the node or the disk, the partitions already moved stay on the new nodes.`,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err error
+				id  uint64
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if id, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+				err = fmt.Errorf("Parse job ID fail: %v\n", err)
+				return
+			}
+			if err = client.AdminAPI().CancelDecommissionJob(id); err != nil {
+				return
+			}
+			stdout("Job %v is cancelled!\n", id)
+		},
+	}
+	return cmd
+}
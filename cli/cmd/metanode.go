@@ -136,7 +136,7 @@ func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 			if err = client.NodeAPI().MetaNodeDecommission(nodeAddr); err != nil {
 				return
 			}
-			stdout("Decommission meta node successfully\n")
+			stdout("Decommission meta node started, see the progress by 'job list'\n")
 
 		},
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
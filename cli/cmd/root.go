@@ -63,6 +63,7 @@ func NewRootCmd(client *master.MasterClient) *ChubaoFSCmd {
 		newConfigCmd(),
 		newCompatibilityCmd(),
 		newZoneCmd(client),
+		newJobCmd(client),
 	)
 	return cmd
 }
@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -40,6 +41,7 @@ func newUserCmd(client *master.MasterClient) *cobra.Command {
 		newUserListCmd(client),
 		newUserPermCmd(client),
 		newUserUpdateCmd(client),
+		newUserQuotaCmd(client),
 		newUserDeleteCmd(client),
 	)
 	return cmd
@@ -304,6 +306,58 @@ func newUserInfoCmd(client *master.MasterClient) *cobra.Command {
 	return cmd
 }
 
+const (
+	cmdUserQuotaUse   = "quota [USER ID]"
+	cmdUserQuotaShort = "Set the quota of the volumes owned by a user, 0 for no limit"
+)
+
+func newUserQuotaCmd(client *master.MasterClient) *cobra.Command {
+	var optCapacity uint64
+	var optVolCount uint64
+	var optObjectCount uint64
+	var cmd = &cobra.Command{
+		Use:   cmdUserQuotaUse,
+		Short: cmdUserQuotaShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var userID = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var param = proto.UserQuotaUpdateParam{
+				UserID: userID,
+				Quota: proto.UserQuota{
+					Capacity:    optCapacity,
+					VolCount:    optVolCount,
+					ObjectCount: optObjectCount,
+				},
+			}
+			if _, err = client.UserAPI().UpdateQuota(&param); err != nil {
+				return
+			}
+			var userInfo *proto.UserInfo
+			if userInfo, err = client.UserAPI().GetUserInfo(userID); err != nil {
+				return
+			}
+			stdout("Update user quota success:\n")
+			printUserQuota(userInfo.Quota, userInfo.Usage)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validUsers(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Uint64Var(&optCapacity, "capacity", 0, "Specify the total capacity of the volumes in GB")
+	cmd.Flags().Uint64Var(&optVolCount, "vol-count", 0, "Specify the number of the volumes")
+	cmd.Flags().Uint64Var(&optObjectCount, "object-count", 0, "Specify the number of the files and directories in the volumes")
+	return cmd
+}
+
 const (
 	cmdUserPermUse   = "perm [USER ID] [VOLUME] [PERM (READONLY,RO,READWRITE,RW,NONE)]"
 	cmdUserPermShort = "Setup volume permission for a user"
@@ -425,6 +479,9 @@ func printUserInfo(userInfo *proto.UserInfo) {
 	stdout("  Secret Key : %v\n", userInfo.SecretKey)
 	stdout("  Type       : %v\n", userInfo.UserType)
 	stdout("  Create Time: %v\n", userInfo.CreateTime)
+	if !userInfo.Quota.IsUnlimited() || userInfo.Usage != nil {
+		printUserQuota(userInfo.Quota, userInfo.Usage)
+	}
 	if userInfo.Policy == nil {
 		return
 	}
@@ -437,3 +494,24 @@ func printUserInfo(userInfo *proto.UserInfo) {
 		stdout("%-20v    %-12v\n", vol, strings.Join(perms, ","))
 	}
 }
+
+func printUserQuota(quota *proto.UserQuota, usage *proto.UserUsage) {
+	if quota == nil {
+		quota = &proto.UserQuota{}
+	}
+	if usage == nil {
+		usage = &proto.UserUsage{}
+	}
+	var formatLimit = func(limit uint64) string {
+		if limit == 0 {
+			return "unlimited"
+		}
+		return strconv.FormatUint(limit, 10)
+	}
+	stdout("[Quota]\n")
+	stdout("%-20v    %-12v    %-12v\n", "ITEM", "USED", "LIMIT")
+	stdout("%-20v    %-12v    %-12v\n", "Capacity (GB)", usage.Capacity, formatLimit(quota.Capacity))
+	stdout("%-20v    %-12v    %-12v\n", "Volumes", usage.VolCount, formatLimit(quota.VolCount))
+	stdout("%-20v    %-12v    %-12v\n", "Objects", usage.ObjectCount, formatLimit(quota.ObjectCount))
+	stdout("%-20v    %-12v\n", "Used Size", formatSize(usage.UsedSize))
+}
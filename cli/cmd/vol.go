@@ -48,6 +48,8 @@ func newVolCmd(client *master.MasterClient) *cobra.Command {
 		newVolInfoCmd(client),
 		newVolDeleteCmd(client),
 		newVolTransferCmd(client),
+		newVolRenameCmd(client),
+		newVolCloneCmd(client),
 		newVolAddDPCmd(client),
 	)
 	return cmd
@@ -485,6 +487,82 @@ func newVolTransferCmd(client *master.MasterClient) *cobra.Command {
 	return cmd
 }
 
+const (
+	cmdVolRenameUse   = "rename [VOLUME NAME] [NEW NAME]"
+	cmdVolRenameShort = "Rename a volume, the old name is kept as an alias of it"
+)
+
+func newVolRenameCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolRenameUse,
+		Short: cmdVolRenameShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var newName = args[1]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				err = fmt.Errorf("Rename volume failed:\n%v\n", err)
+				return
+			}
+			if err = client.AdminAPI().RenameVolume(volumeName, newName, calcAuthKey(svv.Owner)); err != nil {
+				err = fmt.Errorf("Rename volume failed:\n%v\n", err)
+				return
+			}
+			stdout("Rename volume success.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdVolCloneUse   = "clone [SOURCE VOLUME] [VOLUME NAME] [USER ID]"
+	cmdVolCloneShort = "Create a volume with a copy of the metadata of the source volume, sharing the data of it"
+)
+
+func newVolCloneCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolCloneUse,
+		Short: cmdVolCloneShort,
+		Args:  cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var source = args[0]
+			var volumeName = args[1]
+			var userID = args[2]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().CloneVolume(volumeName, source, userID); err != nil {
+				err = fmt.Errorf("Clone volume failed:\n%v\n", err)
+				return
+			}
+			stdout("Clone volume success.\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
 const (
 	cmdVolAddDPCmdUse   = "add-dp [VOLUME] [NUMBER]"
 	cmdVolAddDPCmdShort = "Create and add more data partition to a volume"
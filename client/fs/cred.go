@@ -0,0 +1,71 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"bazil.org/fuse"
+	"golang.org/x/net/context"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+)
+
+type credKey struct{}
+
+// WithContext attaches the credential of the process making the request to the context, so the meta nodes
+// check the permissions of the caller instead of the ones of the mount owner.
+func (s *Super) WithContext(ctx context.Context, req fuse.Request) context.Context {
+	hdr := req.Hdr()
+	cred := &proto.UserCred{Uid: hdr.Uid, Gid: hdr.Gid}
+	if s.enablePosixACL {
+		cred.Gids = processGroups(hdr.Pid)
+	}
+	return context.WithValue(ctx, credKey{}, cred)
+}
+
+// metaOf returns the meta wrapper issuing the operations with the credential of the caller of the request.
+func (s *Super) metaOf(ctx context.Context) *meta.CredWrapper {
+	cred, _ := ctx.Value(credKey{}).(*proto.UserCred)
+	return s.mw.WithCred(cred)
+}
+
+// processGroups returns the supplementary groups of the process, nil if they are not available.
+func processGroups(pid uint32) (gids []uint32) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Groups:") {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, "Groups:")) {
+			if gid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				gids = append(gids, uint32(gid))
+			}
+		}
+		break
+	}
+	return
+}
@@ -17,6 +17,7 @@ package fs
 import (
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,6 +55,8 @@ type Super struct {
 	fsyncOnClose  bool
 	enableXattr   bool
 	rootIno       uint64
+
+	enablePosixACL bool
 }
 
 // Functions that Super needs to implement
@@ -67,12 +70,17 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	s = new(Super)
 	var masters = strings.Split(opt.Master, meta.HostsSeparator)
 	var metaConfig = &meta.MetaConfig{
-		Volume:        opt.Volname,
-		Owner:         opt.Owner,
-		Masters:       masters,
-		Authenticate:  opt.Authenticate,
-		TicketMess:    opt.TicketMess,
-		ValidateOwner: opt.Authenticate || opt.AccessKey == "",
+		Volume:         opt.Volname,
+		Owner:          opt.Owner,
+		Masters:        masters,
+		Authenticate:   opt.Authenticate,
+		TicketMess:     opt.TicketMess,
+		ValidateOwner:  opt.Authenticate || opt.AccessKey == "",
+		EnablePosixACL: opt.EnablePosixACL,
+		UserCred: &proto.UserCred{
+			Uid: uint32(os.Getuid()),
+			Gid: uint32(os.Getgid()),
+		},
 	}
 	s.mw, err = meta.NewMetaWrapper(metaConfig)
 	if err != nil {
@@ -102,6 +110,7 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	s.disableDcache = opt.DisableDcache
 	s.fsyncOnClose = opt.FsyncOnClose
 	s.enableXattr = opt.EnableXattr
+	s.enablePosixACL = opt.EnablePosixACL
 
 	var extentConfig = &stream.ExtentConfig{
 		Volume:            opt.Volname,
@@ -114,6 +123,16 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		OnGetExtents:      s.mw.GetExtents,
 		OnTruncate:        s.mw.Truncate,
 		OnEvictIcache:     s.ic.Delete,
+
+		OnGetTieredExtents: s.mw.GetTieredExtents,
+		OnGetTierSecure:    s.mw.TierSecure,
+	}
+	// neither defragment nor rehydrate the files of a read-only mount
+	if !opt.Rdonly {
+		extentConfig.OnReplaceExtentKeys = s.mw.ReplaceExtentKeys
+		extentConfig.OnPrepareExtentKey = s.mw.PrepareExtentKey
+		extentConfig.OnDiscardExtentKey = s.mw.DiscardExtentKey
+		extentConfig.DefragThreshold = int(opt.DefragThreshold)
 	}
 	s.ec, err = stream.NewExtentClient(extentConfig)
 	if err != nil {
@@ -186,6 +205,85 @@ func (s *Super) SetRate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Defragment rewrites the fragmented extents of the file, or of all the files
+// under the directory, of the given path relative to the mount point.
+func (s *Super) Defragment(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	minFragments := 2
+	if val := r.FormValue("fragments"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			w.Write([]byte("Invalid fragments\n"))
+			return
+		}
+		minFragments = n
+	}
+
+	ino, mode, err := s.lookupPath(r.FormValue("path"))
+	if err != nil {
+		w.Write([]byte(fmt.Sprintf("Lookup path failed: %v\n", err)))
+		return
+	}
+
+	count, err := s.defragment(ino, mode, minFragments)
+	if err != nil {
+		w.Write([]byte(fmt.Sprintf("Defragment failed after %v files: %v\n", count, err)))
+		return
+	}
+	w.Write([]byte(fmt.Sprintf("Defragment %v files successfully\n", count)))
+}
+
+func (s *Super) lookupPath(path string) (ino uint64, mode uint32, err error) {
+	ino = s.rootIno
+	mode = uint32(os.ModeDir)
+	for _, name := range strings.Split(path, "/") {
+		if name == "" || name == "." {
+			continue
+		}
+		if ino, mode, err = s.mw.Lookup_ll(ino, name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *Super) defragment(ino uint64, mode uint32, minFragments int) (count int, err error) {
+	if proto.IsRegular(mode) {
+		if err = s.ec.OpenStream(ino); err != nil {
+			return
+		}
+		err = s.ec.Defragment(ino, minFragments)
+		if closeErr := s.ec.CloseStream(ino); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			log.LogErrorf("Defragment: ino(%v) err(%v)", ino, err)
+			return
+		}
+		return 1, nil
+	}
+
+	if !proto.IsDir(mode) {
+		return
+	}
+	children, err := s.mw.ReadDir_ll(ino)
+	if err != nil {
+		return
+	}
+	for _, child := range children {
+		n, e := s.defragment(child.Inode, child.Type, minFragments)
+		count += n
+		if e != nil {
+			return count, e
+		}
+	}
+	return
+}
+
 func (s *Super) exporterKey(act string) string {
 	return fmt.Sprintf("%v_fuseclient_%v", s.cluster, act)
 }
@@ -44,6 +44,7 @@ import (
 	"bazil.org/fuse/fs"
 	cfs "github.com/chubaofs/chubaofs/client/fs"
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
@@ -69,6 +70,7 @@ const (
 	ControlCommandSetRate      = "/rate/set"
 	ControlCommandGetRate      = "/rate/get"
 	ControlCommandFreeOSMemory = "/debug/freeosmemory"
+	ControlCommandDefrag       = "/defrag"
 	Role                       = "Client"
 )
 
@@ -112,6 +114,10 @@ func main() {
 		daemonize.SignalOutcome(err)
 		os.Exit(1)
 	}
+	if err = util.InitPacketTLS(opt.TLSCertFile, opt.TLSKeyFile, opt.TLSCAFile, opt.TLSServerName); err != nil {
+		daemonize.SignalOutcome(err)
+		os.Exit(1)
+	}
 
 	if opt.MaxCPUs > 0 {
 		runtime.GOMAXPROCS(int(opt.MaxCPUs))
@@ -177,7 +183,8 @@ func main() {
 
 	exporter.RegistConsul(super.ClusterName(), ModuleName, cfg)
 
-	if err = fs.Serve(fsConn, super); err != nil {
+	server := fs.New(fsConn, &fs.Config{WithContext: super.WithContext})
+	if err = server.Serve(super); err != nil {
 		log.LogFlush()
 		syslog.Printf("fs Serve returns err(%v)", err)
 		os.Exit(1)
@@ -242,6 +249,7 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 	http.HandleFunc(ControlCommandGetRate, super.GetRate)
 	http.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
 	http.HandleFunc(ControlCommandFreeOSMemory, freeOSMemory)
+	http.HandleFunc(ControlCommandDefrag, super.Defragment)
 	http.HandleFunc(log.GetLogPath, log.GetLog)
 
 	go func() {
@@ -350,6 +358,11 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.EnableXattr = GlobalMountOptions[proto.EnableXattr].GetBool()
 	opt.NearRead = GlobalMountOptions[proto.NearRead].GetBool()
 	opt.EnablePosixACL = GlobalMountOptions[proto.EnablePosixACL].GetBool()
+	opt.DefragThreshold = GlobalMountOptions[proto.DefragThreshold].GetInt64()
+	opt.TLSCertFile = GlobalMountOptions[proto.PacketTLSCert].GetString()
+	opt.TLSKeyFile = GlobalMountOptions[proto.PacketTLSKey].GetString()
+	opt.TLSCAFile = GlobalMountOptions[proto.PacketTLSCA].GetString()
+	opt.TLSServerName = GlobalMountOptions[proto.PacketTLSServerName].GetString()
 
 	if opt.MountPoint == "" || opt.Volname == "" || opt.Owner == "" || opt.Master == "" {
 		return nil, errors.New(fmt.Sprintf("invalid config file: lack of mandatory fields, mountPoint(%v), volName(%v), owner(%v), masterAddr(%v)", opt.MountPoint, opt.Volname, opt.Owner, opt.Master))
@@ -360,6 +373,7 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 
 func checkPermission(opt *proto.MountOptions) (err error) {
 	var mc = master.NewMasterClientFromString(opt.Master, false)
+	mc.SetCredential(opt.AccessKey, opt.SecretKey)
 
 	// Check token permission
 	var info *proto.VolStatInfo
@@ -42,6 +42,7 @@ import (
 	"github.com/chubaofs/chubaofs/datanode"
 	"github.com/chubaofs/chubaofs/master"
 	"github.com/chubaofs/chubaofs/metanode"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/log"
 	"github.com/chubaofs/chubaofs/util/ump"
@@ -81,6 +82,7 @@ var (
 	configFile       = flag.String("c", "", "config file path")
 	configVersion    = flag.Bool("v", false, "show version")
 	configForeground = flag.Bool("f", false, "run foreground")
+	configRestore    = flag.String("restore", "", "seed the store of a new master from the metadata backup, and exit")
 )
 
 func interceptSignal(s common.Server) {
@@ -134,6 +136,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *configRestore != "" {
+		if role := cfg.GetString(ConfigKeyRole); role != RoleMaster {
+			fmt.Printf("Restore failed: role[%v] is not %v\n", role, RoleMaster)
+			os.Exit(1)
+		}
+		count, err := master.RestoreMetadata(cfg, *configRestore)
+		if err != nil {
+			fmt.Printf("Restore failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored %v key-values from %v\n", count, *configRestore)
+		os.Exit(0)
+	}
+
 	if !*configForeground {
 		if err := startDaemon(); err != nil {
 			fmt.Printf("Server start failed: %v\n", err)
@@ -240,6 +256,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the packet protocol runs over TLS with the certificate of the role if configured
+	if err = util.InitPacketTLS(cfg.GetString(proto.TLSCertFile), cfg.GetString(proto.TLSKeyFile),
+		cfg.GetString(proto.TLSCAFile), cfg.GetString(proto.TLSServerName)); err != nil {
+		log.LogFlush()
+		daemonize.SignalOutcome(fmt.Errorf("Fatal: init TLS fail:%v ", err))
+		os.Exit(1)
+	}
+
 	if profPort != "" {
 		go func() {
 			http.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
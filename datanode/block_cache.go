@@ -0,0 +1,78 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	MetricBlockCacheHits      = "blockCache_hits"
+	MetricBlockCacheMisses    = "blockCache_misses"
+	MetricBlockCacheHitRatio  = "blockCache_hit_ratio"
+	MetricBlockCacheUsedRatio = "blockCache_used_ratio"
+)
+
+type blockCacheMetrics struct {
+	hits       *exporter.Counter
+	misses     *exporter.Counter
+	hitRatio   *exporter.Gauge
+	usedRatio  *exporter.Gauge
+	lastHits   uint64
+	lastMisses uint64
+}
+
+// startBlockCache creates the block cache on the local SSD if the cache directory is configured.
+func (manager *SpaceManager) startBlockCache(cfg *config.Config) (err error) {
+	dir := cfg.GetString(ConfigKeyCacheDir)
+	size := cfg.GetInt64(ConfigKeyCacheSize)
+	if dir == "" || size <= 0 {
+		return
+	}
+	if manager.blockCache, err = storage.NewBlockCache(dir, uint64(size)*util.GB); err != nil {
+		return
+	}
+	manager.blockCacheMetrics = &blockCacheMetrics{
+		hits:      exporter.NewCounter(MetricBlockCacheHits),
+		misses:    exporter.NewCounter(MetricBlockCacheMisses),
+		hitRatio:  exporter.NewGauge(MetricBlockCacheHitRatio),
+		usedRatio: exporter.NewGauge(MetricBlockCacheUsedRatio),
+	}
+	log.LogInfof("action[startBlockCache] dir(%v) size(%vGB)", dir, size)
+	return
+}
+
+// updateBlockCacheMetrics exports the hits and the misses of the block cache since the last update.
+func (manager *SpaceManager) updateBlockCacheMetrics() {
+	if manager.blockCache == nil {
+		return
+	}
+	m := manager.blockCacheMetrics
+	hits, misses, blocks, slots := manager.blockCache.Stat()
+	deltaHits, deltaMisses := hits-m.lastHits, misses-m.lastMisses
+	m.lastHits, m.lastMisses = hits, misses
+	m.hits.Add(int64(deltaHits))
+	m.misses.Add(int64(deltaMisses))
+	if deltaHits+deltaMisses > 0 {
+		m.hitRatio.Set(float64(deltaHits) / float64(deltaHits+deltaMisses))
+	}
+	m.usedRatio.Set(float64(blocks) / float64(slots))
+	log.LogDebugf("action[updateBlockCacheMetrics] hits(%v) misses(%v) blocks(%v) slots(%v)",
+		deltaHits, deltaMisses, blocks, slots)
+}
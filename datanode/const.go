@@ -14,10 +14,13 @@
 
 package datanode
 
+import (
+	"time"
+)
+
 const (
 	IntervalToUpdateReplica       = 600 // interval to update the replica
 	IntervalToUpdatePartitionSize = 60  // interval to update the partition size
-	NumOfFilesToRecoverInParallel = 10  // number of files to be recovered simultaneously
 )
 
 // Network protocol
@@ -62,6 +65,18 @@ const (
 	ActionSyncTinyDeleteRecord       = "ActionSyncTinyDeleteRecord"
 	ActionStreamReadTinyExtentRepair = "ActionStreamReadTinyExtentRepair"
 	ActionBatchMarkDelete            = "ActionBatchMarkDelete"
+
+	ActionWriteEcShard  = "ActionWriteEcShard"
+	ActionReadEcShard   = "ActionReadEcShard"
+	ActionDeleteEcShard = "ActionDeleteEcShard"
+	ActionSyncEcExtents = "ActionSyncEcExtents"
+	ActionConvertToEc   = "ActionConvertToEc"
+
+	ActionScrubExtent = "ActionScrubExtent"
+
+	ActionCompactTinyExtent = "ActionCompactTinyExtent"
+
+	ActionShareDataPartition = "ActionShareDataPartition"
 )
 
 // Apply the raft log operation. Currently we only have the random write operation.
@@ -99,3 +114,65 @@ const (
 	MaxFullSyncTinyDeleteTime          = 3600 * 24
 	MinTinyExtentDeleteRecordSyncSize  = 4 * 1024 * 1024
 )
+
+// Erasure coding
+const (
+	EcShardDirPrefix  = "ecshard"
+	EcExtentsFileName = "EC_EXTENTS"
+	TempEcExtentsFile = ".ec_extents"
+	EcShardHeaderSize = 4  // crc of the shard
+	EcRepairInterval  = 10 // in minutes
+)
+
+// Quorum write
+const (
+	LaggingExtentsFileName = "LAGGING_EXTENTS"
+	TempLaggingExtentsFile = ".lagging_extents"
+)
+
+// Sharing with the cloned volumes
+const (
+	SharedDeletesFileName = "SHARED_DELETES"
+	TempSharedDeletesFile = ".shared_deletes"
+)
+
+// Scrubbing
+const (
+	ScrubCheckInterval    = 10        // in minutes
+	ScrubPeriod           = 24 * 3600 // each partition is scrubbed once in a period, in seconds
+	ScrubExtentTimeout    = 600       // timeout of scrubbing an extent on a replica, in seconds
+	DefaultScrubLimitRate = 16        // MB/s read by scrubbing on each disk
+)
+
+// Compression
+const (
+	CompressCheckInterval = 10   // in minutes
+	ExtentSealTime        = 3600 // normal extents not written for the time are sealed and compressed, in seconds
+)
+
+// Tiny extent compaction
+const (
+	TinyCompactCheckInterval = 10               // in minutes
+	TinyCompactMinSize       = 64 * 1024 * 1024 // tiny extents smaller than the size are not compacted
+	TinyCompactDeleteRatio   = 0.5              // tiny extents with the ratio of the deleted data are compacted
+	MaxCompactingTinyExtents = 8                // max tiny extents of a partition compacting at the same time
+	TinyCompactTimeout       = 60               // in seconds
+)
+
+// Repair scheduling
+const (
+	RepairWorkerCount      = 32                     // number of the extents repaired simultaneously on the node
+	RepairYieldLatency     = 50 * time.Millisecond  // repairs yield to the client requests slower than the latency
+	RepairYieldInterval    = 100 * time.Millisecond // time to wait each time a repair yields
+	RepairMaxYieldTime     = 10 * time.Second       // max time a repair yields before reading the next packet
+	RepairThrottleInterval = 10 * time.Millisecond  // time to wait each time a repair exceeds the bandwidth limits
+	LatencyStaleTime       = time.Second            // latency not updated for the time is ignored
+)
+
+// Migration between disks
+const (
+	MigratingPartitionPrefix = "migrating_"
+	DiskBalanceInterval      = 10  // in minutes
+	DiskBalanceThreshold     = 0.1 // the partitions are migrated if the usage ratios of the disks differ more than it
+	MigrateCopyBufferSize    = 128 * 1024
+)
@@ -29,7 +29,6 @@ import (
 	"github.com/chubaofs/chubaofs/storage"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
-	"hash/crc32"
 )
 
 // DataPartitionRepairTask defines the reapir task for the data partition.
@@ -41,6 +40,7 @@ type DataPartitionRepairTask struct {
 	ExtentsToBeRepaired            []*storage.ExtentInfo
 	LeaderTinyDeleteRecordFileSize int64
 	LeaderAddr                     string
+	HealthyReplicas                int // number of the replicas with nothing to repair
 }
 
 func NewDataPartitionRepairTask(extentFiles []*storage.ExtentInfo, tinyDeleteRecordFileSize int64, source, leaderAddr string) (task *DataPartitionRepairTask) {
@@ -87,9 +87,13 @@ func (dp *DataPartition) repair(extentType uint8) {
 			return
 		}
 	}
+	var laggingExtents []uint64 // normal extents acknowledged with quorum before all the replicas persist them
+	if extentType == proto.NormalExtentType {
+		laggingExtents = dp.laggingExtentIDs()
+	}
 
 	repairTasks := make([]*DataPartitionRepairTask, dp.getReplicaLen())
-	err := dp.buildDataPartitionRepairTask(repairTasks, extentType, tinyExtents)
+	err := dp.buildDataPartitionRepairTask(repairTasks, extentType, tinyExtents, laggingExtents)
 
 	if err != nil {
 		log.LogErrorf(errors.Stack(err))
@@ -115,6 +119,7 @@ func (dp *DataPartition) repair(extentType uint8) {
 	// ask the leader to do the repair
 	dp.DoRepair(repairTasks)
 	end := time.Now().UnixNano()
+	dp.clearRepairedLaggingExtents(repairTasks, laggingExtents)
 
 	// every time we need to figureAnnotatef out which extents need to be repaired and which ones do not.
 	dp.sendAllTinyExtentsToC(extentType, availableTinyExtents, brokenTinyExtents)
@@ -131,9 +136,9 @@ func (dp *DataPartition) repair(extentType uint8) {
 		dp.extentStore.BrokenTinyExtentCnt(), (end-start)/int64(time.Millisecond), MasterClient.Nodes())
 }
 
-func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartitionRepairTask, extentType uint8, tinyExtents []uint64) (err error) {
+func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartitionRepairTask, extentType uint8, tinyExtents, laggingExtents []uint64) (err error) {
 	// get the local extent info
-	extents, leaderTinyDeleteRecordFileSize, err := dp.getLocalExtentInfo(extentType, tinyExtents)
+	extents, leaderTinyDeleteRecordFileSize, err := dp.getLocalExtentInfo(extentType, tinyExtents, laggingExtents)
 	if err != nil {
 		return err
 	}
@@ -144,7 +149,7 @@ func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartiti
 
 	// new repair tasks for the followers
 	for index := 1; index < dp.getReplicaLen(); index++ {
-		extents, err := dp.getRemoteExtentInfo(extentType, tinyExtents, dp.getReplicaAddr(index))
+		extents, err := dp.getRemoteExtentInfo(extentType, tinyExtents, laggingExtents, dp.getReplicaAddr(index))
 		if err != nil {
 			log.LogErrorf("buildDataPartitionRepairTask PartitionID(%v) on (%v) err(%v)", dp.partitionID, dp.getReplicaAddr(index), err)
 			continue
@@ -156,12 +161,12 @@ func (dp *DataPartition) buildDataPartitionRepairTask(repairTasks []*DataPartiti
 	return
 }
 
-func (dp *DataPartition) getLocalExtentInfo(extentType uint8, tinyExtents []uint64) (extents []*storage.ExtentInfo, leaderTinyDeleteRecordFileSize int64, err error) {
+func (dp *DataPartition) getLocalExtentInfo(extentType uint8, tinyExtents, laggingExtents []uint64) (extents []*storage.ExtentInfo, leaderTinyDeleteRecordFileSize int64, err error) {
 	localExtents := make([]*storage.ExtentInfo, 0)
 	extents = make([]*storage.ExtentInfo, 0)
 
 	if extentType == proto.NormalExtentType {
-		localExtents, leaderTinyDeleteRecordFileSize, err = dp.extentStore.GetAllWatermarks(storage.NormalExtentFilter())
+		localExtents, leaderTinyDeleteRecordFileSize, err = dp.extentStore.GetAllWatermarks(storage.NormalExtentWithLaggingFilter(laggingExtents))
 	} else {
 		localExtents, leaderTinyDeleteRecordFileSize, err = dp.extentStore.GetAllWatermarks(storage.TinyExtentFilter(tinyExtents))
 	}
@@ -182,19 +187,22 @@ func (dp *DataPartition) getLocalExtentInfo(extentType uint8, tinyExtents []uint
 	return
 }
 
-func (dp *DataPartition) getRemoteExtentInfo(extentType uint8, tinyExtents []uint64,
+func (dp *DataPartition) getRemoteExtentInfo(extentType uint8, tinyExtents, laggingExtents []uint64,
 	target string) (extentFiles []*storage.ExtentInfo, err error) {
 	p := repl.NewPacketToGetAllWatermarks(dp.partitionID, extentType)
 	extentFiles = make([]*storage.ExtentInfo, 0)
 	if extentType == proto.TinyExtentType {
 		p.Data, err = json.Marshal(tinyExtents)
-		if err != nil {
-			err = errors.Trace(err, "getRemoteExtentInfo DataPartition(%v) GetAllWatermarks", dp.partitionID)
-			return
-		}
-		p.Size = uint32(len(p.Data))
+	} else if len(laggingExtents) > 0 {
+		// the follower reports the lagging extents along with the idle ones
+		p.Data, err = json.Marshal(laggingExtents)
 	}
-	var conn *net.TCPConn
+	if err != nil {
+		err = errors.Trace(err, "getRemoteExtentInfo DataPartition(%v) GetAllWatermarks", dp.partitionID)
+		return
+	}
+	p.Size = uint32(len(p.Data))
+	var conn net.Conn
 	conn, err = gConnPool.GetConnect(target) // get remote connection
 	if err != nil {
 		err = errors.Trace(err, "getRemoteExtentInfo DataPartition(%v) get host(%v) connect", dp.partitionID, target)
@@ -235,19 +243,7 @@ func (dp *DataPartition) DoRepair(repairTasks []*DataPartitionRepairTask) {
 		}
 		store.Create(extentInfo.FileID)
 	}
-	for _, extentInfo := range repairTasks[0].ExtentsToBeRepaired {
-		err := dp.streamRepairExtent(extentInfo)
-		if err != nil {
-			err = errors.Trace(err, "doStreamExtentFixRepair %v", dp.applyRepairKey(int(extentInfo.FileID)))
-			localExtentInfo, opErr := dp.ExtentStore().Watermark(uint64(extentInfo.FileID))
-			if opErr != nil {
-				err = errors.Trace(err, opErr.Error())
-			}
-			err = errors.Trace(err, "partition(%v) remote(%v) local(%v)",
-				dp.partitionID, extentInfo, localExtentInfo)
-			log.LogWarnf("action[doStreamExtentFixRepair] err(%v).", err)
-		}
-	}
+	dp.repairExtents(repairTasks[0])
 }
 
 func (dp *DataPartition) moveToBrokenTinyExtentC(extentType uint8, extents []uint64) {
@@ -321,6 +317,19 @@ func (dp *DataPartition) prepareRepairTasks(repairTasks []*DataPartitionRepairTa
 	}
 	dp.buildExtentCreationTasks(repairTasks, extentInfoMap)
 	availableTinyExtents, brokenTinyExtents = dp.buildExtentRepairTasks(repairTasks, extentInfoMap)
+
+	// the repairs of the partitions with fewer healthy replicas are scheduled first
+	var healthyReplicas int
+	for _, repairTask := range repairTasks {
+		if repairTask != nil && len(repairTask.ExtentsToBeCreated) == 0 && len(repairTask.ExtentsToBeRepaired) == 0 {
+			healthyReplicas++
+		}
+	}
+	for _, repairTask := range repairTasks {
+		if repairTask != nil {
+			repairTask.HealthyReplicas = healthyReplicas
+		}
+	}
 	return
 }
 
@@ -397,7 +406,7 @@ func (dp *DataPartition) buildExtentRepairTasks(repairTasks []*DataPartitionRepa
 
 func (dp *DataPartition) notifyFollower(wg *sync.WaitGroup, index int, members []*DataPartitionRepairTask) (err error) {
 	p := repl.NewPacketToNotifyExtentRepair(dp.partitionID) // notify all the followers to repair
-	var conn *net.TCPConn
+	var conn net.Conn
 	target := dp.getReplicaAddr(index)
 	p.Data, _ = json.Marshal(members[index])
 	p.Size = uint32(len(p.Data))
@@ -433,11 +442,9 @@ func (dp *DataPartition) NotifyExtentRepair(members []*DataPartitionRepairTask)
 	return
 }
 
-// DoStreamExtentFixRepair executes the repair on the followers.
-func (dp *DataPartition) doStreamExtentFixRepair(wg *sync.WaitGroup, remoteExtentInfo *storage.ExtentInfo) {
-	defer wg.Done()
-
-	err := dp.streamRepairExtent(remoteExtentInfo)
+// DoStreamExtentFixRepair executes the repair of an extent scheduled by the repair scheduler.
+func (dp *DataPartition) doStreamExtentFixRepair(remoteExtentInfo *storage.ExtentInfo) (err error) {
+	err = dp.streamRepairExtent(remoteExtentInfo)
 
 	if err != nil {
 		err = errors.Trace(err, "doStreamExtentFixRepair %v", dp.applyRepairKey(int(remoteExtentInfo.FileID)))
@@ -449,6 +456,7 @@ func (dp *DataPartition) doStreamExtentFixRepair(wg *sync.WaitGroup, remoteExten
 			dp.partitionID, remoteExtentInfo, localExtentInfo)
 		log.LogWarnf("action[doStreamExtentFixRepair] err(%v).", err)
 	}
+	return
 }
 
 func (dp *DataPartition) applyRepairKey(extentID int) (m string) {
@@ -486,7 +494,7 @@ func (dp *DataPartition) streamRepairExtent(remoteExtentInfo *storage.ExtentInfo
 		}
 		request = repl.NewTinyExtentRepairReadPacket(dp.partitionID, remoteExtentInfo.FileID, int(localExtentInfo.Size), int(sizeDiff))
 	}
-	var conn *net.TCPConn
+	var conn net.Conn
 	conn, err = gConnPool.GetConnect(remoteExtentInfo.Source)
 	if err != nil {
 		return errors.Trace(err, "streamRepairExtent get conn from host(%v) error", remoteExtentInfo.Source)
@@ -533,11 +541,12 @@ func (dp *DataPartition) streamRepairExtent(remoteExtentInfo *storage.ExtentInfo
 			return
 		}
 
+		dp.disk.space.repairScheduler.throttle(dp.disk, int(reply.Size))
 		log.LogInfof(fmt.Sprintf("action[streamRepairExtent] fix(%v_%v) start fix from (%v)"+
 			" remoteSize(%v)localSize(%v) reply(%v).", dp.partitionID, localExtentInfo.FileID, remoteExtentInfo.String(),
 			remoteExtentInfo.Size, currFixOffset, reply.GetUniqueLogId()))
-		actualCrc := crc32.ChecksumIEEE(reply.Data[:reply.Size])
-		if reply.CRC != crc32.ChecksumIEEE(reply.Data[:reply.Size]) {
+		actualCrc := dp.checksum(reply.Data[:reply.Size])
+		if reply.CRC != actualCrc {
 			err = fmt.Errorf("streamRepairExtent crc mismatch expectCrc(%v) actualCrc(%v) extent(%v_%v) start fix from (%v)"+
 				" remoteSize(%v) localSize(%v) request(%v) reply(%v) ", reply.CRC, actualCrc, dp.partitionID, remoteExtentInfo.String(),
 				remoteExtentInfo.Source, remoteExtentInfo.Size, currFixOffset, request.GetUniqueLogId(), reply.GetUniqueLogId())
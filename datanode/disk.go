@@ -27,8 +27,10 @@ import (
 	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
+	"golang.org/x/time/rate"
 	"os"
 )
 
@@ -60,6 +62,11 @@ type Disk struct {
 	partitionMap                              map[uint64]*DataPartition
 	syncTinyDeleteRecordFromLeaderOnEveryDisk chan bool
 	space                                     *SpaceManager
+	scrubLimiter                              *rate.Limiter
+	qosLimiter                                *ioLimiter
+	repairLimiter                             *ioLimiter     // bandwidth of the repairs on the disk
+	repairedBytes                             uint64         // size of the data repaired on the disk
+	latency                                   latencyTracker // latency of the client requests on the disk
 }
 
 const (
@@ -77,6 +84,9 @@ func NewDisk(path string, reservedSpace uint64, maxErrCnt int, space *SpaceManag
 	d.space = space
 	d.partitionMap = make(map[uint64]*DataPartition)
 	d.syncTinyDeleteRecordFromLeaderOnEveryDisk = make(chan bool, SyncTinyDeleteRecordFromLeaderOnEveryDisk)
+	d.scrubLimiter = rate.NewLimiter(rate.Limit(DefaultScrubLimitRate*util.MB), util.BlockSize)
+	d.qosLimiter = newIOLimiter()
+	d.repairLimiter = newIOLimiter()
 	d.computeUsage()
 	d.updateSpaceInfo()
 	d.startScheduleToUpdateSpaceInfo()
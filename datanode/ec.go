@@ -0,0 +1,687 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/erasure"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// EcExtentInfo defines an extent converted into erasure coded shards,
+// the i-th shard of which is stored on Hosts[i].
+type EcExtentInfo struct {
+	ExtentID  uint64
+	Size      int64
+	Crc       uint32
+	DataNum   uint8
+	ParityNum uint8
+	Hosts     []string
+	Restored  bool `json:",omitempty"` // the extent is written back to the replicas before it is modified
+}
+
+func (ei *EcExtentInfo) shardSize() int64 {
+	return (ei.Size + int64(ei.DataNum) - 1) / int64(ei.DataNum)
+}
+
+func (ei *EcExtentInfo) String() string {
+	return fmt.Sprintf("%v_%v_%v+%v", ei.ExtentID, ei.Size, ei.DataNum, ei.ParityNum)
+}
+
+// The shards of the data partition are stored in the directory "ecshard_<partitionID>" on one of the disks,
+// and each shard file named "<extentID>_<index>" starts with the crc of the shard.
+func (manager *SpaceManager) ecShardPath(partitionID, extentID uint64, index int, create bool) (name string, err error) {
+	dirName := fmt.Sprintf(EcShardDirPrefix+"_%v", partitionID)
+	fileName := fmt.Sprintf("%v_%v", extentID, index)
+	for _, d := range manager.GetDisks() {
+		dir := path.Join(d.Path, dirName)
+		if _, err = os.Stat(dir); err == nil {
+			return path.Join(dir, fileName), nil
+		}
+	}
+	if !create {
+		err = os.ErrNotExist
+		return
+	}
+
+	manager.createPartitionMutex.Lock()
+	defer manager.createPartitionMutex.Unlock()
+	d := manager.minPartitionCnt(nil)
+	if d == nil {
+		err = ErrNoSpaceToCreatePartition
+		return
+	}
+	dir := path.Join(d.Path, dirName)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	return path.Join(dir, fileName), nil
+}
+
+func (manager *SpaceManager) writeEcShard(partitionID, extentID uint64, index int, data []byte) (err error) {
+	var (
+		name string
+		fp   *os.File
+	)
+	if name, err = manager.ecShardPath(partitionID, extentID, index, true); err != nil {
+		return
+	}
+	tmpName := name + ".tmp"
+	if fp, err = os.OpenFile(tmpName, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644); err != nil {
+		return
+	}
+	header := make([]byte, EcShardHeaderSize)
+	binary.BigEndian.PutUint32(header, crc32.ChecksumIEEE(data))
+	if _, err = fp.Write(header); err == nil {
+		if _, err = fp.Write(data); err == nil {
+			err = fp.Sync()
+		}
+	}
+	fp.Close()
+	if err != nil {
+		os.Remove(tmpName)
+		return
+	}
+	return os.Rename(tmpName, name)
+}
+
+// readEcShard reads the given range of the shard, and the whole shard is read and verified if size is 0.
+func (manager *SpaceManager) readEcShard(partitionID, extentID uint64, index int, offset, size int64) (data []byte, err error) {
+	var (
+		name string
+		fp   *os.File
+	)
+	if name, err = manager.ecShardPath(partitionID, extentID, index, false); err != nil {
+		return
+	}
+	if fp, err = os.Open(name); err != nil {
+		return
+	}
+	defer fp.Close()
+
+	if size == 0 {
+		var buf []byte
+		if buf, err = ioutil.ReadAll(fp); err != nil {
+			return
+		}
+		if len(buf) < EcShardHeaderSize {
+			err = storage.ParameterMismatchError
+			return
+		}
+		data = buf[EcShardHeaderSize:]
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(buf[:EcShardHeaderSize]) {
+			err = storage.CrcMismatchError
+		}
+		return
+	}
+	data = make([]byte, size)
+	if _, err = fp.ReadAt(data, EcShardHeaderSize+offset); err == io.EOF {
+		err = storage.ParameterMismatchError
+	}
+	return
+}
+
+func (manager *SpaceManager) deleteEcShard(partitionID, extentID uint64, index int) (err error) {
+	var name string
+	if name, err = manager.ecShardPath(partitionID, extentID, index, false); err != nil {
+		return nil
+	}
+	if err = os.Remove(name); os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+func (dp *DataPartition) loadEcExtents() (err error) {
+	var data []byte
+	dp.ecExtents = make(map[uint64]*EcExtentInfo)
+	if data, err = ioutil.ReadFile(path.Join(dp.Path(), EcExtentsFileName)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	infos := make([]*EcExtentInfo, 0)
+	if err = json.Unmarshal(data, &infos); err != nil {
+		return
+	}
+	for _, info := range infos {
+		dp.ecExtents[info.ExtentID] = info
+	}
+	return
+}
+
+// persistEcExtents must be called with the ecExtentsLock held.
+func (dp *DataPartition) persistEcExtents() (err error) {
+	var data []byte
+	infos := make([]*EcExtentInfo, 0, len(dp.ecExtents))
+	for _, info := range dp.ecExtents {
+		infos = append(infos, info)
+	}
+	if data, err = json.Marshal(infos); err != nil {
+		return
+	}
+	tmpName := path.Join(dp.Path(), TempEcExtentsFile)
+	if err = ioutil.WriteFile(tmpName, data, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmpName, path.Join(dp.Path(), EcExtentsFileName))
+}
+
+func (dp *DataPartition) getEcExtent(extentID uint64) (info *EcExtentInfo) {
+	dp.ecExtentsLock.RLock()
+	defer dp.ecExtentsLock.RUnlock()
+	return dp.ecExtents[extentID]
+}
+
+func (dp *DataPartition) ecExtentList() (infos []*EcExtentInfo) {
+	dp.ecExtentsLock.RLock()
+	defer dp.ecExtentsLock.RUnlock()
+	infos = make([]*EcExtentInfo, 0, len(dp.ecExtents))
+	for _, info := range dp.ecExtents {
+		infos = append(infos, info)
+	}
+	return
+}
+
+// applyEcExtents records the erasure coded extents, and removes the local replicas of them. The local replica
+// which mismatches the shards is kept, and the extent is not recorded then. The restored extents are written
+// back to the local extent store, and forgotten.
+func (dp *DataPartition) applyEcExtents(infos []*EcExtentInfo) (err error) {
+	applied := make([]*EcExtentInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Restored {
+			if err = dp.restoreEcExtent(info.ExtentID); err != nil {
+				return
+			}
+			continue
+		}
+		if !dp.hasLiveExtent(info.ExtentID) {
+			applied = append(applied, info)
+			continue
+		}
+		var crc uint32
+		if crc, err = dp.localExtentCrc(info.ExtentID, info.Size); err != nil || crc != info.Crc {
+			log.LogWarnf("action[applyEcExtents] partition(%v) extent(%v) crc(%v) mismatch(%v) err(%v), keep the local replica",
+				dp.partitionID, info, crc, info.Crc, err)
+			err = errors.NewErrorf("partition(%v) extent(%v) mismatches the shards", dp.partitionID, info)
+			continue
+		}
+		applied = append(applied, info)
+	}
+	var persistErr error
+	dp.ecExtentsLock.Lock()
+	for _, info := range applied {
+		dp.ecExtents[info.ExtentID] = info
+	}
+	persistErr = dp.persistEcExtents()
+	dp.ecExtentsLock.Unlock()
+	if persistErr != nil {
+		return persistErr
+	}
+
+	for _, info := range applied {
+		if !dp.hasLiveExtent(info.ExtentID) {
+			continue
+		}
+		if e := dp.extentStore.MarkDelete(info.ExtentID, 0, 0); e != nil {
+			log.LogErrorf("action[applyEcExtents] partition(%v) extent(%v) mark delete err(%v)", dp.partitionID, info, e)
+		}
+	}
+	return
+}
+
+func (dp *DataPartition) hasLiveExtent(extentID uint64) bool {
+	ei, err := dp.extentStore.Watermark(extentID)
+	return err == nil && !ei.IsDeleted
+}
+
+// localExtentCrc computes the crc of the local replica of the extent, which must be of the given size.
+func (dp *DataPartition) localExtentCrc(extentID uint64, size int64) (crc uint32, err error) {
+	var ei *storage.ExtentInfo
+	if ei, err = dp.extentStore.Watermark(extentID); err != nil {
+		return
+	}
+	if int64(ei.Size) != size {
+		err = errors.NewErrorf("extent(%v) size(%v) mismatch(%v)", extentID, ei.Size, size)
+		return
+	}
+	data := make([]byte, size)
+	if err = dp.readLocalExtent(extentID, data); err != nil {
+		return
+	}
+	return crc32.ChecksumIEEE(data), nil
+}
+
+func (dp *DataPartition) readLocalExtent(extentID uint64, data []byte) (err error) {
+	for offset := 0; offset < len(data); offset += util.BlockSize {
+		size := util.Min(len(data)-offset, util.BlockSize)
+		if _, err = dp.extentStore.Read(extentID, int64(offset), int64(size), data[offset:offset+size], false); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// restoreEcExtent writes the erasure coded extent back to the local extent store, and forgets the erasure coding
+// of it, so that it can be modified like the other extents.
+func (dp *DataPartition) restoreEcExtent(extentID uint64) (err error) {
+	dp.ecRestoreLock.Lock()
+	defer dp.ecRestoreLock.Unlock()
+	info := dp.getEcExtent(extentID)
+	if info == nil {
+		return
+	}
+	if !dp.hasLiveExtent(extentID) {
+		var data []byte
+		if data, err = dp.readEcExtent(info, 0, info.Size); err != nil {
+			return
+		}
+		if crc32.ChecksumIEEE(data) != info.Crc {
+			return storage.CrcMismatchError
+		}
+		if err = dp.extentStore.Recreate(extentID); err != nil {
+			return
+		}
+		for offset := 0; offset < len(data); offset += util.BlockSize {
+			size := util.Min(len(data)-offset, util.BlockSize)
+			block := data[offset : offset+size]
+			if err = dp.extentStore.Write(extentID, int64(offset), int64(size), block, dp.checksum(block), storage.AppendWriteType, true); err != nil {
+				return
+			}
+		}
+		log.LogInfof("action[restoreEcExtent] partition(%v) extent(%v) restored", dp.partitionID, info)
+	}
+	dp.deleteEcExtent(extentID, false)
+	return
+}
+
+// restoreEcExtentOnReplicas restores the erasure coded extent on all the replicas before it is modified,
+// and removes the shards of it if all the replicas have restored it. The leader is the first one to restore it.
+func (dp *DataPartition) restoreEcExtentOnReplicas(extentID uint64) (err error) {
+	info := dp.getEcExtent(extentID)
+	if info == nil {
+		return
+	}
+	if err = dp.restoreEcExtent(extentID); err != nil {
+		return
+	}
+	restored := *info
+	restored.Restored = true
+	var data []byte
+	if data, err = json.Marshal([]*EcExtentInfo{&restored}); err != nil {
+		return
+	}
+	allRestored := true
+	replicas := dp.Replicas()
+	for i := 1; i < len(replicas); i++ {
+		replica := replicas[i]
+		if _, e := dp.sendEcPacket(replica, repl.NewEcPacket(proto.OpSyncEcExtents, dp.partitionID, 0, data)); e != nil {
+			// the replica restores it by itself when applying the write, while the shards are kept
+			log.LogWarnf("action[restoreEcExtentOnReplicas] partition(%v) extent(%v) replica(%v) err(%v)", dp.partitionID, info, replica, e)
+			allRestored = false
+		}
+	}
+	if allRestored {
+		dp.removeEcShards(info)
+	}
+	return
+}
+
+// deleteEcExtent removes the erasure coded extent, and the shards of it if removeShards is set.
+func (dp *DataPartition) deleteEcExtent(extentID uint64, removeShards bool) {
+	dp.ecExtentsLock.Lock()
+	info, ok := dp.ecExtents[extentID]
+	if ok {
+		delete(dp.ecExtents, extentID)
+		if err := dp.persistEcExtents(); err != nil {
+			log.LogErrorf("action[deleteEcExtent] partition(%v) extent(%v) persist err(%v)", dp.partitionID, extentID, err)
+		}
+	}
+	dp.ecExtentsLock.Unlock()
+	if !ok || !removeShards {
+		return
+	}
+	dp.removeEcShards(info)
+}
+
+func (dp *DataPartition) removeEcShards(info *EcExtentInfo) {
+	go func() {
+		for i, host := range info.Hosts {
+			if err := dp.sendEcShardRequest(host, proto.OpDeleteEcShard, info.ExtentID, i, 0, 0); err != nil {
+				log.LogWarnf("action[removeEcShards] partition(%v) extent(%v) shard(%v) host(%v) err(%v)",
+					dp.partitionID, info, i, host, err)
+			}
+		}
+	}()
+}
+
+func (dp *DataPartition) sendEcPacket(target string, p *repl.Packet) (reply *repl.Packet, err error) {
+	return dp.sendPacket(target, p, proto.ReadDeadlineTime)
+}
+
+// sendPacket sends the packet to the target host and waits for the reply within the timeout in seconds.
+func (dp *DataPartition) sendPacket(target string, p *repl.Packet, timeout int) (reply *repl.Packet, err error) {
+	var conn net.Conn
+	if conn, err = gConnPool.GetConnect(target); err != nil {
+		err = errors.Trace(err, "partition(%v) get host(%v) connect", dp.partitionID, target)
+		return
+	}
+	if err = p.WriteToConn(conn); err != nil {
+		gConnPool.PutConnect(conn, true)
+		err = errors.Trace(err, "partition(%v) write to host(%v)", dp.partitionID, target)
+		return
+	}
+	reply = new(repl.Packet)
+	if err = reply.ReadFromConn(conn, timeout); err != nil {
+		gConnPool.PutConnect(conn, true)
+		err = errors.Trace(err, "partition(%v) read from host(%v)", dp.partitionID, target)
+		return
+	}
+	gConnPool.PutConnect(conn, false)
+	if reply.ResultCode != proto.OpOk {
+		err = errors.NewErrorf("partition(%v) host(%v) op(%v) err(%v)",
+			dp.partitionID, target, p.GetOpMsg(), string(reply.Data[:reply.Size]))
+	}
+	return
+}
+
+func (dp *DataPartition) writeEcShard(target string, extentID uint64, index int, data []byte) (err error) {
+	p := repl.NewEcPacket(proto.OpWriteEcShard, dp.partitionID, extentID, data)
+	p.KernelOffset = uint64(index)
+	_, err = dp.sendEcPacket(target, p)
+	return
+}
+
+func (dp *DataPartition) sendEcShardRequest(target string, opcode uint8, extentID uint64, index int,
+	offset, size int64) (err error) {
+	_, err = dp.doEcShardRequest(target, opcode, extentID, index, offset, size)
+	return
+}
+
+func (dp *DataPartition) doEcShardRequest(target string, opcode uint8, extentID uint64, index int,
+	offset, size int64) (reply *repl.Packet, err error) {
+	return dp.sendEcShardPacket(target, opcode, extentID, &proto.EcShardRequest{Index: index, Offset: offset, Size: size})
+}
+
+func (dp *DataPartition) sendEcShardPacket(target string, opcode uint8, extentID uint64, req *proto.EcShardRequest) (reply *repl.Packet, err error) {
+	var data []byte
+	if data, err = json.Marshal(req); err != nil {
+		return
+	}
+	return dp.sendEcPacket(target, repl.NewEcPacket(opcode, dp.partitionID, extentID, data))
+}
+
+// verifyEcShard verifies the whole shard against its crc on the host, without reading it back.
+func (dp *DataPartition) verifyEcShard(info *EcExtentInfo, index int) (err error) {
+	_, err = dp.sendEcShardPacket(info.Hosts[index], proto.OpReadEcShard, info.ExtentID, &proto.EcShardRequest{Index: index, Verify: true})
+	return
+}
+
+func (dp *DataPartition) readEcShard(info *EcExtentInfo, index int, offset, size int64) (data []byte, err error) {
+	var reply *repl.Packet
+	if reply, err = dp.doEcShardRequest(info.Hosts[index], proto.OpReadEcShard, info.ExtentID, index, offset, size); err != nil {
+		return
+	}
+	data = reply.Data[:reply.Size]
+	if size != 0 && int64(len(data)) != size {
+		err = errors.NewErrorf("partition(%v) extent(%v) shard(%v) read size(%v) mismatch(%v)",
+			dp.partitionID, info, index, len(data), size)
+	}
+	return
+}
+
+// reconstructEcShards reads any k shards of the extent, and rebuilds all the shards from them.
+func (dp *DataPartition) reconstructEcShards(info *EcExtentInfo) (shards [][]byte, err error) {
+	var encoder *erasure.Encoder
+	if encoder, err = erasure.NewEncoder(int(info.DataNum), int(info.ParityNum)); err != nil {
+		return
+	}
+	shards = make([][]byte, len(info.Hosts))
+	found := 0
+	for i := range info.Hosts {
+		if found == int(info.DataNum) {
+			break
+		}
+		data, err := dp.readEcShard(info, i, 0, 0)
+		if err != nil {
+			log.LogWarnf("action[reconstructEcShards] partition(%v) extent(%v) shard(%v) err(%v)", dp.partitionID, info, i, err)
+			continue
+		}
+		shards[i] = data
+		found++
+	}
+	if err = encoder.Reconstruct(shards); err != nil {
+		err = errors.Trace(err, "partition(%v) extent(%v) reconstruct", dp.partitionID, info)
+	}
+	return
+}
+
+// readEcExtent reads the range of the erasure coded extent from the data shards,
+// and reconstructs the extent from any k shards if some of the data shards are unavailable.
+func (dp *DataPartition) readEcExtent(info *EcExtentInfo, offset, size int64) (data []byte, err error) {
+	if offset < 0 || size <= 0 || offset+size > info.Size {
+		err = storage.ParameterMismatchError
+		return
+	}
+	shardSize := info.shardSize()
+	data = make([]byte, 0, size)
+	for pos, end := offset, offset+size; pos < end; {
+		index := pos / shardSize
+		shardOffset := pos - index*shardSize
+		readSize := shardSize - shardOffset
+		if readSize > end-pos {
+			readSize = end - pos
+		}
+		var buf []byte
+		if buf, err = dp.readEcShard(info, int(index), shardOffset, readSize); err != nil {
+			log.LogWarnf("action[readEcExtent] partition(%v) extent(%v) shard(%v) err(%v), try to reconstruct",
+				dp.partitionID, info, index, err)
+			return dp.readReconstructedEcExtent(info, offset, size)
+		}
+		data = append(data, buf...)
+		pos += readSize
+	}
+	return
+}
+
+func (dp *DataPartition) readReconstructedEcExtent(info *EcExtentInfo, offset, size int64) (data []byte, err error) {
+	var (
+		shards  [][]byte
+		encoder *erasure.Encoder
+	)
+	if shards, err = dp.reconstructEcShards(info); err != nil {
+		return
+	}
+	if encoder, err = erasure.NewEncoder(int(info.DataNum), int(info.ParityNum)); err != nil {
+		return
+	}
+	if data, err = encoder.Join(shards, int(info.Size)); err != nil {
+		return
+	}
+	if crc32.ChecksumIEEE(data) != info.Crc {
+		err = storage.CrcMismatchError
+		return
+	}
+	return data[offset : offset+size], nil
+}
+
+// convertToEc converts the cold normal extents into erasure coded shards, which is only done by the leader.
+func (dp *DataPartition) convertToEc(req *proto.ConvertDataPartitionToEcRequest) {
+	if !atomic.CompareAndSwapInt32(&dp.ecTaskRunning, 0, 1) {
+		log.LogWarnf("action[convertToEc] partition(%v) ec task is running", dp.partitionID)
+		return
+	}
+	defer atomic.StoreInt32(&dp.ecTaskRunning, 0)
+
+	dp.ecExtentsLock.Lock()
+	dp.ecHosts = req.EcHosts
+	dp.ecExtentsLock.Unlock()
+
+	extents, _, err := dp.extentStore.GetAllWatermarks(storage.NormalExtentFilter())
+	if err != nil {
+		log.LogErrorf("action[convertToEc] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+	var converted int
+	now := time.Now().Unix()
+	for _, ei := range extents {
+		if ei.Size == 0 || now-ei.ModifyTime < req.ColdTime || dp.getEcExtent(ei.FileID) != nil {
+			continue
+		}
+		if !dp.isLeader || dp.Status() == proto.Unavailable {
+			return
+		}
+		var info *EcExtentInfo
+		if info, err = dp.encodeExtent(ei, req); err != nil {
+			log.LogErrorf("action[convertToEc] partition(%v) extent(%v) encode err(%v)", dp.partitionID, ei, err)
+			continue
+		}
+		if err = dp.syncEcExtents([]*EcExtentInfo{info}); err != nil {
+			// the shards are kept, since the replicas which have applied it rely on them
+			log.LogErrorf("action[convertToEc] partition(%v) extent(%v) sync err(%v)", dp.partitionID, info, err)
+			continue
+		}
+		converted++
+	}
+	log.LogInfof("action[convertToEc] partition(%v) converted(%v) extents", dp.partitionID, converted)
+}
+
+func (dp *DataPartition) encodeExtent(ei *storage.ExtentInfo, req *proto.ConvertDataPartitionToEcRequest) (info *EcExtentInfo, err error) {
+	var encoder *erasure.Encoder
+	if encoder, err = erasure.NewEncoder(int(req.DataNum), int(req.ParityNum)); err != nil {
+		return
+	}
+	if len(req.EcHosts) != int(req.DataNum)+int(req.ParityNum) {
+		err = erasure.ErrInvalidShardNum
+		return
+	}
+
+	data := make([]byte, ei.Size)
+	if err = dp.readLocalExtent(ei.FileID, data); err != nil {
+		return
+	}
+	// the extent must be sealed while encoding, the overwrites change the modify time only
+	if current, err := dp.extentStore.Watermark(ei.FileID); err != nil || current.Size != ei.Size || current.ModifyTime != ei.ModifyTime {
+		return nil, errors.NewErrorf("extent(%v) is modified while encoding", ei.FileID)
+	}
+
+	shards := encoder.Split(data)
+	if err = encoder.Encode(shards); err != nil {
+		return
+	}
+	info = &EcExtentInfo{
+		ExtentID:  ei.FileID,
+		Size:      int64(ei.Size),
+		Crc:       crc32.ChecksumIEEE(data),
+		DataNum:   req.DataNum,
+		ParityNum: req.ParityNum,
+		Hosts:     req.EcHosts,
+	}
+	for i, host := range info.Hosts {
+		if err = dp.writeEcShard(host, info.ExtentID, i, shards[i]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// syncEcExtents sends the erasure coded extents to all the replicas, the leader is the last one to apply them,
+// so that the extents are always readable from the leader.
+func (dp *DataPartition) syncEcExtents(infos []*EcExtentInfo) (err error) {
+	var data []byte
+	if data, err = json.Marshal(infos); err != nil {
+		return
+	}
+	replicas := dp.Replicas()
+	for i := len(replicas) - 1; i >= 0; i-- {
+		p := repl.NewEcPacket(proto.OpSyncEcExtents, dp.partitionID, 0, data)
+		if _, err = dp.sendEcPacket(replicas[i], p); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// repairEcExtents regenerates the lost or corrupted shards of the erasure coded extents, and moves the shards
+// on the dead hosts replaced by the master to the new hosts, which is only done by the leader.
+func (dp *DataPartition) repairEcExtents() {
+	if !atomic.CompareAndSwapInt32(&dp.ecTaskRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&dp.ecTaskRunning, 0)
+
+	dp.ecExtentsLock.RLock()
+	ecHosts := dp.ecHosts
+	dp.ecExtentsLock.RUnlock()
+	for _, info := range dp.ecExtentList() {
+		if !dp.isLeader {
+			return
+		}
+		lost := make([]int, 0)
+		for i := range info.Hosts {
+			if err := dp.verifyEcShard(info, i); err != nil {
+				log.LogWarnf("action[repairEcExtents] partition(%v) extent(%v) shard(%v) host(%v) err(%v)",
+					dp.partitionID, info, i, info.Hosts[i], err)
+				lost = append(lost, i)
+			}
+		}
+		if len(lost) == 0 {
+			continue
+		}
+		shards, err := dp.reconstructEcShards(info)
+		if err != nil {
+			log.LogErrorf("action[repairEcExtents] partition(%v) extent(%v) lost(%v) err(%v)", dp.partitionID, info, lost, err)
+			continue
+		}
+		repaired := *info
+		repaired.Hosts = append([]string{}, info.Hosts...)
+		for _, i := range lost {
+			host := info.Hosts[i]
+			if len(ecHosts) == len(info.Hosts) && ecHosts[i] != host {
+				host = ecHosts[i]
+			}
+			if err = dp.writeEcShard(host, info.ExtentID, i, shards[i]); err != nil {
+				log.LogErrorf("action[repairEcExtents] partition(%v) extent(%v) shard(%v) host(%v) err(%v)", dp.partitionID, info, i, host, err)
+				continue
+			}
+			repaired.Hosts[i] = host
+			log.LogInfof("action[repairEcExtents] partition(%v) extent(%v) shard(%v) repaired on host(%v)", dp.partitionID, info, i, host)
+		}
+		if reflect.DeepEqual(repaired.Hosts, info.Hosts) {
+			continue
+		}
+		if err = dp.syncEcExtents([]*EcExtentInfo{&repaired}); err != nil {
+			log.LogErrorf("action[repairEcExtents] partition(%v) extent(%v) sync hosts(%v) err(%v)", dp.partitionID, info, repaired.Hosts, err)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package datanode
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/util"
 	"golang.org/x/time/rate"
 )
 
@@ -11,6 +14,7 @@ var (
 	MaxExtentRepairLimit    = 20000
 	MinExtentRepairLimit    = 5
 	extentRepairLimiteRater = make(chan struct{}, MaxExtentRepairLimit)
+	scrubLimitRate          = uint64(DefaultScrubLimitRate) // MB/s read by scrubbing on each disk
 )
 
 func requestDoExtentRepair() (err error) {
@@ -61,3 +65,19 @@ func setLimiter(limiter *rate.Limiter, limitValue uint64) {
 	}
 	limiter.SetLimit(l)
 }
+
+func setScrubLimit(value uint64) {
+	if value == 0 {
+		value = DefaultScrubLimitRate
+	}
+	atomic.StoreUint64(&scrubLimitRate, value)
+}
+
+// scrubWait limits the rate of reading the disk by scrubbing.
+func (d *Disk) scrubWait(size int) {
+	l := rate.Limit(atomic.LoadUint64(&scrubLimitRate) * util.MB)
+	if d.scrubLimiter.Limit() != l {
+		d.scrubLimiter.SetLimit(l)
+	}
+	d.scrubLimiter.WaitN(context.Background(), size)
+}
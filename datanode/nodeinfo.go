@@ -43,7 +43,13 @@ func (m *DataNode) updateNodeInfo() {
 	}
 	setLimiter(deleteLimiteRater, clusterInfo.DataNodeDeleteLimitRate)
 	setDoExtentRepair(int(clusterInfo.DataNodeAutoRepairLimitRate))
+	setScrubLimit(clusterInfo.DataNodeScrubLimitRate)
+	m.updateDiskQos(clusterInfo.DataNodeDiskIopsLimit, clusterInfo.DataNodeDiskBandwidthLimit)
+	m.space.repairScheduler.setLimit(clusterInfo.DataNodeRepairNodeBandwidth, clusterInfo.DataNodeRepairDiskBandwidth, m.space.GetDisks())
 	log.LogInfof("updateNodeInfo from master:"+
-		"deleteLimite(%v),autoRepairLimit(%v)", clusterInfo.DataNodeDeleteLimitRate,
-		clusterInfo.DataNodeAutoRepairLimitRate)
+		"deleteLimite(%v),autoRepairLimit(%v),scrubLimit(%v),diskIopsLimit(%v),diskBandwidthLimit(%v),"+
+		"repairNodeBandwidth(%v),repairDiskBandwidth(%v)",
+		clusterInfo.DataNodeDeleteLimitRate, clusterInfo.DataNodeAutoRepairLimitRate, clusterInfo.DataNodeScrubLimitRate,
+		clusterInfo.DataNodeDiskIopsLimit, clusterInfo.DataNodeDiskBandwidthLimit,
+		clusterInfo.DataNodeRepairNodeBandwidth, clusterInfo.DataNodeRepairDiskBandwidth)
 }
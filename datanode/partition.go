@@ -24,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"hash/crc32"
@@ -59,6 +60,10 @@ type DataPartitionMetadata struct {
 	Hosts                   []string
 	DataPartitionCreateType int
 	LastTruncateID          uint64
+	Checksum                string
+	SharedExtentID          uint64
+	SharedRanges            []*proto.SharedExtentRange
+	SharedTinySizes         map[uint64]uint64
 }
 
 type sortedPeers []proto.Peer
@@ -115,6 +120,30 @@ type DataPartition struct {
 	loadExtentHeaderStatus        int
 	DataPartitionCreateType       int
 	isLoadingDataPartition        bool
+
+	ecExtents     map[uint64]*EcExtentInfo // erasure coded extents converted from the cold normal extents
+	ecExtentsLock sync.RWMutex
+	ecTaskRunning int32
+	ecHosts       []string   // hosts of the shards given by the master, the dead ones of which are replaced
+	ecRestoreLock sync.Mutex // serializes the restoration of the erasure coded extents before they are modified
+
+	scrubRunning   int32
+	lastScrubTime  int64
+	scrubBadBlocks uint64   // number of the bad blocks found by scrubbing
+	scrubRepaired  uint64   // number of the bad blocks of the local replica repaired by scrubbing
+	scrubRepairing sync.Map // extents of the local replica being repaired
+
+	compressRunning int32
+
+	tinyCompactRunning int32
+
+	checksumMismatches uint64     // number of the data mismatching the checksums found by the partition
+	laggingExtents     sync.Map   // normal extents acknowledged with quorum before all the replicas persist them
+	laggingLock        sync.Mutex // serializes the persistence of the lagging extents
+
+	shareLock         sync.RWMutex        // protects the shared ranges and the deletions of the shared extents
+	sharedDeletes     map[uint64][]uint64 // meta partitions deleting each shared normal extent not yet released
+	sharedDeletesLock sync.Mutex          // serializes the persistence of the deletions of the shared extents
 }
 
 func CreateDataPartition(dpCfg *dataPartitionCfg, disk *Disk, request *proto.CreateDataPartitionRequest) (dp *DataPartition, err error) {
@@ -190,14 +219,18 @@ func LoadDataPartition(partitionDir string, disk *Disk) (dp *DataPartition, err
 	}
 
 	dpCfg := &dataPartitionCfg{
-		VolName:       meta.VolumeID,
-		PartitionSize: meta.PartitionSize,
-		PartitionID:   meta.PartitionID,
-		Peers:         meta.Peers,
-		Hosts:         meta.Hosts,
-		RaftStore:     disk.space.GetRaftStore(),
-		NodeID:        disk.space.GetNodeID(),
-		ClusterID:     disk.space.GetClusterID(),
+		VolName:         meta.VolumeID,
+		PartitionSize:   meta.PartitionSize,
+		PartitionID:     meta.PartitionID,
+		Peers:           meta.Peers,
+		Hosts:           meta.Hosts,
+		Checksum:        meta.Checksum,
+		SharedExtentID:  meta.SharedExtentID,
+		SharedRanges:    meta.SharedRanges,
+		SharedTinySizes: meta.SharedTinySizes,
+		RaftStore:       disk.space.GetRaftStore(),
+		NodeID:          disk.space.GetNodeID(),
+		ClusterID:       disk.space.GetClusterID(),
 	}
 	if dp, err = newDataPartition(dpCfg, disk); err != nil {
 		return
@@ -243,12 +276,24 @@ func newDataPartition(dpCfg *dataPartitionCfg, disk *Disk) (dp *DataPartition, e
 		snapshot:        make([]*proto.File, 0),
 		partitionStatus: proto.ReadWrite,
 		config:          dpCfg,
+		sharedDeletes:   make(map[uint64][]uint64),
 	}
 	partition.replicasInit()
 	partition.extentStore, err = storage.NewExtentStore(partition.path, dpCfg.PartitionID, dpCfg.PartitionSize)
 	if err != nil {
 		return
 	}
+	partition.extentStore.SetBlockCache(disk.space.blockCache)
+	partition.extentStore.SetChecksum(dpCfg.Checksum)
+	if err = partition.loadEcExtents(); err != nil {
+		return
+	}
+	if err = partition.loadLaggingExtents(); err != nil {
+		return
+	}
+	if err = partition.loadSharedDeletes(); err != nil {
+		return
+	}
 
 	disk.AttachDataPartition(partition)
 	dp = partition
@@ -414,6 +459,7 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 	sp := sortedPeers(dp.config.Peers)
 	sort.Sort(sp)
 
+	dp.shareLock.RLock()
 	md := &DataPartitionMetadata{
 		VolumeID:                dp.config.VolName,
 		PartitionID:             dp.config.PartitionID,
@@ -423,7 +469,12 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		DataPartitionCreateType: dp.DataPartitionCreateType,
 		CreateTime:              time.Now().Format(TimeLayout),
 		LastTruncateID:          dp.lastTruncateID,
+		Checksum:                dp.config.Checksum,
+		SharedExtentID:          dp.sharedExtentID(),
+		SharedRanges:            dp.config.SharedRanges,
+		SharedTinySizes:         dp.config.SharedTinySizes,
 	}
+	dp.shareLock.RUnlock()
 	if metaData, err = json.Marshal(md); err != nil {
 		return
 	}
@@ -451,6 +502,18 @@ func (dp *DataPartition) statusUpdateScheduler() {
 			} else {
 				dp.LaunchRepair(proto.NormalExtentType)
 			}
+			if index%EcRepairInterval == 0 && dp.isLeader {
+				go dp.repairEcExtents()
+			}
+			if index%ScrubCheckInterval == 0 && dp.isLeader {
+				go dp.scrub()
+			}
+			if index%CompressCheckInterval == 0 {
+				go dp.compressExtents()
+			}
+			if index%TinyCompactCheckInterval == 0 {
+				go dp.compactTinyExtents()
+			}
 		case <-snapshotTicker.C:
 			dp.ReloadSnapshot()
 		case <-dp.stopC:
@@ -523,6 +586,18 @@ func (dp *DataPartition) ExtentStore() *storage.ExtentStore {
 	return dp.extentStore
 }
 
+// checksum computes the checksum of the data with the checksum type of the partition.
+func (dp *DataPartition) checksum(data []byte) uint32 {
+	return proto.Checksum(dp.config.Checksum, data)
+}
+
+// checkChecksumMismatch counts the error if the data read mismatches the stored checksum.
+func (dp *DataPartition) checkChecksumMismatch(err error) {
+	if err != nil && strings.Contains(err.Error(), proto.ErrChecksumMismatch.Error()) {
+		atomic.AddUint64(&dp.checksumMismatches, 1)
+	}
+}
+
 func (dp *DataPartition) checkIsDiskError(err error) (diskError bool) {
 	if err == nil {
 		return
@@ -669,27 +744,7 @@ func (dp *DataPartition) DoExtentStoreRepair(repairTask *DataPartitionRepairTask
 		info := &storage.ExtentInfo{Source: extentInfo.Source, FileID: extentInfo.FileID, Size: extentInfo.Size}
 		repairTask.ExtentsToBeRepaired = append(repairTask.ExtentsToBeRepaired, info)
 	}
-	var (
-		wg           *sync.WaitGroup
-		recoverIndex int
-	)
-	wg = new(sync.WaitGroup)
-	for _, extentInfo := range repairTask.ExtentsToBeRepaired {
-
-		if !store.HasExtent(uint64(extentInfo.FileID)) {
-			continue
-		}
-		wg.Add(1)
-
-		// repair the extents
-		go dp.doStreamExtentFixRepair(wg, extentInfo)
-		recoverIndex++
-
-		if recoverIndex%NumOfFilesToRecoverInParallel == 0 {
-			wg.Wait()
-		}
-	}
-	wg.Wait()
+	dp.repairExtents(repairTask)
 	dp.doStreamFixTinyDeleteRecord(repairTask)
 }
 
@@ -716,7 +771,7 @@ func (dp *DataPartition) doStreamFixTinyDeleteRecord(repairTask *DataPartitionRe
 	var (
 		localTinyDeleteFileSize int64
 		err                     error
-		conn                    *net.TCPConn
+		conn                    net.Conn
 	)
 	if !dp.pushSyncDeleteRecordFromLeaderMesg() {
 		return
@@ -0,0 +1,114 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// compactTinyExtents reclaims the space of the tiny extents with too much data deleted.
+// The leader stops writing to such a tiny extent and reports it to the master, then the meta nodes relocate
+// the live data of it to the other tiny extents. Once all of its data has been deleted, the tiny extent
+// is truncated to empty on all the replicas and becomes writable again.
+func (dp *DataPartition) compactTinyExtents() {
+	if !atomic.CompareAndSwapInt32(&dp.tinyCompactRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&dp.tinyCompactRunning, 0)
+	if dp.isSharingFrozen() {
+		return
+	}
+	deleted, err := dp.extentStore.TinyDeletedSizes()
+	if err != nil {
+		log.LogErrorf("action[compactTinyExtents] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+	if !dp.isLeader {
+		return
+	}
+
+	compacting := dp.extentStore.CompactingTinyExtents()
+	for _, extentID := range compacting {
+		// the data shared by the cloned volumes is referred to by the meta nodes of the other volumes
+		if dp.hasSharedTinyData(extentID) {
+			continue
+		}
+		ei, err := dp.extentStore.Watermark(extentID)
+		if err != nil || deleted[extentID] < int64(ei.Size) {
+			continue
+		}
+		if err = dp.resetTinyExtent(extentID, int64(ei.Size)); err != nil {
+			log.LogWarnf("action[compactTinyExtents] partition(%v) reset extent(%v) err(%v)", dp.partitionID, extentID, err)
+			continue
+		}
+		log.LogInfof("action[compactTinyExtents] partition(%v) extent(%v) size(%v) reclaimed",
+			dp.partitionID, extentID, ei.Size)
+	}
+
+	candidates := make([]uint64, 0)
+	for extentID, size := range deleted {
+		if dp.extentStore.IsTinyExtentCompacting(extentID) || dp.hasSharedTinyData(extentID) {
+			continue
+		}
+		ei, err := dp.extentStore.Watermark(extentID)
+		if err != nil || ei.Size < TinyCompactMinSize || float64(size) < float64(ei.Size)*TinyCompactDeleteRatio {
+			continue
+		}
+		candidates = append(candidates, extentID)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return deleted[candidates[i]] > deleted[candidates[j]] })
+	count := len(dp.extentStore.CompactingTinyExtents())
+	for _, extentID := range candidates {
+		if count >= MaxCompactingTinyExtents {
+			break
+		}
+		dp.extentStore.MarkTinyExtentCompacting(extentID)
+		count++
+		log.LogInfof("action[compactTinyExtents] partition(%v) start to compact extent(%v) deleted(%v)",
+			dp.partitionID, extentID, deleted[extentID])
+	}
+}
+
+// resetTinyExtent truncates the tiny extent on the followers before the leader, so that the extent
+// keeps compacting and unwritable until it is reset on all the replicas.
+func (dp *DataPartition) resetTinyExtent(extentID uint64, size int64) (err error) {
+	replicas := dp.Replicas()
+	if len(replicas) == 0 {
+		return
+	}
+	for _, target := range replicas[1:] {
+		p := repl.NewCompactTinyExtentPacket(dp.partitionID, extentID, size)
+		if _, err = dp.sendPacket(target, p, TinyCompactTimeout); err != nil {
+			return
+		}
+	}
+	return dp.extentStore.ResetTinyExtent(extentID, size)
+}
+
+// relocatingTinyExtents returns the tiny extents whose live data are to be relocated by the meta nodes.
+func (dp *DataPartition) relocatingTinyExtents() []uint64 {
+	if !dp.isLeader {
+		return nil
+	}
+	extents := dp.extentStore.CompactingTinyExtents()
+	if len(extents) == 0 {
+		return nil
+	}
+	return extents
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (s *DataNode) getVolCompression(volName string) string {
+	s.volCompressionLock.RLock()
+	defer s.volCompressionLock.RUnlock()
+	return s.volCompression[volName]
+}
+
+// updateVolCompression updates the compression modes of the volumes distributed by the master.
+func (s *DataNode) updateVolCompression(volCompression map[string]string) {
+	s.volCompressionLock.Lock()
+	defer s.volCompressionLock.Unlock()
+	s.volCompression = volCompression
+}
+
+// compressExtents compresses the sealed normal extents of the partition if the volume enables compression.
+// Each replica compresses its extents independently, and the data read from the replicas is the same.
+func (dp *DataPartition) compressExtents() {
+	mode := dp.disk.space.dataNode.getVolCompression(dp.volumeID)
+	if mode == proto.CompressionNone {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&dp.compressRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&dp.compressRunning, 0)
+
+	extents, _, err := dp.extentStore.GetAllWatermarks(storage.NormalExtentFilter())
+	if err != nil {
+		log.LogErrorf("action[compressExtents] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+	var count int
+	for _, ei := range extents {
+		if atomic.LoadUint64(&ei.CompressedSize) > 0 || time.Now().Unix()-ei.ModifyTime <= ExtentSealTime {
+			continue
+		}
+		compressed, err := dp.extentStore.CompressExtent(ei.FileID, mode)
+		if err != nil {
+			log.LogWarnf("action[compressExtents] partition(%v) extent(%v) err(%v)", dp.partitionID, ei.FileID, err)
+			continue
+		}
+		if compressed {
+			count++
+		}
+	}
+	if count > 0 {
+		rawSize, compressedSize := dp.extentStore.CompressionStat()
+		log.LogInfof("action[compressExtents] partition(%v) compressed(%v) extents, rawSize(%v) compressedSize(%v)",
+			dp.partitionID, count, rawSize, compressedSize)
+	}
+}
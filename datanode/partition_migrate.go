@@ -0,0 +1,330 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var (
+	AutoDiskBalanceStatus = true
+)
+
+// MigratePartition moves the data partition to another disk of the local node in the background.
+// The raft members of the partition are not changed. Only one partition is migrated at a time.
+func (manager *SpaceManager) MigratePartition(partitionID uint64, diskPath string) (err error) {
+	dp := manager.Partition(partitionID)
+	if dp == nil {
+		return fmt.Errorf("partition(%v) not exist", partitionID)
+	}
+	target, err := manager.GetDisk(diskPath)
+	if err != nil {
+		return
+	}
+	if err = checkMigration(dp, target); err != nil {
+		return
+	}
+	if !atomic.CompareAndSwapUint64(&manager.migratingPartition, 0, partitionID) {
+		return ErrPartitionMigrating
+	}
+	go func() {
+		defer atomic.StoreUint64(&manager.migratingPartition, 0)
+		if err := manager.migratePartition(dp, target); err != nil {
+			msg := fmt.Sprintf("migrate partition(%v) from disk(%v) to disk(%v) err(%v)",
+				dp.partitionID, dp.disk.Path, target.Path, err)
+			log.LogErrorf("action[MigratePartition] %v", msg)
+			exporter.Warning(msg)
+		}
+	}()
+	return
+}
+
+func checkMigration(dp *DataPartition, target *Disk) (err error) {
+	if dp.disk == target {
+		return fmt.Errorf("partition(%v) is already on disk(%v)", dp.partitionID, target.Path)
+	}
+	if dp.isLoadingDataPartition {
+		return fmt.Errorf("partition(%v) is loading", dp.partitionID)
+	}
+	if target.Status != proto.ReadWrite {
+		return fmt.Errorf("disk(%v) is not writable", target.Path)
+	}
+	if target.Available <= uint64(dp.Used()) {
+		return fmt.Errorf("disk(%v) available(%v) is not enough for partition(%v) used(%v)",
+			target.Path, target.Available, dp.partitionID, dp.Used())
+	}
+	return
+}
+
+// migratePartition copies the files of the partition to the target disk while the partition is serving,
+// then stops the partition, copies the files changed in the meantime and reloads the partition on the target disk.
+// The partition is reloaded on the source disk if the migration fails after it is stopped.
+func (manager *SpaceManager) migratePartition(dp *DataPartition, target *Disk) (err error) {
+	source := dp.disk
+	dirName := path.Base(dp.Path())
+	tmpPath := path.Join(target.Path, MigratingPartitionPrefix+dirName)
+	newPath := path.Join(target.Path, dirName)
+	log.LogInfof("action[migratePartition] partition(%v) from disk(%v) to disk(%v) start",
+		dp.partitionID, source.Path, target.Path)
+
+	if err = os.RemoveAll(tmpPath); err != nil {
+		return
+	}
+	if err = syncDir(dp.Path(), tmpPath); err != nil {
+		os.RemoveAll(tmpPath)
+		return
+	}
+
+	// the requests to the stopped partition fail and are retried by the clients
+	dp.partitionStatus = proto.Unavailable
+	dp.Stop()
+	dp.storeAppliedID(dp.appliedID)
+	dp.PersistMetadata()
+	defer func() {
+		if err == nil {
+			return
+		}
+		os.RemoveAll(tmpPath)
+		source.DetachDataPartition(dp)
+		if _, e := LoadDataPartition(dp.Path(), source); e != nil {
+			log.LogErrorf("action[migratePartition] reload partition(%v) on disk(%v) err(%v)",
+				dp.partitionID, source.Path, e)
+		}
+	}()
+	if err = syncDir(dp.Path(), tmpPath); err != nil {
+		return
+	}
+	if err = os.Rename(tmpPath, newPath); err != nil {
+		return
+	}
+	source.DetachDataPartition(dp)
+	var newDp *DataPartition
+	if newDp, err = LoadDataPartition(newPath, target); err != nil {
+		if newDp != nil {
+			newDp.Stop()
+		}
+		target.DetachDataPartition(dp)
+		tmpPath = newPath
+		return
+	}
+	if err = os.RemoveAll(dp.Path()); err != nil {
+		log.LogWarnf("action[migratePartition] remove partition(%v) path(%v) err(%v)", dp.partitionID, dp.Path(), err)
+		err = nil
+	}
+	log.LogInfof("action[migratePartition] partition(%v) from disk(%v) to disk(%v) finished",
+		dp.partitionID, source.Path, target.Path)
+	return
+}
+
+// syncDir makes the directory dst the same as src. The files of the same size and modification time are skipped,
+// so that only the files changed since the last sync are copied.
+func syncDir(src, dst string) (err error) {
+	if err = filepath.Walk(src, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if ti, err := os.Stat(target); err == nil && ti.Size() == info.Size() && ti.ModTime().Equal(info.ModTime()) {
+			return nil
+		}
+		return copyFile(name, target, info)
+	}); err != nil {
+		return
+	}
+	// remove the files deleted from src since the last sync
+	return filepath.Walk(dst, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, name)
+		if err != nil {
+			return err
+		}
+		if _, err = os.Lstat(filepath.Join(src, rel)); os.IsNotExist(err) {
+			if err = os.RemoveAll(name); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return err
+	})
+}
+
+// copyFile copies the regular file and keeps its holes, as the tiny extents are sparse files.
+func copyFile(src, dst string, info os.FileInfo) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return
+	}
+	defer out.Close()
+
+	buf := make([]byte, MigrateCopyBufferSize)
+	zero := make([]byte, MigrateCopyBufferSize)
+	var offset int64
+	for {
+		n, e := in.Read(buf)
+		if n > 0 {
+			if !bytes.Equal(buf[:n], zero[:n]) {
+				if _, err = out.WriteAt(buf[:n], offset); err != nil {
+					return
+				}
+			}
+			offset += int64(n)
+		}
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return e
+		}
+	}
+	if err = out.Truncate(offset); err != nil {
+		return
+	}
+	if err = out.Sync(); err != nil {
+		return
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// balanceDisks migrates a partition away from the disk whose errors are increasing,
+// or from the most used disk to the least used one if their usage ratios differ too much.
+func (manager *SpaceManager) balanceDisks() {
+	if !AutoDiskBalanceStatus || atomic.LoadUint64(&manager.migratingPartition) != 0 {
+		return
+	}
+	disks := manager.GetDisks()
+	errRising := make(map[string]bool)
+	for _, d := range disks {
+		errCnt := atomic.LoadUint64(&d.ReadErrCnt) + atomic.LoadUint64(&d.WriteErrCnt)
+		if last, ok := manager.diskErrCnt[d.Path]; ok && errCnt > last {
+			errRising[d.Path] = true
+		}
+		manager.diskErrCnt[d.Path] = errCnt
+	}
+	dp, target := planDiskBalance(disks, errRising)
+	if dp == nil {
+		return
+	}
+	log.LogInfof("action[balanceDisks] migrate partition(%v) used(%v) from disk(%v) to disk(%v)",
+		dp.partitionID, dp.Used(), dp.disk.Path, target.Path)
+	if err := manager.MigratePartition(dp.partitionID, target.Path); err != nil {
+		log.LogWarnf("action[balanceDisks] migrate partition(%v) err(%v)", dp.partitionID, err)
+	}
+}
+
+// planDiskBalance selects the partition to migrate and the target disk, which is the least used healthy disk.
+// The smallest partition is moved away from the disk whose errors are increasing. Otherwise, the largest partition
+// that keeps the most used disk no less used than the target after the migration is selected.
+func planDiskBalance(disks []*Disk, errRising map[string]bool) (dp *DataPartition, target *Disk) {
+	usage := func(d *Disk) float64 {
+		return float64(d.Used) / float64(d.Total)
+	}
+	for _, d := range disks {
+		if d.Status != proto.ReadWrite || errRising[d.Path] || d.Total == 0 {
+			continue
+		}
+		if target == nil || usage(d) < usage(target) {
+			target = d
+		}
+	}
+	if target == nil {
+		return nil, nil
+	}
+
+	for _, d := range disks {
+		if d == target || !errRising[d.Path] {
+			continue
+		}
+		for _, p := range d.partitions() {
+			if uint64(p.Used()) < target.Available && (dp == nil || p.Used() < dp.Used()) {
+				dp = p
+			}
+		}
+		if dp != nil {
+			return
+		}
+	}
+
+	var source *Disk
+	for _, d := range disks {
+		if d == target || d.Total == 0 {
+			continue
+		}
+		if source == nil || usage(d) > usage(source) {
+			source = d
+		}
+	}
+	if source == nil || usage(source)-usage(target) <= DiskBalanceThreshold {
+		return nil, nil
+	}
+	for _, p := range source.partitions() {
+		used := uint64(p.Used())
+		if used == 0 || used >= target.Available || used > source.Used {
+			continue
+		}
+		if float64(source.Used-used)/float64(source.Total) < float64(target.Used+used)/float64(target.Total) {
+			continue
+		}
+		if dp == nil || p.Used() > dp.Used() {
+			dp = p
+		}
+	}
+	if dp == nil {
+		return nil, nil
+	}
+	return
+}
+
+func (d *Disk) partitions() (partitions []*DataPartition) {
+	d.RLock()
+	defer d.RUnlock()
+	partitions = make([]*DataPartition, 0, len(d.partitionMap))
+	for _, dp := range d.partitionMap {
+		if dp.isLoadingDataPartition {
+			continue
+		}
+		partitions = append(partitions, dp)
+	}
+	return
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func newBalanceDisk(name string, total, used uint64, partitionUsed ...int) *Disk {
+	d := &Disk{
+		Path:         name,
+		Total:        total,
+		Used:         used,
+		Available:    total - used,
+		Status:       proto.ReadWrite,
+		partitionMap: make(map[uint64]*DataPartition),
+	}
+	for i, u := range partitionUsed {
+		id := uint64(len(name)*100 + i + 1)
+		d.partitionMap[id] = &DataPartition{partitionID: id, used: u, disk: d}
+	}
+	return d
+}
+
+func TestPlanDiskBalance(t *testing.T) {
+	full := newBalanceDisk("/full", 100*util.GB, 80*util.GB, 10*util.GB, 20*util.GB, 40*util.GB)
+	empty := newBalanceDisk("/empty", 100*util.GB, 20*util.GB, 20*util.GB)
+	disks := []*Disk{full, empty}
+
+	// the 40G partition would make the target more used than the source
+	dp, target := planDiskBalance(disks, nil)
+	if dp == nil || target != empty || dp.Used() != 20*util.GB {
+		t.Fatalf("unexpected plan partition(%v) target(%v)", dp, target)
+	}
+
+	// the smallest partition is moved away from the disk with increasing errors
+	dp, target = planDiskBalance(disks, map[string]bool{"/full": true})
+	if dp == nil || target != empty || dp.Used() != 10*util.GB {
+		t.Fatalf("unexpected plan partition(%v) target(%v)", dp, target)
+	}
+
+	// the disks are balanced
+	full.Used = 25 * util.GB
+	if dp, _ = planDiskBalance(disks, nil); dp != nil {
+		t.Fatalf("unexpected migration of partition(%v)", dp.partitionID)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "syncdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	src, dst := path.Join(root, "src"), path.Join(root, "dst")
+	if err = os.MkdirAll(path.Join(src, "wal"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 3*MigrateCopyBufferSize)
+	copy(data[2*MigrateCopyBufferSize:], "tail")
+	files := map[string][]byte{"1": data, "wal/1.log": []byte("log"), "META": []byte("meta")}
+	for name, content := range files {
+		if err = ioutil.WriteFile(path.Join(src, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = syncDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	// the changed and the deleted files are synchronized again
+	files["META"] = []byte("new meta")
+	delete(files, "wal/1.log")
+	if err = ioutil.WriteFile(path.Join(src, "META"), files["META"], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(path.Join(src, "wal/1.log")); err != nil {
+		t.Fatal(err)
+	}
+	if err = syncDir(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		got, err := ioutil.ReadFile(path.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("file(%v) mismatch", name)
+		}
+	}
+	if _, err = os.Stat(path.Join(dst, "wal/1.log")); !os.IsNotExist(err) {
+		t.Fatalf("deleted file still exists, err(%v)", err)
+	}
+}
@@ -236,6 +236,13 @@ func (dp *DataPartition) ApplyRandomWrite(command []byte, raftApplyID uint64) (r
 	}
 	log.LogDebugf("[ApplyRandomWrite] ApplyID(%v) Partition(%v)_Extent(%v)_ExtentOffset(%v)_Size(%v)",
 		raftApplyID, dp.partitionID, opItem.extentID, opItem.offset, opItem.size)
+	if e := dp.restoreEcExtent(opItem.extentID); e != nil {
+		// the write is dropped like the one of a missing extent, and the extent is repaired from the leader
+		log.LogErrorf("[ApplyRandomWrite] ApplyID(%v) Partition(%v)_Extent(%v) restore from the shards err(%v)",
+			raftApplyID, dp.partitionID, opItem.extentID, e)
+		dp.deleteEcExtent(opItem.extentID, false)
+		dp.extentStore.ForgetDeletedNormalExtent(opItem.extentID)
+	}
 	for i := 0; i < 20; i++ {
 		err = dp.ExtentStore().Write(opItem.extentID, opItem.offset, opItem.size, opItem.data, opItem.crc, storage.RandomWriteType, opItem.opcode == proto.OpSyncRandomWrite)
 		if dp.checkIsDiskError(err) {
@@ -0,0 +1,192 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (s *DataNode) isVolQuorumWrite(volName string) bool {
+	s.volQuorumWriteLock.RLock()
+	defer s.volQuorumWriteLock.RUnlock()
+	return s.volQuorumWrite[volName]
+}
+
+// updateVolQuorumWrite updates the volumes writing with quorum distributed by the master.
+func (s *DataNode) updateVolQuorumWrite(volQuorumWrite map[string]bool) {
+	s.volQuorumWriteLock.Lock()
+	defer s.volQuorumWriteLock.Unlock()
+	s.volQuorumWrite = volQuorumWrite
+}
+
+// checkQuorumWrite marks the appends to the normal extents on the leader to be acknowledged once a majority
+// of the replicas persist them, if the volume writes with quorum. The tiny extents are shared by the files
+// and always written to all the replicas.
+func (s *DataNode) checkQuorumWrite(p *repl.Packet) {
+	if !p.IsWriteOperation() || !p.IsForwardPkt() || p.IsTinyExtentType() {
+		return
+	}
+	dp := p.Object.(*DataPartition)
+	p.QuorumWrite = s.isVolQuorumWrite(dp.volumeID)
+}
+
+// markLaggingExtent records the normal extent acknowledged before all the followers persist the data.
+// The lagging replicas are caught up by the next repair of the partition. The extents are persisted
+// so that they are still repaired after the leader restarts.
+func (dp *DataPartition) markLaggingExtent(extentID uint64) {
+	if _, ok := dp.laggingExtents.LoadOrStore(extentID, true); ok {
+		return
+	}
+	if err := dp.persistLaggingExtents(); err != nil {
+		log.LogErrorf("action[markLaggingExtent] partition(%v) extent(%v) persist err(%v)", dp.partitionID, extentID, err)
+	}
+}
+
+// laggingExtentIDs returns the normal extents with the replicas lagging behind.
+func (dp *DataPartition) laggingExtentIDs() (extents []uint64) {
+	extents = make([]uint64, 0)
+	dp.laggingExtents.Range(func(key, value interface{}) bool {
+		extents = append(extents, key.(uint64))
+		return true
+	})
+	return
+}
+
+// clearLaggingExtents clears the lagging extents once they are repaired, and forwards them to all the followers again.
+func (dp *DataPartition) clearLaggingExtents(extents []uint64) {
+	if len(extents) == 0 {
+		return
+	}
+	for _, extentID := range extents {
+		dp.laggingExtents.Delete(extentID)
+	}
+	repl.ClearMissedExtents(dp.partitionID, extents)
+	if err := dp.persistLaggingExtents(); err != nil {
+		log.LogErrorf("action[clearLaggingExtents] partition(%v) persist err(%v)", dp.partitionID, err)
+	}
+}
+
+func (dp *DataPartition) loadLaggingExtents() (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path.Join(dp.Path(), LaggingExtentsFileName)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	extents := make([]uint64, 0)
+	if err = json.Unmarshal(data, &extents); err != nil {
+		return
+	}
+	for _, extentID := range extents {
+		dp.laggingExtents.Store(extentID, true)
+	}
+	return
+}
+
+func (dp *DataPartition) persistLaggingExtents() (err error) {
+	dp.laggingLock.Lock()
+	defer dp.laggingLock.Unlock()
+	var data []byte
+	if data, err = json.Marshal(dp.laggingExtentIDs()); err != nil {
+		return
+	}
+	tmpName := path.Join(dp.Path(), TempLaggingExtentsFile)
+	if err = ioutil.WriteFile(tmpName, data, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmpName, path.Join(dp.Path(), LaggingExtentsFileName))
+}
+
+// clearRepairedLaggingExtents clears the lagging extents verified on all the replicas, and the deleted ones.
+// The ones not yet idle, on the unreachable replicas or still being repaired are kept for the next repair.
+func (dp *DataPartition) clearRepairedLaggingExtents(repairTasks []*DataPartitionRepairTask, laggingExtents []uint64) {
+	if len(laggingExtents) == 0 {
+		return
+	}
+	for _, task := range repairTasks {
+		if task == nil {
+			return
+		}
+	}
+	replicas := dp.Replicas()
+	repaired := make([]uint64, 0, len(laggingExtents))
+	for _, extentID := range laggingExtents {
+		if !dp.extentStore.HasExtent(extentID) || dp.extentStore.IsDeletedNormalExtent(extentID) {
+			repaired = append(repaired, extentID)
+			continue
+		}
+		if _, ok := repairTasks[0].extents[extentID]; !ok {
+			continue
+		}
+		consistent, err := dp.verifyLaggingExtent(extentID, replicas)
+		if err != nil {
+			log.LogWarnf("action[clearRepairedLaggingExtents] partition(%v) extent(%v) err(%v)", dp.partitionID, extentID, err)
+			continue
+		}
+		if consistent {
+			repaired = append(repaired, extentID)
+		}
+	}
+	dp.clearLaggingExtents(repaired)
+}
+
+// verifyLaggingExtent compares the crc of each block of the lagging extent on the followers with that on the leader.
+// A follower missing a packet may persist the following ones with a hole in between, which the repair by the size
+// does not find. The mismatching blocks are repaired with the data of the leader in the background, so the extent
+// is consistent only once all the replicas have the same size and the same blocks.
+func (dp *DataPartition) verifyLaggingExtent(extentID uint64, replicas []string) (consistent bool, err error) {
+	results := make([]*proto.ScrubExtentResponse, len(replicas))
+	for i, host := range replicas {
+		if results[i], err = dp.requestScrubExtent(host, extentID); err != nil {
+			return
+		}
+	}
+	if len(results[0].BadBlocks) > 0 {
+		// the bad blocks of the leader are repaired by the scrub first
+		return
+	}
+	consistent = true
+	for i := 1; i < len(results); i++ {
+		if results[i].Size != results[0].Size {
+			consistent = false
+			continue
+		}
+		req := &proto.ScrubExtentRequest{ExtentId: extentID, RepairSource: replicas[0]}
+		for blockNo, crc := range results[0].BlockCrcs {
+			if results[i].BlockCrcs[blockNo] != crc {
+				req.RepairBlocks = append(req.RepairBlocks, blockNo)
+				req.RepairCrcs = append(req.RepairCrcs, crc)
+			}
+		}
+		if len(req.RepairBlocks) == 0 {
+			continue
+		}
+		consistent = false
+		log.LogWarnf("action[verifyLaggingExtent] partition(%v) extent(%v) host(%v) mismatch blocks(%v)",
+			dp.partitionID, extentID, replicas[i], req.RepairBlocks)
+		if e := dp.requestRepairScrubbedExtent(replicas[i], req); e != nil {
+			err = e
+		}
+	}
+	return
+}
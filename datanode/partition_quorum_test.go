@@ -0,0 +1,47 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestPersistLaggingExtents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lagging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dp := &DataPartition{partitionID: 1, path: dir}
+	dp.markLaggingExtent(1025)
+	dp.markLaggingExtent(1026)
+	dp.markLaggingExtent(1027)
+	dp.clearLaggingExtents([]uint64{1026})
+
+	// the lagging extents are loaded after the restart
+	restarted := &DataPartition{partitionID: 1, path: dir}
+	if err = restarted.loadLaggingExtents(); err != nil {
+		t.Fatal(err)
+	}
+	extents := restarted.laggingExtentIDs()
+	sort.Slice(extents, func(i, j int) bool { return extents[i] < extents[j] })
+	if len(extents) != 2 || extents[0] != 1025 || extents[1] != 1027 {
+		t.Fatalf("unexpected lagging extents(%v)", extents)
+	}
+}
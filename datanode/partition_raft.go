@@ -36,14 +36,18 @@ import (
 )
 
 type dataPartitionCfg struct {
-	VolName       string              `json:"vol_name"`
-	ClusterID     string              `json:"cluster_id"`
-	PartitionID   uint64              `json:"partition_id"`
-	PartitionSize int                 `json:"partition_size"`
-	Peers         []proto.Peer        `json:"peers"`
-	Hosts         []string            `json:"hosts"`
-	NodeID        uint64              `json:"-"`
-	RaftStore     raftstore.RaftStore `json:"-"`
+	VolName         string                     `json:"vol_name"`
+	ClusterID       string                     `json:"cluster_id"`
+	PartitionID     uint64                     `json:"partition_id"`
+	PartitionSize   int                        `json:"partition_size"`
+	Peers           []proto.Peer               `json:"peers"`
+	Hosts           []string                   `json:"hosts"`
+	Checksum        string                     `json:"checksum"`
+	SharedExtentID  uint64                     `json:"shared_extent_id"` // extents up to it are shared by the cloned volumes
+	SharedRanges    []*proto.SharedExtentRange `json:"shared_ranges"`
+	SharedTinySizes map[uint64]uint64          `json:"shared_tiny_sizes"` // sizes of the tiny extents shared by the cloned volumes
+	NodeID          uint64                     `json:"-"`
+	RaftStore       raftstore.RaftStore        `json:"-"`
 }
 
 func (dp *DataPartition) raftPort() (heartbeat, replica int, err error) {
@@ -563,7 +567,7 @@ func (dp *DataPartition) findMaxAppliedID(allAppliedIDs []uint64) (maxAppliedID
 // Get the partition size from the leader.
 func (dp *DataPartition) getLeaderPartitionSize(maxExtentID uint64) (size uint64, err error) {
 	var (
-		conn *net.TCPConn
+		conn net.Conn
 	)
 
 	p := NewPacketToGetPartitionSize(dp.partitionID)
@@ -599,7 +603,7 @@ func (dp *DataPartition) getLeaderPartitionSize(maxExtentID uint64) (size uint64
 // Get the MaxExtentID partition  from the leader.
 func (dp *DataPartition) getLeaderMaxExtentIDAndPartitionSize() (maxExtentID, PartitionSize uint64, err error) {
 	var (
-		conn *net.TCPConn
+		conn net.Conn
 	)
 
 	p := NewPacketToGetMaxExtentIDAndPartitionSIze(dp.partitionID)
@@ -646,7 +650,7 @@ func (dp *DataPartition) broadcastMinAppliedID(minAppliedID uint64) (err error)
 			continue
 		}
 		target := dp.getReplicaAddr(i)
-		var conn *net.TCPConn
+		var conn net.Conn
 		conn, err = gConnPool.GetConnect(target)
 		if err != nil {
 			return
@@ -701,7 +705,7 @@ func (dp *DataPartition) getAllReplicaAppliedID() (allAppliedID []uint64, replyN
 
 // Get target members' applied id
 func (dp *DataPartition) getRemoteAppliedID(target string, p *repl.Packet) (appliedID uint64, err error) {
-	var conn *net.TCPConn
+	var conn net.Conn
 	start := time.Now().UnixNano()
 	defer func() {
 		if err != nil {
@@ -0,0 +1,279 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	MetricScrubBadBlocks      = "scrub_bad_blocks"
+	MetricScrubRepairedBlocks = "scrub_repaired_blocks"
+)
+
+// scrub re-reads the normal extents on all the replicas once in a scrub period, and repairs the bad blocks
+// with the data of a healthy replica. It is only done by the leader.
+func (dp *DataPartition) scrub() {
+	if time.Now().Unix()-atomic.LoadInt64(&dp.lastScrubTime) < ScrubPeriod {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&dp.scrubRunning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&dp.scrubRunning, 0)
+
+	extents, _, err := dp.extentStore.GetAllWatermarks(storage.NormalExtentFilter())
+	if err != nil {
+		log.LogErrorf("action[scrub] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+	replicas := dp.Replicas()
+	for _, ei := range extents {
+		if !dp.isLeader {
+			return
+		}
+		// the crc of the blocks written recently may not be computed yet
+		if time.Now().Unix()-ei.ModifyTime <= storage.UpdateCrcInterval {
+			continue
+		}
+		if err = dp.scrubExtent(ei.FileID, replicas); err != nil {
+			log.LogWarnf("action[scrub] partition(%v) extent(%v) err(%v)", dp.partitionID, ei.FileID, err)
+		}
+	}
+	atomic.StoreInt64(&dp.lastScrubTime, time.Now().Unix())
+	log.LogInfof("action[scrub] partition(%v) finished, badBlocks(%v) repaired(%v)", dp.partitionID,
+		atomic.LoadUint64(&dp.scrubBadBlocks), atomic.LoadUint64(&dp.scrubRepaired))
+}
+
+func (dp *DataPartition) scrubExtent(extentID uint64, replicas []string) (err error) {
+	results := make([]*proto.ScrubExtentResponse, len(replicas))
+	for i, host := range replicas {
+		if results[i], err = dp.requestScrubExtent(host, extentID); err != nil {
+			return
+		}
+	}
+	badBlocks := findScrubBadBlocks(results)
+
+	source := -1
+	for i := range results {
+		if len(badBlocks[i]) == 0 {
+			source = i
+			break
+		}
+	}
+	for i, blocks := range badBlocks {
+		if len(blocks) == 0 {
+			continue
+		}
+		atomic.AddUint64(&dp.scrubBadBlocks, uint64(len(blocks)))
+		exporter.NewCounter(MetricScrubBadBlocks).Add(int64(len(blocks)))
+		log.LogWarnf("action[scrubExtent] partition(%v) extent(%v) host(%v) bad blocks(%v)",
+			dp.partitionID, extentID, replicas[i], blocks)
+		if source < 0 {
+			err = fmt.Errorf("no healthy replica to repair host(%v)", replicas[i])
+			exporter.Warning(fmt.Sprintf("scrub partition(%v) extent(%v) err(%v)", dp.partitionID, extentID, err))
+			continue
+		}
+		req := &proto.ScrubExtentRequest{
+			ExtentId:     extentID,
+			RepairSource: replicas[source],
+			RepairBlocks: make([]int, 0, len(blocks)),
+			RepairCrcs:   make([]uint32, 0, len(blocks)),
+		}
+		for _, blockNo := range blocks {
+			if blockNo < len(results[source].BlockCrcs) {
+				req.RepairBlocks = append(req.RepairBlocks, blockNo)
+				req.RepairCrcs = append(req.RepairCrcs, results[source].BlockCrcs[blockNo])
+			}
+		}
+		if e := dp.requestRepairScrubbedExtent(replicas[i], req); e != nil {
+			err = e
+			exporter.Warning(fmt.Sprintf("scrub partition(%v) extent(%v) repair host(%v) err(%v)",
+				dp.partitionID, extentID, replicas[i], e))
+		}
+	}
+	return
+}
+
+// findScrubBadBlocks returns the sorted bad blocks of each replica. Besides the blocks mismatching the recorded crc,
+// a block is bad if the replicas have the same size and its crc differs from that of the majority.
+func findScrubBadBlocks(results []*proto.ScrubExtentResponse) (badBlocks [][]int) {
+	badBlocks = make([][]int, len(results))
+	sameSize := true
+	for i, r := range results {
+		if r.Size != results[0].Size || len(r.BlockCrcs) != len(results[0].BlockCrcs) {
+			sameSize = false
+		}
+		badBlocks[i] = append(badBlocks[i], r.BadBlocks...)
+	}
+	if sameSize {
+		for blockNo := range results[0].BlockCrcs {
+			count := make(map[uint32]int)
+			for _, r := range results {
+				count[r.BlockCrcs[blockNo]]++
+			}
+			for crc, n := range count {
+				if n*2 <= len(results) {
+					continue
+				}
+				for i, r := range results {
+					if r.BlockCrcs[blockNo] != crc && !containsBlock(badBlocks[i], blockNo) {
+						badBlocks[i] = append(badBlocks[i], blockNo)
+					}
+				}
+			}
+		}
+	}
+	for i := range badBlocks {
+		sort.Ints(badBlocks[i])
+	}
+	return
+}
+
+func containsBlock(blocks []int, blockNo int) bool {
+	for _, b := range blocks {
+		if b == blockNo {
+			return true
+		}
+	}
+	return false
+}
+
+func (dp *DataPartition) requestScrubExtent(target string, extentID uint64) (resp *proto.ScrubExtentResponse, err error) {
+	data, _ := json.Marshal(&proto.ScrubExtentRequest{ExtentId: extentID})
+	reply, err := dp.sendPacket(target, repl.NewScrubExtentPacket(dp.partitionID, extentID, data), ScrubExtentTimeout)
+	if err != nil {
+		return
+	}
+	resp = new(proto.ScrubExtentResponse)
+	err = json.Unmarshal(reply.Data[:reply.Size], resp)
+	return
+}
+
+func (dp *DataPartition) requestRepairScrubbedExtent(target string, req *proto.ScrubExtentRequest) (err error) {
+	data, _ := json.Marshal(req)
+	_, err = dp.sendPacket(target, repl.NewScrubExtentPacket(dp.partitionID, req.ExtentId, data), ScrubExtentTimeout)
+	return
+}
+
+// scrubExtentLocally verifies the data of the extent on the local disk.
+func (dp *DataPartition) scrubExtentLocally(extentID uint64) (resp *proto.ScrubExtentResponse, err error) {
+	size, crcs, badBlocks, err := dp.extentStore.ScrubExtent(extentID, dp.disk.scrubWait)
+	if err != nil {
+		return
+	}
+	if len(badBlocks) > 0 {
+		log.LogWarnf("action[scrubExtentLocally] partition(%v) extent(%v) crc mismatch blocks(%v)",
+			dp.partitionID, extentID, badBlocks)
+	}
+	resp = &proto.ScrubExtentResponse{
+		ExtentId:  extentID,
+		Size:      uint64(size),
+		BlockCrcs: crcs,
+		BadBlocks: badBlocks,
+	}
+	return
+}
+
+// repairScrubbedExtent starts to repair the bad blocks of the local replica in the background,
+// unless the extent is being repaired already.
+func (dp *DataPartition) repairScrubbedExtent(req *proto.ScrubExtentRequest) {
+	if _, repairing := dp.scrubRepairing.LoadOrStore(req.ExtentId, true); repairing {
+		return
+	}
+	go func() {
+		defer dp.scrubRepairing.Delete(req.ExtentId)
+		repaired, err := dp.repairScrubbedBlocks(req)
+		atomic.AddUint64(&dp.scrubRepaired, uint64(repaired))
+		exporter.NewCounter(MetricScrubRepairedBlocks).Add(int64(repaired))
+		if err != nil {
+			exporter.Warning(fmt.Sprintf("scrub partition(%v) extent(%v) repair from host(%v) err(%v)",
+				dp.partitionID, req.ExtentId, req.RepairSource, err))
+			return
+		}
+		log.LogInfof("action[repairScrubbedExtent] partition(%v) extent(%v) blocks(%v) repaired from host(%v)",
+			dp.partitionID, req.ExtentId, req.RepairBlocks, req.RepairSource)
+	}()
+}
+
+// repairScrubbedBlocks overwrites each bad block with that of the healthy replica, leaving the rest of the extent intact.
+func (dp *DataPartition) repairScrubbedBlocks(req *proto.ScrubExtentRequest) (repaired int, err error) {
+	if len(req.RepairBlocks) != len(req.RepairCrcs) {
+		return 0, storage.ParameterMismatchError
+	}
+	for i, blockNo := range req.RepairBlocks {
+		var ei *storage.ExtentInfo
+		if ei, err = dp.extentStore.Watermark(req.ExtentId); err != nil {
+			return
+		}
+		offset := blockNo * util.BlockSize
+		if uint64(offset) >= ei.Size {
+			continue
+		}
+		var data []byte
+		if data, err = dp.readRemoteRange(req.RepairSource, req.ExtentId, offset, util.Min(util.BlockSize, int(ei.Size)-offset)); err != nil {
+			return
+		}
+		if err = dp.extentStore.RepairBlock(req.ExtentId, blockNo, data, req.RepairCrcs[i]); err != nil {
+			return
+		}
+		repaired++
+	}
+	return
+}
+
+// readRemoteRange reads the data of the normal extent in the range from the replica.
+func (dp *DataPartition) readRemoteRange(source string, extentID uint64, offset, size int) (data []byte, err error) {
+	request := repl.NewExtentRepairReadPacket(dp.partitionID, extentID, offset, size)
+	conn, err := gConnPool.GetConnect(source)
+	if err != nil {
+		return
+	}
+	defer func() {
+		gConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = request.WriteToConn(conn); err != nil {
+		return
+	}
+	data = make([]byte, 0, size)
+	for len(data) < size {
+		reply := repl.NewPacket()
+		if err = reply.ReadFromConn(conn, ScrubExtentTimeout); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk {
+			err = fmt.Errorf("read from host(%v) err(%v)", source, string(reply.Data[:reply.Size]))
+			return
+		}
+		if reply.ReqID != request.ReqID || reply.ExtentID != extentID || reply.Size == 0 ||
+			reply.ExtentOffset != int64(offset+len(data)) || dp.checksum(reply.Data[:reply.Size]) != reply.CRC {
+			err = fmt.Errorf("invalid reply(%v) of request(%v) from host(%v)", reply.GetUniqueLogId(), request.GetUniqueLogId(), source)
+			return
+		}
+		data = append(data, reply.Data[:reply.Size]...)
+	}
+	return
+}
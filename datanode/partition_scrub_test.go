@@ -0,0 +1,42 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestFindScrubBadBlocks(t *testing.T) {
+	results := []*proto.ScrubExtentResponse{
+		{Size: 3, BlockCrcs: []uint32{1, 2, 3}},
+		{Size: 3, BlockCrcs: []uint32{1, 5, 3}},
+		{Size: 3, BlockCrcs: []uint32{1, 2, 4}, BadBlocks: []int{2, 0}},
+	}
+	expected := [][]int{nil, {1}, {0, 2}}
+	if badBlocks := findScrubBadBlocks(results); !reflect.DeepEqual(badBlocks, expected) {
+		t.Fatalf("bad blocks %v, expected %v", badBlocks, expected)
+	}
+
+	// the crc is not compared between the replicas of different sizes
+	results[1].Size = 2
+	results[1].BlockCrcs = []uint32{1, 5}
+	expected = [][]int{nil, nil, {0, 2}}
+	if badBlocks := findScrubBadBlocks(results); !reflect.DeepEqual(badBlocks, expected) {
+		t.Fatalf("bad blocks %v, expected %v", badBlocks, expected)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The normal extents up to the shared extent id are shared with the cloned volumes. Each cloned volume refers to
+// the extents up to the extent id of its range, and deletes them from the meta partitions of the range, while the
+// owner volume deletes them from the other meta partitions. A shared extent is deleted once the owner and all the
+// cloned volumes referring to it have deleted it. The data of the tiny extents below the shared sizes is never
+// deleted, as the tiny extents are shared by the files of all the volumes.
+
+func (dp *DataPartition) sharedExtentID() uint64 {
+	return atomic.LoadUint64(&dp.config.SharedExtentID)
+}
+
+// isSharingFrozen returns if the volume is being cloned, when no extents are modified or deleted.
+func (dp *DataPartition) isSharingFrozen() bool {
+	return dp.sharedExtentID() == math.MaxUint64
+}
+
+// isSharedExtent returns if the normal extent is shared by the cloned volumes, which is not modified any more.
+func (dp *DataPartition) isSharedExtent(extentID uint64) bool {
+	return !storage.IsTinyExtent(extentID) && extentID <= dp.sharedExtentID()
+}
+
+// isSharedTinyData returns if the data of the tiny extent at the offset is shared by the cloned volumes.
+func (dp *DataPartition) isSharedTinyData(extentID, offset uint64) bool {
+	dp.shareLock.RLock()
+	defer dp.shareLock.RUnlock()
+	return offset < dp.config.SharedTinySizes[extentID]
+}
+
+// hasSharedTinyData returns if any data of the tiny extent is shared by the cloned volumes.
+func (dp *DataPartition) hasSharedTinyData(extentID uint64) bool {
+	dp.shareLock.RLock()
+	defer dp.shareLock.RUnlock()
+	return dp.config.SharedTinySizes[extentID] > 0
+}
+
+// checkDeleteExtent returns if the data of the extent at the offset can be deleted on the request of the meta
+// partition. The deletion of a shared normal extent is recorded, and the extent is deleted once released.
+func (dp *DataPartition) checkDeleteExtent(extentID, offset, metaPartitionID uint64) (ok bool, err error) {
+	if dp.isSharingFrozen() {
+		err = errors.NewErrorf("partition(%v) is being cloned, try again later", dp.partitionID)
+		return
+	}
+	if storage.IsTinyExtent(extentID) {
+		return !dp.isSharedTinyData(extentID, offset), nil
+	}
+	if !dp.isSharedExtent(extentID) || !dp.extentStore.HasExtent(extentID) {
+		return true, nil
+	}
+	return dp.markDeleteSharedExtent(extentID, metaPartitionID)
+}
+
+// markDeleteSharedExtent records the deletion of the shared normal extent by the meta partition, and returns
+// if the extent is released by all the volumes referring to it.
+func (dp *DataPartition) markDeleteSharedExtent(extentID, metaPartitionID uint64) (released bool, err error) {
+	dp.shareLock.Lock()
+	deleters := dp.sharedDeletes[extentID]
+	if !containsID(deleters, metaPartitionID) {
+		deleters = append(deleters, metaPartitionID)
+	}
+	if released = isSharedExtentReleased(extentID, deleters, dp.config.SharedRanges); released {
+		delete(dp.sharedDeletes, extentID)
+	} else {
+		dp.sharedDeletes[extentID] = deleters
+	}
+	dp.shareLock.Unlock()
+	if released {
+		// the deletions of the extents not existing any more are dropped on loading
+		return
+	}
+	if err = dp.persistSharedDeletes(); err != nil {
+		return
+	}
+	log.LogInfof("action[markDeleteSharedExtent] partition(%v) extent(%v) deleted by mps(%v)", dp.partitionID, extentID, deleters)
+	return
+}
+
+// isSharedExtentReleased returns if the shared extent is deleted by the owner volume, from a meta partition out of
+// all the ranges, and by each cloned volume with a range covering the extent.
+func isSharedExtentReleased(extentID uint64, deleters []uint64, ranges []*proto.SharedExtentRange) bool {
+	deletedByOwner := false
+	for _, id := range deleters {
+		if !rangesContain(ranges, id) {
+			deletedByOwner = true
+			break
+		}
+	}
+	if !deletedByOwner {
+		return false
+	}
+	for _, r := range ranges {
+		if r.ExtentID < extentID {
+			continue
+		}
+		deletedByClone := false
+		for _, id := range deleters {
+			if containsID(r.MetaPartitionIDs, id) {
+				deletedByClone = true
+				break
+			}
+		}
+		if !deletedByClone {
+			return false
+		}
+	}
+	return true
+}
+
+func rangesContain(ranges []*proto.SharedExtentRange, metaPartitionID uint64) bool {
+	for _, r := range ranges {
+		if containsID(r.MetaPartitionIDs, metaPartitionID) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []uint64, id uint64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// share updates the extents shared with the cloned volumes, and returns the max extent id and the sizes of the tiny
+// extents of the partition. The deletions by the cloned volumes removed are forgotten, and the shared extents
+// released by the update are deleted.
+func (dp *DataPartition) share(req *proto.ShareDataPartitionRequest) (maxExtentID uint64, tinySizes map[uint64]uint64, err error) {
+	dp.shareLock.Lock()
+	removed := make([]uint64, 0)
+	for _, r := range dp.config.SharedRanges {
+		for _, id := range r.MetaPartitionIDs {
+			if !rangesContain(req.SharedRanges, id) {
+				removed = append(removed, id)
+			}
+		}
+	}
+	atomic.StoreUint64(&dp.config.SharedExtentID, req.SharedExtentID)
+	dp.config.SharedRanges = req.SharedRanges
+	dp.config.SharedTinySizes = req.SharedTinySizes
+	dp.shareLock.Unlock()
+	if err = dp.PersistMetadata(); err != nil {
+		return
+	}
+	if !dp.isSharingFrozen() {
+		if err = dp.releaseSharedExtents(removed); err != nil {
+			return
+		}
+	}
+	maxExtentID, _ = dp.extentStore.GetMaxExtentIDAndPartitionSize()
+	tinySizes = make(map[uint64]uint64)
+	for extentID := uint64(storage.TinyExtentStartID); extentID < storage.TinyExtentStartID+storage.TinyExtentCount; extentID++ {
+		if size, e := dp.extentStore.GetTinyExtentOffset(extentID); e == nil && size > 0 {
+			tinySizes[extentID] = uint64(size)
+		}
+	}
+	log.LogInfof("action[share] partition(%v) sharedExtentID(%v) ranges(%v) maxExtentID(%v)",
+		dp.partitionID, req.SharedExtentID, len(req.SharedRanges), maxExtentID)
+	return
+}
+
+// releaseSharedExtents forgets the deletions by the meta partitions removed, and deletes the shared extents released.
+func (dp *DataPartition) releaseSharedExtents(removed []uint64) (err error) {
+	released := make([]uint64, 0)
+	dp.shareLock.Lock()
+	for extentID, deleters := range dp.sharedDeletes {
+		kept := make([]uint64, 0, len(deleters))
+		for _, id := range deleters {
+			if !containsID(removed, id) {
+				kept = append(kept, id)
+			}
+		}
+		switch {
+		case len(kept) == 0 || !dp.extentStore.HasExtent(extentID):
+			delete(dp.sharedDeletes, extentID)
+		case isSharedExtentReleased(extentID, kept, dp.config.SharedRanges):
+			delete(dp.sharedDeletes, extentID)
+			released = append(released, extentID)
+		default:
+			dp.sharedDeletes[extentID] = kept
+		}
+	}
+	dp.shareLock.Unlock()
+	for _, extentID := range released {
+		log.LogInfof("action[releaseSharedExtents] partition(%v) delete the released extent(%v)", dp.partitionID, extentID)
+		dp.extentStore.MarkDelete(extentID, 0, 0)
+		dp.deleteEcExtent(extentID, dp.isLeader)
+	}
+	return dp.persistSharedDeletes()
+}
+
+func (dp *DataPartition) loadSharedDeletes() (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path.Join(dp.Path(), SharedDeletesFileName)); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	deletes := make(map[uint64][]uint64)
+	if err = json.Unmarshal(data, &deletes); err != nil {
+		return
+	}
+	for extentID, deleters := range deletes {
+		if dp.extentStore.HasExtent(extentID) {
+			dp.sharedDeletes[extentID] = deleters
+		}
+	}
+	return
+}
+
+func (dp *DataPartition) persistSharedDeletes() (err error) {
+	dp.sharedDeletesLock.Lock()
+	defer dp.sharedDeletesLock.Unlock()
+	var data []byte
+	dp.shareLock.RLock()
+	data, err = json.Marshal(dp.sharedDeletes)
+	dp.shareLock.RUnlock()
+	if err != nil {
+		return
+	}
+	tmpName := path.Join(dp.Path(), TempSharedDeletesFile)
+	if err = ioutil.WriteFile(tmpName, data, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmpName, path.Join(dp.Path(), SharedDeletesFileName))
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestIsSharedExtentReleased(t *testing.T) {
+	// clone1 refers to the extents up to 2000 from mps 11 and 12, clone2 up to 3000 from mp 21
+	ranges := []*proto.SharedExtentRange{
+		{ExtentID: 2000, MetaPartitionIDs: []uint64{11, 12}},
+		{ExtentID: 3000, MetaPartitionIDs: []uint64{21}},
+	}
+	tests := []struct {
+		name     string
+		extentID uint64
+		deleters []uint64
+		released bool
+	}{
+		{"owner only", 1500, []uint64{1}, false},
+		{"clones only", 1500, []uint64{12, 21}, false},
+		{"owner and a clone", 1500, []uint64{1, 11}, false},
+		{"owner and all the clones", 1500, []uint64{1, 12, 21}, true},
+		{"out of the range of clone1", 2500, []uint64{2, 21}, true},
+		{"out of all the ranges", 3500, []uint64{1}, true},
+	}
+	for _, tt := range tests {
+		if released := isSharedExtentReleased(tt.extentID, tt.deleters, ranges); released != tt.released {
+			t.Errorf("name[%v] released[%v] expected[%v]", tt.name, released, tt.released)
+		}
+	}
+}
+
+func TestCheckDeleteSharedExtent(t *testing.T) {
+	dp := &DataPartition{partitionID: 1, config: &dataPartitionCfg{
+		SharedExtentID:  2000,
+		SharedTinySizes: map[uint64]uint64{1: 4096},
+	}}
+	if dp.isSharedExtent(1) || !dp.isSharedExtent(1500) || dp.isSharedExtent(2500) {
+		t.Errorf("the tiny extents and the extents after the shared extent id are not shared")
+	}
+	tests := []struct {
+		extentID  uint64
+		offset    uint64
+		deletable bool
+	}{
+		{1, 0, false},
+		{1, 4096, true},
+		{2, 0, true},
+	}
+	for _, tt := range tests {
+		deletable, err := dp.checkDeleteExtent(tt.extentID, tt.offset, 1)
+		if err != nil || deletable != tt.deletable {
+			t.Errorf("extent[%v] offset[%v] deletable[%v] err[%v] expected[%v]", tt.extentID, tt.offset, deletable, err, tt.deletable)
+		}
+	}
+	// no data is deleted while the volume is being cloned
+	dp.config.SharedExtentID = math.MaxUint64
+	if _, err := dp.checkDeleteExtent(2, 4096, 1); err == nil {
+		t.Errorf("deleting while being cloned is expected to fail")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+	"golang.org/x/time/rate"
+)
+
+// ioLimiter limits the requests per second and the bandwidth of the I/O requests.
+type ioLimiter struct {
+	sync.RWMutex
+	iopsLimit      uint64
+	bandwidthLimit uint64 // MB/s
+	iops           *rate.Limiter
+	bandwidth      *rate.Limiter
+}
+
+func newIOLimiter() *ioLimiter {
+	return &ioLimiter{
+		iops:      rate.NewLimiter(rate.Inf, 1),
+		bandwidth: rate.NewLimiter(rate.Inf, util.BlockSize),
+	}
+}
+
+func newRateLimiter(limit uint64, minBurst int) *rate.Limiter {
+	if limit == 0 {
+		return rate.NewLimiter(rate.Inf, minBurst)
+	}
+	return rate.NewLimiter(rate.Limit(limit), util.Max(int(limit), minBurst))
+}
+
+// setLimit sets the limits of the requests per second and the bandwidth in MB/s, 0 if unlimited.
+// The limiters are renewed with full tokens if the limits are changed.
+func (l *ioLimiter) setLimit(iops, bandwidth uint64) {
+	l.Lock()
+	defer l.Unlock()
+	if l.iopsLimit != iops {
+		l.iopsLimit = iops
+		l.iops = newRateLimiter(iops, 1)
+	}
+	if l.bandwidthLimit != bandwidth {
+		l.bandwidthLimit = bandwidth
+		l.bandwidth = newRateLimiter(bandwidth*util.MB, util.BlockSize)
+	}
+}
+
+func (l *ioLimiter) reserve(now time.Time, size int) []*rate.Reservation {
+	l.RLock()
+	defer l.RUnlock()
+	return []*rate.Reservation{l.iops.ReserveN(now, 1), l.bandwidth.ReserveN(now, util.Min(size, l.bandwidth.Burst()))}
+}
+
+// allowIO consumes the tokens of all the limiters for a request of the size,
+// or none of them if any limiter is exhausted.
+func allowIO(size int, limiters ...*ioLimiter) bool {
+	now := time.Now()
+	reservations := make([]*rate.Reservation, 0, 2*len(limiters))
+	allowed := true
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		rs := l.reserve(now, size)
+		reservations = append(reservations, rs...)
+		for _, r := range rs {
+			if !r.OK() || r.DelayFrom(now) > 0 {
+				allowed = false
+			}
+		}
+		if !allowed {
+			break
+		}
+	}
+	if !allowed {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	return allowed
+}
+
+// isQosPacket returns if the packet is an I/O request of the clients, which is subject to the I/O limits.
+// The write requests forwarded by the leader have been limited by the leader.
+func isQosPacket(p *repl.Packet) bool {
+	switch p.Opcode {
+	case proto.OpStreamRead, proto.OpRead, proto.OpStreamFollowerRead, proto.OpRandomWrite, proto.OpSyncRandomWrite:
+		return true
+	}
+	return p.IsWriteOperation() && p.IsLeaderPacket()
+}
+
+// checkQos rejects the request exceeding the I/O limits of the volume or the disk with TryAgainError,
+// so that the client retries it later.
+func (s *DataNode) checkQos(p *repl.Packet) (err error) {
+	if !isQosPacket(p) {
+		return
+	}
+	dp := p.Object.(*DataPartition)
+	if allowIO(int(p.Size), s.getVolLimiter(dp.volumeID), dp.disk.qosLimiter) {
+		return
+	}
+	log.LogDebugf("action[checkQos] partition(%v) vol(%v) disk(%v) packet(%v) is throttled",
+		dp.partitionID, dp.volumeID, dp.disk.Path, p.GetUniqueLogId())
+	return fmt.Errorf("%v: the I/O limit of vol(%v) or disk(%v) is exceeded",
+		storage.TryAgainError, dp.volumeID, dp.disk.Path)
+}
+
+func (s *DataNode) getVolLimiter(volName string) *ioLimiter {
+	s.volLimitersLock.RLock()
+	defer s.volLimitersLock.RUnlock()
+	return s.volLimiters[volName]
+}
+
+// updateVolQos updates the I/O limits of the volumes distributed by the master,
+// and the volumes not in volQos become unlimited.
+func (s *DataNode) updateVolQos(volQos map[string]*proto.VolQos) {
+	s.volLimitersLock.Lock()
+	defer s.volLimitersLock.Unlock()
+	limiters := make(map[string]*ioLimiter, len(volQos))
+	for name, qos := range volQos {
+		if !qos.Enabled() {
+			continue
+		}
+		l, ok := s.volLimiters[name]
+		if !ok {
+			l = newIOLimiter()
+		}
+		l.setLimit(qos.IopsLimit, qos.BandwidthLimit)
+		limiters[name] = l
+	}
+	s.volLimiters = limiters
+}
+
+// updateDiskQos sets the I/O limits of every disk, 0 if unlimited.
+func (s *DataNode) updateDiskQos(iops, bandwidth uint64) {
+	for _, d := range s.space.GetDisks() {
+		d.qosLimiter.setLimit(iops, bandwidth)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestAllowIO(t *testing.T) {
+	vol, disk := newIOLimiter(), newIOLimiter()
+	if !allowIO(util.BlockSize, vol, disk, nil) {
+		t.Fatalf("unlimited request is throttled")
+	}
+
+	vol.setLimit(2, 0)
+	disk.setLimit(0, 1)
+	for i := 0; i < 2; i++ {
+		if !allowIO(util.BlockSize, vol, disk) {
+			t.Fatalf("request %v is throttled", i)
+		}
+	}
+	if allowIO(util.BlockSize, vol, disk) {
+		t.Fatalf("request exceeding the iops limit is allowed")
+	}
+
+	// the tokens of the volume are not consumed by the request throttled by the disk
+	vol.setLimit(1000, 0)
+	disk.setLimit(0, 1)
+	if allowIO(util.MB, vol, disk) {
+		t.Fatalf("request exceeding the bandwidth limit is allowed")
+	}
+	disk.setLimit(0, 0)
+	for i := 0; i < 1000; i++ {
+		if !allowIO(util.BlockSize, vol, disk) {
+			t.Fatalf("request %v is throttled", i)
+		}
+	}
+}
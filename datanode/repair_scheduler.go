@@ -0,0 +1,280 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/storage"
+)
+
+// repairTask is the repair of an extent scheduled by the repair scheduler.
+type repairTask struct {
+	dp       *DataPartition
+	extent   *storage.ExtentInfo
+	priority int    // number of the healthy replicas of the partition, the fewer the earlier the task runs
+	seq      uint64 // tasks of the same priority run in the order submitted
+	size     uint64 // size of the data to be repaired estimated on submission
+	wg       *sync.WaitGroup
+}
+
+type repairQueue []*repairTask
+
+func (q repairQueue) Len() int { return len(q) }
+func (q repairQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q repairQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *repairQueue) Push(x interface{}) { *q = append(*q, x.(*repairTask)) }
+func (q *repairQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return t
+}
+
+// RepairStat defines the statistics of the extent repairs on the data node.
+type RepairStat struct {
+	Pending            int    // number of the extents waiting to be repaired
+	Running            int    // number of the extents being repaired
+	PendingBytes       uint64 // size of the data to be repaired by the pending and the running extents
+	RepairedBytes      uint64
+	RepairedExtents    uint64
+	FailedExtents      uint64
+	Yields             uint64 // times the repairs yielded to the client requests
+	NodeBandwidthLimit uint64 // MB/s, 0 if unlimited
+	DiskBandwidthLimit uint64 // MB/s, 0 if unlimited
+	Disks              map[string]*RepairDiskStat
+}
+
+// RepairDiskStat defines the statistics of the extent repairs on a disk.
+type RepairDiskStat struct {
+	Pending       int
+	Running       int
+	RepairedBytes uint64
+	Latency       int64 // average latency of the client requests, in microseconds
+}
+
+// repairScheduler runs the extent repairs of all the partitions on the node with a fixed number of workers.
+// The extents of the partitions with fewer healthy replicas are repaired first, the bandwidth of the repairs
+// is limited on the node and on each disk, and the repairs yield to the client requests while the requests
+// on the disk are slow.
+type repairScheduler struct {
+	sync.Mutex
+	cond          *sync.Cond
+	queue         repairQueue
+	seq           uint64
+	running       map[*repairTask]bool
+	pendingBytes  uint64
+	stopped       bool
+	limiter       *ioLimiter // bandwidth of the repairs on the node
+	nodeLimit     uint64
+	diskLimit     uint64
+	repairedBytes uint64
+	repaired      uint64
+	failed        uint64
+	yields        uint64
+	repairFunc    func(t *repairTask) error
+}
+
+func newRepairScheduler() *repairScheduler {
+	s := &repairScheduler{
+		queue:   make(repairQueue, 0),
+		running: make(map[*repairTask]bool),
+		limiter: newIOLimiter(),
+	}
+	s.cond = sync.NewCond(s)
+	s.repairFunc = func(t *repairTask) error {
+		return t.dp.doStreamExtentFixRepair(t.extent)
+	}
+	return s
+}
+
+func (s *repairScheduler) start(workers int) {
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+}
+
+// stop stops the workers after the running repairs, and drops the pending ones.
+func (s *repairScheduler) stop() {
+	s.Lock()
+	s.stopped = true
+	pending := s.queue
+	s.queue = make(repairQueue, 0)
+	for _, t := range pending {
+		s.pendingBytes -= t.size
+	}
+	s.Unlock()
+	s.cond.Broadcast()
+	for _, t := range pending {
+		t.wg.Done()
+	}
+}
+
+// submit queues the repair of the extent, and wg is done once the extent is repaired.
+func (s *repairScheduler) submit(dp *DataPartition, extent *storage.ExtentInfo, priority int, wg *sync.WaitGroup) {
+	t := &repairTask{dp: dp, extent: extent, priority: priority, wg: wg}
+	if ei, err := dp.extentStore.Watermark(extent.FileID); err == nil && extent.Size > ei.Size {
+		t.size = extent.Size - ei.Size
+	}
+	wg.Add(1)
+	s.Lock()
+	if s.stopped {
+		s.Unlock()
+		wg.Done()
+		return
+	}
+	s.seq++
+	t.seq = s.seq
+	s.pendingBytes += t.size
+	heap.Push(&s.queue, t)
+	s.Unlock()
+	s.cond.Signal()
+}
+
+func (s *repairScheduler) work() {
+	for {
+		s.Lock()
+		for len(s.queue) == 0 && !s.stopped {
+			s.cond.Wait()
+		}
+		if s.stopped {
+			s.Unlock()
+			return
+		}
+		t := heap.Pop(&s.queue).(*repairTask)
+		s.running[t] = true
+		s.Unlock()
+
+		err := s.repairFunc(t)
+
+		s.Lock()
+		delete(s.running, t)
+		s.pendingBytes -= t.size
+		s.Unlock()
+		if err != nil {
+			atomic.AddUint64(&s.failed, 1)
+		} else {
+			atomic.AddUint64(&s.repaired, 1)
+		}
+		t.wg.Done()
+	}
+}
+
+// setLimit sets the bandwidth limits of the repairs on the node and on each disk in MB/s, 0 if unlimited.
+func (s *repairScheduler) setLimit(nodeLimit, diskLimit uint64, disks []*Disk) {
+	s.Lock()
+	s.nodeLimit, s.diskLimit = nodeLimit, diskLimit
+	s.Unlock()
+	s.limiter.setLimit(0, nodeLimit)
+	for _, d := range disks {
+		d.repairLimiter.setLimit(0, diskLimit)
+	}
+}
+
+// throttle is called before the repair writes the data of the size to the disk. It waits while the client
+// requests on the disk are slow, up to RepairMaxYieldTime, and then until the bandwidth limits allow the data.
+func (s *repairScheduler) throttle(d *Disk, size int) {
+	for yield := time.Duration(0); yield < RepairMaxYieldTime && d.latency.average() > RepairYieldLatency; yield += RepairYieldInterval {
+		atomic.AddUint64(&s.yields, 1)
+		time.Sleep(RepairYieldInterval)
+	}
+	for !allowIO(size, s.limiter, d.repairLimiter) {
+		time.Sleep(RepairThrottleInterval)
+	}
+	atomic.AddUint64(&s.repairedBytes, uint64(size))
+	atomic.AddUint64(&d.repairedBytes, uint64(size))
+}
+
+func (s *repairScheduler) stat(disks []*Disk) *RepairStat {
+	stat := &RepairStat{
+		RepairedBytes:   atomic.LoadUint64(&s.repairedBytes),
+		RepairedExtents: atomic.LoadUint64(&s.repaired),
+		FailedExtents:   atomic.LoadUint64(&s.failed),
+		Yields:          atomic.LoadUint64(&s.yields),
+		Disks:           make(map[string]*RepairDiskStat, len(disks)),
+	}
+	for _, d := range disks {
+		stat.Disks[d.Path] = &RepairDiskStat{
+			RepairedBytes: atomic.LoadUint64(&d.repairedBytes),
+			Latency:       int64(d.latency.average() / time.Microsecond),
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	stat.Pending, stat.Running = len(s.queue), len(s.running)
+	stat.PendingBytes = s.pendingBytes
+	stat.NodeBandwidthLimit, stat.DiskBandwidthLimit = s.nodeLimit, s.diskLimit
+	for _, t := range s.queue {
+		if ds, ok := stat.Disks[t.dp.disk.Path]; ok {
+			ds.Pending++
+		}
+	}
+	for t := range s.running {
+		if ds, ok := stat.Disks[t.dp.disk.Path]; ok {
+			ds.Running++
+		}
+	}
+	return stat
+}
+
+// latencyTracker tracks the moving average latency of the client requests.
+type latencyTracker struct {
+	avg     int64 // in nanoseconds
+	updated int64 // unix time in nanoseconds
+}
+
+func (l *latencyTracker) record(cost time.Duration) {
+	for {
+		old := atomic.LoadInt64(&l.avg)
+		avg := int64(cost)
+		if old > 0 {
+			avg = old - old/8 + avg/8
+		}
+		if atomic.CompareAndSwapInt64(&l.avg, old, avg) {
+			break
+		}
+	}
+	atomic.StoreInt64(&l.updated, time.Now().UnixNano())
+}
+
+// average returns the average latency, or 0 if no request has been done recently.
+func (l *latencyTracker) average() time.Duration {
+	if time.Now().UnixNano()-atomic.LoadInt64(&l.updated) > int64(LatencyStaleTime) {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&l.avg))
+}
+
+// repairExtents repairs the extents of the repair task by the repair scheduler and waits for them to finish.
+func (dp *DataPartition) repairExtents(repairTask *DataPartitionRepairTask) {
+	scheduler := dp.disk.space.repairScheduler
+	wg := new(sync.WaitGroup)
+	for _, extentInfo := range repairTask.ExtentsToBeRepaired {
+		if !dp.extentStore.HasExtent(extentInfo.FileID) {
+			continue
+		}
+		scheduler.submit(dp, extentInfo, repairTask.HealthyReplicas, wg)
+	}
+	wg.Wait()
+}
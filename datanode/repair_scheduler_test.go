@@ -0,0 +1,105 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestRepairScheduler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repair_scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := storage.NewExtentStore(dir, 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Disk{Path: "/disk", repairLimiter: newIOLimiter()}
+	dp := &DataPartition{partitionID: 1, disk: d, extentStore: store}
+
+	s := newRepairScheduler()
+	started, release := make(chan bool), make(chan bool)
+	order := make([]uint64, 0)
+	s.repairFunc = func(task *repairTask) error {
+		order = append(order, task.extent.FileID)
+		started <- true
+		<-release
+		return nil
+	}
+	s.start(1)
+	defer s.stop()
+
+	wg := new(sync.WaitGroup)
+	s.submit(dp, &storage.ExtentInfo{FileID: 1, Size: util.MB}, 2, wg)
+	<-started
+	s.submit(dp, &storage.ExtentInfo{FileID: 2, Size: util.MB}, 2, wg)
+	s.submit(dp, &storage.ExtentInfo{FileID: 3, Size: util.MB}, 1, wg)
+	s.submit(dp, &storage.ExtentInfo{FileID: 4, Size: util.MB}, 1, wg)
+
+	stat := s.stat([]*Disk{d})
+	if stat.Pending != 3 || stat.Running != 1 || stat.PendingBytes != 4*util.MB || stat.Disks[d.Path].Pending != 3 {
+		t.Fatalf("unexpected stat %+v disk %+v", stat, stat.Disks[d.Path])
+	}
+	for i := 0; i < 4; i++ {
+		release <- true
+		if i < 3 {
+			<-started
+		}
+	}
+	wg.Wait()
+	// the partitions with fewer healthy replicas are repaired first
+	for i, id := range []uint64{1, 3, 4, 2} {
+		if order[i] != id {
+			t.Fatalf("unexpected repair order %v", order)
+		}
+	}
+	if stat = s.stat(nil); stat.Pending != 0 || stat.PendingBytes != 0 || stat.RepairedExtents != 4 {
+		t.Fatalf("unexpected stat %+v", stat)
+	}
+}
+
+func TestRepairThrottle(t *testing.T) {
+	s := newRepairScheduler()
+	d := &Disk{Path: "/disk", repairLimiter: newIOLimiter()}
+	s.setLimit(0, 1, []*Disk{d})
+
+	start := time.Now()
+	s.throttle(d, util.MB)
+	s.throttle(d, util.MB/2)
+	if cost := time.Since(start); cost < 400*time.Millisecond {
+		t.Fatalf("repair exceeding the disk bandwidth limit is not throttled, cost(%v)", cost)
+	}
+	if stat := s.stat([]*Disk{d}); stat.RepairedBytes != util.MB+util.MB/2 || stat.Disks[d.Path].RepairedBytes != stat.RepairedBytes {
+		t.Fatalf("unexpected stat %+v", stat)
+	}
+
+	// the repair yields to the slow client requests for a while
+	s.setLimit(0, 0, []*Disk{d})
+	d.latency.record(time.Second)
+	start = time.Now()
+	s.throttle(d, util.BlockSize)
+	if time.Since(start) < RepairYieldInterval || s.stat(nil).Yields == 0 {
+		t.Fatalf("repair does not yield to the slow client requests")
+	}
+}
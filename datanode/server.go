@@ -45,6 +45,7 @@ var (
 	ErrNoSpaceToCreatePartition    = errors.New("No disk space to create a data partition")
 	ErrNewSpaceManagerFailed       = errors.New("Creater new space manager failed")
 	ErrGetMasterDatanodeInfoFailed = errors.New("Failed to get datanode info from master")
+	ErrPartitionMigrating          = errors.New("A data partition is being migrated")
 
 	LocalIP, serverPort string
 	gConnPool           = util.NewConnectPool()
@@ -69,10 +70,15 @@ const (
 	ConfigKeyPort          = "port"          // int
 	ConfigKeyMasterAddr    = "masterAddr"    // array
 	ConfigKeyZone          = "zoneName"      // string
+	ConfigKeyRack          = "rack"          // string
+	ConfigKeyHost          = "host"          // string
+	ConfigKeyPowerDomain   = "powerDomain"   // string
 	ConfigKeyDisks         = "disks"         // array
 	ConfigKeyRaftDir       = "raftDir"       // string
 	ConfigKeyRaftHeartbeat = "raftHeartbeat" // string
 	ConfigKeyRaftReplica   = "raftReplica"   // string
+	ConfigKeyCacheDir      = "cacheDir"      // string
+	ConfigKeyCacheSize     = "cacheSize"     // int, in GB
 )
 
 // DataNode defines the structure of a data node.
@@ -80,6 +86,7 @@ type DataNode struct {
 	space           *SpaceManager
 	port            string
 	zoneName        string
+	labels          map[string]string // the rack, the host and the power domain registered on the master
 	clusterID       string
 	localIP         string
 	localServerAddr string
@@ -93,6 +100,15 @@ type DataNode struct {
 	stopC       chan bool
 
 	control common.Control
+
+	volLimiters     map[string]*ioLimiter // I/O limiters of the volumes
+	volLimitersLock sync.RWMutex
+
+	volCompression     map[string]string // compression modes of the volumes
+	volCompressionLock sync.RWMutex
+
+	volQuorumWrite     map[string]bool // volumes writing with quorum
+	volQuorumWriteLock sync.RWMutex
 }
 
 func NewServer() *DataNode {
@@ -196,10 +212,16 @@ func (s *DataNode) parseConfig(cfg *config.Config) (err error) {
 	if s.zoneName == "" {
 		s.zoneName = DefaultZoneName
 	}
+	s.labels = map[string]string{
+		proto.NodeLabelRack:  cfg.GetString(ConfigKeyRack),
+		proto.NodeLabelHost:  cfg.GetString(ConfigKeyHost),
+		proto.NodeLabelPower: cfg.GetString(ConfigKeyPowerDomain),
+	}
 
 	log.LogDebugf("action[parseConfig] load masterAddrs(%v).", MasterClient.Nodes())
 	log.LogDebugf("action[parseConfig] load port(%v).", s.port)
 	log.LogDebugf("action[parseConfig] load zoneName(%v).", s.zoneName)
+	log.LogDebugf("action[parseConfig] load labels(%v).", s.labels)
 	return
 }
 
@@ -213,6 +235,9 @@ func (s *DataNode) startSpaceManager(cfg *config.Config) (err error) {
 	s.space.SetRaftStore(s.raftStore)
 	s.space.SetNodeID(s.nodeID)
 	s.space.SetClusterID(s.clusterID)
+	if err = s.space.startBlockCache(cfg); err != nil {
+		return
+	}
 
 	var wg sync.WaitGroup
 	for _, d := range cfg.GetSlice(ConfigKeyDisks) {
@@ -285,7 +310,7 @@ func (s *DataNode) register(cfg *config.Config) {
 
 			// register this data node on the master
 			var nodeID uint64
-			if nodeID, err = MasterClient.NodeAPI().AddDataNode(fmt.Sprintf("%s:%v", LocalIP, s.port), s.zoneName); err != nil {
+			if nodeID, err = MasterClient.NodeAPI().AddDataNode(fmt.Sprintf("%s:%v", LocalIP, s.port), s.zoneName, s.labels); err != nil {
 				log.LogErrorf("action[registerToMaster] cannot register this node to master[%v] err(%v).",
 					masterAddr, err)
 				timer.Reset(2 * time.Second)
@@ -354,12 +379,14 @@ func (s *DataNode) registerHandler() {
 	http.HandleFunc("/stats", s.getStatAPI)
 	http.HandleFunc("/raftStatus", s.getRaftStatus)
 	http.HandleFunc("/setAutoRepairStatus", s.setAutoRepairStatus)
+	http.HandleFunc("/migratePartition", s.migratePartition)
+	http.HandleFunc("/setAutoDiskBalance", s.setAutoDiskBalance)
 }
 
 func (s *DataNode) startTCPService() (err error) {
 	log.LogInfo("Start: startTCPService")
 	addr := fmt.Sprintf(":%v", s.port)
-	l, err := net.Listen(NetworkProtocol, addr)
+	l, err := util.Listen(NetworkProtocol, addr)
 	log.LogDebugf("action[startTCPService] listen %v address(%v).", NetworkProtocol, addr)
 	if err != nil {
 		log.LogError("failed to listen, err:", err)
@@ -392,10 +419,7 @@ func (s *DataNode) stopTCPService() (err error) {
 func (s *DataNode) serveConn(conn net.Conn) {
 	space := s.space
 	space.Stats().AddConnection()
-	c, _ := conn.(*net.TCPConn)
-	c.SetKeepAlive(true)
-	c.SetNoDelay(true)
-	packetProcessor := repl.NewReplProtocol(c, s.Prepare, s.OperatePacket, s.Post)
+	packetProcessor := repl.NewReplProtocol(conn, s.Prepare, s.OperatePacket, s.Post)
 	packetProcessor.ServerConn()
 }
 
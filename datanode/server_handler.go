@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/storage"
@@ -56,11 +57,13 @@ func (s *DataNode) getDiskAPI(w http.ResponseWriter, r *http.Request) {
 		disks = append(disks, disk)
 	}
 	diskReport := &struct {
-		Disks []interface{} `json:"disks"`
-		Zone  string        `json:"zone"`
+		Disks              []interface{} `json:"disks"`
+		Zone               string        `json:"zone"`
+		MigratingPartition uint64        `json:"migratingPartition"`
 	}{
-		Disks: disks,
-		Zone:  s.zoneName,
+		Disks:              disks,
+		Zone:               s.zoneName,
+		MigratingPartition: atomic.LoadUint64(&s.space.migratingPartition),
 	}
 	s.buildSuccessResp(w, diskReport)
 }
@@ -69,7 +72,13 @@ func (s *DataNode) getStatAPI(w http.ResponseWriter, r *http.Request) {
 	response := &proto.DataNodeHeartbeatResponse{}
 	s.buildHeartBeatResponse(response)
 
-	s.buildSuccessResp(w, response)
+	s.buildSuccessResp(w, &struct {
+		*proto.DataNodeHeartbeatResponse
+		Repair *RepairStat
+	}{
+		DataNodeHeartbeatResponse: response,
+		Repair:                    s.space.repairScheduler.stat(s.space.GetDisks()),
+	})
 }
 
 func (s *DataNode) setAutoRepairStatus(w http.ResponseWriter, r *http.Request) {
@@ -91,6 +100,54 @@ func (s *DataNode) setAutoRepairStatus(w http.ResponseWriter, r *http.Request) {
 	s.buildSuccessResp(w, autoRepair)
 }
 
+func (s *DataNode) migratePartition(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramPartitionID = "id"
+		paramDisk        = "disk"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partitionID, err := strconv.ParseUint(r.FormValue(paramPartitionID), 10, 64)
+	if err != nil {
+		err = fmt.Errorf("parse param %v fail: %v", paramPartitionID, err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	diskPath := r.FormValue(paramDisk)
+	if diskPath == "" {
+		err = fmt.Errorf("lack of param %v", paramDisk)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = s.space.MigratePartition(partitionID, diskPath); err != nil {
+		s.buildFailureResp(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.buildSuccessResp(w, fmt.Sprintf("migrating partition(%v) to disk(%v)", partitionID, diskPath))
+}
+
+func (s *DataNode) setAutoDiskBalance(w http.ResponseWriter, r *http.Request) {
+	const (
+		paramAutoBalance = "autoBalance"
+	)
+	if err := r.ParseForm(); err != nil {
+		err = fmt.Errorf("parse form fail: %v", err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	autoBalance, err := strconv.ParseBool(r.FormValue(paramAutoBalance))
+	if err != nil {
+		err = fmt.Errorf("parse param %v fail: %v", paramAutoBalance, err)
+		s.buildFailureResp(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	AutoDiskBalanceStatus = autoBalance
+	s.buildSuccessResp(w, autoBalance)
+}
+
 func (s *DataNode) getRaftStatus(w http.ResponseWriter, r *http.Request) {
 	const (
 		paramRaftID = "raftID"
@@ -183,6 +240,11 @@ func (s *DataNode) getPartitionAPI(w http.ResponseWriter, r *http.Request) {
 		Replicas             []string              `json:"replicas"`
 		TinyDeleteRecordSize int64                 `json:"tinyDeleteRecordSize"`
 		RaftStatus           *raft.Status          `json:"raftStatus"`
+		CompressedRawSize    uint64                `json:"compressedRawSize"`
+		CompressedSize       uint64                `json:"compressedSize"`
+		CompressionRatio     float64               `json:"compressionRatio"`
+		TinyReclaimableSize  uint64                `json:"tinyReclaimableSize"`
+		CompactingTiny       []uint64              `json:"compactingTinyExtents"`
 	}{
 		VolName:              partition.volumeID,
 		ID:                   partition.partitionID,
@@ -195,6 +257,12 @@ func (s *DataNode) getPartitionAPI(w http.ResponseWriter, r *http.Request) {
 		Replicas:             partition.Replicas(),
 		TinyDeleteRecordSize: tinyDeleteRecordSize,
 		RaftStatus:           partition.raftPartition.Status(),
+		TinyReclaimableSize:  partition.ExtentStore().TinyReclaimableSize(),
+		CompactingTiny:       partition.ExtentStore().CompactingTinyExtents(),
+	}
+	result.CompressedRawSize, result.CompressedSize = partition.ExtentStore().CompressionStat()
+	if result.CompressedSize > 0 {
+		result.CompressionRatio = float64(result.CompressedRawSize) / float64(result.CompressedSize)
 	}
 	s.buildSuccessResp(w, result)
 }
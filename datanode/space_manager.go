@@ -17,10 +17,12 @@ package datanode
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/raftstore"
+	"github.com/chubaofs/chubaofs/storage"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/log"
 	"math"
@@ -42,6 +44,11 @@ type SpaceManager struct {
 	diskList             []string
 	dataNode             *DataNode
 	createPartitionMutex sync.RWMutex
+	migratingPartition   uint64            // id of the partition being migrated between the disks
+	diskErrCnt           map[string]uint64 // number of the errors of each disk at the last balance
+	blockCache           *storage.BlockCache
+	blockCacheMetrics    *blockCacheMetrics
+	repairScheduler      *repairScheduler
 }
 
 // NewSpaceManager creates a new space manager.
@@ -51,9 +58,12 @@ func NewSpaceManager(dataNode *DataNode) *SpaceManager {
 	space.disks = make(map[string]*Disk)
 	space.diskList = make([]string, 0)
 	space.partitions = make(map[uint64]*DataPartition)
+	space.diskErrCnt = make(map[string]uint64)
 	space.stats = NewStats(dataNode.zoneName)
 	space.stopC = make(chan bool, 0)
 	space.dataNode = dataNode
+	space.repairScheduler = newRepairScheduler()
+	space.repairScheduler.start(RepairWorkerCount)
 
 	go space.statUpdateScheduler()
 
@@ -64,6 +74,7 @@ func (manager *SpaceManager) Stop() {
 	defer func() {
 		recover()
 	}()
+	manager.repairScheduler.stop()
 	close(manager.stopC)
 }
 
@@ -191,7 +202,7 @@ func (manager *SpaceManager) updateMetrics() {
 		remainingCapacityToCreatePartition, maxCapacityToCreatePartition, partitionCnt)
 }
 
-func (manager *SpaceManager) minPartitionCnt() (d *Disk) {
+func (manager *SpaceManager) minPartitionCnt(excludedDisks []string) (d *Disk) {
 	manager.diskMutex.Lock()
 	defer manager.diskMutex.Unlock()
 	var (
@@ -200,7 +211,7 @@ func (manager *SpaceManager) minPartitionCnt() (d *Disk) {
 	)
 	minWeight = math.MaxFloat64
 	for _, disk := range manager.disks {
-		if disk.Available <= 5*util.GB || disk.Status != proto.ReadWrite {
+		if disk.Available <= 5*util.GB || disk.Status != proto.ReadWrite || isExcludedDisk(disk.Path, excludedDisks) {
 			continue
 		}
 		diskWeight := disk.getSelectWeight()
@@ -218,15 +229,30 @@ func (manager *SpaceManager) minPartitionCnt() (d *Disk) {
 	d = minWeightDisk
 	return d
 }
+
+func isExcludedDisk(path string, excludedDisks []string) bool {
+	for _, excluded := range excludedDisks {
+		if excluded == path {
+			return true
+		}
+	}
+	return false
+}
+
 func (manager *SpaceManager) statUpdateScheduler() {
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
+		balanceTicker := time.NewTicker(DiskBalanceInterval * time.Minute)
 		for {
 			select {
 			case <-ticker.C:
 				manager.updateMetrics()
+				manager.updateBlockCacheMetrics()
+			case <-balanceTicker.C:
+				manager.balanceDisks()
 			case <-manager.stopC:
 				ticker.Stop()
+				balanceTicker.Stop()
 				return
 			}
 		}
@@ -258,14 +284,18 @@ func (manager *SpaceManager) CreatePartition(request *proto.CreateDataPartitionR
 	manager.partitionMutex.Lock()
 	defer manager.partitionMutex.Unlock()
 	dpCfg := &dataPartitionCfg{
-		PartitionID:   request.PartitionId,
-		VolName:       request.VolumeId,
-		Peers:         request.Members,
-		Hosts:         request.Hosts,
-		RaftStore:     manager.raftStore,
-		NodeID:        manager.nodeID,
-		ClusterID:     manager.clusterID,
-		PartitionSize: request.PartitionSize,
+		PartitionID:     request.PartitionId,
+		VolName:         request.VolumeId,
+		Peers:           request.Members,
+		Hosts:           request.Hosts,
+		Checksum:        request.Checksum,
+		SharedExtentID:  request.SharedExtentID,
+		SharedRanges:    request.SharedRanges,
+		SharedTinySizes: request.SharedTinySizes,
+		RaftStore:       manager.raftStore,
+		NodeID:          manager.nodeID,
+		ClusterID:       manager.clusterID,
+		PartitionSize:   request.PartitionSize,
 	}
 	dp = manager.partitions[dpCfg.PartitionID]
 	if dp != nil {
@@ -274,7 +304,7 @@ func (manager *SpaceManager) CreatePartition(request *proto.CreateDataPartitionR
 		}
 		return
 	}
-	disk := manager.minPartitionCnt()
+	disk := manager.minPartitionCnt(request.ExcludedDisks)
 	if disk == nil {
 		return nil, ErrNoSpaceToCreatePartition
 	}
@@ -319,6 +349,7 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	space := s.space
 	space.RangePartitions(func(partition *DataPartition) bool {
 		leaderAddr, isLeader := partition.IsRaftLeader()
+		compressedRawSize, compressedSize := partition.ExtentStore().CompressionStat()
 		vr := &proto.PartitionReport{
 			VolName:         partition.volumeID,
 			PartitionID:     uint64(partition.partitionID),
@@ -329,6 +360,17 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 			IsLeader:        isLeader,
 			ExtentCount:     partition.GetExtentCount(),
 			NeedCompare:     true,
+			ScrubTime:       atomic.LoadInt64(&partition.lastScrubTime),
+			ScrubBadBlocks:  atomic.LoadUint64(&partition.scrubBadBlocks),
+			ScrubRepaired:   atomic.LoadUint64(&partition.scrubRepaired),
+
+			CompressedRawSize: compressedRawSize,
+			CompressedSize:    compressedSize,
+
+			TinyReclaimableSize:   partition.ExtentStore().TinyReclaimableSize(),
+			CompactingTinyExtents: partition.relocatingTinyExtents(),
+
+			ChecksumMismatches: atomic.LoadUint64(&partition.checksumMismatches),
 		}
 		log.LogDebugf("action[Heartbeats] dpid(%v), status(%v) total(%v) used(%v) leader(%v) isLeader(%v).", vr.PartitionID, vr.PartitionStatus, vr.Total, vr.Used, leaderAddr, vr.IsLeader)
 		response.PartitionReports = append(response.PartitionReports, vr)
@@ -336,9 +378,16 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	})
 
 	disks := space.GetDisks()
+	response.DiskReports = make([]*proto.DiskReport, 0, len(disks))
 	for _, d := range disks {
 		if d.Status == proto.Unavailable {
 			response.BadDisks = append(response.BadDisks, d.Path)
 		}
+		response.DiskReports = append(response.DiskReports, &proto.DiskReport{
+			Path:        d.Path,
+			Status:      d.Status,
+			ReadErrCnt:  atomic.LoadUint64(&d.ReadErrCnt),
+			WriteErrCnt: atomic.LoadUint64(&d.WriteErrCnt),
+		})
 	}
 }
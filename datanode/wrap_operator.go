@@ -37,7 +37,7 @@ import (
 	raftProto "github.com/tiglabs/raft/proto"
 )
 
-func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
+func (s *DataNode) OperatePacket(p *repl.Packet, c net.Conn) (err error) {
 	sz := p.Size
 	tpObject := exporter.NewTPCnt(p.GetOpMsg())
 	start := time.Now().UnixNano()
@@ -64,6 +64,9 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		}
 		p.Size = resultSize
 		tpObject.Set(err)
+		if dp, ok := p.Object.(*DataPartition); ok && isQosPacket(p) {
+			dp.disk.latency.record(time.Duration(time.Now().UnixNano() - start))
+		}
 	}()
 	switch p.Opcode {
 	case proto.OpCreateExtent:
@@ -114,6 +117,22 @@ func (s *DataNode) OperatePacket(p *repl.Packet, c *net.TCPConn) (err error) {
 		s.handlePacketToReadTinyDeleteRecordFile(p, c)
 	case proto.OpBroadcastMinAppliedID:
 		s.handleBroadcastMinAppliedID(p)
+	case proto.OpWriteEcShard:
+		s.handlePacketToWriteEcShard(p)
+	case proto.OpReadEcShard:
+		s.handlePacketToReadEcShard(p)
+	case proto.OpDeleteEcShard:
+		s.handlePacketToDeleteEcShard(p)
+	case proto.OpSyncEcExtents:
+		s.handlePacketToSyncEcExtents(p)
+	case proto.OpScrubExtent:
+		s.handlePacketToScrubExtent(p)
+	case proto.OpCompactTinyExtent:
+		s.handlePacketToCompactTinyExtent(p)
+	case proto.OpConvertDataPartitionToEc:
+		s.handlePacketToConvertDataPartitionToEc(p)
+	case proto.OpShareDataPartition:
+		s.handlePacketToShareDataPartition(p)
 	default:
 		p.PackErrorBody(repl.ErrorUnknownOp.Error(), repl.ErrorUnknownOp.Error()+strconv.Itoa(int(p.Opcode)))
 	}
@@ -211,6 +230,9 @@ func (s *DataNode) handleHeartbeatPacket(p *repl.Packet) {
 		if task.OpCode == proto.OpDataNodeHeartbeat {
 			marshaled, _ := json.Marshal(task.Request)
 			_ = json.Unmarshal(marshaled, request)
+			s.updateVolQos(request.VolQos)
+			s.updateVolCompression(request.VolCompression)
+			s.updateVolQuorumWrite(request.VolQuorumWrite)
 			response.Status = proto.TaskSucceeds
 		} else {
 			response.Status = proto.TaskFailed
@@ -326,18 +348,30 @@ func (s *DataNode) handleMarkDeletePacket(p *repl.Packet, c net.Conn) {
 		}
 	}()
 	partition := p.Object.(*DataPartition)
+	// the packets of the meta nodes carry the id of the meta partition deleting the extent in the kernel offset
+	var deletable bool
 	if p.ExtentType == proto.TinyExtentType {
 		ext := new(proto.TinyExtentDeleteRecord)
 		err = json.Unmarshal(p.Data, ext)
 		if err == nil {
+			if deletable, err = partition.checkDeleteExtent(p.ExtentID, ext.ExtentOffset, p.KernelOffset); err != nil || !deletable {
+				log.LogInfof("handleMarkDeletePacket skip the shared PartitionID(%v)_Extent(%v)_Offset(%v)",
+					p.PartitionID, p.ExtentID, ext.ExtentOffset)
+				return
+			}
 			log.LogInfof("handleMarkDeletePacket Delete PartitionID(%v)_Extent(%v)_Offset(%v)_Size(%v)",
 				p.PartitionID, p.ExtentID, ext.ExtentOffset, ext.Size)
 			partition.ExtentStore().MarkDelete(p.ExtentID, int64(ext.ExtentOffset), int64(ext.Size))
 		}
 	} else {
+		if deletable, err = partition.checkDeleteExtent(p.ExtentID, 0, p.KernelOffset); err != nil || !deletable {
+			log.LogInfof("handleMarkDeletePacket skip the shared PartitionID(%v)_Extent(%v)", p.PartitionID, p.ExtentID)
+			return
+		}
 		log.LogInfof("handleMarkDeletePacket Delete PartitionID(%v)_Extent(%v)",
 			p.PartitionID, p.ExtentID)
 		partition.ExtentStore().MarkDelete(p.ExtentID, 0, 0)
+		partition.deleteEcExtent(p.ExtentID, p.IsForwardPkt())
 	}
 
 	return
@@ -362,9 +396,19 @@ func (s *DataNode) handleBatchMarkDeletePacket(p *repl.Packet, c net.Conn) {
 	store := partition.ExtentStore()
 	if err == nil {
 		for _, ext := range exts {
+			var deletable bool
+			if deletable, err = partition.checkDeleteExtent(ext.ExtentId, ext.ExtentOffset, p.KernelOffset); err != nil {
+				return
+			}
+			if !deletable {
+				continue
+			}
 			DeleteLimiterWait()
 			log.LogInfof(fmt.Sprintf("recive DeleteExtent (%v) from (%v)", ext, c.RemoteAddr().String()))
 			store.MarkDelete(ext.ExtentId, int64(ext.ExtentOffset), int64(ext.Size))
+			if !storage.IsTinyExtent(ext.ExtentId) {
+				partition.deleteEcExtent(ext.ExtentId, p.IsForwardPkt())
+			}
 		}
 	}
 
@@ -408,7 +452,7 @@ func (s *DataNode) handleWritePacket(p *repl.Packet) {
 			}
 			currSize := util.Min(int(size), util.BlockSize)
 			data := p.Data[offset : offset+currSize]
-			crc := crc32.ChecksumIEEE(data)
+			crc := partition.checksum(data)
 			err = store.Write(p.ExtentID, p.ExtentOffset+int64(offset), int64(currSize), data, crc, storage.AppendWriteType, p.IsSyncWrite())
 			partition.checkIsDiskError(err)
 			if err != nil {
@@ -437,6 +481,14 @@ func (s *DataNode) handleRandomWritePacket(p *repl.Packet) {
 		err = raft.ErrNotLeader
 		return
 	}
+	if partition.isSharedExtent(p.ExtentID) || (storage.IsTinyExtent(p.ExtentID) && partition.isSharedTinyData(p.ExtentID, uint64(p.ExtentOffset))) {
+		err = storage.ExtentSharedError
+		return
+	}
+	// the erasure coded extent is written back to the replicas before it is modified
+	if err = partition.restoreEcExtentOnReplicas(p.ExtentID); err != nil {
+		return
+	}
 	err = partition.RandomWriteSubmit(p)
 	if err != nil && strings.Contains(err.Error(), raft.ErrNotLeader.Error()) {
 		err = raft.ErrNotLeader
@@ -508,6 +560,14 @@ func (s *DataNode) extentRepairReadPacket(p *repl.Packet, connect net.Conn, isRe
 	offset := p.ExtentOffset
 	store := partition.ExtentStore()
 
+	// the extent converted into erasure coded shards is read from the shards
+	var ecData []byte
+	if ecInfo := partition.getEcExtent(p.ExtentID); ecInfo != nil && !store.HasExtent(p.ExtentID) {
+		if ecData, err = partition.readEcExtent(ecInfo, offset, int64(needReplySize)); err != nil {
+			return
+		}
+	}
+
 	for {
 		if needReplySize <= 0 {
 			break
@@ -515,7 +575,8 @@ func (s *DataNode) extentRepairReadPacket(p *repl.Packet, connect net.Conn, isRe
 		err = nil
 		reply := repl.NewStreamReadResponsePacket(p.ReqID, p.PartitionID, p.ExtentID)
 		reply.StartT = p.StartT
-		currReadSize := uint32(util.Min(int(needReplySize), util.ReadBlockSize))
+		// align the reads to the blocks, so that the whole blocks are verified against the stored checksums
+		currReadSize := uint32(util.Min(int(needReplySize), util.ReadBlockSize-int(offset%util.ReadBlockSize)))
 		if currReadSize == util.ReadBlockSize {
 			reply.Data, _ = proto.Buffers.Get(util.ReadBlockSize)
 		} else {
@@ -525,8 +586,15 @@ func (s *DataNode) extentRepairReadPacket(p *repl.Packet, connect net.Conn, isRe
 		reply.ExtentOffset = offset
 		p.Size = uint32(currReadSize)
 		p.ExtentOffset = offset
-		reply.CRC, err = store.Read(reply.ExtentID, offset, int64(currReadSize), reply.Data, isRepairRead)
+		if ecData != nil {
+			pos := len(ecData) - int(needReplySize)
+			copy(reply.Data[:currReadSize], ecData[pos:])
+			reply.CRC = partition.checksum(reply.Data[:currReadSize])
+		} else {
+			reply.CRC, err = store.Read(reply.ExtentID, offset, int64(currReadSize), reply.Data, isRepairRead)
+		}
 		partition.checkIsDiskError(err)
+		partition.checkChecksumMismatch(err)
 		tpObject.Set(err)
 		p.CRC = reply.CRC
 		if err != nil {
@@ -562,7 +630,13 @@ func (s *DataNode) handlePacketToGetAllWatermarks(p *repl.Packet) {
 	partition := p.Object.(*DataPartition)
 	store := partition.ExtentStore()
 	if p.ExtentType == proto.NormalExtentType {
-		fInfoList, _, err = store.GetAllWatermarks(storage.NormalExtentFilter())
+		laggingExtents := make([]uint64, 0)
+		if p.Size > 0 {
+			err = json.Unmarshal(p.Data[:p.Size], &laggingExtents)
+		}
+		if err == nil {
+			fInfoList, _, err = store.GetAllWatermarks(storage.NormalExtentWithLaggingFilter(laggingExtents))
+		}
 	} else {
 		extents := make([]uint64, 0)
 		err = json.Unmarshal(p.Data, &extents)
@@ -691,7 +765,7 @@ func (s *DataNode) tinyExtentRepairRead(request *repl.Packet, connect net.Conn)
 	return
 }
 
-func (s *DataNode) handlePacketToReadTinyDeleteRecordFile(p *repl.Packet, connect *net.TCPConn) {
+func (s *DataNode) handlePacketToReadTinyDeleteRecordFile(p *repl.Packet, connect net.Conn) {
 	var (
 		err error
 	)
@@ -767,6 +841,233 @@ func (s *DataNode) handleBroadcastMinAppliedID(p *repl.Packet) {
 	return
 }
 
+// Handle OpWriteEcShard packet.
+func (s *DataNode) handlePacketToWriteEcShard(p *repl.Packet) {
+	var (
+		err error
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionWriteEcShard, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	if crc32.ChecksumIEEE(p.Data[:p.Size]) != p.CRC {
+		err = storage.CrcMismatchError
+		return
+	}
+	err = s.space.writeEcShard(p.PartitionID, p.ExtentID, int(p.KernelOffset), p.Data[:p.Size])
+	return
+}
+
+// Handle OpReadEcShard packet.
+func (s *DataNode) handlePacketToReadEcShard(p *repl.Packet) {
+	var (
+		err  error
+		data []byte
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionReadEcShard, err.Error())
+		} else {
+			p.PacketOkWithBody(data)
+		}
+	}()
+	req := new(proto.EcShardRequest)
+	if err = json.Unmarshal(p.Data[:p.Size], req); err != nil {
+		return
+	}
+	if req.Verify {
+		// the whole shard is verified against its crc while reading
+		_, err = s.space.readEcShard(p.PartitionID, p.ExtentID, req.Index, 0, 0)
+		return
+	}
+	data, err = s.space.readEcShard(p.PartitionID, p.ExtentID, req.Index, req.Offset, req.Size)
+	return
+}
+
+// Handle OpDeleteEcShard packet.
+func (s *DataNode) handlePacketToDeleteEcShard(p *repl.Packet) {
+	var (
+		err error
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionDeleteEcShard, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	req := new(proto.EcShardRequest)
+	if err = json.Unmarshal(p.Data[:p.Size], req); err != nil {
+		return
+	}
+	err = s.space.deleteEcShard(p.PartitionID, p.ExtentID, req.Index)
+	return
+}
+
+// Handle OpSyncEcExtents packet.
+func (s *DataNode) handlePacketToSyncEcExtents(p *repl.Packet) {
+	var (
+		err error
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionSyncEcExtents, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	partition := p.Object.(*DataPartition)
+	infos := make([]*EcExtentInfo, 0)
+	if err = json.Unmarshal(p.Data[:p.Size], &infos); err != nil {
+		return
+	}
+	err = partition.applyEcExtents(infos)
+	return
+}
+
+// Handle OpScrubExtent packet.
+func (s *DataNode) handlePacketToScrubExtent(p *repl.Packet) {
+	var (
+		err  error
+		data []byte
+		resp *proto.ScrubExtentResponse
+		req  = &proto.ScrubExtentRequest{}
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionScrubExtent, err.Error())
+		} else {
+			p.PacketOkWithBody(data)
+		}
+	}()
+	partition := p.Object.(*DataPartition)
+	if err = json.Unmarshal(p.Data[:p.Size], req); err != nil {
+		return
+	}
+	if req.RepairSource != "" {
+		partition.repairScrubbedExtent(req)
+		return
+	}
+	if resp, err = partition.scrubExtentLocally(req.ExtentId); err != nil {
+		return
+	}
+	data, err = json.Marshal(resp)
+	return
+}
+
+// Handle OpCompactTinyExtent packet.
+func (s *DataNode) handlePacketToCompactTinyExtent(p *repl.Packet) {
+	var err error
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionCompactTinyExtent, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+	partition := p.Object.(*DataPartition)
+	err = partition.ExtentStore().ResetTinyExtent(p.ExtentID, p.ExtentOffset)
+	log.LogInfof("action[handlePacketToCompactTinyExtent] partition(%v) extent(%v) size(%v) err(%v)",
+		partition.partitionID, p.ExtentID, p.ExtentOffset, err)
+	return
+}
+
+// Handle OpConvertDataPartitionToEc packet.
+func (s *DataNode) handlePacketToConvertDataPartitionToEc(p *repl.Packet) {
+	var (
+		err          error
+		reqData      []byte
+		isRaftLeader bool
+		req          = &proto.ConvertDataPartitionToEcRequest{}
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionConvertToEc, err.Error())
+		} else {
+			p.PacketOkReply()
+		}
+	}()
+
+	adminTask := &proto.AdminTask{}
+	decode := json.NewDecoder(bytes.NewBuffer(p.Data))
+	decode.UseNumber()
+	if err = decode.Decode(adminTask); err != nil {
+		return
+	}
+	if reqData, err = json.Marshal(adminTask.Request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(reqData, req); err != nil {
+		return
+	}
+	p.AddMesgLog(string(reqData))
+	dp := s.space.Partition(req.PartitionId)
+	if dp == nil {
+		err = fmt.Errorf("partition %v not exsit", req.PartitionId)
+		return
+	}
+	p.PartitionID = req.PartitionId
+
+	isRaftLeader, err = s.forwardToRaftLeader(dp, p)
+	if !isRaftLeader {
+		err = raft.ErrNotLeader
+		return
+	}
+	if int(req.DataNum)+int(req.ParityNum) != len(req.EcHosts) || req.DataNum == 0 || req.ParityNum == 0 {
+		err = fmt.Errorf("partition %v invalid ec config %v+%v hosts(%v)", req.PartitionId, req.DataNum, req.ParityNum, req.EcHosts)
+		return
+	}
+	go dp.convertToEc(req)
+	return
+}
+
+// Handle OpShareDataPartition packet.
+func (s *DataNode) handlePacketToShareDataPartition(p *repl.Packet) {
+	var (
+		err     error
+		reqData []byte
+		data    []byte
+		req     = &proto.ShareDataPartitionRequest{}
+		resp    = &proto.ShareDataPartitionResponse{}
+	)
+	defer func() {
+		if err != nil {
+			p.PackErrorBody(ActionShareDataPartition, err.Error())
+		} else {
+			p.PacketOkWithBody(data)
+		}
+	}()
+
+	adminTask := &proto.AdminTask{}
+	decode := json.NewDecoder(bytes.NewBuffer(p.Data))
+	decode.UseNumber()
+	if err = decode.Decode(adminTask); err != nil {
+		return
+	}
+	if reqData, err = json.Marshal(adminTask.Request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(reqData, req); err != nil {
+		return
+	}
+	p.AddMesgLog(string(reqData))
+	dp := s.space.Partition(req.PartitionId)
+	if dp == nil {
+		err = fmt.Errorf("partition %v not exsit", req.PartitionId)
+		return
+	}
+	p.PartitionID = req.PartitionId
+	resp.PartitionId = req.PartitionId
+	if resp.MaxExtentID, resp.TinySizes, err = dp.share(req); err != nil {
+		return
+	}
+	data, err = json.Marshal(resp)
+	return
+}
+
 // Handle handlePacketToGetAppliedID packet.
 func (s *DataNode) handlePacketToGetAppliedID(p *repl.Packet) {
 	partition := p.Object.(*DataPartition)
@@ -1006,7 +1307,7 @@ func (s *DataNode) handlePacketToDataPartitionTryToLeaderrr(p *repl.Packet) {
 
 func (s *DataNode) forwardToRaftLeader(dp *DataPartition, p *repl.Packet) (ok bool, err error) {
 	var (
-		conn       *net.TCPConn
+		conn       net.Conn
 		leaderAddr string
 	)
 
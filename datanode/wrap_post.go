@@ -27,6 +27,9 @@ func (s *DataNode) Post(p *repl.Packet) error {
 	if p.IsReadOperation() {
 		p.NeedReply = false
 	}
+	if !p.IsErrPacket() && p.HasLaggingFollowers() {
+		p.Object.(*DataPartition).markLaggingExtent(p.ExtentID)
+	}
 	s.cleanupPkt(p)
 	s.addMetrics(p)
 	return nil
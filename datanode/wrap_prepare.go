@@ -17,7 +17,7 @@ package datanode
 import (
 	"encoding/json"
 	"fmt"
-	"hash/crc32"
+	"sync/atomic"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/repl"
@@ -31,7 +31,7 @@ func (s *DataNode) Prepare(p *repl.Packet) (err error) {
 			p.PackErrorBody(repl.ActionPreparePkt, err.Error())
 		}
 	}()
-	if p.IsMasterCommand() {
+	if p.IsMasterCommand() || p.IsEcShardOperation() {
 		return
 	}
 	p.BeforeTp(s.clusterID)
@@ -39,12 +39,16 @@ func (s *DataNode) Prepare(p *repl.Packet) (err error) {
 	if err != nil {
 		return
 	}
+	if err = s.checkPartition(p); err != nil {
+		return
+	}
 	if err = s.checkCrc(p); err != nil {
 		return
 	}
-	if err = s.checkPartition(p); err != nil {
+	if err = s.checkQos(p); err != nil {
 		return
 	}
+	s.checkQuorumWrite(p)
 
 	// For certain packet, we meed to add some additional extent information.
 	if err = s.addExtentInfo(p); err != nil {
@@ -65,8 +69,10 @@ func (s *DataNode) checkCrc(p *repl.Packet) (err error) {
 	if !p.IsWriteOperation() {
 		return
 	}
-	crc := crc32.ChecksumIEEE(p.Data[:p.Size])
+	dp := p.Object.(*DataPartition)
+	crc := dp.checksum(p.Data[:p.Size])
 	if crc != p.CRC {
+		atomic.AddUint64(&dp.checksumMismatches, 1)
 		return storage.CrcMismatchError
 	}
 
@@ -110,6 +110,9 @@ func Clean(opt string) error {
 	var metaConfig = &meta.MetaConfig{
 		Volume:  VolName,
 		Masters: masters,
+		// The administrator cleans the garbage of all the users, which the meta nodes
+		// refuse without a credential if they enforce the POSIX ACL.
+		UserCred: &proto.UserCred{},
 	}
 
 	gMetaWrapper, err = meta.NewMetaWrapper(metaConfig)
@@ -115,21 +115,14 @@ func (sender *AdminTaskManager) doSendTasks() {
 	sender.sendTasks(tasks)
 }
 
-func (sender *AdminTaskManager) getConn() (conn *net.TCPConn, err error) {
+func (sender *AdminTaskManager) getConn() (conn net.Conn, err error) {
 	if useConnPool {
 		return sender.connPool.GetConnect(sender.targetAddr)
 	}
-	var connect net.Conn
-	connect, err = net.Dial("tcp", sender.targetAddr)
-	if err == nil {
-		conn = connect.(*net.TCPConn)
-		conn.SetKeepAlive(true)
-		conn.SetNoDelay(true)
-	}
-	return
+	return util.DailTimeOut(sender.targetAddr, connectTimeout*time.Second)
 }
 
-func (sender *AdminTaskManager) putConn(conn *net.TCPConn, forceClose bool) {
+func (sender *AdminTaskManager) putConn(conn net.Conn, forceClose bool) {
 	if useConnPool {
 		sender.connPool.PutConnect(conn, forceClose)
 	}
@@ -195,6 +188,11 @@ func (sender *AdminTaskManager) sendAdminTask(task *proto.AdminTask, conn net.Co
 }
 
 func (sender *AdminTaskManager) syncSendAdminTask(task *proto.AdminTask) (packet *proto.Packet, err error) {
+	return sender.syncSendAdminTaskWithDeadline(task, proto.SyncSendTaskDeadlineTime)
+}
+
+// syncSendAdminTaskWithDeadline waits for the response in seconds of the deadline, for the tasks taking long.
+func (sender *AdminTaskManager) syncSendAdminTaskWithDeadline(task *proto.AdminTask, deadline int) (packet *proto.Packet, err error) {
 	log.LogInfof("action[syncSendAdminTask],task[%v]", task)
 	packet, err = sender.buildPacket(task)
 	if err != nil {
@@ -214,7 +212,7 @@ func (sender *AdminTaskManager) syncSendAdminTask(task *proto.AdminTask) (packet
 	if err = packet.WriteToConn(conn); err != nil {
 		return nil, errors.Trace(err, "action[syncSendAdminTask],WriteToConn failed,task:%v,reqID[%v]", task.ID, packet.ReqID)
 	}
-	if err = packet.ReadFromConn(conn, proto.SyncSendTaskDeadlineTime); err != nil {
+	if err = packet.ReadFromConn(conn, deadline); err != nil {
 		return nil, errors.Trace(err, "action[syncSendAdminTask],ReadFromConn failed task:%v,reqID[%v]", task.ID, packet.ReqID)
 	}
 	if packet.ResultCode != proto.OpOk {
@@ -0,0 +1,254 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// apiPermission is the permission required by a group of the APIs if the admin authentication is enabled.
+type apiPermission uint8
+
+const (
+	apiPermPublic apiPermission = iota // views, and the APIs called by the clients and the nodes
+	apiPermUser                        // any user, on the resources of its own
+	apiPermAdmin                       // users of the admin type and the root
+	apiPermRoot                        // the root only
+)
+
+var (
+	// management of the cluster and the raft group of the masters
+	clusterAPIs = []string{
+		proto.AdminClusterFreeze,
+		proto.AddRaftNode,
+		proto.RemoveRaftNode,
+		proto.AdminSetNodeInfo,
+		proto.AdminSetMetaNodeThreshold,
+		proto.UpdateZone,
+		proto.AdminSetRebalance,
+		proto.AdminSetAutoDecommission,
+	}
+
+	// the records of the operations and the backup of the metadata, not audited as the views
+	auditAPIs = []string{
+		proto.AdminListAuditRecords,
+		proto.AdminBackupMetadata,
+	}
+
+	// the APIs authenticated even if the admin authentication is off, as they expose the secret keys of the users
+	signedAPIs = []string{
+		proto.AdminBackupMetadata,
+	}
+
+	// management of the volumes and the partitions
+	volumeAPIs = []string{
+		proto.AdminCreateVol,
+		proto.AdminDeleteVol,
+		proto.AdminUpdateVol,
+		proto.AdminRenameVol,
+		proto.AdminCloneVol,
+		proto.AdminVolShrink,
+		proto.AdminVolExpand,
+		proto.AdminCreateMetaPartition,
+		proto.AdminLoadMetaPartition,
+		proto.AdminDecommissionMetaPartition,
+		proto.AdminAddMetaReplica,
+		proto.AdminDeleteMetaReplica,
+		proto.AdminCreateDataPartition,
+		proto.AdminLoadDataPartition,
+		proto.AdminDecommissionDataPartition,
+		proto.AdminAddDataReplica,
+		proto.AdminDeleteDataReplica,
+		proto.AdminConvertDataPartitionToEc,
+		proto.TokenAddURI,
+		proto.TokenDelURI,
+		proto.TokenUpdateURI,
+	}
+
+	// management of the data nodes and the meta nodes
+	nodeAPIs = []string{
+		proto.DecommissionMetaNode,
+		proto.DecommissionDataNode,
+		proto.DecommissionDisk,
+		proto.AdminUpdateMetaNode,
+		proto.AdminUpdateDataNode,
+		proto.AdminCancelJob,
+	}
+
+	// management of the users
+	userAPIs = []string{
+		proto.UserCreate,
+		proto.UserDelete,
+		proto.UserUpdate,
+		proto.UserUpdatePolicy,
+		proto.UserRemovePolicy,
+		proto.UserDeleteVolPolicy,
+		proto.UserList,
+		proto.UserTransferVol,
+		proto.UserUpdateQuota,
+		proto.UsersOfVol,
+	}
+
+	// APIs the users call on themselves, and the graphql APIs checking the permissions of the users on their own
+	selfAPIs = []string{
+		proto.UserGetAKInfo,
+		proto.UserGetInfo,
+		proto.AdminClusterAPI,
+		proto.AdminUserAPI,
+		proto.AdminVolumeAPI,
+	}
+
+	apiPermissions = newAPIPermissions()
+)
+
+// newAPIPermissions maps the paths of the APIs to the permissions required, the APIs not listed are public.
+func newAPIPermissions() map[string]apiPermission {
+	perms := make(map[string]apiPermission)
+	for _, group := range [][]string{clusterAPIs, auditAPIs} {
+		for _, path := range group {
+			perms[path] = apiPermRoot
+		}
+	}
+	for _, group := range [][]string{volumeAPIs, nodeAPIs, userAPIs} {
+		for _, path := range group {
+			perms[path] = apiPermAdmin
+		}
+	}
+	for _, path := range selfAPIs {
+		perms[path] = apiPermUser
+	}
+	return perms
+}
+
+func isAdminUser(userInfo *proto.UserInfo) bool {
+	return userInfo.UserType == proto.UserTypeRoot || userInfo.UserType == proto.UserTypeAdmin
+}
+
+// authenticate verifies the signature of the request by the secret key of the user and the permission of the user
+// on the API, if the admin authentication is enabled or the API is always signed. The request returned carries the
+// user in its context.
+func (m *Server) authenticate(r *http.Request) (req *http.Request, err error) {
+	perm := apiPermissions[r.URL.Path]
+	if perm == apiPermPublic || (!m.config.adminAuth && !contains(signedAPIs, r.URL.Path)) {
+		return r, nil
+	}
+	ak := r.Header.Get(proto.AdminAuthAccessKey)
+	timestamp := r.Header.Get(proto.AdminAuthTimestamp)
+	signature := r.Header.Get(proto.AdminAuthSignature)
+	nonce := r.Header.Get(proto.AdminAuthNonce)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if ak == "" || signature == "" || nonce == "" || err != nil {
+		return nil, proto.ErrInvalidSignature
+	}
+	now := time.Now().Unix()
+	if skew := now - signedAt; skew > proto.AdminAuthMaxTimeSkew || skew < -proto.AdminAuthMaxTimeSkew {
+		return nil, proto.ErrInvalidSignature
+	}
+	userInfo, err := m.user.getKeyInfo(ak)
+	if err != nil {
+		return nil, proto.ErrInvalidAccessKey
+	}
+	var body []byte
+	if r.Body != nil {
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, proto.ErrReadBodyError
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	expected := proto.SignAdminRequest(userInfo.SecretKey, r.Method, r.URL.Path, r.URL.Query(), body, timestamp, nonce)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, proto.ErrInvalidSignature
+	}
+	if !m.nonces.add(ak+keySeparator+nonce, signedAt, now) {
+		log.LogWarnf("action[authenticate] user[%v] replayed the request, path[%v] nonce[%v]", userInfo.UserID, r.URL.Path, nonce)
+		return nil, proto.ErrInvalidSignature
+	}
+	if err = checkAPIPermission(r, perm, userInfo); err != nil {
+		log.LogWarnf("action[authenticate] user[%v] type[%v] denied, path[%v]", userInfo.UserID, userInfo.UserType, r.URL.Path)
+		return
+	}
+	return r.WithContext(context.WithValue(r.Context(), proto.UserInfoKey, userInfo)), nil
+}
+
+func checkAPIPermission(r *http.Request, perm apiPermission, userInfo *proto.UserInfo) error {
+	switch perm {
+	case apiPermRoot:
+		if userInfo.UserType != proto.UserTypeRoot {
+			return proto.ErrNoPermission
+		}
+	case apiPermAdmin:
+		if !isAdminUser(userInfo) {
+			return proto.ErrNoPermission
+		}
+	case apiPermUser:
+		if isAdminUser(userInfo) {
+			return nil
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case proto.UserGetAKInfo:
+			if query.Get(akKey) != userInfo.AccessKey {
+				return proto.ErrNoPermission
+			}
+		case proto.UserGetInfo:
+			if query.Get(userKey) != userInfo.UserID {
+				return proto.ErrNoPermission
+			}
+		}
+	}
+	return nil
+}
+
+// nonceCache keeps the nonces of the signed requests until their signatures expire, so that a signed request
+// is not replayed within the time skew allowed. It is kept in memory on the leader, and the signatures replayed
+// after the leader changes are still rejected once they expire.
+type nonceCache struct {
+	sync.Mutex
+	nonces    map[string]int64 // the time the signature expires by the nonce
+	lastPrune int64
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{nonces: make(map[string]int64)}
+}
+
+// add returns false if the nonce is used by another request signed within the time skew.
+func (nc *nonceCache) add(nonce string, signedAt, now int64) bool {
+	nc.Lock()
+	defer nc.Unlock()
+	if now-nc.lastPrune >= proto.AdminAuthMaxTimeSkew {
+		for key, expireAt := range nc.nonces {
+			if expireAt < now {
+				delete(nc.nonces, key)
+			}
+		}
+		nc.lastPrune = now
+	}
+	if _, ok := nc.nonces[nonce]; ok {
+		return false
+	}
+	nc.nonces[nonce] = signedAt + proto.AdminAuthMaxTimeSkew
+	return true
+}
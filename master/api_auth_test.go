@@ -0,0 +1,177 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newAuthTestServer() *Server {
+	u := &User{}
+	for _, info := range []*proto.UserInfo{
+		{UserID: "root", AccessKey: "rootak", SecretKey: "rootsk", UserType: proto.UserTypeRoot},
+		{UserID: "admin", AccessKey: "adminak", SecretKey: "adminsk", UserType: proto.UserTypeAdmin},
+		{UserID: "user", AccessKey: "userak", SecretKey: "usersk", UserType: proto.UserTypeNormal},
+	} {
+		u.userStore.Store(info.UserID, info)
+		u.AKStore.Store(info.AccessKey, &proto.AKUser{AccessKey: info.AccessKey, UserID: info.UserID})
+	}
+	cfg := newClusterConfig()
+	cfg.adminAuth = true
+	return &Server{config: cfg, user: u, nonces: newNonceCache()}
+}
+
+func newSignedRequest(method, path string, query url.Values, body []byte, ak, sk string, signedAt int64, nonce string) *http.Request {
+	r := httptest.NewRequest(method, path+"?"+query.Encode(), bytes.NewReader(body))
+	timestamp := strconv.FormatInt(signedAt, 10)
+	r.Header.Set(proto.AdminAuthAccessKey, ak)
+	r.Header.Set(proto.AdminAuthTimestamp, timestamp)
+	r.Header.Set(proto.AdminAuthNonce, nonce)
+	r.Header.Set(proto.AdminAuthSignature, proto.SignAdminRequest(sk, method, path, query, body, timestamp, nonce))
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	m := newAuthTestServer()
+	now := time.Now().Unix()
+	query := url.Values{nameKey: {"vol"}}
+	body := []byte("body")
+
+	r := newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now, "n1")
+	authReq, err := m.authenticate(r)
+	if err != nil {
+		t.Fatalf("authenticate err(%v)", err)
+	}
+	if userInfo, ok := authReq.Context().Value(proto.UserInfoKey).(*proto.UserInfo); !ok || userInfo.UserID != "admin" {
+		t.Fatalf("unexpected user in the context(%v)", authReq.Context().Value(proto.UserInfoKey))
+	}
+	// the body is still readable by the handler
+	if data, _ := ioutil.ReadAll(authReq.Body); !bytes.Equal(data, body) {
+		t.Fatalf("unexpected body(%s)", data)
+	}
+
+	// the replayed request is rejected
+	r = newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now, "n1")
+	if _, err = m.authenticate(r); err != proto.ErrInvalidSignature {
+		t.Fatalf("expect the replay rejected, err(%v)", err)
+	}
+
+	cases := []struct {
+		name string
+		r    *http.Request
+		err  error
+	}{
+		{"wrong secret key", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "rootsk", now, "n2"), proto.ErrInvalidSignature},
+		{"expired", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now-proto.AdminAuthMaxTimeSkew-1, "n3"), proto.ErrInvalidSignature},
+		{"in the future", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now+proto.AdminAuthMaxTimeSkew+1, "n4"), proto.ErrInvalidSignature},
+		{"no nonce", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now, ""), proto.ErrInvalidSignature},
+		{"unknown access key", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "unknown", "adminsk", now, "n5"), proto.ErrInvalidAccessKey},
+		{"normal user on the admin API", newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "userak", "usersk", now, "n6"), proto.ErrNoPermission},
+		{"admin on the root API", newSignedRequest(http.MethodPost, proto.AdminClusterFreeze, nil, nil, "adminak", "adminsk", now, "n7"), proto.ErrNoPermission},
+		{"root on the root API", newSignedRequest(http.MethodPost, proto.AdminClusterFreeze, nil, nil, "rootak", "rootsk", now, "n8"), nil},
+	}
+	for _, c := range cases {
+		if _, err = m.authenticate(c.r); err != c.err {
+			t.Errorf("%v: expect err(%v), got(%v)", c.name, c.err, err)
+		}
+	}
+
+	// the body is signed
+	r = newSignedRequest(http.MethodPost, proto.AdminCreateVol, query, body, "adminak", "adminsk", now, "n9")
+	r.Body = ioutil.NopCloser(bytes.NewReader([]byte("tampered")))
+	if _, err = m.authenticate(r); err != proto.ErrInvalidSignature {
+		t.Fatalf("expect the tampered body rejected, err(%v)", err)
+	}
+
+	// the public APIs and the requests without the admin authentication are not authenticated
+	if _, err = m.authenticate(httptest.NewRequest(http.MethodGet, proto.AdminGetCluster, nil)); err != nil {
+		t.Fatalf("public API err(%v)", err)
+	}
+	m.config.adminAuth = false
+	if _, err = m.authenticate(httptest.NewRequest(http.MethodPost, proto.AdminCreateVol, nil)); err != nil {
+		t.Fatalf("admin auth disabled err(%v)", err)
+	}
+
+	// the backup is signed by the root even without the admin authentication
+	if _, err = m.authenticate(httptest.NewRequest(http.MethodGet, proto.AdminBackupMetadata, nil)); err != proto.ErrInvalidSignature {
+		t.Fatalf("expect the unsigned backup rejected, err(%v)", err)
+	}
+	r = newSignedRequest(http.MethodGet, proto.AdminBackupMetadata, nil, nil, "adminak", "adminsk", now, "n10")
+	if _, err = m.authenticate(r); err != proto.ErrNoPermission {
+		t.Fatalf("expect the backup by the admin rejected, err(%v)", err)
+	}
+	r = newSignedRequest(http.MethodGet, proto.AdminBackupMetadata, nil, nil, "rootak", "rootsk", now, "n11")
+	if _, err = m.authenticate(r); err != nil {
+		t.Fatalf("backup by the root err(%v)", err)
+	}
+}
+
+func TestCheckAPIPermission(t *testing.T) {
+	root := &proto.UserInfo{UserID: "root", AccessKey: "rootak", UserType: proto.UserTypeRoot}
+	admin := &proto.UserInfo{UserID: "admin", AccessKey: "adminak", UserType: proto.UserTypeAdmin}
+	user := &proto.UserInfo{UserID: "user", AccessKey: "userak", UserType: proto.UserTypeNormal}
+	request := func(path string, query url.Values) *http.Request {
+		return httptest.NewRequest(http.MethodGet, path+"?"+query.Encode(), nil)
+	}
+	cases := []struct {
+		name     string
+		r        *http.Request
+		perm     apiPermission
+		userInfo *proto.UserInfo
+		err      error
+	}{
+		{"root on root", request(proto.AdminClusterFreeze, nil), apiPermRoot, root, nil},
+		{"admin on root", request(proto.AdminClusterFreeze, nil), apiPermRoot, admin, proto.ErrNoPermission},
+		{"admin on admin", request(proto.AdminCreateVol, nil), apiPermAdmin, admin, nil},
+		{"root on admin", request(proto.AdminCreateVol, nil), apiPermAdmin, root, nil},
+		{"user on admin", request(proto.AdminCreateVol, nil), apiPermAdmin, user, proto.ErrNoPermission},
+		{"user on its own info", request(proto.UserGetInfo, url.Values{userKey: {"user"}}), apiPermUser, user, nil},
+		{"user on the info of another", request(proto.UserGetInfo, url.Values{userKey: {"admin"}}), apiPermUser, user, proto.ErrNoPermission},
+		{"user on its own key", request(proto.UserGetAKInfo, url.Values{akKey: {"userak"}}), apiPermUser, user, nil},
+		{"user on the key of another", request(proto.UserGetAKInfo, url.Values{akKey: {"adminak"}}), apiPermUser, user, proto.ErrNoPermission},
+		{"admin on the info of another", request(proto.UserGetInfo, url.Values{userKey: {"user"}}), apiPermUser, admin, nil},
+		{"user on public", request(proto.AdminGetCluster, nil), apiPermPublic, user, nil},
+	}
+	for _, c := range cases {
+		if err := checkAPIPermission(c.r, c.perm, c.userInfo); err != c.err {
+			t.Errorf("%v: expect err(%v), got(%v)", c.name, c.err, err)
+		}
+	}
+}
+
+func TestNonceCache(t *testing.T) {
+	nc := newNonceCache()
+	now := time.Now().Unix()
+	if !nc.add("ak_n1", now, now) || nc.add("ak_n1", now, now+1) {
+		t.Fatal("expect the nonce rejected within the time skew")
+	}
+	// the expired nonces are pruned
+	later := now + 2*proto.AdminAuthMaxTimeSkew + 1
+	if !nc.add("ak_n2", later, later) {
+		t.Fatal("expect the new nonce accepted")
+	}
+	if _, ok := nc.nonces["ak_n1"]; ok {
+		t.Fatal("expect the expired nonce pruned")
+	}
+}
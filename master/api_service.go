@@ -193,6 +193,7 @@ func (m *Server) clusterStat(w http.ResponseWriter, r *http.Request) {
 		DataNodeStatInfo: m.cluster.dataNodeStatInfo,
 		MetaNodeStatInfo: m.cluster.metaNodeStatInfo,
 		ZoneStatInfo:     make(map[string]*proto.ZoneStat, 0),
+		Forecasts:        m.cluster.capacityHistory.forecasts(""),
 	}
 	for zoneName, zoneStat := range m.cluster.zoneStatInfos {
 		cs.ZoneStatInfo[zoneName] = zoneStat
@@ -241,13 +242,25 @@ func (m *Server) getIPAddr(w http.ResponseWriter, r *http.Request) {
 	batchCount := atomic.LoadUint64(&m.cluster.cfg.MetaNodeDeleteBatchCount)
 	limitRate := atomic.LoadUint64(&m.cluster.cfg.DataNodeDeleteLimitRate)
 	deleteSleepMs := atomic.LoadUint64(&m.cluster.cfg.MetaNodeDeleteWorkerSleepMs)
+	defragThreshold := atomic.LoadUint64(&m.cluster.cfg.MetaNodeDefragThreshold)
 	autoRepairRate := atomic.LoadUint64(&m.cluster.cfg.DataNodeAutoRepairLimitRate)
+	scrubRate := atomic.LoadUint64(&m.cluster.cfg.DataNodeScrubLimitRate)
+	diskIopsLimit := atomic.LoadUint64(&m.cluster.cfg.DataNodeDiskIopsLimit)
+	diskBandwidthLimit := atomic.LoadUint64(&m.cluster.cfg.DataNodeDiskBandwidthLimit)
+	repairNodeBandwidth := atomic.LoadUint64(&m.cluster.cfg.DataNodeRepairNodeBandwidth)
+	repairDiskBandwidth := atomic.LoadUint64(&m.cluster.cfg.DataNodeRepairDiskBandwidth)
 	cInfo := &proto.ClusterInfo{
 		Cluster:                     m.cluster.Name,
 		MetaNodeDeleteBatchCount:    batchCount,
 		MetaNodeDeleteWorkerSleepMs: deleteSleepMs,
+		MetaNodeDefragThreshold:     defragThreshold,
 		DataNodeDeleteLimitRate:     limitRate,
 		DataNodeAutoRepairLimitRate: autoRepairRate,
+		DataNodeScrubLimitRate:      scrubRate,
+		DataNodeDiskIopsLimit:       diskIopsLimit,
+		DataNodeDiskBandwidthLimit:  diskBandwidthLimit,
+		DataNodeRepairNodeBandwidth: repairNodeBandwidth,
+		DataNodeRepairDiskBandwidth: repairDiskBandwidth,
 		Ip:                          strings.Split(r.RemoteAddr, ":")[0],
 	}
 	sendOkReply(w, r, newSuccessHTTPReply(cInfo))
@@ -381,6 +394,10 @@ func (m *Server) addDataReplica(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = m.cluster.checkDataPlacement(dp, "", addr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
 	if err = m.cluster.addDataReplica(dp, addr); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
@@ -438,6 +455,10 @@ func (m *Server) addMetaReplica(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err = m.cluster.checkMetaPlacement(mp, "", addr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
 	if err = m.cluster.addMetaReplica(mp, addr); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
@@ -502,6 +523,31 @@ func (m *Server) decommissionDataPartition(w http.ResponseWriter, r *http.Reques
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
+func (m *Server) convertDataPartitionToEc(w http.ResponseWriter, r *http.Request) {
+	var (
+		dp          *DataPartition
+		partitionID uint64
+		coldTime    int64
+		err         error
+	)
+
+	if partitionID, coldTime, err = parseRequestToConvertDataPartitionToEc(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if dp, err = m.cluster.getDataPartitionByID(partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataPartitionNotExists))
+		return
+	}
+	if err = m.cluster.convertDataPartitionToEc(dp, coldTime); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	rstMsg := fmt.Sprintf(proto.AdminConvertDataPartitionToEc+" dataPartitionID :%v coldTime:%v ecHosts:%v successfully",
+		partitionID, coldTime, dp.EcHosts)
+	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
+}
+
 func (m *Server) diagnoseDataPartition(w http.ResponseWriter, r *http.Request) {
 	var (
 		err               error
@@ -554,6 +600,10 @@ func (m *Server) markDeleteVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	// the volume may be deleted by the old name, while the policies of the users refer to the current name
+	if vol, e := m.cluster.getVol(name); e == nil {
+		name = vol.Name
+	}
 	if err = m.cluster.markDeleteVol(name, authKey); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
@@ -582,6 +632,14 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		description    string
 		dpSelectorName string
 		dpSelectorParm string
+		ecDataNum      uint8
+		ecParityNum    uint8
+		tierPolicy     proto.TierPolicy
+		qos            proto.VolQos
+		compression    string
+		checksum       string
+		quorumWrite    bool
+		placement      string
 		vol            *Vol
 	)
 
@@ -608,6 +666,42 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	if ecDataNum, ecParityNum, err = parseEcInfoToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if tierPolicy, err = parseTierPolicyToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if qos, err = parseQosToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if compression, err = parseCompressionToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if checksum, err = parseChecksumToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if quorumWrite, err = parseQuorumWriteToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if placement, err = parsePlacementPolicyToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+
+	defer m.cluster.lockUserQuota(vol.Owner)()
+	if capacity > vol.Capacity {
+		if err = m.cluster.checkUserQuota(m.user, vol.Owner, capacity-vol.Capacity, 0); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeUserQuotaExceeded, Msg: err.Error()})
+			return
+		}
+	}
 
 	newArgs := getVolVarargs(vol)
 
@@ -619,6 +713,14 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 	newArgs.enableToken = enableToken
 	newArgs.dpSelectorName = dpSelectorName
 	newArgs.dpSelectorParm = dpSelectorParm
+	newArgs.ecDataNum = ecDataNum
+	newArgs.ecParityNum = ecParityNum
+	newArgs.tierPolicy = tierPolicy
+	newArgs.qos = qos
+	newArgs.compression = compression
+	newArgs.checksum = checksum
+	newArgs.quorumWrite = quorumWrite
+	newArgs.placement = placement
 
 	if err = m.cluster.updateVol(name, authKey, newArgs); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
@@ -650,6 +752,11 @@ func (m *Server) volExpand(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	defer m.cluster.lockUserQuota(vol.Owner)()
+	if err = m.cluster.checkUserQuota(m.user, vol.Owner, uint64(capacity)-vol.Capacity, 0); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeUserQuotaExceeded, Msg: err.Error()})
+		return
+	}
 
 	newArgs := getVolVarargs(vol)
 	newArgs.capacity = uint64(capacity)
@@ -713,18 +820,28 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 		enableToken  bool
 		zoneName     string
 		description  string
+		checksum     string
 	)
 
 	if name, owner, zoneName, description, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken, err = parseRequestToCreateVol(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	if checksum, err = extractChecksum(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
 	if !(dpReplicaNum == 2 || dpReplicaNum == 3) {
 		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", dpReplicaNum)
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if vol, err = m.cluster.createVol(name, owner, zoneName, description, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken); err != nil {
+	defer m.cluster.lockUserQuota(owner)()
+	if err = m.cluster.checkUserQuota(m.user, owner, uint64(capacity), 1); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeUserQuotaExceeded, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.createVol(name, owner, zoneName, description, mpCount, dpReplicaNum, size, capacity, followerRead, authenticate, crossZone, enableToken, checksum); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -737,6 +854,62 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+func (m *Server) renameVol(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		newName string
+		authKey string
+		err     error
+		msg     string
+	)
+	if name, newName, authKey, err = parseRequestToRenameVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.renameVol(m.user, name, newName, authKey); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf("rename vol[%v] to [%v] successfully,from[%v]", name, newName, r.RemoteAddr)
+	log.LogWarn(msg)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
+func (m *Server) cloneVol(w http.ResponseWriter, r *http.Request) {
+	var (
+		name   string
+		source string
+		owner  string
+		vol    *Vol
+		err    error
+		msg    string
+	)
+	if name, source, owner, err = parseRequestToCloneVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(source); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrVolNotExists))
+		return
+	}
+	defer m.cluster.lockUserQuota(owner)()
+	if err = m.cluster.checkUserQuota(m.user, owner, vol.Capacity, 1); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeUserQuotaExceeded, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.cloneVol(source, name, owner); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	if err = m.associateVolWithUser(owner, name); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	msg = fmt.Sprintf("clone vol[%v] from vol[%v] successfully, has [%v] meta partitions", name, source, len(vol.MetaPartitions))
+	log.LogWarn(msg)
+	sendOkReply(w, r, newSuccessHTTPReply(msg))
+}
+
 func (m *Server) getVolSimpleInfo(w http.ResponseWriter, r *http.Request) {
 	var (
 		err     error
@@ -790,7 +963,16 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 		CreateTime:         time.Unix(vol.createTime, 0).Format(proto.TimeFormat),
 		Description:        vol.description,
 		DpSelectorName:     vol.dpSelectorName,
+		EcDataNum:          vol.ecDataNum,
+		EcParityNum:        vol.ecParityNum,
 		DpSelectorParm:     vol.dpSelectorParm,
+		Tier:               vol.tierPolicyView(),
+		IopsLimit:          vol.qos.IopsLimit,
+		BandwidthLimit:     vol.qos.BandwidthLimit,
+		Compression:        vol.compression,
+		Checksum:           vol.checksum,
+		QuorumWrite:        vol.quorumWrite,
+		PlacementPolicy:    vol.placementPolicy,
 	}
 }
 
@@ -798,14 +980,15 @@ func (m *Server) addDataNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		nodeAddr string
 		zoneName string
+		labels   map[string]string
 		id       uint64
 		err      error
 	)
-	if nodeAddr, zoneName, err = parseRequestForAddNode(r); err != nil {
+	if nodeAddr, zoneName, labels, err = parseRequestForAddNode(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if id, err = m.cluster.addDataNode(nodeAddr, zoneName); err != nil {
+	if id, err = m.cluster.addDataNode(nodeAddr, zoneName, labels); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -847,6 +1030,8 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 		NodeSetID:                 dataNode.NodeSetID,
 		PersistenceDataPartitions: dataNode.PersistenceDataPartitions,
 		BadDisks:                  dataNode.BadDisks,
+		Labels:                    dataNode.Labels,
+		DiskReports:               dataNode.DiskReports,
 	}
 
 	sendOkReply(w, r, newSuccessHTTPReply(dataNodeInfo))
@@ -856,6 +1041,7 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 func (m *Server) decommissionDataNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		node        *DataNode
+		job         *decommissionJob
 		rstMsg      string
 		offLineAddr string
 		err         error
@@ -870,11 +1056,11 @@ func (m *Server) decommissionDataNode(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataNodeNotExists))
 		return
 	}
-	if err = m.cluster.decommissionDataNode(node); err != nil {
+	if job, err = m.cluster.decommissionDataNode(node); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	rstMsg = fmt.Sprintf("decommission data node [%v] successfully", offLineAddr)
+	rstMsg = fmt.Sprintf("decommission data node [%v] job[%v] started", offLineAddr, job.ID)
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
@@ -914,6 +1100,49 @@ func (m *Server) setNodeInfoHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if val, ok := params[nodeScrubRateKey]; ok {
+		if v, ok := val.(uint64); ok {
+			if err = m.cluster.setDataNodeScrubLimitRate(v); err != nil {
+				sendErrReply(w, r, newErrHTTPReply(err))
+				return
+			}
+		}
+	}
+
+	_, hasIops := params[nodeDiskIopsLimitKey]
+	_, hasBandwidth := params[nodeDiskBandwidthKey]
+	if hasIops || hasBandwidth {
+		iopsLimit := atomic.LoadUint64(&m.cluster.cfg.DataNodeDiskIopsLimit)
+		bandwidthLimit := atomic.LoadUint64(&m.cluster.cfg.DataNodeDiskBandwidthLimit)
+		if v, ok := params[nodeDiskIopsLimitKey].(uint64); ok {
+			iopsLimit = v
+		}
+		if v, ok := params[nodeDiskBandwidthKey].(uint64); ok {
+			bandwidthLimit = v
+		}
+		if err = m.cluster.setDataNodeDiskQos(iopsLimit, bandwidthLimit); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+	}
+
+	_, hasNodeRepair := params[nodeRepairBandwidthKey]
+	_, hasDiskRepair := params[diskRepairBandwidthKey]
+	if hasNodeRepair || hasDiskRepair {
+		nodeBandwidth := atomic.LoadUint64(&m.cluster.cfg.DataNodeRepairNodeBandwidth)
+		diskBandwidth := atomic.LoadUint64(&m.cluster.cfg.DataNodeRepairDiskBandwidth)
+		if v, ok := params[nodeRepairBandwidthKey].(uint64); ok {
+			nodeBandwidth = v
+		}
+		if v, ok := params[diskRepairBandwidthKey].(uint64); ok {
+			diskBandwidth = v
+		}
+		if err = m.cluster.setDataNodeRepairBandwidth(nodeBandwidth, diskBandwidth); err != nil {
+			sendErrReply(w, r, newErrHTTPReply(err))
+			return
+		}
+	}
+
 	if val, ok := params[nodeDeleteWorkerSleepMs]; ok {
 		if v, ok := val.(uint64); ok {
 			if err = m.cluster.setMetaNodeDeleteWorkerSleepMs(v); err != nil {
@@ -922,6 +1151,14 @@ func (m *Server) setNodeInfoHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if val, ok := params[nodeDefragThresholdKey]; ok {
+		if v, ok := val.(uint64); ok {
+			if err = m.cluster.setMetaNodeDefragThreshold(v); err != nil {
+				sendErrReply(w, r, newErrHTTPReply(err))
+				return
+			}
+		}
+	}
 	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set nodeinfo params %v successfully", params)))
 
 }
@@ -932,11 +1169,150 @@ func (m *Server) getNodeInfoHandler(w http.ResponseWriter, r *http.Request) {
 	resp[nodeDeleteBatchCountKey] = fmt.Sprintf("%v", m.cluster.cfg.MetaNodeDeleteBatchCount)
 	resp[nodeMarkDeleteRateKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeDeleteLimitRate)
 	resp[nodeDeleteWorkerSleepMs] = fmt.Sprintf("%v", m.cluster.cfg.MetaNodeDeleteWorkerSleepMs)
+	resp[nodeDefragThresholdKey] = fmt.Sprintf("%v", m.cluster.cfg.MetaNodeDefragThreshold)
 	resp[nodeAutoRepairRateKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeAutoRepairLimitRate)
+	resp[nodeScrubRateKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeScrubLimitRate)
+	resp[nodeDiskIopsLimitKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeDiskIopsLimit)
+	resp[nodeDiskBandwidthKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeDiskBandwidthLimit)
+	resp[nodeRepairBandwidthKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeRepairNodeBandwidth)
+	resp[diskRepairBandwidthKey] = fmt.Sprintf("%v", m.cluster.cfg.DataNodeRepairDiskBandwidth)
 
 	sendOkReply(w, r, newSuccessHTTPReply(resp))
 }
 
+// Turn on or off the rebalancer, and set the threshold of the imbalance and the max concurrent migrations.
+func (m *Server) setRebalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		err            error
+		paused         bool
+		threshold      float64
+		maxConcurrency uint64
+	)
+	paused, threshold, maxConcurrency = m.cluster.rebalancer.settings()
+	enable := !paused
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if value := r.FormValue(enableKey); value != "" {
+		if enable, err = strconv.ParseBool(value); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(enableKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(thresholdKey); value != "" {
+		if threshold, err = strconv.ParseFloat(value, 64); err != nil || threshold <= 0 || threshold >= 1 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(thresholdKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(maxConcurrencyKey); value != "" {
+		if maxConcurrency, err = strconv.ParseUint(value, 10, 64); err != nil || maxConcurrency == 0 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(maxConcurrencyKey).Error()})
+			return
+		}
+	}
+	if err = m.cluster.setRebalance(enable, threshold, maxConcurrency); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set rebalance enable[%v] threshold[%v] maxConcurrency[%v] successfully",
+		enable, threshold, maxConcurrency)))
+}
+
+// View the settings, the imbalance of the nodes and the migrations of the rebalancer.
+func (m *Server) getRebalance(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getRebalanceView()))
+}
+
+func (m *Server) setAutoDecommission(w http.ResponseWriter, r *http.Request) {
+	var (
+		err            error
+		enable         bool
+		errorThreshold uint64
+		maxConcurrency uint64
+		badDiskLimit   uint64
+	)
+	enable, errorThreshold, maxConcurrency, badDiskLimit = m.cluster.diskFailure.settings()
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if value := r.FormValue(enableKey); value != "" {
+		if enable, err = strconv.ParseBool(value); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(enableKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(errorThresholdKey); value != "" {
+		if errorThreshold, err = strconv.ParseUint(value, 10, 64); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(errorThresholdKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(maxConcurrencyKey); value != "" {
+		if maxConcurrency, err = strconv.ParseUint(value, 10, 64); err != nil || maxConcurrency == 0 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(maxConcurrencyKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(badDiskLimitKey); value != "" {
+		if badDiskLimit, err = strconv.ParseUint(value, 10, 64); err != nil || badDiskLimit == 0 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(badDiskLimitKey).Error()})
+			return
+		}
+	}
+	if err = m.cluster.setAutoDecommission(enable, errorThreshold, maxConcurrency, badDiskLimit); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set auto decommission enable[%v] errorThreshold[%v] maxConcurrency[%v] badDiskLimit[%v] successfully",
+		enable, errorThreshold, maxConcurrency, badDiskLimit)))
+}
+
+func (m *Server) getAutoDecommission(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getAutoDecommissionView()))
+}
+
+func (m *Server) listAuditRecords(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		since int64
+		limit = defaultAuditRecordLimit
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if value := r.FormValue(sinceKey); value != "" {
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(sinceKey).Error()})
+			return
+		}
+	}
+	if value := r.FormValue(limitKey); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(limitKey).Error()})
+			return
+		}
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(m.audit.list(r.FormValue(userKey), r.FormValue(opKey), since, limit)))
+}
+
+// backupMetadata streams the backup of the metadata, which is not wrapped in the HTTPReply. The backup is invalid
+// without the trailer if it fails halfway.
+func (m *Server) backupMetadata(w http.ResponseWriter, r *http.Request) {
+	fileName := fmt.Sprintf("%v_metadata_%v.backup", m.clusterName, time.Now().Format("20060102150405"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", fileName))
+	count, err := m.fsm.backup(w, m.clusterName)
+	if err != nil {
+		log.LogErrorf("action[backupMetadata] remoteAddr[%v] err[%v]", r.RemoteAddr, err)
+		return
+	}
+	log.LogInfof("action[backupMetadata] remoteAddr[%v] %v key-values backed up", r.RemoteAddr, count)
+}
+
 func (m *Server) diagnoseMetaPartition(w http.ResponseWriter, r *http.Request) {
 	var (
 		err               error
@@ -978,11 +1354,10 @@ func (m *Server) diagnoseMetaPartition(w http.ResponseWriter, r *http.Request) {
 func (m *Server) decommissionDisk(w http.ResponseWriter, r *http.Request) {
 	var (
 		node                  *DataNode
+		job                   *decommissionJob
 		rstMsg                string
 		offLineAddr, diskPath string
 		err                   error
-		badPartitionIds       []uint64
-		badPartitions         []*DataPartition
 	)
 
 	if offLineAddr, diskPath, err = parseRequestToDecommissionNode(r); err != nil {
@@ -994,24 +1369,54 @@ func (m *Server) decommissionDisk(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataNodeNotExists))
 		return
 	}
-	badPartitions = node.badPartitions(diskPath, m.cluster)
-	if len(badPartitions) == 0 {
-		rstMsg = fmt.Sprintf("receive decommissionDisk node[%v] no any partitions on disk[%v],offline successfully",
-			node.Addr, diskPath)
-		sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
+	if job, err = m.cluster.decommissionDisk(node, diskPath); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	rstMsg = fmt.Sprintf("receive decommissionDisk node[%v] disk[%v], job[%v] started",
+		node.Addr, diskPath, job.ID)
+	Warn(m.clusterName, rstMsg)
+	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
+}
+
+// List the decommission jobs and their progress.
+func (m *Server) listDecommissionJobs(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.getDecommissionJobViews()))
+}
+
+// Show the decommission job and the states of its partitions.
+func (m *Server) getDecommissionJob(w http.ResponseWriter, r *http.Request) {
+	var (
+		id   uint64
+		info *proto.DecommissionJob
+		err  error
+	)
+	if id, err = parseAndExtractJobID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if info, err = m.cluster.getDecommissionJobInfo(id); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	for _, bdp := range badPartitions {
-		badPartitionIds = append(badPartitionIds, bdp.PartitionID)
+	sendOkReply(w, r, newSuccessHTTPReply(info))
+}
+
+// Cancel the decommission job, the partitions already moved stay on the new nodes.
+func (m *Server) cancelDecommissionJob(w http.ResponseWriter, r *http.Request) {
+	var (
+		id  uint64
+		err error
+	)
+	if id, err = parseAndExtractJobID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
 	}
-	rstMsg = fmt.Sprintf("receive decommissionDisk node[%v] disk[%v], badPartitionIds[%v] has offline successfully",
-		node.Addr, diskPath, badPartitionIds)
-	if err = m.cluster.decommissionDisk(node, diskPath, badPartitions); err != nil {
+	if err = m.cluster.cancelDecommissionJob(id); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	Warn(m.clusterName, rstMsg)
-	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("cancel job[%v] successfully", id)))
 }
 
 // handle tasks such as heartbeat，loadDataPartition，deleteDataPartition, etc.
@@ -1029,14 +1434,15 @@ func (m *Server) addMetaNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		nodeAddr string
 		zoneName string
+		labels   map[string]string
 		id       uint64
 		err      error
 	)
-	if nodeAddr, zoneName, err = parseRequestForAddNode(r); err != nil {
+	if nodeAddr, zoneName, labels, err = parseRequestForAddNode(r); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if id, err = m.cluster.addMetaNode(nodeAddr, zoneName); err != nil {
+	if id, err = m.cluster.addMetaNode(nodeAddr, zoneName, labels); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -1115,6 +1521,7 @@ func (m *Server) getMetaNode(w http.ResponseWriter, r *http.Request) {
 		MetaPartitionCount:        metaNode.MetaPartitionCount,
 		NodeSetID:                 metaNode.NodeSetID,
 		PersistenceMetaPartitions: metaNode.PersistenceMetaPartitions,
+		Labels:                    metaNode.Labels,
 	}
 	sendOkReply(w, r, newSuccessHTTPReply(metaNodeInfo))
 }
@@ -1169,6 +1576,7 @@ func (m *Server) loadMetaPartition(w http.ResponseWriter, r *http.Request) {
 func (m *Server) decommissionMetaNode(w http.ResponseWriter, r *http.Request) {
 	var (
 		metaNode    *MetaNode
+		job         *decommissionJob
 		rstMsg      string
 		offLineAddr string
 		err         error
@@ -1183,11 +1591,11 @@ func (m *Server) decommissionMetaNode(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrMetaNodeNotExists))
 		return
 	}
-	if err = m.cluster.decommissionMetaNode(metaNode); err != nil {
+	if job, err = m.cluster.decommissionMetaNode(metaNode); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
-	rstMsg = fmt.Sprintf("decommissionMetaNode metaNode [%v] has offline successfully", offLineAddr)
+	rstMsg = fmt.Sprintf("decommissionMetaNode metaNode [%v] job[%v] started", offLineAddr, job.ID)
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
@@ -1275,7 +1683,7 @@ func parseRequestForUpdateMetaNode(r *http.Request) (nodeAddr string, id uint64,
 	return
 }
 
-func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, err error) {
+func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, labels map[string]string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
 	}
@@ -1285,6 +1693,14 @@ func parseRequestForAddNode(r *http.Request) (nodeAddr, zoneName string, err err
 	if zoneName = r.FormValue(zoneNameKey); zoneName == "" {
 		zoneName = DefaultZoneName
 	}
+	for _, key := range proto.NodeLabels {
+		if value := r.FormValue(key); value != "" {
+			if labels == nil {
+				labels = make(map[string]string)
+			}
+			labels[key] = value
+		}
+	}
 	return
 }
 
@@ -1295,6 +1711,13 @@ func parseAndExtractNodeAddr(r *http.Request) (nodeAddr string, err error) {
 	return extractNodeAddr(r)
 }
 
+func parseAndExtractJobID(r *http.Request) (id uint64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	return extractNodeID(r)
+}
+
 func parseRequestToDecommissionNode(r *http.Request) (nodeAddr, diskPath string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1380,6 +1803,32 @@ func parseRequestToDeleteVol(r *http.Request) (name, authKey string, err error)
 
 }
 
+func parseRequestToRenameVol(r *http.Request) (name, newName, authKey string, err error) {
+	if name, authKey, err = parseVolNameAndAuthKey(r); err != nil {
+		return
+	}
+	if newName = r.FormValue(newNameKey); newName == "" {
+		err = keyNotFound(newNameKey)
+		return
+	}
+	if !volNameRegexp.MatchString(newName) {
+		err = errors.New("newName can only be number and letters")
+	}
+	return
+}
+
+func parseRequestToCloneVol(r *http.Request) (name, source, owner string, err error) {
+	if name, err = parseAndExtractName(r); err != nil {
+		return
+	}
+	if source = r.FormValue(sourceKey); source == "" {
+		err = keyNotFound(sourceKey)
+		return
+	}
+	owner, err = extractOwner(r)
+	return
+}
+
 func parseRequestToUpdateVol(r *http.Request) (name, authKey, description string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1441,6 +1890,154 @@ func parseDefaultInfoToUpdateVol(r *http.Request, vol *Vol) (zoneName string, ca
 	return
 }
 
+// parseEcInfoToUpdateVol parses the erasure coding config, both of which are 0 to disable erasure coding.
+func parseEcInfoToUpdateVol(r *http.Request, vol *Vol) (ecDataNum, ecParityNum uint8, err error) {
+	ecDataNum, ecParityNum = vol.ecDataNum, vol.ecParityNum
+	dataNumStr, parityNumStr := r.FormValue(ecDataNumKey), r.FormValue(ecParityNumKey)
+	if dataNumStr == "" && parityNumStr == "" {
+		return
+	}
+	if dataNumStr == "" || parityNumStr == "" {
+		err = keyNotFound(ecDataNumKey + " or " + ecParityNumKey)
+		return
+	}
+	var dataNum, parityNum int
+	if dataNum, err = strconv.Atoi(dataNumStr); err != nil {
+		err = unmatchedKey(ecDataNumKey)
+		return
+	}
+	if parityNum, err = strconv.Atoi(parityNumStr); err != nil {
+		err = unmatchedKey(ecParityNumKey)
+		return
+	}
+	if !(dataNum == 0 && parityNum == 0) &&
+		(dataNum < 1 || parityNum < 1 || dataNum+parityNum > maxEcShardNum) {
+		err = fmt.Errorf("ecDataNum[%v] and ecParityNum[%v] must be both 0, or both positive with sum no more than %v",
+			dataNum, parityNum, maxEcShardNum)
+		return
+	}
+	return uint8(dataNum), uint8(parityNum), nil
+}
+
+// parseQosToUpdateVol parses the I/O limits of the volume, the unspecified limits keep the current values.
+func parseQosToUpdateVol(r *http.Request, vol *Vol) (qos proto.VolQos, err error) {
+	qos = vol.qos
+	for key, field := range map[string]*uint64{
+		iopsLimitKey:      &qos.IopsLimit,
+		bandwidthLimitKey: &qos.BandwidthLimit,
+	} {
+		if value := r.FormValue(key); value != "" {
+			if *field, err = strconv.ParseUint(value, 10, 64); err != nil {
+				err = unmatchedKey(key)
+				return
+			}
+		}
+	}
+	return
+}
+
+// parseCompressionToUpdateVol parses the compression mode, "none" to disable compression.
+func parseCompressionToUpdateVol(r *http.Request, vol *Vol) (compression string, err error) {
+	compression = vol.compression
+	value := r.FormValue(compressionKey)
+	if value == "" {
+		return
+	}
+	if compression = value; compression == "none" {
+		compression = proto.CompressionNone
+	}
+	if !proto.IsValidCompression(compression) {
+		err = unmatchedKey(compressionKey)
+	}
+	return
+}
+
+// extractChecksum parses the checksum type of the data, "crc32" for the IEEE CRC32 by default.
+func extractChecksum(r *http.Request) (checksum string, err error) {
+	return parseChecksum(r.FormValue(checksumKey))
+}
+
+func parseChecksum(value string) (checksum string, err error) {
+	if checksum = value; checksum == "crc32" {
+		checksum = proto.ChecksumCRC32
+	}
+	if !proto.IsValidChecksum(checksum) {
+		err = unmatchedKey(checksumKey)
+	}
+	return
+}
+
+// parseChecksumToUpdateVol parses the checksum type of the data partitions created afterwards,
+// the existing data partitions keep their own checksum types.
+func parseChecksumToUpdateVol(r *http.Request, vol *Vol) (checksum string, err error) {
+	if r.FormValue(checksumKey) == "" {
+		return vol.checksum, nil
+	}
+	return extractChecksum(r)
+}
+
+func parseQuorumWriteToUpdateVol(r *http.Request, vol *Vol) (quorumWrite bool, err error) {
+	value := r.FormValue(quorumWriteKey)
+	if value == "" {
+		return vol.quorumWrite, nil
+	}
+	if quorumWrite, err = strconv.ParseBool(value); err != nil {
+		err = unmatchedKey(quorumWriteKey)
+	}
+	return
+}
+
+// parsePlacementPolicyToUpdateVol parses the label of the nodes the replicas are spread across, ``none`` clears
+// the policy. The policy applies to the replicas placed afterwards.
+func parsePlacementPolicyToUpdateVol(r *http.Request, vol *Vol) (policy string, err error) {
+	policy = r.FormValue(placementPolicyKey)
+	switch policy {
+	case "":
+		return vol.placementPolicy, nil
+	case "none":
+		return "", nil
+	}
+	if !isValidPlacementPolicy(policy) {
+		err = unmatchedKey(placementPolicyKey)
+	}
+	return
+}
+
+// parseTierPolicyToUpdateVol parses the tiering policy, the unspecified fields keep the current values.
+func parseTierPolicyToUpdateVol(r *http.Request, vol *Vol) (policy proto.TierPolicy, err error) {
+	policy = vol.tierPolicy
+	if value := r.FormValue(tierColdDaysKey); value != "" {
+		var coldDays uint64
+		if coldDays, err = strconv.ParseUint(value, 10, 32); err != nil {
+			err = unmatchedKey(tierColdDaysKey)
+			return
+		}
+		policy.ColdDays = uint32(coldDays)
+	}
+	if value := r.FormValue(tierRehydrateKey); value != "" {
+		if policy.Rehydrate, err = strconv.ParseBool(value); err != nil {
+			err = unmatchedKey(tierRehydrateKey)
+			return
+		}
+	}
+	for key, field := range map[string]*string{
+		tierEndpointKey:  &policy.Endpoint,
+		tierRegionKey:    &policy.Region,
+		tierBucketKey:    &policy.Bucket,
+		tierAccessKeyKey: &policy.AccessKey,
+		tierSecretKeyKey: &policy.SecretKey,
+	} {
+		if value := r.FormValue(key); value != "" {
+			*field = value
+		}
+	}
+	if policy.ColdDays > 0 && (policy.Endpoint == "" || policy.Bucket == "") {
+		err = fmt.Errorf("%v and %v are required when %v is positive", tierEndpointKey, tierBucketKey, tierColdDaysKey)
+		return
+	}
+	return
+}
+
 func parseBoolFieldToUpdateVol(r *http.Request, vol *Vol) (followerRead, authenticate bool, err error) {
 	if followerReadStr := r.FormValue(followerReadKey); followerReadStr != "" {
 		if followerRead, err = strconv.ParseBool(followerReadStr); err != nil {
@@ -1626,6 +2223,23 @@ func extractDataPartitionID(r *http.Request) (ID uint64, err error) {
 	return strconv.ParseUint(value, 10, 64)
 }
 
+func parseRequestToConvertDataPartitionToEc(r *http.Request) (ID uint64, coldTime int64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if ID, err = extractDataPartitionID(r); err != nil {
+		return
+	}
+	coldTime = defaultEcColdTime
+	if value := r.FormValue(coldTimeKey); value != "" {
+		if coldTime, err = strconv.ParseInt(value, 10, 64); err != nil || coldTime < 0 {
+			err = unmatchedKey(coldTimeKey)
+			return
+		}
+	}
+	return
+}
+
 func parseRequestToDecommissionDataPartition(r *http.Request) (ID uint64, nodeAddr string, err error) {
 	return extractDataPartitionIDAndAddr(r)
 }
@@ -1779,6 +2393,30 @@ func parseAndExtractSetNodeInfoParams(r *http.Request) (params map[string]interf
 		params[nodeAutoRepairRateKey] = val
 	}
 
+	if value = r.FormValue(nodeScrubRateKey); value != "" {
+		noParams = false
+		var val = uint64(0)
+		val, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			err = unmatchedKey(nodeScrubRateKey)
+			return
+		}
+		params[nodeScrubRateKey] = val
+	}
+
+	for _, key := range []string{nodeDiskIopsLimitKey, nodeDiskBandwidthKey, nodeRepairBandwidthKey, diskRepairBandwidthKey} {
+		if value = r.FormValue(key); value != "" {
+			noParams = false
+			var val = uint64(0)
+			val, err = strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				err = unmatchedKey(key)
+				return
+			}
+			params[key] = val
+		}
+	}
+
 	if value = r.FormValue(nodeDeleteWorkerSleepMs); value != "" {
 		noParams = false
 		var val = uint64(0)
@@ -1789,6 +2427,16 @@ func parseAndExtractSetNodeInfoParams(r *http.Request) (params map[string]interf
 		}
 		params[nodeDeleteWorkerSleepMs] = val
 	}
+	if value = r.FormValue(nodeDefragThresholdKey); value != "" {
+		noParams = false
+		var val = uint64(0)
+		val, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			err = unmatchedKey(nodeDefragThresholdKey)
+			return
+		}
+		params[nodeDefragThresholdKey] = val
+	}
 	if noParams {
 		err = keyNotFound(nodeDeleteBatchCountKey)
 		return
@@ -1928,6 +2576,28 @@ func (m *Server) getDataPartitions(w http.ResponseWriter, r *http.Request) {
 	send(w, r, body)
 }
 
+func (m *Server) reportChecksumMismatch(w http.ResponseWriter, r *http.Request) {
+	var (
+		dp          *DataPartition
+		partitionID uint64
+		addr        string
+		err         error
+	)
+	if partitionID, addr, err = extractDataPartitionIDAndAddr(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if dp, err = m.cluster.getDataPartitionByID(partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataPartitionNotExists))
+		return
+	}
+	if err = dp.reportClientChecksumMismatch(addr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("report checksum mismatch of partition[%v] on [%v] successfully", partitionID, addr)))
+}
+
 func (m *Server) getVol(w http.ResponseWriter, r *http.Request) {
 	var (
 		err     error
@@ -102,7 +102,7 @@ func createDefaultMasterServerForTest() *Server {
 	testServer.cluster.checkMetaNodeHeartbeat()
 	time.Sleep(5 * time.Second)
 	testServer.cluster.scheduleToUpdateStatInfo()
-	vol, err := testServer.cluster.createVol(commonVolName, "cfs", testZone2, "", 3, 3, 3, 100, false, false, false, false)
+	vol, err := testServer.cluster.createVol(commonVolName, "cfs", testZone2, "", 3, 3, 3, 100, false, false, false, false, proto.ChecksumCRC32)
 	if err != nil {
 		panic(err)
 	}
@@ -201,6 +201,20 @@ func TestSetMetaNodeThreshold(t *testing.T) {
 	}
 }
 
+func TestSetAutoDecommission(t *testing.T) {
+	reqURL := fmt.Sprintf("%v%v?enable=true&errorThreshold=100&maxConcurrency=2", hostAddr, proto.AdminSetAutoDecommission)
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	defer server.cluster.setAutoDecommission(false, 0, 0, 0)
+	view := server.cluster.getAutoDecommissionView()
+	if !view.Enable || view.ErrorThreshold != 100 || view.MaxConcurrency != 2 || view.BadDiskLimit != defaultBadDiskLimit {
+		t.Errorf("set auto decommission failed, view[%v]", view)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v", hostAddr, proto.AdminGetAutoDecommission)
+	process(reqURL, t)
+}
+
 func TestSetDisableAutoAlloc(t *testing.T) {
 	enable := true
 	reqURL := fmt.Sprintf("%v%v?enable=%v", hostAddr, proto.AdminClusterFreeze, enable)
@@ -670,6 +684,20 @@ func TestCreateUser(t *testing.T) {
 	post(reqURL, data, t)
 }
 
+func TestAuditRecords(t *testing.T) {
+	records := server.audit.list("", proto.UserCreate, 0, 1)
+	if len(records) != 1 || records[0].Params["id"] != testUserID || records[0].Params["sk"] != maskedParam ||
+		records[0].Authenticated {
+		t.Errorf("audit records of the user creation %v", records)
+		return
+	}
+	reqURL := fmt.Sprintf("%v%v?op=%v", hostAddr, proto.AdminListAuditRecords, proto.AdminGetVol)
+	reply := process(reqURL, t)
+	if records, ok := reply.Data.([]interface{}); !ok || len(records) != 0 {
+		t.Errorf("the views are audited, %v", reply.Data)
+	}
+}
+
 func TestGetUser(t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v?user=%v", hostAddr, proto.UserGetInfo, testUserID)
 	fmt.Println(reqURL)
@@ -825,6 +853,56 @@ func TestDeleteVolPolicy(t *testing.T) {
 	}
 }
 
+func TestUserQuota(t *testing.T) {
+	userID := "quotauser"
+	if _, err := server.user.createKey(&proto.UserCreateParam{ID: userID, Type: proto.UserTypeNormal}); err != nil {
+		t.Error(err)
+		return
+	}
+	param := &proto.UserQuotaUpdateParam{UserID: userID, Quota: proto.UserQuota{Capacity: 150, VolCount: 1}}
+	data, err := json.Marshal(param)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	post(fmt.Sprintf("%v%v", hostAddr, proto.UserUpdateQuota), data, t)
+	createURL := "%v%v?name=%v&replicas=3&capacity=100&owner=%v&mpCount=2&zoneName=%v"
+	process(fmt.Sprintf(createURL, hostAddr, proto.AdminCreateVol, "quotaVol1", userID, testZone2), t)
+
+	// the second volume exceeds the volume count, and the expansion exceeds the capacity
+	for _, reqURL := range []string{
+		fmt.Sprintf(createURL, hostAddr, proto.AdminCreateVol, "quotaVol2", userID, testZone2),
+		fmt.Sprintf("%v%v?name=%v&capacity=200&authKey=%v", hostAddr, proto.AdminVolExpand, "quotaVol1", buildAuthKey(userID)),
+	} {
+		resp, err := http.Get(reqURL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		reply := &proto.HTTPReply{}
+		err = json.NewDecoder(resp.Body).Decode(reply)
+		resp.Body.Close()
+		if err != nil || reply.Code != proto.ErrCodeUserQuotaExceeded {
+			t.Errorf("url[%v] reply[%v] err[%v], expect the quota exceeded", reqURL, reply, err)
+		}
+	}
+	if _, err = server.cluster.getVol("quotaVol2"); err == nil {
+		t.Errorf("the vol exceeding the quota is created")
+	}
+	reply := process(fmt.Sprintf("%v%v?user=%v", hostAddr, proto.UserGetInfo, userID), t)
+	data, _ = json.Marshal(reply.Data)
+	userInfo := &proto.UserInfo{}
+	if err = json.Unmarshal(data, userInfo); err != nil {
+		t.Error(err)
+		return
+	}
+	if userInfo.Quota == nil || userInfo.Quota.VolCount != 1 || userInfo.Usage == nil ||
+		userInfo.Usage.VolCount != 1 || userInfo.Usage.Capacity != 100 {
+		t.Errorf("user info quota[%v] usage[%v]", userInfo.Quota, userInfo.Usage)
+	}
+	process(fmt.Sprintf("%v%v?name=%v&authKey=%v", hostAddr, proto.AdminDeleteVol, "quotaVol1", buildAuthKey(userID)), t)
+}
+
 func TestListUser(t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v?keywords=%v", hostAddr, proto.UserList, "test")
 	fmt.Println(reqURL)
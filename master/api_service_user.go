@@ -116,12 +116,36 @@ func (m *Server) getUserInfo(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
+	sendOkReply(w, r, newSuccessHTTPReply(&userInfoWithUsage{UserInfo: userInfo, Usage: m.cluster.getUserUsage(userID)}))
+}
+
+func (m *Server) updateUserQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		userInfo *proto.UserInfo
+		bytes    []byte
+		err      error
+	)
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	var param = proto.UserQuotaUpdateParam{}
+	if err = json.Unmarshal(bytes, &param); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if userInfo, err = m.user.updateQuota(&param); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	log.LogWarnf("action[updateUserQuota] user[%v] quota[%v] from[%v]", param.UserID, param.Quota, r.RemoteAddr)
 	sendOkReply(w, r, newSuccessHTTPReply(userInfo))
 }
 
 func (m *Server) updateUserPolicy(w http.ResponseWriter, r *http.Request) {
 	var (
 		userInfo *proto.UserInfo
+		vol      *Vol
 		bytes    []byte
 		err      error
 	)
@@ -134,10 +158,12 @@ func (m *Server) updateUserPolicy(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if _, err = m.cluster.getVol(param.Volume); err != nil {
+	if vol, err = m.cluster.getVol(param.Volume); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
 		return
 	}
+	// the policies refer to the current name of the volume renamed
+	param.Volume = vol.Name
 	if userInfo, err = m.user.updatePolicy(&param); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
@@ -148,6 +174,7 @@ func (m *Server) updateUserPolicy(w http.ResponseWriter, r *http.Request) {
 func (m *Server) removeUserPolicy(w http.ResponseWriter, r *http.Request) {
 	var (
 		userInfo *proto.UserInfo
+		vol      *Vol
 		bytes    []byte
 		err      error
 	)
@@ -160,10 +187,11 @@ func (m *Server) removeUserPolicy(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if _, err = m.cluster.getVol(param.Volume); err != nil {
+	if vol, err = m.cluster.getVol(param.Volume); err != nil {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
 		return
 	}
+	param.Volume = vol.Name
 	if userInfo, err = m.user.removePolicy(&param); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
@@ -209,10 +237,18 @@ func (m *Server) transferUserVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
 		return
 	}
+	param.Volume = vol.Name
 	if !param.Force && vol.Owner != param.UserSrc {
 		sendErrReply(w, r, newErrHTTPReply(proto.ErrHaveNoPolicy))
 		return
 	}
+	defer m.cluster.lockUserQuota(param.UserDst)()
+	if vol.Owner != param.UserDst {
+		if err = m.cluster.checkUserQuota(m.user, param.UserDst, vol.Capacity, 1); err != nil {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeUserQuotaExceeded, Msg: err.Error()})
+			return
+		}
+	}
 	if userInfo, err = m.user.transferVol(&param); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
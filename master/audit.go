@@ -0,0 +1,270 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsarahq/thunder/graphql"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	maxAuditRecords         = 10000 // the recent records kept in memory for the queries
+	defaultAuditRecordLimit = 100
+	maskedParam             = "***"
+)
+
+var (
+	// the APIs of the graphql schemas, audited for the mutations only
+	graphqlAPIs = []string{
+		proto.AdminClusterAPI,
+		proto.AdminUserAPI,
+		proto.AdminVolumeAPI,
+	}
+
+	auditedAPIs = newAuditedAPIs()
+
+	secretParams     = []string{"pwd", "password", "sk", "secretKey", volAuthKey, tierSecretKeyKey}
+	secretArgPattern = regexp.MustCompile(`\b(pwd|password|sk|secretKey|authKey|tierSecretKey)(\s*:\s*)"[^"]*"`)
+)
+
+// newAuditedAPIs returns the mutating APIs, which are the APIs requiring the permissions except the views,
+// and the registration of the nodes.
+func newAuditedAPIs() map[string]bool {
+	audited := make(map[string]bool)
+	for _, group := range [][]string{clusterAPIs, volumeAPIs, nodeAPIs, userAPIs} {
+		for _, path := range group {
+			audited[path] = true
+		}
+	}
+	delete(audited, proto.UserList)
+	delete(audited, proto.UsersOfVol)
+	audited[proto.AddDataNode] = true
+	audited[proto.AddMetaNode] = true
+	return audited
+}
+
+// auditLog writes the records of the mutating APIs called on the leader to the audit log of the master, and keeps
+// the recent ones for the queries. The records are lost on the change of the leader, but not the audit logs.
+type auditLog struct {
+	records []*proto.AuditRecord
+	sync.RWMutex
+}
+
+func newAuditLog() *auditLog {
+	return &auditLog{records: make([]*proto.AuditRecord, 0)}
+}
+
+func (a *auditLog) add(record *proto.AuditRecord) {
+	if data, err := json.Marshal(record); err == nil {
+		log.LogAudit(string(data))
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.records = append(a.records, record)
+	if len(a.records) > maxAuditRecords {
+		a.records = a.records[len(a.records)-maxAuditRecords:]
+	}
+}
+
+// list returns the latest records matching the user and the prefix of the operation since the time, if given.
+func (a *auditLog) list(user, op string, since int64, limit int) (records []*proto.AuditRecord) {
+	a.RLock()
+	defer a.RUnlock()
+	records = make([]*proto.AuditRecord, 0)
+	for i := len(a.records) - 1; i >= 0 && len(records) < limit; i-- {
+		record := a.records[i]
+		if record.Time < since {
+			break
+		}
+		if (user != "" && record.User != user) || !strings.HasPrefix(record.Op, op) {
+			continue
+		}
+		records = append(records, record)
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return
+}
+
+// auditResponseWriter keeps the reply to take the result of the API.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	reply bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.reply.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// startAudit begins the record of the request if audited, and returns the writer to serve the request with and the
+// function to finish the record by the request authenticated, which carries the user.
+func (m *Server) startAudit(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func(*http.Request)) {
+	var trustedProxies []string
+	if m.config != nil {
+		trustedProxies = m.config.trustedProxies
+	}
+	record := newAuditRecord(r, trustedProxies)
+	if record == nil {
+		return w, func(*http.Request) {}
+	}
+	aw := &auditResponseWriter{ResponseWriter: w}
+	return aw, func(req *http.Request) {
+		if userInfo, ok := req.Context().Value(proto.UserInfoKey).(*proto.UserInfo); ok {
+			record.User = userInfo.UserID
+			record.Authenticated = true
+		}
+		record.Code, record.Msg = parseAuditResult(aw.reply.Bytes())
+		m.audit.add(record)
+	}
+}
+
+// newAuditRecord returns nil if the request is not audited. The client in X-Forwarded-For is recorded only if
+// the request comes from one of the trusted proxies, otherwise the header may be forged by the client.
+func newAuditRecord(r *http.Request, trustedProxies []string) (record *proto.AuditRecord) {
+	path := r.URL.Path
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		params[key] = strings.Join(values, ",")
+	}
+	if contains(graphqlAPIs, path) {
+		body := readAuditBody(r)
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return
+		}
+		query, err := graphql.Parse(req.Query, req.Variables)
+		if err != nil || query.Kind != "mutation" {
+			return
+		}
+		fields := make([]string, 0, len(query.Selections))
+		for _, selection := range query.Selections {
+			fields = append(fields, selection.Name)
+		}
+		path += ":" + strings.Join(fields, ",")
+		params["query"] = secretArgPattern.ReplaceAllString(req.Query, `$1$2"`+maskedParam+`"`)
+		if len(req.Variables) > 0 {
+			variables, _ := json.Marshal(maskSecrets(req.Variables))
+			params["variables"] = string(variables)
+		}
+	} else if auditedAPIs[path] {
+		if body := readAuditBody(r); len(body) > 0 {
+			var fields map[string]interface{}
+			if err := json.Unmarshal(body, &fields); err == nil {
+				for key, value := range maskSecrets(fields) {
+					if s, ok := value.(string); ok {
+						params[key] = s
+					} else {
+						data, _ := json.Marshal(value)
+						params[key] = string(data)
+					}
+				}
+			}
+		}
+	} else {
+		return
+	}
+	for _, key := range secretParams {
+		if _, ok := params[key]; ok {
+			params[key] = maskedParam
+		}
+	}
+	record = &proto.AuditRecord{
+		Time:       time.Now().Unix(),
+		User:       r.Header.Get(proto.UserKey),
+		RemoteAddr: r.RemoteAddr,
+		Op:         path,
+		Params:     params,
+	}
+	if !isTrustedProxy(r.RemoteAddr, trustedProxies) {
+		return
+	}
+	if client := forwardedClient(r); client != "" {
+		record.RemoteAddr, record.ProxyAddr = client, r.RemoteAddr
+	}
+	return
+}
+
+func isTrustedProxy(remoteAddr string, trustedProxies []string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return contains(trustedProxies, host)
+}
+
+// forwardedClient returns the original client in X-Forwarded-For, which is added by the proxies and by the
+// followers forwarding the requests to the leader.
+func forwardedClient(r *http.Request) string {
+	for _, addr := range strings.Split(r.Header.Get("X-Forwarded-For"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			return addr
+		}
+	}
+	return ""
+}
+
+// readAuditBody reads the body and puts it back for the handler.
+func readAuditBody(r *http.Request) (body []byte) {
+	if r.Body == nil {
+		return
+	}
+	body, _ = ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return
+}
+
+func maskSecrets(fields map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if contains(secretParams, key) {
+			value = maskedParam
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// parseAuditResult takes the result from the reply of the APIs, or of the graphql APIs.
+func parseAuditResult(reply []byte) (code int32, msg string) {
+	var result struct {
+		Code   int32    `json:"code"`
+		Msg    string   `json:"msg"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return proto.ErrCodeInternalError, strings.TrimSpace(string(reply))
+	}
+	if len(result.Errors) > 0 {
+		return proto.ErrCodeInternalError, strings.Join(result.Errors, ";")
+	}
+	return result.Code, result.Msg
+}
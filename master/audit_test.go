@@ -0,0 +1,62 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, proto.AdminDeleteVol+"?name=vol&authKey=key", nil)
+	r.RemoteAddr = "192.168.0.1:17010"
+	record := newAuditRecord(r, nil)
+	if record == nil || record.RemoteAddr != r.RemoteAddr || record.ProxyAddr != "" || record.Params[volAuthKey] != maskedParam {
+		t.Fatalf("record[%v]", record)
+	}
+
+	r.Header.Set("X-Forwarded-For", " 10.0.0.5 , 192.168.0.2")
+	if record = newAuditRecord(r, []string{"192.168.0.1"}); record.RemoteAddr != "10.0.0.5" || record.ProxyAddr != r.RemoteAddr {
+		t.Errorf("forwarded: remote[%v] proxy[%v]", record.RemoteAddr, record.ProxyAddr)
+	}
+	// the header sent by a client not being a proxy is ignored
+	if record = newAuditRecord(r, []string{"192.168.0.3"}); record.RemoteAddr != r.RemoteAddr || record.ProxyAddr != "" {
+		t.Errorf("forged: remote[%v] proxy[%v]", record.RemoteAddr, record.ProxyAddr)
+	}
+
+	if record = newAuditRecord(httptest.NewRequest(http.MethodGet, proto.AdminGetVol, nil), nil); record != nil {
+		t.Errorf("view: record[%v]", record)
+	}
+}
+
+func TestAuditAuthenticated(t *testing.T) {
+	m := &Server{audit: newAuditLog()}
+	r := httptest.NewRequest(http.MethodGet, proto.AdminDeleteVol+"?name=vol", nil)
+	r.Header.Set(proto.UserKey, "claimed")
+	_, finish := m.startAudit(httptest.NewRecorder(), r)
+	finish(r)
+	authReq := r.WithContext(context.WithValue(r.Context(), proto.UserInfoKey, &proto.UserInfo{UserID: "signer"}))
+	_, finish = m.startAudit(httptest.NewRecorder(), r)
+	finish(authReq)
+	records := m.audit.list("", proto.AdminDeleteVol, 0, defaultAuditRecordLimit)
+	if len(records) != 2 || records[0].User != "claimed" || records[0].Authenticated ||
+		records[1].User != "signer" || !records[1].Authenticated {
+		t.Errorf("records[%v]", records)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+const (
+	defaultCapacityHistoryDays = 7
+	defaultCapacityWarnDays    = 7
+	forecastSampleInterval     = 30 * 60 // seconds between the samples kept in the history
+	minForecastHistory         = 60 * 60 // seconds of the history required to estimate the growth
+	forecastWarnInterval       = 60 * 60 // seconds between the alarms of the same volume, zone or node set
+	secondsPerDay              = 24 * 60 * 60
+)
+
+type usageSample struct {
+	time  int64
+	used  float64 // GB
+	total float64 // GB
+}
+
+type usageHistory struct {
+	scope        string
+	name         string
+	samples      []usageSample
+	forecast     *proto.CapacityForecast
+	lastWarnTime int64
+}
+
+// record adds the sample to the history if the last one is old enough, drops the samples out of the window,
+// and estimates the growth from the samples and the current one.
+func (h *usageHistory) record(sample usageSample, window int64) {
+	if n := len(h.samples); n == 0 || sample.time-h.samples[n-1].time >= forecastSampleInterval {
+		h.samples = append(h.samples, sample)
+	}
+	start := 0
+	for start < len(h.samples)-1 && sample.time-h.samples[start].time > window {
+		start++
+	}
+	h.samples = h.samples[start:]
+	h.forecast = forecastCapacity(h.scope, h.name, h.samples, sample)
+}
+
+// forecastCapacity fits the used space to the time by the least squares, and projects when it reaches the total.
+func forecastCapacity(scope, name string, samples []usageSample, current usageSample) (f *proto.CapacityForecast) {
+	f = &proto.CapacityForecast{
+		Scope:      scope,
+		Name:       name,
+		TotalGB:    fixedPoint(current.total, 2),
+		UsedGB:     fixedPoint(current.used, 2),
+		DaysToFull: -1,
+	}
+	points := samples
+	if last := samples[len(samples)-1]; last.time != current.time {
+		points = append(append(make([]usageSample, 0, len(samples)+1), samples...), current)
+	}
+	span := current.time - points[0].time
+	f.HistoryHours = fixedPoint(float64(span)/3600, 2)
+	if span < minForecastHistory {
+		return
+	}
+	var meanX, meanY float64
+	for _, p := range points {
+		meanX += float64(p.time-points[0].time) / secondsPerDay
+		meanY += p.used
+	}
+	meanX /= float64(len(points))
+	meanY /= float64(len(points))
+	var covariance, variance float64
+	for _, p := range points {
+		dx := float64(p.time-points[0].time)/secondsPerDay - meanX
+		covariance += dx * (p.used - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return
+	}
+	growth := covariance / variance
+	f.GrowthGBPerDay = fixedPoint(growth, 2)
+	if growth <= 0 {
+		return
+	}
+	if remaining := current.total - current.used; remaining > 0 {
+		f.DaysToFull = fixedPoint(remaining/growth, 2)
+	} else {
+		f.DaysToFull = 0
+	}
+	return
+}
+
+// capacityHistory keeps the usage of the volumes, the zones and the node sets in memory on the leader,
+// so the history restarts after the leader changes.
+type capacityHistory struct {
+	sync.RWMutex
+	histories map[string]*usageHistory
+}
+
+func newCapacityHistory() *capacityHistory {
+	return &capacityHistory{histories: make(map[string]*usageHistory)}
+}
+
+func (ch *capacityHistory) forecasts(scope string) (forecasts []*proto.CapacityForecast) {
+	ch.RLock()
+	defer ch.RUnlock()
+	forecasts = make([]*proto.CapacityForecast, 0, len(ch.histories))
+	for _, h := range ch.histories {
+		if h.forecast != nil && (scope == "" || h.scope == scope) {
+			forecasts = append(forecasts, h.forecast)
+		}
+	}
+	sort.Slice(forecasts, func(i, j int) bool {
+		if forecasts[i].Scope != forecasts[j].Scope {
+			return forecasts[i].Scope < forecasts[j].Scope
+		}
+		return forecasts[i].Name < forecasts[j].Name
+	})
+	return
+}
+
+// updateCapacityForecasts records the current usage, and warns of the volumes, the zones and the node sets
+// projected to be full within the days configured.
+func (c *Cluster) updateCapacityForecasts() {
+	now := time.Now().Unix()
+	current := make(map[string]*usageHistory)
+	samples := make(map[string]usageSample)
+	add := func(scope, name string, used, total uint64) {
+		if total == 0 {
+			return
+		}
+		key := scope + keySeparator + name
+		current[key] = &usageHistory{scope: scope, name: name}
+		samples[key] = usageSample{time: now, used: float64(used) / util.GB, total: float64(total) / util.GB}
+	}
+	for _, vol := range c.copyVols() {
+		if vol.Status == markDelete {
+			continue
+		}
+		add(proto.ForecastScopeVol, vol.Name, vol.totalUsedSpace(), vol.Capacity*util.GB)
+	}
+	for _, zone := range c.t.getAllZones() {
+		var zoneUsed, zoneTotal uint64
+		for _, ns := range zone.getAllNodeSet() {
+			var used, total uint64
+			ns.dataNodes.Range(func(key, value interface{}) bool {
+				dataNode := value.(*DataNode)
+				used += dataNode.Used
+				total += dataNode.Total
+				return true
+			})
+			add(proto.ForecastScopeNodeSet, zone.name+keySeparator+strconv.FormatUint(ns.ID, 10), used, total)
+			zoneUsed += used
+			zoneTotal += total
+		}
+		add(proto.ForecastScopeZone, zone.name, zoneUsed, zoneTotal)
+	}
+
+	window := c.cfg.capacityHistoryDays * secondsPerDay
+	warnings := make([]string, 0)
+	c.capacityHistory.Lock()
+	for key, h := range current {
+		if old, ok := c.capacityHistory.histories[key]; ok {
+			h = old
+		} else {
+			c.capacityHistory.histories[key] = h
+		}
+		h.record(samples[key], window)
+		f := h.forecast
+		if c.cfg.capacityWarnDays > 0 && f.DaysToFull >= 0 && f.DaysToFull < float64(c.cfg.capacityWarnDays) &&
+			now-h.lastWarnTime >= forecastWarnInterval {
+			h.lastWarnTime = now
+			warnings = append(warnings, fmt.Sprintf("clusterID[%v] %v[%v] is projected to be full in [%v] days, used[%v]GB total[%v]GB growth[%v]GB/day",
+				c.Name, f.Scope, f.Name, f.DaysToFull, f.UsedGB, f.TotalGB, f.GrowthGBPerDay))
+		}
+	}
+	for key := range c.capacityHistory.histories {
+		if _, ok := current[key]; !ok {
+			delete(c.capacityHistory.histories, key)
+		}
+	}
+	c.capacityHistory.Unlock()
+	for _, msg := range warnings {
+		Warn(c.Name, msg)
+	}
+}
@@ -16,6 +16,7 @@ package master
 
 import (
 	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -31,6 +32,7 @@ import (
 type Cluster struct {
 	Name                      string
 	vols                      map[string]*Vol
+	volAliases                map[string]string // the old names of the renamed volumes -> the current names
 	dataNodes                 sync.Map
 	metaNodes                 sync.Map
 	dpMutex                   sync.Mutex   // data partition mutex
@@ -55,6 +57,13 @@ type Cluster struct {
 	MasterSecretKey           []byte
 	lastMasterZoneForDataNode string
 	lastMasterZoneForMetaNode string
+	rebalancer                *rebalancer
+	migrations                sync.Map // the replicas of the partitions being migrated, by migrationKey
+	decommissionJobs          sync.Map
+	diskFailure               *diskFailureHandler
+	capacityHistory           *capacityHistory
+	audit                     *auditLog // of the server, for the actions taken by the master itself
+	userQuotaLocks            sync.Map  // serializes the changes of the volumes checked by the quota of each user
 }
 
 func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition raftstore.Partition, cfg *clusterConfig) (c *Cluster) {
@@ -62,6 +71,7 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.Name = name
 	c.leaderInfo = leaderInfo
 	c.vols = make(map[string]*Vol, 0)
+	c.volAliases = make(map[string]string, 0)
 	c.cfg = cfg
 	c.t = newTopology()
 	c.BadDataPartitionIds = new(sync.Map)
@@ -72,6 +82,9 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.fsm = fsm
 	c.partition = partition
 	c.idAlloc = newIDAllocator(c.fsm.store, c.partition)
+	c.rebalancer = newRebalancer()
+	c.diskFailure = newDiskFailureHandler()
+	c.capacityHistory = newCapacityHistory()
 	return
 }
 
@@ -88,6 +101,9 @@ func (c *Cluster) scheduleTask() {
 	c.scheduleToCheckMetaPartitionRecoveryProgress()
 	c.scheduleToLoadMetaPartitions()
 	c.scheduleToReduceReplicaNum()
+	c.scheduleToRebalance()
+	c.scheduleToRunDecommissionJobs()
+	c.scheduleToHandleBadDisks()
 }
 
 func (c *Cluster) masterAddr() (addr string) {
@@ -248,10 +264,13 @@ func (c *Cluster) checkLeaderAddr() {
 
 func (c *Cluster) checkDataNodeHeartbeat() {
 	tasks := make([]*proto.AdminTask, 0)
+	volQos := c.volQos()
+	volCompression := c.volCompression()
+	volQuorumWrite := c.volQuorumWrite()
 	c.dataNodes.Range(func(addr, dataNode interface{}) bool {
 		node := dataNode.(*DataNode)
 		node.checkLiveness()
-		task := node.createHeartbeatTask(c.masterAddr())
+		task := node.createHeartbeatTask(c.masterAddr(), volQos, volCompression, volQuorumWrite)
 		tasks = append(tasks, task)
 		return true
 	})
@@ -430,15 +449,19 @@ func (c *Cluster) updateMetaNodeBaseInfo(nodeAddr string, id uint64) (err error)
 	return
 }
 
-func (c *Cluster) addMetaNode(nodeAddr, zoneName string) (id uint64, err error) {
+func (c *Cluster) addMetaNode(nodeAddr, zoneName string, labels map[string]string) (id uint64, err error) {
 	c.mnMutex.Lock()
 	defer c.mnMutex.Unlock()
 	var metaNode *MetaNode
 	if value, ok := c.metaNodes.Load(nodeAddr); ok {
 		metaNode = value.(*MetaNode)
+		if err = c.updateMetaNodeLabels(metaNode, labels); err != nil {
+			return
+		}
 		return metaNode.ID, nil
 	}
 	metaNode = newMetaNode(nodeAddr, zoneName, c.Name)
+	metaNode.Labels = labels
 	zone, err := c.t.getZone(zoneName)
 	if err != nil {
 		zone = c.t.putZoneIfAbsent(newZone(zoneName))
@@ -473,16 +496,20 @@ errHandler:
 	return
 }
 
-func (c *Cluster) addDataNode(nodeAddr, zoneName string) (id uint64, err error) {
+func (c *Cluster) addDataNode(nodeAddr, zoneName string, labels map[string]string) (id uint64, err error) {
 	c.dnMutex.Lock()
 	defer c.dnMutex.Unlock()
 	var dataNode *DataNode
 	if node, ok := c.dataNodes.Load(nodeAddr); ok {
 		dataNode = node.(*DataNode)
+		if err = c.updateDataNodeLabels(dataNode, labels); err != nil {
+			return
+		}
 		return dataNode.ID, nil
 	}
 
 	dataNode = newDataNode(nodeAddr, zoneName, c.Name)
+	dataNode.Labels = labels
 	zone, err := c.t.getZone(zoneName)
 	if err != nil {
 		zone = c.t.putZoneIfAbsent(newZone(zoneName))
@@ -595,15 +622,22 @@ func (c *Cluster) putVol(vol *Vol) {
 	defer c.volMutex.Unlock()
 	if _, ok := c.vols[vol.Name]; !ok {
 		c.vols[vol.Name] = vol
+		for _, alias := range vol.aliases {
+			c.volAliases[alias] = vol.Name
+		}
 	}
 }
 
+// getVol returns the volume by the name, or by the old names if renamed, which the partitions,
+// the tokens and the clients not yet refreshed still refer to.
 func (c *Cluster) getVol(volName string) (vol *Vol, err error) {
 	c.volMutex.RLock()
 	defer c.volMutex.RUnlock()
 	vol, ok := c.vols[volName]
 	if !ok {
-		err = proto.ErrVolNotExists
+		if vol, ok = c.vols[c.volAliases[volName]]; !ok {
+			err = proto.ErrVolNotExists
+		}
 	}
 	return
 }
@@ -611,6 +645,11 @@ func (c *Cluster) getVol(volName string) (vol *Vol, err error) {
 func (c *Cluster) deleteVol(name string) {
 	c.volMutex.Lock()
 	defer c.volMutex.Unlock()
+	if vol, ok := c.vols[name]; ok {
+		for _, alias := range vol.aliases {
+			delete(c.volAliases, alias)
+		}
+	}
 	delete(c.vols, name)
 	return
 }
@@ -628,12 +667,18 @@ func (c *Cluster) markDeleteVol(name, authKey string) (err error) {
 	if !matchKey(serverAuthKey, authKey) {
 		return proto.ErrVolAuthKeyNotMatch
 	}
+	if clone, shared := c.isVolShared(vol); shared {
+		return fmt.Errorf("the data of vol[%v] is shared by the cloned vol[%v]", name, clone)
+	}
 
 	vol.Status = markDelete
 	if err = c.syncUpdateVol(vol); err != nil {
 		vol.Status = normal
 		return proto.ErrPersistenceByRaft
 	}
+	if len(vol.sharedPartitionIDs) > 0 {
+		c.releaseSharedDataPartitions(vol)
+	}
 	return
 }
 
@@ -677,13 +722,14 @@ func (c *Cluster) createDataPartition(volName string, zoneNum int) (dp *DataPart
 	vol.createDpMutex.Lock()
 	defer vol.createDpMutex.Unlock()
 	errChannel := make(chan error, vol.dpReplicaNum)
-	if targetHosts, targetPeers, err = c.chooseTargetDataNodes("", nil, nil, int(vol.dpReplicaNum), zoneNum, vol.zoneName); err != nil {
+	if targetHosts, targetPeers, err = c.chooseTargetDataNodes("", nil, nil, int(vol.dpReplicaNum), zoneNum, vol.zoneName, newPlacement(vol.placementPolicy)); err != nil {
 		goto errHandler
 	}
 	if partitionID, err = c.idAlloc.allocateDataPartitionID(); err != nil {
 		goto errHandler
 	}
 	dp = newDataPartition(partitionID, vol.dpReplicaNum, volName, vol.ID)
+	dp.Checksum = vol.checksum
 	dp.Hosts = targetHosts
 	dp.Peers = targetPeers
 	for _, host := range targetHosts {
@@ -743,7 +789,7 @@ errHandler:
 }
 
 func (c *Cluster) syncCreateDataPartitionToDataNode(host string, size uint64, dp *DataPartition, peers []proto.Peer, hosts []string, createType int) (diskPath string, err error) {
-	task := dp.createTaskToCreateDataPartition(host, size, peers, hosts, createType)
+	task := dp.createTaskToCreateDataPartition(host, size, peers, hosts, createType, c.decommissionedDisks(host))
 	dataNode, err := c.dataNode(host)
 	if err != nil {
 		return
@@ -785,7 +831,7 @@ func (c *Cluster) decideZoneNum(crossZone bool) (zoneNum int) {
 	}
 	return zoneNum
 }
-func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []uint64, excludeHosts []string, replicaNum int, zoneNum int, specifiedZone string) (hosts []string, peers []proto.Peer, err error) {
+func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []uint64, excludeHosts []string, replicaNum int, zoneNum int, specifiedZone string, pl *placement) (hosts []string, peers []proto.Peer, err error) {
 
 	var (
 		masterZone *Zone
@@ -820,7 +866,7 @@ func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []ui
 		return nil, nil, fmt.Errorf("no enough zones[%v] to be selected,crossNum[%v]", len(zones), zoneNum)
 	}
 	if len(zones) == 1 {
-		if hosts, peers, err = zones[0].getAvailDataNodeHosts(excludeNodeSets, excludeHosts, replicaNum); err != nil {
+		if hosts, peers, err = zones[0].getAvailDataNodeHosts(excludeNodeSets, excludeHosts, replicaNum, pl); err != nil {
 			log.LogErrorf("action[chooseTargetDataNodes],err[%v]", err)
 			return
 		}
@@ -834,7 +880,7 @@ func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []ui
 	//replicaNum is equal with the number of allocated zones
 	if replicaNum == len(zones) {
 		for _, zone := range zones {
-			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, 1)
+			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, 1, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
@@ -858,14 +904,14 @@ func (c *Cluster) chooseTargetDataNodes(excludeZone string, excludeNodeSets []ui
 	for _, zone := range zones {
 		if zone.name == masterZone.name {
 			rNum := replicaNum - len(zones) + 1
-			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, rNum)
+			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, rNum, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
 			hosts = append(hosts, selectedHosts...)
 			peers = append(peers, selectedPeers...)
 		} else {
-			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, 1)
+			selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(excludeNodeSets, excludeHosts, 1, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
@@ -987,44 +1033,11 @@ func (c *Cluster) getAllMetaPartitionsByMetaNode(addr string) (partitions []*Met
 	return
 }
 
-func (c *Cluster) decommissionDataNode(dataNode *DataNode) (err error) {
-	msg := fmt.Sprintf("action[decommissionDataNode], Node[%v] OffLine", dataNode.Addr)
-	log.LogWarn(msg)
-	var wg sync.WaitGroup
-	dataNode.ToBeOffline = true
-	dataNode.AvailableSpace = 1
-	partitions := c.getAllDataPartitionByDataNode(dataNode.Addr)
-	errChannel := make(chan error, len(partitions))
-	defer func() {
-		dataNode.ToBeOffline = false
-		close(errChannel)
-	}()
-	for _, dp := range partitions {
-		wg.Add(1)
-		go func(dp *DataPartition) {
-			defer wg.Done()
-			if err1 := c.decommissionDataPartition(dataNode.Addr, dp, dataNodeOfflineErr); err1 != nil {
-				errChannel <- err1
-			}
-		}(dp)
-	}
-	wg.Wait()
-	select {
-	case err = <-errChannel:
-		return
-	default:
-	}
-	if err = c.syncDeleteDataNode(dataNode); err != nil {
-		msg = fmt.Sprintf("action[decommissionDataNode],clusterID[%v] Node[%v] OffLine failed,err[%v]",
-			c.Name, dataNode.Addr, err)
-		Warn(c.Name, msg)
-		return
-	}
-	c.delDataNodeFromCache(dataNode)
-	msg = fmt.Sprintf("action[decommissionDataNode],clusterID[%v] Node[%v] OffLine success",
-		c.Name, dataNode.Addr)
-	Warn(c.Name, msg)
-	return
+// decommissionDataNode starts a job moving all the data partitions out of the data node,
+// and the data node is deleted once they are all moved.
+func (c *Cluster) decommissionDataNode(dataNode *DataNode) (job *decommissionJob, err error) {
+	log.LogWarnf("action[decommissionDataNode], Node[%v] OffLine", dataNode.Addr)
+	return c.createDecommissionJob(proto.DecommissionDataNodeJob, dataNode.Addr, "")
 }
 
 func (c *Cluster) delDataNodeFromCache(dataNode *DataNode) {
@@ -1055,6 +1068,7 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 		excludeNodeSets []uint64
 		zones           []string
 		excludeZone     string
+		pl              *placement
 	)
 	dp.RLock()
 	if ok := dp.hasHost(offlineAddr); !ok {
@@ -1063,6 +1077,11 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 	}
 	replica, _ = dp.getReplica(offlineAddr)
 	dp.RUnlock()
+	// the replicas of the partition being rebalanced are changed by the rebalancer only
+	if c.isMigrating(proto.RebalanceDataPartition, dp.PartitionID) {
+		err = fmt.Errorf("data partition[%v] is being migrated", dp.PartitionID)
+		goto errHandler
+	}
 	if err = c.validateDecommissionDataPartition(dp, offlineAddr); err != nil {
 		goto errHandler
 	}
@@ -1081,10 +1100,11 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 	if ns, err = zone.getNodeSet(dataNode.NodeSetID); err != nil {
 		goto errHandler
 	}
-	if targetHosts, _, err = ns.getAvailDataNodeHosts(dp.Hosts, 1); err != nil {
+	pl = c.dataPlacement(dp, offlineAddr)
+	if targetHosts, _, err = ns.getAvailDataNodeHosts(dp.Hosts, 1, pl); err != nil {
 		// select data nodes from the other node set in same zone
 		excludeNodeSets = append(excludeNodeSets, ns.ID)
-		if targetHosts, _, err = zone.getAvailDataNodeHosts(excludeNodeSets, dp.Hosts, 1); err != nil {
+		if targetHosts, _, err = zone.getAvailDataNodeHosts(excludeNodeSets, dp.Hosts, 1, pl); err != nil {
 			// select data nodes from the other zone
 			zones = dp.getLiveZones(offlineAddr)
 			if len(zones) == 0 {
@@ -1092,7 +1112,7 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 			} else {
 				excludeZone = zones[0]
 			}
-			if targetHosts, _, err = c.chooseTargetDataNodes(excludeZone, excludeNodeSets, dp.Hosts, 1, 1, ""); err != nil {
+			if targetHosts, _, err = c.chooseTargetDataNodes(excludeZone, excludeNodeSets, dp.Hosts, 1, 1, "", pl); err != nil {
 				goto errHandler
 			}
 		}
@@ -1124,6 +1144,40 @@ errHandler:
 	return
 }
 
+// migrateDataPartition adds the replica of the data partition on the target data node, and removes the one
+// on the source in the background once the target catches up. The partition stays read only until then.
+// It is used by the rebalancer, the decommission takes the replica offline synchronously instead.
+func (c *Cluster) migrateDataPartition(dp *DataPartition, srcAddr, targetAddr string) (err error) {
+	var m *partitionMigration
+	if m, err = c.startMigration(proto.RebalanceDataPartition, dp.PartitionID, srcAddr, targetAddr); err != nil {
+		return fmt.Errorf("vol[%v],data partition[%v],err[%v]", dp.VolName, dp.PartitionID, err)
+	}
+	defer func() {
+		if err != nil {
+			c.finishMigration(m)
+		}
+	}()
+	if err = c.checkDataPlacement(dp, srcAddr, targetAddr); err != nil {
+		return
+	}
+	if err = c.addDataReplica(dp, targetAddr); err != nil {
+		dp.RLock()
+		rollback := dp.hasHost(targetAddr)
+		dp.RUnlock()
+		if rollback {
+			c.removeDataReplica(dp, targetAddr, false)
+		}
+		return
+	}
+	dp.Status = proto.ReadOnly
+	dp.isRecover = true
+	dp.RLock()
+	c.syncUpdateDataPartition(dp)
+	dp.RUnlock()
+	go c.finishDataMigration(dp, m)
+	return
+}
+
 func (c *Cluster) validateDecommissionDataPartition(dp *DataPartition, offlineAddr string) (err error) {
 	dp.RLock()
 	defer dp.RUnlock()
@@ -1148,6 +1202,92 @@ func (c *Cluster) validateDecommissionDataPartition(dp *DataPartition, offlineAd
 	return
 }
 
+// convertDataPartitionToEc asks the leader of the data partition to convert the extents, which are not modified
+// for coldTime seconds, into erasure coded shards with the erasure coding config of the vol.
+func (c *Cluster) convertDataPartitionToEc(dp *DataPartition, coldTime int64) (err error) {
+	var (
+		vol        *Vol
+		dataNode   *DataNode
+		ecHosts    []string
+		leaderAddr string
+		shardNum   int
+	)
+	defer func() {
+		if err != nil {
+			log.LogErrorf("action[convertDataPartitionToEc],vol[%v],data partition[%v],err[%v]", dp.VolName, dp.PartitionID, err)
+		}
+	}()
+	if vol, err = c.getVol(dp.VolName); err != nil {
+		return
+	}
+	if vol.ecDataNum == 0 || vol.ecParityNum == 0 {
+		err = fmt.Errorf("vol[%v] does not enable erasure coding", vol.Name)
+		return
+	}
+	shardNum = int(vol.ecDataNum) + int(vol.ecParityNum)
+
+	dp.offlineMutex.Lock()
+	defer dp.offlineMutex.Unlock()
+	dp.RLock()
+	oldEcHosts := dp.EcHosts
+	dp.RUnlock()
+	// the shards of a partition are always placed on the same data nodes, unless the config of the vol is changed,
+	// or some of the data nodes are dead, the shards on which are moved to the new ones by the leader
+	if ecHosts, err = c.placeEcHosts(vol, oldEcHosts, shardNum); err != nil {
+		return
+	}
+	if !reflect.DeepEqual(ecHosts, oldEcHosts) {
+		dp.Lock()
+		dp.EcHosts = ecHosts
+		dp.Unlock()
+		dp.RLock()
+		err = c.syncUpdateDataPartition(dp)
+		dp.RUnlock()
+		if err != nil {
+			dp.Lock()
+			dp.EcHosts = oldEcHosts
+			dp.Unlock()
+			return
+		}
+	}
+	dp.RLock()
+	leaderAddr = dp.getLeaderAddr()
+	task := dp.createTaskToConvertToEc(leaderAddr, vol.ecDataNum, vol.ecParityNum, coldTime)
+	dp.RUnlock()
+
+	if leaderAddr == "" {
+		err = proto.ErrNoLeader
+		return
+	}
+	if dataNode, err = c.dataNode(leaderAddr); err != nil {
+		return
+	}
+	_, err = dataNode.TaskManager.syncSendAdminTask(task)
+	return
+}
+
+// placeEcHosts returns the hosts of the shards of the data partition, the dead ones of which are replaced.
+func (c *Cluster) placeEcHosts(vol *Vol, hosts []string, shardNum int) (ecHosts []string, err error) {
+	if len(hosts) != shardNum {
+		ecHosts, _, err = c.chooseTargetDataNodes("", nil, nil, shardNum, c.decideZoneNum(vol.crossZone), vol.zoneName, newPlacement(vol.placementPolicy))
+		return
+	}
+	ecHosts = make([]string, len(hosts))
+	copy(ecHosts, hosts)
+	for i, host := range hosts {
+		if dataNode, e := c.dataNode(host); e == nil && dataNode.isActive {
+			continue
+		}
+		var newHosts []string
+		if newHosts, _, err = c.chooseTargetDataNodes("", nil, ecHosts, 1, 1, vol.zoneName, newPlacement(vol.placementPolicy)); err != nil {
+			return
+		}
+		log.LogWarnf("action[placeEcHosts] vol[%v] ec host[%v] of shard[%v] is dead, replaced by [%v]", vol.Name, host, i, newHosts[0])
+		ecHosts[i] = newHosts[0]
+	}
+	return
+}
+
 func (c *Cluster) addDataReplica(dp *DataPartition, addr string) (err error) {
 	defer func() {
 		if err != nil {
@@ -1453,43 +1593,11 @@ func (c *Cluster) getBadDataPartitionsView() (bpvs []badPartitionView) {
 	return
 }
 
-func (c *Cluster) decommissionMetaNode(metaNode *MetaNode) (err error) {
-	msg := fmt.Sprintf("action[decommissionMetaNode],clusterID[%v] Node[%v] begin", c.Name, metaNode.Addr)
-	log.LogWarn(msg)
-	var wg sync.WaitGroup
-	metaNode.ToBeOffline = true
-	metaNode.MaxMemAvailWeight = 1
-	partitions := c.getAllMetaPartitionByMetaNode(metaNode.Addr)
-	errChannel := make(chan error, len(partitions))
-	defer func() {
-		metaNode.ToBeOffline = false
-		close(errChannel)
-	}()
-	for _, mp := range partitions {
-		wg.Add(1)
-		go func(mp *MetaPartition) {
-			defer wg.Done()
-			if err1 := c.decommissionMetaPartition(metaNode.Addr, mp); err1 != nil {
-				errChannel <- err1
-			}
-		}(mp)
-	}
-	wg.Wait()
-	select {
-	case err = <-errChannel:
-		return
-	default:
-	}
-	if err = c.syncDeleteMetaNode(metaNode); err != nil {
-		msg = fmt.Sprintf("action[decommissionMetaNode],clusterID[%v] Node[%v] OffLine failed,err[%v]",
-			c.Name, metaNode.Addr, err)
-		Warn(c.Name, msg)
-		return
-	}
-	c.deleteMetaNodeFromCache(metaNode)
-	msg = fmt.Sprintf("action[decommissionMetaNode],clusterID[%v] Node[%v] OffLine success", c.Name, metaNode.Addr)
-	Warn(c.Name, msg)
-	return
+// decommissionMetaNode starts a job moving all the meta partitions out of the meta node,
+// and the meta node is deleted once they are all moved.
+func (c *Cluster) decommissionMetaNode(metaNode *MetaNode) (job *decommissionJob, err error) {
+	log.LogWarnf("action[decommissionMetaNode],clusterID[%v] Node[%v] begin", c.Name, metaNode.Addr)
+	return c.createDecommissionJob(proto.DecommissionMetaNodeJob, metaNode.Addr, "")
 }
 
 func (c *Cluster) deleteMetaNodeFromCache(metaNode *MetaNode) {
@@ -1511,6 +1619,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		oldDescription    string
 		oldDpSelectorName string
 		oldDpSelectorParm string
+		oldEcDataNum      uint8
+		oldEcParityNum    uint8
+		oldTierPolicy     proto.TierPolicy
+		oldQos            proto.VolQos
+		oldCompression    string
+		oldChecksum       string
+		oldQuorumWrite    bool
+		oldPlacement      string
 		volUsedSpace      uint64
 	)
 	if vol, err = c.getVol(name); err != nil {
@@ -1564,6 +1680,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	oldDescription = vol.description
 	oldDpSelectorName = vol.dpSelectorName
 	oldDpSelectorParm = vol.dpSelectorParm
+	oldEcDataNum = vol.ecDataNum
+	oldEcParityNum = vol.ecParityNum
+	oldTierPolicy = vol.tierPolicy
+	oldQos = vol.qos
+	oldCompression = vol.compression
+	oldChecksum = vol.checksum
+	oldQuorumWrite = vol.quorumWrite
+	oldPlacement = vol.placementPolicy
 
 	vol.zoneName = newArgs.zoneName
 	vol.Capacity = newArgs.capacity
@@ -1579,6 +1703,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	}
 	vol.dpSelectorName = newArgs.dpSelectorName
 	vol.dpSelectorParm = newArgs.dpSelectorParm
+	vol.ecDataNum = newArgs.ecDataNum
+	vol.ecParityNum = newArgs.ecParityNum
+	vol.tierPolicy = newArgs.tierPolicy
+	vol.qos = newArgs.qos
+	vol.compression = newArgs.compression
+	vol.checksum = newArgs.checksum
+	vol.quorumWrite = newArgs.quorumWrite
+	vol.placementPolicy = newArgs.placement
 
 	if err = c.syncUpdateVol(vol); err != nil {
 		vol.Capacity = oldCapacity
@@ -1590,6 +1722,14 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		vol.description = oldDescription
 		vol.dpSelectorName = oldDpSelectorName
 		vol.dpSelectorParm = oldDpSelectorParm
+		vol.ecDataNum = oldEcDataNum
+		vol.ecParityNum = oldEcParityNum
+		vol.tierPolicy = oldTierPolicy
+		vol.qos = oldQos
+		vol.compression = oldCompression
+		vol.checksum = oldChecksum
+		vol.quorumWrite = oldQuorumWrite
+		vol.placementPolicy = oldPlacement
 
 		log.LogErrorf("action[updateVol] vol[%v] err[%v]", name, err)
 		err = proto.ErrPersistenceByRaft
@@ -1605,7 +1745,7 @@ errHandler:
 
 // Create a new volume.
 // By default we create 3 meta partitions and 10 data partitions during initialization.
-func (c *Cluster) createVol(name, owner, zoneName, description string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
+func (c *Cluster) createVol(name, owner, zoneName, description string, mpCount, dpReplicaNum, size, capacity int, followerRead, authenticate, crossZone, enableToken bool, checksum string) (vol *Vol, err error) {
 	var (
 		dataPartitionSize       uint64
 		readWriteDataPartitions int
@@ -1629,7 +1769,7 @@ func (c *Cluster) createVol(name, owner, zoneName, description string, mpCount,
 	} else if !crossZone {
 		zoneName = DefaultZoneName
 	}
-	if vol, err = c.doCreateVol(name, owner, zoneName, description, dataPartitionSize, uint64(capacity), dpReplicaNum, followerRead, authenticate, crossZone, enableToken); err != nil {
+	if vol, err = c.doCreateVol(name, owner, zoneName, description, dataPartitionSize, uint64(capacity), dpReplicaNum, followerRead, authenticate, crossZone, enableToken, checksum); err != nil {
 		goto errHandler
 	}
 	if err = vol.initMetaPartitions(c, mpCount); err != nil {
@@ -1657,7 +1797,7 @@ errHandler:
 	return
 }
 
-func (c *Cluster) doCreateVol(name, owner, zoneName, description string, dpSize, capacity uint64, dpReplicaNum int, followerRead, authenticate, crossZone, enableToken bool) (vol *Vol, err error) {
+func (c *Cluster) doCreateVol(name, owner, zoneName, description string, dpSize, capacity uint64, dpReplicaNum int, followerRead, authenticate, crossZone, enableToken bool, checksum string) (vol *Vol, err error) {
 	var id uint64
 	c.createVolMutex.Lock()
 	defer c.createVolMutex.Unlock()
@@ -1671,6 +1811,7 @@ func (c *Cluster) doCreateVol(name, owner, zoneName, description string, dpSize,
 		goto errHandler
 	}
 	vol = newVol(id, name, owner, zoneName, dpSize, capacity, uint8(dpReplicaNum), defaultReplicaNum, followerRead, authenticate, crossZone, enableToken, createTime, description)
+	vol.checksum = checksum
 	// refresh oss secure
 	vol.refreshOSSSecure()
 	if err = c.syncAddVol(vol); err != nil {
@@ -1727,7 +1868,7 @@ func (c *Cluster) updateInodeIDRange(volName string, start uint64) (err error) {
 }
 
 // Choose the target hosts from the available zones and meta nodes.
-func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []uint64, excludeHosts []string, replicaNum int, crossZone bool, specifiedZone string) (hosts []string, peers []proto.Peer, err error) {
+func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []uint64, excludeHosts []string, replicaNum int, crossZone bool, specifiedZone string, pl *placement) (hosts []string, peers []proto.Peer, err error) {
 	var (
 		zones      []*Zone
 		masterZone *Zone
@@ -1763,7 +1904,7 @@ func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []ui
 		return nil, nil, fmt.Errorf("action[chooseTargetMetaNodes] no enough zones [%v] to be selected, expect select [%v] zones", len(zones), zoneNum)
 	}
 	if len(zones) == 1 {
-		if hosts, peers, err = zones[0].getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, replicaNum); err != nil {
+		if hosts, peers, err = zones[0].getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, replicaNum, pl); err != nil {
 			log.LogErrorf("action[chooseTargetMetaNodes],err[%v]", err)
 			return
 		}
@@ -1777,7 +1918,7 @@ func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []ui
 	//replicaNum is equal with the number of allocated zones
 	if replicaNum == len(zones) {
 		for _, zone := range zones {
-			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, 1)
+			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, 1, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
@@ -1801,14 +1942,14 @@ func (c *Cluster) chooseTargetMetaHosts(excludeZone string, excludeNodeSets []ui
 	for _, zone := range zones {
 		if zone.name == masterZone.name {
 			rNum := replicaNum - len(zones) + 1
-			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, rNum)
+			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, rNum, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
 			hosts = append(hosts, selectedHosts...)
 			peers = append(peers, selectedPeers...)
 		} else {
-			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, 1)
+			selectedHosts, selectedPeers, e := zone.getAvailMetaNodeHosts(excludeNodeSets, excludeHosts, 1, pl)
 			if e != nil {
 				return nil, nil, errors.NewError(e)
 			}
@@ -1946,6 +2087,81 @@ func (c *Cluster) setDataNodeDeleteLimitRate(val uint64) (err error) {
 	return
 }
 
+// volQos returns the I/O limits of the volumes to be enforced by the data nodes.
+func (c *Cluster) volQos() (volQos map[string]*proto.VolQos) {
+	volQos = make(map[string]*proto.VolQos)
+	for name, vol := range c.allVols() {
+		if qos := vol.qos; qos.Enabled() {
+			volQos[name] = &qos
+		}
+	}
+	return
+}
+
+// volCompression returns the compression modes of the volumes with compression enabled.
+func (c *Cluster) volCompression() (volCompression map[string]string) {
+	volCompression = make(map[string]string)
+	for name, vol := range c.allVols() {
+		if vol.compression != proto.CompressionNone {
+			volCompression[name] = vol.compression
+		}
+	}
+	return
+}
+
+// volQuorumWrite returns the volumes acknowledging the appends once a majority of the replicas persist them.
+func (c *Cluster) volQuorumWrite() (volQuorumWrite map[string]bool) {
+	volQuorumWrite = make(map[string]bool)
+	for name, vol := range c.allVols() {
+		if vol.quorumWrite {
+			volQuorumWrite[name] = true
+		}
+	}
+	return
+}
+
+func (c *Cluster) setDataNodeDiskQos(iopsLimit, bandwidthLimit uint64) (err error) {
+	oldIopsLimit := atomic.LoadUint64(&c.cfg.DataNodeDiskIopsLimit)
+	oldBandwidthLimit := atomic.LoadUint64(&c.cfg.DataNodeDiskBandwidthLimit)
+	atomic.StoreUint64(&c.cfg.DataNodeDiskIopsLimit, iopsLimit)
+	atomic.StoreUint64(&c.cfg.DataNodeDiskBandwidthLimit, bandwidthLimit)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setDataNodeDiskQos] err[%v]", err)
+		atomic.StoreUint64(&c.cfg.DataNodeDiskIopsLimit, oldIopsLimit)
+		atomic.StoreUint64(&c.cfg.DataNodeDiskBandwidthLimit, oldBandwidthLimit)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
+func (c *Cluster) setDataNodeRepairBandwidth(nodeBandwidth, diskBandwidth uint64) (err error) {
+	oldNodeBandwidth := atomic.LoadUint64(&c.cfg.DataNodeRepairNodeBandwidth)
+	oldDiskBandwidth := atomic.LoadUint64(&c.cfg.DataNodeRepairDiskBandwidth)
+	atomic.StoreUint64(&c.cfg.DataNodeRepairNodeBandwidth, nodeBandwidth)
+	atomic.StoreUint64(&c.cfg.DataNodeRepairDiskBandwidth, diskBandwidth)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setDataNodeRepairBandwidth] err[%v]", err)
+		atomic.StoreUint64(&c.cfg.DataNodeRepairNodeBandwidth, oldNodeBandwidth)
+		atomic.StoreUint64(&c.cfg.DataNodeRepairDiskBandwidth, oldDiskBandwidth)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
+func (c *Cluster) setDataNodeScrubLimitRate(val uint64) (err error) {
+	oldVal := atomic.LoadUint64(&c.cfg.DataNodeScrubLimitRate)
+	atomic.StoreUint64(&c.cfg.DataNodeScrubLimitRate, val)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setDataNodeScrubLimitRate] err[%v]", err)
+		atomic.StoreUint64(&c.cfg.DataNodeScrubLimitRate, oldVal)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
 func (c *Cluster) setDataNodeAutoRepairLimitRate(val uint64) (err error) {
 	oldVal := atomic.LoadUint64(&c.cfg.DataNodeAutoRepairLimitRate)
 	atomic.StoreUint64(&c.cfg.DataNodeAutoRepairLimitRate, val)
@@ -1970,6 +2186,18 @@ func (c *Cluster) setMetaNodeDeleteWorkerSleepMs(val uint64) (err error) {
 	return
 }
 
+func (c *Cluster) setMetaNodeDefragThreshold(val uint64) (err error) {
+	oldVal := atomic.LoadUint64(&c.cfg.MetaNodeDefragThreshold)
+	atomic.StoreUint64(&c.cfg.MetaNodeDefragThreshold, val)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setMetaNodeDefragThreshold] err[%v]", err)
+		atomic.StoreUint64(&c.cfg.MetaNodeDefragThreshold, oldVal)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
 func (c *Cluster) setDisableAutoAllocate(disableAutoAllocate bool) (err error) {
 	oldFlag := c.DisableAutoAllocate
 	c.DisableAutoAllocate = disableAutoAllocate
@@ -1986,6 +2214,7 @@ func (c *Cluster) clearVols() {
 	c.volMutex.Lock()
 	defer c.volMutex.Unlock()
 	c.vols = make(map[string]*Vol, 0)
+	c.volAliases = make(map[string]string, 0)
 }
 
 func (c *Cluster) clearTopology() {
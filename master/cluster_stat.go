@@ -55,6 +55,7 @@ func (c *Cluster) updateStatInfo() {
 	c.updateMetaNodeStatInfo()
 	c.updateVolStatInfo()
 	c.updateZoneStatInfo()
+	c.updateCapacityForecasts()
 }
 
 func (c *Cluster) updateZoneStatInfo() {
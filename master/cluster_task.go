@@ -103,6 +103,7 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 		oldHosts        []string
 		zones           []string
 		excludeZone     string
+		pl              *placement
 	)
 	log.LogWarnf("action[decommissionMetaPartition],volName[%v],nodeAddr[%v],partitionID[%v] begin", mp.volName, nodeAddr, mp.PartitionID)
 	mp.RLock()
@@ -112,6 +113,11 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 	}
 	oldHosts = mp.Hosts
 	mp.RUnlock()
+	// the replicas of the partition being rebalanced are changed by the rebalancer only
+	if c.isMigrating(proto.RebalanceMetaPartition, mp.PartitionID) {
+		err = fmt.Errorf("meta partition[%v] is being migrated", mp.PartitionID)
+		goto errHandler
+	}
 	if err = c.validateDecommissionMetaPartition(mp, nodeAddr); err != nil {
 		goto errHandler
 	}
@@ -124,10 +130,11 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 	if ns, err = zone.getNodeSet(metaNode.NodeSetID); err != nil {
 		goto errHandler
 	}
-	if _, newPeers, err = ns.getAvailMetaNodeHosts(oldHosts, 1); err != nil {
+	pl = c.metaPlacement(mp, nodeAddr)
+	if _, newPeers, err = ns.getAvailMetaNodeHosts(oldHosts, 1, pl); err != nil {
 		// choose a meta node in other node set in the same zone
 		excludeNodeSets = append(excludeNodeSets, ns.ID)
-		if _, newPeers, err = zone.getAvailMetaNodeHosts(excludeNodeSets, oldHosts, 1); err != nil {
+		if _, newPeers, err = zone.getAvailMetaNodeHosts(excludeNodeSets, oldHosts, 1, pl); err != nil {
 			zones = mp.getLiveZones(nodeAddr)
 			if len(zones) == 0 {
 				excludeZone = zone.name
@@ -135,7 +142,7 @@ func (c *Cluster) decommissionMetaPartition(nodeAddr string, mp *MetaPartition)
 				excludeZone = zones[0]
 			}
 			// choose a meta node in other zone
-			if _, newPeers, err = c.chooseTargetMetaHosts(excludeZone, excludeNodeSets, oldHosts, 1, false, ""); err != nil {
+			if _, newPeers, err = c.chooseTargetMetaHosts(excludeZone, excludeNodeSets, oldHosts, 1, false, "", pl); err != nil {
 				goto errHandler
 			}
 		}
@@ -166,6 +173,38 @@ errHandler:
 	return
 }
 
+// migrateMetaPartition adds the replica of the meta partition on the target meta node, and removes the one
+// on the source in the background once the target catches up. It is used by the rebalancer only.
+func (c *Cluster) migrateMetaPartition(mp *MetaPartition, srcAddr, targetAddr string) (err error) {
+	var m *partitionMigration
+	if m, err = c.startMigration(proto.RebalanceMetaPartition, mp.PartitionID, srcAddr, targetAddr); err != nil {
+		return fmt.Errorf("vol[%v],meta partition[%v],err[%v]", mp.volName, mp.PartitionID, err)
+	}
+	defer func() {
+		if err != nil {
+			c.finishMigration(m)
+		}
+	}()
+	if err = c.checkMetaPlacement(mp, srcAddr, targetAddr); err != nil {
+		return
+	}
+	if err = c.addMetaReplica(mp, targetAddr); err != nil {
+		mp.RLock()
+		rollback := contains(mp.Hosts, targetAddr)
+		mp.RUnlock()
+		if rollback {
+			c.deleteMetaReplica(mp, targetAddr, false)
+		}
+		return
+	}
+	mp.IsRecover = true
+	mp.RLock()
+	c.syncUpdateMetaPartition(mp)
+	mp.RUnlock()
+	go c.finishMetaMigration(mp, m)
+	return
+}
+
 func (c *Cluster) validateDecommissionMetaPartition(mp *MetaPartition, nodeAddr string) (err error) {
 	mp.RLock()
 	defer mp.RUnlock()
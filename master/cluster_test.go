@@ -288,3 +288,28 @@ func TestUpdateInodeIDUpperBound(t *testing.T) {
 	}
 
 }
+
+func TestForecastCapacity(t *testing.T) {
+	h := &usageHistory{scope: proto.ForecastScopeVol, name: "forecast"}
+	window := int64(defaultCapacityHistoryDays * secondsPerDay)
+	start := time.Now().Unix()
+	// 10GB a day on a volume of 100GB
+	for i := int64(0); i <= 144; i++ {
+		now := start + i*600
+		h.record(usageSample{time: now, used: 10 + 10*float64(i)/144, total: 100}, window)
+	}
+	f := h.forecast
+	if f.GrowthGBPerDay != 10 || f.DaysToFull != 8 || f.HistoryHours != 24 {
+		t.Errorf("forecast growth[%v] daysToFull[%v] historyHours[%v], expected 10, 8 and 24",
+			f.GrowthGBPerDay, f.DaysToFull, f.HistoryHours)
+		return
+	}
+	if len(h.samples) != 49 {
+		t.Errorf("samples[%v] kept, expected one every %v seconds", len(h.samples), forecastSampleInterval)
+		return
+	}
+	h.record(usageSample{time: start + 25*3600 + window, used: 20, total: 100}, window)
+	if len(h.samples) != 1 || h.forecast.DaysToFull != -1 {
+		t.Errorf("samples[%v] daysToFull[%v] after the window, expected 1 and -1", len(h.samples), h.forecast.DaysToFull)
+	}
+}
@@ -37,6 +37,10 @@ const (
 	cfgMetaNodeReservedMem              = "metaNodeReservedMem"
 	heartbeatPortKey                    = "heartbeatPort"
 	replicaPortKey                      = "replicaPort"
+	cfgAdminAuth                        = "adminAuth"
+	cfgCapacityHistoryDays              = "capacityHistoryDays"
+	cfgCapacityWarnDays                 = "capacityWarnDays"
+	cfgTrustedProxies                   = "trustedProxies"
 )
 
 //default value
@@ -85,12 +89,22 @@ type clusterConfig struct {
 	MetaNodeDeleteBatchCount            uint64 //metanode delete batch count
 	DataNodeDeleteLimitRate             uint64 //datanode delete limit rate
 	MetaNodeDeleteWorkerSleepMs         uint64 //datanode delete limit rate
+	MetaNodeDefragThreshold             uint64 //metanode extent count of the inodes reported as fragmented
 	DataNodeAutoRepairLimitRate         uint64 //datanode autorepair limit rate
+	DataNodeScrubLimitRate              uint64 //datanode scrub limit rate, MB/s on each disk
+	DataNodeDiskIopsLimit               uint64 //datanode requests limit per second on each disk
+	DataNodeDiskBandwidthLimit          uint64 //datanode bandwidth limit, MB/s on each disk
+	DataNodeRepairNodeBandwidth         uint64 //datanode repair bandwidth limit, MB/s on each node
+	DataNodeRepairDiskBandwidth         uint64 //datanode repair bandwidth limit, MB/s on each disk
 	peers                               []raftstore.PeerAddress
 	peerAddrs                           []string
 	heartbeatPort                       int64
 	replicaPort                         int64
 	diffSpaceUsage                      uint64
+	adminAuth                           bool     // admin APIs are authenticated by the signatures of the users
+	capacityHistoryDays                 int64    // days of the usage kept to forecast the capacity
+	capacityWarnDays                    int64    // warn if the capacity is projected to be full within the days, 0 disables it
+	trustedProxies                      []string // IPs of the proxies and the masters adding X-Forwarded-For
 }
 
 func newClusterConfig() (cfg *clusterConfig) {
@@ -107,9 +121,19 @@ func newClusterConfig() (cfg *clusterConfig) {
 	cfg.MetaNodeThreshold = defaultMetaPartitionMemUsageThreshold
 	cfg.metaNodeReservedMem = defaultMetaNodeReservedMem
 	cfg.diffSpaceUsage = defaultDiffSpaceUsage
+	cfg.capacityHistoryDays = defaultCapacityHistoryDays
+	cfg.capacityWarnDays = defaultCapacityWarnDays
 	return
 }
 
+func (cfg *clusterConfig) parseTrustedProxies(proxies string) {
+	for _, ip := range strings.Split(proxies, commaSplit) {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			cfg.trustedProxies = append(cfg.trustedProxies, ip)
+		}
+	}
+}
+
 func parsePeerAddr(peerAddr string) (id uint64, ip string, port uint64, err error) {
 	peerStr := strings.Split(peerAddr, colonSplit)
 	id, err = strconv.ParseUint(peerStr[0], 10, 64)
@@ -133,6 +157,8 @@ func (cfg *clusterConfig) parsePeers(peerStr string) error {
 			return err
 		}
 		cfg.peers = append(cfg.peers, raftstore.PeerAddress{Peer: proto.Peer{ID: id}, Address: ip, HeartbeatPort: int(cfg.heartbeatPort), ReplicaPort: int(cfg.replicaPort)})
+		// the followers forward the requests to the leader
+		cfg.trustedProxies = append(cfg.trustedProxies, ip)
 		address := fmt.Sprintf("%v:%v", ip, port)
 		fmt.Println(address)
 		AddrDatabase[id] = address
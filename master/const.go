@@ -51,10 +51,40 @@ const (
 	nodeDeleteBatchCountKey = "batchCount"
 	nodeMarkDeleteRateKey   = "markDeleteRate"
 	nodeDeleteWorkerSleepMs = "deleteWorkerSleepMs"
+	nodeDefragThresholdKey  = "defragThreshold"
 	nodeAutoRepairRateKey   = "autoRepairRate"
+	nodeScrubRateKey        = "scrubRate"
 	descriptionKey          = "description"
 	dpSelectorNameKey       = "dpSelectorName"
 	dpSelectorParmKey       = "dpSelectorParm"
+	ecDataNumKey            = "ecDataNum"
+	ecParityNumKey          = "ecParityNum"
+	coldTimeKey             = "coldTime"
+	tierColdDaysKey         = "tierColdDays"
+	tierEndpointKey         = "tierEndpoint"
+	tierRegionKey           = "tierRegion"
+	tierBucketKey           = "tierBucket"
+	tierAccessKeyKey        = "tierAccessKey"
+	tierSecretKeyKey        = "tierSecretKey"
+	tierRehydrateKey        = "tierRehydrate"
+	iopsLimitKey            = "iopsLimit"
+	bandwidthLimitKey       = "bandwidthLimit"
+	compressionKey          = "compression"
+	checksumKey             = "checksum"
+	quorumWriteKey          = "quorumWrite"
+	placementPolicyKey      = "placementPolicy"
+	nodeDiskIopsLimitKey    = "diskIopsLimit"
+	nodeDiskBandwidthKey    = "diskBandwidthLimit"
+	nodeRepairBandwidthKey  = "repairNodeBandwidth"
+	diskRepairBandwidthKey  = "repairDiskBandwidth"
+	maxConcurrencyKey       = "maxConcurrency"
+	errorThresholdKey       = "errorThreshold"
+	badDiskLimitKey         = "badDiskLimit"
+	sinceKey                = "since"
+	limitKey                = "limit"
+	opKey                   = "op"
+	newNameKey              = "newName"
+	sourceKey               = "source"
 )
 
 const (
@@ -96,6 +126,8 @@ const (
 	retrySendSyncTaskInternal                    = 3 * time.Second
 	defaultRangeOfCountDifferencesAllowed        = 50
 	defaultMinusOfMaxInodeID                     = 1000
+	maxEcShardNum                                = 16
+	defaultEcColdTime                            = 7 * 24 * 3600
 )
 
 const (
@@ -140,6 +172,12 @@ const (
 	OpSyncAddToken    uint32 = 0x20
 	OpSyncDelToken    uint32 = 0x21
 	OpSyncUpdateToken uint32 = 0x22
+
+	opSyncPutDecommissionJob    uint32 = 0x23
+	opSyncDeleteDecommissionJob uint32 = 0x24
+
+	opSyncPutMigration    uint32 = 0x25
+	opSyncDeleteMigration uint32 = 0x26
 )
 
 const (
@@ -153,6 +191,8 @@ const (
 	clusterAcronym        = "c"
 	nodeSetAcronym        = "s"
 	tokenAcronym          = "t"
+	jobAcronym            = "job"
+	migrationAcronym      = "migration"
 	maxDataPartitionIDKey = keySeparator + "max_dp_id"
 	maxMetaPartitionIDKey = keySeparator + "max_mp_id"
 	maxCommonIDKey        = keySeparator + "max_common_id"
@@ -163,6 +203,8 @@ const (
 	metaPartitionPrefix   = keySeparator + metaPartitionAcronym + keySeparator
 	clusterPrefix         = keySeparator + clusterAcronym + keySeparator
 	nodeSetPrefix         = keySeparator + nodeSetAcronym + keySeparator
+	jobPrefix             = keySeparator + jobAcronym + keySeparator
+	migrationPrefix       = keySeparator + migrationAcronym + keySeparator
 
 	akAcronym      = "ak"
 	userAcronym    = "user"
@@ -43,7 +43,9 @@ type DataNode struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	DiskReports               []*proto.DiskReport
 	ToBeOffline               bool
+	Labels                    map[string]string `graphql:"-"` // the rack, the host and the power domain registered by the node
 }
 
 func newDataNode(addr, zoneName, clusterID string) (dataNode *DataNode) {
@@ -89,6 +91,7 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 	dataNode.DataPartitionCount = resp.CreatedPartitionCnt
 	dataNode.DataPartitionReports = resp.PartitionReports
 	dataNode.BadDisks = resp.BadDisks
+	dataNode.DiskReports = resp.DiskReports
 	if dataNode.Total == 0 {
 		dataNode.UsageRatio = 0.0
 	} else {
@@ -128,6 +131,13 @@ func (dataNode *DataNode) GetAddr() string {
 	return dataNode.Addr
 }
 
+// GetLabel implements "GetLabel" in the Node interface
+func (dataNode *DataNode) GetLabel(key string) string {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return nodeLabel(dataNode.Labels, dataNode.Addr, key)
+}
+
 // SetCarry implements "SetCarry" in the Node interface
 func (dataNode *DataNode) SetCarry(carry float64) {
 	dataNode.Lock()
@@ -148,10 +158,14 @@ func (dataNode *DataNode) clean() {
 	dataNode.TaskManager.exitCh <- struct{}{}
 }
 
-func (dataNode *DataNode) createHeartbeatTask(masterAddr string) (task *proto.AdminTask) {
+func (dataNode *DataNode) createHeartbeatTask(masterAddr string, volQos map[string]*proto.VolQos,
+	volCompression map[string]string, volQuorumWrite map[string]bool) (task *proto.AdminTask) {
 	request := &proto.HeartBeatRequest{
-		CurrTime:   time.Now().Unix(),
-		MasterAddr: masterAddr,
+		CurrTime:       time.Now().Unix(),
+		MasterAddr:     masterAddr,
+		VolQos:         volQos,
+		VolCompression: volCompression,
+		VolQuorumWrite: volQuorumWrite,
 	}
 	task = proto.NewAdminTask(proto.OpDataNodeHeartbeat, dataNode.Addr, request)
 	return
@@ -36,6 +36,7 @@ type DataPartition struct {
 	isRecover      bool
 	Replicas       []*DataReplica
 	Hosts          []string // host addresses
+	EcHosts        []string // host addresses of the erasure coded shards
 	Peers          []proto.Peer
 	offlineMutex   sync.RWMutex
 	sync.RWMutex
@@ -44,6 +45,10 @@ type DataPartition struct {
 	MissingNodes            map[string]int64 // key: address of the missing node, value: when the node is missing
 	VolName                 string
 	VolID                   uint64
+	Checksum                string // checksum type of the data, decided by the volume on creation
+	SharedExtentID          uint64 // extents up to it are shared by the cloned volumes, and read only
+	SharedRanges            []*proto.SharedExtentRange
+	SharedTinySizes         map[uint64]uint64 // data of the tiny extents below the sizes is shared by the cloned volumes
 	modifyTime              int64
 	createTime              int64
 	lastWarnTime            int64
@@ -121,10 +126,22 @@ func (partition *DataPartition) createTaskToRemoveRaftMember(removePeer proto.Pe
 	return
 }
 
-func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dataPartitionSize uint64, peers []proto.Peer, hosts []string, createType int) (task *proto.AdminTask) {
+func (partition *DataPartition) createTaskToConvertToEc(leaderAddr string, dataNum, parityNum uint8, coldTime int64) (task *proto.AdminTask) {
+	task = proto.NewAdminTask(proto.OpConvertDataPartitionToEc, leaderAddr, newConvertDataPartitionToEcRequest(
+		partition.PartitionID, dataNum, parityNum, partition.EcHosts, coldTime))
+	partition.resetTaskID(task)
+	return
+}
+
+func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dataPartitionSize uint64, peers []proto.Peer, hosts []string, createType int, excludedDisks []string) (task *proto.AdminTask) {
 
-	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, newCreateDataPartitionRequest(
-		partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType))
+	req := newCreateDataPartitionRequest(partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType)
+	req.Checksum = partition.Checksum
+	req.SharedExtentID = partition.SharedExtentID
+	req.SharedRanges = partition.SharedRanges
+	req.SharedTinySizes = partition.SharedTinySizes
+	req.ExcludedDisks = excludedDisks
+	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, req)
 	partition.resetTaskID(task)
 	return
 }
@@ -135,6 +152,25 @@ func (partition *DataPartition) createTaskToDeleteDataPartition(addr string) (ta
 	return
 }
 
+func (partition *DataPartition) createTaskToShareDataPartition(addr string, req *proto.ShareDataPartitionRequest) (task *proto.AdminTask) {
+	task = proto.NewAdminTask(proto.OpShareDataPartition, addr, req)
+	partition.resetTaskID(task)
+	return
+}
+
+// newShareRequest returns the request to share the extents up to the extent id, with the ranges and the sizes of
+// the tiny extents shared currently.
+func (partition *DataPartition) newShareRequest(sharedExtentID uint64) *proto.ShareDataPartitionRequest {
+	partition.RLock()
+	defer partition.RUnlock()
+	return &proto.ShareDataPartitionRequest{
+		PartitionId:     partition.PartitionID,
+		SharedExtentID:  sharedExtentID,
+		SharedRanges:    partition.SharedRanges,
+		SharedTinySizes: partition.SharedTinySizes,
+	}
+}
+
 func (partition *DataPartition) resetTaskID(t *proto.AdminTask) {
 	t.ID = fmt.Sprintf("%v_DataPartitionID[%v]", t.ID, partition.PartitionID)
 	t.PartitionID = partition.PartitionID
@@ -258,6 +294,20 @@ func (partition *DataPartition) getReplica(addr string) (replica *DataReplica, e
 	return nil, errors.Trace(dataReplicaNotFound(addr), "%v not found", addr)
 }
 
+// reportClientChecksumMismatch counts the checksum mismatch found by a client reading the replica of the address.
+func (partition *DataPartition) reportClientChecksumMismatch(addr string) (err error) {
+	partition.Lock()
+	defer partition.Unlock()
+	replica, err := partition.getReplica(addr)
+	if err != nil {
+		return
+	}
+	replica.ClientChecksumMismatches++
+	log.LogWarnf("action[reportClientChecksumMismatch] partition(%v) replica(%v) mismatches(%v)",
+		partition.PartitionID, addr, replica.ClientChecksumMismatches)
+	return
+}
+
 func (partition *DataPartition) convertToDataPartitionResponse() (dpr *proto.DataPartitionResponse) {
 	dpr = new(proto.DataPartitionResponse)
 	partition.Lock()
@@ -269,9 +319,25 @@ func (partition *DataPartition) convertToDataPartitionResponse() (dpr *proto.Dat
 	copy(dpr.Hosts, partition.Hosts)
 	dpr.LeaderAddr = partition.getLeaderAddr()
 	dpr.IsRecover = partition.isRecover
+	dpr.Checksum = partition.Checksum
+	dpr.CompactingTinyExtents = partition.getCompactingTinyExtents()
+	dpr.SharedExtentID = partition.SharedExtentID
 	return
 }
 
+// getCompactingTinyExtents returns the tiny extents compacting on the first host, which allocates the tiny extents.
+func (partition *DataPartition) getCompactingTinyExtents() []uint64 {
+	if len(partition.Hosts) == 0 {
+		return nil
+	}
+	for _, replica := range partition.Replicas {
+		if replica.Addr == partition.Hosts[0] {
+			return replica.CompactingTinyExtents
+		}
+	}
+	return nil
+}
+
 func (partition *DataPartition) getLeaderAddr() (leaderAddr string) {
 	for _, replica := range partition.Replicas {
 		if replica.IsLeader {
@@ -526,6 +592,14 @@ func (partition *DataPartition) updateMetric(vr *proto.PartitionReport, dataNode
 	replica.setAlive()
 	replica.IsLeader = vr.IsLeader
 	replica.NeedsToCompare = vr.NeedCompare
+	replica.ScrubTime = vr.ScrubTime
+	replica.ScrubBadBlocks = vr.ScrubBadBlocks
+	replica.ScrubRepaired = vr.ScrubRepaired
+	replica.CompressedRawSize = vr.CompressedRawSize
+	replica.CompressedSize = vr.CompressedSize
+	replica.TinyReclaimableSize = vr.TinyReclaimableSize
+	replica.CompactingTinyExtents = vr.CompactingTinyExtents
+	replica.ChecksumMismatches = vr.ChecksumMismatches
 	if replica.DiskPath != vr.DiskPath && vr.DiskPath != "" {
 		oldDiskPath := replica.DiskPath
 		replica.DiskPath = vr.DiskPath
@@ -673,10 +747,13 @@ func (partition *DataPartition) ToProto(c *Cluster) *proto.DataPartitionInfo {
 		Status:                  partition.Status,
 		Replicas:                replicas,
 		Hosts:                   partition.Hosts,
+		EcHosts:                 partition.EcHosts,
 		Peers:                   partition.Peers,
 		Zones:                   zones,
 		MissingNodes:            partition.MissingNodes,
 		VolName:                 partition.VolName,
+		Checksum:                partition.Checksum,
+		SharedExtentID:          partition.SharedExtentID,
 		VolID:                   partition.VolID,
 		FileInCoreMap:           fileInCoreMap,
 		OfflinePeerID:           partition.OfflinePeerID,
@@ -34,6 +34,7 @@ type DataPartitionMap struct {
 	partitions             []*DataPartition
 	responseCache          []byte
 	volName                string
+	sharedPartitions       []*DataPartition // partitions of the source volumes shared by the cloned volume, read only
 }
 
 func newDataPartitionMap(volName string) (dpMap *DataPartitionMap) {
@@ -78,6 +79,12 @@ func (dpMap *DataPartitionMap) put(dp *DataPartition) {
 	}
 }
 
+func (dpMap *DataPartitionMap) setSharedPartitions(partitions []*DataPartition) {
+	dpMap.Lock()
+	defer dpMap.Unlock()
+	dpMap.sharedPartitions = partitions
+}
+
 func (dpMap *DataPartitionMap) setReadWriteDataPartitions(readWrites int, clusterName string) {
 	dpMap.Lock()
 	defer dpMap.Unlock()
@@ -137,6 +144,14 @@ func (dpMap *DataPartitionMap) getDataPartitionsView(minPartitionID uint64) (dpR
 		dpResp := dp.convertToDataPartitionResponse()
 		dpResps = append(dpResps, dpResp)
 	}
+	for _, dp := range dpMap.sharedPartitions {
+		if dp.PartitionID <= minPartitionID {
+			continue
+		}
+		dpResp := dp.convertToDataPartitionResponse()
+		dpResp.Status = proto.ReadOnly
+		dpResps = append(dpResps, dpResp)
+	}
 
 	return
 }
@@ -0,0 +1,542 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	decommissionJobInterval      = 10 * time.Second
+	decommissionJobBatch         = 10 // partitions moved by a job at the same time
+	decommissionJobMaxRetries    = 5
+	decommissionJobRetryBackoff  = time.Minute // doubled on each retry
+	decommissionJobMaxBackoff    = 30 * time.Minute
+	decommissionJobRetentionTime = 7 * 24 * time.Hour // of the finished jobs
+)
+
+// A decommission job moves the partitions out of a data node, a meta node or a disk of a data node. The job and
+// the state of each partition are persisted by the raft of the masters, so the new leader resumes the jobs.
+// The partitions failed to move are retried with backoff, and the node is deleted once all of its partitions
+// are moved and recovered. The job lock guards the state of the job only, and is never held across the raft
+// operations, so the views and the cancellation are not blocked by the migrations.
+type decommissionJob struct {
+	*proto.DecommissionJob
+	sync.Mutex
+	runLock sync.Mutex // serializes the rounds of the job
+}
+
+func (job *decommissionJob) finished() bool {
+	return job.Status != proto.JobRunning
+}
+
+func (job *decommissionJob) view() (view *proto.DecommissionJobView) {
+	view = &proto.DecommissionJobView{
+		ID:         job.ID,
+		Type:       job.Type,
+		Addr:       job.Addr,
+		DiskPath:   job.DiskPath,
+		Status:     job.Status,
+		Msg:        job.Msg,
+		CreateTime: job.CreateTime,
+		UpdateTime: job.UpdateTime,
+		Total:      len(job.Partitions),
+	}
+	for _, p := range job.Partitions {
+		switch p.Status {
+		case proto.PartitionPending:
+			view.Pending++
+		case proto.PartitionMigrating:
+			view.Migrating++
+		case proto.PartitionDone:
+			view.Done++
+		case proto.PartitionFailed:
+			view.Failed++
+		}
+	}
+	return
+}
+
+func (job *decommissionJob) hasPartition(partitionID uint64) bool {
+	for _, p := range job.Partitions {
+		if p.PartitionID == partitionID {
+			return true
+		}
+	}
+	return false
+}
+
+func (job *decommissionJob) addPartition(partitionID uint64, volName string) {
+	job.Partitions = append(job.Partitions, &proto.DecommissionPartition{
+		PartitionID: partitionID,
+		VolName:     volName,
+		Status:      proto.PartitionPending,
+	})
+}
+
+// retryDecommissionPartition schedules the partition failed to move to be retried with backoff,
+// or fails it after the max retries.
+func retryDecommissionPartition(p *proto.DecommissionPartition, err error) {
+	p.Retries++
+	p.Msg = err.Error()
+	if p.Retries >= decommissionJobMaxRetries {
+		p.Status = proto.PartitionFailed
+		return
+	}
+	backoff := decommissionJobRetryBackoff << uint(p.Retries-1)
+	if backoff > decommissionJobMaxBackoff {
+		backoff = decommissionJobMaxBackoff
+	}
+	p.Status = proto.PartitionPending
+	p.NextRetryTime = time.Now().Add(backoff).Unix()
+}
+
+// key=#job#id
+func (c *Cluster) syncPutDecommissionJob(job *decommissionJob) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncPutDecommissionJob
+	metadata.K = jobPrefix + strconv.FormatUint(job.ID, 10)
+	job.Lock()
+	metadata.V, err = json.Marshal(job.DecommissionJob)
+	job.Unlock()
+	if err != nil {
+		return
+	}
+	return c.submit(metadata)
+}
+
+func (c *Cluster) syncDeleteDecommissionJob(job *decommissionJob) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncDeleteDecommissionJob
+	metadata.K = jobPrefix + strconv.FormatUint(job.ID, 10)
+	return c.submit(metadata)
+}
+
+func (c *Cluster) loadDecommissionJobs() (err error) {
+	result, err := c.fsm.store.SeekForPrefix([]byte(jobPrefix))
+	if err != nil {
+		err = fmt.Errorf("action[loadDecommissionJobs],err:%v", err.Error())
+		return err
+	}
+	for _, value := range result {
+		job := &decommissionJob{DecommissionJob: &proto.DecommissionJob{}}
+		if err = json.Unmarshal(value, job.DecommissionJob); err != nil {
+			err = fmt.Errorf("action[loadDecommissionJobs],value:%v,err:%v", value, err)
+			return err
+		}
+		c.decommissionJobs.Store(job.ID, job)
+		log.LogInfof("action[loadDecommissionJobs],job[%v] type[%v] addr[%v] status[%v]", job.ID, job.Type, job.Addr, job.Status)
+	}
+	return
+}
+
+func (c *Cluster) clearDecommissionJobs() {
+	c.decommissionJobs.Range(func(key, value interface{}) bool {
+		c.decommissionJobs.Delete(key)
+		return true
+	})
+}
+
+func (c *Cluster) decommissionJob(id uint64) (job *decommissionJob, err error) {
+	value, ok := c.decommissionJobs.Load(id)
+	if !ok {
+		return nil, proto.ErrJobNotExists
+	}
+	return value.(*decommissionJob), nil
+}
+
+func (c *Cluster) allDecommissionJobs() (jobs []*decommissionJob) {
+	jobs = make([]*decommissionJob, 0)
+	c.decommissionJobs.Range(func(key, value interface{}) bool {
+		jobs = append(jobs, value.(*decommissionJob))
+		return true
+	})
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return
+}
+
+// createDecommissionJob persists a job moving the partitions out of the node or the disk, and runs its first
+// round at once. Only one job runs on a node or a disk at the same time.
+func (c *Cluster) createDecommissionJob(jobType, addr, diskPath string) (job *decommissionJob, err error) {
+	for _, running := range c.allDecommissionJobs() {
+		running.Lock()
+		exists := !running.finished() && running.Type == jobType && running.Addr == addr && running.DiskPath == diskPath
+		running.Unlock()
+		if exists {
+			return nil, proto.ErrJobExists
+		}
+	}
+	var id uint64
+	if id, err = c.idAlloc.allocateCommonID(); err != nil {
+		return
+	}
+	now := time.Now().Unix()
+	job = &decommissionJob{DecommissionJob: &proto.DecommissionJob{
+		ID:         id,
+		Type:       jobType,
+		Addr:       addr,
+		DiskPath:   diskPath,
+		Status:     proto.JobRunning,
+		CreateTime: now,
+		UpdateTime: now,
+		Partitions: make([]*proto.DecommissionPartition, 0),
+	}}
+	if err = c.collectDecommissionPartitions(job); err != nil {
+		return
+	}
+	if err = c.syncPutDecommissionJob(job); err != nil {
+		return
+	}
+	c.decommissionJobs.Store(job.ID, job)
+	Warn(c.Name, fmt.Sprintf("action[createDecommissionJob] clusterID[%v] job[%v] type[%v] addr[%v] disk[%v] partitions[%v]",
+		c.Name, job.ID, jobType, addr, diskPath, len(job.Partitions)))
+	c.runDecommissionJob(job)
+	return
+}
+
+// collectDecommissionPartitions adds the partitions on the node or the disk not yet in the job.
+func (c *Cluster) collectDecommissionPartitions(job *decommissionJob) (err error) {
+	switch job.Type {
+	case proto.DecommissionDataNodeJob:
+		for _, dp := range c.getAllDataPartitionByDataNode(job.Addr) {
+			if !job.hasPartition(dp.PartitionID) {
+				job.addPartition(dp.PartitionID, dp.VolName)
+			}
+		}
+	case proto.DecommissionDiskJob:
+		var dataNode *DataNode
+		if dataNode, err = c.dataNode(job.Addr); err != nil {
+			return
+		}
+		for _, dp := range dataNode.badPartitions(job.DiskPath, c) {
+			if !job.hasPartition(dp.PartitionID) {
+				job.addPartition(dp.PartitionID, dp.VolName)
+			}
+		}
+	case proto.DecommissionMetaNodeJob:
+		for _, mp := range c.getAllMetaPartitionByMetaNode(job.Addr) {
+			if !job.hasPartition(mp.PartitionID) {
+				job.addPartition(mp.PartitionID, mp.volName)
+			}
+		}
+	}
+	return
+}
+
+func (c *Cluster) scheduleToRunDecommissionJobs() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.runDecommissionJobs()
+			}
+			time.Sleep(decommissionJobInterval)
+		}
+	}()
+}
+
+func (c *Cluster) runDecommissionJobs() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("runDecommissionJobs occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"runDecommissionJobs occurred panic")
+		}
+	}()
+	for _, job := range c.allDecommissionJobs() {
+		job.Lock()
+		expired := job.finished() && time.Since(time.Unix(job.UpdateTime, 0)) > decommissionJobRetentionTime
+		job.Unlock()
+		if expired {
+			if err := c.syncDeleteDecommissionJob(job); err != nil {
+				log.LogErrorf("action[runDecommissionJobs] delete job[%v] err[%v]", job.ID, err)
+				continue
+			}
+			c.decommissionJobs.Delete(job.ID)
+			continue
+		}
+		c.runDecommissionJob(job)
+	}
+}
+
+// runDecommissionJob moves a batch of the pending partitions, checks the recovery of the ones moved,
+// and finishes the job once all the partitions are done or failed.
+func (c *Cluster) runDecommissionJob(job *decommissionJob) {
+	job.runLock.Lock()
+	defer job.runLock.Unlock()
+	job.Lock()
+	if job.finished() {
+		job.Unlock()
+		return
+	}
+	now := time.Now().Unix()
+	batch := make([]*proto.DecommissionPartition, 0, decommissionJobBatch)
+	for _, p := range job.Partitions {
+		if p.Status == proto.PartitionMigrating {
+			c.checkDecommissionPartitionRecovery(job, p)
+		}
+		if p.Status == proto.PartitionPending && p.NextRetryTime <= now && len(batch) < decommissionJobBatch {
+			batch = append(batch, p)
+		}
+	}
+	job.Unlock()
+	c.setDecommissionNodeOffline(job, true)
+	var wg sync.WaitGroup
+	for _, p := range batch {
+		wg.Add(1)
+		go func(p *proto.DecommissionPartition) {
+			defer wg.Done()
+			c.runDecommissionJobPartition(job, p)
+		}(p)
+	}
+	wg.Wait()
+	c.checkDecommissionJobFinished(job)
+	job.Lock()
+	job.UpdateTime = time.Now().Unix()
+	job.Unlock()
+	if err := c.syncPutDecommissionJob(job); err != nil {
+		log.LogErrorf("action[runDecommissionJob] persist job[%v] err[%v]", job.ID, err)
+	}
+}
+
+// runDecommissionJobPartition moves the partition unless the job is cancelled meanwhile.
+func (c *Cluster) runDecommissionJobPartition(job *decommissionJob, p *proto.DecommissionPartition) {
+	job.Lock()
+	cancelled := job.finished()
+	job.Unlock()
+	if cancelled {
+		return
+	}
+	err := c.decommissionJobPartition(job, p.PartitionID)
+	job.Lock()
+	defer job.Unlock()
+	if err != nil {
+		retryDecommissionPartition(p, err)
+		return
+	}
+	p.Status = proto.PartitionMigrating
+	p.Msg = ""
+}
+
+func (c *Cluster) decommissionJobPartition(job *decommissionJob, partitionID uint64) (err error) {
+	if job.Type == proto.DecommissionMetaNodeJob {
+		mp, err := c.getMetaPartitionByID(partitionID)
+		if err != nil {
+			return nil
+		}
+		return c.decommissionMetaPartition(job.Addr, mp)
+	}
+	dp, err := c.getDataPartitionByID(partitionID)
+	if err != nil {
+		return nil
+	}
+	errMsg := dataNodeOfflineErr
+	if job.Type == proto.DecommissionDiskJob {
+		errMsg = diskOfflineErr
+	}
+	return c.decommissionDataPartition(job.Addr, dp, errMsg)
+}
+
+// checkDecommissionPartitionRecovery marks the partition moved done once it is recovered, or deleted.
+func (c *Cluster) checkDecommissionPartitionRecovery(job *decommissionJob, p *proto.DecommissionPartition) {
+	var onNode, recovering bool
+	if job.Type == proto.DecommissionMetaNodeJob {
+		mp, err := c.getMetaPartitionByID(p.PartitionID)
+		if err != nil {
+			p.Status = proto.PartitionDone
+			return
+		}
+		mp.RLock()
+		onNode, recovering = contains(mp.Hosts, job.Addr), mp.IsRecover
+		mp.RUnlock()
+	} else {
+		dp, err := c.getDataPartitionByID(p.PartitionID)
+		if err != nil {
+			p.Status = proto.PartitionDone
+			return
+		}
+		dp.RLock()
+		onNode, recovering = dp.hasHost(job.Addr), dp.isRecover
+		dp.RUnlock()
+	}
+	switch {
+	case onNode:
+		p.Status = proto.PartitionPending
+	case !recovering:
+		p.Status = proto.PartitionDone
+	}
+}
+
+// checkDecommissionJobFinished finishes the job failed to move some partitions, or deletes the node and
+// finishes the job once all the partitions are moved.
+func (c *Cluster) checkDecommissionJobFinished(job *decommissionJob) {
+	job.Lock()
+	moved := c.checkDecommissionPartitionsMoved(job)
+	job.Unlock()
+	if !moved {
+		return
+	}
+	err := c.deleteDecommissionedNode(job)
+	job.Lock()
+	defer job.Unlock()
+	if err != nil {
+		job.Msg = err.Error()
+		return
+	}
+	if !job.finished() {
+		c.finishDecommissionJob(job, proto.JobSucceeded, "")
+	}
+}
+
+// checkDecommissionPartitionsMoved returns true if all the partitions of the job are moved, and fails the job
+// if some partitions failed to move. The job lock must be held.
+func (c *Cluster) checkDecommissionPartitionsMoved(job *decommissionJob) bool {
+	if job.finished() {
+		return false
+	}
+	var failed int
+	for _, p := range job.Partitions {
+		switch p.Status {
+		case proto.PartitionPending, proto.PartitionMigrating:
+			return false
+		case proto.PartitionFailed:
+			failed++
+		}
+	}
+	if failed > 0 {
+		c.finishDecommissionJob(job, proto.JobFailed, fmt.Sprintf("%v partitions failed to move", failed))
+		return false
+	}
+	// the partitions created on the node after the job started
+	total := len(job.Partitions)
+	if err := c.collectDecommissionPartitions(job); err != nil {
+		c.finishDecommissionJob(job, proto.JobFailed, err.Error())
+		return false
+	}
+	return len(job.Partitions) == total
+}
+
+func (c *Cluster) deleteDecommissionedNode(job *decommissionJob) (err error) {
+	switch job.Type {
+	case proto.DecommissionDataNodeJob:
+		var dataNode *DataNode
+		if dataNode, err = c.dataNode(job.Addr); err != nil {
+			return nil
+		}
+		if err = c.syncDeleteDataNode(dataNode); err != nil {
+			return
+		}
+		c.delDataNodeFromCache(dataNode)
+	case proto.DecommissionMetaNodeJob:
+		var metaNode *MetaNode
+		if metaNode, err = c.metaNode(job.Addr); err != nil {
+			return nil
+		}
+		if err = c.syncDeleteMetaNode(metaNode); err != nil {
+			return
+		}
+		c.deleteMetaNodeFromCache(metaNode)
+	}
+	return
+}
+
+func (c *Cluster) finishDecommissionJob(job *decommissionJob, status, msg string) {
+	job.Status = status
+	job.Msg = msg
+	if status != proto.JobSucceeded {
+		c.setDecommissionNodeOffline(job, false)
+	}
+	Warn(c.Name, fmt.Sprintf("action[finishDecommissionJob] clusterID[%v] job[%v] type[%v] addr[%v] disk[%v] %v %v",
+		c.Name, job.ID, job.Type, job.Addr, job.DiskPath, status, msg))
+}
+
+// setDecommissionNodeOffline keeps the new partitions off the node being decommissioned.
+func (c *Cluster) setDecommissionNodeOffline(job *decommissionJob, offline bool) {
+	switch job.Type {
+	case proto.DecommissionDataNodeJob:
+		if dataNode, err := c.dataNode(job.Addr); err == nil {
+			dataNode.ToBeOffline = offline
+			if offline {
+				dataNode.AvailableSpace = 1
+			}
+		}
+	case proto.DecommissionMetaNodeJob:
+		if metaNode, err := c.metaNode(job.Addr); err == nil {
+			metaNode.ToBeOffline = offline
+			if offline {
+				metaNode.MaxMemAvailWeight = 1
+			}
+		}
+	}
+}
+
+// cancelDecommissionJob stops moving the partitions, the ones already moved stay on the new nodes.
+func (c *Cluster) cancelDecommissionJob(id uint64) (err error) {
+	job, err := c.decommissionJob(id)
+	if err != nil {
+		return
+	}
+	job.Lock()
+	if job.finished() {
+		job.Unlock()
+		return fmt.Errorf("job[%v] is %v", job.ID, job.Status)
+	}
+	oldStatus := job.Status
+	c.finishDecommissionJob(job, proto.JobCancelled, "")
+	job.UpdateTime = time.Now().Unix()
+	job.Unlock()
+	if err = c.syncPutDecommissionJob(job); err != nil {
+		job.Lock()
+		job.Status = oldStatus
+		job.Unlock()
+		c.setDecommissionNodeOffline(job, true)
+		err = proto.ErrPersistenceByRaft
+	}
+	return
+}
+
+func (c *Cluster) getDecommissionJobViews() (views []*proto.DecommissionJobView) {
+	views = make([]*proto.DecommissionJobView, 0)
+	for _, job := range c.allDecommissionJobs() {
+		job.Lock()
+		views = append(views, job.view())
+		job.Unlock()
+	}
+	return
+}
+
+func (c *Cluster) getDecommissionJobInfo(id uint64) (info *proto.DecommissionJob, err error) {
+	job, err := c.decommissionJob(id)
+	if err != nil {
+		return
+	}
+	job.Lock()
+	defer job.Unlock()
+	info = &proto.DecommissionJob{}
+	*info = *job.DecommissionJob
+	info.Partitions = make([]*proto.DecommissionPartition, 0, len(job.Partitions))
+	for _, p := range job.Partitions {
+		partition := *p
+		info.Partitions = append(info.Partitions, &partition)
+	}
+	return
+}
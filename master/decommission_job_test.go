@@ -0,0 +1,88 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestRetryDecommissionPartition(t *testing.T) {
+	p := &proto.DecommissionPartition{PartitionID: 1, Status: proto.PartitionMigrating}
+	for i := 1; i < decommissionJobMaxRetries; i++ {
+		before := time.Now()
+		retryDecommissionPartition(p, errors.New("retry"))
+		backoff := decommissionJobRetryBackoff << uint(i-1)
+		if backoff > decommissionJobMaxBackoff {
+			backoff = decommissionJobMaxBackoff
+		}
+		if p.Status != proto.PartitionPending || p.Retries != i || p.Msg != "retry" {
+			t.Fatalf("retry[%v]: status[%v] retries[%v] msg[%v]", i, p.Status, p.Retries, p.Msg)
+		}
+		if next := time.Unix(p.NextRetryTime, 0); next.Before(before.Add(backoff).Add(-time.Second)) ||
+			next.After(time.Now().Add(backoff)) {
+			t.Errorf("retry[%v]: next retry[%v] backoff[%v]", i, next, backoff)
+		}
+	}
+	retryDecommissionPartition(p, errors.New("failed"))
+	if p.Status != proto.PartitionFailed || p.Retries != decommissionJobMaxRetries || p.Msg != "failed" {
+		t.Errorf("status[%v] retries[%v] msg[%v]", p.Status, p.Retries, p.Msg)
+	}
+}
+
+func newTestDecommissionJob(statuses ...string) *decommissionJob {
+	job := &decommissionJob{DecommissionJob: &proto.DecommissionJob{
+		ID:         1,
+		Type:       proto.DecommissionDataNodeJob,
+		Addr:       "127.0.0.1:65535",
+		Status:     proto.JobRunning,
+		Partitions: make([]*proto.DecommissionPartition, 0),
+	}}
+	for i, status := range statuses {
+		job.Partitions = append(job.Partitions, &proto.DecommissionPartition{PartitionID: uint64(i + 1), Status: status})
+	}
+	return job
+}
+
+func TestCheckDecommissionJobFinished(t *testing.T) {
+	c := &Cluster{Name: "test", vols: make(map[string]*Vol)}
+	testCases := []struct {
+		name     string
+		job      *decommissionJob
+		expected string
+	}{
+		{"pending", newTestDecommissionJob(proto.PartitionDone, proto.PartitionPending), proto.JobRunning},
+		{"migrating", newTestDecommissionJob(proto.PartitionFailed, proto.PartitionMigrating), proto.JobRunning},
+		{"failed", newTestDecommissionJob(proto.PartitionDone, proto.PartitionFailed), proto.JobFailed},
+		{"done", newTestDecommissionJob(proto.PartitionDone, proto.PartitionDone), proto.JobSucceeded},
+		{"empty", newTestDecommissionJob(), proto.JobSucceeded},
+	}
+	for _, tc := range testCases {
+		c.checkDecommissionJobFinished(tc.job)
+		if tc.job.Status != tc.expected {
+			t.Errorf("%v: status[%v] expected[%v] msg[%v]", tc.name, tc.job.Status, tc.expected, tc.job.Msg)
+		}
+	}
+
+	job := newTestDecommissionJob(proto.PartitionDone)
+	job.Status = proto.JobCancelled
+	c.checkDecommissionJobFinished(job)
+	if job.Status != proto.JobCancelled {
+		t.Errorf("cancelled: status[%v]", job.Status)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultAutoDecommissionMaxConcurrency = 1 // disks decommissioned at the same time in the cluster
+	defaultBadDiskLimit                   = 3
+	diskFailureCheckInterval              = time.Minute
+	diskErrorWindow                       = time.Hour // the I/O errors of the disks are counted within
+	maxDiskFailureEvents                  = 100
+	diskFailureAuditOp                    = "/disk/autoDecommission"
+)
+
+// The disk failure handler decommissions the bad disks of the data nodes automatically, if enabled. A disk is bad
+// once it is reported unavailable, or its read and write errors within the last diskErrorWindow reach the
+// threshold if set. The disks are moved out by the decommission jobs, at most maxConcurrency disks at the same
+// time, and the new partitions are not created on them. The handler is suspended if more than badDiskLimit disks
+// holding the partitions are bad at the same time, which is more likely a failure of the network or of the power
+// than of the disks, and moving all of them would cascade. The events are written to the audit log as well.
+type diskFailureHandler struct {
+	enable         bool
+	errorThreshold uint64 // 0 if only the unavailable disks are decommissioned
+	maxConcurrency uint64
+	badDiskLimit   uint64
+	suspended      bool
+	events         []*proto.DiskFailureEvent
+	errorSamples   map[string][]diskErrorSample // the error counts reported by the disks within the window
+	sync.RWMutex
+}
+
+type diskErrorSample struct {
+	count uint64 // the read and write errors since the data node started
+	time  int64
+}
+
+func newDiskFailureHandler() *diskFailureHandler {
+	return &diskFailureHandler{
+		maxConcurrency: defaultAutoDecommissionMaxConcurrency,
+		badDiskLimit:   defaultBadDiskLimit,
+		events:         make([]*proto.DiskFailureEvent, 0),
+		errorSamples:   make(map[string][]diskErrorSample),
+	}
+}
+
+func (h *diskFailureHandler) settings() (enable bool, errorThreshold, maxConcurrency, badDiskLimit uint64) {
+	h.RLock()
+	defer h.RUnlock()
+	return h.enable, h.errorThreshold, h.maxConcurrency, h.badDiskLimit
+}
+
+// update updates the settings, and the zero concurrency or limit loaded from the old cluster values
+// falls back to the defaults.
+func (h *diskFailureHandler) update(enable bool, errorThreshold, maxConcurrency, badDiskLimit uint64) {
+	h.Lock()
+	defer h.Unlock()
+	if maxConcurrency == 0 {
+		maxConcurrency = defaultAutoDecommissionMaxConcurrency
+	}
+	if badDiskLimit == 0 {
+		badDiskLimit = defaultBadDiskLimit
+	}
+	h.enable = enable
+	h.errorThreshold = errorThreshold
+	h.maxConcurrency = maxConcurrency
+	h.badDiskLimit = badDiskLimit
+}
+
+// suspend returns if the handler is suspended or resumed by the call.
+func (h *diskFailureHandler) suspend(suspended bool) (changed bool) {
+	h.Lock()
+	defer h.Unlock()
+	changed = h.suspended != suspended
+	h.suspended = suspended
+	return
+}
+
+func (h *diskFailureHandler) isSuspended() bool {
+	h.RLock()
+	defer h.RUnlock()
+	return h.suspended
+}
+
+func (h *diskFailureHandler) record(event *proto.DiskFailureEvent) {
+	h.Lock()
+	defer h.Unlock()
+	event.Time = time.Now().Unix()
+	h.events = append(h.events, event)
+	if len(h.events) > maxDiskFailureEvents {
+		h.events = h.events[len(h.events)-maxDiskFailureEvents:]
+	}
+}
+
+// errorDelta returns the errors of the disk within the window, by the error count reported since the data node
+// started. The errors before the count is first seen by the leader are not counted.
+func (h *diskFailureHandler) errorDelta(key string, count uint64, now int64) uint64 {
+	h.Lock()
+	defer h.Unlock()
+	samples := h.errorSamples[key]
+	if len(samples) > 0 && count < samples[len(samples)-1].count {
+		// the data node restarted, and all the errors reported are new
+		samples = []diskErrorSample{{time: now}}
+	}
+	for len(samples) > 0 && now-samples[0].time > int64(diskErrorWindow/time.Second) {
+		samples = samples[1:]
+	}
+	samples = append(samples, diskErrorSample{count: count, time: now})
+	h.errorSamples[key] = samples
+	return count - samples[0].count
+}
+
+// forgetDisks drops the error counts of the disks no longer reported.
+func (h *diskFailureHandler) forgetDisks(reported map[string]bool) {
+	h.Lock()
+	defer h.Unlock()
+	for key := range h.errorSamples {
+		if !reported[key] {
+			delete(h.errorSamples, key)
+		}
+	}
+}
+
+func (h *diskFailureHandler) allEvents() (events []*proto.DiskFailureEvent) {
+	h.RLock()
+	defer h.RUnlock()
+	events = make([]*proto.DiskFailureEvent, len(h.events))
+	copy(events, h.events)
+	return
+}
+
+// recordDiskFailure keeps the event for the queries, and writes it to the audit log.
+func (c *Cluster) recordDiskFailure(event *proto.DiskFailureEvent) {
+	c.diskFailure.record(event)
+	if c.audit == nil {
+		return
+	}
+	params := map[string]string{"reason": event.Reason}
+	if event.Addr != "" {
+		params[addrKey] = event.Addr
+		params[diskPathKey] = event.DiskPath
+	}
+	if event.JobID != 0 {
+		params[idKey] = strconv.FormatUint(event.JobID, 10)
+	}
+	c.audit.add(&proto.AuditRecord{
+		Time:   event.Time,
+		User:   ModuleName,
+		Op:     diskFailureAuditOp + ":" + event.Action,
+		Params: params,
+	})
+}
+
+func (c *Cluster) scheduleToHandleBadDisks() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.handleBadDisks()
+			}
+			time.Sleep(diskFailureCheckInterval)
+		}
+	}()
+}
+
+// handleBadDisks starts the decommission jobs of the bad disks not yet decommissioned, within the concurrency.
+// The disks whose jobs are cancelled by the operators are left alone until the jobs expire.
+func (c *Cluster) handleBadDisks() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("handleBadDisks occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"handleBadDisks occurred panic")
+		}
+	}()
+	enable, errorThreshold, maxConcurrency, badDiskLimit := c.diskFailure.settings()
+	if !enable {
+		return
+	}
+	badDisks := c.collectBadDisks(errorThreshold)
+	if uint64(len(badDisks)) > badDiskLimit {
+		if c.diskFailure.suspend(true) {
+			reason := fmt.Sprintf("%v bad disks exceed the limit %v", len(badDisks), badDiskLimit)
+			c.recordDiskFailure(&proto.DiskFailureEvent{Action: proto.DiskFailureSuspend, Reason: reason})
+			Warn(c.Name, fmt.Sprintf("action[handleBadDisks] clusterID[%v] automatic disk decommission suspended, %v",
+				c.Name, reason))
+		}
+		return
+	}
+	if c.diskFailure.suspend(false) {
+		c.recordDiskFailure(&proto.DiskFailureEvent{Action: proto.DiskFailureResume,
+			Reason: fmt.Sprintf("%v bad disks within the limit %v", len(badDisks), badDiskLimit)})
+		Warn(c.Name, fmt.Sprintf("action[handleBadDisks] clusterID[%v] automatic disk decommission resumed", c.Name))
+	}
+	running, skipped := c.diskDecommissionJobs()
+	for _, disk := range badDisks {
+		if running >= maxConcurrency {
+			return
+		}
+		if skipped[diskKey(disk.Addr, disk.DiskPath)] {
+			continue
+		}
+		dataNode, err := c.dataNode(disk.Addr)
+		if err != nil {
+			continue
+		}
+		job, err := c.decommissionDisk(dataNode, disk.DiskPath)
+		if err != nil {
+			log.LogErrorf("action[handleBadDisks] decommission disk[%v] of node[%v] err[%v]", disk.DiskPath, disk.Addr, err)
+			continue
+		}
+		running++
+		c.recordDiskFailure(&proto.DiskFailureEvent{Action: proto.DiskFailureDecommission, Addr: disk.Addr,
+			DiskPath: disk.DiskPath, Reason: disk.Reason, JobID: job.ID})
+		Warn(c.Name, fmt.Sprintf("action[handleBadDisks] clusterID[%v] disk[%v] of node[%v] is %v, decommissioned by job[%v]",
+			c.Name, disk.DiskPath, disk.Addr, disk.Reason, job.ID))
+	}
+}
+
+func diskKey(addr, diskPath string) string {
+	return addr + ":" + diskPath
+}
+
+// collectBadDisks returns the bad disks of the active data nodes still holding the data partitions.
+func (c *Cluster) collectBadDisks(errorThreshold uint64) (badDisks []*proto.BadDisk) {
+	badDisks = make([]*proto.BadDisk, 0)
+	now := time.Now().Unix()
+	reported := make(map[string]bool)
+	defer c.diskFailure.forgetDisks(reported)
+	c.dataNodes.Range(func(key, value interface{}) bool {
+		dataNode := value.(*DataNode)
+		dataNode.RLock()
+		active := dataNode.isActive
+		reports := dataNode.DiskReports
+		unavailable := dataNode.BadDisks
+		dataNode.RUnlock()
+		if !active {
+			return true
+		}
+		reasons := make(map[string]string)
+		for _, diskPath := range unavailable {
+			reasons[diskPath] = "unavailable"
+		}
+		for _, report := range reports {
+			key := diskKey(dataNode.Addr, report.Path)
+			reported[key] = true
+			errCnt := c.diskFailure.errorDelta(key, report.ReadErrCnt+report.WriteErrCnt, now)
+			if report.Status == proto.Unavailable {
+				reasons[report.Path] = "unavailable"
+				continue
+			}
+			if errorThreshold > 0 && errCnt >= errorThreshold {
+				reasons[report.Path] = fmt.Sprintf("%v I/O errors within %v", errCnt, diskErrorWindow)
+			}
+		}
+		for diskPath, reason := range reasons {
+			partitions := dataNode.badPartitions(diskPath, c)
+			if len(partitions) == 0 {
+				continue
+			}
+			badDisks = append(badDisks, &proto.BadDisk{Addr: dataNode.Addr, DiskPath: diskPath, Reason: reason,
+				Partitions: len(partitions)})
+		}
+		return true
+	})
+	sort.Slice(badDisks, func(i, j int) bool {
+		return diskKey(badDisks[i].Addr, badDisks[i].DiskPath) < diskKey(badDisks[j].Addr, badDisks[j].DiskPath)
+	})
+	return
+}
+
+// diskDecommissionJobs returns the number of the running disk decommission jobs, and the disks not to be
+// decommissioned again as being decommissioned or cancelled by the operators.
+func (c *Cluster) diskDecommissionJobs() (running uint64, skipped map[string]bool) {
+	skipped = make(map[string]bool)
+	for _, job := range c.allDecommissionJobs() {
+		job.Lock()
+		if job.Type == proto.DecommissionDiskJob {
+			switch job.Status {
+			case proto.JobRunning:
+				running++
+				skipped[diskKey(job.Addr, job.DiskPath)] = true
+			case proto.JobCancelled:
+				skipped[diskKey(job.Addr, job.DiskPath)] = true
+			}
+		}
+		job.Unlock()
+	}
+	return
+}
+
+// decommissionedDisks returns the disks of the data node decommissioned by the jobs not cancelled, which are out of
+// service for the new partitions.
+func (c *Cluster) decommissionedDisks(addr string) (disks []string) {
+	for _, job := range c.allDecommissionJobs() {
+		job.Lock()
+		if job.Type == proto.DecommissionDiskJob && job.Addr == addr && job.Status != proto.JobCancelled {
+			disks = append(disks, job.DiskPath)
+		}
+		job.Unlock()
+	}
+	return
+}
+
+func (c *Cluster) setAutoDecommission(enable bool, errorThreshold, maxConcurrency, badDiskLimit uint64) (err error) {
+	oldEnable, oldErrorThreshold, oldMaxConcurrency, oldBadDiskLimit := c.diskFailure.settings()
+	c.diskFailure.update(enable, errorThreshold, maxConcurrency, badDiskLimit)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setAutoDecommission] err[%v]", err)
+		c.diskFailure.update(oldEnable, oldErrorThreshold, oldMaxConcurrency, oldBadDiskLimit)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
+func (c *Cluster) getAutoDecommissionView() (view *proto.AutoDecommissionView) {
+	enable, errorThreshold, maxConcurrency, badDiskLimit := c.diskFailure.settings()
+	return &proto.AutoDecommissionView{
+		Enable:         enable,
+		ErrorThreshold: errorThreshold,
+		MaxConcurrency: maxConcurrency,
+		BadDiskLimit:   badDiskLimit,
+		Suspended:      c.diskFailure.isSuspended(),
+		BadDisks:       c.collectBadDisks(errorThreshold),
+		Events:         c.diskFailure.allEvents(),
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestDiskErrorDelta(t *testing.T) {
+	h := newDiskFailureHandler()
+	window := int64(diskErrorWindow / time.Second)
+	now := time.Now().Unix()
+	steps := []struct {
+		count    uint64
+		time     int64
+		expected uint64
+	}{
+		{100, now, 0}, // the errors before the first report are not counted
+		{105, now + 60, 5},
+		{120, now + 120, 20},
+		{130, now + window + 90, 10}, // the first two reports are out of the window
+		{3, now + window + 150, 3},   // the data node restarted
+		{4, now + window + 210, 4},
+	}
+	for i, step := range steps {
+		if delta := h.errorDelta("disk", step.count, step.time); delta != step.expected {
+			t.Errorf("step[%v]: delta[%v] expected[%v]", i, delta, step.expected)
+		}
+	}
+	h.forgetDisks(map[string]bool{})
+	if delta := h.errorDelta("disk", 10, now+window+270); delta != 0 {
+		t.Errorf("forgotten: delta[%v]", delta)
+	}
+}
+
+func TestDecommissionedDisks(t *testing.T) {
+	c := &Cluster{Name: "test", audit: newAuditLog(), diskFailure: newDiskFailureHandler()}
+	jobs := []*proto.DecommissionJob{
+		{ID: 1, Type: proto.DecommissionDiskJob, Addr: "a", DiskPath: "/disk1", Status: proto.JobRunning},
+		{ID: 2, Type: proto.DecommissionDiskJob, Addr: "a", DiskPath: "/disk2", Status: proto.JobSucceeded},
+		{ID: 3, Type: proto.DecommissionDiskJob, Addr: "a", DiskPath: "/disk3", Status: proto.JobCancelled},
+		{ID: 4, Type: proto.DecommissionDiskJob, Addr: "b", DiskPath: "/disk1", Status: proto.JobRunning},
+		{ID: 5, Type: proto.DecommissionDataNodeJob, Addr: "a", Status: proto.JobRunning},
+	}
+	for _, job := range jobs {
+		c.decommissionJobs.Store(job.ID, &decommissionJob{DecommissionJob: job})
+	}
+	disks := c.decommissionedDisks("a")
+	if len(disks) != 2 || disks[0] != "/disk1" || disks[1] != "/disk2" {
+		t.Errorf("disks[%v]", disks)
+	}
+
+	c.recordDiskFailure(&proto.DiskFailureEvent{Action: proto.DiskFailureDecommission, Addr: "a", DiskPath: "/disk1",
+		Reason: "unavailable", JobID: 1})
+	records := c.audit.list("", diskFailureAuditOp, 0, defaultAuditRecordLimit)
+	if len(records) != 1 || records[0].Params[idKey] != "1" || records[0].Params[diskPathKey] != "/disk1" ||
+		len(c.diskFailure.allEvents()) != 1 {
+		t.Errorf("records[%v] events[%v]", records, c.diskFailure.allEvents())
+	}
+}
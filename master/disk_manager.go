@@ -16,6 +16,7 @@ package master
 
 import (
 	"fmt"
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/log"
 	"time"
@@ -81,17 +82,8 @@ func (c *Cluster) checkDiskRecoveryProgress() {
 	})
 }
 
-func (c *Cluster) decommissionDisk(dataNode *DataNode, badDiskPath string, badPartitions []*DataPartition) (err error) {
-	msg := fmt.Sprintf("action[decommissionDisk], Node[%v] OffLine,disk[%v]", dataNode.Addr, badDiskPath)
-	log.LogWarn(msg)
-
-	for _, dp := range badPartitions {
-		if err = c.decommissionDataPartition(dataNode.Addr, dp, diskOfflineErr); err != nil {
-			return
-		}
-	}
-	msg = fmt.Sprintf("action[decommissionDisk],clusterID[%v] Node[%v] OffLine success",
-		c.Name, dataNode.Addr)
-	Warn(c.Name, msg)
-	return
+// decommissionDisk starts a job moving the data partitions out of the disk of the data node.
+func (c *Cluster) decommissionDisk(dataNode *DataNode, badDiskPath string) (job *decommissionJob, err error) {
+	log.LogWarnf("action[decommissionDisk], Node[%v] OffLine,disk[%v]", dataNode.Addr, badDiskPath)
+	return c.createDecommissionJob(proto.DecommissionDiskJob, dataNode.Addr, badDiskPath)
 }
@@ -161,6 +161,7 @@ func (s *ClusterService) registerQuery(schema *schemabuilder.Schema) {
 	query.FieldFunc("masterList", s.masterList)
 	query.FieldFunc("getTopology", s.getTopology)
 	query.FieldFunc("alarmList", s.alarmList)
+	query.FieldFunc("capacityForecast", s.capacityForecast)
 }
 
 func (s *ClusterService) registerMutation(schema *schemabuilder.Schema) {
@@ -188,21 +189,12 @@ func (m *ClusterService) decommissionDisk(ctx context.Context, args struct {
 		return nil, err
 	}
 
-	badPartitions := node.badPartitions(args.DiskPath, m.cluster)
-	if len(badPartitions) == 0 {
-		err = fmt.Errorf("node[%v] disk[%v] does not have any data partition", node.Addr, args.DiskPath)
-		return nil, err
-	}
-
-	var badPartitionIds []uint64
-	for _, bdp := range badPartitions {
-		badPartitionIds = append(badPartitionIds, bdp.PartitionID)
-	}
-	rstMsg := fmt.Sprintf("receive decommissionDisk node[%v] disk[%v], badPartitionIds[%v] has offline successfully",
-		node.Addr, args.DiskPath, badPartitionIds)
-	if err = m.cluster.decommissionDisk(node, args.DiskPath, badPartitions); err != nil {
+	job, err := m.cluster.decommissionDisk(node, args.DiskPath)
+	if err != nil {
 		return nil, err
 	}
+	rstMsg := fmt.Sprintf("receive decommissionDisk node[%v] disk[%v], job[%v] started",
+		node.Addr, args.DiskPath, job.ID)
 	Warn(m.cluster.Name, rstMsg)
 
 	return proto.Success("success"), nil
@@ -218,10 +210,11 @@ func (m *ClusterService) decommissionDataNode(ctx context.Context, args struct {
 	if err != nil {
 		return nil, err
 	}
-	if err := m.cluster.decommissionDataNode(node); err != nil {
+	job, err := m.cluster.decommissionDataNode(node)
+	if err != nil {
 		return nil, err
 	}
-	rstMsg := fmt.Sprintf("decommission data node [%v] successfully", args.OffLineAddr)
+	rstMsg := fmt.Sprintf("decommission data node [%v] job[%v] started", args.OffLineAddr, job.ID)
 
 	return proto.Success(rstMsg), nil
 }
@@ -236,10 +229,11 @@ func (m *ClusterService) decommissionMetaNode(ctx context.Context, args struct {
 	if err != nil {
 		return nil, err
 	}
-	if err = m.cluster.decommissionMetaNode(metaNode); err != nil {
+	job, err := m.cluster.decommissionMetaNode(metaNode)
+	if err != nil {
 		return nil, err
 	}
-	log.LogInfof("decommissionMetaNode metaNode [%v] has offline successfully", args.OffLineAddr)
+	log.LogInfof("decommissionMetaNode metaNode [%v] job[%v] started", args.OffLineAddr, job.ID)
 	return proto.Success("success"), nil
 }
 
@@ -436,7 +430,7 @@ func (m *ClusterService) addMetaNode(ctx context.Context, args struct {
 	NodeAddr string
 	ZoneName string
 }) (uint64, error) {
-	if id, err := m.cluster.addMetaNode(args.NodeAddr, args.ZoneName); err != nil {
+	if id, err := m.cluster.addMetaNode(args.NodeAddr, args.ZoneName, nil); err != nil {
 		return 0, err
 	} else {
 		return id, nil
@@ -497,6 +491,20 @@ func (m *ClusterService) clusterFreeze(ctx context.Context, args struct {
 	return proto.Success("success"), nil
 }
 
+// capacityForecast lists the days to full of the volumes, the zones and the node sets, or the ones of the scope if given.
+func (m *ClusterService) capacityForecast(ctx context.Context, args struct {
+	Scope *string
+}) ([]*proto.CapacityForecast, error) {
+	if _, _, err := permissions(ctx, ADMIN); err != nil {
+		return nil, err
+	}
+	var scope string
+	if args.Scope != nil {
+		scope = *args.Scope
+	}
+	return m.cluster.capacityHistory.forecasts(scope), nil
+}
+
 type WarnMessage struct {
 	Time     string `json:"time"`
 	Key      string `json:"key"`
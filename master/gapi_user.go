@@ -130,6 +130,12 @@ func (m *UserService) transferUserVol(ctx context.Context, args proto.UserTransf
 	if !args.Force && vol.Owner != args.UserSrc {
 		return nil, fmt.Errorf("force param need validate user name for vol:[%s]", args.Volume)
 	}
+	defer m.cluster.lockUserQuota(args.UserDst)()
+	if vol.Owner != args.UserDst {
+		if err = m.cluster.checkUserQuota(m.user, args.UserDst, vol.Capacity, 1); err != nil {
+			return nil, err
+		}
+	}
 
 	userInfo, err := m.user.transferVol(&args)
 	if err != nil {
@@ -191,6 +191,7 @@ func (s *VolumeService) createVolume(ctx context.Context, args struct {
 	Name, Owner, ZoneName, Description                 string
 	Capacity, DataPartitionSize, MpCount, DpReplicaNum uint64
 	FollowerRead, Authenticate, CrossZone, EnableToken bool
+	Checksum                                           *string // "crc32" by default, or "crc32c"
 }) (*Vol, error) {
 	uid, per, err := permissions(ctx, ADMIN|USER)
 	if err != nil {
@@ -205,7 +206,19 @@ func (s *VolumeService) createVolume(ctx context.Context, args struct {
 		return nil, fmt.Errorf("[%s] not has permission to create volume for [%s]", uid, args.Owner)
 	}
 
-	vol, err := s.cluster.createVol(args.Name, args.Owner, args.ZoneName, args.Description, int(args.MpCount), int(args.DpReplicaNum), int(args.DataPartitionSize), int(args.Capacity), args.FollowerRead, args.Authenticate, args.CrossZone, args.EnableToken)
+	checksum := proto.ChecksumCRC32
+	if args.Checksum != nil {
+		if checksum, err = parseChecksum(*args.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	defer s.cluster.lockUserQuota(args.Owner)()
+	if err = s.cluster.checkUserQuota(s.user, args.Owner, args.Capacity, 1); err != nil {
+		return nil, err
+	}
+
+	vol, err := s.cluster.createVol(args.Name, args.Owner, args.ZoneName, args.Description, int(args.MpCount), int(args.DpReplicaNum), int(args.DataPartitionSize), int(args.Capacity), args.FollowerRead, args.Authenticate, args.CrossZone, args.EnableToken, checksum)
 	if err != nil {
 		return nil, err
 	}
@@ -312,6 +325,12 @@ func (s *VolumeService) updateVolume(ctx context.Context, args struct {
 
 	if args.Capacity != nil {
 		newArgs.capacity = *args.Capacity
+		defer s.cluster.lockUserQuota(vol.Owner)()
+		if newArgs.capacity > vol.Capacity {
+			if err = s.cluster.checkUserQuota(s.user, vol.Owner, newArgs.capacity-vol.Capacity, 0); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if args.ReplicaNum != nil {
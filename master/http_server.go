@@ -62,7 +62,15 @@ func (m *Server) registerAPIMiddleware(route *mux.Router) {
 				}
 				if m.partition.IsRaftLeader() {
 					if m.metaReady {
-						next.ServeHTTP(w, r)
+						w, finishAudit := m.startAudit(w, r)
+						authReq, err := m.authenticate(r)
+						if err != nil {
+							sendErrReply(w, r, newErrHTTPReply(err))
+							finishAudit(r)
+							return
+						}
+						next.ServeHTTP(w, authReq)
+						finishAudit(authReq)
 						return
 					}
 					log.LogWarnf("action[interceptor] leader meta has not ready")
@@ -123,6 +131,12 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminUpdateVol).
 		HandlerFunc(m.updateVol)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminRenameVol).
+		HandlerFunc(m.renameVol)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCloneVol).
+		HandlerFunc(m.cloneVol)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminVolShrink).
 		HandlerFunc(m.volShrink)
@@ -192,9 +206,15 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminDiagnoseDataPartition).
 		HandlerFunc(m.diagnoseDataPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminConvertDataPartitionToEc).
+		HandlerFunc(m.convertDataPartitionToEc)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.ClientDataPartitions).
 		HandlerFunc(m.getDataPartitions)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.ClientChecksumReport).
+		HandlerFunc(m.reportChecksumMismatch)
 
 	// meta node management APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
@@ -244,6 +264,33 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminGetNodeInfo).
 		HandlerFunc(m.getNodeInfoHandler)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminBackupMetadata).
+		HandlerFunc(m.backupMetadata)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListAuditRecords).
+		HandlerFunc(m.listAuditRecords)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListJobs).
+		HandlerFunc(m.listDecommissionJobs)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetJob).
+		HandlerFunc(m.getDecommissionJob)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCancelJob).
+		HandlerFunc(m.cancelDecommissionJob)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetRebalance).
+		HandlerFunc(m.setRebalance)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetRebalance).
+		HandlerFunc(m.getRebalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetAutoDecommission).
+		HandlerFunc(m.setAutoDecommission)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetAutoDecommission).
+		HandlerFunc(m.getAutoDecommission)
 
 	// user management APIs
 	router.NewRoute().Methods(http.MethodPost).
@@ -276,6 +323,9 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.UserTransferVol).
 		HandlerFunc(m.transferUserVol)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.UserUpdateQuota).
+		HandlerFunc(m.updateUserQuota)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.UsersOfVol).
 		HandlerFunc(m.getUsersOfVol)
@@ -308,6 +358,11 @@ func (m *Server) registerHandler(router *mux.Router, model string, schema *graph
 
 	gHandler := graphql.HTTPHandler(schema)
 	router.NewRoute().Name(model).Methods(http.MethodGet, http.MethodPost).Path(model).HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if _, ok := request.Context().Value(proto.UserInfoKey).(*proto.UserInfo); ok {
+			// the user is authenticated by the signature
+			gHandler.ServeHTTP(writer, request)
+			return
+		}
 		userID := request.Header.Get(proto.UserKey)
 		if userID == "" {
 			ErrResponse(writer, fmt.Errorf("not found [%s] in header", proto.UserKey))
@@ -44,6 +44,7 @@ func (m *Server) handleLeaderChange(leader uint64) {
 		if oldLeaderAddr != m.leaderInfo.addr {
 			m.loadMetadata()
 			m.metaReady = true
+			go m.cluster.resumeMigrations()
 		}
 		m.cluster.checkDataNodeHeartbeat()
 		m.cluster.checkMetaNodeHeartbeat()
@@ -122,6 +123,13 @@ func (m *Server) loadMetadata() {
 	if err = m.cluster.loadDataPartitions(); err != nil {
 		panic(err)
 	}
+	m.cluster.loadSharedDataPartitions()
+	if err = m.cluster.loadDecommissionJobs(); err != nil {
+		panic(err)
+	}
+	if err = m.cluster.loadMigrations(); err != nil {
+		panic(err)
+	}
 	log.LogInfo("action[loadMetadata] end")
 
 	log.LogInfo("action[loadUserInfo] begin")
@@ -149,6 +157,8 @@ func (m *Server) clearMetadata() {
 	m.cluster.clearDataNodes()
 	m.cluster.clearMetaNodes()
 	m.cluster.clearVols()
+	m.cluster.clearDecommissionJobs()
+	m.cluster.clearMigrations()
 	m.user.clearUserStore()
 	m.user.clearAKStore()
 	m.user.clearVolUsers()
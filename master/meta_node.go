@@ -43,6 +43,7 @@ type MetaNode struct {
 	sync.RWMutex              `graphql:"-"`
 	ToBeOffline               bool
 	PersistenceMetaPartitions []uint64
+	Labels                    map[string]string `graphql:"-"` // the rack, the host and the power domain registered by the node
 }
 
 func newMetaNode(addr, zoneName, clusterID string) (node *MetaNode) {
@@ -70,6 +71,13 @@ func (metaNode *MetaNode) GetAddr() string {
 	return metaNode.Addr
 }
 
+// GetLabel implements the Node interface
+func (metaNode *MetaNode) GetLabel(key string) string {
+	metaNode.RLock()
+	defer metaNode.RUnlock()
+	return nodeLabel(metaNode.Labels, metaNode.Addr, key)
+}
+
 // SetCarry implements the Node interface
 func (metaNode *MetaNode) SetCarry(carry float64) {
 	metaNode.Lock()
@@ -598,6 +598,13 @@ func (mr *MetaReplica) createTaskToDeleteReplica(partitionID uint64) (t *proto.A
 	return
 }
 
+func (mr *MetaReplica) createTaskToCloneMetaPartition(partitionID uint64, clone *proto.CreateMetaPartitionRequest) (t *proto.AdminTask) {
+	req := &proto.CloneMetaPartitionRequest{PartitionID: partitionID, Clone: clone}
+	t = proto.NewAdminTask(proto.OpCloneMetaPartition, mr.Addr, req)
+	resetMetaPartitionTaskID(t, partitionID)
+	return
+}
+
 func (mr *MetaReplica) createTaskToLoadMetaPartition(partitionID uint64) (t *proto.AdminTask) {
 	req := &proto.MetaPartitionLoadRequest{PartitionID: partitionID}
 	t = proto.NewAdminTask(proto.OpLoadMetaPartition, mr.Addr, req)
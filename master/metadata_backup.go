@@ -0,0 +1,229 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/chubaofs/chubaofs/raftstore"
+	"github.com/chubaofs/chubaofs/util/config"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	metadataBackupVersion   = 1
+	metadataBackupBatchSize = 1000 // key-values written to the store in a batch on the restore
+)
+
+// The backup of the metadata is a line of the header, followed by the key-values of the store in the raft commands,
+// one per line, and ends with a line of the trailer. The applied index of the raft is left out, as the backup seeds
+// a new master group.
+type metadataBackupHeader struct {
+	Version     int
+	ClusterName string
+	Applied     uint64
+	Time        int64
+}
+
+type metadataBackupTrailer struct {
+	Count uint64
+	CRC   uint32 // of the lines of the key-values
+}
+
+// backup writes the key-values of a consistent snapshot of the store.
+func (mf *MetadataFsm) backup(w io.Writer, clusterName string) (count uint64, err error) {
+	snapshot, err := mf.Snapshot()
+	if err != nil {
+		return
+	}
+	defer snapshot.Close()
+	bw := bufio.NewWriter(w)
+	header := &metadataBackupHeader{
+		Version:     metadataBackupVersion,
+		ClusterName: clusterName,
+		Applied:     snapshot.ApplyIndex(),
+		Time:        time.Now().Unix(),
+	}
+	if err = writeBackupLine(bw, header); err != nil {
+		return
+	}
+	crc := crc32.NewIEEE()
+	for {
+		var data []byte
+		if data, err = snapshot.Next(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		cmd := new(RaftCmd)
+		if err = cmd.Unmarshal(data); err != nil {
+			return
+		}
+		if cmd.K == applied {
+			continue
+		}
+		crc.Write(data)
+		bw.Write(data)
+		if err = bw.WriteByte('\n'); err != nil {
+			return
+		}
+		count++
+	}
+	if err = writeBackupLine(bw, &metadataBackupTrailer{Count: count, CRC: crc.Sum32()}); err != nil {
+		return
+	}
+	err = bw.Flush()
+	return
+}
+
+func writeBackupLine(w *bufio.Writer, v interface{}) (err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	w.Write(data)
+	return w.WriteByte('\n')
+}
+
+// readMetadataBackup verifies the backup by the trailer, and applies the key-values to the function if not nil.
+// The key-values are applied before the trailer is read, so the backup should be verified before it is applied.
+func readMetadataBackup(r io.Reader, apply func(cmd *RaftCmd) error) (header *metadataBackupHeader, count uint64, err error) {
+	br := bufio.NewReader(r)
+	line, err := readBackupLine(br)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read the header err[%v]", err)
+	}
+	header = new(metadataBackupHeader)
+	if err = json.Unmarshal(line, header); err != nil {
+		return nil, 0, fmt.Errorf("invalid header err[%v]", err)
+	}
+	if header.Version != metadataBackupVersion {
+		return nil, 0, fmt.Errorf("unsupported version[%v]", header.Version)
+	}
+	crc := crc32.NewIEEE()
+	if line, err = readBackupLine(br); err != nil {
+		return nil, 0, fmt.Errorf("read the trailer err[%v]", err)
+	}
+	for {
+		var next []byte
+		if next, err = readBackupLine(br); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		cmd := new(RaftCmd)
+		if err = cmd.Unmarshal(line); err != nil {
+			return nil, 0, fmt.Errorf("invalid key-value[%v] err[%v]", count, err)
+		}
+		if apply != nil {
+			if err = apply(cmd); err != nil {
+				return nil, 0, err
+			}
+		}
+		crc.Write(line)
+		count++
+		line = next
+	}
+	trailer := new(metadataBackupTrailer)
+	if err = json.Unmarshal(line, trailer); err != nil || trailer.Count != count || trailer.CRC != crc.Sum32() {
+		return nil, 0, fmt.Errorf("the backup is truncated or corrupted, %v key-values read", count)
+	}
+	return header, count, nil
+}
+
+// readBackupLine returns io.EOF only if nothing is left.
+func readBackupLine(r *bufio.Reader) (line []byte, err error) {
+	line, err = r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimSuffix(line, []byte{'\n'}), err
+}
+
+// RestoreMetadata seeds the store of a master of a new master group from the backup of the metadata, offline. Each
+// master of the group is seeded from the same backup, with its store and raft logs empty.
+func RestoreMetadata(cfg *config.Config, backupFile string) (count uint64, err error) {
+	clusterName := cfg.GetString(ClusterName)
+	storeDir := cfg.GetString(StoreDir)
+	walDir := cfg.GetString(WalDir)
+	if clusterName == "" || storeDir == "" || walDir == "" {
+		return 0, fmt.Errorf("%v, %v and %v are required", ClusterName, StoreDir, WalDir)
+	}
+	if files, e := ioutil.ReadDir(walDir); e == nil && len(files) > 0 {
+		return 0, fmt.Errorf("the raft logs in %v are not empty", walDir)
+	}
+	header, count, err := readMetadataBackupFile(backupFile, nil)
+	if err != nil {
+		return
+	}
+	if header.ClusterName != clusterName {
+		return 0, fmt.Errorf("the backup of the cluster[%v] mismatches the cluster[%v]", header.ClusterName, clusterName)
+	}
+	store, err := raftstore.NewRocksDBStore(storeDir, LRUCacheSize, WriteBufferSize)
+	if err != nil {
+		return
+	}
+	defer store.Close()
+	if !isStoreEmpty(store) {
+		return 0, fmt.Errorf("the store in %v is not empty", storeDir)
+	}
+	batch := make(map[string][]byte)
+	if _, _, err = readMetadataBackupFile(backupFile, func(cmd *RaftCmd) (err error) {
+		batch[cmd.K] = cmd.V
+		if len(batch) >= metadataBackupBatchSize {
+			err = store.BatchPut(batch, true)
+			batch = make(map[string][]byte)
+		}
+		return
+	}); err != nil {
+		return
+	}
+	if err = store.BatchPut(batch, true); err != nil {
+		return
+	}
+	log.LogInfof("action[RestoreMetadata] restored %v key-values of the cluster[%v] applied[%v] at %v from %v",
+		count, header.ClusterName, header.Applied, time.Unix(header.Time, 0), backupFile)
+	return
+}
+
+func readMetadataBackupFile(backupFile string, apply func(cmd *RaftCmd) error) (header *metadataBackupHeader, count uint64, err error) {
+	f, err := os.Open(backupFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	return readMetadataBackup(f, apply)
+}
+
+func isStoreEmpty(store *raftstore.RocksDBStore) bool {
+	snapshot := store.RocksDBSnapshot()
+	iterator := store.Iterator(snapshot)
+	defer func() {
+		iterator.Close()
+		store.ReleaseSnapshot(snapshot)
+	}()
+	iterator.SeekToFirst()
+	return !iterator.Valid()
+}
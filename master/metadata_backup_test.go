@@ -0,0 +1,69 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"bufio"
+	"bytes"
+	"hash/crc32"
+	"testing"
+)
+
+func TestReadMetadataBackup(t *testing.T) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	writeBackupLine(bw, &metadataBackupHeader{Version: metadataBackupVersion, ClusterName: "test", Applied: 10})
+	crc := crc32.NewIEEE()
+	cmds := []*RaftCmd{
+		{Op: opSyncAddVol, K: volPrefix + "1", V: []byte("vol\n1")},
+		{Op: opSyncAddUserInfo, K: userPrefix + "user", V: []byte("user")},
+	}
+	for _, cmd := range cmds {
+		data, _ := cmd.Marshal()
+		crc.Write(data)
+		bw.Write(data)
+		bw.WriteByte('\n')
+	}
+	writeBackupLine(bw, &metadataBackupTrailer{Count: uint64(len(cmds)), CRC: crc.Sum32()})
+	bw.Flush()
+	backup := buf.Bytes()
+
+	restored := make(map[string]string)
+	header, count, err := readMetadataBackup(bytes.NewReader(backup), func(cmd *RaftCmd) error {
+		restored[cmd.K] = string(cmd.V)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read backup err[%v]", err)
+	}
+	if header.ClusterName != "test" || count != uint64(len(cmds)) {
+		t.Errorf("header[%v] count[%v]", header, count)
+	}
+	for _, cmd := range cmds {
+		if restored[cmd.K] != string(cmd.V) {
+			t.Errorf("key[%v] restored[%v] expected[%v]", cmd.K, restored[cmd.K], string(cmd.V))
+		}
+	}
+
+	// the backups truncated or corrupted are rejected
+	truncated := backup[:bytes.LastIndexByte(backup[:len(backup)-1], '\n')+1]
+	if _, _, err = readMetadataBackup(bytes.NewReader(truncated), nil); err == nil {
+		t.Errorf("the truncated backup is accepted")
+	}
+	corrupted := bytes.Replace(backup, []byte(userPrefix+"user"), []byte(userPrefix+"usex"), 1)
+	if _, _, err = readMetadataBackup(bytes.NewReader(corrupted), nil); err == nil {
+		t.Errorf("the corrupted backup is accepted")
+	}
+}
@@ -103,6 +103,7 @@ func (mf *MetadataFsm) Apply(command []byte, index uint64) (resp interface{}, er
 	}
 	log.LogInfof("action[fsmApply],cmd.op[%v],cmd.K[%v],cmd.V[%v]", cmd.Op, cmd.K, string(cmd.V))
 	cmdMap := make(map[string][]byte)
+	delKeys := make([]string, 0)
 	if cmd.Op != opSyncBatchPut {
 		cmdMap[cmd.K] = cmd.V
 		cmdMap[applied] = []byte(strconv.FormatUint(uint64(index), 10))
@@ -114,16 +115,23 @@ func (mf *MetadataFsm) Apply(command []byte, index uint64) (resp interface{}, er
 		}
 		for cmdK, cmd := range nestedCmdMap {
 			log.LogInfof("action[fsmApply],cmd.op[%v],cmd.K[%v],cmd.V[%v]", cmd.Op, cmd.K, string(cmd.V))
+			if isDeleteOp(cmd.Op) {
+				delKeys = append(delKeys, cmd.K)
+				continue
+			}
 			cmdMap[cmdK] = cmd.V
 		}
 		cmdMap[applied] = []byte(strconv.FormatUint(uint64(index), 10))
 	}
-	switch cmd.Op {
-	case opSyncDeleteDataNode, opSyncDeleteMetaNode, opSyncDeleteVol, opSyncDeleteDataPartition, opSyncDeleteMetaPartition,
-		OpSyncDelToken, opSyncDeleteUserInfo, opSyncDeleteAKUser, opSyncDeleteVolUser:
+	switch {
+	case isDeleteOp(cmd.Op):
 		if err = mf.delKeyAndPutIndex(cmd.K, cmdMap); err != nil {
 			panic(err)
 		}
+	case len(delKeys) > 0:
+		if err = mf.store.BatchDeleteAndPut(delKeys, cmdMap, true); err != nil {
+			panic(err)
+		}
 	default:
 		if err = mf.store.BatchPut(cmdMap, true); err != nil {
 			panic(err)
@@ -137,6 +145,16 @@ func (mf *MetadataFsm) Apply(command []byte, index uint64) (resp interface{}, er
 	return
 }
 
+func isDeleteOp(op uint32) bool {
+	switch op {
+	case opSyncDeleteDataNode, opSyncDeleteMetaNode, opSyncDeleteVol, opSyncDeleteDataPartition, opSyncDeleteMetaPartition,
+		OpSyncDelToken, opSyncDeleteUserInfo, opSyncDeleteAKUser, opSyncDeleteVolUser, opSyncDeleteDecommissionJob,
+		opSyncDeleteMigration:
+		return true
+	}
+	return false
+}
+
 // ApplyMemberChange implements the interface of raft.StateMachine
 func (mf *MetadataFsm) ApplyMemberChange(confChange *proto.ConfChange, index uint64) (interface{}, error) {
 	var err error
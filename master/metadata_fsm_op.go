@@ -38,7 +38,20 @@ type clusterValue struct {
 	DataNodeDeleteLimitRate     uint64
 	MetaNodeDeleteBatchCount    uint64
 	MetaNodeDeleteWorkerSleepMs uint64
+	MetaNodeDefragThreshold     uint64
 	DataNodeAutoRepairLimitRate uint64
+	DataNodeScrubLimitRate      uint64
+	DataNodeDiskIopsLimit       uint64
+	DataNodeDiskBandwidthLimit  uint64
+	DataNodeRepairNodeBandwidth uint64
+	DataNodeRepairDiskBandwidth uint64
+	RebalancePaused             bool
+	RebalanceThreshold          float64
+	RebalanceMaxConcurrency     uint64
+	AutoDecommissionDisk        bool
+	DiskErrorThreshold          uint64
+	AutoDecommissionMaxDisks    uint64
+	BadDiskLimit                uint64
 }
 
 func newClusterValue(c *Cluster) (cv *clusterValue) {
@@ -48,9 +61,17 @@ func newClusterValue(c *Cluster) (cv *clusterValue) {
 		DataNodeDeleteLimitRate:     c.cfg.DataNodeDeleteLimitRate,
 		MetaNodeDeleteBatchCount:    c.cfg.MetaNodeDeleteBatchCount,
 		MetaNodeDeleteWorkerSleepMs: c.cfg.MetaNodeDeleteWorkerSleepMs,
+		MetaNodeDefragThreshold:     c.cfg.MetaNodeDefragThreshold,
 		DataNodeAutoRepairLimitRate: c.cfg.DataNodeAutoRepairLimitRate,
+		DataNodeScrubLimitRate:      c.cfg.DataNodeScrubLimitRate,
+		DataNodeDiskIopsLimit:       c.cfg.DataNodeDiskIopsLimit,
+		DataNodeDiskBandwidthLimit:  c.cfg.DataNodeDiskBandwidthLimit,
+		DataNodeRepairNodeBandwidth: c.cfg.DataNodeRepairNodeBandwidth,
+		DataNodeRepairDiskBandwidth: c.cfg.DataNodeRepairDiskBandwidth,
 		DisableAutoAllocate:         c.DisableAutoAllocate,
 	}
+	cv.RebalancePaused, cv.RebalanceThreshold, cv.RebalanceMaxConcurrency = c.rebalancer.settings()
+	cv.AutoDecommissionDisk, cv.DiskErrorThreshold, cv.AutoDecommissionMaxDisks, cv.BadDiskLimit = c.diskFailure.settings()
 	return cv
 }
 
@@ -86,16 +107,21 @@ func newMetaPartitionValue(mp *MetaPartition) (mpv *metaPartitionValue) {
 }
 
 type dataPartitionValue struct {
-	PartitionID   uint64
-	ReplicaNum    uint8
-	Hosts         string
-	Peers         []bsProto.Peer
-	Status        int8
-	VolID         uint64
-	VolName       string
-	OfflinePeerID uint64
-	Replicas      []*replicaValue
-	IsRecover     bool
+	PartitionID     uint64
+	ReplicaNum      uint8
+	Hosts           string
+	Peers           []bsProto.Peer
+	Status          int8
+	VolID           uint64
+	VolName         string
+	OfflinePeerID   uint64
+	Replicas        []*replicaValue
+	IsRecover       bool
+	EcHosts         []string
+	Checksum        string
+	SharedExtentID  uint64
+	SharedRanges    []*bsProto.SharedExtentRange
+	SharedTinySizes map[uint64]uint64
 }
 
 type replicaValue struct {
@@ -105,16 +131,21 @@ type replicaValue struct {
 
 func newDataPartitionValue(dp *DataPartition) (dpv *dataPartitionValue) {
 	dpv = &dataPartitionValue{
-		PartitionID:   dp.PartitionID,
-		ReplicaNum:    dp.ReplicaNum,
-		Hosts:         dp.hostsToString(),
-		Peers:         dp.Peers,
-		Status:        dp.Status,
-		VolID:         dp.VolID,
-		VolName:       dp.VolName,
-		OfflinePeerID: dp.OfflinePeerID,
-		Replicas:      make([]*replicaValue, 0),
-		IsRecover:     dp.isRecover,
+		PartitionID:     dp.PartitionID,
+		ReplicaNum:      dp.ReplicaNum,
+		Hosts:           dp.hostsToString(),
+		Peers:           dp.Peers,
+		Status:          dp.Status,
+		VolID:           dp.VolID,
+		VolName:         dp.VolName,
+		OfflinePeerID:   dp.OfflinePeerID,
+		Replicas:        make([]*replicaValue, 0),
+		IsRecover:       dp.isRecover,
+		EcHosts:         dp.EcHosts,
+		Checksum:        dp.Checksum,
+		SharedExtentID:  dp.SharedExtentID,
+		SharedRanges:    dp.SharedRanges,
+		SharedTinySizes: dp.SharedTinySizes,
 	}
 	for _, replica := range dp.Replicas {
 		rv := &replicaValue{Addr: replica.Addr, DiskPath: replica.DiskPath}
@@ -143,6 +174,17 @@ type volValue struct {
 	Description       string
 	DpSelectorName    string
 	DpSelectorParm    string
+	EcDataNum         uint8
+	EcParityNum       uint8
+	TierPolicy        bsProto.TierPolicy
+	Qos               bsProto.VolQos
+	Compression       string
+	Checksum          string
+	QuorumWrite       bool
+	PlacementPolicy   string
+	Aliases           []string
+	CloneSource       string
+	SharedPartitions  []uint64
 }
 
 func (v *volValue) Bytes() (raw []byte, err error) {
@@ -171,6 +213,17 @@ func newVolValue(vol *Vol) (vv *volValue) {
 		Description:       vol.description,
 		DpSelectorName:    vol.dpSelectorName,
 		DpSelectorParm:    vol.dpSelectorParm,
+		EcDataNum:         vol.ecDataNum,
+		EcParityNum:       vol.ecParityNum,
+		TierPolicy:        vol.tierPolicy,
+		Qos:               vol.qos,
+		Compression:       vol.compression,
+		Checksum:          vol.checksum,
+		QuorumWrite:       vol.quorumWrite,
+		PlacementPolicy:   vol.placementPolicy,
+		Aliases:           vol.aliases,
+		CloneSource:       vol.cloneSource,
+		SharedPartitions:  vol.sharedPartitionIDs,
 	}
 	return
 }
@@ -188,6 +241,7 @@ type dataNodeValue struct {
 	NodeSetID uint64
 	Addr      string
 	ZoneName  string
+	Labels    map[string]string
 }
 
 func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
@@ -196,6 +250,7 @@ func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
 		NodeSetID: dataNode.NodeSetID,
 		Addr:      dataNode.Addr,
 		ZoneName:  dataNode.ZoneName,
+		Labels:    dataNode.Labels,
 	}
 }
 
@@ -204,6 +259,7 @@ type metaNodeValue struct {
 	NodeSetID uint64
 	Addr      string
 	ZoneName  string
+	Labels    map[string]string
 }
 
 func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
@@ -212,6 +268,7 @@ func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
 		NodeSetID: metaNode.NodeSetID,
 		Addr:      metaNode.Addr,
 		ZoneName:  metaNode.ZoneName,
+		Labels:    metaNode.Labels,
 	}
 }
 
@@ -282,6 +339,10 @@ func (m *RaftCmd) setOpType() {
 		m.Op = opSyncAddVolUser
 	case tokenAcronym:
 		m.Op = OpSyncAddToken
+	case jobAcronym:
+		m.Op = opSyncPutDecommissionJob
+	case migrationAcronym:
+		m.Op = opSyncPutMigration
 	default:
 		log.LogWarnf("action[setOpType] unknown opCode[%v]", keyArr[1])
 	}
@@ -528,10 +589,28 @@ func (c *Cluster) updateMetaNodeDeleteWorkerSleepMs(val uint64) {
 	atomic.StoreUint64(&c.cfg.MetaNodeDeleteWorkerSleepMs, val)
 }
 
+func (c *Cluster) updateMetaNodeDefragThreshold(val uint64) {
+	atomic.StoreUint64(&c.cfg.MetaNodeDefragThreshold, val)
+}
+
 func (c *Cluster) updateDataNodeAutoRepairLimit(val uint64) {
 	atomic.StoreUint64(&c.cfg.DataNodeAutoRepairLimitRate, val)
 }
 
+func (c *Cluster) updateDataNodeScrubLimitRate(val uint64) {
+	atomic.StoreUint64(&c.cfg.DataNodeScrubLimitRate, val)
+}
+
+func (c *Cluster) updateDataNodeDiskQos(iopsLimit, bandwidthLimit uint64) {
+	atomic.StoreUint64(&c.cfg.DataNodeDiskIopsLimit, iopsLimit)
+	atomic.StoreUint64(&c.cfg.DataNodeDiskBandwidthLimit, bandwidthLimit)
+}
+
+func (c *Cluster) updateDataNodeRepairBandwidth(nodeBandwidth, diskBandwidth uint64) {
+	atomic.StoreUint64(&c.cfg.DataNodeRepairNodeBandwidth, nodeBandwidth)
+	atomic.StoreUint64(&c.cfg.DataNodeRepairDiskBandwidth, diskBandwidth)
+}
+
 func (c *Cluster) updateDataNodeDeleteLimitRate(val uint64) {
 	atomic.StoreUint64(&c.cfg.DataNodeDeleteLimitRate, val)
 }
@@ -552,8 +631,14 @@ func (c *Cluster) loadClusterValue() (err error) {
 		c.DisableAutoAllocate = cv.DisableAutoAllocate
 		c.updateMetaNodeDeleteBatchCount(cv.MetaNodeDeleteBatchCount)
 		c.updateMetaNodeDeleteWorkerSleepMs(cv.MetaNodeDeleteWorkerSleepMs)
+		c.updateMetaNodeDefragThreshold(cv.MetaNodeDefragThreshold)
 		c.updateDataNodeDeleteLimitRate(cv.DataNodeDeleteLimitRate)
 		c.updateDataNodeAutoRepairLimit(cv.DataNodeAutoRepairLimitRate)
+		c.updateDataNodeScrubLimitRate(cv.DataNodeScrubLimitRate)
+		c.updateDataNodeDiskQos(cv.DataNodeDiskIopsLimit, cv.DataNodeDiskBandwidthLimit)
+		c.updateDataNodeRepairBandwidth(cv.DataNodeRepairNodeBandwidth, cv.DataNodeRepairDiskBandwidth)
+		c.rebalancer.update(cv.RebalancePaused, cv.RebalanceThreshold, cv.RebalanceMaxConcurrency)
+		c.diskFailure.update(cv.AutoDecommissionDisk, cv.DiskErrorThreshold, cv.AutoDecommissionMaxDisks, cv.BadDiskLimit)
 		log.LogInfof("action[loadClusterValue], metaNodeThreshold[%v]", cv.Threshold)
 	}
 	return
@@ -606,6 +691,7 @@ func (c *Cluster) loadDataNodes() (err error) {
 		dataNode := newDataNode(dnv.Addr, dnv.ZoneName, c.Name)
 		dataNode.ID = dnv.ID
 		dataNode.NodeSetID = dnv.NodeSetID
+		dataNode.Labels = dnv.Labels
 		olddn, ok := c.dataNodes.Load(dataNode.Addr)
 		if ok {
 			if olddn.(*DataNode).ID <= dataNode.ID {
@@ -636,6 +722,7 @@ func (c *Cluster) loadMetaNodes() (err error) {
 		metaNode := newMetaNode(mnv.Addr, mnv.ZoneName, c.Name)
 		metaNode.ID = mnv.ID
 		metaNode.NodeSetID = mnv.NodeSetID
+		metaNode.Labels = mnv.Labels
 		oldmn, ok := c.metaNodes.Load(metaNode.Addr)
 		if ok {
 			if oldmn.(*MetaNode).ID <= metaNode.ID {
@@ -740,6 +827,11 @@ func (c *Cluster) loadDataPartitions() (err error) {
 		dp.Peers = dpv.Peers
 		dp.OfflinePeerID = dpv.OfflinePeerID
 		dp.isRecover = dpv.IsRecover
+		dp.EcHosts = dpv.EcHosts
+		dp.Checksum = dpv.Checksum
+		dp.SharedExtentID = dpv.SharedExtentID
+		dp.SharedRanges = dpv.SharedRanges
+		dp.SharedTinySizes = dpv.SharedTinySizes
 		for _, rv := range dpv.Replicas {
 			if !contains(dp.Hosts, rv.Addr) {
 				continue
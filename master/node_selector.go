@@ -40,6 +40,7 @@ type Node interface {
 	SelectNodeForWrite()
 	GetID() uint64
 	GetAddr() string
+	GetLabel(key string) string
 }
 
 // SortedWeightedNodes defines an array sorted by carry
@@ -174,7 +175,7 @@ func getAvailCarryDataNodeTab(maxTotal uint64, excludeHosts []string, dataNodes
 	return
 }
 
-func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selectType int) (newHosts []string, peers []proto.Peer, err error) {
+func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selectType int, pl *placement) (newHosts []string, peers []proto.Peer, err error) {
 	var (
 		maxTotalFunc      GetMaxTotal
 		getCarryNodesFunc GetCarryNodes
@@ -205,8 +206,22 @@ func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selec
 	weightedNodes.setNodeCarry(count, replicaNum)
 	sort.Sort(weightedNodes)
 
-	for i := 0; i < replicaNum; i++ {
-		node := weightedNodes[i].Ptr
+	selected := make([]Node, 0, replicaNum)
+	for _, nt := range weightedNodes {
+		if len(selected) == replicaNum {
+			break
+		}
+		if pl.allows(nt.Ptr, selected) {
+			selected = append(selected, nt.Ptr)
+		}
+	}
+	if len(selected) < replicaNum {
+		err = fmt.Errorf("action[getAvailHosts] no enough writable hosts spread by placement[%v],replicaNum:%v  MatchNodeCount:%v  ",
+			pl, replicaNum, len(selected))
+		return
+	}
+	pl.take(selected)
+	for _, node := range selected {
 		node.SelectNodeForWrite()
 		orderHosts = append(orderHosts, node.GetAddr())
 		peer := proto.Peer{ID: node.GetID(), Addr: node.GetAddr()}
@@ -220,6 +235,6 @@ func getAvailHosts(nodes *sync.Map, excludeHosts []string, replicaNum int, selec
 	return
 }
 
-func (ns *nodeSet) getAvailMetaNodeHosts(excludeHosts []string, replicaNum int) (newHosts []string, peers []proto.Peer, err error) {
-	return getAvailHosts(ns.metaNodes, excludeHosts, replicaNum, selectMetaNode)
+func (ns *nodeSet) getAvailMetaNodeHosts(excludeHosts []string, replicaNum int, pl *placement) (newHosts []string, peers []proto.Peer, err error) {
+	return getAvailHosts(ns.metaNodes, excludeHosts, replicaNum, selectMetaNode, pl)
 }
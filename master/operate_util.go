@@ -55,6 +55,17 @@ func newAddDataPartitionRaftMemberRequest(ID uint64, addPeer proto.Peer) (req *p
 	return
 }
 
+func newConvertDataPartitionToEcRequest(ID uint64, dataNum, parityNum uint8, ecHosts []string, coldTime int64) (req *proto.ConvertDataPartitionToEcRequest) {
+	req = &proto.ConvertDataPartitionToEcRequest{
+		PartitionId: ID,
+		DataNum:     dataNum,
+		ParityNum:   parityNum,
+		EcHosts:     ecHosts,
+		ColdTime:    coldTime,
+	}
+	return
+}
+
 func newRemoveDataPartitionRaftMemberRequest(ID uint64, removePeer proto.Peer) (req *proto.RemoveDataPartitionRaftMemberRequest) {
 	req = &proto.RemoveDataPartitionRaftMemberRequest{
 		PartitionId: ID,
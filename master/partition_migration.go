@@ -0,0 +1,298 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	migrationCheckInterval  = 10 * time.Second
+	migrationCatchUpTimeout = 6 * time.Hour // the target not catching up in time is removed again
+)
+
+// A replica of a partition is migrated by adding the target first, and removing the source once the target
+// catches up with the other replicas, so the partition never has fewer replicas than configured. The migrations
+// are persisted by the raft of the masters, and the new leader resumes the ones interrupted by the change of the
+// leader once it loads the metadata.
+type partitionMigration struct {
+	Kind        string `json:"kind"`
+	PartitionID uint64 `json:"pid"`
+	Src         string `json:"src"`
+	Target      string `json:"target"`
+	StartTime   int64  `json:"start"`
+}
+
+func (c *Cluster) syncPutMigration(m *partitionMigration) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncPutMigration
+	metadata.K = migrationPrefix + migrationKey(m.Kind, m.PartitionID)
+	if metadata.V, err = json.Marshal(m); err != nil {
+		return
+	}
+	return c.submit(metadata)
+}
+
+func (c *Cluster) syncDeleteMigration(m *partitionMigration) (err error) {
+	metadata := new(RaftCmd)
+	metadata.Op = opSyncDeleteMigration
+	metadata.K = migrationPrefix + migrationKey(m.Kind, m.PartitionID)
+	return c.submit(metadata)
+}
+
+func (c *Cluster) loadMigrations() (err error) {
+	result, err := c.fsm.store.SeekForPrefix([]byte(migrationPrefix))
+	if err != nil {
+		err = fmt.Errorf("action[loadMigrations],err:%v", err.Error())
+		return err
+	}
+	for _, value := range result {
+		m := new(partitionMigration)
+		if err = json.Unmarshal(value, m); err != nil {
+			err = fmt.Errorf("action[loadMigrations],value:%v,err:%v", value, err)
+			return err
+		}
+		c.migrations.Store(migrationKey(m.Kind, m.PartitionID), m)
+		log.LogInfof("action[loadMigrations],%v partition[%v] from[%v] to[%v]", m.Kind, m.PartitionID, m.Src, m.Target)
+	}
+	return
+}
+
+func (c *Cluster) clearMigrations() {
+	c.migrations.Range(func(key, value interface{}) bool {
+		c.migrations.Delete(key)
+		return true
+	})
+}
+
+// startMigration records the migration, and fails if the partition is being migrated.
+func (c *Cluster) startMigration(kind string, partitionID uint64, srcAddr, targetAddr string) (m *partitionMigration, err error) {
+	m = &partitionMigration{Kind: kind, PartitionID: partitionID, Src: srcAddr, Target: targetAddr, StartTime: time.Now().Unix()}
+	if _, loaded := c.migrations.LoadOrStore(migrationKey(kind, partitionID), m); loaded {
+		return nil, fmt.Errorf("%v partition[%v] is being migrated", kind, partitionID)
+	}
+	if err = c.syncPutMigration(m); err != nil {
+		c.migrations.Delete(migrationKey(kind, partitionID))
+		return nil, err
+	}
+	return
+}
+
+// finishMigration deletes the record of the migration.
+func (c *Cluster) finishMigration(m *partitionMigration) {
+	if err := c.syncDeleteMigration(m); err != nil {
+		log.LogErrorf("action[finishMigration] %v partition[%v] err[%v]", m.Kind, m.PartitionID, err)
+	}
+	c.abandonMigration(m)
+}
+
+// abandonMigration forgets the migration left to the new leader, unless it is reloaded meanwhile.
+func (c *Cluster) abandonMigration(m *partitionMigration) {
+	key := migrationKey(m.Kind, m.PartitionID)
+	if value, ok := c.migrations.Load(key); ok && value == m {
+		c.migrations.Delete(key)
+	}
+}
+
+func (c *Cluster) isMigrating(kind string, partitionID uint64) bool {
+	_, ok := c.migrations.Load(migrationKey(kind, partitionID))
+	return ok
+}
+
+func (c *Cluster) isCurrentMigration(m *partitionMigration) bool {
+	value, ok := c.migrations.Load(migrationKey(m.Kind, m.PartitionID))
+	return ok && value == m
+}
+
+// resumeMigrations goes on with the migrations loaded by the new leader. The migration whose target has not been
+// added is dropped, and the one whose source has been removed is finished.
+func (c *Cluster) resumeMigrations() {
+	c.migrations.Range(func(key, value interface{}) bool {
+		m := value.(*partitionMigration)
+		var hosts []string
+		var dp *DataPartition
+		var mp *MetaPartition
+		var err error
+		if m.Kind == proto.RebalanceDataPartition {
+			if dp, err = c.getDataPartitionByID(m.PartitionID); err == nil {
+				dp.RLock()
+				hosts = dp.Hosts
+				dp.RUnlock()
+			}
+		} else {
+			if mp, err = c.getMetaPartitionByID(m.PartitionID); err == nil {
+				mp.RLock()
+				hosts = mp.Hosts
+				mp.RUnlock()
+			}
+		}
+		if err != nil || !migrationResumable(hosts, m) {
+			log.LogWarnf("action[resumeMigrations] drop %v partition[%v] from[%v] to[%v] hosts[%v] err[%v]",
+				m.Kind, m.PartitionID, m.Src, m.Target, hosts, err)
+			c.finishMigration(m)
+			return true
+		}
+		c.rebalancer.start(&proto.RebalanceMigration{Kind: m.Kind, PartitionID: m.PartitionID, Src: m.Src,
+			Target: m.Target, Reason: "resumed by the new leader", Status: proto.RebalanceRunning, StartTime: m.StartTime})
+		if dp != nil {
+			go c.finishDataMigration(dp, m)
+		} else {
+			go c.finishMetaMigration(mp, m)
+		}
+		log.LogWarnf("action[resumeMigrations] %v partition[%v] from[%v] to[%v]", m.Kind, m.PartitionID, m.Src, m.Target)
+		return true
+	})
+}
+
+// migrationResumable returns if both the source and the target of the migration are the hosts of the partition.
+func migrationResumable(hosts []string, m *partitionMigration) bool {
+	return contains(hosts, m.Src) && contains(hosts, m.Target)
+}
+
+// waitForCatchUp checks if the target catches up until the timeout, and returns false without an error
+// if the master is no longer the leader, leaving the migration to the new leader.
+func (c *Cluster) waitForCatchUp(m *partitionMigration, caughtUp func() (bool, error)) (ok bool, err error) {
+	deadline := time.Unix(m.StartTime, 0).Add(migrationCatchUpTimeout)
+	for {
+		if (c.partition != nil && !c.partition.IsRaftLeader()) || !c.isCurrentMigration(m) {
+			return false, nil
+		}
+		if ok, err = caughtUp(); ok || err != nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			return false, fmt.Errorf("not caught up in %v", migrationCatchUpTimeout)
+		}
+		time.Sleep(migrationCheckInterval)
+	}
+}
+
+// dataReplicaCaughtUp returns if the target replica uses the space within 1GB of the other live replicas,
+// the same as the recovery of the partitions.
+func dataReplicaCaughtUp(dp *DataPartition, srcAddr, targetAddr string) (ok bool, err error) {
+	dp.RLock()
+	defer dp.RUnlock()
+	if !dp.hasHost(targetAddr) {
+		return false, fmt.Errorf("target[%v] is removed", targetAddr)
+	}
+	target, err := dp.getReplica(targetAddr)
+	if err != nil || target.isMissing(defaultDataPartitionTimeOutSec) {
+		// not reported by the target yet
+		return false, nil
+	}
+	var maxUsed uint64
+	for _, replica := range dp.liveReplicas(defaultDataPartitionTimeOutSec) {
+		if replica.Addr != srcAddr && replica.Used > maxUsed {
+			maxUsed = replica.Used
+		}
+	}
+	return maxUsed < target.Used+util.GB, nil
+}
+
+// metaReplicaCaughtUp returns if the max inode id of the target replica is close to the other replicas,
+// the same as the recovery of the partitions.
+func metaReplicaCaughtUp(mp *MetaPartition, srcAddr, targetAddr string) (ok bool, err error) {
+	mp.RLock()
+	defer mp.RUnlock()
+	if !contains(mp.Hosts, targetAddr) {
+		return false, fmt.Errorf("target[%v] is removed", targetAddr)
+	}
+	target, err := mp.getMetaReplica(targetAddr)
+	if err != nil || target.ReportTime < time.Now().Unix()-defaultMetaPartitionTimeOutSec {
+		return false, nil
+	}
+	var maxInodeID uint64
+	for _, replica := range mp.Replicas {
+		if replica.Addr != srcAddr && replica.MaxInodeID > maxInodeID {
+			maxInodeID = replica.MaxInodeID
+		}
+	}
+	return maxInodeID < target.MaxInodeID+defaultMinusOfMaxInodeID, nil
+}
+
+// finishDataMigration removes the source once the target catches up. The target is removed instead if it fails to,
+// and only if the source is still there, so the partition keeps the replicas configured either way.
+func (c *Cluster) finishDataMigration(dp *DataPartition, m *partitionMigration) {
+	srcAddr, targetAddr := m.Src, m.Target
+	ok, err := c.waitForCatchUp(m, func() (bool, error) { return dataReplicaCaughtUp(dp, srcAddr, targetAddr) })
+	if !ok && err == nil {
+		c.abandonMigration(m)
+		return
+	}
+	defer c.finishMigration(m)
+	if err == nil {
+		err = c.removeDataReplica(dp, srcAddr, false)
+	}
+	dp.RLock()
+	rollback := err != nil && dp.hasHost(srcAddr) && dp.hasHost(targetAddr)
+	dp.RUnlock()
+	if rollback {
+		if e := c.removeDataReplica(dp, targetAddr, false); e != nil {
+			log.LogErrorf("action[finishDataMigration] vol[%v] data partition[%v] roll back target[%v] err[%v]",
+				dp.VolName, dp.PartitionID, targetAddr, e)
+		}
+	}
+	dp.isRecover = false
+	dp.RLock()
+	c.syncUpdateDataPartition(dp)
+	dp.RUnlock()
+	if err != nil {
+		Warn(c.Name, fmt.Sprintf("action[finishDataMigration] clusterID[%v] vol[%v] data partition[%v] from[%v] to[%v] rolled back,err[%v]",
+			c.Name, dp.VolName, dp.PartitionID, srcAddr, targetAddr, err))
+		return
+	}
+	log.LogWarnf("action[finishDataMigration] clusterID[%v] vol[%v] data partition[%v] moved from[%v] to[%v]",
+		c.Name, dp.VolName, dp.PartitionID, srcAddr, targetAddr)
+}
+
+// finishMetaMigration removes the source once the target catches up, or the target otherwise.
+func (c *Cluster) finishMetaMigration(mp *MetaPartition, m *partitionMigration) {
+	srcAddr, targetAddr := m.Src, m.Target
+	ok, err := c.waitForCatchUp(m, func() (bool, error) { return metaReplicaCaughtUp(mp, srcAddr, targetAddr) })
+	if !ok && err == nil {
+		c.abandonMigration(m)
+		return
+	}
+	defer c.finishMigration(m)
+	if err == nil {
+		err = c.deleteMetaReplica(mp, srcAddr, false)
+	}
+	mp.RLock()
+	rollback := err != nil && contains(mp.Hosts, srcAddr) && contains(mp.Hosts, targetAddr)
+	mp.RUnlock()
+	if rollback {
+		if e := c.deleteMetaReplica(mp, targetAddr, false); e != nil {
+			log.LogErrorf("action[finishMetaMigration] vol[%v] meta partition[%v] roll back target[%v] err[%v]",
+				mp.volName, mp.PartitionID, targetAddr, e)
+		}
+	}
+	mp.IsRecover = false
+	mp.RLock()
+	c.syncUpdateMetaPartition(mp)
+	mp.RUnlock()
+	if err != nil {
+		Warn(c.Name, fmt.Sprintf("action[finishMetaMigration] clusterID[%v] vol[%v] meta partition[%v] from[%v] to[%v] rolled back,err[%v]",
+			c.Name, mp.volName, mp.PartitionID, srcAddr, targetAddr, err))
+		return
+	}
+	log.LogWarnf("action[finishMetaMigration] clusterID[%v] vol[%v] meta partition[%v] moved from[%v] to[%v]",
+		c.Name, mp.volName, mp.PartitionID, srcAddr, targetAddr)
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// placement spreads the replicas of a partition across the distinct values of a label of the nodes, by the
+// placement policy of the volume. The nodes without the label are not restricted.
+type placement struct {
+	label string
+	used  map[string]bool // the values of the label taken by the replicas of the partition
+}
+
+func isValidPlacementPolicy(policy string) bool {
+	return policy == "" || contains(proto.NodeLabels, policy)
+}
+
+// newPlacement returns nil if the volume has no placement policy, the nil placement allows any node.
+func newPlacement(policy string) *placement {
+	if policy == "" {
+		return nil
+	}
+	return &placement{label: policy, used: make(map[string]bool)}
+}
+
+func (p *placement) String() string {
+	if p == nil {
+		return "none"
+	}
+	return p.label
+}
+
+// restricted returns if the nodes may be rejected by the placement.
+func (p *placement) restricted() bool {
+	return p != nil
+}
+
+// allows returns if the node can hold a replica, besides the replicas placed and the nodes selected.
+func (p *placement) allows(node Node, selected []Node) bool {
+	if p == nil {
+		return true
+	}
+	value := node.GetLabel(p.label)
+	if value == "" {
+		return true
+	}
+	if p.used[value] {
+		return false
+	}
+	for _, n := range selected {
+		if n.GetLabel(p.label) == value {
+			return false
+		}
+	}
+	return true
+}
+
+// take marks the values of the label of the nodes selected as used by the replicas.
+func (p *placement) take(nodes []Node) {
+	if p == nil {
+		return
+	}
+	for _, node := range nodes {
+		if value := node.GetLabel(p.label); value != "" {
+			p.used[value] = true
+		}
+	}
+}
+
+// nodeLabel returns the value of the label of a node, the host defaults to the IP of the node.
+func nodeLabel(labels map[string]string, addr, key string) string {
+	if value := labels[key]; value != "" {
+		return value
+	}
+	if key == proto.NodeLabelHost {
+		return strings.Split(addr, ":")[0]
+	}
+	return ""
+}
+
+func (c *Cluster) volPlacementPolicy(volName string) string {
+	vol, err := c.getVol(volName)
+	if err != nil {
+		return ""
+	}
+	vol.RLock()
+	defer vol.RUnlock()
+	return vol.placementPolicy
+}
+
+// dataPlacement returns the placement of the data partition with the labels of the replicas taken, except the
+// replica on the excluded address, which is to be replaced.
+func (c *Cluster) dataPlacement(dp *DataPartition, excludeAddr string) (p *placement) {
+	if p = newPlacement(c.volPlacementPolicy(dp.VolName)); p == nil {
+		return
+	}
+	dp.RLock()
+	hosts := dp.Hosts
+	dp.RUnlock()
+	nodes := make([]Node, 0, len(hosts))
+	for _, host := range hosts {
+		if host == excludeAddr {
+			continue
+		}
+		if dataNode, err := c.dataNode(host); err == nil {
+			nodes = append(nodes, dataNode)
+		}
+	}
+	p.take(nodes)
+	return
+}
+
+// metaPlacement returns the placement of the meta partition with the labels of the replicas taken, except the
+// replica on the excluded address, which is to be replaced.
+func (c *Cluster) metaPlacement(mp *MetaPartition, excludeAddr string) (p *placement) {
+	if p = newPlacement(c.volPlacementPolicy(mp.volName)); p == nil {
+		return
+	}
+	mp.RLock()
+	hosts := mp.Hosts
+	mp.RUnlock()
+	nodes := make([]Node, 0, len(hosts))
+	for _, host := range hosts {
+		if host == excludeAddr {
+			continue
+		}
+		if metaNode, err := c.metaNode(host); err == nil {
+			nodes = append(nodes, metaNode)
+		}
+	}
+	p.take(nodes)
+	return
+}
+
+// checkDataPlacement verifies the replica to be added on the target by the placement policy of the volume, the
+// replica on the source is to be removed, if any.
+func (c *Cluster) checkDataPlacement(dp *DataPartition, srcAddr, targetAddr string) (err error) {
+	p := c.dataPlacement(dp, srcAddr)
+	if p == nil {
+		return
+	}
+	dataNode, err := c.dataNode(targetAddr)
+	if err != nil {
+		return
+	}
+	if !p.allows(dataNode, nil) {
+		err = fmt.Errorf("vol[%v],dp[%v] has a replica on the %v[%v] of the host[%v]",
+			dp.VolName, dp.PartitionID, p.label, dataNode.GetLabel(p.label), targetAddr)
+	}
+	return
+}
+
+// checkMetaPlacement verifies the replica to be added on the target by the placement policy of the volume, the
+// replica on the source is to be removed, if any.
+func (c *Cluster) checkMetaPlacement(mp *MetaPartition, srcAddr, targetAddr string) (err error) {
+	p := c.metaPlacement(mp, srcAddr)
+	if p == nil {
+		return
+	}
+	metaNode, err := c.metaNode(targetAddr)
+	if err != nil {
+		return
+	}
+	if !p.allows(metaNode, nil) {
+		err = fmt.Errorf("vol[%v],mp[%v] has a replica on the %v[%v] of the host[%v]",
+			mp.volName, mp.PartitionID, p.label, metaNode.GetLabel(p.label), targetAddr)
+	}
+	return
+}
+
+// updateDataNodeLabels persists the labels registered again by a data node if changed, e.g. moved to another rack.
+func (c *Cluster) updateDataNodeLabels(dataNode *DataNode, labels map[string]string) (err error) {
+	dataNode.Lock()
+	oldLabels := dataNode.Labels
+	if reflect.DeepEqual(oldLabels, labels) {
+		dataNode.Unlock()
+		return
+	}
+	dataNode.Labels = labels
+	dataNode.Unlock()
+	if err = c.syncUpdateDataNode(dataNode); err != nil {
+		dataNode.Lock()
+		dataNode.Labels = oldLabels
+		dataNode.Unlock()
+		return
+	}
+	log.LogInfof("action[updateDataNodeLabels] dataNode[%v] labels[%v] -> [%v]", dataNode.Addr, oldLabels, labels)
+	return
+}
+
+// updateMetaNodeLabels persists the labels registered again by a meta node if changed, e.g. moved to another rack.
+func (c *Cluster) updateMetaNodeLabels(metaNode *MetaNode, labels map[string]string) (err error) {
+	metaNode.Lock()
+	oldLabels := metaNode.Labels
+	if reflect.DeepEqual(oldLabels, labels) {
+		metaNode.Unlock()
+		return
+	}
+	metaNode.Labels = labels
+	metaNode.Unlock()
+	if err = c.syncUpdateMetaNode(metaNode); err != nil {
+		metaNode.Lock()
+		metaNode.Labels = oldLabels
+		metaNode.Unlock()
+		return
+	}
+	log.LogInfof("action[updateMetaNodeLabels] metaNode[%v] labels[%v] -> [%v]", metaNode.Addr, oldLabels, labels)
+	return
+}
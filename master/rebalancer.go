@@ -0,0 +1,557 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultRebalanceThreshold      = 0.1 // the spread of the usage ratios, or of the partition counts to the average
+	defaultRebalanceMaxConcurrency = 2
+	rebalanceInterval              = 2 * time.Minute
+	rebalanceCooldown              = 30 * 60 // seconds a node moving a partition in is not moved out of, and vice versa
+	maxRebalanceHistory            = 100
+)
+
+// The rebalancer moves the replicas of the partitions from the most loaded nodes to the least loaded ones,
+// within the node sets first and then within the zones, once the spread of the load exceeds the threshold.
+// The volumes are placed on their zones, so the imbalance among the zones is only reported.
+// The replicas are moved in the same way as the decommission, and at most maxConcurrency migrations run at the
+// same time. A partition moved by the usage never makes the target more used than the source, and the nodes moving
+// the partitions recently are not moved in the other way, so the partitions do not move back and forth.
+type rebalancer struct {
+	paused         bool
+	threshold      float64
+	maxConcurrency uint64
+	running        map[string]*proto.RebalanceMigration
+	history        []*proto.RebalanceMigration
+	sync.RWMutex
+}
+
+func newRebalancer() *rebalancer {
+	return &rebalancer{
+		threshold:      defaultRebalanceThreshold,
+		maxConcurrency: defaultRebalanceMaxConcurrency,
+		running:        make(map[string]*proto.RebalanceMigration),
+		history:        make([]*proto.RebalanceMigration, 0),
+	}
+}
+
+func migrationKey(kind string, partitionID uint64) string {
+	return fmt.Sprintf("%v_%v", kind, partitionID)
+}
+
+func (rb *rebalancer) settings() (paused bool, threshold float64, maxConcurrency uint64) {
+	rb.RLock()
+	defer rb.RUnlock()
+	return rb.paused, rb.threshold, rb.maxConcurrency
+}
+
+// update updates the settings, and the zero threshold or concurrency loaded from the old cluster values
+// falls back to the defaults.
+func (rb *rebalancer) update(paused bool, threshold float64, maxConcurrency uint64) {
+	rb.Lock()
+	defer rb.Unlock()
+	if threshold <= 0 {
+		threshold = defaultRebalanceThreshold
+	}
+	if maxConcurrency == 0 {
+		maxConcurrency = defaultRebalanceMaxConcurrency
+	}
+	rb.paused = paused
+	rb.threshold = threshold
+	rb.maxConcurrency = maxConcurrency
+}
+
+func (rb *rebalancer) runningCount() int {
+	rb.RLock()
+	defer rb.RUnlock()
+	return len(rb.running)
+}
+
+func (rb *rebalancer) isRunning(kind string, partitionID uint64) bool {
+	rb.RLock()
+	defer rb.RUnlock()
+	_, ok := rb.running[migrationKey(kind, partitionID)]
+	return ok
+}
+
+// recentNodes returns the nodes the partitions are moved out of and into within the cooldown.
+func (rb *rebalancer) recentNodes(now int64) (sources, targets map[string]bool) {
+	rb.RLock()
+	defer rb.RUnlock()
+	sources, targets = make(map[string]bool), make(map[string]bool)
+	for _, m := range rb.history {
+		if m.Status == proto.RebalanceSucceeded && now-m.EndTime < rebalanceCooldown {
+			sources[m.Src] = true
+			targets[m.Target] = true
+		}
+	}
+	return
+}
+
+// busyNodes returns the nodes moving the partitions out or in.
+func (rb *rebalancer) busyNodes() (nodes map[string]bool) {
+	rb.RLock()
+	defer rb.RUnlock()
+	nodes = make(map[string]bool)
+	for _, m := range rb.running {
+		nodes[m.Src] = true
+		nodes[m.Target] = true
+	}
+	return
+}
+
+func (rb *rebalancer) start(m *proto.RebalanceMigration) {
+	rb.Lock()
+	defer rb.Unlock()
+	rb.running[migrationKey(m.Kind, m.PartitionID)] = m
+}
+
+func (rb *rebalancer) finish(m *proto.RebalanceMigration, status, msg string) {
+	rb.Lock()
+	defer rb.Unlock()
+	m.Status = status
+	m.Msg = msg
+	m.EndTime = time.Now().Unix()
+	delete(rb.running, migrationKey(m.Kind, m.PartitionID))
+	rb.history = append(rb.history, m)
+	if len(rb.history) > maxRebalanceHistory {
+		rb.history = rb.history[len(rb.history)-maxRebalanceHistory:]
+	}
+}
+
+func (rb *rebalancer) runningMigrations() (migrations []*proto.RebalanceMigration) {
+	rb.RLock()
+	defer rb.RUnlock()
+	migrations = make([]*proto.RebalanceMigration, 0, len(rb.running))
+	for _, m := range rb.running {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].StartTime < migrations[j].StartTime })
+	return
+}
+
+func (rb *rebalancer) historyMigrations() (migrations []*proto.RebalanceMigration) {
+	rb.RLock()
+	defer rb.RUnlock()
+	migrations = make([]*proto.RebalanceMigration, len(rb.history))
+	copy(migrations, rb.history)
+	return
+}
+
+// nodeLoad is the load of a data node or a meta node compared by the rebalancer.
+type nodeLoad struct {
+	addr      string
+	nodeSetID uint64
+	ratio     float64 // the used space of the data node, or the used memory of the meta node
+	total     uint64  // the total space of the data node, or the total memory of the meta node
+	count     int     // the partitions on the node
+	writable  bool
+}
+
+func dataNodeLoads(nodes *sync.Map) (loads []*nodeLoad) {
+	loads = make([]*nodeLoad, 0)
+	nodes.Range(func(key, value interface{}) bool {
+		dataNode := value.(*DataNode)
+		dataNode.RLock()
+		load := &nodeLoad{
+			addr:      dataNode.Addr,
+			nodeSetID: dataNode.NodeSetID,
+			ratio:     dataNode.UsageRatio,
+			total:     dataNode.Total,
+			count:     int(dataNode.DataPartitionCount),
+		}
+		active := dataNode.isActive && !dataNode.ToBeOffline
+		dataNode.RUnlock()
+		if active {
+			load.writable = dataNode.isWriteAble()
+			loads = append(loads, load)
+		}
+		return true
+	})
+	return
+}
+
+func metaNodeLoads(nodes *sync.Map) (loads []*nodeLoad) {
+	loads = make([]*nodeLoad, 0)
+	nodes.Range(func(key, value interface{}) bool {
+		metaNode := value.(*MetaNode)
+		metaNode.RLock()
+		load := &nodeLoad{
+			addr:      metaNode.Addr,
+			nodeSetID: metaNode.NodeSetID,
+			ratio:     metaNode.Ratio,
+			total:     metaNode.Total,
+			count:     metaNode.MetaPartitionCount,
+		}
+		active := metaNode.IsActive && !metaNode.ToBeOffline
+		metaNode.RUnlock()
+		if active {
+			load.writable = metaNode.isWritable()
+			loads = append(loads, load)
+		}
+		return true
+	})
+	return
+}
+
+func filterNodeLoads(loads []*nodeLoad, match func(load *nodeLoad) bool) (matched []*nodeLoad) {
+	matched = make([]*nodeLoad, 0, len(loads))
+	for _, load := range loads {
+		if match(load) {
+			matched = append(matched, load)
+		}
+	}
+	return
+}
+
+func averageNodeLoad(name string, loads []*nodeLoad) *nodeLoad {
+	avg := &nodeLoad{addr: name}
+	if len(loads) == 0 {
+		return avg
+	}
+	for _, load := range loads {
+		avg.ratio += load.ratio
+		avg.count += load.count
+	}
+	avg.ratio = avg.ratio / float64(len(loads))
+	avg.count = avg.count / len(loads)
+	return avg
+}
+
+// loadSpread returns the spread of the usage ratios and of the partition counts among the nodes.
+func loadSpread(loads []*nodeLoad) (ratioSpread float64, countSpread int) {
+	if len(loads) < 2 {
+		return
+	}
+	minRatio, maxRatio := loads[0].ratio, loads[0].ratio
+	minCount, maxCount := loads[0].count, loads[0].count
+	for _, load := range loads[1:] {
+		if load.ratio < minRatio {
+			minRatio = load.ratio
+		}
+		if load.ratio > maxRatio {
+			maxRatio = load.ratio
+		}
+		if load.count < minCount {
+			minCount = load.count
+		}
+		if load.count > maxCount {
+			maxCount = load.count
+		}
+	}
+	return maxRatio - minRatio, maxCount - minCount
+}
+
+// pickRebalancePair picks the most loaded node to move a partition out of and the least loaded writable node
+// to move it into, if their usage ratios, or their partition counts to the average, differ more than the threshold.
+// The nodes excluded as the sources or the targets are not picked as such. The partition moved by the usage ratios
+// must be no larger than maxMove, which is zero if moved by the partition counts.
+func pickRebalancePair(loads []*nodeLoad, threshold float64, noSrc, noTarget map[string]bool) (src, target *nodeLoad, reason string, maxMove uint64) {
+	if len(loads) < 2 {
+		return
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].ratio < loads[j].ratio })
+	if src = mostLoaded(loads, noSrc); src != nil {
+		if target = leastLoadedWritable(loads, src, noTarget); target != nil && src.ratio-target.ratio > threshold {
+			if maxMove = maxRebalanceMove(src, target); maxMove > 0 {
+				reason = fmt.Sprintf("usage ratio %.2f to %.2f", src.ratio, target.ratio)
+				return
+			}
+		}
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].count < loads[j].count })
+	avg := averageNodeLoad("", loads)
+	if src = mostLoaded(loads, noSrc); src != nil {
+		// the count of the source is still no less than the target after moving one
+		if target = leastLoadedWritable(loads, src, noTarget); target != nil && src.count-target.count > 1 &&
+			float64(src.count-target.count) > threshold*float64(avg.count) {
+			reason = fmt.Sprintf("partition count %v to %v", src.count, target.count)
+			return src, target, reason, 0
+		}
+	}
+	return nil, nil, "", 0
+}
+
+// maxRebalanceMove returns the size of the partition moved from the source to the target at most, for the target
+// to stay no more used than the source.
+func maxRebalanceMove(src, target *nodeLoad) uint64 {
+	if src.total == 0 || target.total == 0 || src.ratio <= target.ratio {
+		return 0
+	}
+	srcTotal, targetTotal := float64(src.total), float64(target.total)
+	return uint64((src.ratio - target.ratio) * srcTotal * targetTotal / (srcTotal + targetTotal))
+}
+
+func mostLoaded(sorted []*nodeLoad, excluded map[string]bool) *nodeLoad {
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if !excluded[sorted[i].addr] {
+			return sorted[i]
+		}
+	}
+	return nil
+}
+
+func leastLoadedWritable(sorted []*nodeLoad, src *nodeLoad, excluded map[string]bool) *nodeLoad {
+	for _, load := range sorted {
+		if load != src && load.writable && !excluded[load.addr] {
+			return load
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) scheduleToRebalance() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.rebalance()
+			}
+			time.Sleep(rebalanceInterval)
+		}
+	}()
+}
+
+func (c *Cluster) rebalance() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("rebalance occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", c.Name, ModuleName),
+				"rebalance occurred panic")
+		}
+	}()
+	c.checkRebalanceProgress()
+	paused, threshold, maxConcurrency := c.rebalancer.settings()
+	if paused {
+		return
+	}
+	for _, zone := range c.t.getAllZones() {
+		if uint64(c.rebalancer.runningCount()) >= maxConcurrency {
+			return
+		}
+		c.rebalanceZone(zone, proto.RebalanceDataPartition, threshold)
+		if uint64(c.rebalancer.runningCount()) >= maxConcurrency {
+			return
+		}
+		c.rebalanceZone(zone, proto.RebalanceMetaPartition, threshold)
+	}
+}
+
+// rebalanceZone starts at most one migration of the kind in the zone each round, as the load of the nodes
+// is not updated until the next heartbeats. The node sets are balanced first to keep the replicas of the
+// partitions in the same node set.
+func (c *Cluster) rebalanceZone(zone *Zone, kind string, threshold float64) {
+	var loads []*nodeLoad
+	if kind == proto.RebalanceDataPartition {
+		loads = dataNodeLoads(zone.dataNodes)
+	} else {
+		loads = metaNodeLoads(zone.metaNodes)
+	}
+	busy := c.rebalancer.busyNodes()
+	loads = filterNodeLoads(loads, func(load *nodeLoad) bool { return !busy[load.addr] })
+	recentSources, recentTargets := c.rebalancer.recentNodes(time.Now().Unix())
+	for _, ns := range zone.getAllNodeSet() {
+		nsLoads := filterNodeLoads(loads, func(load *nodeLoad) bool { return load.nodeSetID == ns.ID })
+		if src, target, reason, maxMove := pickRebalancePair(nsLoads, threshold, recentTargets, recentSources); src != nil {
+			if c.startRebalanceMigration(kind, src.addr, target.addr, reason, maxMove) {
+				return
+			}
+		}
+	}
+	if src, target, reason, maxMove := pickRebalancePair(loads, threshold, recentTargets, recentSources); src != nil {
+		c.startRebalanceMigration(kind, src.addr, target.addr, reason, maxMove)
+	}
+}
+
+// startRebalanceMigration moves a partition from the source node to the target, and returns false if no
+// partition can be moved.
+func (c *Cluster) startRebalanceMigration(kind, srcAddr, targetAddr, reason string, maxMove uint64) bool {
+	m := &proto.RebalanceMigration{
+		Kind:      kind,
+		Src:       srcAddr,
+		Target:    targetAddr,
+		Reason:    reason,
+		Status:    proto.RebalanceRunning,
+		StartTime: time.Now().Unix(),
+	}
+	var err error
+	if kind == proto.RebalanceDataPartition {
+		dp := c.pickDataPartitionToRebalance(srcAddr, targetAddr, maxMove)
+		if dp == nil {
+			return false
+		}
+		m.PartitionID, m.VolName = dp.PartitionID, dp.VolName
+		c.rebalancer.start(m)
+		err = c.migrateDataPartition(dp, srcAddr, targetAddr)
+	} else {
+		mp := c.pickMetaPartitionToRebalance(srcAddr, targetAddr)
+		if mp == nil {
+			return false
+		}
+		m.PartitionID, m.VolName = mp.PartitionID, mp.volName
+		c.rebalancer.start(m)
+		err = c.migrateMetaPartition(mp, srcAddr, targetAddr)
+	}
+	if err != nil {
+		c.rebalancer.finish(m, proto.RebalanceFailed, err.Error())
+		Warn(c.Name, fmt.Sprintf("action[rebalance] clusterID[%v] vol[%v] %v partition[%v] from[%v] to[%v] failed,err[%v]",
+			c.Name, m.VolName, kind, m.PartitionID, srcAddr, targetAddr, err))
+		return true
+	}
+	log.LogWarnf("action[rebalance] clusterID[%v] vol[%v] %v partition[%v] from[%v] to[%v] started, %v",
+		c.Name, m.VolName, kind, m.PartitionID, srcAddr, targetAddr, reason)
+	return true
+}
+
+// pickDataPartitionToRebalance picks the largest data partition on the source node which can be decommissioned,
+// and is no larger than maxMove if it is not zero.
+func (c *Cluster) pickDataPartitionToRebalance(srcAddr, targetAddr string, maxMove uint64) (picked *DataPartition) {
+	var pickedUsed uint64
+	for _, dp := range c.getAllDataPartitionByDataNode(srcAddr) {
+		dp.RLock()
+		skip := dp.hasHost(targetAddr) || dp.isRecover
+		used := dp.getMaxUsedSpace()
+		dp.RUnlock()
+		if skip || c.rebalancer.isRunning(proto.RebalanceDataPartition, dp.PartitionID) {
+			continue
+		}
+		if (maxMove > 0 && used > maxMove) || (picked != nil && used <= pickedUsed) {
+			continue
+		}
+		if err := c.validateDecommissionDataPartition(dp, srcAddr); err != nil {
+			continue
+		}
+		if err := c.checkDataPlacement(dp, srcAddr, targetAddr); err != nil {
+			continue
+		}
+		picked, pickedUsed = dp, used
+	}
+	return
+}
+
+// pickMetaPartitionToRebalance picks a meta partition on the source node which can be decommissioned.
+func (c *Cluster) pickMetaPartitionToRebalance(srcAddr, targetAddr string) *MetaPartition {
+	for _, mp := range c.getAllMetaPartitionByMetaNode(srcAddr) {
+		mp.RLock()
+		skip := contains(mp.Hosts, targetAddr) || mp.IsRecover
+		mp.RUnlock()
+		if skip || c.rebalancer.isRunning(proto.RebalanceMetaPartition, mp.PartitionID) {
+			continue
+		}
+		if err := c.validateDecommissionMetaPartition(mp, srcAddr); err != nil {
+			continue
+		}
+		if err := c.checkMetaPlacement(mp, srcAddr, targetAddr); err != nil {
+			continue
+		}
+		return mp
+	}
+	return nil
+}
+
+// checkRebalanceProgress finishes the migrations whose sources are removed, or whose targets are rolled back.
+func (c *Cluster) checkRebalanceProgress() {
+	for _, m := range c.rebalancer.runningMigrations() {
+		if c.isMigrating(m.Kind, m.PartitionID) {
+			continue
+		}
+		var hosts []string
+		if m.Kind == proto.RebalanceDataPartition {
+			dp, err := c.getDataPartitionByID(m.PartitionID)
+			if err != nil {
+				c.rebalancer.finish(m, proto.RebalanceFailed, err.Error())
+				continue
+			}
+			dp.RLock()
+			hosts = dp.Hosts
+			dp.RUnlock()
+		} else {
+			mp, err := c.getMetaPartitionByID(m.PartitionID)
+			if err != nil {
+				c.rebalancer.finish(m, proto.RebalanceFailed, err.Error())
+				continue
+			}
+			mp.RLock()
+			hosts = mp.Hosts
+			mp.RUnlock()
+		}
+		if contains(hosts, m.Src) || !contains(hosts, m.Target) {
+			c.rebalancer.finish(m, proto.RebalanceFailed, "the migration is rolled back")
+			continue
+		}
+		c.rebalancer.finish(m, proto.RebalanceSucceeded, "")
+		log.LogInfof("action[checkRebalanceProgress] vol[%v] %v partition[%v] moved from[%v] to[%v]",
+			m.VolName, m.Kind, m.PartitionID, m.Src, m.Target)
+	}
+}
+
+func (c *Cluster) setRebalance(enable bool, threshold float64, maxConcurrency uint64) (err error) {
+	oldPaused, oldThreshold, oldMaxConcurrency := c.rebalancer.settings()
+	c.rebalancer.update(!enable, threshold, maxConcurrency)
+	if err = c.syncPutCluster(); err != nil {
+		log.LogErrorf("action[setRebalance] err[%v]", err)
+		c.rebalancer.update(oldPaused, oldThreshold, oldMaxConcurrency)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	return
+}
+
+func (c *Cluster) getRebalanceView() (view *proto.RebalanceView) {
+	paused, threshold, maxConcurrency := c.rebalancer.settings()
+	view = &proto.RebalanceView{
+		Enable:         !paused,
+		Threshold:      threshold,
+		MaxConcurrency: maxConcurrency,
+		Imbalances:     make([]*proto.RebalanceImbalance, 0),
+		Running:        c.rebalancer.runningMigrations(),
+		History:        c.rebalancer.historyMigrations(),
+	}
+	zoneDataLoads := make([]*nodeLoad, 0)
+	zoneMetaLoads := make([]*nodeLoad, 0)
+	for _, zone := range c.t.getAllZones() {
+		dataLoads := dataNodeLoads(zone.dataNodes)
+		metaLoads := metaNodeLoads(zone.metaNodes)
+		view.Imbalances = append(view.Imbalances, newRebalanceImbalance("zone", zone.name, dataLoads, metaLoads))
+		for _, ns := range zone.getAllNodeSet() {
+			inNodeSet := func(load *nodeLoad) bool { return load.nodeSetID == ns.ID }
+			view.Imbalances = append(view.Imbalances, newRebalanceImbalance("nodeSet", fmt.Sprintf("%v/%v", zone.name, ns.ID),
+				filterNodeLoads(dataLoads, inNodeSet), filterNodeLoads(metaLoads, inNodeSet)))
+		}
+		if len(dataLoads) > 0 {
+			zoneDataLoads = append(zoneDataLoads, averageNodeLoad(zone.name, dataLoads))
+		}
+		if len(metaLoads) > 0 {
+			zoneMetaLoads = append(zoneMetaLoads, averageNodeLoad(zone.name, metaLoads))
+		}
+	}
+	view.Imbalances = append(view.Imbalances, newRebalanceImbalance("cluster", c.Name, zoneDataLoads, zoneMetaLoads))
+	return
+}
+
+func newRebalanceImbalance(scope, name string, dataLoads, metaLoads []*nodeLoad) *proto.RebalanceImbalance {
+	imbalance := &proto.RebalanceImbalance{Scope: scope, Name: name}
+	imbalance.DataUsageSpread, imbalance.DataPartitionSpread = loadSpread(dataLoads)
+	imbalance.MetaMemSpread, imbalance.MetaPartitionSpread = loadSpread(metaLoads)
+	return imbalance
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func newTestNodeLoads() []*nodeLoad {
+	return []*nodeLoad{
+		{addr: "a", ratio: 0.9, total: 100 * util.GB, count: 10, writable: true},
+		{addr: "b", ratio: 0.5, total: 100 * util.GB, count: 10, writable: true},
+		{addr: "c", ratio: 0.1, total: 100 * util.GB, count: 10, writable: true},
+	}
+}
+
+func TestPickRebalancePair(t *testing.T) {
+	src, target, _, maxMove := pickRebalancePair(newTestNodeLoads(), 0.1, nil, nil)
+	if src == nil || src.addr != "a" || target.addr != "c" {
+		t.Fatalf("pick by ratio: src[%v] target[%v]", src, target)
+	}
+	if expect := maxRebalanceMove(src, target); maxMove != expect || maxMove == 0 {
+		t.Errorf("pick by ratio: maxMove[%v] expect[%v]", maxMove, expect)
+	}
+
+	loads := newTestNodeLoads()
+	for _, load := range loads {
+		load.ratio = 0.5
+	}
+	loads[0].count, loads[2].count = 20, 2
+	src, target, _, maxMove = pickRebalancePair(loads, 0.1, nil, nil)
+	if src == nil || src.addr != "a" || target.addr != "c" || maxMove != 0 {
+		t.Errorf("pick by count: src[%v] target[%v] maxMove[%v]", src, target, maxMove)
+	}
+
+	loads = newTestNodeLoads()
+	loads[0].ratio, loads[2].ratio = 0.55, 0.5
+	if src, _, _, _ = pickRebalancePair(loads, 0.1, nil, nil); src != nil {
+		t.Errorf("below the threshold: src[%v]", src)
+	}
+
+	loads = newTestNodeLoads()
+	loads[0].count, loads[2].count = 11, 10
+	loads[0].ratio, loads[2].ratio = 0.5, 0.5
+	if src, _, _, _ = pickRebalancePair(loads, 0, nil, nil); src != nil {
+		t.Errorf("overshoot by count: src[%v]", src)
+	}
+
+	loads = newTestNodeLoads()
+	loads[1].writable, loads[2].writable = false, false
+	if src, _, _, _ = pickRebalancePair(loads, 0.1, nil, nil); src != nil {
+		t.Errorf("no writable target: src[%v]", src)
+	}
+
+	src, target, _, _ = pickRebalancePair(newTestNodeLoads(), 0.1, map[string]bool{"a": true}, map[string]bool{"c": true})
+	if src != nil {
+		t.Errorf("excluded: src[%v] target[%v]", src, target)
+	}
+	src, target, _, _ = pickRebalancePair(newTestNodeLoads(), 0.1, nil, map[string]bool{"c": true})
+	if src == nil || src.addr != "a" || target.addr != "b" {
+		t.Errorf("excluded target: src[%v] target[%v]", src, target)
+	}
+}
+
+func TestMaxRebalanceMove(t *testing.T) {
+	src := &nodeLoad{ratio: 0.8, total: 100 * util.GB}
+	target := &nodeLoad{ratio: 0.2, total: 200 * util.GB}
+	maxMove := maxRebalanceMove(src, target)
+	srcRatio := (0.8*100*util.GB - float64(maxMove)) / (100 * util.GB)
+	targetRatio := (0.2*200*util.GB + float64(maxMove)) / (200 * util.GB)
+	if maxMove == 0 || targetRatio-srcRatio > 0.001 {
+		t.Errorf("maxMove[%v] srcRatio[%v] targetRatio[%v]", maxMove, srcRatio, targetRatio)
+	}
+	if maxMove = maxRebalanceMove(target, src); maxMove != 0 {
+		t.Errorf("less used source: maxMove[%v]", maxMove)
+	}
+	if maxMove = maxRebalanceMove(&nodeLoad{ratio: 0.8}, target); maxMove != 0 {
+		t.Errorf("unknown total: maxMove[%v]", maxMove)
+	}
+}
+
+func TestRebalancerUpdate(t *testing.T) {
+	rb := newRebalancer()
+	rb.update(true, 0, 0)
+	paused, threshold, maxConcurrency := rb.settings()
+	if !paused || threshold != defaultRebalanceThreshold || maxConcurrency != defaultRebalanceMaxConcurrency {
+		t.Errorf("paused[%v] threshold[%v] maxConcurrency[%v]", paused, threshold, maxConcurrency)
+	}
+	rb.update(false, 0.2, 5)
+	if paused, threshold, maxConcurrency = rb.settings(); paused || threshold != 0.2 || maxConcurrency != 5 {
+		t.Errorf("paused[%v] threshold[%v] maxConcurrency[%v]", paused, threshold, maxConcurrency)
+	}
+}
+
+func TestRebalancerFinish(t *testing.T) {
+	rb := newRebalancer()
+	for i := 0; i < maxRebalanceHistory+10; i++ {
+		m := &proto.RebalanceMigration{Kind: proto.RebalanceDataPartition, PartitionID: uint64(i), Src: "a", Target: "b"}
+		rb.start(m)
+		if !rb.isRunning(m.Kind, m.PartitionID) || !rb.busyNodes()["b"] {
+			t.Fatalf("migration[%v] is not running", i)
+		}
+		rb.finish(m, proto.RebalanceSucceeded, "")
+	}
+	if rb.runningCount() != 0 || len(rb.busyNodes()) != 0 {
+		t.Errorf("running[%v]", rb.runningMigrations())
+	}
+	history := rb.historyMigrations()
+	if len(history) != maxRebalanceHistory || history[0].PartitionID != 10 {
+		t.Errorf("history[%v] first[%v]", len(history), history[0].PartitionID)
+	}
+	sources, targets := rb.recentNodes(time.Now().Unix())
+	if !sources["a"] || !targets["b"] || sources["b"] || targets["a"] {
+		t.Errorf("sources[%v] targets[%v]", sources, targets)
+	}
+	if sources, targets = rb.recentNodes(time.Now().Unix() + rebalanceCooldown); len(sources) != 0 || len(targets) != 0 {
+		t.Errorf("after the cooldown: sources[%v] targets[%v]", sources, targets)
+	}
+}
+
+func TestLoadSpread(t *testing.T) {
+	loads := newTestNodeLoads()
+	loads[1].count = 4
+	ratioSpread, countSpread := loadSpread(loads)
+	if ratioSpread < 0.79 || ratioSpread > 0.81 || countSpread != 6 {
+		t.Errorf("ratioSpread[%v] countSpread[%v]", ratioSpread, countSpread)
+	}
+	if ratioSpread, countSpread = loadSpread(loads[:1]); ratioSpread != 0 || countSpread != 0 {
+		t.Errorf("single node: ratioSpread[%v] countSpread[%v]", ratioSpread, countSpread)
+	}
+}
+
+func TestMigrationResumable(t *testing.T) {
+	m := &partitionMigration{Kind: proto.RebalanceDataPartition, PartitionID: 1, Src: "a", Target: "d"}
+	if !migrationResumable([]string{"a", "b", "c", "d"}, m) {
+		t.Errorf("target added: not resumable")
+	}
+	if migrationResumable([]string{"a", "b", "c"}, m) {
+		t.Errorf("target not added: resumable")
+	}
+	if migrationResumable([]string{"b", "c", "d"}, m) {
+		t.Errorf("source removed: resumable")
+	}
+}
@@ -82,6 +82,8 @@ type Server struct {
 	reverseProxy *httputil.ReverseProxy
 	metaReady    bool
 	apiServer    *http.Server
+	audit        *auditLog
+	nonces       *nonceCache
 }
 
 // NewServer creates a new server
@@ -116,7 +118,10 @@ func (m *Server) Start(cfg *config.Config) (err error) {
 	if m.cluster.MasterSecretKey, err = cryptoutil.Base64Decode(MasterSecretKey); err != nil {
 		return fmt.Errorf("action[Start] failed %v, err: master service Key invalid = %s", proto.ErrInvalidCfg, MasterSecretKey)
 	}
+	m.audit = newAuditLog()
+	m.cluster.audit = m.audit
 	m.cluster.scheduleTask()
+	m.nonces = newNonceCache()
 	m.startHTTPService(ModuleName, cfg)
 	exporter.RegistConsul(m.clusterName, ModuleName, cfg)
 	metricsService := newMonitorMetrics(m.cluster)
@@ -226,6 +231,21 @@ func (m *Server) checkConfig(cfg *config.Config) (err error) {
 			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
 		}
 	}
+	m.config.adminAuth = cfg.GetBool(cfgAdminAuth)
+	m.config.parseTrustedProxies(cfg.GetString(cfgTrustedProxies))
+	if historyDays := cfg.GetString(cfgCapacityHistoryDays); historyDays != "" {
+		if m.config.capacityHistoryDays, err = strconv.ParseInt(historyDays, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
+	if m.config.capacityHistoryDays <= 0 {
+		m.config.capacityHistoryDays = defaultCapacityHistoryDays
+	}
+	if warnDays := cfg.GetString(cfgCapacityWarnDays); warnDays != "" {
+		if m.config.capacityWarnDays, err = strconv.ParseInt(warnDays, 10, 64); err != nil {
+			return fmt.Errorf("%v,err:%v", proto.ErrInvalidCfg, err.Error())
+		}
+	}
 	m.tickInterval = int(cfg.GetFloat(cfgTickInterval))
 	m.electionTick = int(cfg.GetFloat(cfgElectionTick))
 	if m.tickInterval <= 300 {
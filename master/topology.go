@@ -397,8 +397,8 @@ func (ns *nodeSet) dataNodeCount() int {
 	return count
 }
 
-func (ns *nodeSet) getAvailDataNodeHosts(excludeHosts []string, replicaNum int) (hosts []string, peers []proto.Peer, err error) {
-	return getAvailHosts(ns.dataNodes, excludeHosts, replicaNum, selectDataNode)
+func (ns *nodeSet) getAvailDataNodeHosts(excludeHosts []string, replicaNum int, pl *placement) (hosts []string, peers []proto.Peer, err error) {
+	return getAvailHosts(ns.dataNodes, excludeHosts, replicaNum, selectDataNode, pl)
 }
 
 // Zone stores all the zone related information
@@ -662,27 +662,42 @@ func (zone *Zone) getDataNodeMaxTotal() (maxTotal uint64) {
 	return
 }
 
-func (zone *Zone) getAvailDataNodeHosts(excludeNodeSets []uint64, excludeHosts []string, replicaNum int) (newHosts []string, peers []proto.Peer, err error) {
+// getAvailDataNodeHosts selects the hosts from a node set of the zone. The node sets are tried one by one if the
+// placement is restricted, as the nodes of a node set may not spread across enough racks or hosts.
+func (zone *Zone) getAvailDataNodeHosts(excludeNodeSets []uint64, excludeHosts []string, replicaNum int, pl *placement) (newHosts []string, peers []proto.Peer, err error) {
 	if replicaNum == 0 {
 		return
 	}
-	ns, err := zone.allocNodeSetForDataNode(excludeNodeSets, uint8(replicaNum))
-	if err != nil {
-		return nil, nil, errors.Trace(err, "zone[%v] alloc node set,replicaNum[%v]", zone.name, replicaNum)
+	excludeNodeSets = append([]uint64{}, excludeNodeSets...)
+	for {
+		ns, err := zone.allocNodeSetForDataNode(excludeNodeSets, uint8(replicaNum))
+		if err != nil {
+			return nil, nil, errors.Trace(err, "zone[%v] alloc node set,replicaNum[%v]", zone.name, replicaNum)
+		}
+		if newHosts, peers, err = ns.getAvailDataNodeHosts(excludeHosts, replicaNum, pl); err == nil || !pl.restricted() {
+			return newHosts, peers, err
+		}
+		excludeNodeSets = append(excludeNodeSets, ns.ID)
 	}
-	return ns.getAvailDataNodeHosts(excludeHosts, replicaNum)
 }
 
-func (zone *Zone) getAvailMetaNodeHosts(excludeNodeSets []uint64, excludeHosts []string, replicaNum int) (newHosts []string, peers []proto.Peer, err error) {
+// getAvailMetaNodeHosts selects the hosts from a node set of the zone. The node sets are tried one by one if the
+// placement is restricted, as the nodes of a node set may not spread across enough racks or hosts.
+func (zone *Zone) getAvailMetaNodeHosts(excludeNodeSets []uint64, excludeHosts []string, replicaNum int, pl *placement) (newHosts []string, peers []proto.Peer, err error) {
 	if replicaNum == 0 {
 		return
 	}
-	ns, err := zone.allocNodeSetForMetaNode(excludeNodeSets, uint8(replicaNum))
-	if err != nil {
-		return nil, nil, errors.NewErrorf("zone[%v],err[%v]", zone.name, err)
+	excludeNodeSets = append([]uint64{}, excludeNodeSets...)
+	for {
+		ns, err := zone.allocNodeSetForMetaNode(excludeNodeSets, uint8(replicaNum))
+		if err != nil {
+			return nil, nil, errors.NewErrorf("zone[%v],err[%v]", zone.name, err)
+		}
+		if newHosts, peers, err = ns.getAvailMetaNodeHosts(excludeHosts, replicaNum, pl); err == nil || !pl.restricted() {
+			return newHosts, peers, err
+		}
+		excludeNodeSets = append(excludeNodeSets, ns.ID)
 	}
-	return ns.getAvailMetaNodeHosts(excludeHosts, replicaNum)
-
 }
 
 func (zone *Zone) dataNodeCount() (len int) {
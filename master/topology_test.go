@@ -2,6 +2,7 @@ package master
 
 import (
 	"fmt"
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util"
 	"testing"
 	"time"
@@ -57,7 +58,7 @@ func TestSingleZone(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	newHosts, _, err := zones[0].getAvailDataNodeHosts(nil, nil, replicaNum)
+	newHosts, _, err := zones[0].getAvailDataNodeHosts(nil, nil, replicaNum, nil)
 	if err != nil {
 		t.Error(err)
 		return
@@ -111,13 +112,13 @@ func TestAllocZones(t *testing.T) {
 	cluster.t = topo
 	cluster.cfg = newClusterConfig()
 	//don't cross zone
-	hosts, _, err := cluster.chooseTargetDataNodes("", nil, nil, replicaNum, 1, "")
+	hosts, _, err := cluster.chooseTargetDataNodes("", nil, nil, replicaNum, 1, "", nil)
 	if err != nil {
 		t.Error(err)
 		return
 	}
 	//cross zone
-	hosts, _, err = cluster.chooseTargetDataNodes("", nil, nil, replicaNum, 2, "")
+	hosts, _, err = cluster.chooseTargetDataNodes("", nil, nil, replicaNum, 2, "", nil)
 	if err != nil {
 		t.Error(err)
 		return
@@ -137,3 +138,40 @@ func TestAllocZones(t *testing.T) {
 		}
 	}
 }
+
+func TestPlacementSpreadAcrossRacks(t *testing.T) {
+	zoneName := "placement"
+	zone := newZone(zoneName)
+	nodeSet := newNodeSet(1, 6, zoneName)
+	zone.putNodeSet(nodeSet)
+	racks := map[string]string{mds1Addr: "r1", mds2Addr: "r1", mds3Addr: "r1", mds4Addr: "r2", mds5Addr: "r3"}
+	for addr, rack := range racks {
+		dn := createDataNodeForTopo(addr, zoneName, nodeSet)
+		dn.Labels = map[string]string{proto.NodeLabelRack: rack}
+		nodeSet.putDataNode(dn)
+	}
+	hosts, _, err := nodeSet.getAvailDataNodeHosts(nil, 3, newPlacement(proto.NodeLabelRack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spread := make(map[string]bool)
+	for _, host := range hosts {
+		spread[racks[host]] = true
+	}
+	if len(spread) != 3 {
+		t.Errorf("replicas %v are not spread across the racks", hosts)
+	}
+	if _, _, err = nodeSet.getAvailDataNodeHosts(nil, 4, newPlacement(proto.NodeLabelRack)); err == nil {
+		t.Errorf("4 replicas should not be spread across 3 racks")
+	}
+	pl := newPlacement(proto.NodeLabelRack)
+	pl.used["r1"] = true
+	if hosts, _, err = nodeSet.getAvailDataNodeHosts(nil, 2, pl); err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range hosts {
+		if racks[host] == "r1" {
+			t.Errorf("replica %v is placed on the rack taken", host)
+		}
+	}
+}
@@ -225,6 +225,31 @@ func (u *User) updateKey(param *proto.UserUpdateParam) (userInfo *proto.UserInfo
 	return
 }
 
+func (u *User) updateQuota(param *proto.UserQuotaUpdateParam) (userInfo *proto.UserInfo, err error) {
+	if userInfo, err = u.getUserInfo(param.UserID); err != nil {
+		return
+	}
+	userInfo.Mu.Lock()
+	defer userInfo.Mu.Unlock()
+	if userInfo.UserType == proto.UserTypeRoot {
+		err = proto.ErrNoPermission
+		return
+	}
+	formerQuota := userInfo.Quota
+	quota := param.Quota
+	userInfo.Quota = &quota
+	if quota.IsUnlimited() {
+		userInfo.Quota = nil
+	}
+	if err = u.syncUpdateUserInfo(userInfo); err != nil {
+		userInfo.Quota = formerQuota
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+	log.LogInfof("action[updateQuota], userID: %v, quota: %v", param.UserID, quota)
+	return
+}
+
 func (u *User) getKeyInfo(ak string) (userInfo *proto.UserInfo, err error) {
 	var akUser *proto.AKUser
 	if akUser, err = u.getAKUser(ak); err != nil {
@@ -0,0 +1,75 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// userInfoWithUsage reports the usage of the user along with the user info, without storing it in the user info.
+type userInfoWithUsage struct {
+	*proto.UserInfo
+	Usage *proto.UserUsage `json:"usage,omitempty"`
+}
+
+// getUserUsage sums up the volumes owned by the user, the volumes deleted are not counted.
+func (c *Cluster) getUserUsage(userID string) (usage *proto.UserUsage) {
+	usage = new(proto.UserUsage)
+	for _, vol := range c.allVols() {
+		if vol.Owner != userID || vol.Status == markDelete {
+			continue
+		}
+		usage.VolCount++
+		usage.Capacity += vol.Capacity
+		usage.UsedSize += vol.totalUsedSpace()
+		for _, mp := range vol.cloneMetaPartitionMap() {
+			usage.ObjectCount += mp.InodeCount
+		}
+	}
+	return
+}
+
+// lockUserQuota serializes the check of the quota of the user with the change of the volumes checked, so that the
+// concurrent requests of the user don't exceed the quota together. It returns the function to unlock.
+func (c *Cluster) lockUserQuota(userID string) (unlock func()) {
+	mu, _ := c.userQuotaLocks.LoadOrStore(userID, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// checkUserQuota checks if the volumes of the user with the capacity and the volumes added are within the quota.
+// The users not created yet have no quota. The caller holds the lock of the user quota until the volumes are changed.
+func (c *Cluster) checkUserQuota(u *User, userID string, capacity, volCount uint64) (err error) {
+	userInfo, err := u.getUserInfo(userID)
+	if err == proto.ErrUserNotExists {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	userInfo.Mu.RLock()
+	quota := userInfo.Quota
+	userInfo.Mu.RUnlock()
+	if quota.IsUnlimited() {
+		return
+	}
+	if err = c.getUserUsage(userID).Exceeds(quota, capacity, volCount); err != nil {
+		log.LogWarnf("action[checkUserQuota] user[%v] capacity[%v] volCount[%v] err[%v]", userID, capacity, volCount, err)
+	}
+	return
+}
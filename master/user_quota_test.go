@@ -0,0 +1,45 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockUserQuota(t *testing.T) {
+	c := &Cluster{Name: "test"}
+	unlock := c.lockUserQuota("user1")
+
+	// the other users are not blocked
+	c.lockUserQuota("user2")()
+
+	locked := make(chan struct{})
+	go func() {
+		c.lockUserQuota("user1")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatalf("the quota of the user is locked twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatalf("the quota of the user is not unlocked")
+	}
+}
@@ -35,6 +35,14 @@ type VolVarargs struct {
 	enableToken    bool
 	dpSelectorName string
 	dpSelectorParm string
+	ecDataNum      uint8
+	ecParityNum    uint8
+	tierPolicy     proto.TierPolicy
+	qos            proto.VolQos
+	compression    string
+	checksum       string
+	quorumWrite    bool
+	placement      string
 }
 
 // Vol represents a set of meta partitionMap and data partitionMap
@@ -69,6 +77,17 @@ type Vol struct {
 	description        string
 	dpSelectorName     string
 	dpSelectorParm     string
+	ecDataNum          uint8 // number of data shards of the erasure coded cold extents, 0 if disabled
+	ecParityNum        uint8
+	tierPolicy         proto.TierPolicy // policy of migrating the cold extents to the external store
+	qos                proto.VolQos     // I/O limits enforced by each data node
+	compression        string           // compression mode of the sealed normal extents
+	checksum           string           // checksum type of the data partitions created afterwards
+	quorumWrite        bool             // appends are acknowledged once a majority of the replicas persist them
+	placementPolicy    string           // label of the nodes the replicas are spread across, empty if not restricted
+	aliases            []string         // the old names before renamed, still referred to by the partitions
+	cloneSource        string           // name of the volume cloned from, on the clone
+	sharedPartitionIDs []uint64         // data partitions of the source volumes the cloned metadata refers to
 	sync.RWMutex
 }
 
@@ -127,6 +146,17 @@ func newVolFromVolValue(vv *volValue) (vol *Vol) {
 	vol.Status = vv.Status
 	vol.dpSelectorName = vv.DpSelectorName
 	vol.dpSelectorParm = vv.DpSelectorParm
+	vol.ecDataNum = vv.EcDataNum
+	vol.ecParityNum = vv.EcParityNum
+	vol.tierPolicy = vv.TierPolicy
+	vol.qos = vv.Qos
+	vol.compression = vv.Compression
+	vol.checksum = vv.Checksum
+	vol.quorumWrite = vv.QuorumWrite
+	vol.placementPolicy = vv.PlacementPolicy
+	vol.aliases = vv.Aliases
+	vol.cloneSource = vv.CloneSource
+	vol.sharedPartitionIDs = vv.SharedPartitions
 	return vol
 }
 
@@ -460,6 +490,7 @@ func (vol *Vol) updateViewCache(c *Cluster) {
 	view := proto.NewVolView(vol.Name, vol.Status, vol.FollowerRead, vol.createTime)
 	view.SetOwner(vol.Owner)
 	view.SetOSSSecure(vol.OSSAccessKey, vol.OSSSecretKey)
+	view.TierSecure = vol.tierSecure()
 	mpViews := vol.getMetaPartitionsView()
 	view.MetaPartitions = mpViews
 	mpViewsReply := newSuccessHTTPReply(mpViews)
@@ -755,7 +786,7 @@ func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64) (mp *MetaPa
 		wg          sync.WaitGroup
 	)
 	errChannel := make(chan error, vol.mpReplicaNum)
-	if hosts, peers, err = c.chooseTargetMetaHosts("", nil, nil, int(vol.mpReplicaNum), vol.crossZone, vol.zoneName); err != nil {
+	if hosts, peers, err = c.chooseTargetMetaHosts("", nil, nil, int(vol.mpReplicaNum), vol.crossZone, vol.zoneName, newPlacement(vol.placementPolicy)); err != nil {
 		log.LogErrorf("action[doCreateMetaPartition] chooseTargetMetaHosts err[%v]", err)
 		return nil, errors.NewError(err)
 	}
@@ -811,6 +842,26 @@ func (vol *Vol) doCreateMetaPartition(c *Cluster, start, end uint64) (mp *MetaPa
 	return
 }
 
+// tierPolicyView returns the tiering policy for the clients, which still need the store to read
+// the migrated extents after the tiering is disabled, nil if the store has never been configured.
+// The keys of the store are only sent with the view of the vol, see tierSecure.
+func (vol *Vol) tierPolicyView() *proto.TierPolicy {
+	if vol.tierPolicy.Endpoint == "" {
+		return nil
+	}
+	policy := vol.tierPolicy
+	policy.AccessKey, policy.SecretKey = "", ""
+	return &policy
+}
+
+// tierSecure returns the keys of the tier store, nil if the store has never been configured.
+func (vol *Vol) tierSecure() *proto.OSSSecure {
+	if vol.tierPolicy.Endpoint == "" {
+		return nil
+	}
+	return &proto.OSSSecure{AccessKey: vol.tierPolicy.AccessKey, SecretKey: vol.tierPolicy.SecretKey}
+}
+
 func getVolVarargs(vol *Vol) *VolVarargs {
 	return &VolVarargs{
 		zoneName:       vol.zoneName,
@@ -822,5 +873,13 @@ func getVolVarargs(vol *Vol) *VolVarargs {
 		enableToken:    vol.enableToken,
 		dpSelectorName: vol.dpSelectorName,
 		dpSelectorParm: vol.dpSelectorParm,
+		ecDataNum:      vol.ecDataNum,
+		ecParityNum:    vol.ecParityNum,
+		tierPolicy:     vol.tierPolicy,
+		qos:            vol.qos,
+		compression:    vol.compression,
+		checksum:       vol.checksum,
+		quorumWrite:    vol.quorumWrite,
+		placement:      vol.placementPolicy,
 	}
 }
@@ -0,0 +1,512 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// seconds to wait for a meta partition to store the snapshot of the clone on all the replicas
+	cloneMetaPartitionDeadline = 600
+	// seconds between the checks of the clone on the replicas of a meta partition
+	cloneMetaPartitionCheckInterval = 5
+)
+
+// cloneVol creates the volume sharing the data of the source volume, with a copy of the current metadata of it.
+// 1. The extents of the source, and of the volumes it shares, are frozen on the data nodes, so that none is
+// overwritten or deleted.
+// 2. Each meta partition of the source is cloned into a new meta partition on the same meta nodes by the raft.
+// 3. The extents up to the max extent id at the time of the clone, and the data of the tiny extents up to their
+// sizes, are shared, and the newer ones are unfrozen.
+// The normal extents shared are read only to both of the volumes, and the writes on them are redirected to new
+// extents by the clients. Each shared extent is deleted once all the volumes referring to it have deleted it.
+func (c *Cluster) cloneVol(srcName, name, owner string) (vol *Vol, err error) {
+	var (
+		src       *Vol
+		id        uint64
+		dps       []*DataPartition
+		upstream  []*DataPartition
+		frozen    []*DataPartition
+		mps       []*MetaPartition
+		responses map[uint64]*proto.ShareDataPartitionResponse
+		shares    map[uint64]*proto.ShareDataPartitionRequest
+	)
+	c.createVolMutex.Lock()
+	defer c.createVolMutex.Unlock()
+	if src, err = c.getVol(srcName); err != nil {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	if src.Status == markDelete {
+		err = proto.ErrVolNotExists
+		goto errHandler
+	}
+	if _, err = c.getVol(name); err == nil {
+		err = proto.ErrDuplicateVol
+		goto errHandler
+	}
+	if src.tierPolicy.Endpoint != "" {
+		err = fmt.Errorf("the volume migrating extents to the external store can't be cloned")
+		goto errHandler
+	}
+	src.createDpMutex.Lock()
+	defer src.createDpMutex.Unlock()
+	src.createMpMutex.Lock()
+	defer src.createMpMutex.Unlock()
+
+	for _, dp := range src.cloneDataPartitionMap() {
+		dps = append(dps, dp)
+	}
+	// the meta partitions of the clone refer to the extents the source shares with the volumes it is cloned from
+	upstream = c.getSharedDataPartitions(src)
+	frozen = append(append(frozen, dps...), upstream...)
+	defer func() {
+		if err != nil {
+			c.restoreSharedDataPartitions(frozen)
+		}
+	}()
+	if _, err = c.shareDataPartitions(frozen, math.MaxUint64); err != nil {
+		goto errHandler
+	}
+	if id, err = c.idAlloc.allocateCommonID(); err != nil {
+		goto errHandler
+	}
+	if mps, err = c.cloneMetaPartitions(src, name, id); err != nil {
+		goto errHandler
+	}
+	defer func() {
+		if err != nil {
+			c.deleteClonedMetaPartitions(mps)
+		}
+	}()
+	if responses, err = c.shareDataPartitions(dps, math.MaxUint64); err != nil {
+		goto errHandler
+	}
+	shares = newClonedShares(src, dps, upstream, mps, responses)
+	for _, dp := range frozen {
+		if _, err = c.shareDataPartition(dp, shares[dp.PartitionID]); err != nil {
+			goto errHandler
+		}
+	}
+
+	vol = newVol(id, name, owner, src.zoneName, src.dataPartitionSize, src.Capacity, src.dpReplicaNum, src.mpReplicaNum,
+		src.FollowerRead, src.authenticate, src.crossZone, src.enableToken, time.Now().Unix(), src.description)
+	vol.dpSelectorName, vol.dpSelectorParm = src.dpSelectorName, src.dpSelectorParm
+	vol.ecDataNum, vol.ecParityNum = src.ecDataNum, src.ecParityNum
+	vol.qos = src.qos
+	vol.compression = src.compression
+	vol.checksum = src.checksum
+	vol.quorumWrite = src.quorumWrite
+	vol.placementPolicy = src.placementPolicy
+	vol.cloneSource = src.Name
+	vol.sharedPartitionIDs = append([]uint64{}, src.sharedPartitionIDs...)
+	for _, dp := range dps {
+		vol.sharedPartitionIDs = append(vol.sharedPartitionIDs, dp.PartitionID)
+	}
+	vol.refreshOSSSecure()
+	if err = c.syncCloneVol(vol, mps, frozen, shares); err != nil {
+		goto errHandler
+	}
+	for _, dp := range frozen {
+		share := shares[dp.PartitionID]
+		dp.Lock()
+		dp.SharedExtentID, dp.SharedRanges, dp.SharedTinySizes = share.SharedExtentID, share.SharedRanges, share.SharedTinySizes
+		dp.Unlock()
+	}
+	c.putVol(vol)
+	for _, mp := range mps {
+		vol.addMetaPartition(mp)
+	}
+	vol.dataPartitions.setSharedPartitions(c.getSharedDataPartitions(vol))
+	if vol.enableToken {
+		if err = c.createToken(vol, proto.ReadOnlyToken); err != nil {
+			goto errHandler
+		}
+		if err = c.createToken(vol, proto.ReadWriteToken); err != nil {
+			goto errHandler
+		}
+	}
+	if err = vol.initDataPartitions(c); err != nil {
+		log.LogWarnf("action[cloneVol] vol[%v] init data partitions err[%v]", name, err)
+		err = nil
+	}
+	vol.updateViewCache(c)
+	src.dataPartitions.updateResponseCache(true, 0)
+	src.updateViewCache(c)
+	log.LogWarnf("action[cloneVol] vol[%v] cloned from vol[%v], mps[%v] dps[%v]", name, srcName, len(mps), len(dps))
+	return
+
+errHandler:
+	err = fmt.Errorf("action[cloneVol], clusterID[%v] source:%v name:%v, err:%v ", c.Name, srcName, name, err)
+	log.LogError(errors.Stack(err))
+	Warn(c.Name, err.Error())
+	return
+}
+
+// newClonedShares returns the extents of the data partitions shared with the clone of the source volume.
+// The extents of the source up to the max extent ids, and the data of its tiny extents up to the sizes, are shared
+// with the meta partitions of the clone. The extents of the upstream volumes shared with the source are shared with
+// the clone up to the same extent ids.
+func newClonedShares(src *Vol, dps, upstream []*DataPartition, mps []*MetaPartition,
+	responses map[uint64]*proto.ShareDataPartitionResponse) (shares map[uint64]*proto.ShareDataPartitionRequest) {
+	mpIDs := make([]uint64, 0, len(mps))
+	for _, mp := range mps {
+		mpIDs = append(mpIDs, mp.PartitionID)
+	}
+	srcMpIDs := make([]uint64, 0)
+	for id := range src.cloneMetaPartitionMap() {
+		srcMpIDs = append(srcMpIDs, id)
+	}
+	shares = make(map[uint64]*proto.ShareDataPartitionRequest, len(dps)+len(upstream))
+	for _, dp := range dps {
+		resp := responses[dp.PartitionID]
+		share := dp.newShareRequest(resp.MaxExtentID)
+		share.SharedRanges = append(append([]*proto.SharedExtentRange{}, share.SharedRanges...),
+			&proto.SharedExtentRange{ExtentID: resp.MaxExtentID, MetaPartitionIDs: mpIDs})
+		tinySizes := make(map[uint64]uint64, len(resp.TinySizes))
+		for extentID, size := range share.SharedTinySizes {
+			tinySizes[extentID] = size
+		}
+		for extentID, size := range resp.TinySizes {
+			if size > tinySizes[extentID] {
+				tinySizes[extentID] = size
+			}
+		}
+		share.SharedTinySizes = tinySizes
+		shares[dp.PartitionID] = share
+	}
+	for _, dp := range upstream {
+		dp.RLock()
+		sharedExtentID := dp.SharedExtentID
+		dp.RUnlock()
+		share := dp.newShareRequest(sharedExtentID)
+		ranges := append([]*proto.SharedExtentRange{}, share.SharedRanges...)
+		for _, r := range share.SharedRanges {
+			if containsAnyID(r.MetaPartitionIDs, srcMpIDs) {
+				ranges = append(ranges, &proto.SharedExtentRange{ExtentID: r.ExtentID, MetaPartitionIDs: mpIDs})
+			}
+		}
+		share.SharedRanges = ranges
+		shares[dp.PartitionID] = share
+	}
+	return
+}
+
+func containsAnyID(ids, others []uint64) bool {
+	for _, id := range ids {
+		for _, other := range others {
+			if id == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shareDataPartitions shares the extents of the data partitions up to the extent id, with the ranges and the tiny
+// extents shared currently, and returns the max extent id and the sizes of the tiny extents of each partition.
+func (c *Cluster) shareDataPartitions(dps []*DataPartition, sharedExtentID uint64) (responses map[uint64]*proto.ShareDataPartitionResponse, err error) {
+	responses = make(map[uint64]*proto.ShareDataPartitionResponse, len(dps))
+	for _, dp := range dps {
+		if responses[dp.PartitionID], err = c.shareDataPartition(dp, dp.newShareRequest(sharedExtentID)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// shareDataPartition shares the extents of the data partition on all the replicas, and returns the max extent id
+// and the max size of each tiny extent among the replicas.
+func (c *Cluster) shareDataPartition(dp *DataPartition, req *proto.ShareDataPartitionRequest) (result *proto.ShareDataPartitionResponse, err error) {
+	dp.RLock()
+	hosts := append([]string{}, dp.Hosts...)
+	dp.RUnlock()
+	result = &proto.ShareDataPartitionResponse{PartitionId: dp.PartitionID, TinySizes: make(map[uint64]uint64)}
+	for _, host := range hosts {
+		var (
+			dataNode *DataNode
+			packet   *proto.Packet
+		)
+		if dataNode, err = c.dataNode(host); err != nil {
+			return
+		}
+		task := dp.createTaskToShareDataPartition(host, req)
+		if packet, err = dataNode.TaskManager.syncSendAdminTask(task); err != nil {
+			return nil, fmt.Errorf("share dp[%v] on host[%v] err[%v]", dp.PartitionID, host, err)
+		}
+		resp := &proto.ShareDataPartitionResponse{}
+		if err = json.Unmarshal(packet.Data, resp); err != nil {
+			return
+		}
+		if resp.MaxExtentID > result.MaxExtentID {
+			result.MaxExtentID = resp.MaxExtentID
+		}
+		for extentID, size := range resp.TinySizes {
+			if size > result.TinySizes[extentID] {
+				result.TinySizes[extentID] = size
+			}
+		}
+	}
+	return
+}
+
+// restoreSharedDataPartitions unfreezes the extents of the data partitions if the clone fails.
+func (c *Cluster) restoreSharedDataPartitions(dps []*DataPartition) {
+	for _, dp := range dps {
+		dp.RLock()
+		sharedExtentID := dp.SharedExtentID
+		dp.RUnlock()
+		if _, err := c.shareDataPartition(dp, dp.newShareRequest(sharedExtentID)); err != nil {
+			Warn(c.Name, fmt.Sprintf("action[restoreSharedDataPartitions] dp[%v] err[%v]", dp.PartitionID, err))
+		}
+	}
+}
+
+// cloneMetaPartitions clones the meta partitions of the source volume in the order of the inode ranges.
+func (c *Cluster) cloneMetaPartitions(src *Vol, name string, volID uint64) (mps []*MetaPartition, err error) {
+	srcMps := make([]*MetaPartition, 0)
+	for _, mp := range src.cloneMetaPartitionMap() {
+		srcMps = append(srcMps, mp)
+	}
+	sort.Slice(srcMps, func(i, j int) bool { return srcMps[i].Start < srcMps[j].Start })
+	mps = make([]*MetaPartition, 0, len(srcMps))
+	for _, srcMp := range srcMps {
+		var (
+			partitionID uint64
+			leader      *MetaReplica
+		)
+		if partitionID, err = c.idAlloc.allocateMetaPartitionID(); err != nil {
+			return
+		}
+		srcMp.RLock()
+		hosts := append([]string{}, srcMp.Hosts...)
+		peers := append([]proto.Peer{}, srcMp.Peers...)
+		leader, err = srcMp.getMetaReplicaLeader()
+		srcMp.RUnlock()
+		if err != nil {
+			return mps, fmt.Errorf("mp[%v] err[%v]", srcMp.PartitionID, err)
+		}
+		req := &proto.CreateMetaPartitionRequest{
+			Start:       srcMp.Start,
+			End:         srcMp.End,
+			PartitionID: partitionID,
+			Members:     peers,
+			VolName:     name,
+		}
+		task := leader.createTaskToCloneMetaPartition(srcMp.PartitionID, req)
+		mp := newMetaPartition(partitionID, srcMp.Start, srcMp.End, src.mpReplicaNum, name, volID)
+		mp.setHosts(hosts)
+		mp.setPeers(peers)
+		mps = append(mps, mp)
+		deadline := time.Now().Add(cloneMetaPartitionDeadline * time.Second)
+		if _, err = leader.metaNode.Sender.syncSendAdminTaskWithDeadline(task, cloneMetaPartitionDeadline); err != nil {
+			return mps, fmt.Errorf("clone mp[%v] err[%v]", srcMp.PartitionID, err)
+		}
+		for _, host := range hosts {
+			if err = mp.afterCreation(host, c); err != nil {
+				return
+			}
+		}
+		if err = c.checkClonedMetaPartition(mp, leader.Addr, deadline); err != nil {
+			return mps, fmt.Errorf("clone mp[%v] err[%v]", srcMp.PartitionID, err)
+		}
+		mp.Status = proto.ReadWrite
+	}
+	return
+}
+
+// checkClonedMetaPartition waits for the clone to be stored on all the replicas, with the same inodes and dentries
+// as on the leader. A replica catching up with the source by a snapshot of the raft doesn't apply the clone, and
+// misses the meta partition of the clone.
+func (c *Cluster) checkClonedMetaPartition(mp *MetaPartition, leaderAddr string, deadline time.Time) (err error) {
+	for {
+		if err = c.compareClonedMetaPartition(mp, leaderAddr); err == nil || time.Now().After(deadline) {
+			return
+		}
+		log.LogWarnf("action[checkClonedMetaPartition] mp[%v] err[%v]", mp.PartitionID, err)
+		time.Sleep(cloneMetaPartitionCheckInterval * time.Second)
+	}
+}
+
+func (c *Cluster) compareClonedMetaPartition(mp *MetaPartition, leaderAddr string) (err error) {
+	responses := make(map[string]*proto.MetaPartitionLoadResponse, len(mp.Hosts))
+	for _, host := range mp.Hosts {
+		var (
+			mr     *MetaReplica
+			packet *proto.Packet
+		)
+		if mr, err = mp.getMetaReplica(host); err != nil {
+			return
+		}
+		task := mr.createTaskToLoadMetaPartition(mp.PartitionID)
+		if packet, err = mr.metaNode.Sender.syncSendAdminTask(task); err != nil {
+			return fmt.Errorf("load on host[%v] err[%v]", host, err)
+		}
+		resp := &proto.MetaPartitionLoadResponse{}
+		if err = json.Unmarshal(packet.Data, resp); err != nil {
+			return
+		}
+		responses[host] = resp
+	}
+	leader, ok := responses[leaderAddr]
+	if !ok {
+		return fmt.Errorf("leader[%v] is not a replica", leaderAddr)
+	}
+	for host, resp := range responses {
+		if resp.InodeCount != leader.InodeCount || resp.DentryCount != leader.DentryCount {
+			return fmt.Errorf("host[%v] inodes[%v] dentries[%v] mismatch the leader[%v] inodes[%v] dentries[%v]",
+				host, resp.InodeCount, resp.DentryCount, leaderAddr, leader.InodeCount, leader.DentryCount)
+		}
+	}
+	return
+}
+
+// deleteClonedMetaPartitions deletes the replicas of the meta partitions cloned if the clone fails.
+func (c *Cluster) deleteClonedMetaPartitions(mps []*MetaPartition) {
+	tasks := make([]*proto.AdminTask, 0)
+	for _, mp := range mps {
+		for _, host := range mp.Hosts {
+			metaNode, err := c.metaNode(host)
+			if err != nil {
+				continue
+			}
+			mr := newMetaReplica(mp.Start, mp.End, metaNode)
+			tasks = append(tasks, mr.createTaskToDeleteReplica(mp.PartitionID))
+		}
+	}
+	c.addMetaNodeTasks(tasks)
+}
+
+// syncCloneVol persists the cloned volume, the meta partitions of it and the shared extents in a raft command.
+func (c *Cluster) syncCloneVol(vol *Vol, mps []*MetaPartition, dps []*DataPartition, shares map[uint64]*proto.ShareDataPartitionRequest) (err error) {
+	cmds := make(map[string]*RaftCmd)
+	volCmd := &RaftCmd{Op: opSyncAddVol, K: volPrefix + strconv.FormatUint(vol.ID, 10)}
+	if volCmd.V, err = json.Marshal(newVolValue(vol)); err != nil {
+		return
+	}
+	cmds[volCmd.K] = volCmd
+	for _, mp := range mps {
+		var mpCmd *RaftCmd
+		if mpCmd, err = c.buildMetaPartitionRaftCmd(opSyncAddMetaPartition, mp); err != nil {
+			return
+		}
+		cmds[mpCmd.K] = mpCmd
+	}
+	for _, dp := range dps {
+		dp.RLock()
+		dpv := newDataPartitionValue(dp)
+		dp.RUnlock()
+		share := shares[dp.PartitionID]
+		dpv.SharedExtentID, dpv.SharedRanges, dpv.SharedTinySizes = share.SharedExtentID, share.SharedRanges, share.SharedTinySizes
+		dpCmd := &RaftCmd{Op: opSyncUpdateDataPartition,
+			K: dataPartitionPrefix + strconv.FormatUint(dp.VolID, 10) + keySeparator + strconv.FormatUint(dp.PartitionID, 10)}
+		if dpCmd.V, err = json.Marshal(dpv); err != nil {
+			return
+		}
+		cmds[dpCmd.K] = dpCmd
+	}
+	if err = c.syncBatchCommitCmd(cmds); err != nil {
+		err = proto.ErrPersistenceByRaft
+	}
+	return
+}
+
+// releaseSharedDataPartitions removes the ranges of the cloned volume being deleted from the data partitions it
+// shares, so that the shared extents deleted by the other volumes are released. The extents of a partition are not
+// shared any more once no cloned volume refers to them.
+func (c *Cluster) releaseSharedDataPartitions(vol *Vol) {
+	mpIDs := make([]uint64, 0)
+	for id := range vol.cloneMetaPartitionMap() {
+		mpIDs = append(mpIDs, id)
+	}
+	for _, dp := range c.getSharedDataPartitions(vol) {
+		dp.RLock()
+		sharedExtentID := dp.SharedExtentID
+		dp.RUnlock()
+		share := dp.newShareRequest(sharedExtentID)
+		ranges := make([]*proto.SharedExtentRange, 0, len(share.SharedRanges))
+		for _, r := range share.SharedRanges {
+			if !containsAnyID(r.MetaPartitionIDs, mpIDs) {
+				ranges = append(ranges, r)
+			}
+		}
+		if len(ranges) == len(share.SharedRanges) {
+			continue
+		}
+		share.SharedRanges = ranges
+		if len(ranges) == 0 {
+			share.SharedExtentID, share.SharedTinySizes = 0, nil
+		}
+		dp.Lock()
+		dp.SharedExtentID, dp.SharedRanges, dp.SharedTinySizes = share.SharedExtentID, share.SharedRanges, share.SharedTinySizes
+		err := c.syncUpdateDataPartition(dp)
+		dp.Unlock()
+		if err == nil {
+			_, err = c.shareDataPartition(dp, share)
+		}
+		if err != nil {
+			Warn(c.Name, fmt.Sprintf("action[releaseSharedDataPartitions] vol[%v] dp[%v] err[%v]", vol.Name, dp.PartitionID, err))
+		}
+	}
+}
+
+// getSharedDataPartitions resolves the data partitions of the source volumes shared by the cloned volume.
+func (c *Cluster) getSharedDataPartitions(vol *Vol) (dps []*DataPartition) {
+	dps = make([]*DataPartition, 0, len(vol.sharedPartitionIDs))
+	for _, partitionID := range vol.sharedPartitionIDs {
+		dp, err := c.getDataPartitionByID(partitionID)
+		if err != nil {
+			log.LogWarnf("action[getSharedDataPartitions] vol[%v] dp[%v] err[%v]", vol.Name, partitionID, err)
+			continue
+		}
+		dps = append(dps, dp)
+	}
+	return
+}
+
+func (c *Cluster) loadSharedDataPartitions() {
+	for _, vol := range c.allVols() {
+		if len(vol.sharedPartitionIDs) == 0 {
+			continue
+		}
+		vol.dataPartitions.setSharedPartitions(c.getSharedDataPartitions(vol))
+	}
+}
+
+// isVolShared returns the cloned volume sharing the data partitions of the volume, if any.
+func (c *Cluster) isVolShared(vol *Vol) (clone string, shared bool) {
+	dps := vol.cloneDataPartitionMap()
+	for _, v := range c.allVols() {
+		if v == vol {
+			continue
+		}
+		for _, partitionID := range v.sharedPartitionIDs {
+			if _, ok := dps[partitionID]; ok {
+				return v.Name, true
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestNewClonedShares(t *testing.T) {
+	// the source is cloned from an upstream volume by mp 11, and cloned before by mp 21
+	src := &Vol{Name: "src", MetaPartitions: map[uint64]*MetaPartition{11: {PartitionID: 11}, 12: {PartitionID: 12}}}
+	dp := newDataPartition(1, 3, "src", 1)
+	dp.SharedExtentID = 1500
+	dp.SharedRanges = []*proto.SharedExtentRange{{ExtentID: 1500, MetaPartitionIDs: []uint64{21}}}
+	dp.SharedTinySizes = map[uint64]uint64{1: 8192, 2: 4096}
+	upstreamDp := newDataPartition(2, 3, "upstream", 2)
+	upstreamDp.SharedExtentID = 3000
+	upstreamDp.SharedRanges = []*proto.SharedExtentRange{
+		{ExtentID: 3000, MetaPartitionIDs: []uint64{11, 12}},
+		{ExtentID: 2500, MetaPartitionIDs: []uint64{41}},
+	}
+	mps := []*MetaPartition{{PartitionID: 31}, {PartitionID: 32}}
+	responses := map[uint64]*proto.ShareDataPartitionResponse{
+		1: {PartitionId: 1, MaxExtentID: 2000, TinySizes: map[uint64]uint64{1: 12288, 3: 4096}},
+	}
+
+	shares := newClonedShares(src, []*DataPartition{dp}, []*DataPartition{upstreamDp}, mps, responses)
+
+	share := shares[1]
+	if share.SharedExtentID != 2000 || len(share.SharedRanges) != 2 || share.SharedRanges[1].ExtentID != 2000 ||
+		len(share.SharedRanges[1].MetaPartitionIDs) != 2 {
+		t.Errorf("dp[1] shared extent[%v] ranges[%v]", share.SharedExtentID, share.SharedRanges)
+	}
+	if share.SharedTinySizes[1] != 12288 || share.SharedTinySizes[2] != 4096 || share.SharedTinySizes[3] != 4096 {
+		t.Errorf("dp[1] shared tiny sizes[%v]", share.SharedTinySizes)
+	}
+	// the clone refers to the upstream extents the source refers to
+	share = shares[2]
+	if share.SharedExtentID != 3000 || len(share.SharedRanges) != 3 || share.SharedRanges[2].ExtentID != 3000 {
+		t.Errorf("dp[2] shared extent[%v] ranges[%v]", share.SharedExtentID, share.SharedRanges)
+	}
+	// the ranges persisted are not modified
+	if len(dp.SharedRanges) != 1 || len(upstreamDp.SharedRanges) != 2 || dp.SharedTinySizes[1] != 8192 {
+		t.Errorf("the ranges of the partitions are modified")
+	}
+}
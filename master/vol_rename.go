@@ -0,0 +1,137 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// renameVol renames the volume, and the policies of the users on it, in a raft command. The old name is kept as
+// an alias of the volume, as the partitions on the nodes and the clients not yet refreshed still refer to it, and
+// is not reused until the volume is deleted.
+func (c *Cluster) renameVol(u *User, name, newName, authKey string) (err error) {
+	c.createVolMutex.Lock()
+	defer c.createVolMutex.Unlock()
+	vol, err := c.getVol(name)
+	if err != nil || vol.Name != name {
+		return proto.ErrVolNotExists
+	}
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	if existed, e := c.getVol(newName); e == nil && existed != vol {
+		return proto.ErrDuplicateVol
+	}
+	if newName == name {
+		return
+	}
+	aliases := make([]string, 0, len(vol.aliases)+1)
+	for _, alias := range vol.aliases {
+		if alias != newName {
+			aliases = append(aliases, alias)
+		}
+	}
+	aliases = append(aliases, name)
+
+	vv := newVolValue(vol)
+	vv.Name = newName
+	vv.Aliases = aliases
+	cmds := make(map[string]*RaftCmd)
+	volCmd := &RaftCmd{Op: opSyncUpdateVol, K: volPrefix + strconv.FormatUint(vol.ID, 10)}
+	if volCmd.V, err = json.Marshal(vv); err != nil {
+		return
+	}
+	cmds[volCmd.K] = volCmd
+
+	// the users are locked before the volumes of the users, as adding the own volumes does
+	userInfos := make([]*proto.UserInfo, 0)
+	userIDs, _ := u.getUsersOfVol(name)
+	sort.Strings(userIDs)
+	for _, userID := range userIDs {
+		userInfo, e := u.getUserInfo(userID)
+		if e != nil {
+			continue
+		}
+		userInfo.Mu.Lock()
+		defer userInfo.Mu.Unlock()
+		userInfos = append(userInfos, userInfo)
+	}
+	u.volUserMutex.Lock()
+	defer u.volUserMutex.Unlock()
+	renamed := make([]*proto.UserInfo, 0)
+	defer func() {
+		if err != nil {
+			for _, userInfo := range renamed {
+				userInfo.Policy.RenameVol(newName, name)
+			}
+		}
+	}()
+	for _, userInfo := range userInfos {
+		if !userInfo.Policy.RenameVol(name, newName) {
+			continue
+		}
+		renamed = append(renamed, userInfo)
+		userCmd := &RaftCmd{Op: opSyncUpdateUserInfo, K: userPrefix + userInfo.UserID}
+		if userCmd.V, err = json.Marshal(userInfo); err != nil {
+			return
+		}
+		cmds[userCmd.K] = userCmd
+	}
+	var newVolUser *proto.VolUser
+	if value, ok := u.volUser.Load(name); ok {
+		volUser := value.(*proto.VolUser)
+		volUser.Mu.RLock()
+		newVolUser = &proto.VolUser{Vol: newName, UserIDs: append([]string{}, volUser.UserIDs...)}
+		volUser.Mu.RUnlock()
+		volUserCmd := &RaftCmd{Op: opSyncAddVolUser, K: volUserPrefix + newName}
+		if volUserCmd.V, err = json.Marshal(newVolUser); err != nil {
+			return
+		}
+		cmds[volUserCmd.K] = volUserCmd
+		cmds[volUserPrefix+name] = &RaftCmd{Op: opSyncDeleteVolUser, K: volUserPrefix + name}
+	}
+	if err = c.syncBatchCommitCmd(cmds); err != nil {
+		log.LogErrorf("action[renameVol] vol[%v] newName[%v] err[%v]", name, newName, err)
+		err = proto.ErrPersistenceByRaft
+		return
+	}
+
+	// the vol is locked before the volumes of the cluster, as checking the status of the vol does
+	vol.Lock()
+	c.volMutex.Lock()
+	delete(c.vols, name)
+	delete(c.volAliases, newName)
+	vol.Name = newName
+	vol.aliases = aliases
+	c.vols[newName] = vol
+	c.volAliases[name] = newName
+	c.volMutex.Unlock()
+	vol.Unlock()
+	c.volStatInfo.Delete(name)
+	if newVolUser != nil {
+		u.volUser.Delete(name)
+		u.volUser.Store(newName, newVolUser)
+	}
+	vol.updateViewCache(c)
+	msg := fmt.Sprintf("action[renameVol] vol[%v] renamed to [%v], users[%v]", name, newName, len(renamed))
+	log.LogWarn(msg)
+	return
+}
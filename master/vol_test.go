@@ -64,6 +64,37 @@ func TestVol(t *testing.T) {
 	vol.deleteVolFromStore(server.cluster)
 }
 
+func TestRenameVol(t *testing.T) {
+	name := "renameVol"
+	newName := "renamedVol"
+	createVol(name, t)
+	reqURL := fmt.Sprintf("%v%v?name=%v&newName=%v&authKey=%v",
+		hostAddr, proto.AdminRenameVol, name, newName, buildAuthKey("cfs"))
+	fmt.Println(reqURL)
+	process(reqURL, t)
+	vol, err := server.cluster.getVol(newName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if alias, err := server.cluster.getVol(name); err != nil || alias != vol {
+		t.Errorf("the old name[%v] is not kept as an alias of vol[%v], err[%v]", name, vol.Name, err)
+	}
+	userInfo, err := server.user.getUserInfo("cfs")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !userInfo.Policy.IsOwn(newName) || userInfo.Policy.IsOwn(name) {
+		t.Errorf("the policy of the owner is not renamed, own vols[%v]", userInfo.Policy.OwnVols)
+	}
+	// the old name can't be reused by another volume
+	if _, err = server.cluster.createVol(name, "cfs", testZone2, "", 3, 3, 0, 100, false, false, false, false, ""); err == nil {
+		t.Errorf("vol[%v] is created with the alias of vol[%v]", name, newName)
+	}
+	markDeleteVol(newName, t)
+}
+
 func createVol(name string, t *testing.T) {
 	reqURL := fmt.Sprintf("%v%v?name=%v&replicas=3&type=extent&capacity=100&owner=cfs&mpCount=2&zoneName=%v", hostAddr, proto.AdminCreateVol, name, testZone2)
 	fmt.Println(reqURL)
@@ -0,0 +1,538 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Permission bits requested by a metadata operation.
+const (
+	permExec  uint32 = 1
+	permWrite uint32 = 2
+	permRead  uint32 = 4
+)
+
+// Tags of the POSIX ACL entries, same as the ones used by the linux kernel.
+const (
+	aclTagUserObj  uint16 = 0x01
+	aclTagUser     uint16 = 0x02
+	aclTagGroupObj uint16 = 0x04
+	aclTagGroup    uint16 = 0x08
+	aclTagMask     uint16 = 0x10
+	aclTagOther    uint16 = 0x20
+)
+
+const (
+	aclXAttrVersion   uint32 = 0x0002
+	aclXAttrHeaderLen        = 4
+	aclXAttrEntryLen         = 8
+	aclUndefinedID    uint32 = 0xFFFFFFFF
+)
+
+// enablePosixACL makes the meta node enforce the uid/gid/mode and POSIX ACL
+// permission checks on the metadata operations issued by the clients.
+var enablePosixACL bool
+
+// PosixACLEntry is a single entry of a POSIX access control list.
+type PosixACLEntry struct {
+	Tag  uint16
+	Perm uint16
+	ID   uint32
+}
+
+// PosixACL is a POSIX access control list stored in the
+// "system.posix_acl_access" and "system.posix_acl_default" extended attributes.
+// Encoded with the same layout as linux xattrs:
+//  +-------+---------+-----+------+----+-----+
+//  | item  | Version | Tag | Perm | ID | ... |
+//  +-------+---------+-----+------+----+-----+
+//  | bytes |    4    |  2  |  2   | 4  | ... |
+//  +-------+---------+-----+------+----+-----+
+type PosixACL []PosixACLEntry
+
+// ParsePosixACL decodes a POSIX ACL from the raw value of an extended attribute.
+func ParsePosixACL(raw []byte) (acl PosixACL, err error) {
+	if len(raw) < aclXAttrHeaderLen || (len(raw)-aclXAttrHeaderLen)%aclXAttrEntryLen != 0 {
+		err = fmt.Errorf("invalid posix acl length %v", len(raw))
+		return
+	}
+	if version := binary.LittleEndian.Uint32(raw); version != aclXAttrVersion {
+		err = fmt.Errorf("unsupported posix acl version %v", version)
+		return
+	}
+	count := (len(raw) - aclXAttrHeaderLen) / aclXAttrEntryLen
+	acl = make(PosixACL, 0, count)
+	for off := aclXAttrHeaderLen; off < len(raw); off += aclXAttrEntryLen {
+		acl = append(acl, PosixACLEntry{
+			Tag:  binary.LittleEndian.Uint16(raw[off:]),
+			Perm: binary.LittleEndian.Uint16(raw[off+2:]),
+			ID:   binary.LittleEndian.Uint32(raw[off+4:]),
+		})
+	}
+	if err = acl.validate(); err != nil {
+		acl = nil
+	}
+	return
+}
+
+// Bytes encodes the ACL into the raw value of an extended attribute.
+func (acl PosixACL) Bytes() []byte {
+	raw := make([]byte, aclXAttrHeaderLen+len(acl)*aclXAttrEntryLen)
+	binary.LittleEndian.PutUint32(raw, aclXAttrVersion)
+	off := aclXAttrHeaderLen
+	for _, e := range acl {
+		binary.LittleEndian.PutUint16(raw[off:], e.Tag)
+		binary.LittleEndian.PutUint16(raw[off+2:], e.Perm)
+		binary.LittleEndian.PutUint32(raw[off+4:], e.ID)
+		off += aclXAttrEntryLen
+	}
+	return raw
+}
+
+func (acl PosixACL) validate() error {
+	var userObj, groupObj, other, mask, named int
+	for _, e := range acl {
+		if e.Perm&^0x7 != 0 {
+			return fmt.Errorf("invalid posix acl permission %v", e.Perm)
+		}
+		switch e.Tag {
+		case aclTagUserObj:
+			userObj++
+		case aclTagGroupObj:
+			groupObj++
+		case aclTagOther:
+			other++
+		case aclTagMask:
+			mask++
+		case aclTagUser, aclTagGroup:
+			named++
+		default:
+			return fmt.Errorf("invalid posix acl tag %v", e.Tag)
+		}
+	}
+	if userObj != 1 || groupObj != 1 || other != 1 || mask > 1 {
+		return fmt.Errorf("invalid posix acl entries")
+	}
+	if named > 0 && mask == 0 {
+		return fmt.Errorf("posix acl with named entries requires a mask entry")
+	}
+	return nil
+}
+
+func (acl PosixACL) entry(tag uint16) *PosixACLEntry {
+	for i := range acl {
+		if acl[i].Tag == tag {
+			return &acl[i]
+		}
+	}
+	return nil
+}
+
+// IsMinimal returns true if the ACL is equivalent to the permission bits of the file mode.
+func (acl PosixACL) IsMinimal() bool {
+	return len(acl) == 3
+}
+
+// Mode returns the permission bits of the file mode described by the ACL.
+func (acl PosixACL) Mode() uint32 {
+	var mode uint32
+	for _, e := range acl {
+		switch e.Tag {
+		case aclTagUserObj:
+			mode |= uint32(e.Perm) << 6
+		case aclTagOther:
+			mode |= uint32(e.Perm)
+		}
+	}
+	if group := acl.entry(aclTagMask); group != nil {
+		mode |= uint32(group.Perm) << 3
+	} else if group = acl.entry(aclTagGroupObj); group != nil {
+		mode |= uint32(group.Perm) << 3
+	}
+	return mode
+}
+
+// Inherit creates the access ACL of a new file from the default ACL of the parent
+// directory, restricted by the permission bits of the requested mode.
+func (acl PosixACL) Inherit(mode uint32) (access PosixACL) {
+	access = make(PosixACL, len(acl))
+	copy(access, acl)
+	hasMask := access.entry(aclTagMask) != nil
+	for i := range access {
+		e := &access[i]
+		switch e.Tag {
+		case aclTagUserObj:
+			e.Perm &= uint16(mode>>6) & 0x7
+		case aclTagMask:
+			e.Perm &= uint16(mode>>3) & 0x7
+		case aclTagGroupObj:
+			if !hasMask {
+				e.Perm &= uint16(mode>>3) & 0x7
+			}
+		case aclTagOther:
+			e.Perm &= uint16(mode) & 0x7
+		}
+	}
+	return
+}
+
+// Chmod returns the ACL with the owner, group and other entries set to the permission bits of the
+// new file mode, like posix_acl_chmod. The mask entry holds the group bits if there is one.
+func (acl PosixACL) Chmod(mode uint32) (access PosixACL) {
+	access = make(PosixACL, len(acl))
+	copy(access, acl)
+	hasMask := access.entry(aclTagMask) != nil
+	for i := range access {
+		e := &access[i]
+		switch e.Tag {
+		case aclTagUserObj:
+			e.Perm = uint16(mode>>6) & 0x7
+		case aclTagMask:
+			e.Perm = uint16(mode>>3) & 0x7
+		case aclTagGroupObj:
+			if !hasMask {
+				e.Perm = uint16(mode>>3) & 0x7
+			}
+		case aclTagOther:
+			e.Perm = uint16(mode) & 0x7
+		}
+	}
+	return
+}
+
+// Permit checks if the caller is granted the wanted permission bits on a file
+// owned by the specified owner and group.
+func (acl PosixACL) Permit(owner, group uint32, cred *proto.UserCred, want uint32) bool {
+	if cred.Uid == owner {
+		return uint32(acl.entry(aclTagUserObj).Perm)&want == want
+	}
+	var mask uint16 = 0x7
+	if e := acl.entry(aclTagMask); e != nil {
+		mask = e.Perm
+	}
+	for _, e := range acl {
+		if e.Tag == aclTagUser && e.ID == cred.Uid {
+			return uint32(e.Perm&mask)&want == want
+		}
+	}
+	var matched bool
+	for _, e := range acl {
+		var gid uint32
+		switch e.Tag {
+		case aclTagGroupObj:
+			gid = group
+		case aclTagGroup:
+			gid = e.ID
+		default:
+			continue
+		}
+		if !inGroups(cred, gid) {
+			continue
+		}
+		if uint32(e.Perm&mask)&want == want {
+			return true
+		}
+		matched = true
+	}
+	if matched {
+		return false
+	}
+	return uint32(acl.entry(aclTagOther).Perm)&want == want
+}
+
+// minimalACL builds the ACL equivalent to the permission bits of the file mode.
+func minimalACL(mode uint32) PosixACL {
+	return PosixACL{
+		{Tag: aclTagUserObj, Perm: uint16(mode>>6) & 0x7, ID: aclUndefinedID},
+		{Tag: aclTagGroupObj, Perm: uint16(mode>>3) & 0x7, ID: aclUndefinedID},
+		{Tag: aclTagOther, Perm: uint16(mode) & 0x7, ID: aclUndefinedID},
+	}
+}
+
+func inGroups(cred *proto.UserCred, gid uint32) bool {
+	if cred.Gid == gid {
+		return true
+	}
+	for _, g := range cred.Gids {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+func isACLXAttr(key string) bool {
+	return key == proto.XAttrKeyPosixACLAccess || key == proto.XAttrKeyPosixACLDefault
+}
+
+// getAccessACL returns the access ACL of the inode, or the ACL derived from the
+// file mode if the inode has none.
+func (mp *metaPartition) getAccessACL(ino *Inode) PosixACL {
+	if item := mp.extendTree.Get(NewExtend(ino.Inode)); item != nil {
+		if raw, exist := item.(*Extend).Get([]byte(proto.XAttrKeyPosixACLAccess)); exist && len(raw) > 0 {
+			if acl, err := ParsePosixACL(raw); err == nil {
+				return acl
+			}
+		}
+	}
+	return minimalACL(ino.Type)
+}
+
+// chmodACL rewrites the stored access ACL of the inode after its mode is changed,
+// so that the ACL keeps granting the permission bits of the mode.
+func (mp *metaPartition) chmodACL(inode uint64, mode uint32) {
+	item := mp.extendTree.CopyGet(NewExtend(inode))
+	if item == nil {
+		return
+	}
+	extend := item.(*Extend)
+	raw, exist := extend.Get([]byte(proto.XAttrKeyPosixACLAccess))
+	if !exist || len(raw) == 0 {
+		return
+	}
+	acl, err := ParsePosixACL(raw)
+	if err != nil {
+		return
+	}
+	extend.Put([]byte(proto.XAttrKeyPosixACLAccess), acl.Chmod(mode).Bytes())
+}
+
+// getAliveInode returns the inode which is not marked as deleted.
+func (mp *metaPartition) getAliveInode(inode uint64) *Inode {
+	item := mp.inodeTree.Get(NewInode(inode, 0))
+	if item == nil {
+		return nil
+	}
+	ino := item.(*Inode)
+	if ino.ShouldDelete() {
+		return nil
+	}
+	return ino
+}
+
+// checkPermission checks whether the caller is granted the wanted permission
+// bits on the specified inode.
+func (mp *metaPartition) checkPermission(inode uint64, cred *proto.UserCred, want uint32) (status uint8) {
+	if !enablePosixACL {
+		return proto.OpOk
+	}
+	if cred == nil {
+		return proto.OpNotPerm
+	}
+	ino := mp.getAliveInode(inode)
+	if ino == nil {
+		return proto.OpNotExistErr
+	}
+	if cred.Uid == 0 {
+		return proto.OpOk
+	}
+	ino.RLock()
+	owner, group := ino.Uid, ino.Gid
+	ino.RUnlock()
+	if !mp.getAccessACL(ino).Permit(owner, group, cred, want) {
+		return proto.OpNotPerm
+	}
+	return proto.OpOk
+}
+
+// checkOwner checks whether the caller is the owner of the inode or the super user.
+func (mp *metaPartition) checkOwner(inode uint64, cred *proto.UserCred) (status uint8) {
+	if !enablePosixACL {
+		return proto.OpOk
+	}
+	if cred == nil {
+		return proto.OpNotPerm
+	}
+	ino := mp.getAliveInode(inode)
+	if ino == nil {
+		return proto.OpNotExistErr
+	}
+	ino.RLock()
+	defer ino.RUnlock()
+	if cred.Uid != 0 && cred.Uid != ino.Uid {
+		return proto.OpNotPerm
+	}
+	return proto.OpOk
+}
+
+// checkDeletePermission checks whether the caller is allowed to remove the
+// dentry from the parent directory, honoring the sticky bit of the directory.
+func (mp *metaPartition) checkDeletePermission(parentID uint64, name string, cred *proto.UserCred) (status uint8) {
+	if status = mp.checkPermission(parentID, cred, permWrite|permExec); status != proto.OpOk || !enablePosixACL {
+		return
+	}
+	parent := mp.getAliveInode(parentID)
+	if parent == nil || cred.Uid == 0 {
+		return
+	}
+	parent.RLock()
+	sticky, owner := proto.OsMode(parent.Type)&os.ModeSticky != 0, parent.Uid
+	parent.RUnlock()
+	if !sticky || cred.Uid == owner {
+		return
+	}
+	dentry, st := mp.getDentry(&Dentry{ParentId: parentID, Name: name})
+	if st != proto.OpOk {
+		return
+	}
+	childOwner, err := mp.getInodeOwner(dentry.Inode)
+	if err != nil {
+		log.LogWarnf("checkDeletePermission: partitionID(%v) parentID(%v) name(%v) err(%v)",
+			mp.config.PartitionId, parentID, name, err)
+		return proto.OpNotPerm
+	}
+	if childOwner != cred.Uid {
+		return proto.OpNotPerm
+	}
+	return
+}
+
+// getInodeOwner returns the owner of the stored inode, which is got from the meta partition
+// hosting it if it is out of the range of this one.
+func (mp *metaPartition) getInodeOwner(inode uint64) (owner uint32, err error) {
+	host := mp
+	if inode < mp.config.Start || inode > mp.config.End {
+		host = mp.manager.getLocalPartitionByInode(mp.config.VolName, inode)
+	}
+	if host == nil {
+		var info *proto.InodeInfo
+		if info, err = mp.getRemoteInode(inode); err != nil {
+			return
+		}
+		return info.Uid, nil
+	}
+	ino := host.getAliveInode(inode)
+	if ino == nil {
+		err = fmt.Errorf("inode(%v) not exist", inode)
+		return
+	}
+	ino.RLock()
+	owner = ino.Uid
+	ino.RUnlock()
+	return
+}
+
+// checkSetAttrPermission checks whether the caller is allowed to change the
+// attributes of the inode.
+func (mp *metaPartition) checkSetAttrPermission(req *SetattrRequest) (status uint8) {
+	if !enablePosixACL {
+		return proto.OpOk
+	}
+	cred := req.Cred
+	if cred == nil {
+		return proto.OpNotPerm
+	}
+	ino := mp.getAliveInode(req.Inode)
+	if ino == nil {
+		return proto.OpNotExistErr
+	}
+	if cred.Uid == 0 {
+		return proto.OpOk
+	}
+	ino.RLock()
+	owner := ino.Uid
+	ino.RUnlock()
+	if req.Valid&proto.AttrUid != 0 && req.Uid != owner {
+		return proto.OpNotPerm
+	}
+	if req.Valid&(proto.AttrMode|proto.AttrUid|proto.AttrGid) != 0 && cred.Uid != owner {
+		return proto.OpNotPerm
+	}
+	if req.Valid&proto.AttrGid != 0 && !inGroups(cred, req.Gid) {
+		return proto.OpNotPerm
+	}
+	if req.Valid&(proto.AttrModifyTime|proto.AttrAccessTime) != 0 && cred.Uid != owner {
+		return mp.checkPermission(req.Inode, cred, permWrite)
+	}
+	return proto.OpOk
+}
+
+// checkXAttrPermission checks whether the caller is allowed to modify the
+// extended attribute. Only the owner can change the ACL of an inode, and the
+// tier extents are maintained by the meta node only.
+func (mp *metaPartition) checkXAttrPermission(inode uint64, key string, cred *proto.UserCred) (status uint8) {
+	if key == proto.XAttrKeyTier {
+		return proto.OpNotPerm
+	}
+	if isACLXAttr(key) {
+		return mp.checkOwner(inode, cred)
+	}
+	return mp.checkPermission(inode, cred, permWrite)
+}
+
+// inheritACL applies the default ACL of the parent directory to the new inode,
+// and returns the extended attributes to be stored with it.
+func inheritACL(ino *Inode, defaultACL string) (extend *Extend, err error) {
+	if defaultACL == "" {
+		return
+	}
+	var acl PosixACL
+	if acl, err = ParsePosixACL([]byte(defaultACL)); err != nil {
+		return
+	}
+	access := acl.Inherit(ino.Type)
+	ino.Type = ino.Type&^uint32(os.ModePerm) | access.Mode()
+	extend = NewExtend(ino.Inode)
+	if !access.IsMinimal() {
+		extend.Put([]byte(proto.XAttrKeyPosixACLAccess), access.Bytes())
+	}
+	if proto.IsDir(ino.Type) {
+		extend.Put([]byte(proto.XAttrKeyPosixACLDefault), []byte(defaultACL))
+	}
+	if len(extend.dataMap) == 0 {
+		extend = nil
+	}
+	return
+}
+
+// marshalInodeWithACL encodes the inode and its inherited ACL into the value of a single raft log entry:
+//  +-------+------------+-------+--------+
+//  | item  | InodeLen   | Inode | Extend |
+//  +-------+------------+-------+--------+
+//  | bytes |     4      |  ...  |  ...   |
+//  +-------+------------+-------+--------+
+func marshalInodeWithACL(ino *Inode, extend *Extend) (raw []byte, err error) {
+	var inoRaw, extendRaw []byte
+	if inoRaw, err = ino.Marshal(); err != nil {
+		return
+	}
+	if extendRaw, err = extend.Bytes(); err != nil {
+		return
+	}
+	raw = make([]byte, 4, 4+len(inoRaw)+len(extendRaw))
+	binary.BigEndian.PutUint32(raw, uint32(len(inoRaw)))
+	raw = append(append(raw, inoRaw...), extendRaw...)
+	return
+}
+
+func unmarshalInodeWithACL(raw []byte) (ino *Inode, extend *Extend, err error) {
+	if len(raw) < 4 || uint64(len(raw)-4) < uint64(binary.BigEndian.Uint32(raw)) {
+		err = fmt.Errorf("invalid inode with acl length %v", len(raw))
+		return
+	}
+	inoLen := 4 + int(binary.BigEndian.Uint32(raw))
+	ino = NewInode(0, 0)
+	if err = ino.Unmarshal(raw[4:inoLen]); err != nil {
+		return
+	}
+	extend, err = NewExtendFromBytes(raw[inoLen:])
+	return
+}
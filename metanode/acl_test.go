@@ -0,0 +1,217 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestACL() PosixACL {
+	return PosixACL{
+		{Tag: aclTagUserObj, Perm: 7, ID: aclUndefinedID},
+		{Tag: aclTagUser, Perm: 6, ID: 1001},
+		{Tag: aclTagGroupObj, Perm: 5, ID: aclUndefinedID},
+		{Tag: aclTagGroup, Perm: 7, ID: 2001},
+		{Tag: aclTagMask, Perm: 5, ID: aclUndefinedID},
+		{Tag: aclTagOther, Perm: 0, ID: aclUndefinedID},
+	}
+}
+
+func TestPosixACL_Bytes(t *testing.T) {
+	acl := newTestACL()
+	parsed, err := ParsePosixACL(acl.Bytes())
+	if err != nil {
+		t.Fatalf("parse posix acl fail cause: %v", err)
+	}
+	if !reflect.DeepEqual(acl, parsed) {
+		t.Fatalf("result mismatch: expect %v, actual %v", acl, parsed)
+	}
+	if _, err = ParsePosixACL([]byte{2, 0, 0, 0, 1}); err == nil {
+		t.Fatalf("parse truncated posix acl should fail")
+	}
+	if _, err = ParsePosixACL(append(PosixACL{}, acl[0], acl[1], acl[2], acl[5]).Bytes()); err == nil {
+		t.Fatalf("parse posix acl without mask should fail")
+	}
+}
+
+func TestPosixACL_Permit(t *testing.T) {
+	acl := newTestACL()
+	var cases = []struct {
+		cred   *proto.UserCred
+		want   uint32
+		permit bool
+	}{
+		{&proto.UserCred{Uid: 1000, Gid: 1000}, permRead | permWrite | permExec, true},
+		{&proto.UserCred{Uid: 1001, Gid: 3000}, permRead, true},
+		{&proto.UserCred{Uid: 1001, Gid: 3000}, permWrite, false}, // limited by mask
+		{&proto.UserCred{Uid: 1002, Gid: 1000}, permRead | permExec, true},
+		{&proto.UserCred{Uid: 1002, Gid: 1000}, permWrite, false},
+		{&proto.UserCred{Uid: 1003, Gid: 3000, Gids: []uint32{2001}}, permRead | permExec, true},
+		{&proto.UserCred{Uid: 1003, Gid: 3000, Gids: []uint32{2001}}, permWrite, false},
+		{&proto.UserCred{Uid: 1004, Gid: 3000}, permRead, false},
+	}
+	for i, c := range cases {
+		if permit := acl.Permit(1000, 1000, c.cred, c.want); permit != c.permit {
+			t.Fatalf("case %v: cred %v want %v: expect %v, actual %v", i, c.cred, c.want, c.permit, permit)
+		}
+	}
+}
+
+func TestInheritACL(t *testing.T) {
+	def := newTestACL()
+
+	file := NewInode(100, uint32(0664))
+	extend, err := inheritACL(file, string(def.Bytes()))
+	if err != nil {
+		t.Fatalf("inherit acl fail cause: %v", err)
+	}
+	if perm := file.Type & uint32(os.ModePerm); perm != 0640 {
+		t.Fatalf("file mode mismatch: expect %o, actual %o", 0640, perm)
+	}
+	if _, exist := extend.Get([]byte(proto.XAttrKeyPosixACLDefault)); exist {
+		t.Fatalf("regular file should not inherit default acl")
+	}
+	raw, exist := extend.Get([]byte(proto.XAttrKeyPosixACLAccess))
+	if !exist {
+		t.Fatalf("regular file should have an access acl")
+	}
+	access, err := ParsePosixACL(raw)
+	if err != nil {
+		t.Fatalf("parse access acl fail cause: %v", err)
+	}
+	if mask := access.entry(aclTagMask); mask.Perm != 4 {
+		t.Fatalf("mask mismatch: expect 4, actual %v", mask.Perm)
+	}
+
+	dir := NewInode(101, uint32(os.ModeDir|0777))
+	if extend, err = inheritACL(dir, string(def.Bytes())); err != nil {
+		t.Fatalf("inherit acl fail cause: %v", err)
+	}
+	if _, exist = extend.Get([]byte(proto.XAttrKeyPosixACLDefault)); !exist {
+		t.Fatalf("directory should inherit default acl")
+	}
+	if !proto.IsDir(dir.Type) {
+		t.Fatalf("directory type lost after inheriting acl")
+	}
+}
+
+func TestInodeWithACLMarshal(t *testing.T) {
+	ino := NewInode(100, uint32(0640))
+	ino.Uid, ino.Gid = 1000, 1000
+	extend := NewExtend(ino.Inode)
+	extend.Put([]byte(proto.XAttrKeyPosixACLAccess), newTestACL().Bytes())
+	raw, err := marshalInodeWithACL(ino, extend)
+	if err != nil {
+		t.Fatalf("marshal fail cause: %v", err)
+	}
+	decodedIno, decodedExtend, err := unmarshalInodeWithACL(raw)
+	if err != nil {
+		t.Fatalf("unmarshal fail cause: %v", err)
+	}
+	if decodedIno.Inode != ino.Inode || decodedIno.Uid != ino.Uid || decodedIno.Type != ino.Type {
+		t.Fatalf("inode mismatch: expect %v, actual %v", ino, decodedIno)
+	}
+	if value, exist := decodedExtend.Get([]byte(proto.XAttrKeyPosixACLAccess)); !exist || string(value) != string(newTestACL().Bytes()) {
+		t.Fatalf("access acl lost after unmarshal")
+	}
+	if _, _, err = unmarshalInodeWithACL(raw[:3]); err == nil {
+		t.Fatalf("truncated value should be refused")
+	}
+}
+
+func TestCheckDeletePermissionSticky(t *testing.T) {
+	enablePosixACL = true
+	defer func() { enablePosixACL = false }()
+	// the inodes out of the range are hosted by another partition of the same node
+	remoteMP := &metaPartition{
+		config:    &MetaPartitionConfig{PartitionId: 2, VolName: "vol", Start: 101, End: 200},
+		inodeTree: NewBtree(),
+	}
+	manager := &metadataManager{partitions: map[uint64]MetaPartition{2: remoteMP}}
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "vol", Start: 1, End: 100},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+		manager:    manager,
+	}
+	manager.partitions[1] = mp
+	parent := NewInode(1, uint32(os.ModeDir|os.ModeSticky|0777))
+	local := NewInode(2, uint32(0644))
+	local.Uid = 1001
+	remote := NewInode(150, uint32(0644))
+	remote.Uid = 1001
+	mp.inodeTree.ReplaceOrInsert(parent, true)
+	mp.inodeTree.ReplaceOrInsert(local, true)
+	remoteMP.inodeTree.ReplaceOrInsert(remote, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "local", Inode: 2}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "remote", Inode: 150}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "missing", Inode: 160}, true)
+
+	cases := []struct {
+		name   string
+		cred   *proto.UserCred
+		status uint8
+	}{
+		{"local", &proto.UserCred{Uid: 1001}, proto.OpOk},
+		{"local", &proto.UserCred{Uid: 1002}, proto.OpNotPerm},
+		{"remote", &proto.UserCred{Uid: 1001}, proto.OpOk},
+		{"remote", &proto.UserCred{Uid: 1002}, proto.OpNotPerm},
+		{"missing", &proto.UserCred{Uid: 1001}, proto.OpNotPerm},
+		{"remote", &proto.UserCred{Uid: 0}, proto.OpOk},
+	}
+	for i, c := range cases {
+		if status := mp.checkDeletePermission(1, c.name, c.cred); status != c.status {
+			t.Fatalf("case %v: expect %v, actual %v", i, c.status, status)
+		}
+	}
+}
+
+func TestChmodACL(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{Start: 1, End: 100},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+	}
+	ino := NewInode(2, uint32(0750))
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	extend := NewExtend(2)
+	extend.Put([]byte(proto.XAttrKeyPosixACLAccess), newTestACL().Bytes())
+	mp.extendTree.ReplaceOrInsert(extend, true)
+
+	if err := mp.fsmSetAttr(&SetattrRequest{Inode: 2, Valid: proto.AttrMode, Mode: uint32(0604)}); err != nil {
+		t.Fatalf("set attr fail cause: %v", err)
+	}
+	acl := mp.getAccessACL(ino)
+	if mode := acl.Mode(); mode != 0604 {
+		t.Fatalf("acl mode mismatch: expect %o, actual %o", 0604, mode)
+	}
+	// the group owner entry is kept, and limited by the mask
+	if group := acl.entry(aclTagGroupObj); group.Perm != 5 {
+		t.Fatalf("group entry mismatch: expect 5, actual %v", group.Perm)
+	}
+	if acl.Permit(1000, 1000, &proto.UserCred{Uid: 1001, Gid: 3000}, permRead) {
+		t.Fatalf("named user should be limited by the mask")
+	}
+
+	minimal := minimalACL(0750).Chmod(0640)
+	if minimal.Mode() != 0640 || minimal.entry(aclTagGroupObj).Perm != 4 {
+		t.Fatalf("minimal acl mismatch: %v", minimal)
+	}
+}
@@ -59,6 +59,8 @@ func (m *MetaNode) registerAPIHandler() (err error) {
 	http.HandleFunc("/getDirectory", m.getDirectoryHandler)
 	http.HandleFunc("/getAllDentry", m.getAllDentriesHandler)
 	http.HandleFunc("/getParams", m.getParamsHandler)
+	// get inodes which have too many extent keys
+	http.HandleFunc("/getFragmentedInodes", m.getFragmentedInodesHandler)
 	return
 }
 
@@ -172,6 +174,48 @@ func (m *MetaNode) getAllInodesHandler(w http.ResponseWriter, r *http.Request) {
 	mp.GetInodeTree().Ascend(f)
 }
 
+func (m *MetaNode) getFragmentedInodesHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := NewAPIResponse(http.StatusBadRequest, "")
+	defer func() {
+		data, _ := resp.Marshal()
+		if _, err := w.Write(data); err != nil {
+			log.LogErrorf("[getFragmentedInodesHandler] response %s", err)
+		}
+	}()
+	pid, err := strconv.ParseUint(r.FormValue("pid"), 10, 64)
+	if err != nil {
+		resp.Msg = err.Error()
+		return
+	}
+	threshold := DefragThreshold()
+	if value := r.FormValue("threshold"); value != "" {
+		if threshold, err = strconv.ParseUint(value, 10, 64); err != nil {
+			resp.Msg = err.Error()
+			return
+		}
+	}
+	mp, err := m.metadataManager.GetPartition(pid)
+	if err != nil {
+		resp.Code = http.StatusNotFound
+		resp.Msg = err.Error()
+		return
+	}
+	inodes := make(map[uint64]int)
+	mp.GetInodeTree().Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		if proto.IsRegular(ino.Type) && !ino.ShouldDelete() {
+			if cnt := ino.Extents.Len(); uint64(cnt) >= threshold {
+				inodes[ino.Inode] = cnt
+			}
+		}
+		return true
+	})
+	resp.Data = inodes
+	resp.Code = http.StatusOK
+	resp.Msg = http.StatusText(http.StatusOK)
+}
+
 func (m *MetaNode) getInodeHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	resp := NewAPIResponse(http.StatusBadRequest, "")
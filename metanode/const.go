@@ -117,6 +117,11 @@ const (
 	opFSMDeleteDentryBatch
 	opFSMUnlinkInodeBatch
 	opFSMEvictInodeBatch
+	opFSMExtentsReplace
+	opFSMExtentRelocate
+	opFSMClonePartition
+	opFSMCreateInodeWithACL
+	opFSMTierExtents
 )
 
 var (
@@ -147,6 +152,11 @@ const (
 	cfgDeleteBatchCount  = "deleteBatchCount"
 	cfgTotalMem          = "totalMem"
 	cfgZoneName          = "zoneName"
+	cfgRack              = "rack"
+	cfgHost              = "host"
+	cfgPowerDomain       = "powerDomain"
+	cfgEnablePosixACL    = "enablePosixACL"
+	cfgDefragThreshold   = "defragExtentThreshold"
 
 	metaNodeDeleteBatchCountKey = "batchCount"
 )
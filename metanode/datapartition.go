@@ -27,6 +27,9 @@ type DataPartition struct {
 	ReplicaNum    uint8
 	PartitionType string
 	Hosts         []string
+	Checksum      string // checksum type of the data
+
+	CompactingTinyExtents []uint64 // tiny extents whose live data are to be relocated
 }
 
 // GetAllAddrs returns all addresses of the data partition.
@@ -34,6 +37,16 @@ func (dp *DataPartition) GetAllAddrs() (m string) {
 	return strings.Join(dp.Hosts[1:], proto.AddrSplit) + proto.AddrSplit
 }
 
+// IsTinyExtentCompacting returns if the live data of the tiny extent are to be relocated.
+func (dp *DataPartition) IsTinyExtentCompacting(extentID uint64) bool {
+	for _, id := range dp.CompactingTinyExtents {
+		if id == extentID {
+			return true
+		}
+	}
+	return false
+}
+
 // DataPartitionsView defines the view of the data node.
 type DataPartitionsView struct {
 	DataPartitions []*DataPartition
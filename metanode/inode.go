@@ -406,6 +406,24 @@ func (i *Inode) AppendExtents(eks []proto.ExtentKey, ct int64) (delExtents []pro
 	return
 }
 
+// ReplaceExtents replaces the contiguous extent keys with a single extent key.
+// The modify time is kept since the file data is not changed.
+func (i *Inode) ReplaceExtents(oldEks []proto.ExtentKey, ek proto.ExtentKey) (delExtents []proto.ExtentKey, ok bool) {
+	i.Lock()
+	defer i.Unlock()
+	if delExtents, ok = i.Extents.Replace(oldEks, ek); ok {
+		i.Generation++
+	}
+	return
+}
+
+// HasExtent returns true if any extent key of the inode refers to the extent.
+func (i *Inode) HasExtent(partitionID, extentID uint64) bool {
+	i.RLock()
+	defer i.RUnlock()
+	return i.Extents.HasExtent(partitionID, extentID)
+}
+
 func (i *Inode) ExtentsTruncate(length uint64, ct int64) (delExtents []proto.ExtentKey) {
 	i.Lock()
 	delExtents = i.Extents.Truncate(length)
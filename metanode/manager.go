@@ -116,6 +116,8 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opMetaExtentsDel(conn, p, remoteAddr)
 	case proto.OpMetaTruncate:
 		err = m.opMetaExtentsTruncate(conn, p, remoteAddr)
+	case proto.OpMetaExtentsReplace:
+		err = m.opMetaExtentsReplace(conn, p, remoteAddr)
 	case proto.OpMetaLookup:
 		err = m.opMetaLookup(conn, p, remoteAddr)
 	case proto.OpDeleteMetaPartition:
@@ -132,6 +134,8 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet,
 		err = m.opRemoveMetaPartitionRaftMember(conn, p, remoteAddr)
 	case proto.OpMetaPartitionTryToLeader:
 		err = m.opMetaPartitionTryToLeader(conn, p, remoteAddr)
+	case proto.OpCloneMetaPartition:
+		err = m.opCloneMetaPartition(conn, p, remoteAddr)
 	case proto.OpMetaBatchInodeGet:
 		err = m.opMetaBatchInodeGet(conn, p, remoteAddr)
 	case proto.OpMetaDeleteInode:
@@ -227,6 +231,23 @@ func (m *metadataManager) getPartition(id uint64) (mp MetaPartition, err error)
 	return
 }
 
+// getLocalPartitionByInode returns the partition of the volume hosted by this node whose range
+// covers the inode, or nil if there is none.
+func (m *metadataManager) getLocalPartitionByInode(volName string, inode uint64) *metaPartition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, partition := range m.partitions {
+		mp, ok := partition.(*metaPartition)
+		if !ok || mp.config.VolName != volName {
+			continue
+		}
+		if inode >= mp.config.Start && inode <= mp.config.End {
+			return mp
+		}
+	}
+	return nil
+}
+
 func (m *metadataManager) loadPartitions() (err error) {
 	var metaNodeInfo *proto.MetaNodeInfo
 	for i := 0; i < 3; i++ {
@@ -366,9 +387,35 @@ func (m *metadataManager) createPartition(request *proto.CreateMetaPartitionRequ
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	partitionId := fmt.Sprintf("%d", request.PartitionID)
+	mpc := m.newPartitionConfig(request)
+
+	if oldMp, ok := m.partitions[request.PartitionID]; ok {
+		err = oldMp.IsEquareCreateMetaPartitionRequst(request)
+		return
+	}
+
+	partition := NewMetaPartition(mpc, m)
+	if err = partition.PersistMetadata(); err != nil {
+		err = errors.NewErrorf("[createPartition]->%s", err.Error())
+		return
+	}
+
+	if err = partition.Start(); err != nil {
+		os.RemoveAll(mpc.RootDir)
+		log.LogErrorf("load meta partition %v fail: %v", request.PartitionID, err)
+		err = errors.NewErrorf("[createPartition]->%s", err.Error())
+		return
+	}
 
-	mpc := &MetaPartitionConfig{
+	m.partitions[request.PartitionID] = partition
+	log.LogInfof("load meta partition %v success", request.PartitionID)
+
+	return
+}
+
+func (m *metadataManager) newPartitionConfig(request *proto.CreateMetaPartitionRequest) (mpc *MetaPartitionConfig) {
+	partitionId := fmt.Sprintf("%d", request.PartitionID)
+	mpc = &MetaPartitionConfig{
 		PartitionId: request.PartitionID,
 		VolName:     request.VolName,
 		Start:       request.Start,
@@ -383,28 +430,39 @@ func (m *metadataManager) createPartition(request *proto.CreateMetaPartitionRequ
 	mpc.AfterStop = func() {
 		m.detachPartition(request.PartitionID)
 	}
+	return
+}
+
+// clonePartition creates the meta partition of the cloned volume from the snapshot of the source meta partition,
+// with the inode ids allocated from the cursor of the source.
+func (m *metadataManager) clonePartition(request *proto.CreateMetaPartitionRequest, cursor uint64, sm *storeMsg) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if oldMp, ok := m.partitions[request.PartitionID]; ok {
 		err = oldMp.IsEquareCreateMetaPartitionRequst(request)
 		return
 	}
-
+	mpc := m.newPartitionConfig(request)
+	mpc.Cursor = cursor
 	partition := NewMetaPartition(mpc, m)
 	if err = partition.PersistMetadata(); err != nil {
-		err = errors.NewErrorf("[createPartition]->%s", err.Error())
+		err = errors.NewErrorf("[clonePartition]->%s", err.Error())
+		return
+	}
+	if err = partition.(*metaPartition).store(sm); err != nil {
+		os.RemoveAll(mpc.RootDir)
+		err = errors.NewErrorf("[clonePartition]->%s", err.Error())
 		return
 	}
-
 	if err = partition.Start(); err != nil {
 		os.RemoveAll(mpc.RootDir)
-		log.LogErrorf("load meta partition %v fail: %v", request.PartitionID, err)
-		err = errors.NewErrorf("[createPartition]->%s", err.Error())
+		log.LogErrorf("load cloned meta partition %v fail: %v", request.PartitionID, err)
+		err = errors.NewErrorf("[clonePartition]->%s", err.Error())
 		return
 	}
-
 	m.partitions[request.PartitionID] = partition
-	log.LogInfof("load meta partition %v success", request.PartitionID)
-
+	log.LogInfof("clone meta partition %v success", request.PartitionID)
 	return
 }
 
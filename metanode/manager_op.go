@@ -790,6 +790,47 @@ func (m *metadataManager) opDecommissionMetaPartition(conn net.Conn,
 	return
 }
 
+func (m *metadataManager) opCloneMetaPartition(conn net.Conn,
+	p *Packet, remoteAddr string) (err error) {
+	req := &proto.CloneMetaPartitionRequest{}
+	adminTask := &proto.AdminTask{
+		Request: req,
+	}
+	decode := json.NewDecoder(bytes.NewBuffer(p.Data))
+	decode.UseNumber()
+	if err = decode.Decode(adminTask); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		err = errors.NewErrorf("[%v] req: %v, resp: %v", p.GetOpMsgWithReqAndResult(), req, err.Error())
+		return err
+	}
+	mp, err := m.getPartition(req.PartitionID)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		err = errors.NewErrorf("[%v] req: %v, resp: %v", p.GetOpMsgWithReqAndResult(), req, err.Error())
+		return err
+	}
+	if !m.serveProxy(conn, mp, p) {
+		return nil
+	}
+	if req.Clone == nil {
+		err = errors.NewErrorf("[opCloneMetaPartition]: partitionID= %d, no clone", req.PartitionID)
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		return
+	}
+	if err = mp.ClonePartition(req.Clone); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		return err
+	}
+	p.PacketOkReply()
+	m.respondToClient(conn, p)
+	log.LogInfof("%s [opCloneMetaPartition] req:%v; clone: %v", remoteAddr, req, req.Clone)
+	return
+}
+
 func (m *metadataManager) opAddMetaPartitionRaftMember(conn net.Conn,
 	p *Packet, remoteAddr string) (err error) {
 	var reqData []byte
@@ -1162,6 +1203,31 @@ func (m *metadataManager) opMetaBatchExtentsAdd(conn net.Conn, p *Packet, remote
 	return
 }
 
+func (m *metadataManager) opMetaExtentsReplace(conn net.Conn, p *Packet, remoteAddr string) (err error) {
+	req := &proto.ReplaceExtentKeysRequest{}
+	if err = json.Unmarshal(p.Data, req); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		err = errors.NewErrorf("[%v] req: %v, resp: %v", p.GetOpMsgWithReqAndResult(), req, err.Error())
+		return
+	}
+	mp, err := m.getPartition(req.PartitionId)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, ([]byte)(err.Error()))
+		m.respondToClient(conn, p)
+		err = errors.NewErrorf("[%v] req: %v, resp: %v", p.GetOpMsgWithReqAndResult(), req, err.Error())
+		return
+	}
+	if !m.serveProxy(conn, mp, p) {
+		return
+	}
+	err = mp.ExtentsReplace(req, p)
+	_ = m.respondToClient(conn, p)
+	log.LogDebugf("%s [opMetaExtentsReplace] req: %d - %v, resp: %v, body: %s",
+		remoteAddr, p.GetReqID(), req, p.GetResultMsg(), p.Data)
+	return
+}
+
 func (m *metadataManager) opCreateMultipart(conn net.Conn, p *Packet, remote string) (err error) {
 	req := &proto.CreateMultipartRequest{}
 	if err = json.Unmarshal(p.Data, req); err != nil {
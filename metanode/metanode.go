@@ -55,6 +55,7 @@ type MetaNode struct {
 	raftHeartbeatPort string
 	raftReplicatePort string
 	zoneName          string
+	labels            map[string]string // the rack, the host and the power domain registered on the master
 	httpStopC         chan uint8
 
 	control common.Control
@@ -170,6 +171,12 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 	m.raftHeartbeatPort = cfg.GetString(cfgRaftHeartbeatPort)
 	m.raftReplicatePort = cfg.GetString(cfgRaftReplicaPort)
 	m.zoneName = cfg.GetString(cfgZoneName)
+	m.labels = map[string]string{
+		proto.NodeLabelRack:  cfg.GetString(cfgRack),
+		proto.NodeLabelHost:  cfg.GetString(cfgHost),
+		proto.NodeLabelPower: cfg.GetString(cfgPowerDomain),
+	}
+	enablePosixACL = cfg.GetBool(cfgEnablePosixACL)
 	configTotalMem, _ = strconv.ParseUint(cfg.GetString(cfgTotalMem), 10, 64)
 
 	if configTotalMem == 0 {
@@ -181,6 +188,10 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 		updateDeleteBatchCount(uint64(deleteBatchCount))
 	}
 
+	if threshold := cfg.GetInt64(cfgDefragThreshold); threshold > 0 {
+		defragThreshold = uint64(threshold)
+	}
+
 	total, _, err := util.GetMemInfo()
 	if err == nil && configTotalMem > total-util.GB {
 		return fmt.Errorf("bad totalMem config,Recommended to be configured as 80 percent of physical machine memory")
@@ -220,6 +231,9 @@ func (m *MetaNode) parseConfig(cfg *config.Config) (err error) {
 	log.LogInfof("[parseConfig] load raftHeartbeatPort[%v].", m.raftHeartbeatPort)
 	log.LogInfof("[parseConfig] load raftReplicatePort[%v].", m.raftReplicatePort)
 	log.LogInfof("[parseConfig] load zoneName[%v].", m.zoneName)
+	log.LogInfof("[parseConfig] load labels[%v].", m.labels)
+	log.LogInfof("[parseConfig] load enablePosixACL[%v].", enablePosixACL)
+	log.LogInfof("[parseConfig] load defragExtentThreshold[%v].", defragThreshold)
 
 	addrs := cfg.GetSlice(proto.MasterAddr)
 	masters := make([]string, 0, len(addrs))
@@ -293,7 +307,7 @@ func (m *MetaNode) register() (err error) {
 			step++
 		}
 		var nodeID uint64
-		if nodeID, err = masterClient.NodeAPI().AddMetaNode(nodeAddress, m.zoneName); err != nil {
+		if nodeID, err = masterClient.NodeAPI().AddMetaNode(nodeAddress, m.zoneName, m.labels); err != nil {
 			log.LogErrorf("register: register to master fail: address(%v) err(%s)", nodeAddress, err)
 			time.Sleep(3 * time.Second)
 			continue
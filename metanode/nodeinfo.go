@@ -10,16 +10,21 @@ import (
 const (
 	UpdateNodeInfoTicket     = 1 * time.Minute
 	DefaultDeleteBatchCounts = 128
+	DefaultDefragThreshold   = 1024
 )
 
 type NodeInfo struct {
 	deleteBatchCount uint64
+	defragThreshold  uint64
 }
 
 var (
 	nodeInfo                   = &NodeInfo{}
 	nodeInfoStopC              = make(chan struct{}, 0)
 	deleteWorkerSleepMs uint64 = 0
+	// inodes with at least defragThreshold extent keys are reported as fragmented,
+	// unless the master sets the threshold of the cluster
+	defragThreshold uint64 = DefaultDefragThreshold
 )
 
 func DeleteBatchCount() uint64 {
@@ -34,6 +39,20 @@ func updateDeleteBatchCount(val uint64) {
 	atomic.StoreUint64(&nodeInfo.deleteBatchCount, val)
 }
 
+// DefragThreshold returns the extent count of the inodes reported to the clients as fragmented.
+// The metanode only reports them, the clients defragment the files.
+func DefragThreshold() uint64 {
+	val := atomic.LoadUint64(&nodeInfo.defragThreshold)
+	if val == 0 {
+		val = defragThreshold
+	}
+	return val
+}
+
+func updateDefragThreshold(val uint64) {
+	atomic.StoreUint64(&nodeInfo.defragThreshold, val)
+}
+
 func updateDeleteWorkerSleepMs(val uint64) {
 	atomic.StoreUint64(&deleteWorkerSleepMs, val)
 }
@@ -72,4 +91,5 @@ func (m *MetaNode) updateNodeInfo() {
 	}
 	updateDeleteBatchCount(clusterInfo.MetaNodeDeleteBatchCount)
 	updateDeleteWorkerSleepMs(clusterInfo.MetaNodeDeleteWorkerSleepMs)
+	updateDefragThreshold(clusterInfo.MetaNodeDefragThreshold)
 }
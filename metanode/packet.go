@@ -16,6 +16,7 @@ package metanode
 
 import (
 	"encoding/json"
+
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/storage"
 )
@@ -25,7 +26,9 @@ type Packet struct {
 }
 
 // NewPacketToDeleteExtent returns a new packet to delete the extent.
-func NewPacketToDeleteExtent(dp *DataPartition, ext *proto.ExtentKey) *Packet {
+// The id of the meta partition deleting it is carried in the kernel offset, by which the data nodes count the
+// deletions of the extents shared by the cloned volumes.
+func NewPacketToDeleteExtent(dp *DataPartition, ext *proto.ExtentKey, metaPartitionID uint64) *Packet {
 	p := new(Packet)
 	p.Magic = proto.ProtoMagic
 	p.Opcode = proto.OpMarkDelete
@@ -37,6 +40,7 @@ func NewPacketToDeleteExtent(dp *DataPartition, ext *proto.ExtentKey) *Packet {
 		p.Size = uint32(len(p.Data))
 	}
 	p.ExtentID = ext.ExtentId
+	p.KernelOffset = metaPartitionID
 	p.ReqID = proto.GenerateRequestID()
 	p.RemainingFollowers = uint8(len(dp.Hosts) - 1)
 	p.Arg = ([]byte)(dp.GetAllAddrs())
@@ -45,8 +49,50 @@ func NewPacketToDeleteExtent(dp *DataPartition, ext *proto.ExtentKey) *Packet {
 	return p
 }
 
+// NewPacketToReadExtent returns a new packet to read the data of the extent key from the leader.
+func NewPacketToReadExtent(ek *proto.ExtentKey) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpStreamRead
+	p.ExtentType = proto.NormalExtentType
+	p.PartitionID = ek.PartitionId
+	p.ExtentID = ek.ExtentId
+	p.ExtentOffset = int64(ek.ExtentOffset)
+	p.Size = ek.Size
+	p.ReqID = proto.GenerateRequestID()
+	return p
+}
+
+// NewPacketToGetInode returns a new packet to get the inode from the leader of the meta partition hosting it.
+func NewPacketToGetInode(volName string, partitionID, inode uint64) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpMetaInodeGet
+	p.PartitionID = partitionID
+	p.ReqID = proto.GenerateRequestID()
+	p.MarshalData(&proto.InodeGetRequest{VolName: volName, PartitionID: partitionID, Inode: inode})
+	return p
+}
+
+// NewPacketToWriteTinyExtent returns a new packet to write the data to a tiny extent allocated by the leader.
+func NewPacketToWriteTinyExtent(dp *DataPartition, data []byte) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpWrite
+	p.ExtentType = proto.TinyExtentType
+	p.PartitionID = dp.PartitionID
+	p.Data = data
+	p.Size = uint32(len(data))
+	p.CRC = proto.Checksum(dp.Checksum, data)
+	p.ReqID = proto.GenerateRequestID()
+	p.RemainingFollowers = uint8(len(dp.Hosts) - 1)
+	p.Arg = ([]byte)(dp.GetAllAddrs())
+	p.ArgLen = uint32(len(p.Arg))
+	return p
+}
+
 // NewPacketToBatchDeleteExtent returns a new packet to batch delete the extent.
-func NewPacketToBatchDeleteExtent(dp *DataPartition, exts []*proto.ExtentKey) *Packet {
+func NewPacketToBatchDeleteExtent(dp *DataPartition, exts []*proto.ExtentKey, metaPartitionID uint64) *Packet {
 	p := new(Packet)
 	p.Magic = proto.ProtoMagic
 	p.Opcode = proto.OpBatchDeleteExtent
@@ -54,6 +100,7 @@ func NewPacketToBatchDeleteExtent(dp *DataPartition, exts []*proto.ExtentKey) *P
 	p.PartitionID = uint64(dp.PartitionID)
 	p.Data, _ = json.Marshal(exts)
 	p.Size = uint32(len(p.Data))
+	p.KernelOffset = metaPartitionID
 	p.ReqID = proto.GenerateRequestID()
 	p.RemainingFollowers = uint8(len(dp.Hosts) - 1)
 	p.Arg = ([]byte)(dp.GetAllAddrs())
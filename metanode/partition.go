@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"fmt"
@@ -33,6 +34,7 @@ import (
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tier"
 	raftproto "github.com/tiglabs/raft/proto"
 )
 
@@ -146,6 +148,7 @@ type OpExtent interface {
 	ExtentsList(req *proto.GetExtentsRequest, p *Packet) (err error)
 	ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error)
 	BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error)
+	ExtentsReplace(req *proto.ReplaceExtentKeysRequest, p *Packet) (err error)
 }
 
 type OpMultipart interface {
@@ -181,6 +184,7 @@ type OpPartition interface {
 	TryToLeader(groupID uint64) error
 	CanRemoveRaftMember(peer proto.Peer) error
 	IsEquareCreateMetaPartitionRequst(request *proto.CreateMetaPartitionRequest) (err error)
+	ClonePartition(request *proto.CreateMetaPartitionRequest) (err error)
 }
 
 // MetaPartition defines the interface for the meta partition operations.
@@ -215,9 +219,13 @@ type metaPartition struct {
 	freeList               *freeList // free inode list
 	extDelCh               chan []proto.ExtentKey
 	extReset               chan struct{}
+	defragTargets          *defragTargets
 	vol                    *Vol
 	manager                *metadataManager
 	isLoadingMetaPartition bool
+	tierLock               sync.Mutex
+	tierPolicy             proto.TierPolicy // the policy with which tierStore is created
+	tierStore              tier.Store
 }
 
 func (mp *metaPartition) ForceSetMetaPartitionToLoadding() {
@@ -385,6 +393,7 @@ func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaP
 		freeList:      newFreeList(),
 		extDelCh:      make(chan []proto.ExtentKey, 10000),
 		extReset:      make(chan struct{}),
+		defragTargets: newDefragTargets(),
 		vol:           NewVol(),
 		manager:       manager,
 	}
@@ -692,3 +701,56 @@ func (mp *metaPartition) canRemoveSelf() (canRemove bool, err error) {
 	}
 	return
 }
+
+// getRemoteInode gets the inode from the leader of the meta partition of the volume hosting it.
+func (mp *metaPartition) getRemoteInode(inode uint64) (info *proto.InodeInfo, err error) {
+	views, err := masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	var view *proto.MetaPartitionView
+	for _, v := range views {
+		if inode >= v.Start && inode <= v.End {
+			view = v
+			break
+		}
+	}
+	if view == nil || view.LeaderAddr == "" {
+		err = errors.NewErrorf("no leader of the meta partition hosting inode(%v)", inode)
+		return
+	}
+	conn, err := mp.config.ConnPool.GetConnect(view.LeaderAddr)
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return
+	}
+	p := NewPacketToGetInode(mp.config.VolName, view.PartitionID, inode)
+	if err = p.WriteToConn(conn); err != nil {
+		err = errors.NewErrorf("write to metaNode %s, %s", p.GetUniqueLogId(), err.Error())
+		return
+	}
+	reply := new(Packet)
+	if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		err = errors.NewErrorf("read response from metaNode %s, %s", p.GetUniqueLogId(), err.Error())
+		return
+	}
+	if reply.ResultCode != proto.OpOk || reply.ReqID != p.ReqID {
+		err = errors.NewErrorf("get inode from metaNode %s response: %s", p.GetUniqueLogId(), reply.GetResultMsg())
+		return
+	}
+	resp := new(proto.InodeGetResponse)
+	if err = reply.UnmarshalData(resp); err != nil {
+		return
+	}
+	if resp.Info == nil {
+		err = errors.NewErrorf("get inode from metaNode %s: empty response", p.GetUniqueLogId())
+		return
+	}
+	return resp.Info, nil
+}
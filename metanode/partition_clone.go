@@ -0,0 +1,71 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// ClonePartition clones the meta partition into the meta partition of the cloned volume by the raft, so that each
+// replica creates the clone from the same state. The extent keys of the clone refer to the data of the source,
+// which are shared on the data nodes.
+func (mp *metaPartition) ClonePartition(request *proto.CreateMetaPartitionRequest) (err error) {
+	val, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+	resp, err := mp.submit(opFSMClonePartition, val)
+	if err != nil {
+		return
+	}
+	if status := resp.(uint8); status != proto.OpOk {
+		err = errors.NewErrorf("clone meta partition(%v) status(%v)", request.PartitionID, status)
+	}
+	return
+}
+
+// fsmClonePartition stores the snapshot of the trees as the snapshot of the clone, if the clone is on the node.
+// The apply of the partition is blocked until the snapshot is stored.
+func (mp *metaPartition) fsmClonePartition(request *proto.CreateMetaPartitionRequest) (status uint8) {
+	status = proto.OpOk
+	isMember := false
+	for _, peer := range request.Members {
+		if peer.ID == mp.config.NodeId {
+			isMember = true
+		}
+	}
+	if !isMember {
+		return
+	}
+	sm := &storeMsg{
+		command:       opFSMClonePartition,
+		inodeTree:     mp.getInodeTree(),
+		dentryTree:    mp.getDentryTree(),
+		extendTree:    mp.extendTree.GetTree(),
+		multipartTree: mp.multipartTree.GetTree(),
+	}
+	if err := mp.manager.clonePartition(request, mp.GetCursor(), sm); err != nil {
+		log.LogErrorf("action[fsmClonePartition] partition(%v) clone(%v) err(%v)", mp.config.PartitionId,
+			request.PartitionID, err)
+		return proto.OpErr
+	}
+	log.LogInfof("action[fsmClonePartition] partition(%v) cloned into partition(%v) of vol(%v)", mp.config.PartitionId,
+		request.PartitionID, request.VolName)
+	return
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	// the defragmentation of a range is done in seconds, the targets left by the clients which quit
+	// in the middle are forgotten after the expiration, and their extents are leaked.
+	defragTargetExpiration = time.Hour
+)
+
+type defragTarget struct {
+	inode       uint64
+	partitionID uint64
+	extentID    uint64
+}
+
+// defragTargets records the extents allocated by the clients to replace the extent keys of the inodes,
+// which are not committed yet. Only the extents recorded can be deleted if the replacement is abandoned.
+type defragTargets struct {
+	sync.Mutex
+	targets   map[defragTarget]time.Time
+	lastPrune time.Time
+}
+
+func newDefragTargets() *defragTargets {
+	return &defragTargets{targets: make(map[defragTarget]time.Time)}
+}
+
+func (d *defragTargets) add(inode uint64, ek *proto.ExtentKey) {
+	d.Lock()
+	defer d.Unlock()
+	now := time.Now()
+	if now.Sub(d.lastPrune) > defragTargetExpiration {
+		for target, added := range d.targets {
+			if now.Sub(added) > defragTargetExpiration {
+				delete(d.targets, target)
+			}
+		}
+		d.lastPrune = now
+	}
+	d.targets[defragTarget{inode: inode, partitionID: ek.PartitionId, extentID: ek.ExtentId}] = now
+}
+
+// remove forgets the target, and returns whether it is recorded for the inode.
+func (d *defragTargets) remove(inode uint64, ek *proto.ExtentKey) bool {
+	d.Lock()
+	defer d.Unlock()
+	target := defragTarget{inode: inode, partitionID: ek.PartitionId, extentID: ek.ExtentId}
+	added, ok := d.targets[target]
+	if !ok {
+		return false
+	}
+	delete(d.targets, target)
+	return time.Since(added) <= defragTargetExpiration
+}
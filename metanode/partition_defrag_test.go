@@ -0,0 +1,64 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestFsmDiscardExtent(t *testing.T) {
+	mp := &metaPartition{
+		config:        &MetaPartitionConfig{Start: 1, End: 100},
+		inodeTree:     NewBtree(),
+		extDelCh:      make(chan []proto.ExtentKey, 1),
+		defragTargets: newDefragTargets(),
+	}
+	ino := NewInode(2, 0644)
+	ino.AppendExtents([]proto.ExtentKey{{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 4096}}, 100)
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	other := NewInode(3, 0644)
+	mp.inodeTree.ReplaceOrInsert(other, true)
+	target := proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1026, Size: 4096}
+
+	tests := []struct {
+		name   string
+		req    *proto.ReplaceExtentKeysRequest
+		status uint8
+		delete bool
+	}{
+		{"unrecorded", &proto.ReplaceExtentKeysRequest{Inode: 2, Extent: target, Discard: true}, proto.OpNotPerm, false},
+		{"prepare", &proto.ReplaceExtentKeysRequest{Inode: 3, Extent: target, Prepare: true}, proto.OpOk, false},
+		{"other inode", &proto.ReplaceExtentKeysRequest{Inode: 2, Extent: target, Discard: true}, proto.OpNotPerm, false},
+		{"referred", &proto.ReplaceExtentKeysRequest{Inode: 2, Extent: ino.Extents.CopyExtents()[0], Discard: true}, proto.OpNotPerm, false},
+		{"recorded", &proto.ReplaceExtentKeysRequest{Inode: 3, Extent: target, Discard: true}, proto.OpOk, true},
+		{"twice", &proto.ReplaceExtentKeysRequest{Inode: 3, Extent: target, Discard: true}, proto.OpNotPerm, false},
+		{"prepare deleted", &proto.ReplaceExtentKeysRequest{Inode: 4, Extent: target, Prepare: true}, proto.OpNotExistErr, false},
+	}
+	for _, tt := range tests {
+		if status := mp.fsmReplaceExtents(tt.req); status != tt.status {
+			t.Fatalf("name[%v] expect status %v, actual %v", tt.name, tt.status, status)
+		}
+		if deleted := len(mp.extDelCh) == 1; deleted != tt.delete {
+			t.Fatalf("name[%v] expect delete %v, actual %v", tt.name, tt.delete, deleted)
+		}
+		if tt.delete {
+			if eks := <-mp.extDelCh; eks[0].ExtentId != target.ExtentId {
+				t.Fatalf("name[%v] unexpected deleted extents %v", tt.name, eks)
+			}
+		}
+	}
+}
@@ -47,6 +47,8 @@ func (mp *metaPartition) startFreeList() (err error) {
 	// start vol update ticket
 	go mp.updateVolWorker()
 	go mp.deleteWorker()
+	go mp.tierWorker()
+	go mp.relocateWorker()
 	mp.startToDeleteExtents()
 	return
 }
@@ -76,6 +78,9 @@ func (mp *metaPartition) updateVolWorker() {
 				Status:      view.DataPartitions[i].Status,
 				Hosts:       view.DataPartitions[i].Hosts,
 				ReplicaNum:  view.DataPartitions[i].ReplicaNum,
+				Checksum:    view.DataPartitions[i].Checksum,
+
+				CompactingTinyExtents: view.DataPartitions[i].CompactingTinyExtents,
 			}
 		}
 		return newView
@@ -254,6 +259,7 @@ func (mp *metaPartition) deleteMarkedInodes(inoSlice []uint64) {
 	}
 	for _, inode := range shouldCommit {
 		if err == nil {
+			mp.deleteInodeTierObjects(inode.Inode)
 			mp.internalDeleteInode(inode)
 		} else {
 			mp.freeList.Push(inode.Inode)
@@ -275,7 +281,7 @@ func (mp *metaPartition) syncToRaftFollowersFreeInode(hasDeleteInodes []byte) (e
 }
 
 func (mp *metaPartition) notifyRaftFollowerToFreeInodes(wg *sync.WaitGroup, target string, hasDeleteInodes []byte) (err error) {
-	var conn *net.TCPConn
+	var conn net.Conn
 	conn, err = mp.config.ConnPool.GetConnect(target)
 	defer func() {
 		wg.Done()
@@ -330,7 +336,7 @@ func (mp *metaPartition) doDeleteMarkedInodes(ext *proto.ExtentKey) (err error)
 			err.Error(), ext.PartitionId, ext.ExtentId)
 		return
 	}
-	p := NewPacketToDeleteExtent(dp, ext)
+	p := NewPacketToDeleteExtent(dp, ext, mp.config.PartitionId)
 	if err = p.WriteToConn(conn); err != nil {
 		err = errors.NewErrorf("write to dataNode %s, %s", p.GetUniqueLogId(),
 			err.Error())
@@ -380,7 +386,7 @@ func (mp *metaPartition) doBatchDeleteExtentsByPartition(partitionID uint64, ext
 			err.Error(), partitionID)
 		return
 	}
-	p := NewPacketToBatchDeleteExtent(dp, exts)
+	p := NewPacketToBatchDeleteExtent(dp, exts, mp.config.PartitionId)
 	if err = p.WriteToConn(conn); err != nil {
 		err = errors.NewErrorf("write to dataNode %s, %s", p.GetUniqueLogId(),
 			err.Error())
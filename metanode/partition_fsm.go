@@ -56,6 +56,18 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			mp.config.Cursor = ino.Inode
 		}
 		resp = mp.fsmCreateInode(ino)
+	case opFSMCreateInodeWithACL:
+		var (
+			ino    *Inode
+			extend *Extend
+		)
+		if ino, extend, err = unmarshalInodeWithACL(msg.V); err != nil {
+			return
+		}
+		if mp.config.Cursor < ino.Inode {
+			mp.config.Cursor = ino.Inode
+		}
+		resp = mp.fsmCreateInodeWithACL(ino, extend)
 	case opFSMUnlinkInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
@@ -99,6 +111,30 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmSetAttr(req)
+	case opFSMExtentsReplace:
+		req := &proto.ReplaceExtentKeysRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmReplaceExtents(req)
+	case opFSMExtentRelocate:
+		req := &proto.RelocateExtentKeyRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmRelocateExtent(req)
+	case opFSMTierExtents:
+		req := &proto.TierExtentsRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmTierExtents(req)
+	case opFSMClonePartition:
+		req := &proto.CreateMetaPartitionRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp = mp.fsmClonePartition(req)
 	case opFSMCreateDentry:
 		den := &Dentry{}
 		if err = den.Unmarshal(msg.V); err != nil {
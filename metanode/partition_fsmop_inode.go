@@ -42,6 +42,15 @@ func (mp *metaPartition) fsmCreateInode(ino *Inode) (status uint8) {
 	return
 }
 
+// fsmCreateInodeWithACL creates the inode together with the ACL inherited from the parent directory,
+// so the inode is never seen without it.
+func (mp *metaPartition) fsmCreateInodeWithACL(ino *Inode, extend *Extend) (status uint8) {
+	if status = mp.fsmCreateInode(ino); status == proto.OpOk {
+		mp.fsmSetXAttr(extend)
+	}
+	return
+}
+
 func (mp *metaPartition) fsmCreateLinkInode(ino *Inode) (resp *InodeResponse) {
 	resp = NewInodeResponse()
 	resp.Status = proto.OpOk
@@ -219,6 +228,85 @@ func (mp *metaPartition) fsmAppendExtents(ino *Inode) (status uint8) {
 	return
 }
 
+func (mp *metaPartition) fsmReplaceExtents(req *proto.ReplaceExtentKeysRequest) (status uint8) {
+	status = proto.OpOk
+	var ino *Inode
+	if item := mp.inodeTree.CopyGet(NewInode(req.Inode, 0)); item != nil {
+		ino = item.(*Inode)
+	}
+	if req.Prepare {
+		if ino == nil || ino.ShouldDelete() {
+			status = proto.OpNotExistErr
+			return
+		}
+		mp.defragTargets.add(req.Inode, &req.Extent)
+		return
+	}
+	if req.Discard {
+		if !mp.discardExtent(req.Inode, ino, req.Extent) {
+			status = proto.OpNotPerm
+		}
+		return
+	}
+	if ino == nil || ino.ShouldDelete() {
+		mp.discardExtent(req.Inode, ino, req.Extent)
+		status = proto.OpNotExistErr
+		return
+	}
+	delExtents, ok := ino.ReplaceExtents(req.OldExtents, req.Extent)
+	if !ok {
+		// the extent keys have been modified since the defragmentation began,
+		// so the extent written to replace them is of no use
+		mp.discardExtent(req.Inode, ino, req.Extent)
+		status = proto.OpArgMismatchErr
+		return
+	}
+	mp.defragTargets.remove(req.Inode, &req.Extent)
+	log.LogInfof("fsmReplaceExtents inode(%v) ek(%v) exts(%v)", ino.Inode, req.Extent, delExtents)
+	mp.extDelCh <- delExtents
+	return
+}
+
+// discardExtent deletes the extent written for a replacement which is not committed, if it is recorded
+// as a replacement target of the inode. The extent is kept if the inode refers to it, which happens if the
+// replacement has been committed by a former request. It returns false if the extent is not recorded.
+func (mp *metaPartition) discardExtent(inode uint64, ino *Inode, ek proto.ExtentKey) bool {
+	if !mp.defragTargets.remove(inode, &ek) {
+		log.LogWarnf("discardExtent: ek(%v) is not a replacement target of inode(%v)", ek, inode)
+		return false
+	}
+	if ino != nil && ino.HasExtent(ek.PartitionId, ek.ExtentId) {
+		return true
+	}
+	log.LogInfof("discardExtent ek(%v)", ek)
+	mp.extDelCh <- []proto.ExtentKey{ek}
+	return true
+}
+
+func (mp *metaPartition) fsmRelocateExtent(req *proto.RelocateExtentKeyRequest) (status uint8) {
+	status = proto.OpOk
+	item := mp.inodeTree.CopyGet(NewInode(req.Inode, 0))
+	if item == nil {
+		status = proto.OpNotExistErr
+		return
+	}
+	ino := item.(*Inode)
+	if ino.ShouldDelete() {
+		status = proto.OpNotExistErr
+		return
+	}
+	if _, ok := ino.ReplaceExtents([]proto.ExtentKey{req.OldExtent}, req.Extent); !ok {
+		// the extent key has been modified since the relocation began
+		status = proto.OpArgMismatchErr
+		return
+	}
+	log.LogInfof("fsmRelocateExtent inode(%v) oek(%v) ek(%v)", ino.Inode, req.OldExtent, req.Extent)
+	// the data of a tiny extent is deleted by the range of the extent key,
+	// even if the other extent keys refer to the same extent
+	mp.extDelCh <- []proto.ExtentKey{req.OldExtent}
+	return
+}
+
 func (mp *metaPartition) fsmExtentsTruncate(ino *Inode) (resp *InodeResponse) {
 	resp = NewInodeResponse()
 
@@ -303,5 +391,8 @@ func (mp *metaPartition) fsmSetAttr(req *SetattrRequest) (err error) {
 		return
 	}
 	ino.SetAttr(req)
+	if req.Valid&proto.AttrMode != 0 {
+		mp.chmodACL(req.Inode, req.Mode)
+	}
 	return
 }
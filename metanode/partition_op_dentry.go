@@ -35,6 +35,10 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 		Inode:    req.Inode,
 		Type:     req.Mode,
 	}
+	if status := mp.checkPermission(req.ParentID, req.Cred, permWrite|permExec); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	val, err := dentry.Marshal()
 	if err != nil {
 		return
@@ -50,6 +54,10 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err error) {
+	if status := mp.checkDeletePermission(req.ParentID, req.Name, req.Cred); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
@@ -80,6 +88,12 @@ func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet) (err error) {
+	for _, d := range req.Dens {
+		if status := mp.checkDeletePermission(req.ParentID, d.Name, req.Cred); status != proto.OpOk {
+			p.PacketErrorWithBody(status, nil)
+			return
+		}
+	}
 
 	db := make(DentryBatch, 0, len(req.Dens))
 
@@ -155,6 +169,10 @@ func (mp *metaPartition) UpdateDentry(req *UpdateDentryReq, p *Packet) (err erro
 		Name:     req.Name,
 		Inode:    req.Inode,
 	}
+	if status := mp.checkDeletePermission(req.ParentID, req.Name, req.Cred); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	val, err := dentry.Marshal()
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
@@ -180,6 +198,10 @@ func (mp *metaPartition) UpdateDentry(req *UpdateDentryReq, p *Packet) (err erro
 
 // ReadDir reads the directory based on the given request.
 func (mp *metaPartition) ReadDir(req *ReadDirReq, p *Packet) (err error) {
+	if status := mp.checkPermission(req.ParentID, req.Cred, permRead); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	resp := mp.readDir(req)
 	reply, err := json.Marshal(resp)
 	if err != nil {
@@ -192,6 +214,10 @@ func (mp *metaPartition) ReadDir(req *ReadDirReq, p *Packet) (err error) {
 
 // Lookup looks up the given dentry from the request.
 func (mp *metaPartition) Lookup(req *LookupReq, p *Packet) (err error) {
+	if status := mp.checkPermission(req.ParentID, req.Cred, permExec); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
@@ -21,6 +21,16 @@ import (
 )
 
 func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err error) {
+	if status := mp.checkXAttrPermission(req.Inode, req.Key, req.Cred); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
+	if isACLXAttr(req.Key) && len(req.Value) > 0 {
+		if _, err = ParsePosixACL([]byte(req.Value)); err != nil {
+			p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+			return
+		}
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), []byte(req.Value))
 	if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
@@ -87,6 +97,10 @@ func (mp *metaPartition) BatchGetXAttr(req *proto.BatchGetXAttrRequest, p *Packe
 }
 
 func (mp *metaPartition) RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (err error) {
+	if status := mp.checkXAttrPermission(req.Inode, req.Key, req.Cred); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), nil)
 	if _, err = mp.putExtend(opFSMRemoveXAttr, extend); err != nil {
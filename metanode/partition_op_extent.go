@@ -16,6 +16,7 @@ package metanode
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -23,6 +24,10 @@ import (
 
 // ExtentAppend appends an extent.
 func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Packet) (err error) {
+	if status := mp.checkPermission(req.Inode, req.Cred, permWrite); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	ext := req.Extent
 	ino.Extents.Append(ext)
@@ -40,6 +45,54 @@ func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Pack
 	return
 }
 
+// ExtentsReplace replaces a contiguous range of extent keys with a single one.
+func (mp *metaPartition) ExtentsReplace(req *proto.ReplaceExtentKeysRequest, p *Packet) (err error) {
+	if status := mp.checkPermission(req.Inode, req.Cred, permWrite); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
+	if err = checkReplaceExtents(req); err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	val, err := json.Marshal(req)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMExtentsReplace, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// The old extent keys must be contiguous and covered by the new extent key exactly.
+func checkReplaceExtents(req *proto.ReplaceExtentKeysRequest) error {
+	if req.Prepare && req.Discard {
+		return fmt.Errorf("both prepare and discard")
+	}
+	if req.Prepare || req.Discard {
+		return nil
+	}
+	if len(req.OldExtents) == 0 {
+		return fmt.Errorf("no extent keys to be replaced")
+	}
+	offset := req.Extent.FileOffset
+	for _, ek := range req.OldExtents {
+		if ek.FileOffset != offset {
+			return fmt.Errorf("extent keys are not contiguous at offset(%v)", offset)
+		}
+		offset += uint64(ek.Size)
+	}
+	if offset != req.Extent.FileOffset+uint64(req.Extent.Size) {
+		return fmt.Errorf("extent key(%v) does not match the replaced range", req.Extent)
+	}
+	return nil
+}
+
 // ExtentsList returns the list of extents.
 func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (err error) {
 	ino := NewInode(req.Inode, 0)
@@ -59,6 +112,7 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 				return true
 			})
 		})
+		resp.Tiers = mp.getTierExtents(ino.Inode)
 		reply, err = json.Marshal(resp)
 		if err != nil {
 			status = proto.OpErr
@@ -71,6 +125,10 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 
 // ExtentsTruncate truncates an extent.
 func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error) {
+	if status := mp.checkPermission(req.Inode, req.Cred, permWrite); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	ino := NewInode(req.Inode, proto.Mode(os.ModePerm))
 	ino.Size = req.Size
 	val, err := ino.Marshal()
@@ -89,6 +147,10 @@ func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (er
 }
 
 func (mp *metaPartition) BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error) {
+	if status := mp.checkPermission(req.Inode, req.Cred, permWrite); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	extents := req.Extents
 	for _, extent := range extents {
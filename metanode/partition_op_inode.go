@@ -56,12 +56,26 @@ func (mp *metaPartition) CreateInode(req *CreateInoReq, p *Packet) (err error) {
 	ino.Uid = req.Uid
 	ino.Gid = req.Gid
 	ino.LinkTarget = req.Target
-	val, err := ino.Marshal()
+	extend, err := inheritACL(ino, req.DefaultACL)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, []byte(err.Error()))
+		return
+	}
+	var (
+		op  = opFSMCreateInode
+		val []byte
+	)
+	if extend != nil {
+		op = opFSMCreateInodeWithACL
+		val, err = marshalInodeWithACL(ino, extend)
+	} else {
+		val, err = ino.Marshal()
+	}
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
 		return
 	}
-	resp, err := mp.submit(opFSMCreateInode, val)
+	resp, err := mp.submit(op, val)
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
 		return
@@ -306,6 +320,15 @@ func (mp *metaPartition) EvictInodeBatch(req *BatchEvictInodeReq, p *Packet) (er
 
 // SetAttr set the inode attributes.
 func (mp *metaPartition) SetAttr(reqData []byte, p *Packet) (err error) {
+	req := &SetattrRequest{}
+	if err = json.Unmarshal(reqData, req); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	if status := mp.checkSetAttrPermission(req); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	_, err = mp.submit(opFSMSetAttr, reqData)
 	if err != nil {
 		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
@@ -0,0 +1,187 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	RelocateCheckInterval = 10 * time.Minute
+)
+
+type relocateTask struct {
+	inode uint64
+	ek    proto.ExtentKey
+}
+
+// relocateWorker relocates the data of the tiny extents compacting on the data nodes periodically.
+func (mp *metaPartition) relocateWorker() {
+	t := time.NewTicker(RelocateCheckInterval)
+	for {
+		select {
+		case <-mp.stopC:
+			t.Stop()
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				continue
+			}
+			mp.relocateTinyExtents()
+		}
+	}
+}
+
+func (mp *metaPartition) relocateTinyExtents() {
+	tasks := make([]relocateTask, 0)
+	mp.inodeTree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		if !proto.IsRegular(ino.Type) || ino.ShouldDelete() {
+			return true
+		}
+		ino.Extents.Range(func(ek proto.ExtentKey) bool {
+			if !storage.IsTinyExtent(ek.ExtentId) {
+				return true
+			}
+			if dp := mp.vol.GetPartition(ek.PartitionId); dp != nil && dp.IsTinyExtentCompacting(ek.ExtentId) {
+				tasks = append(tasks, relocateTask{inode: ino.Inode, ek: ek})
+			}
+			return true
+		})
+		return true
+	})
+	if len(tasks) == 0 {
+		return
+	}
+
+	var relocated int
+	for _, task := range tasks {
+		if _, ok := mp.IsLeader(); !ok {
+			return
+		}
+		if err := mp.relocateExtentKey(task.inode, task.ek); err != nil {
+			log.LogWarnf("relocateTinyExtents: mp(%v) ino(%v) ek(%v) err(%v)", mp.config.PartitionId, task.inode, task.ek, err)
+			continue
+		}
+		relocated++
+	}
+	log.LogInfof("relocateTinyExtents: mp(%v) relocated(%v/%v) extent keys", mp.config.PartitionId, relocated, len(tasks))
+}
+
+// relocateExtentKey copies the data of the extent key to a new tiny extent location, and replaces the extent key
+// of the inode with the new one. The old range is deleted through the extent deletion of the partition.
+func (mp *metaPartition) relocateExtentKey(inoID uint64, ek proto.ExtentKey) (err error) {
+	item := mp.inodeTree.Get(NewInode(inoID, 0))
+	if item == nil {
+		return
+	}
+	ino := item.(*Inode)
+	var modifyTime int64
+	ino.DoReadFunc(func() {
+		modifyTime = ino.ModifyTime
+	})
+	data, err := mp.readExtent(&ek)
+	if err != nil {
+		return
+	}
+	newEk, err := mp.writeTinyExtent(&ek, data)
+	if err != nil {
+		return
+	}
+
+	// The data may be overwritten in place while it is copied, in which case
+	// the copy is discarded and the extent key is relocated in the next round.
+	var modified bool
+	ino.DoReadFunc(func() {
+		modified = ino.ModifyTime != modifyTime
+	})
+	if modified {
+		mp.extDelCh <- []proto.ExtentKey{newEk}
+		return errors.NewErrorf("inode modified during the relocation")
+	}
+
+	// the relocation is issued by the meta node itself on behalf of the super user
+	req := &proto.RelocateExtentKeyRequest{
+		Inode:     inoID,
+		OldExtent: ek,
+		Extent:    newEk,
+		Cred:      &proto.UserCred{},
+	}
+	if status := mp.checkPermission(req.Inode, req.Cred, permWrite); status != proto.OpOk {
+		mp.extDelCh <- []proto.ExtentKey{newEk}
+		return errors.NewErrorf("relocate status(%v)", status)
+	}
+	val, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	resp, err := mp.submit(opFSMExtentRelocate, val)
+	if err == nil && resp.(uint8) != proto.OpOk {
+		err = errors.NewErrorf("relocate status(%v)", resp)
+	}
+	if err != nil {
+		mp.extDelCh <- []proto.ExtentKey{newEk}
+	}
+	return
+}
+
+// writeTinyExtent writes the data of the extent key to a tiny extent allocated by the leader of the data partition,
+// and returns the new extent key.
+func (mp *metaPartition) writeTinyExtent(ek *proto.ExtentKey, data []byte) (newEk proto.ExtentKey, err error) {
+	dp := mp.vol.GetPartition(ek.PartitionId)
+	if dp == nil {
+		err = errors.NewErrorf("unknown dataPartitionID=%d in vol", ek.PartitionId)
+		return
+	}
+	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return
+	}
+	p := NewPacketToWriteTinyExtent(dp, data)
+	if err = p.WriteToConn(conn); err != nil {
+		err = errors.NewErrorf("write to dataNode %s, %s", p.GetUniqueLogId(), err.Error())
+		return
+	}
+	reply := new(Packet)
+	if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		err = errors.NewErrorf("read response from dataNode %s, %s", p.GetUniqueLogId(), err.Error())
+		return
+	}
+	if reply.ResultCode != proto.OpOk || reply.ReqID != p.ReqID {
+		err = errors.NewErrorf("write to dataNode %s response: %s", p.GetUniqueLogId(), reply.GetResultMsg())
+		return
+	}
+	if !storage.IsTinyExtent(reply.ExtentID) || reply.ExtentID == ek.ExtentId {
+		err = errors.NewErrorf("write to dataNode %s: invalid extent(%v)", p.GetUniqueLogId(), reply.ExtentID)
+		return
+	}
+	newEk = *ek
+	newEk.ExtentId = reply.ExtentID
+	newEk.ExtentOffset = uint64(reply.ExtentOffset)
+	return
+}
@@ -0,0 +1,417 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tier"
+)
+
+const (
+	TierCheckInterval = time.Hour
+)
+
+// tierWorker migrates the extents of the cold files to the tier store of the vol periodically.
+func (mp *metaPartition) tierWorker() {
+	t := time.NewTicker(TierCheckInterval)
+	for {
+		select {
+		case <-mp.stopC:
+			t.Stop()
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				continue
+			}
+			mp.tierColdExtents()
+		}
+	}
+}
+
+// getTierStore returns the tier store of the vol, or nil if the vol has never configured one.
+// The policy is fetched from the master if refresh is true or there is no store yet.
+func (mp *metaPartition) getTierStore(refresh bool) (store tier.Store, policy proto.TierPolicy, err error) {
+	mp.tierLock.Lock()
+	defer mp.tierLock.Unlock()
+	if mp.tierStore != nil && !refresh {
+		return mp.tierStore, mp.tierPolicy, nil
+	}
+	view, err := masterClient.AdminAPI().GetVolumeSimpleInfo(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	if view.Tier == nil {
+		return
+	}
+	// the keys of the store are not in the simple view
+	vv, err := masterClient.ClientAPI().GetVolumeWithoutAuthKey(mp.config.VolName)
+	if err != nil {
+		return
+	}
+	if vv.TierSecure != nil {
+		view.Tier.AccessKey, view.Tier.SecretKey = vv.TierSecure.AccessKey, vv.TierSecure.SecretKey
+	}
+	if mp.tierStore == nil || *view.Tier != mp.tierPolicy {
+		if store, err = tier.NewStore(view.Tier); err != nil {
+			return
+		}
+		mp.tierStore = store
+		mp.tierPolicy = *view.Tier
+	}
+	return mp.tierStore, mp.tierPolicy, nil
+}
+
+func (mp *metaPartition) tierColdExtents() {
+	store, policy, err := mp.getTierStore(true)
+	if err != nil {
+		log.LogErrorf("tierColdExtents: mp(%v) get tier store err(%v)", mp.config.PartitionId, err)
+		return
+	}
+	if store == nil {
+		return
+	}
+
+	// remove the objects of the extent keys no longer referenced by the inodes
+	tiered := make([]uint64, 0)
+	mp.extendTree.Ascend(func(i BtreeItem) bool {
+		if _, exist := i.(*Extend).Get([]byte(proto.XAttrKeyTier)); exist {
+			tiered = append(tiered, i.(*Extend).inode)
+		}
+		return true
+	})
+	for _, ino := range tiered {
+		if err = mp.reconcileTierExtents(store, ino); err != nil {
+			log.LogWarnf("tierColdExtents: mp(%v) reconcile ino(%v) err(%v)", mp.config.PartitionId, ino, err)
+		}
+	}
+
+	if !policy.Enabled() {
+		return
+	}
+	coldTime := time.Now().Unix() - int64(policy.ColdDays)*24*3600
+	cold := make([]uint64, 0)
+	mp.inodeTree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		ino.DoReadFunc(func() {
+			if proto.IsRegular(ino.Type) && !ino.ShouldDelete() && ino.Extents.Len() > 0 &&
+				ino.AccessTime < coldTime && ino.ModifyTime < coldTime {
+				cold = append(cold, ino.Inode)
+			}
+		})
+		return true
+	})
+	log.LogDebugf("tierColdExtents: mp(%v) cold inodes(%v) tiered inodes(%v)", mp.config.PartitionId, len(cold), len(tiered))
+
+	for _, ino := range cold {
+		if _, ok := mp.IsLeader(); !ok {
+			return
+		}
+		if err = mp.tierInode(store, ino); err != nil {
+			log.LogWarnf("tierColdExtents: mp(%v) ino(%v) err(%v)", mp.config.PartitionId, ino, err)
+		}
+	}
+}
+
+// tierInode uploads the extent keys of the inode to the tier store, records them in the
+// xattr of the inode, and then deletes the extents which are completely migrated.
+func (mp *metaPartition) tierInode(store tier.Store, inoID uint64) (err error) {
+	item := mp.inodeTree.Get(NewInode(inoID, 0))
+	if item == nil {
+		return
+	}
+	ino := item.(*Inode)
+	var modifyTime int64
+	ino.DoReadFunc(func() {
+		modifyTime = ino.ModifyTime
+	})
+	eks := ino.Extents.CopyExtents()
+	tiers := mp.getTierExtents(inoID)
+
+	added := make([]proto.TierExtent, 0)
+	for i := range eks {
+		ek := &eks[i]
+		if storage.IsTinyExtent(ek.ExtentId) || findTierExtent(tiers, ek) != nil {
+			continue
+		}
+		var data []byte
+		if data, err = mp.readExtent(ek); err != nil {
+			break
+		}
+		key := tier.ObjectKey(mp.config.VolName, inoID, ek)
+		if err = store.Put(key, data); err != nil {
+			break
+		}
+		added = append(added, proto.TierExtent{
+			PartitionId:  ek.PartitionId,
+			ExtentId:     ek.ExtentId,
+			ExtentOffset: ek.ExtentOffset,
+			Size:         ek.Size,
+			Key:          key,
+			Crcs:         tier.BlockCrcs(data),
+		})
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	// The data may be overwritten in place while it is uploaded, in which case the extent keys
+	// or the modify time differ, the objects are discarded and the file is migrated in the next round.
+	req := &proto.TierExtentsRequest{
+		Inode:      inoID,
+		ModifyTime: modifyTime,
+		Extents:    eks,
+		Tiers:      added,
+	}
+	val, e := json.Marshal(req)
+	if e != nil {
+		deleteTierObjects(store, added)
+		return e
+	}
+	resp, e := mp.submit(opFSMTierExtents, val)
+	if e == nil && resp.(uint8) != proto.OpOk {
+		e = errors.NewErrorf("tier status(%v)", resp)
+	}
+	if e != nil {
+		deleteTierObjects(store, added)
+		return e
+	}
+	log.LogInfof("tierInode: mp(%v) ino(%v) tiered(%v)", mp.config.PartitionId, inoID, len(added))
+	return
+}
+
+// fsmTierExtents records the tier extents in the xattr of the inode and deletes the extents migrated completely,
+// only if the inode is not modified since the extent keys are migrated.
+func (mp *metaPartition) fsmTierExtents(req *proto.TierExtentsRequest) (status uint8) {
+	status = proto.OpOk
+	item := mp.inodeTree.CopyGet(NewInode(req.Inode, 0))
+	if item == nil {
+		status = proto.OpNotExistErr
+		return
+	}
+	ino := item.(*Inode)
+	if ino.ShouldDelete() {
+		status = proto.OpNotExistErr
+		return
+	}
+	var modifyTime int64
+	ino.DoReadFunc(func() {
+		modifyTime = ino.ModifyTime
+	})
+	eks := ino.Extents.CopyExtents()
+	if modifyTime != req.ModifyTime || !equalExtentKeys(eks, req.Extents) {
+		status = proto.OpArgMismatchErr
+		return
+	}
+	tiers := append(mp.getTierExtents(req.Inode), req.Tiers...)
+	raw, err := json.Marshal(tiers)
+	if err != nil {
+		status = proto.OpErr
+		return
+	}
+	extend := NewExtend(req.Inode)
+	extend.Put([]byte(proto.XAttrKeyTier), raw)
+	mp.fsmSetXAttr(extend)
+	delExtents := tieredExtents(eks, tiers, req.Tiers)
+	log.LogInfof("fsmTierExtents inode(%v) tiered(%v) deleted extents(%v)", req.Inode, len(req.Tiers), delExtents)
+	if len(delExtents) > 0 {
+		mp.extDelCh <- delExtents
+	}
+	return
+}
+
+func equalExtentKeys(a, b []proto.ExtentKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileTierExtents removes the tier extents which are no longer referenced by the extent keys of the inode.
+func (mp *metaPartition) reconcileTierExtents(store tier.Store, inoID uint64) (err error) {
+	item := mp.inodeTree.Get(NewInode(inoID, 0))
+	if item == nil {
+		return
+	}
+	eks := item.(*Inode).Extents.CopyExtents()
+	tiers := mp.getTierExtents(inoID)
+	valid := make([]proto.TierExtent, 0, len(tiers))
+	stale := make([]proto.TierExtent, 0)
+	for _, t := range tiers {
+		referenced := false
+		for i := range eks {
+			if t.Contains(&eks[i]) {
+				referenced = true
+				break
+			}
+		}
+		if referenced {
+			valid = append(valid, t)
+		} else {
+			stale = append(stale, t)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	if err = mp.putTierExtents(inoID, valid); err != nil {
+		return
+	}
+	deleteTierObjects(store, stale)
+	return
+}
+
+// deleteInodeTierObjects removes the objects of the inode, which is to be freed.
+func (mp *metaPartition) deleteInodeTierObjects(inoID uint64) {
+	tiers := mp.getTierExtents(inoID)
+	if len(tiers) == 0 {
+		return
+	}
+	store, _, err := mp.getTierStore(false)
+	if err != nil || store == nil {
+		log.LogErrorf("deleteInodeTierObjects: mp(%v) ino(%v) tiers(%v) err(%v)", mp.config.PartitionId, inoID, len(tiers), err)
+		return
+	}
+	deleteTierObjects(store, tiers)
+}
+
+func deleteTierObjects(store tier.Store, tiers []proto.TierExtent) {
+	for _, t := range tiers {
+		if err := store.Delete(t.Key); err != nil {
+			log.LogWarnf("deleteTierObjects: key(%v) err(%v)", t.Key, err)
+		}
+	}
+}
+
+// getTierExtents returns the tier extents recorded in the xattr of the inode.
+func (mp *metaPartition) getTierExtents(inoID uint64) (tiers []proto.TierExtent) {
+	item := mp.extendTree.Get(NewExtend(inoID))
+	if item == nil {
+		return
+	}
+	raw, exist := item.(*Extend).Get([]byte(proto.XAttrKeyTier))
+	if !exist || len(raw) == 0 {
+		return
+	}
+	if err := json.Unmarshal(raw, &tiers); err != nil {
+		log.LogErrorf("getTierExtents: mp(%v) ino(%v) err(%v)", mp.config.PartitionId, inoID, err)
+		return nil
+	}
+	return
+}
+
+func (mp *metaPartition) putTierExtents(inoID uint64, tiers []proto.TierExtent) (err error) {
+	var (
+		extend = NewExtend(inoID)
+		op     = uint32(opFSMSetXAttr)
+		raw    []byte
+	)
+	if len(tiers) == 0 {
+		op = opFSMRemoveXAttr
+	} else if raw, err = json.Marshal(tiers); err != nil {
+		return
+	}
+	extend.Put([]byte(proto.XAttrKeyTier), raw)
+	_, err = mp.putExtend(op, extend)
+	return
+}
+
+// readExtent reads the data of the extent key from the leader of the data partition.
+func (mp *metaPartition) readExtent(ek *proto.ExtentKey) (data []byte, err error) {
+	dp := mp.vol.GetPartition(ek.PartitionId)
+	if dp == nil {
+		err = errors.NewErrorf("unknown dataPartitionID=%d in vol", ek.PartitionId)
+		return
+	}
+	conn, err := mp.config.ConnPool.GetConnect(dp.Hosts[0])
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	if err != nil {
+		return
+	}
+	p := NewPacketToReadExtent(ek)
+	if err = p.WriteToConn(conn); err != nil {
+		err = errors.NewErrorf("write to dataNode %s, %s", p.GetUniqueLogId(), err.Error())
+		return
+	}
+	data = make([]byte, 0, ek.Size)
+	for uint32(len(data)) < ek.Size {
+		reply := new(Packet)
+		if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			err = errors.NewErrorf("read response from dataNode %s, %s", p.GetUniqueLogId(), err.Error())
+			return
+		}
+		if reply.ResultCode != proto.OpOk || reply.ReqID != p.ReqID {
+			err = errors.NewErrorf("read from dataNode %s response: %s", p.GetUniqueLogId(), reply.GetResultMsg())
+			return
+		}
+		if reply.Size == 0 || proto.Checksum(dp.Checksum, reply.Data[:reply.Size]) != reply.CRC {
+			err = errors.NewErrorf("read from dataNode %s: invalid reply size(%v) or crc", p.GetUniqueLogId(), reply.Size)
+			return
+		}
+		data = append(data, reply.Data[:reply.Size]...)
+	}
+	return
+}
+
+// findTierExtent returns the tier extent holding the data of the extent key, or nil if the key is not tiered.
+func findTierExtent(tiers []proto.TierExtent, ek *proto.ExtentKey) *proto.TierExtent {
+	for i := range tiers {
+		if tiers[i].Contains(ek) {
+			return &tiers[i]
+		}
+	}
+	return nil
+}
+
+// tieredExtents returns the extents of the extent keys which are all tiered.
+func tieredExtents(eks []proto.ExtentKey, tiers []proto.TierExtent, added []proto.TierExtent) (delExtents []proto.ExtentKey) {
+	delExtents = make([]proto.ExtentKey, 0)
+	deleted := make(map[string]bool)
+	for _, t := range added {
+		ek := proto.ExtentKey{PartitionId: t.PartitionId, ExtentId: t.ExtentId}
+		if deleted[ek.GetExtentKey()] || !isExtentTiered(eks, tiers, t.PartitionId, t.ExtentId) {
+			continue
+		}
+		deleted[ek.GetExtentKey()] = true
+		delExtents = append(delExtents, ek)
+	}
+	return
+}
+
+// isExtentTiered returns if all the extent keys referring to the extent are tiered.
+func isExtentTiered(eks []proto.ExtentKey, tiers []proto.TierExtent, partitionID, extentID uint64) bool {
+	for i := range eks {
+		if eks[i].PartitionId == partitionID && eks[i].ExtentId == extentID && findTierExtent(tiers, &eks[i]) == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestIsExtentTiered(t *testing.T) {
+	eks := []proto.ExtentKey{
+		{FileOffset: 0, PartitionId: 1, ExtentId: 1025, ExtentOffset: 0, Size: 4096},
+		{FileOffset: 4096, PartitionId: 1, ExtentId: 1025, ExtentOffset: 8192, Size: 4096},
+		{FileOffset: 8192, PartitionId: 2, ExtentId: 1026, ExtentOffset: 0, Size: 4096},
+	}
+	tiers := []proto.TierExtent{
+		{PartitionId: 1, ExtentId: 1025, ExtentOffset: 0, Size: 4096, Key: "vol/1/1_1025_0"},
+	}
+	if findTierExtent(tiers, &eks[0]) == nil || findTierExtent(tiers, &eks[1]) != nil {
+		t.Fatalf("unexpected tier extents of the keys")
+	}
+	if isExtentTiered(eks, tiers, 1, 1025) {
+		t.Fatalf("extent 1025 is partially tiered")
+	}
+	tiers = append(tiers, proto.TierExtent{PartitionId: 1, ExtentId: 1025, ExtentOffset: 8192, Size: 4096, Key: "vol/1/1_1025_8192"})
+	if !isExtentTiered(eks, tiers, 1, 1025) {
+		t.Fatalf("extent 1025 is completely tiered")
+	}
+	if isExtentTiered(eks, tiers, 2, 1026) {
+		t.Fatalf("extent 1026 is not tiered")
+	}
+}
+
+func TestFsmTierExtents(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{Start: 1, End: 100},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+		extDelCh:   make(chan []proto.ExtentKey, 1),
+	}
+	ino := NewInode(2, 0644)
+	ino.AppendExtents([]proto.ExtentKey{{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 4096}}, 100)
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	tiers := []proto.TierExtent{{PartitionId: 1, ExtentId: 1025, Size: 4096, Key: "vol/2/1_1025_0"}}
+
+	// the extent keys migrated are modified since
+	req := &proto.TierExtentsRequest{Inode: 2, ModifyTime: ino.ModifyTime, Tiers: tiers,
+		Extents: []proto.ExtentKey{{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 2048}}}
+	if status := mp.fsmTierExtents(req); status != proto.OpArgMismatchErr {
+		t.Fatalf("expect status %v, actual %v", proto.OpArgMismatchErr, status)
+	}
+	req.Extents = ino.Extents.CopyExtents()
+	req.ModifyTime = ino.ModifyTime - 1
+	if status := mp.fsmTierExtents(req); status != proto.OpArgMismatchErr {
+		t.Fatalf("expect status %v, actual %v", proto.OpArgMismatchErr, status)
+	}
+	if len(mp.getTierExtents(2)) != 0 || len(mp.extDelCh) != 0 {
+		t.Fatalf("the mismatched request is applied")
+	}
+
+	req.ModifyTime = ino.ModifyTime
+	if status := mp.fsmTierExtents(req); status != proto.OpOk {
+		t.Fatalf("expect status %v, actual %v", proto.OpOk, status)
+	}
+	if recorded := mp.getTierExtents(2); len(recorded) != 1 || recorded[0].Key != tiers[0].Key {
+		t.Fatalf("unexpected tier extents %v", recorded)
+	}
+	if deleted := <-mp.extDelCh; len(deleted) != 1 || deleted[0].ExtentId != 1025 {
+		t.Fatalf("unexpected deleted extents %v", deleted)
+	}
+}
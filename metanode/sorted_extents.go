@@ -148,6 +148,70 @@ func (se *SortedExtents) Truncate(offset uint64) (deleteExtents []proto.ExtentKe
 	return
 }
 
+// Replace replaces the contiguous extent keys with the new extent key which covers
+// exactly the same file range. It fails if the extent keys have been changed since
+// they were read, so that the newer data written by others is not overwritten.
+func (se *SortedExtents) Replace(oldEks []proto.ExtentKey, ek proto.ExtentKey) (deleteExtents []proto.ExtentKey, ok bool) {
+	if len(oldEks) == 0 {
+		return
+	}
+
+	se.Lock()
+	defer se.Unlock()
+
+	startIndex := -1
+	for idx, key := range se.eks {
+		if key.FileOffset == oldEks[0].FileOffset {
+			startIndex = idx
+			break
+		}
+	}
+	if startIndex < 0 || startIndex+len(oldEks) > len(se.eks) {
+		return
+	}
+	for i, key := range oldEks {
+		cur := se.eks[startIndex+i]
+		if cur.FileOffset != key.FileOffset || cur.Size != key.Size || cur.PartitionId != key.PartitionId ||
+			cur.ExtentId != key.ExtentId || cur.ExtentOffset != key.ExtentOffset {
+			return
+		}
+	}
+
+	endIndex := startIndex + len(oldEks)
+	upperExtents := make([]proto.ExtentKey, len(se.eks)-endIndex)
+	copy(upperExtents, se.eks[endIndex:])
+	se.eks = se.eks[:startIndex]
+	se.eks = append(se.eks, ek)
+	se.eks = append(se.eks, upperExtents...)
+
+	// An extent file may be referenced by several extent keys of the inode,
+	// so it can only be deleted if none of the remaining keys refers to it.
+	deleteExtents = make([]proto.ExtentKey, 0, len(oldEks))
+	for _, key := range oldEks {
+		if !se.doHasExtent(key.PartitionId, key.ExtentId) {
+			deleteExtents = append(deleteExtents, key)
+		}
+	}
+	ok = true
+	return
+}
+
+// HasExtent returns true if any extent key refers to the extent.
+func (se *SortedExtents) HasExtent(partitionID, extentID uint64) bool {
+	se.RLock()
+	defer se.RUnlock()
+	return se.doHasExtent(partitionID, extentID)
+}
+
+func (se *SortedExtents) doHasExtent(partitionID, extentID uint64) bool {
+	for _, key := range se.eks {
+		if key.PartitionId == partitionID && key.ExtentId == extentID {
+			return true
+		}
+	}
+	return false
+}
+
 func (se *SortedExtents) Len() int {
 	se.RLock()
 	defer se.RUnlock()
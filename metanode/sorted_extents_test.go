@@ -84,3 +84,33 @@ func TestTruncate01(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestReplace(t *testing.T) {
+	se := NewSortedExtents()
+	se.Append(proto.ExtentKey{FileOffset: 0, Size: 1000, PartitionId: 1, ExtentId: 1})
+	se.Append(proto.ExtentKey{FileOffset: 1000, Size: 1000, PartitionId: 1, ExtentId: 2})
+	se.Append(proto.ExtentKey{FileOffset: 2000, Size: 1000, PartitionId: 1, ExtentId: 1, ExtentOffset: 1000})
+	se.Append(proto.ExtentKey{FileOffset: 3000, Size: 1000, PartitionId: 1, ExtentId: 3})
+	oldEks := se.CopyExtents()[1:4]
+
+	// stale extent keys must not be replaced
+	stale := append([]proto.ExtentKey{}, oldEks...)
+	stale[1].Size = 500
+	if _, ok := se.Replace(stale, proto.ExtentKey{FileOffset: 1000, Size: 3000, PartitionId: 2, ExtentId: 4}); ok {
+		t.Fatalf("replace stale extent keys should fail")
+	}
+
+	delExtents, ok := se.Replace(oldEks, proto.ExtentKey{FileOffset: 1000, Size: 3000, PartitionId: 2, ExtentId: 4})
+	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
+	if !ok || len(se.eks) != 2 || se.eks[1].ExtentId != 4 || se.Size() != 4000 {
+		t.Fatalf("replace extent keys failed: eks(%v)", se.eks)
+	}
+	// extent 1 is still referenced by the first key
+	if len(delExtents) != 2 || delExtents[0].ExtentId != 2 || delExtents[1].ExtentId != 3 {
+		t.Fatalf("unexpected deleted extents: %v", delExtents)
+	}
+	// the new extent of a replacement committed must not be discarded
+	if !se.HasExtent(2, 4) || se.HasExtent(1, 2) {
+		t.Fatalf("unexpected extents referenced: eks(%v)", se.eks)
+	}
+}
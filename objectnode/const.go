@@ -153,6 +153,13 @@ const (
 	DefaultDirMode  = DefaultFileMode | os.ModeDir
 )
 
+// Owner of the files and the directories created by the object node, and the credential it
+// presents to the meta nodes if they enforce the POSIX ACL.
+const (
+	DefaultPosixUid uint32 = 0
+	DefaultPosixGid uint32 = 0
+)
+
 const (
 	SplitFileRangeBlockSize     = 10 * 1024 * 1024 // 10MB
 	ParallelDownloadPartSize    = 10 * 1024 * 1024
@@ -41,6 +41,9 @@ type VolumeLoader struct {
 	closeOnce  sync.Once
 	closeCh    chan struct{}
 	metaStrict bool
+	posixUid   uint32
+	posixGid   uint32
+	posixACL   bool
 }
 
 func (loader *VolumeLoader) blacklistCleanup() {
@@ -136,6 +139,9 @@ func (loader *VolumeLoader) loadVolume(volName string) (*Volume, error) {
 			Store:            loader.store,
 			OnAsyncTaskError: onAsyncTaskError,
 			MetaStrict:       loader.metaStrict,
+			PosixUid:         loader.posixUid,
+			PosixGid:         loader.posixGid,
+			PosixACL:         loader.posixACL,
 		}
 		if volume, err = NewVolume(config); err != nil {
 			if err != proto.ErrVolNotExists {
@@ -173,13 +179,16 @@ func (loader *VolumeLoader) Close() {
 	})
 }
 
-func NewVolumeLoader(masters []string, store Store, strict bool) *VolumeLoader {
+func NewVolumeLoader(masters []string, store Store, strict bool, uid, gid uint32, posixACL bool) *VolumeLoader {
 	loader := &VolumeLoader{
 		masters:    masters,
 		store:      store,
 		volumes:    make(map[string]*Volume),
 		closeCh:    make(chan struct{}),
 		metaStrict: strict,
+		posixUid:   uid,
+		posixGid:   gid,
+		posixACL:   posixACL,
 	}
 	go loader.blacklistCleanup()
 	return loader
@@ -191,6 +200,9 @@ type VolumeManager struct {
 	loaders    [volumeLoaderNum]*VolumeLoader
 	store      Store
 	metaStrict bool
+	posixUid   uint32
+	posixGid   uint32
+	posixACL   bool
 	closeOnce  sync.Once
 	closeCh    chan struct{}
 }
@@ -222,15 +234,18 @@ func (m *VolumeManager) init() {
 		vm: m,
 	}
 	for i := 0; i < len(m.loaders); i++ {
-		m.loaders[i] = NewVolumeLoader(m.masters, m.store, m.metaStrict)
+		m.loaders[i] = NewVolumeLoader(m.masters, m.store, m.metaStrict, m.posixUid, m.posixGid, m.posixACL)
 	}
 }
 
-func NewVolumeManager(masters []string, strict bool) *VolumeManager {
+func NewVolumeManager(masters []string, strict bool, uid, gid uint32, posixACL bool) *VolumeManager {
 	manager := &VolumeManager{
 		masters:    masters,
 		closeCh:    make(chan struct{}),
 		metaStrict: strict,
+		posixUid:   uid,
+		posixGid:   gid,
+		posixACL:   posixACL,
 	}
 	manager.init()
 	return manager
@@ -76,6 +76,13 @@ type VolumeConfig struct {
 
 	// Get OSSMeta from the MetaNode every time if it is set true.
 	MetaStrict bool
+
+	// Owner of the files and the directories created through the volume.
+	PosixUid uint32
+	PosixGid uint32
+
+	// Inherit the default ACL of the parent directory if it is set true.
+	PosixACL bool
 }
 
 type PutFileOption struct {
@@ -129,6 +136,8 @@ type Volume struct {
 	metaLoader ossMetaLoader
 	ticker     *time.Ticker
 	createTime int64
+	uid        uint32
+	gid        uint32
 
 	closeOnce sync.Once
 	closeCh   chan struct{}
@@ -283,7 +292,7 @@ func (v *Volume) SetXAttr(path string, key string, data []byte, autoCreate bool)
 			return err
 		}
 		var inodeInfo *proto.InodeInfo
-		if inodeInfo, err = v.mw.Create_ll(parentID, filename, DefaultFileMode, 0, 0, nil); err != nil {
+		if inodeInfo, err = v.mw.Create_ll(parentID, filename, DefaultFileMode, v.uid, v.gid, nil); err != nil {
 			return err
 		}
 		inode = inodeInfo.Inode
@@ -486,7 +495,7 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	// This file has only inode but no dentry. In this way, this temporary file can be made invisible
 	// in the true sense. In order to avoid the adverse impact of other user operations on temporary data.
 	var invisibleTempDataInode *proto.InodeInfo
-	if invisibleTempDataInode, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	if invisibleTempDataInode, err = v.mw.InodeCreate_ll(DefaultFileMode, v.uid, v.gid, nil); err != nil {
 		return
 	}
 	defer func() {
@@ -765,7 +774,7 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 
 	// create temp file (inode only, invisible for user)
 	var tempInodeInfo *proto.InodeInfo
-	if tempInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	if tempInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, v.uid, v.gid, nil); err != nil {
 		log.LogErrorf("WritePart: meta create inode fail: multipartID(%v) partID(%v) err(%v)",
 			multipartId, partId, err)
 		return nil, err
@@ -892,7 +901,7 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 
 	// create inode for complete data
 	var completeInodeInfo *proto.InodeInfo
-	if completeInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
+	if completeInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, v.uid, v.gid, nil); err != nil {
 		log.LogErrorf("CompleteMultipart: meta inode create fail: volume(%v) path(%v) multipartID(%v) err(%v)",
 			v.name, path, multipartID, err)
 		return
@@ -1425,7 +1434,7 @@ func (v *Volume) recursiveMakeDirectory(path string) (ino uint64, err error) {
 		}
 		if err == syscall.ENOENT {
 			var info *proto.InodeInfo
-			info, err = v.mw.Create_ll(ino, pathItem.Name, uint32(DefaultDirMode), 0, 0, nil)
+			info, err = v.mw.Create_ll(ino, pathItem.Name, uint32(DefaultDirMode), v.uid, v.gid, nil)
 			if err != nil && err == syscall.EEXIST {
 				existInode, mode, e := v.mw.Lookup_ll(ino, pathItem.Name)
 				if e != nil {
@@ -1470,7 +1479,7 @@ func (v *Volume) lookupDirectories(dirs []string, autoCreate bool) (inode uint64
 		if lookupErr == syscall.ENOENT {
 			var inodeInfo *proto.InodeInfo
 			var createErr error
-			inodeInfo, createErr = v.mw.Create_ll(parentId, dir, uint32(DefaultDirMode), 0, 0, nil)
+			inodeInfo, createErr = v.mw.Create_ll(parentId, dir, uint32(DefaultDirMode), v.uid, v.gid, nil)
 			if createErr != nil && createErr != syscall.EEXIST {
 				log.LogErrorf("lookupDirectories: meta create fail, parentID(%v) name(%v) mode(%v) err(%v)", parentId, dir, os.ModeDir, createErr)
 				return 0, createErr
@@ -2098,7 +2107,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 	tLastName = pathItems[len(pathItems)-1].Name
 
 	// create target file inode and set target inode to be source file inode
-	if tInodeInfo, err = v.mw.InodeCreate_ll(uint32(sMode), 0, 0, nil); err != nil {
+	if tInodeInfo, err = v.mw.InodeCreate_ll(uint32(sMode), v.uid, v.gid, nil); err != nil {
 		return
 	}
 	defer func() {
@@ -2308,6 +2317,10 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		OnAsyncTaskError: func(err error) {
 			config.OnAsyncTaskError.OnError(err)
 		},
+		// Objects are created by the configured owner, and inherit the default ACL
+		// of the parent directory like the files created through the mount point if configured.
+		EnablePosixACL: config.PosixACL,
+		UserCred:       &proto.UserCred{Uid: config.PosixUid, Gid: config.PosixGid},
 	}
 
 	var metaWrapper *meta.MetaWrapper
@@ -2326,6 +2339,9 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		OnAppendExtentKey: metaWrapper.AppendExtentKey,
 		OnGetExtents:      metaWrapper.GetExtents,
 		OnTruncate:        metaWrapper.Truncate,
+
+		OnGetTieredExtents: metaWrapper.GetTieredExtents,
+		OnGetTierSecure:    metaWrapper.TierSecure,
 	}
 	var extentClient *stream.ExtentClient
 	if extentClient, err = stream.NewExtentClient(extentConfig); err != nil {
@@ -2338,6 +2354,8 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		name:       config.Volume,
 		store:      config.Store,
 		createTime: metaWrapper.VolCreateTime(),
+		uid:        config.PosixUid,
+		gid:        config.PosixGid,
 		closeCh:    make(chan struct{}),
 		onAsyncTaskError: func(err error) {
 			if err == syscall.ENOENT {
@@ -65,6 +65,25 @@ const (
 	//		}
 	configStrict = "strict"
 
+	// The integer configurations set the owner of the files and the directories created by the object node,
+	// which is also the identity checked by the meta nodes if they enforce the POSIX ACL. The user root and
+	// the group root by default.
+	// Example:
+	//		{
+	//			"posixUid": 1000,
+	//			"posixGid": 1000
+	//		}
+	configPosixUid = "posixUid"
+	configPosixGid = "posixGid"
+
+	// A bool type configuration makes the files and the directories created by the object node inherit
+	// the default ACL of the parent directory, like the ones created through the mount point. False by default.
+	// Example:
+	//		{
+	//			"enablePosixACL": true
+	//		}
+	configEnablePosixACL = "enablePosixACL"
+
 	// The character creation array configuration item is used to configure the domain name bound to the object
 	// storage interface. You can bind multiple. ObjectNode uses this configuration to implement automatic
 	// resolution of pan-domain names.
@@ -77,6 +96,16 @@ const (
 	// The configuration in the example will allow ObjectNode to automatically resolve "* .object.chubao.io".
 	configDomains = "domains"
 
+	// The access key and the secret key of an admin user, signing the requests to the admin APIs of the master
+	// if the admin authentication is enabled on the master.
+	// Example:
+	//		{
+	//			"masterAccessKey": "39bEF4RrAQgMj6RV",
+	//			"masterSecretKey": "TRL6o3JL16YOqvZGIohBDFTHZDEcFsyd"
+	//		}
+	configMasterAccessKey = "masterAccessKey"
+	configMasterSecretKey = "masterSecretKey"
+
 	disabledActions               = "disabledActions"
 	configSignatureIgnoredActions = "signatureIgnoredActions"
 )
@@ -176,9 +205,21 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	strict := cfg.GetBool(configStrict)
 	log.LogInfof("loadConfig: strict: %v", strict)
 
+	// parse posix identity config
+	uid, gid := DefaultPosixUid, DefaultPosixGid
+	if cfg.HasKey(configPosixUid) {
+		uid = uint32(cfg.GetInt64(configPosixUid))
+	}
+	if cfg.HasKey(configPosixGid) {
+		gid = uint32(cfg.GetInt64(configPosixGid))
+	}
+	posixACL := cfg.GetBool(configEnablePosixACL)
+	log.LogInfof("loadConfig: posix uid: %v gid: %v acl: %v", uid, gid, posixACL)
+
 	o.mc = master.NewMasterClient(masters, false)
-	o.vm = NewVolumeManager(masters, strict)
-	o.userStore = NewUserInfoStore(masters, strict)
+	o.mc.SetCredential(cfg.GetString(configMasterAccessKey), cfg.GetString(configMasterSecretKey))
+	o.vm = NewVolumeManager(masters, strict, uid, gid, posixACL)
+	o.userStore = NewUserInfoStore(o.mc, strict)
 
 	return
 }
@@ -0,0 +1,52 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// The admin APIs of the master are authenticated by the access key of a user and the signature of the request
+// by its secret key, if the admin authentication is enabled on the master.
+const (
+	AdminAuthAccessKey = "X-Cfs-Access-Key"
+	AdminAuthTimestamp = "X-Cfs-Timestamp" // unix time in seconds when the request is signed
+	AdminAuthSignature = "X-Cfs-Signature"
+	AdminAuthNonce     = "X-Cfs-Nonce" // random string unique to each request, against the replay of the signature
+
+	AdminAuthMaxTimeSkew = 300 // seconds a signature stays valid
+)
+
+// SignAdminRequest computes the signature of the admin API request, the hex encoded HMAC-SHA256 by the secret key
+// of the method, the path, the sorted query, the SHA256 of the body, the timestamp and the nonce.
+func SignAdminRequest(secretKey, method, path string, query url.Values, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{method, path, query.Encode(), hex.EncodeToString(bodyHash[:]), timestamp, nonce}, "\n")
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewAdminAuthNonce generates a random nonce for signing an admin API request.
+func NewAdminAuthNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSignAdminRequest(t *testing.T) {
+	query := url.Values{"name": {"vol"}, "capacity": {"10"}}
+	sign := func(secretKey, method, path string, query url.Values, body, timestamp, nonce string) string {
+		return SignAdminRequest(secretKey, method, path, query, []byte(body), timestamp, nonce)
+	}
+	signature := sign("sk", "POST", AdminCreateVol, query, "body", "1600000000", "nonce")
+	if len(signature) != 64 {
+		t.Fatalf("unexpected signature(%v)", signature)
+	}
+
+	// the query is signed sorted by the keys
+	reordered := url.Values{"capacity": {"10"}}
+	reordered.Set("name", "vol")
+	if sign("sk", "POST", AdminCreateVol, reordered, "body", "1600000000", "nonce") != signature {
+		t.Fatal("expect the same signature of the same query")
+	}
+
+	// any part of the request changes the signature
+	changed := []string{
+		sign("sk2", "POST", AdminCreateVol, query, "body", "1600000000", "nonce"),
+		sign("sk", "GET", AdminCreateVol, query, "body", "1600000000", "nonce"),
+		sign("sk", "POST", AdminDeleteVol, query, "body", "1600000000", "nonce"),
+		sign("sk", "POST", AdminCreateVol, url.Values{"name": {"vol"}, "capacity": {"20"}}, "body", "1600000000", "nonce"),
+		sign("sk", "POST", AdminCreateVol, query, "body2", "1600000000", "nonce"),
+		sign("sk", "POST", AdminCreateVol, query, "body", "1600000001", "nonce"),
+		sign("sk", "POST", AdminCreateVol, query, "body", "1600000000", "nonce2"),
+	}
+	for i, s := range changed {
+		if s == signature {
+			t.Fatalf("expect the signature changed by the part(%v)", i)
+		}
+	}
+	if NewAdminAuthNonce() == NewAdminAuthNonce() {
+		t.Fatal("expect the nonces unique")
+	}
+}
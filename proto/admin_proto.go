@@ -23,12 +23,15 @@ const (
 	AdminCreateDataPartition       = "/dataPartition/create"
 	AdminDecommissionDataPartition = "/dataPartition/decommission"
 	AdminDiagnoseDataPartition     = "/dataPartition/diagnose"
+	AdminConvertDataPartitionToEc  = "/dataPartition/convertToEc"
 	AdminDeleteDataReplica         = "/dataReplica/delete"
 	AdminAddDataReplica            = "/dataReplica/add"
 	AdminDeleteVol                 = "/vol/delete"
 	AdminUpdateVol                 = "/vol/update"
 	AdminVolShrink                 = "/vol/shrink"
 	AdminVolExpand                 = "/vol/expand"
+	AdminRenameVol                 = "/vol/rename"
+	AdminCloneVol                  = "/vol/clone"
 	AdminCreateVol                 = "/admin/createVol"
 	AdminGetVol                    = "/admin/getVol"
 	AdminClusterFreeze             = "/cluster/freeze"
@@ -39,6 +42,15 @@ const (
 	AdminListVols                  = "/vol/list"
 	AdminSetNodeInfo               = "/admin/setNodeInfo"
 	AdminGetNodeInfo               = "/admin/getNodeInfo"
+	AdminSetRebalance              = "/rebalance/set"
+	AdminGetRebalance              = "/rebalance/status"
+	AdminSetAutoDecommission       = "/disk/autoDecommission/set"
+	AdminGetAutoDecommission       = "/disk/autoDecommission/status"
+	AdminListJobs                  = "/job/list"
+	AdminGetJob                    = "/job/get"
+	AdminCancelJob                 = "/job/cancel"
+	AdminListAuditRecords          = "/audit/list"
+	AdminBackupMetadata            = "/admin/backup"
 
 	//graphql master api
 	AdminClusterAPI = "/api/cluster"
@@ -59,6 +71,7 @@ const (
 	ClientMetaPartition  = "/metaPartition/get"
 	ClientVolStat        = "/client/volStat"
 	ClientMetaPartitions = "/client/metaPartitions"
+	ClientChecksumReport = "/client/checksumMismatch"
 
 	//raft node APIs
 	AddRaftNode    = "/raftNode/add"
@@ -109,6 +122,7 @@ const (
 	UserGetInfo         = "/user/info"
 	UserGetAKInfo       = "/user/akInfo"
 	UserTransferVol     = "/user/transferVol"
+	UserUpdateQuota     = "/user/updateQuota"
 	UserList            = "/user/list"
 	UsersOfVol          = "/vol/users"
 	//graphql api for header
@@ -149,20 +163,31 @@ type ClusterInfo struct {
 	Ip                          string
 	MetaNodeDeleteBatchCount    uint64
 	MetaNodeDeleteWorkerSleepMs uint64
+	MetaNodeDefragThreshold     uint64
 	DataNodeDeleteLimitRate     uint64
 	DataNodeAutoRepairLimitRate uint64
+	DataNodeScrubLimitRate      uint64
+	DataNodeDiskIopsLimit       uint64
+	DataNodeDiskBandwidthLimit  uint64
+	DataNodeRepairNodeBandwidth uint64
+	DataNodeRepairDiskBandwidth uint64
 }
 
 // CreateDataPartitionRequest defines the request to create a data partition.
 type CreateDataPartitionRequest struct {
-	PartitionType string
-	PartitionId   uint64
-	PartitionSize int
-	VolumeId      string
-	IsRandomWrite bool
-	Members       []Peer
-	Hosts         []string
-	CreateType    int
+	PartitionType   string
+	PartitionId     uint64
+	PartitionSize   int
+	VolumeId        string
+	IsRandomWrite   bool
+	Members         []Peer
+	Hosts           []string
+	CreateType      int
+	Checksum        string
+	SharedExtentID  uint64 // extents up to it are shared with the cloned volumes, and read only
+	SharedRanges    []*SharedExtentRange
+	SharedTinySizes map[uint64]uint64
+	ExcludedDisks   []string // the disks being decommissioned, not to create the partition on
 }
 
 // CreateDataPartitionResponse defines the response to the request of creating a data partition.
@@ -199,6 +224,67 @@ type AddDataPartitionRaftMemberRequest struct {
 	AddPeer     Peer
 }
 
+// ConvertDataPartitionToEcRequest defines the request of converting the cold extents of a data partition
+// into erasure coded shards.
+type ConvertDataPartitionToEcRequest struct {
+	PartitionId uint64
+	DataNum     uint8
+	ParityNum   uint8
+	EcHosts     []string // the i-th shard of every extent is stored on EcHosts[i]
+	ColdTime    int64    // extents not modified for ColdTime seconds are cold
+}
+
+// ShareDataPartitionRequest defines the request of sharing the extents up to SharedExtentID of a data partition
+// with the cloned volumes. The normal extents shared are not modified any more, and deleted once all the volumes
+// referring to them delete them. The data of the tiny extents below the sizes shared are not deleted any more.
+// No extents are deleted while SharedExtentID is math.MaxUint64, when the volume is being cloned.
+type ShareDataPartitionRequest struct {
+	PartitionId     uint64
+	SharedExtentID  uint64
+	SharedRanges    []*SharedExtentRange
+	SharedTinySizes map[uint64]uint64 // by the tiny extent ids
+}
+
+// ShareDataPartitionResponse defines the response to the request of sharing the extents of a data partition.
+type ShareDataPartitionResponse struct {
+	PartitionId uint64
+	MaxExtentID uint64
+	TinySizes   map[uint64]uint64 // the sizes of the tiny extents, by the extent ids
+}
+
+// SharedExtentRange represents the normal extents of a data partition up to ExtentID referred to by a cloned volume,
+// which deletes them from its meta partitions. The extents are deleted by the owner volume from the other meta
+// partitions.
+type SharedExtentRange struct {
+	ExtentID         uint64
+	MetaPartitionIDs []uint64
+}
+
+// EcShardRequest defines the request of reading or deleting a shard of an erasure coded extent.
+type EcShardRequest struct {
+	Index  int
+	Offset int64
+	Size   int64
+	Verify bool // verify the whole shard against its crc without reading it back
+}
+
+// ScrubExtentRequest defines the request to verify the blocks of an extent on a replica,
+// or to repair the extent from RepairOffset with the data of the source replica.
+type ScrubExtentRequest struct {
+	ExtentId     uint64
+	RepairSource string
+	RepairBlocks []int    // bad blocks of the replica to repair
+	RepairCrcs   []uint32 // crc of each block to repair on the source
+}
+
+// ScrubExtentResponse defines the response to the request of verifying the blocks of an extent.
+type ScrubExtentResponse struct {
+	ExtentId  uint64
+	Size      uint64
+	BlockCrcs []uint32 // crc of the data of each block on the disk
+	BadBlocks []int    // blocks mismatching the recorded crc
+}
+
 // RemoveDataPartitionRaftMemberRequest defines the request of add raftMember a data partition.
 type RemoveDataPartitionRaftMemberRequest struct {
 	PartitionId uint64
@@ -261,6 +347,10 @@ type LoadMetaPartitionMetricResponse struct {
 type HeartBeatRequest struct {
 	CurrTime   int64
 	MasterAddr string
+	VolQos     map[string]*VolQos // I/O limits of the volumes enforced by the data nodes
+
+	VolCompression map[string]string // compression modes of the volumes with compression enabled
+	VolQuorumWrite map[string]bool   // volumes acknowledging the appends once a majority of the replicas persist them
 }
 
 // PartitionReport defines the partition report.
@@ -274,6 +364,17 @@ type PartitionReport struct {
 	IsLeader        bool
 	ExtentCount     int
 	NeedCompare     bool
+	ScrubTime       int64  // the last time the partition is scrubbed
+	ScrubBadBlocks  uint64 // number of the corrupt blocks found by scrubbing
+	ScrubRepaired   uint64 // number of the corrupt blocks repaired
+
+	CompressedRawSize uint64 // size of the data of the compressed extents
+	CompressedSize    uint64 // size of the files of the compressed extents
+
+	TinyReclaimableSize   uint64   // bytes reclaimed by compacting the tiny extents
+	CompactingTinyExtents []uint64 // tiny extents whose data is being relocated
+
+	ChecksumMismatches uint64 // number of the checksum mismatches found by the data node
 }
 
 // DataNodeHeartbeatResponse defines the response to the data node heartbeat.
@@ -290,6 +391,15 @@ type DataNodeHeartbeatResponse struct {
 	Status              uint8
 	Result              string
 	BadDisks            []string
+	DiskReports         []*DiskReport
+}
+
+// DiskReport defines the status and the I/O errors of a disk of the data node.
+type DiskReport struct {
+	Path        string
+	Status      int
+	ReadErrCnt  uint64
+	WriteErrCnt uint64
 }
 
 // MetaPartitionReport defines the meta partition report.
@@ -399,6 +509,15 @@ type DataPartitionResponse struct {
 	LeaderAddr  string
 	Epoch       uint64
 	IsRecover   bool
+	Checksum    string `json:",omitempty"`
+
+	CompactingTinyExtents []uint64 `json:",omitempty"` // tiny extents whose data is relocated by the meta nodes
+	SharedExtentID        uint64   `json:",omitempty"` // extents up to it are shared by the cloned volumes
+}
+
+// IsSharedExtent returns if the extent is shared by the cloned volumes, which is written by the appends only.
+func (dp *DataPartitionResponse) IsSharedExtent(extentID uint64) bool {
+	return extentID <= dp.SharedExtentID
 }
 
 // DataPartitionsView defines the view of a data partition
@@ -440,6 +559,7 @@ type VolView struct {
 	MetaPartitions []*MetaPartitionView
 	DataPartitions []*DataPartitionResponse
 	OSSSecure      *OSSSecure
+	TierSecure     *OSSSecure `json:",omitempty"` // keys of the tier store, nil if the vol has never configured one
 	CreateTime     int64
 }
 
@@ -498,6 +618,53 @@ type SimpleVolView struct {
 	Description        string
 	DpSelectorName     string
 	DpSelectorParm     string
+	EcDataNum          uint8
+	EcParityNum        uint8
+	Tier               *TierPolicy `graphql:"-"`
+	IopsLimit          uint64
+	BandwidthLimit     uint64
+	Compression        string
+	Checksum           string
+	QuorumWrite        bool
+	PlacementPolicy    string
+}
+
+// TierPolicy defines the policy of migrating the cold extents of a volume to an external S3-compatible store.
+type TierPolicy struct {
+	ColdDays  uint32 // extents of the files not accessed for ColdDays days are migrated, 0 if disabled
+	Endpoint  string // the endpoint of the store, or "file://<dir>" for a local directory
+	Region    string
+	Bucket    string
+	AccessKey string // only sent with the authenticated view of the vol, not the simple one
+	SecretKey string
+	Rehydrate bool // whether the clients write the extents back to the data nodes after reading them
+}
+
+// Enabled returns if the cold extents are migrated.
+func (p *TierPolicy) Enabled() bool {
+	return p != nil && p.ColdDays > 0
+}
+
+// Compression modes of the sealed normal extents of a volume
+const (
+	CompressionNone  = ""
+	CompressionFlate = "flate"
+)
+
+// IsValidCompression returns if the compression mode is supported.
+func IsValidCompression(mode string) bool {
+	return mode == CompressionNone || mode == CompressionFlate
+}
+
+// VolQos defines the I/O limits of a volume enforced by each data node.
+type VolQos struct {
+	IopsLimit      uint64 // requests per second, 0 if unlimited
+	BandwidthLimit uint64 // MB per second, 0 if unlimited
+}
+
+// Enabled returns if any I/O limit is set.
+func (q *VolQos) Enabled() bool {
+	return q != nil && (q.IopsLimit > 0 || q.BandwidthLimit > 0)
 }
 
 // MasterAPIAccessResp defines the response for getting meta partition
@@ -0,0 +1,45 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"errors"
+	"hash/crc32"
+)
+
+// Checksum types of the data of a volume. The checksum type of a data partition is decided by its volume
+// on creation and never changes, since the checksums of the blocks are stored along with the data.
+const (
+	ChecksumCRC32  = ""       // CRC32 with the IEEE polynomial
+	ChecksumCRC32C = "crc32c" // CRC32 with the Castagnoli polynomial, computed by the SSE4.2 instructions if supported
+)
+
+// ErrChecksumMismatch is returned by the data node reading the data mismatching the checksum stored on writing.
+var ErrChecksumMismatch = errors.New("data mismatches the stored checksum")
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// IsValidChecksum returns if the checksum type is supported.
+func IsValidChecksum(checksum string) bool {
+	return checksum == ChecksumCRC32 || checksum == ChecksumCRC32C
+}
+
+// Checksum computes the checksum of the data with the checksum type.
+func Checksum(checksum string, data []byte) uint32 {
+	if checksum == ChecksumCRC32C {
+		return crc32.Checksum(data, castagnoliTable)
+	}
+	return crc32.ChecksumIEEE(data)
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// ClusterSpec declares the desired configuration of a cluster. The sections omitted are left as they are,
+// and the volumes and the users not declared are neither changed nor deleted.
+type ClusterSpec struct {
+	Cluster *ClusterSettingsSpec `json:"cluster,omitempty"`
+	Zones   []*ZoneSpec          `json:"zones,omitempty"`
+	Vols    []*VolSpec           `json:"vols,omitempty"`
+	Users   []*UserSpec          `json:"users,omitempty"`
+}
+
+// ClusterSettingsSpec declares the settings of the cluster, the ones omitted are left as they are.
+type ClusterSettingsSpec struct {
+	DisableAutoAlloc  *bool                 `json:"disableAutoAlloc,omitempty"`
+	MetaNodeThreshold *float32              `json:"metaNodeThreshold,omitempty"`
+	NodeInfo          map[string]uint64     `json:"nodeInfo,omitempty"` // parameters of /admin/setNodeInfo
+	Rebalance         *RebalanceSpec        `json:"rebalance,omitempty"`
+	AutoDecommission  *AutoDecommissionSpec `json:"autoDecommission,omitempty"`
+}
+
+type RebalanceSpec struct {
+	Enable         bool    `json:"enable"`
+	Threshold      float64 `json:"threshold"`
+	MaxConcurrency uint64  `json:"maxConcurrency"`
+}
+
+type AutoDecommissionSpec struct {
+	Enable         bool   `json:"enable"`
+	ErrorThreshold uint64 `json:"errorThreshold"`
+	MaxConcurrency uint64 `json:"maxConcurrency"`
+	BadDiskLimit   uint64 `json:"badDiskLimit"`
+}
+
+// ZoneSpec declares if the zone is available for the new partitions.
+type ZoneSpec struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+}
+
+// VolSpec declares a volume, the volumes not existing are created.
+type VolSpec struct {
+	Name            string `json:"name"`
+	Owner           string `json:"owner"`
+	Capacity        uint64 `json:"capacity"` // GB
+	ZoneName        string `json:"zoneName,omitempty"`
+	Description     string `json:"description,omitempty"`
+	FollowerRead    bool   `json:"followerRead"`
+	Authenticate    bool   `json:"authenticate"`
+	EnableToken     bool   `json:"enableToken"`
+	IopsLimit       uint64 `json:"iopsLimit"`
+	BandwidthLimit  uint64 `json:"bandwidthLimit"`
+	Compression     string `json:"compression,omitempty"`
+	Checksum        string `json:"checksum,omitempty"`
+	QuorumWrite     bool   `json:"quorumWrite"`
+	PlacementPolicy string `json:"placementPolicy,omitempty"`
+}
+
+// UserSpec declares a user, the users not existing are created with the keys generated.
+// The keys and the passwords are not declared.
+type UserSpec struct {
+	ID             string              `json:"id"`
+	Type           string              `json:"type"`
+	Description    string              `json:"description,omitempty"`
+	Quota          *UserQuota          `json:"quota,omitempty"`
+	AuthorizedVols map[string][]string `json:"authorizedVols"` // the volumes authorized besides the ones owned, null if not declared
+}
@@ -81,6 +81,10 @@ var (
 	ErrInvalidAccessKey                = errors.New("invalid access key")
 	ErrInvalidSecretKey                = errors.New("invalid secret key")
 	ErrIsOwner                         = errors.New("user owns the volume")
+	ErrInvalidSignature                = errors.New("invalid or expired signature")
+	ErrJobNotExists                    = errors.New("job not exists")
+	ErrJobExists                       = errors.New("job of the node already exists")
+	ErrUserQuotaExceeded               = errors.New("user quota exceeded")
 )
 
 // http response error code and error message definitions
@@ -144,6 +148,10 @@ const (
 	ErrCodeInvalidAccessKey
 	ErrCodeInvalidSecretKey
 	ErrCodeIsOwner
+	ErrCodeInvalidSignature
+	ErrCodeJobNotExists
+	ErrCodeJobExists
+	ErrCodeUserQuotaExceeded
 )
 
 // Err2CodeMap error map to code
@@ -205,6 +213,10 @@ var Err2CodeMap = map[error]int32{
 	ErrInvalidAccessKey:                ErrCodeInvalidAccessKey,
 	ErrInvalidSecretKey:                ErrCodeInvalidSecretKey,
 	ErrIsOwner:                         ErrCodeIsOwner,
+	ErrInvalidSignature:                ErrCodeInvalidSignature,
+	ErrJobNotExists:                    ErrCodeJobNotExists,
+	ErrJobExists:                       ErrCodeJobExists,
+	ErrUserQuotaExceeded:               ErrCodeUserQuotaExceeded,
 }
 
 func ParseErrorCode(code int32) error {
@@ -273,6 +285,10 @@ var code2ErrMap = map[int32]error{
 	ErrCodeInvalidAccessKey:                ErrInvalidAccessKey,
 	ErrCodeInvalidSecretKey:                ErrInvalidSecretKey,
 	ErrCodeIsOwner:                         ErrIsOwner,
+	ErrCodeInvalidSignature:                ErrInvalidSignature,
+	ErrCodeJobNotExists:                    ErrJobNotExists,
+	ErrCodeJobExists:                       ErrJobExists,
+	ErrCodeUserQuotaExceeded:               ErrUserQuotaExceeded,
 }
 
 type GeneralResp struct {
@@ -30,6 +30,15 @@ const (
 	FlagsAppend
 )
 
+// Extended attribute keys which carry POSIX access control lists.
+const (
+	XAttrKeyPosixACLAccess  = "system.posix_acl_access"
+	XAttrKeyPosixACLDefault = "system.posix_acl_default"
+)
+
+// XAttrKeyTier is the reserved extended attribute key which records the extents migrated to the tier store.
+const XAttrKeyTier = "cfs.tier"
+
 // Mode returns the fileMode.
 func Mode(osMode os.FileMode) uint32 {
 	return uint32(osMode)
@@ -118,6 +127,21 @@ func (info XAttrInfo) String() string {
 	return fmt.Sprintf("XAttrInfo{Inode(%v), XAttrs(%v)}", info.Inode, builder.String())
 }
 
+// UserCred defines the identity of the caller which issues a metadata operation.
+type UserCred struct {
+	Uid  uint32   `json:"uid"`
+	Gid  uint32   `json:"gid"`
+	Gids []uint32 `json:"gids,omitempty"` // supplementary groups
+}
+
+// String returns the string format of the user credential.
+func (cred *UserCred) String() string {
+	if cred == nil {
+		return "UserCred{nil}"
+	}
+	return fmt.Sprintf("UserCred{Uid(%v),Gid(%v),Gids(%v)}", cred.Uid, cred.Gid, cred.Gids)
+}
+
 // Dentry defines the dentry struct.
 type Dentry struct {
 	Name  string `json:"name"`
@@ -138,6 +162,7 @@ type CreateInodeRequest struct {
 	Uid         uint32 `json:"uid"`
 	Gid         uint32 `json:"gid"`
 	Target      []byte `json:"tgt"`
+	DefaultACL  string `json:"dacl,omitempty"` // default ACL of the parent directory
 }
 
 // CreateInodeResponse defines the response to the request of creating an inode.
@@ -200,21 +225,23 @@ type BatchEvictInodeRequest struct {
 
 // CreateDentryRequest defines the request to create a dentry.
 type CreateDentryRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	ParentID    uint64 `json:"pino"`
-	Inode       uint64 `json:"ino"`
-	Name        string `json:"name"`
-	Mode        uint32 `json:"mode"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Inode       uint64    `json:"ino"`
+	Name        string    `json:"name"`
+	Mode        uint32    `json:"mode"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // UpdateDentryRequest defines the request to update a dentry.
 type UpdateDentryRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	ParentID    uint64 `json:"pino"`
-	Name        string `json:"name"`
-	Inode       uint64 `json:"ino"` // new inode number
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Name        string    `json:"name"`
+	Inode       uint64    `json:"ino"` // new inode number
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // UpdateDentryResponse defines the response to the request of updating a dentry.
@@ -224,17 +251,19 @@ type UpdateDentryResponse struct {
 
 // DeleteDentryRequest define the request tp delete a dentry.
 type DeleteDentryRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	ParentID    uint64 `json:"pino"`
-	Name        string `json:"name"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Name        string    `json:"name"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 type BatchDeleteDentryRequest struct {
-	VolName     string   `json:"vol"`
-	PartitionID uint64   `json:"pid"`
-	ParentID    uint64   `json:"pino"`
-	Dens        []Dentry `json:"dens"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Dens        []Dentry  `json:"dens"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // DeleteDentryResponse defines the response to the request of deleting a dentry.
@@ -252,10 +281,11 @@ type BatchDeleteDentryResponse struct {
 
 // LookupRequest defines the request for lookup.
 type LookupRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	ParentID    uint64 `json:"pino"`
-	Name        string `json:"name"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Name        string    `json:"name"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // LookupResponse defines the response for the loopup request.
@@ -290,9 +320,10 @@ type BatchInodeGetResponse struct {
 
 // ReadDirRequest defines the request to read dir.
 type ReadDirRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	ParentID    uint64 `json:"pino"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	ParentID    uint64    `json:"pino"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // ReadDirResponse defines the response to the request of reading dir.
@@ -306,6 +337,7 @@ type AppendExtentKeyRequest struct {
 	PartitionID uint64    `json:"pid"`
 	Inode       uint64    `json:"ino"`
 	Extent      ExtentKey `json:"ek"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // GetExtentsRequest defines the reques to get extents.
@@ -317,30 +349,80 @@ type GetExtentsRequest struct {
 
 // GetExtentsResponse defines the response to the request of getting extents.
 type GetExtentsResponse struct {
-	Generation uint64      `json:"gen"`
-	Size       uint64      `json:"sz"`
-	Extents    []ExtentKey `json:"eks"`
+	Generation uint64       `json:"gen"`
+	Size       uint64       `json:"sz"`
+	Extents    []ExtentKey  `json:"eks"`
+	Tiers      []TierExtent `json:"tiers,omitempty"`
+}
+
+// TierExtent defines the location of an extent key migrated to the tier store,
+// the object of which holds the data of the extent in [ExtentOffset, ExtentOffset+Size).
+type TierExtent struct {
+	PartitionId  uint64   `json:"pid"`
+	ExtentId     uint64   `json:"eid"`
+	ExtentOffset uint64   `json:"eoff"`
+	Size         uint32   `json:"sz"`
+	Key          string   `json:"key"`
+	Crcs         []uint32 `json:"crcs,omitempty"` // crcs of the blocks of the object
+}
+
+// Contains returns if the data of the extent key is stored in the tier extent.
+func (t *TierExtent) Contains(ek *ExtentKey) bool {
+	return t.PartitionId == ek.PartitionId && t.ExtentId == ek.ExtentId &&
+		t.ExtentOffset <= ek.ExtentOffset && ek.ExtentOffset+uint64(ek.Size) <= t.ExtentOffset+uint64(t.Size)
+}
+
+// ReplaceExtentKeysRequest defines the request to replace a contiguous range of
+// extent keys with a single extent key holding the same data.
+type ReplaceExtentKeysRequest struct {
+	VolName     string      `json:"vol"`
+	PartitionId uint64      `json:"pid"`
+	Inode       uint64      `json:"ino"`
+	OldExtents  []ExtentKey `json:"oeks"`
+	Extent      ExtentKey   `json:"ek"`
+	Prepare     bool        `json:"prepare,omitempty"` // the new extent is allocated, and is recorded as the replacement target of the inode
+	Discard     bool        `json:"discard,omitempty"` // the replacement is abandoned, and the new extent is deleted
+	Cred        *UserCred   `json:"cred,omitempty"`
+}
+
+// RelocateExtentKeyRequest defines the request to relocate the data of an extent key to a new extent key.
+type RelocateExtentKeyRequest struct {
+	Inode     uint64    `json:"ino"`
+	OldExtent ExtentKey `json:"oek"`
+	Extent    ExtentKey `json:"ek"`
+	Cred      *UserCred `json:"cred,omitempty"`
+}
+
+// TierExtentsRequest defines the request to record the tier extents of an inode, and to delete
+// the extents migrated completely, if the extent keys of the inode are still the ones migrated.
+type TierExtentsRequest struct {
+	Inode      uint64       `json:"ino"`
+	ModifyTime int64        `json:"mt"`
+	Extents    []ExtentKey  `json:"eks"`
+	Tiers      []TierExtent `json:"tiers"`
 }
 
 // TruncateRequest defines the request to truncate.
 type TruncateRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	Inode       uint64 `json:"ino"`
-	Size        uint64 `json:"sz"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	Inode       uint64    `json:"ino"`
+	Size        uint64    `json:"sz"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 // SetAttrRequest defines the request to set attribute.
 type SetAttrRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	Inode       uint64 `json:"ino"`
-	Mode        uint32 `json:"mode"`
-	Uid         uint32 `json:"uid"`
-	Gid         uint32 `json:"gid"`
-	ModifyTime  int64  `json:"mt"`
-	AccessTime  int64  `json:"at"`
-	Valid       uint32 `json:"valid"`
+	VolName     string    `json:"vol"`
+	PartitionID uint64    `json:"pid"`
+	Inode       uint64    `json:"ino"`
+	Mode        uint32    `json:"mode"`
+	Uid         uint32    `json:"uid"`
+	Gid         uint32    `json:"gid"`
+	ModifyTime  int64     `json:"mt"`
+	AccessTime  int64     `json:"at"`
+	Valid       uint32    `json:"valid"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 const (
@@ -371,14 +453,16 @@ type AppendExtentKeysRequest struct {
 	PartitionId uint64      `json:"pid"`
 	Inode       uint64      `json:"ino"`
 	Extents     []ExtentKey `json:"eks"`
+	Cred        *UserCred   `json:"cred,omitempty"`
 }
 
 type SetXAttrRequest struct {
-	VolName     string `json:"vol"`
-	PartitionId uint64 `json:"pid"`
-	Inode       uint64 `json:"ino"`
-	Key         string `json:"key"`
-	Value       string `json:"val"`
+	VolName     string    `json:"vol"`
+	PartitionId uint64    `json:"pid"`
+	Inode       uint64    `json:"ino"`
+	Key         string    `json:"key"`
+	Value       string    `json:"val"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 type GetXAttrRequest struct {
@@ -397,10 +481,11 @@ type GetXAttrResponse struct {
 }
 
 type RemoveXAttrRequest struct {
-	VolName     string `json:"vol"`
-	PartitionId uint64 `json:"pid"`
-	Inode       uint64 `json:"ino"`
-	Key         string `json:"key"`
+	VolName     string    `json:"vol"`
+	PartitionId uint64    `json:"pid"`
+	Inode       uint64    `json:"ino"`
+	Key         string    `json:"key"`
+	Cred        *UserCred `json:"cred,omitempty"`
 }
 
 type ListXAttrRequest struct {
@@ -41,6 +41,13 @@ type CreateMetaPartitionRequest struct {
 	Members     []Peer
 }
 
+// CloneMetaPartitionRequest defines the request to clone a meta partition into a meta partition of the cloned volume,
+// which is created on the replicas of the source meta partition.
+type CloneMetaPartitionRequest struct {
+	PartitionID uint64
+	Clone       *CreateMetaPartitionRequest
+}
+
 // CreateMetaPartitionResponse defines the response to the request of creating a meta partition.
 type CreateMetaPartitionResponse struct {
 	VolName     string
@@ -39,6 +39,7 @@ type MetaNodeInfo struct {
 	MetaPartitionCount        int
 	NodeSetID                 uint64
 	PersistenceMetaPartitions []uint64
+	Labels                    map[string]string
 }
 
 // DataNode stores all the information about a data node
@@ -59,8 +60,21 @@ type DataNodeInfo struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	Labels                    map[string]string
+	DiskReports               []*DiskReport
 }
 
+// The labels registered by the nodes, by which the replicas of a partition are spread across the racks, the
+// physical hosts or the power domains if the placement policy of the volume is the label.
+const (
+	NodeLabelRack  = "rack"
+	NodeLabelHost  = "host"  // the IP of the node if not registered
+	NodeLabelPower = "power" // the power domain
+)
+
+// NodeLabels lists the labels the nodes register.
+var NodeLabels = []string{NodeLabelRack, NodeLabelHost, NodeLabelPower}
+
 // MetaPartition defines the structure of a meta partition
 type MetaPartitionInfo struct {
 	PartitionID   uint64
@@ -126,6 +140,25 @@ type ClusterStatInfo struct {
 	DataNodeStatInfo *NodeStatInfo
 	MetaNodeStatInfo *NodeStatInfo
 	ZoneStatInfo     map[string]*ZoneStat
+	Forecasts        []*CapacityForecast `json:",omitempty"`
+}
+
+// scopes of the capacity forecasts
+const (
+	ForecastScopeVol     = "vol"
+	ForecastScopeZone    = "zone"
+	ForecastScopeNodeSet = "nodeSet"
+)
+
+// CapacityForecast estimates when the space of a volume, a zone or a node set runs out by the growth of the usage.
+type CapacityForecast struct {
+	Scope          string
+	Name           string
+	TotalGB        float64
+	UsedGB         float64
+	GrowthGBPerDay float64 // growth of the used space in the history, negative if shrinking
+	DaysToFull     float64 // -1 if the used space is not growing or the history is too short
+	HistoryHours   float64 // time span of the samples the growth is estimated from
 }
 
 type ZoneStat struct {
@@ -164,11 +197,14 @@ type DataPartitionInfo struct {
 	Status                  int8
 	Replicas                []*DataReplica
 	Hosts                   []string // host addresses
+	EcHosts                 []string // host addresses of the erasure coded shards
 	Peers                   []Peer
 	Zones                   []string
 	MissingNodes            map[string]int64 // key: address of the missing node, value: when the node is missing
 	VolName                 string
 	VolID                   uint64
+	Checksum                string
+	SharedExtentID          uint64 // extents up to it are shared by the cloned volumes
 	OfflinePeerID           uint64
 	FileInCoreMap           map[string]*FileInCore
 	FilesWithMissingReplica map[string]int64 // key: file name, value: last time when a missing replica is found
@@ -200,6 +236,18 @@ type DataReplica struct {
 	IsLeader        bool
 	NeedsToCompare  bool
 	DiskPath        string
+	ScrubTime       int64
+	ScrubBadBlocks  uint64
+	ScrubRepaired   uint64
+
+	CompressedRawSize uint64
+	CompressedSize    uint64
+
+	TinyReclaimableSize   uint64
+	CompactingTinyExtents []uint64
+
+	ChecksumMismatches       uint64 // found by the data node
+	ClientChecksumMismatches uint64 // found by the clients reading the replica
 }
 
 // data partition diagnosis represents the inactive data nodes, corrupt data partitions, and data partitions lack of replicas
@@ -217,3 +265,155 @@ type MetaPartitionDiagnosis struct {
 	LackReplicaMetaPartitionIDs []uint64
 	BadMetaPartitionIDs         []BadPartitionView
 }
+
+// The kinds and the states of the partition migrations planned by the rebalancer of the master.
+const (
+	RebalanceDataPartition = "data"
+	RebalanceMetaPartition = "meta"
+
+	RebalanceRunning   = "running"
+	RebalanceSucceeded = "succeeded"
+	RebalanceFailed    = "failed"
+)
+
+// RebalanceMigration represents a replica of a partition moved by the rebalancer from the source node to the target.
+type RebalanceMigration struct {
+	Kind        string
+	PartitionID uint64
+	VolName     string
+	Src         string
+	Target      string
+	Reason      string
+	Status      string
+	Msg         string
+	StartTime   int64
+	EndTime     int64
+}
+
+// RebalanceImbalance represents the spread between the most and the least loaded data nodes and meta nodes
+// in a node set or a zone, or between the averages of the zones in the cluster.
+type RebalanceImbalance struct {
+	Scope               string // cluster, zone or nodeSet
+	Name                string
+	DataUsageSpread     float64
+	DataPartitionSpread int
+	MetaMemSpread       float64
+	MetaPartitionSpread int
+}
+
+// RebalanceView represents the settings, the imbalance and the migrations of the rebalancer.
+type RebalanceView struct {
+	Enable         bool
+	Threshold      float64
+	MaxConcurrency uint64
+	Imbalances     []*RebalanceImbalance
+	Running        []*RebalanceMigration
+	History        []*RebalanceMigration
+}
+
+// The actions taken on the bad disks by the automatic disk decommission of the master.
+const (
+	DiskFailureDecommission = "decommission"
+	DiskFailureSuspend      = "suspend" // too many disks are bad at the same time
+	DiskFailureResume       = "resume"
+)
+
+// BadDisk represents a bad disk of a data node holding the data partitions.
+type BadDisk struct {
+	Addr       string
+	DiskPath   string
+	Reason     string
+	Partitions int
+}
+
+// DiskFailureEvent records an action taken on the bad disks by the automatic disk decommission.
+type DiskFailureEvent struct {
+	Time     int64
+	Action   string
+	Addr     string
+	DiskPath string
+	Reason   string
+	JobID    uint64
+}
+
+// AutoDecommissionView represents the settings, the bad disks and the events of the automatic disk decommission.
+type AutoDecommissionView struct {
+	Enable         bool
+	ErrorThreshold uint64
+	MaxConcurrency uint64
+	BadDiskLimit   uint64
+	Suspended      bool
+	BadDisks       []*BadDisk
+	Events         []*DiskFailureEvent
+}
+
+// AuditRecord records a call of the mutating APIs of the master, and the result.
+type AuditRecord struct {
+	Time          int64
+	User          string            // the user signing the request, or claimed in the header if not authenticated
+	Authenticated bool              // false if the admin authentication is off, or the API is public
+	RemoteAddr    string            // the original client, taken from X-Forwarded-For if forwarded
+	ProxyAddr     string            // the proxy or the follower master forwarding the request, if forwarded
+	Op            string            // the path, followed by the fields of the graphql mutations
+	Params        map[string]string // the secrets are masked
+	Code          int32
+	Msg           string
+}
+
+// The types and the states of the decommission jobs of the master, and the states of their partitions.
+const (
+	DecommissionDataNodeJob = "dataNode"
+	DecommissionMetaNodeJob = "metaNode"
+	DecommissionDiskJob     = "disk"
+
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+
+	PartitionPending   = "pending"
+	PartitionMigrating = "migrating" // moved to the new node and recovering
+	PartitionDone      = "done"
+	PartitionFailed    = "failed"
+)
+
+// DecommissionPartition represents a partition moved out of the node or the disk by the decommission job.
+type DecommissionPartition struct {
+	PartitionID   uint64
+	VolName       string
+	Status        string
+	Retries       int
+	NextRetryTime int64
+	Msg           string
+}
+
+// DecommissionJob represents the decommission of a data node, a meta node or a disk of a data node,
+// persisted by the master and resumed by the new leader.
+type DecommissionJob struct {
+	ID         uint64
+	Type       string
+	Addr       string
+	DiskPath   string
+	Status     string
+	Msg        string
+	CreateTime int64
+	UpdateTime int64
+	Partitions []*DecommissionPartition
+}
+
+// DecommissionJobView represents the progress of a decommission job.
+type DecommissionJobView struct {
+	ID         uint64
+	Type       string
+	Addr       string
+	DiskPath   string
+	Status     string
+	Msg        string
+	CreateTime int64
+	UpdateTime int64
+	Total      int
+	Pending    int
+	Migrating  int
+	Done       int
+	Failed     int
+}
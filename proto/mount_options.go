@@ -47,6 +47,11 @@ const (
 	EnableXattr
 	NearRead
 	EnablePosixACL
+	DefragThreshold
+	PacketTLSCert
+	PacketTLSKey
+	PacketTLSCA
+	PacketTLSServerName
 
 	MaxMountOption
 )
@@ -56,6 +61,12 @@ const (
 	MasterAddr       = "masterAddr"
 	ListenPort       = "listen"
 	ObjectNodeDomain = "objectNodeDomain"
+
+	// certificate of the role for the packet protocol over TLS, see util.InitPacketTLS
+	TLSCertFile   = "tlsCertFile"
+	TLSKeyFile    = "tlsKeyFile"
+	TLSCAFile     = "tlsCAFile"
+	TLSServerName = "tlsServerName"
 )
 
 type MountOption struct {
@@ -112,6 +123,12 @@ func InitMountOptions(opts []MountOption) {
 	opts[MaxCPUs] = MountOption{"maxcpus", "The maximum number of CPUs that can be executing", "", int64(-1)}
 	opts[EnableXattr] = MountOption{"enableXattr", "Enable xattr support", "", false}
 	opts[EnablePosixACL] = MountOption{"enablePosixACL", "enable posix ACL support", "", false}
+	opts[DefragThreshold] = MountOption{"defragThreshold", "Defragment files with at least so many extents", "", int64(0)}
+
+	opts[PacketTLSCert] = MountOption{TLSCertFile, "Certificate of the client for the packet protocol over TLS", "", ""}
+	opts[PacketTLSKey] = MountOption{TLSKeyFile, "Private key of the client certificate", "", ""}
+	opts[PacketTLSCA] = MountOption{TLSCAFile, "CA of the cluster verifying the certificates of the nodes", "", ""}
+	opts[PacketTLSServerName] = MountOption{TLSServerName, "Server name verified against the certificates of the nodes", "", ""}
 
 	for i := 0; i < MaxMountOption; i++ {
 		flag.StringVar(&opts[i].cmdlineValue, opts[i].keyword, "", opts[i].description)
@@ -209,36 +226,41 @@ func (opt *MountOption) GetInt64() int64 {
 }
 
 type MountOptions struct {
-	Config         *config.Config
-	MountPoint     string
-	Volname        string
-	Owner          string
-	Master         string
-	Logpath        string
-	Loglvl         string
-	Profport       string
-	IcacheTimeout  int64
-	LookupValid    int64
-	AttrValid      int64
-	ReadRate       int64
-	WriteRate      int64
-	EnSyncWrite    int64
-	AutoInvalData  int64
-	UmpDatadir     string
-	Rdonly         bool
-	WriteCache     bool
-	KeepCache      bool
-	FollowerRead   bool
-	Authenticate   bool
-	TicketMess     auth.TicketMess
-	TokenKey       string
-	AccessKey      string
-	SecretKey      string
-	DisableDcache  bool
-	SubDir         string
-	FsyncOnClose   bool
-	MaxCPUs        int64
-	EnableXattr    bool
-	NearRead       bool
-	EnablePosixACL bool
+	Config          *config.Config
+	MountPoint      string
+	Volname         string
+	Owner           string
+	Master          string
+	Logpath         string
+	Loglvl          string
+	Profport        string
+	IcacheTimeout   int64
+	LookupValid     int64
+	AttrValid       int64
+	ReadRate        int64
+	WriteRate       int64
+	EnSyncWrite     int64
+	AutoInvalData   int64
+	UmpDatadir      string
+	Rdonly          bool
+	WriteCache      bool
+	KeepCache       bool
+	FollowerRead    bool
+	Authenticate    bool
+	TicketMess      auth.TicketMess
+	TokenKey        string
+	AccessKey       string
+	SecretKey       string
+	DisableDcache   bool
+	SubDir          string
+	FsyncOnClose    bool
+	MaxCPUs         int64
+	EnableXattr     bool
+	NearRead        bool
+	EnablePosixACL  bool
+	DefragThreshold int64
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSCAFile       string
+	TLSServerName   string
 }
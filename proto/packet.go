@@ -68,6 +68,18 @@ const (
 	OpTinyExtentRepairRead           uint8 = 0x15
 	OpGetMaxExtentIDAndPartitionSize uint8 = 0x16
 
+	// Operations: erasure coded shards between data nodes
+	OpWriteEcShard  uint8 = 0x17
+	OpReadEcShard   uint8 = 0x18
+	OpDeleteEcShard uint8 = 0x19
+	OpSyncEcExtents uint8 = 0x1A
+
+	// Operations: scrubbing between data nodes
+	OpScrubExtent uint8 = 0x1B
+
+	// Operations: tiny extent compaction between data nodes
+	OpCompactTinyExtent uint8 = 0x1C
+
 	// Operations: Client -> MetaNode.
 	OpMetaCreateInode   uint8 = 0x20
 	OpMetaUnlinkInode   uint8 = 0x21
@@ -98,6 +110,7 @@ const (
 	OpMetaRemoveXAttr     uint8 = 0x37
 	OpMetaListXAttr       uint8 = 0x38
 	OpMetaBatchGetXAttr   uint8 = 0x39
+	OpMetaExtentsReplace  uint8 = 0x3A // replace a range of extent keys, used by defragmentation
 
 	// Operations: Master -> MetaNode
 	OpCreateMetaPartition           uint8 = 0x40
@@ -109,6 +122,7 @@ const (
 	OpAddMetaPartitionRaftMember    uint8 = 0x46
 	OpRemoveMetaPartitionRaftMember uint8 = 0x47
 	OpMetaPartitionTryToLeader      uint8 = 0x48
+	OpCloneMetaPartition            uint8 = 0x49
 
 	// Operations: Master -> DataNode
 	OpCreateDataPartition           uint8 = 0x60
@@ -121,6 +135,8 @@ const (
 	OpAddDataPartitionRaftMember    uint8 = 0x67
 	OpRemoveDataPartitionRaftMember uint8 = 0x68
 	OpDataPartitionTryToLeader      uint8 = 0x69
+	OpConvertDataPartitionToEc      uint8 = 0x6A
+	OpShareDataPartition            uint8 = 0x6B
 
 	// Operations: MultipartInfo
 	OpCreateMultipart  uint8 = 0x70
@@ -339,6 +355,24 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpTinyExtentRepairRead"
 	case OpGetMaxExtentIDAndPartitionSize:
 		m = "OpGetMaxExtentIDAndPartitionSize"
+	case OpWriteEcShard:
+		m = "OpWriteEcShard"
+	case OpReadEcShard:
+		m = "OpReadEcShard"
+	case OpDeleteEcShard:
+		m = "OpDeleteEcShard"
+	case OpSyncEcExtents:
+		m = "OpSyncEcExtents"
+	case OpScrubExtent:
+		m = "OpScrubExtent"
+	case OpCompactTinyExtent:
+		m = "OpCompactTinyExtent"
+	case OpConvertDataPartitionToEc:
+		m = "OpConvertDataPartitionToEc"
+	case OpShareDataPartition:
+		m = "OpShareDataPartition"
+	case OpCloneMetaPartition:
+		m = "OpCloneMetaPartition"
 	case OpBroadcastMinAppliedID:
 		m = "OpBroadcastMinAppliedID"
 	case OpRemoveDataPartitionRaftMember:
@@ -369,6 +403,8 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpMetaListXAttr"
 	case OpMetaBatchGetXAttr:
 		m = "OpMetaBatchGetXAttr"
+	case OpMetaExtentsReplace:
+		m = "OpMetaExtentsReplace"
 	case OpCreateMultipart:
 		m = "OpCreateMultipart"
 	case OpGetMultipart:
@@ -101,10 +101,50 @@ type UserInfo struct {
 	UserType    UserType     `json:"user_type" graphql:"user_type"`
 	CreateTime  string       `json:"create_time" graphql:"create_time"`
 	Description string       `json:"description" graphql:"description"`
+	Quota       *UserQuota   `json:"quota,omitempty" graphql:"-"`
+	Usage       *UserUsage   `json:"usage,omitempty" graphql:"-"` // reported by the master, not persisted
 	Mu          sync.RWMutex `json:"-" graphql:"-"`
 	EMPTY       bool         //graphql need ???
 }
 
+// UserQuota limits the volumes owned by the user in total, 0 for no limit.
+type UserQuota struct {
+	Capacity    uint64 `json:"capacity"` // GB
+	VolCount    uint64 `json:"vol_count"`
+	ObjectCount uint64 `json:"object_count"`
+}
+
+// IsUnlimited returns if none of the limits is set.
+func (q *UserQuota) IsUnlimited() bool {
+	return q == nil || (q.Capacity == 0 && q.VolCount == 0 && q.ObjectCount == 0)
+}
+
+// UserUsage is the usage of the volumes owned by the user.
+type UserUsage struct {
+	Capacity    uint64 `json:"capacity"` // GB
+	UsedSize    uint64 `json:"used_size"`
+	VolCount    uint64 `json:"vol_count"`
+	ObjectCount uint64 `json:"object_count"`
+}
+
+// Exceeds returns the error if the usage after adding the capacity and the volumes exceeds the quota. The object
+// count is only checked as is, since the objects are not created by the master.
+func (usage *UserUsage) Exceeds(quota *UserQuota, capacity, volCount uint64) error {
+	if quota == nil {
+		return nil
+	}
+	if quota.Capacity > 0 && capacity > 0 && usage.Capacity+capacity > quota.Capacity {
+		return fmt.Errorf("%v: capacity[%v] used[%v] requested[%v]", ErrUserQuotaExceeded, quota.Capacity, usage.Capacity, capacity)
+	}
+	if quota.VolCount > 0 && volCount > 0 && usage.VolCount+volCount > quota.VolCount {
+		return fmt.Errorf("%v: vol count[%v] used[%v]", ErrUserQuotaExceeded, quota.VolCount, usage.VolCount)
+	}
+	if quota.ObjectCount > 0 && usage.ObjectCount >= quota.ObjectCount {
+		return fmt.Errorf("%v: object count[%v] used[%v]", ErrUserQuotaExceeded, quota.ObjectCount, usage.ObjectCount)
+	}
+	return nil
+}
+
 func (i *UserInfo) String() string {
 	if i == nil {
 		return "nil"
@@ -219,6 +259,24 @@ func (policy *UserPolicy) RemoveAuthorizedVol(volume string) {
 	delete(policy.AuthorizedVols, volume)
 }
 
+// RenameVol replaces the volume owned or authorized by the new name, and returns if the policy is changed.
+func (policy *UserPolicy) RenameVol(volume, newName string) (changed bool) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	for i, ownVol := range policy.OwnVols {
+		if ownVol == volume {
+			policy.OwnVols[i] = newName
+			changed = true
+		}
+	}
+	if actions, ok := policy.AuthorizedVols[volume]; ok {
+		delete(policy.AuthorizedVols, volume)
+		policy.AuthorizedVols[newName] = actions
+		changed = true
+	}
+	return
+}
+
 func (policy *UserPolicy) SetPerm(volume string, perm Permission) {
 	policy.mu.Lock()
 	defer policy.mu.Unlock()
@@ -339,6 +397,11 @@ type UserTransferVolParam struct {
 	Force   bool   `json:"force"`
 }
 
+type UserQuotaUpdateParam struct {
+	UserID string    `json:"user_id"`
+	Quota  UserQuota `json:"quota"`
+}
+
 type UserUpdateParam struct {
 	UserID      string   `json:"user_id"`
 	AccessKey   string   `json:"access_key"`
@@ -59,6 +59,11 @@ func (rs *RocksDBStore) Open(lruCacheSize, writeBufferSize int) error {
 
 }
 
+// Close closes the RocksDB instance.
+func (rs *RocksDBStore) Close() {
+	rs.db.Close()
+}
+
 // Del deletes a key-value pair.
 func (rs *RocksDBStore) Del(key interface{}, isSync bool) (result interface{}, err error) {
 	ro := gorocksdb.NewDefaultReadOptions()
@@ -166,6 +171,28 @@ func (rs *RocksDBStore) BatchPut(cmdMap map[string][]byte, isSync bool) error {
 	return nil
 }
 
+// BatchDeleteAndPut deletes the keys and puts the key-value pairs in the cmdMap in batch.
+func (rs *RocksDBStore) BatchDeleteAndPut(keys []string, cmdMap map[string][]byte, isSync bool) error {
+	wo := gorocksdb.NewDefaultWriteOptions()
+	wo.SetSync(isSync)
+	wb := gorocksdb.NewWriteBatch()
+	defer func() {
+		wo.Destroy()
+		wb.Destroy()
+	}()
+	for _, key := range keys {
+		wb.Delete([]byte(key))
+	}
+	for key, value := range cmdMap {
+		wb.Put([]byte(key), value)
+	}
+	if err := rs.db.Write(wo, wb); err != nil {
+		err = fmt.Errorf("action[batchDeleteAndPutToRocksDB],err:%v", err)
+		return err
+	}
+	return nil
+}
+
 // SeekForPrefix seeks for the place where the prefix is located in the snapshots.
 func (rs *RocksDBStore) SeekForPrefix(prefix []byte) (result map[string][]byte, err error) {
 	result = make(map[string][]byte)
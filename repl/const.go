@@ -44,3 +44,8 @@ const (
 	FollowerTransportExiting = 1
 	FollowerTransportExited  = -1
 )
+
+const (
+	MaxMissedExtents       = 4096
+	MissedExtentExpiration = 3600 // the missed extent not written for the time is sealed and repaired, in seconds
+)
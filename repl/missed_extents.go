@@ -0,0 +1,117 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package repl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var (
+	gMissedExtents = newMissedExtents()
+)
+
+type extentKey struct {
+	addr        string
+	partitionID uint64
+	extentID    uint64
+}
+
+// missedExtents records the extents written with quorum the followers have missed a packet of. They are shared by
+// the connections to the followers, so the follower stops receiving the extent even on a new connection, until
+// the lagging extent is repaired or it is sealed.
+type missedExtents struct {
+	sync.Mutex
+	extents map[extentKey]int64 // the last time the extent is written, in seconds
+}
+
+func newMissedExtents() *missedExtents {
+	return &missedExtents{extents: make(map[extentKey]int64)}
+}
+
+func (me *missedExtents) add(key extentKey) {
+	me.Lock()
+	defer me.Unlock()
+	now := time.Now().Unix()
+	if _, ok := me.extents[key]; !ok && len(me.extents) >= MaxMissedExtents {
+		me.evict(now)
+	}
+	me.extents[key] = now
+}
+
+// has returns if the follower has missed a packet of the extent, and keeps the extent being written from expiring.
+func (me *missedExtents) has(key extentKey) bool {
+	me.Lock()
+	defer me.Unlock()
+	writeTime, ok := me.extents[key]
+	if !ok {
+		return false
+	}
+	now := time.Now().Unix()
+	if now-writeTime > MissedExtentExpiration {
+		delete(me.extents, key)
+		return false
+	}
+	me.extents[key] = now
+	return true
+}
+
+// evict deletes the expired extents, or the least recently written one if none expires.
+// The hole the follower may leave in the evicted extent is still found by the verification of the lagging extent.
+func (me *missedExtents) evict(now int64) {
+	var (
+		oldest     extentKey
+		oldestTime int64
+	)
+	for key, writeTime := range me.extents {
+		if now-writeTime > MissedExtentExpiration {
+			delete(me.extents, key)
+			continue
+		}
+		if oldestTime == 0 || writeTime < oldestTime {
+			oldest, oldestTime = key, writeTime
+		}
+	}
+	if len(me.extents) < MaxMissedExtents {
+		return
+	}
+	delete(me.extents, oldest)
+	log.LogWarnf("action[evictMissedExtent] follower(%v) partition(%v) extent(%v) evicted",
+		oldest.addr, oldest.partitionID, oldest.extentID)
+}
+
+func (me *missedExtents) clear(partitionID uint64, extentIDs []uint64) {
+	ids := make(map[uint64]bool, len(extentIDs))
+	for _, extentID := range extentIDs {
+		ids[extentID] = true
+	}
+	me.Lock()
+	defer me.Unlock()
+	for key := range me.extents {
+		if key.partitionID == partitionID && ids[key.extentID] {
+			delete(me.extents, key)
+		}
+	}
+}
+
+// ClearMissedExtents forwards the extents to all the followers again once the lagging extents are repaired.
+func ClearMissedExtents(partitionID uint64, extentIDs []uint64) {
+	if len(extentIDs) == 0 {
+		return
+	}
+	gMissedExtents.clear(partitionID, extentIDs)
+}
@@ -16,6 +16,7 @@ package repl
 
 import (
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net"
 	"strings"
@@ -32,6 +33,7 @@ import (
 var (
 	ErrBadNodes       = errors.New("BadNodesErr")
 	ErrArgLenMismatch = errors.New("ArgLenMismatchErr")
+	ErrFollowerMissed = errors.New("FollowerMissedPacketErr")
 )
 
 type Packet struct {
@@ -43,11 +45,16 @@ type Packet struct {
 	TpObject        *exporter.TimePointCount
 	NeedReply       bool
 	OrgBuffer       []byte
+
+	QuorumWrite      bool       // acknowledged once a majority of the replicas persist the packet
+	quorumCh         chan error // results of the followers of the quorum write
+	laggingFollowers int        // followers not persisting the quorum write when it is acknowledged
 }
 
 type FollowerPacket struct {
 	proto.Packet
 	respCh chan error
+	quorum bool // the packet is written with quorum
 }
 
 func NewFollowerPacket() (fp *FollowerPacket) {
@@ -77,13 +84,16 @@ func (p *FollowerPacket) identificationErrorResultCode(errLog string, errMsg str
 	} else if strings.Contains(errMsg, storage.ParameterMismatchError.Error()) ||
 		strings.Contains(errMsg, ErrorUnknownOp.Error()) {
 		p.ResultCode = proto.OpArgMismatchErr
-	} else if strings.Contains(errMsg, proto.ErrDataPartitionNotExists.Error()) {
+	} else if strings.Contains(errMsg, proto.ErrDataPartitionNotExists.Error()) ||
+		strings.Contains(errMsg, proto.ErrChecksumMismatch.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
 	} else if strings.Contains(errMsg, storage.ExtentNotFoundError.Error()) ||
 		strings.Contains(errMsg, storage.ExtentHasBeenDeletedError.Error()) {
 		p.ResultCode = proto.OpNotExistErr
 	} else if strings.Contains(errMsg, storage.NoSpaceError.Error()) {
 		p.ResultCode = proto.OpDiskNoSpaceErr
+	} else if strings.Contains(errMsg, storage.ExtentSharedError.Error()) {
+		p.ResultCode = proto.OpNotPerm
 	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) {
 		p.ResultCode = proto.OpAgain
 	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
@@ -224,6 +234,53 @@ func NewExtentRepairReadPacket(partitionID uint64, extentID uint64, offset, size
 	return
 }
 
+// NewEcPacket returns a new packet to operate the erasure coded extents.
+func NewEcPacket(opcode uint8, partitionID uint64, extentID uint64, data []byte) (p *Packet) {
+	p = new(Packet)
+	p.ExtentID = extentID
+	p.PartitionID = partitionID
+	p.Magic = proto.ProtoMagic
+	p.Opcode = opcode
+	p.ExtentType = proto.NormalExtentType
+	p.Data = data
+	p.Size = uint32(len(data))
+	p.CRC = crc32.ChecksumIEEE(data)
+	p.ReqID = proto.GenerateRequestID()
+
+	return
+}
+
+// NewScrubExtentPacket returns a new packet to scrub the extent on a replica.
+func NewScrubExtentPacket(partitionID uint64, extentID uint64, data []byte) (p *Packet) {
+	p = new(Packet)
+	p.ExtentID = extentID
+	p.PartitionID = partitionID
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpScrubExtent
+	p.ExtentType = proto.NormalExtentType
+	p.Data = data
+	p.Size = uint32(len(data))
+	p.CRC = crc32.ChecksumIEEE(data)
+	p.ReqID = proto.GenerateRequestID()
+
+	return
+}
+
+// NewCompactTinyExtentPacket returns a new packet to reset the compacted tiny extent on a replica,
+// which is rejected if the extent is larger than the given size.
+func NewCompactTinyExtentPacket(partitionID uint64, extentID uint64, size int64) (p *Packet) {
+	p = new(Packet)
+	p.ExtentID = extentID
+	p.PartitionID = partitionID
+	p.ExtentOffset = size
+	p.Magic = proto.ProtoMagic
+	p.Opcode = proto.OpCompactTinyExtent
+	p.ExtentType = proto.TinyExtentType
+	p.ReqID = proto.GenerateRequestID()
+
+	return
+}
+
 func NewTinyExtentRepairReadPacket(partitionID uint64, extentID uint64, offset, size int) (p *Packet) {
 	p = new(Packet)
 	p.ExtentID = extentID
@@ -293,13 +350,16 @@ func (p *Packet) identificationErrorResultCode(errLog string, errMsg string) {
 	} else if strings.Contains(errMsg, storage.ParameterMismatchError.Error()) ||
 		strings.Contains(errMsg, ErrorUnknownOp.Error()) {
 		p.ResultCode = proto.OpArgMismatchErr
-	} else if strings.Contains(errMsg, proto.ErrDataPartitionNotExists.Error()) {
+	} else if strings.Contains(errMsg, proto.ErrDataPartitionNotExists.Error()) ||
+		strings.Contains(errMsg, proto.ErrChecksumMismatch.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
 	} else if strings.Contains(errMsg, storage.ExtentNotFoundError.Error()) ||
 		strings.Contains(errMsg, storage.ExtentHasBeenDeletedError.Error()) {
 		p.ResultCode = proto.OpNotExistErr
 	} else if strings.Contains(errMsg, storage.NoSpaceError.Error()) {
 		p.ResultCode = proto.OpDiskNoSpaceErr
+	} else if strings.Contains(errMsg, storage.ExtentSharedError.Error()) {
+		p.ResultCode = proto.OpNotPerm
 	} else if strings.Contains(errMsg, storage.TryAgainError.Error()) {
 		p.ResultCode = proto.OpAgain
 	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
@@ -370,12 +430,20 @@ func (p *Packet) IsMasterCommand() bool {
 		proto.OpDecommissionDataPartition,
 		proto.OpAddDataPartitionRaftMember,
 		proto.OpRemoveDataPartitionRaftMember,
-		proto.OpDataPartitionTryToLeader:
+		proto.OpDataPartitionTryToLeader,
+		proto.OpConvertDataPartitionToEc,
+		proto.OpShareDataPartition:
 		return true
 	}
 	return false
 }
 
+// IsEcShardOperation returns if the packet operates the erasure coded shards,
+// which may be stored on the data node without the data partition.
+func (p *Packet) IsEcShardOperation() bool {
+	return p.Opcode == proto.OpWriteEcShard || p.Opcode == proto.OpReadEcShard || p.Opcode == proto.OpDeleteEcShard
+}
+
 func (p *Packet) IsForwardPacket() bool {
 	r := p.RemainingFollowers > 0
 	return r
@@ -394,6 +462,11 @@ func (p *Packet) IsTinyExtentType() bool {
 	return p.ExtentType == proto.TinyExtentType
 }
 
+// HasLaggingFollowers returns if the quorum write is acknowledged before all the followers persist it.
+func (p *Packet) HasLaggingFollowers() bool {
+	return p.laggingFollowers > 0
+}
+
 func (p *Packet) IsWriteOperation() bool {
 	return p.Opcode == proto.OpWrite || p.Opcode == proto.OpSyncWrite
 }
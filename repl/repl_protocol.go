@@ -45,7 +45,7 @@ type ReplProtocol struct {
 	toBeProcessedCh chan *Packet // the goroutine receives an available packet and then sends it to this channel
 	responseCh      chan *Packet // this chan is used to write response to the client
 
-	sourceConn *net.TCPConn
+	sourceConn net.Conn
 	exitC      chan bool
 	exited     int32
 	exitedMu   sync.RWMutex
@@ -54,7 +54,7 @@ type ReplProtocol struct {
 	lock             sync.RWMutex
 
 	prepareFunc  func(p *Packet) error                 // prepare packet
-	operatorFunc func(p *Packet, c *net.TCPConn) error // operator
+	operatorFunc func(p *Packet, c net.Conn) error // operator
 	postFunc     func(p *Packet) error                 // post-processing packet
 
 	isError int32
@@ -69,6 +69,8 @@ type FollowerTransport struct {
 	exitCh   chan struct{}
 	exitedMu sync.RWMutex
 	isclosed int32
+
+	missed *missedExtents
 }
 
 func NewFollowersTransport(addr string) (ft *FollowerTransport, err error) {
@@ -84,6 +86,7 @@ func NewFollowersTransport(addr string) (ft *FollowerTransport, err error) {
 	ft.sendCh = make(chan *FollowerPacket, 200)
 	ft.recvCh = make(chan *FollowerPacket, 200)
 	ft.exitCh = make(chan struct{})
+	ft.missed = gMissedExtents
 	go ft.serverWriteToFollower()
 	go ft.serverReadFromFollower()
 
@@ -96,7 +99,7 @@ func (ft *FollowerTransport) serverWriteToFollower() {
 		case p := <-ft.sendCh:
 			if err := p.WriteToConn(ft.conn); err != nil {
 				p.PackErrorBody(ActionSendToFollowers, err.Error())
-				p.respCh <- fmt.Errorf(string(p.Data[:p.Size]))
+				p.respCh <- ft.quorumResult(p, fmt.Errorf(string(p.Data[:p.Size])))
 				ft.conn.Close()
 				continue
 			}
@@ -135,6 +138,7 @@ func (ft *FollowerTransport) readFollowerResult(request *FollowerPacket) (err er
 	reply := NewPacket()
 	defer func() {
 		reply.clean()
+		err = ft.quorumResult(request, err)
 		request.respCh <- err
 		if err != nil {
 			ft.conn.Close()
@@ -168,6 +172,29 @@ func (ft *FollowerTransport) readFollowerResult(request *FollowerPacket) (err er
 	return
 }
 
+// quorumResult records the extent once the follower misses a packet written with quorum. The packets following it
+// may have been sent already and persisted by the follower with a hole in between, so they are not counted as
+// acknowledged either, and the follower is caught up by the repair of the lagging extent.
+func (ft *FollowerTransport) quorumResult(p *FollowerPacket, err error) error {
+	if !p.quorum {
+		return err
+	}
+	key := extentKey{addr: ft.addr, partitionID: p.PartitionID, extentID: p.ExtentID}
+	if err != nil {
+		ft.missed.add(key)
+		return err
+	}
+	if ft.missed.has(key) {
+		return ErrFollowerMissed
+	}
+	return nil
+}
+
+// hasMissed returns if the follower has missed a packet of the extent written with quorum.
+func (ft *FollowerTransport) hasMissed(partitionID, extentID uint64) bool {
+	return ft.missed.has(extentKey{addr: ft.addr, partitionID: partitionID, extentID: extentID})
+}
+
 func (ft *FollowerTransport) Destory() {
 	ft.exitedMu.Lock()
 	atomic.StoreInt32(&ft.isclosed, FollowerTransportExiting)
@@ -187,8 +214,8 @@ func (ft *FollowerTransport) Write(p *FollowerPacket) {
 	ft.sendCh <- p
 }
 
-func NewReplProtocol(inConn *net.TCPConn, prepareFunc func(p *Packet) error,
-	operatorFunc func(p *Packet, c *net.TCPConn) error, postFunc func(p *Packet) error) *ReplProtocol {
+func NewReplProtocol(inConn net.Conn, prepareFunc func(p *Packet) error,
+	operatorFunc func(p *Packet, c net.Conn) error, postFunc func(p *Packet) error) *ReplProtocol {
 	rp := new(ReplProtocol)
 	rp.packetList = list.New()
 	rp.ackCh = make(chan struct{}, RequestChanSize)
@@ -284,15 +311,33 @@ func (rp *ReplProtocol) readPkgAndPrepare() (err error) {
 }
 
 func (rp *ReplProtocol) sendRequestToAllFollowers(request *Packet) (index int, err error) {
+	if request.QuorumWrite {
+		request.quorumCh = make(chan error, len(request.followersAddrs))
+	}
 	for index = 0; index < len(request.followersAddrs); index++ {
 		var transport *FollowerTransport
 		if transport, err = rp.allocateFollowersConns(request, index); err != nil {
+			if request.QuorumWrite {
+				// the unreachable follower is caught up by the repair if a majority persists the packet
+				request.quorumCh <- err
+				err = nil
+				continue
+			}
 			request.PackErrorBody(ActionSendToFollowers, err.Error())
 			return
 		}
+		if request.QuorumWrite && transport.hasMissed(request.PartitionID, request.ExtentID) {
+			// the follower missing a packet stops receiving the extent, otherwise it leaves a hole in the extent
+			request.quorumCh <- ErrFollowerMissed
+			continue
+		}
 		followerRequest := NewFollowerPacket()
 		copyPacket(request, followerRequest)
 		followerRequest.RemainingFollowers = 0
+		if request.QuorumWrite {
+			followerRequest.respCh = request.quorumCh
+			followerRequest.quorum = true
+		}
 		request.followerPackets[index] = followerRequest
 		transport.Write(followerRequest)
 	}
@@ -378,6 +423,10 @@ func (rp *ReplProtocol) checkLocalResultAndReciveAllFollowerResponse() {
 	if request.IsErrPacket() {
 		return
 	}
+	if request.QuorumWrite {
+		rp.receiveQuorumFollowerResponse(request)
+		return
+	}
 	for index := 0; index < len(request.followersAddrs); index++ {
 		followerPacket := request.followerPackets[index]
 		err := <-followerPacket.respCh
@@ -390,6 +439,32 @@ func (rp *ReplProtocol) checkLocalResultAndReciveAllFollowerResponse() {
 	return
 }
 
+// receiveQuorumFollowerResponse waits for the responses of the followers until a majority of the replicas,
+// including the leader which has persisted the packet, persist the packet.
+func (rp *ReplProtocol) receiveQuorumFollowerResponse(request *Packet) {
+	followers := len(request.followersAddrs)
+	needAcks := (followers + 1) / 2
+	var acks, fails int
+	defer func() {
+		request.laggingFollowers = followers - acks
+		if acks+fails < followers {
+			// the buffer is still being sent to the slow followers, and is garbage collected afterwards
+			request.OrgBuffer = nil
+		}
+	}()
+	for acks < needAcks {
+		err := <-request.quorumCh
+		if err == nil {
+			acks++
+			continue
+		}
+		if fails++; followers-fails < needAcks {
+			request.PackErrorBody(ActionReceiveFromFollower, err.Error())
+			return
+		}
+	}
+}
+
 // Write a reply to the client.
 func (rp *ReplProtocol) writeResponse(reply *Packet) {
 	var err error
@@ -0,0 +1,81 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package repl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuorumResultAfterMiss(t *testing.T) {
+	ft := &FollowerTransport{addr: "127.0.0.1:17310", missed: newMissedExtents()}
+	newPacket := func(extentID uint64, quorum bool) *FollowerPacket {
+		p := NewFollowerPacket()
+		p.PartitionID = 1
+		p.ExtentID = extentID
+		p.quorum = quorum
+		return p
+	}
+
+	if err := ft.quorumResult(newPacket(1025, true), nil); err != nil {
+		t.Fatalf("unexpected err(%v)", err)
+	}
+	missErr := errors.New("miss")
+	if err := ft.quorumResult(newPacket(1025, true), missErr); err != missErr {
+		t.Fatalf("unexpected err(%v)", err)
+	}
+	if !ft.hasMissed(1, 1025) || ft.hasMissed(1, 1026) {
+		t.Fatal("unexpected missed extents")
+	}
+	// the packet following the missed one is not acknowledged even if the follower persists it
+	if err := ft.quorumResult(newPacket(1025, true), nil); err != ErrFollowerMissed {
+		t.Fatalf("unexpected err(%v)", err)
+	}
+	if err := ft.quorumResult(newPacket(1026, true), nil); err != nil {
+		t.Fatalf("unexpected err(%v)", err)
+	}
+	// the packets written to all the replicas are not tracked
+	if err := ft.quorumResult(newPacket(1027, false), missErr); err != missErr || ft.hasMissed(1, 1027) {
+		t.Fatalf("unexpected err(%v)", err)
+	}
+}
+
+func TestMissedExtents(t *testing.T) {
+	me := newMissedExtents()
+	key := extentKey{addr: "127.0.0.1:17310", partitionID: 1, extentID: 1025}
+	me.add(key)
+	me.add(extentKey{addr: "127.0.0.1:17310", partitionID: 2, extentID: 1025})
+	// the repaired extent is forwarded to the follower again
+	me.clear(1, []uint64{1025})
+	if me.has(key) || len(me.extents) != 1 {
+		t.Fatalf("unexpected missed extents(%v)", me.extents)
+	}
+	// the extent not written for the expiration is sealed
+	me.add(key)
+	me.extents[key] -= MissedExtentExpiration + 1
+	if me.has(key) {
+		t.Fatal("expired extent not deleted")
+	}
+	// the least recently written extent is evicted once full
+	for i := 0; i < MaxMissedExtents; i++ {
+		me.add(extentKey{addr: "127.0.0.1:17310", partitionID: 3, extentID: uint64(i)})
+	}
+	me.extents[extentKey{addr: "127.0.0.1:17310", partitionID: 3, extentID: 7}] -= 10
+	me.add(key)
+	if len(me.extents) != MaxMissedExtents || !me.has(key) ||
+		me.has(extentKey{addr: "127.0.0.1:17310", partitionID: 3, extentID: 7}) {
+		t.Fatalf("unexpected missed extents count(%v)", len(me.extents))
+	}
+}
@@ -32,6 +32,10 @@ type AppendExtentKeyFunc func(inode uint64, key proto.ExtentKey) error
 type GetExtentsFunc func(inode uint64) (uint64, uint64, []proto.ExtentKey, error)
 type TruncateFunc func(inode, size uint64) error
 type EvictIcacheFunc func(inode uint64)
+type ReplaceExtentKeysFunc func(inode uint64, oldEks []proto.ExtentKey, ek proto.ExtentKey) error
+type PrepareExtentKeyFunc func(inode uint64, ek proto.ExtentKey) error
+type DiscardExtentKeyFunc func(inode uint64, ek proto.ExtentKey) error
+type GetTieredExtentsFunc func(inode uint64) (uint64, uint64, []proto.ExtentKey, []proto.TierExtent, error)
 
 const (
 	MaxMountRetryLimit = 5
@@ -87,6 +91,17 @@ type ExtentConfig struct {
 	OnGetExtents      GetExtentsFunc
 	OnTruncate        TruncateFunc
 	OnEvictIcache     EvictIcacheFunc
+	// Optional, defragmentation is disabled if it is not set.
+	OnReplaceExtentKeys ReplaceExtentKeysFunc
+	OnPrepareExtentKey  PrepareExtentKeyFunc
+	OnDiscardExtentKey  DiscardExtentKeyFunc
+	// Idle streamers with at least DefragThreshold extent keys are defragmented
+	// automatically, zero means never.
+	DefragThreshold int
+	// Optional, the extents migrated to the tier store can not be read if it is not set.
+	OnGetTieredExtents GetTieredExtentsFunc
+	// Optional, the tier store is accessed without the keys if it is not set.
+	OnGetTierSecure wrapper.TierSecureFunc
 }
 
 // ExtentClient defines the struct of the extent client.
@@ -102,6 +117,13 @@ type ExtentClient struct {
 	getExtents      GetExtentsFunc
 	truncate        TruncateFunc
 	evictIcache     EvictIcacheFunc //May be null, must check before using
+
+	replaceExtentKeys ReplaceExtentKeysFunc //May be null, must check before using
+	prepareExtentKey  PrepareExtentKeyFunc  //May be null, must check before using
+	discardExtentKey  DiscardExtentKeyFunc  //May be null, must check before using
+	defragThreshold   int
+
+	getTieredExtents GetTieredExtentsFunc //May be null, must check before using
 }
 
 // NewExtentClient returns a new extent client.
@@ -110,7 +132,7 @@ func NewExtentClient(config *ExtentConfig) (client *ExtentClient, err error) {
 
 	limit := MaxMountRetryLimit
 retry:
-	client.dataWrapper, err = wrapper.NewDataPartitionWrapper(config.Volume, config.Masters)
+	client.dataWrapper, err = wrapper.NewDataPartitionWrapper(config.Volume, config.Masters, config.OnGetTierSecure)
 	if err != nil {
 		if limit <= 0 {
 			return nil, errors.Trace(err, "Init data wrapper failed!")
@@ -126,6 +148,11 @@ retry:
 	client.getExtents = config.OnGetExtents
 	client.truncate = config.OnTruncate
 	client.evictIcache = config.OnEvictIcache
+	client.replaceExtentKeys = config.OnReplaceExtentKeys
+	client.prepareExtentKey = config.OnPrepareExtentKey
+	client.discardExtentKey = config.OnDiscardExtentKey
+	client.defragThreshold = config.DefragThreshold
+	client.getTieredExtents = config.OnGetTieredExtents
 	client.dataWrapper.InitFollowerRead(config.FollowerRead)
 	client.dataWrapper.SetNearRead(config.NearRead)
 
@@ -186,6 +213,16 @@ func (client *ExtentClient) EvictStream(inode uint64) error {
 	return nil
 }
 
+// Defragment rewrites the runs of at least minFragments contiguous extent keys
+// of the inode into new extents. The stream must have been opened.
+func (client *ExtentClient) Defragment(inode uint64, minFragments int) error {
+	s := client.GetStreamer(inode)
+	if s == nil {
+		return fmt.Errorf("Defragment: stream is not opened yet, ino(%v)", inode)
+	}
+	return s.IssueDefragRequest(minFragments)
+}
+
 // RefreshExtentsCache refreshes the extent cache.
 func (client *ExtentClient) RefreshExtentsCache(inode uint64) error {
 	s := client.GetStreamer(inode)
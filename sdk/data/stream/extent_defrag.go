@@ -0,0 +1,164 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// max size of the data rewritten into one new extent
+	defragMaxRangeSize = 32 * util.MB
+	// number of idle ticks before an idle streamer is defragmented automatically
+	defragIdlePeriod = 5
+)
+
+// autoDefragment defragments the streamer once it has been idle for a while
+// and the number of the extent keys reaches the threshold.
+func (s *Streamer) autoDefragment() {
+	threshold := s.client.defragThreshold
+	if threshold <= 0 || s.client.replaceExtentKeys == nil || s.traversed != defragIdlePeriod {
+		return
+	}
+	if s.extents.Len() < threshold {
+		return
+	}
+	if err := s.defragment(2); err != nil {
+		log.LogWarnf("autoDefragment: ino(%v) err(%v)", s.inode, err)
+	}
+}
+
+// defragment rewrites every run of at least minFragments contiguous extent keys
+// into a new extent, and replaces the run with the new extent key in the meta node.
+func (s *Streamer) defragment(minFragments int) (err error) {
+	if s.client.replaceExtentKeys == nil {
+		return syscall.ENOTSUP
+	}
+	if minFragments < 2 {
+		minFragments = 2
+	}
+
+	s.closeOpenHandler()
+	if err = s.flush(); err != nil {
+		return
+	}
+	// make sure the extent keys to be replaced are the same as the remote ones
+	if err = s.GetExtents(); err != nil {
+		return
+	}
+
+	ranges := planDefragRanges(s.extents.List(), minFragments, defragMaxRangeSize)
+	log.LogDebugf("defragment: ino(%v) extents(%v) ranges(%v)", s.inode, s.extents.Len(), len(ranges))
+
+	for _, eks := range ranges {
+		if err = s.defragRange(eks); err != nil {
+			log.LogErrorf("defragment: ino(%v) eks(%v) err(%v)", s.inode, eks, err)
+			// refresh the extent keys in case they are changed by others
+			s.GetExtents()
+			return
+		}
+	}
+	return
+}
+
+func (s *Streamer) defragRange(eks []proto.ExtentKey) (err error) {
+	offset := int(eks[0].FileOffset)
+	size := 0
+	for _, ek := range eks {
+		size += int(ek.Size)
+	}
+
+	data, err := s.readDefragRange(offset, size)
+	if err != nil {
+		return
+	}
+
+	eh := NewExtentHandler(s, offset, proto.NormalExtentType)
+	eh.replacedKeys = eks
+	eh.replacedCRC = crc32.ChecksumIEEE(data)
+	defer eh.cleanup()
+
+	if _, err = eh.write(data, offset, size, false); err != nil {
+		return
+	}
+	return eh.flush()
+}
+
+func (s *Streamer) readDefragRange(offset, size int) (data []byte, err error) {
+	data = make([]byte, size)
+	read, err := s.read(data, offset, size)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if read != size {
+		return nil, errors.New(fmt.Sprintf("defragRange: read size mismatch, ino(%v) offset(%v) size(%v) read(%v)", s.inode, offset, size, read))
+	}
+	return data, nil
+}
+
+// verifyDefragData reads the data of the extent keys again right before they are replaced,
+// since the data overwritten in place by others changes no extent key.
+func (s *Streamer) verifyDefragData(eks []proto.ExtentKey, crc uint32) error {
+	offset := int(eks[0].FileOffset)
+	size := 0
+	for _, ek := range eks {
+		size += int(ek.Size)
+	}
+	data, err := s.readDefragRange(offset, size)
+	if err != nil {
+		return err
+	}
+	if actual := crc32.ChecksumIEEE(data); actual != crc {
+		return errors.New(fmt.Sprintf("verifyDefragData: data changed during defragmentation, ino(%v) offset(%v) size(%v) crc(%v) actual(%v)", s.inode, offset, size, crc, actual))
+	}
+	return nil
+}
+
+// planDefragRanges collects the runs of contiguous extent keys, whose total size
+// does not exceed maxSize, and which consist of at least minFragments keys.
+func planDefragRanges(extents []*proto.ExtentKey, minFragments int, maxSize uint64) (ranges [][]proto.ExtentKey) {
+	var (
+		run     []proto.ExtentKey
+		runSize uint64
+		end     uint64
+	)
+
+	flushRun := func() {
+		if len(run) >= minFragments {
+			ranges = append(ranges, run)
+		}
+		run = nil
+		runSize = 0
+	}
+
+	for _, ek := range extents {
+		if len(run) > 0 && (ek.FileOffset != end || runSize+uint64(ek.Size) > maxSize) {
+			flushRun()
+		}
+		run = append(run, *ek)
+		runSize += uint64(ek.Size)
+		end = ek.FileOffset + uint64(ek.Size)
+	}
+	flushRun()
+	return
+}
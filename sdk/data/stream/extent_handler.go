@@ -17,6 +17,7 @@ package stream
 import (
 	"fmt"
 	"net"
+	"sort"
 	"sync/atomic"
 	"time"
 
@@ -74,7 +75,7 @@ type ExtentHandler struct {
 
 	// Allocated in the sender, and released in the receiver.
 	// Will not be changed.
-	conn *net.TCPConn
+	conn net.Conn
 	dp   *wrapper.DataPartition
 
 	// Issue a signal to this channel when *inflight* hits zero.
@@ -87,10 +88,23 @@ type ExtentHandler struct {
 	key   *proto.ExtentKey
 	dirty bool // indicate if open handler is dirty.
 
+	// Packets acknowledged while an earlier packet is sent again, keyed by the extent offset.
+	// They are added to the key once the earlier one is acknowledged, or recovered with it.
+	// The packets to recover wait for the ones being sent again, so that they are recovered
+	// in the order of the file offset. Updated in *receiver* ONLY.
+	pending    map[uint64]*Packet
+	resending  int
+	recovering []*Packet
+
 	// Created in receiver ONLY in recovery status.
 	// Will not be changed once assigned.
 	recoverHandler *ExtentHandler
 
+	// Extent keys to be replaced by the key of this handler, and the crc of
+	// their data, set for defragmentation ONLY. Such handler can not be recovered.
+	replacedKeys []proto.ExtentKey
+	replacedCRC  uint32
+
 	// The stream writer gets the write requests, and constructs the packets
 	// to be sent to the request channel.
 	// The *sender* gets the packets from the *request* channel, sends it to the corresponding data
@@ -238,7 +252,7 @@ func (eh *ExtentHandler) sender() {
 
 			//log.LogDebugf("ExtentHandler sender: extent allocated, eh(%v) dp(%v) extID(%v) packet(%v)", eh, eh.dp, eh.extID, packet.GetUniqueLogId())
 
-			if err = packet.writeToConn(eh.conn); err != nil {
+			if err = packet.writeToConn(eh.conn, eh.dp.Checksum); err != nil {
 				log.LogWarnf("sender writeTo: failed, eh(%v) err(%v) packet(%v)", eh, err, packet)
 				eh.setClosed()
 				eh.setRecovery()
@@ -283,17 +297,24 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 
 	//log.LogDebugf("processReply enter: eh(%v) packet(%v)", eh, packet.GetUniqueLogId())
 
+	if packet.resent {
+		packet.resent = false
+		eh.resending--
+	}
+
 	status := eh.getStatus()
 	if status >= ExtentStatusError {
 		eh.discardPacket(packet)
 		log.LogErrorf("processReply discard packet: handler is in error status, inflight(%v) eh(%v) packet(%v)", atomic.LoadInt32(&eh.inflight), eh, packet)
+		if eh.resending == 0 {
+			for _, p := range eh.recovering {
+				eh.discardPacket(p)
+			}
+			eh.recovering = nil
+		}
 		return
 	} else if status >= ExtentStatusRecovery {
-		if err := eh.recoverPacket(packet); err != nil {
-			eh.discardPacket(packet)
-			log.LogErrorf("processReply discard packet: handler is in recovery status, inflight(%v) eh(%v) packet(%v) err(%v)", atomic.LoadInt32(&eh.inflight), eh, packet, err)
-		}
-		log.LogDebugf("processReply recover packet: handler is in recovery status, inflight(%v) from eh(%v) to recoverHandler(%v) packet(%v)", atomic.LoadInt32(&eh.inflight), eh, eh.recoverHandler, packet)
+		eh.recoverInOrder(packet, "handler is in recovery status")
 		return
 	}
 
@@ -306,6 +327,11 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 
 	log.LogDebugf("processReply: get reply, eh(%v) packet(%v) reply(%v)", eh, packet, reply)
 
+	if reply.ResultCode == proto.OpAgain && packet.againCount < StreamSendMaxRetry {
+		eh.resendPacket(packet)
+		return
+	}
+
 	if reply.ResultCode != proto.OpOk {
 		errmsg := fmt.Sprintf("reply NOK: reply(%v)", reply)
 		eh.processReplyError(packet, errmsg)
@@ -338,6 +364,35 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 		extOffset = packet.KernelOffset - uint64(eh.fileOffset)
 	}
 
+	// the packets after the one sent again are kept until it is acknowledged
+	if eh.storeMode == proto.NormalExtentType && extOffset != eh.keyEnd() {
+		if eh.pending == nil {
+			eh.pending = make(map[uint64]*Packet)
+		}
+		eh.pending[extOffset] = packet
+		return
+	}
+	eh.appendToKey(packet, extID, extOffset)
+	for eh.storeMode == proto.NormalExtentType {
+		next, ok := eh.pending[eh.keyEnd()]
+		if !ok {
+			break
+		}
+		delete(eh.pending, eh.keyEnd())
+		eh.appendToKey(next, extID, eh.keyEnd())
+	}
+	return
+}
+
+// keyEnd returns the extent offset of the next packet to be added to the key.
+func (eh *ExtentHandler) keyEnd() uint64 {
+	if eh.key == nil {
+		return 0
+	}
+	return eh.key.ExtentOffset + uint64(eh.key.Size)
+}
+
+func (eh *ExtentHandler) appendToKey(packet *Packet, extID, extOffset uint64) {
 	if eh.key == nil {
 		eh.key = &proto.ExtentKey{
 			FileOffset:   uint64(eh.fileOffset),
@@ -353,18 +408,52 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 	proto.Buffers.Put(packet.Data)
 	packet.Data = nil
 	eh.dirty = true
-	return
+}
+
+// resendPacket sends the packet throttled by the data node again on the same extent after backing off,
+// which is not counted as an error of the packet. The receiver goes on with the other replies meanwhile.
+func (eh *ExtentHandler) resendPacket(packet *Packet) {
+	packet.againCount++
+	packet.resent = true
+	eh.resending++
+	// Increase before the deferred decrease in processReply, so the handler is not flushed meanwhile.
+	atomic.AddInt32(&eh.inflight, 1)
+	time.AfterFunc(StreamSendSleepInterval, func() {
+		eh.request <- packet
+	})
+	log.LogDebugf("resendPacket: eh(%v) packet(%v) times(%v)", eh, packet, packet.againCount)
 }
 
 func (eh *ExtentHandler) processReplyError(packet *Packet, errmsg string) {
 	eh.setClosed()
 	eh.setRecovery()
-	if err := eh.recoverPacket(packet); err != nil {
-		eh.discardPacket(packet)
-		log.LogErrorf("processReplyError discard packet: eh(%v) packet(%v) err(%v) errmsg(%v)", eh, packet, err, errmsg)
-	} else {
-		log.LogWarnf("processReplyError recover packet: from eh(%v) to recoverHandler(%v) packet(%v) errmsg(%v)", eh, eh.recoverHandler, packet, errmsg)
+	// the packets acknowledged after the failed one are not referred by the key
+	for offset, p := range eh.pending {
+		eh.recovering = append(eh.recovering, p)
+		delete(eh.pending, offset)
 	}
+	eh.recoverInOrder(packet, errmsg)
+}
+
+// recoverInOrder recovers the packet along with the ones waiting, unless some packets are being sent again,
+// which are recovered before the packets following them.
+func (eh *ExtentHandler) recoverInOrder(packet *Packet, errmsg string) {
+	eh.recovering = append(eh.recovering, packet)
+	if eh.resending > 0 {
+		return
+	}
+	sort.Slice(eh.recovering, func(i, j int) bool {
+		return eh.recovering[i].KernelOffset < eh.recovering[j].KernelOffset
+	})
+	for _, p := range eh.recovering {
+		if err := eh.recoverPacket(p); err != nil {
+			eh.discardPacket(p)
+			log.LogErrorf("recoverInOrder discard packet: eh(%v) packet(%v) err(%v) errmsg(%v)", eh, p, err, errmsg)
+		} else {
+			log.LogWarnf("recoverInOrder recover packet: from eh(%v) to recoverHandler(%v) packet(%v) errmsg(%v)", eh, eh.recoverHandler, p, errmsg)
+		}
+	}
+	eh.recovering = nil
 }
 
 func (eh *ExtentHandler) flush() (err error) {
@@ -406,6 +495,9 @@ func (eh *ExtentHandler) cleanup() (err error) {
 // can ONLY be called when the handler is not open any more
 func (eh *ExtentHandler) appendExtentKey() (err error) {
 	//log.LogDebugf("appendExtentKey enter: eh(%v)", eh)
+	if eh.replacedKeys != nil {
+		return eh.replaceExtentKeys()
+	}
 	if eh.key != nil {
 		if eh.dirty {
 			eh.stream.extents.Append(eh.key, true)
@@ -421,6 +513,36 @@ func (eh *ExtentHandler) appendExtentKey() (err error) {
 	return
 }
 
+// replace the extent keys with the key of the defragment handler, which is
+// committed ONLY if all the data has been written into a single extent, and
+// the data of the extent keys is not changed meanwhile. The new extent is
+// discarded otherwise.
+func (eh *ExtentHandler) replaceExtentKeys() (err error) {
+	if !eh.dirty {
+		return
+	}
+	defer func() {
+		if err != nil && eh.key != nil && eh.stream.client.discardExtentKey != nil {
+			if discardErr := eh.stream.client.discardExtentKey(eh.inode, *eh.key); discardErr != nil {
+				log.LogWarnf("replaceExtentKeys: discard extent failed, eh(%v) ek(%v) err(%v)", eh, eh.key, discardErr)
+			}
+			eh.dirty = false
+		}
+	}()
+	if eh.getStatus() >= ExtentStatusRecovery || eh.key == nil || int(eh.key.Size) != eh.size {
+		return errors.New(fmt.Sprintf("replaceExtentKeys: incomplete extent, eh(%v) size(%v) ek(%v)", eh, eh.size, eh.key))
+	}
+	if err = eh.stream.verifyDefragData(eh.replacedKeys, eh.replacedCRC); err != nil {
+		return
+	}
+	if err = eh.stream.client.replaceExtentKeys(eh.inode, eh.replacedKeys, *eh.key); err != nil {
+		return
+	}
+	eh.stream.extents.Append(eh.key, true)
+	eh.dirty = false
+	return
+}
+
 // This function is meaningful to be called from stream writer flush method,
 // because there is no new write request.
 func (eh *ExtentHandler) waitForFlush() {
@@ -450,6 +572,9 @@ func (eh *ExtentHandler) recoverPacket(packet *Packet) error {
 	if packet.errCount >= MaxPacketErrorCount {
 		return errors.New(fmt.Sprintf("recoverPacket failed: reach max error limit, eh(%v) packet(%v)", eh, packet))
 	}
+	if eh.replacedKeys != nil {
+		return errors.New(fmt.Sprintf("recoverPacket failed: defragment handler, eh(%v) packet(%v)", eh, packet))
+	}
 
 	handler := eh.recoverHandler
 	if handler == nil {
@@ -478,7 +603,7 @@ func (eh *ExtentHandler) discardPacket(packet *Packet) {
 func (eh *ExtentHandler) allocateExtent() (err error) {
 	var (
 		dp    *wrapper.DataPartition
-		conn  *net.TCPConn
+		conn  net.Conn
 		extID int
 	)
 
@@ -504,6 +629,15 @@ func (eh *ExtentHandler) allocateExtent() (err error) {
 			continue
 		}
 
+		// record the extent of the defragment handler before it is written, so that
+		// it can be discarded if the replacement is abandoned
+		if eh.replacedKeys != nil && eh.stream.client.prepareExtentKey != nil {
+			ek := proto.ExtentKey{PartitionId: dp.PartitionID, ExtentId: uint64(extID)}
+			if err = eh.stream.client.prepareExtentKey(eh.inode, ek); err != nil {
+				return errors.Trace(err, "allocateExtent: failed to prepare ek(%v), eh(%v)", ek, eh)
+			}
+		}
+
 		if conn, err = StreamConnPool.GetConnect(dp.Hosts[0]); err != nil {
 			log.LogWarnf("allocateExtent: failed to create connection, eh(%v) err(%v) dp(%v) exclude(%v)",
 				eh, err, dp, exclude)
@@ -530,16 +664,8 @@ func (eh *ExtentHandler) allocateExtent() (err error) {
 	return err
 }
 
-func (eh *ExtentHandler) createConnection(dp *wrapper.DataPartition) (*net.TCPConn, error) {
-	conn, err := net.DialTimeout("tcp", dp.Hosts[0], time.Second)
-	if err != nil {
-		return nil, err
-	}
-	connect := conn.(*net.TCPConn)
-	// TODO unhandled error
-	connect.SetKeepAlive(true)
-	connect.SetNoDelay(true)
-	return connect, nil
+func (eh *ExtentHandler) createConnection(dp *wrapper.DataPartition) (net.Conn, error) {
+	return util.DailTimeOut(dp.Hosts[0], time.Second)
 }
 
 func (eh *ExtentHandler) createExtent(dp *wrapper.DataPartition) (extID int, err error) {
@@ -21,10 +21,12 @@ import (
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/log"
-	"hash/crc32"
 	"net"
+	"strings"
 )
 
+var errChecksumMismatch = errors.New("checksum mismatch")
+
 // ExtentReader defines the struct of the extent reader.
 type ExtentReader struct {
 	inode        uint64
@@ -59,7 +61,7 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 
 	log.LogDebugf("ExtentReader Read enter: size(%v) req(%v) reqPacket(%v)", size, req, reqPacket)
 
-	err = sc.Send(reqPacket, func(conn *net.TCPConn) (error, bool) {
+	err = sc.Send(reqPacket, func(conn net.Conn) (error, bool) {
 		readBytes = 0
 		for readBytes < size {
 			replyPacket := NewReply(reqPacket.ReqID, reader.dp.PartitionID, reqPacket.ExtentID)
@@ -79,6 +81,12 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 			}
 
 			e = reader.checkStreamReply(reqPacket, replyPacket)
+			if e == errChecksumMismatch {
+				// the data is read from the other replicas instead, which serve the reads as followers
+				go reader.dp.ClientWrapper.ReportChecksumMismatch(reader.dp.PartitionID, sc.currAddr)
+				reqPacket.Opcode = proto.OpStreamFollowerRead
+				return TryOtherAddrError, false
+			}
 			if e != nil {
 				// Dont change the error message, since the caller will
 				// check if it is NotLeaderErr.
@@ -100,6 +108,11 @@ func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error)
 
 func (reader *ExtentReader) checkStreamReply(request *Packet, reply *Packet) (err error) {
 	if reply.ResultCode == proto.OpTryOtherAddr {
+		// the data node finds the data on the disk mismatching the stored checksum
+		msg := reply.Data[:util.Min(int(reply.Size), len(reply.Data))]
+		if strings.Contains(string(msg), proto.ErrChecksumMismatch.Error()) {
+			return errChecksumMismatch
+		}
 		return TryOtherAddrError
 	}
 
@@ -116,10 +129,11 @@ func (reader *ExtentReader) checkStreamReply(request *Packet, reply *Packet) (er
 		err = errors.New(fmt.Sprintf("checkStreamReply: inconsistent req and reply, req(%v) reply(%v)", request, reply))
 		return
 	}
-	expectCrc := crc32.ChecksumIEEE(reply.Data[:reply.Size])
+	expectCrc := proto.Checksum(reader.dp.Checksum, reply.Data[:reply.Size])
 	if reply.CRC != expectCrc {
-		err = errors.New(fmt.Sprintf("checkStreamReply: inconsistent CRC, expectCRC(%v) replyCRC(%v)", expectCrc, reply.CRC))
-		return
+		log.LogWarnf("checkStreamReply: inconsistent CRC, expectCRC(%v) replyCRC(%v) req(%v) reply(%v)",
+			expectCrc, reply.CRC, request, reply)
+		return errChecksumMismatch
 	}
 	return nil
 }
@@ -0,0 +1,98 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tier"
+)
+
+// tierExtent returns the tier extent holding the data of the extent key, or nil if the key is not tiered.
+func (s *Streamer) tierExtent(ek *proto.ExtentKey) *proto.TierExtent {
+	s.tierLock.RLock()
+	defer s.tierLock.RUnlock()
+	for i := range s.tiers {
+		if s.tiers[i].Contains(ek) {
+			t := s.tiers[i]
+			return &t
+		}
+	}
+	return nil
+}
+
+// readTier reads the data of the request from the object of the tier extent,
+// and asks the streamer to write the extent key back to the data nodes if required.
+func (s *Streamer) readTier(t *proto.TierExtent, req *ExtentRequest) (readBytes int, err error) {
+	store, rehydrate := s.client.dataWrapper.TierStore()
+	if store == nil {
+		return 0, syscall.EIO
+	}
+	offset := int64(req.ExtentKey.ExtentOffset-t.ExtentOffset) + int64(req.FileOffset-int(req.ExtentKey.FileOffset))
+	data, err := tier.GetVerified(store, t, offset, int64(req.Size))
+	if err != nil {
+		return
+	}
+	readBytes = copy(req.Data[:req.Size], data)
+
+	if rehydrate && s.client.replaceExtentKeys != nil {
+		// never block the read, the key will be rehydrated by the next read otherwise
+		select {
+		case s.request <- &RehydrateRequest{ek: *req.ExtentKey}:
+		default:
+		}
+	}
+	return
+}
+
+// rehydrate rewrites the data of the tiered extent key into a new extent, and replaces the key
+// with the new one, after which the object is removed by the meta node.
+func (s *Streamer) rehydrate(ek proto.ExtentKey) {
+	var err error
+	defer func() {
+		if err != nil {
+			log.LogWarnf("rehydrate: ino(%v) ek(%v) err(%v)", s.inode, ek, err)
+		}
+	}()
+
+	s.closeOpenHandler()
+	if err = s.flush(); err != nil {
+		return
+	}
+	if err = s.GetExtents(); err != nil {
+		return
+	}
+	// the key may have been rehydrated by the former requests or changed by others
+	if s.tierExtent(&ek) == nil || !s.hasExtentKey(&ek) {
+		return
+	}
+	if err = s.defragRange([]proto.ExtentKey{ek}); err != nil {
+		s.GetExtents()
+		return
+	}
+	log.LogDebugf("rehydrate: ino(%v) ek(%v)", s.inode, ek)
+}
+
+func (s *Streamer) hasExtentKey(ek *proto.ExtentKey) bool {
+	for _, key := range s.extents.List() {
+		if key.PartitionId == ek.PartitionId && key.ExtentId == ek.ExtentId && key.ExtentOffset == ek.ExtentOffset &&
+			key.FileOffset == ek.FileOffset && key.Size == ek.Size {
+			return true
+		}
+	}
+	return false
+}
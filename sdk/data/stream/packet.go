@@ -20,7 +20,6 @@ import (
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
 	"github.com/chubaofs/chubaofs/util"
-	"hash/crc32"
 	"io"
 	"net"
 	"time"
@@ -29,8 +28,10 @@ import (
 // Packet defines a wrapper of the packet in proto.
 type Packet struct {
 	proto.Packet
-	inode    uint64
-	errCount int
+	inode      uint64
+	errCount   int
+	againCount int  // times the packet is throttled by the data node
+	resent     bool // the packet is being sent again after it is throttled
 }
 
 // String returns the string format of the packet.
@@ -137,8 +138,8 @@ func (p *Packet) isValidReadReply(q *Packet) bool {
 	return false
 }
 
-func (p *Packet) writeToConn(conn net.Conn) error {
-	p.CRC = crc32.ChecksumIEEE(p.Data[:p.Size])
+func (p *Packet) writeToConn(conn net.Conn, checksum string) error {
+	p.CRC = proto.Checksum(checksum, p.Data[:p.Size])
 	return p.WriteToConn(conn)
 }
 
@@ -40,6 +40,9 @@ type Streamer struct {
 	extents *ExtentCache
 	once    sync.Once
 
+	tierLock sync.RWMutex
+	tiers    []proto.TierExtent // locations of the extent keys migrated to the tier store
+
 	handler   *ExtentHandler   // current open handler
 	dirtylist *DirtyExtentList // dirty handlers
 	dirty     bool             // whether current open handler is in the dirty list
@@ -70,7 +73,18 @@ func (s *Streamer) String() string {
 
 // TODO should we call it RefreshExtents instead?
 func (s *Streamer) GetExtents() error {
-	return s.extents.Refresh(s.inode, s.client.getExtents)
+	if s.client.getTieredExtents == nil {
+		return s.extents.Refresh(s.inode, s.client.getExtents)
+	}
+	return s.extents.Refresh(s.inode, func(inode uint64) (gen, size uint64, eks []proto.ExtentKey, err error) {
+		var tiers []proto.TierExtent
+		if gen, size, eks, tiers, err = s.client.getTieredExtents(inode); err == nil {
+			s.tierLock.Lock()
+			s.tiers = tiers
+			s.tierLock.Unlock()
+		}
+		return
+	})
 }
 
 // GetExtentReader returns the extent reader.
@@ -140,6 +154,14 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 			// Reading a hole, just fill zero
 			total += req.Size
 			log.LogDebugf("Stream read hole: ino(%v) req(%v) total(%v)", s.inode, req, total)
+		} else if t := s.tierExtent(req.ExtentKey); t != nil {
+			readBytes, err = s.readTier(t, req)
+			log.LogDebugf("Stream read tier: ino(%v) req(%v) tier(%v) readBytes(%v) err(%v)", s.inode, req, t.Key, readBytes, err)
+			total += readBytes
+			if err != nil {
+				log.LogErrorf("Stream read tier: ino(%v) req(%v) tier(%v) err(%v)", s.inode, req, t.Key, err)
+				break
+			}
 		} else {
 			reader, err = s.GetExtentReader(req.ExtentKey)
 			if err != nil {
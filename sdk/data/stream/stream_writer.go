@@ -17,7 +17,6 @@ package stream
 import (
 	"fmt"
 	"golang.org/x/net/context"
-	"hash/crc32"
 	"net"
 	"sync/atomic"
 	"syscall"
@@ -88,6 +87,18 @@ type EvictRequest struct {
 	done chan struct{}
 }
 
+// RehydrateRequest defines a request to write a tiered extent key back to the data nodes.
+type RehydrateRequest struct {
+	ek proto.ExtentKey
+}
+
+// DefragRequest defines a defragment request.
+type DefragRequest struct {
+	minFragments int
+	err          error
+	done         chan struct{}
+}
+
 // Open request shall grab the lock until request is sent to the request channel
 func (s *Streamer) IssueOpenRequest() error {
 	request := openRequestPool.Get().(*OpenRequest)
@@ -164,6 +175,16 @@ func (s *Streamer) IssueEvictRequest() error {
 	return err
 }
 
+func (s *Streamer) IssueDefragRequest(minFragments int) error {
+	request := &DefragRequest{
+		minFragments: minFragments,
+		done:         make(chan struct{}, 1),
+	}
+	s.request <- request
+	<-request.done
+	return request.err
+}
+
 func (s *Streamer) server() {
 	t := time.NewTicker(2 * time.Second)
 	defer t.Stop()
@@ -180,6 +201,7 @@ func (s *Streamer) server() {
 			return
 		case <-t.C:
 			s.traverse()
+			s.autoDefragment()
 			if s.refcnt <= 0 {
 				s.client.streamerLock.Lock()
 				if s.idle >= streamWriterIdleTimeoutPeriod && len(s.request) == 0 {
@@ -231,6 +253,9 @@ func (s *Streamer) abortRequest(request interface{}) {
 	case *EvictRequest:
 		request.err = syscall.EAGAIN
 		request.done <- struct{}{}
+	case *DefragRequest:
+		request.err = syscall.EAGAIN
+		request.done <- struct{}{}
 	default:
 	}
 }
@@ -255,6 +280,11 @@ func (s *Streamer) handleRequest(request interface{}) {
 	case *EvictRequest:
 		request.err = s.evict()
 		request.done <- struct{}{}
+	case *DefragRequest:
+		request.err = s.defragment(request.minFragments)
+		request.done <- struct{}{}
+	case *RehydrateRequest:
+		s.rehydrate(request.ek)
 	default:
 	}
 }
@@ -295,8 +325,12 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 
 	for _, req := range requests {
 		var writeSize int
-		if req.ExtentKey != nil {
+		// the data of the tiered or shared extent keys are overwritten by appending new extents
+		if req.ExtentKey != nil && s.tierExtent(req.ExtentKey) == nil && !s.sharedExtent(req.ExtentKey) {
 			writeSize, err = s.doOverwrite(req, direct)
+			if err == ExtentSharedError {
+				writeSize, err = s.doWrite(req.Data, req.FileOffset, req.Size, direct)
+			}
 		} else {
 			writeSize, err = s.doWrite(req.Data, req.FileOffset, req.Size, direct)
 		}
@@ -314,6 +348,15 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 	return
 }
 
+// sharedExtent returns if the extent is shared by the cloned volumes, so that it is read only.
+func (s *Streamer) sharedExtent(ek *proto.ExtentKey) bool {
+	dp, err := s.client.dataWrapper.GetDataPartition(ek.PartitionId)
+	if err != nil {
+		return false
+	}
+	return dp.IsSharedExtent(ek.ExtentId)
+}
+
 func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err error) {
 	var dp *wrapper.DataPartition
 
@@ -351,10 +394,10 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		packSize := util.Min(size-total, util.BlockSize)
 		copy(reqPacket.Data[:packSize], req.Data[total:total+packSize])
 		reqPacket.Size = uint32(packSize)
-		reqPacket.CRC = crc32.ChecksumIEEE(reqPacket.Data[:packSize])
+		reqPacket.CRC = proto.Checksum(dp.Checksum, reqPacket.Data[:packSize])
 
 		replyPacket := new(Packet)
-		err = sc.Send(reqPacket, func(conn *net.TCPConn) (error, bool) {
+		err = sc.Send(reqPacket, func(conn net.Conn) (error, bool) {
 			e := replyPacket.ReadFromConn(conn, proto.ReadDeadlineTime)
 			if e != nil {
 				log.LogWarnf("Stream Writer doOverwrite: ino(%v) failed to read from connect, req(%v) err(%v)", s.inode, reqPacket, e)
@@ -376,6 +419,11 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		reqPacket.Data = nil
 		log.LogDebugf("doOverwrite: ino(%v) req(%v) reqPacket(%v) err(%v) replyPacket(%v)", s.inode, req, reqPacket, err, replyPacket)
 
+		if err == nil && replyPacket.ResultCode == proto.OpNotPerm {
+			log.LogWarnf("doOverwrite: ino(%v) extent shared, req(%v) replyPacket(%v)", s.inode, req, replyPacket)
+			err = ExtentSharedError
+			break
+		}
 		if err != nil || replyPacket.ResultCode != proto.OpOk {
 			err = errors.New(fmt.Sprintf("doOverwrite: failed or reply NOK: err(%v) ino(%v) req(%v) replyPacket(%v)", err, s.inode, req, replyPacket))
 			break
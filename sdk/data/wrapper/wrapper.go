@@ -26,6 +26,7 @@ import (
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/iputil"
 	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/chubaofs/chubaofs/util/tier"
 )
 
 var (
@@ -33,6 +34,9 @@ var (
 	MinWriteAbleDataPartitionCnt = 10
 )
 
+// TierSecureFunc returns the keys of the tier store, which are not in the simple view of the vol.
+type TierSecureFunc func() (accessKey, secretKey string)
+
 type DataPartitionView struct {
 	DataPartitions []*DataPartition
 }
@@ -56,12 +60,17 @@ type Wrapper struct {
 
 	dpSelector DataPartitionSelector
 
+	tierPolicy    proto.TierPolicy
+	tierStore     tier.Store     // store of the migrated extents, nil if the vol has never configured one
+	getTierSecure TierSecureFunc // may be nil, the store is accessed without the keys then
+
 	HostsStatus map[string]bool
 }
 
 // NewDataPartitionWrapper returns a new data partition wrapper.
-func NewDataPartitionWrapper(volName string, masters []string) (w *Wrapper, err error) {
+func NewDataPartitionWrapper(volName string, masters []string, getTierSecure TierSecureFunc) (w *Wrapper, err error) {
 	w = new(Wrapper)
+	w.getTierSecure = getTierSecure
 	w.stopC = make(chan struct{})
 	w.masters = masters
 	w.mc = masterSDK.NewMasterClient(masters, false)
@@ -127,6 +136,7 @@ func (w *Wrapper) getSimpleVolView() (err error) {
 	w.followerRead = view.FollowerRead
 	w.dpSelectorName = view.DpSelectorName
 	w.dpSelectorParm = view.DpSelectorParm
+	w.updateTierStore(view.Tier)
 
 	log.LogInfof("getSimpleVolView: get volume simple info: ID(%v) name(%v) owner(%v) status(%v) capacity(%v) "+
 		"metaReplicas(%v) dataReplicas(%v) mpCnt(%v) dpCnt(%v) followerRead(%v) createTime(%v) dpSelectorName(%v) "+
@@ -173,10 +183,41 @@ func (w *Wrapper) updateSimpleVolView() (err error) {
 		w.dpSelectorChanged = true
 		w.Unlock()
 	}
+	w.updateTierStore(view.Tier)
 
 	return nil
 }
 
+func (w *Wrapper) updateTierStore(policy *proto.TierPolicy) {
+	if policy == nil {
+		return
+	}
+	if w.getTierSecure != nil {
+		policy.AccessKey, policy.SecretKey = w.getTierSecure()
+	}
+	w.Lock()
+	defer w.Unlock()
+	if w.tierStore != nil && *policy == w.tierPolicy {
+		return
+	}
+	store, err := tier.NewStore(policy)
+	if err != nil {
+		log.LogWarnf("updateTierStore: volume(%v) endpoint(%v) bucket(%v) err(%v)", w.volName, policy.Endpoint, policy.Bucket, err)
+		return
+	}
+	log.LogInfof("updateTierStore: volume(%v) endpoint(%v) bucket(%v) rehydrate(%v)", w.volName, policy.Endpoint, policy.Bucket, policy.Rehydrate)
+	w.tierPolicy = *policy
+	w.tierStore = store
+}
+
+// TierStore returns the store of the extents migrated from the data nodes, and whether
+// the extents shall be written back to the data nodes after they are read.
+func (w *Wrapper) TierStore() (store tier.Store, rehydrate bool) {
+	w.RLock()
+	defer w.RUnlock()
+	return w.tierStore, w.tierPolicy.Rehydrate
+}
+
 func (w *Wrapper) updateDataPartition(isInit bool) (err error) {
 
 	var dpv *proto.DataPartitionsView
@@ -230,6 +271,7 @@ func (w *Wrapper) replaceOrInsertPartition(dp *DataPartition) {
 		old.ReplicaNum = dp.ReplicaNum
 		old.Hosts = dp.Hosts
 		old.NearHosts = dp.Hosts
+		old.SharedExtentID = dp.SharedExtentID
 		dp.Metrics = old.Metrics
 	} else {
 		dp.Metrics = NewDataPartitionMetrics()
@@ -254,6 +296,14 @@ func (w *Wrapper) GetDataPartition(partitionID uint64) (*DataPartition, error) {
 	return dp, nil
 }
 
+// ReportChecksumMismatch reports the data read from the replica of the partition mismatching the checksum
+// to the master.
+func (w *Wrapper) ReportChecksumMismatch(partitionID uint64, addr string) {
+	if err := w.mc.ClientAPI().ReportChecksumMismatch(partitionID, addr); err != nil {
+		log.LogWarnf("ReportChecksumMismatch: partition(%v) addr(%v) err(%v)", partitionID, addr, err)
+	}
+}
+
 // WarningMsg returns the warning message that contains the cluster name.
 func (w *Wrapper) WarningMsg() string {
 	return fmt.Sprintf("%s_client_warning", w.clusterName)
@@ -16,6 +16,7 @@ package master
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -63,6 +64,16 @@ func (api *AdminAPI) ListZones() (zoneViews []*proto.ZoneView, err error) {
 	}
 	return
 }
+
+func (api *AdminAPI) UpdateZone(name string, available bool) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.UpdateZone)
+	request.addParam("name", name)
+	request.addParam("enable", strconv.FormatBool(available))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
 func (api *AdminAPI) Topo() (topo *proto.TopologyView, err error ){
 	var buf []byte
 	var request = newAPIRequest(http.MethodGet, proto.GetTopologyView)
@@ -207,6 +218,28 @@ func (api *AdminAPI) DeleteVolume(volName, authKey string) (err error) {
 	return
 }
 
+func (api *AdminAPI) RenameVolume(volName, newName, authKey string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminRenameVol)
+	request.addParam("name", volName)
+	request.addParam("newName", newName)
+	request.addParam("authKey", authKey)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) CloneVolume(volName, source, owner string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminCloneVol)
+	request.addParam("name", volName)
+	request.addParam("source", source)
+	request.addParam("owner", owner)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminUpdateVol)
 	request.addParam("name", volName)
@@ -223,6 +256,29 @@ func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int,
 	return
 }
 
+// UpdateVolumeSpec updates the volume to the spec, the checksum is left as it is if not specified.
+func (api *AdminAPI) UpdateVolumeSpec(spec *proto.VolSpec, authKey string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminUpdateVol)
+	request.addParam("name", spec.Name)
+	request.addParam("authKey", authKey)
+	request.addParam("capacity", strconv.FormatUint(spec.Capacity, 10))
+	request.addParam("zoneName", spec.ZoneName)
+	request.addParam("description", spec.Description)
+	request.addParam("followerRead", strconv.FormatBool(spec.FollowerRead))
+	request.addParam("authenticate", strconv.FormatBool(spec.Authenticate))
+	request.addParam("enableToken", strconv.FormatBool(spec.EnableToken))
+	request.addParam("iopsLimit", strconv.FormatUint(spec.IopsLimit, 10))
+	request.addParam("bandwidthLimit", strconv.FormatUint(spec.BandwidthLimit, 10))
+	request.addParam("quorumWrite", strconv.FormatBool(spec.QuorumWrite))
+	request.addParam("checksum", spec.Checksum)
+	request.addParam("compression", noneIfEmpty(spec.Compression))
+	request.addParam("placementPolicy", noneIfEmpty(spec.PlacementPolicy))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) VolShrink(volName string, capacity uint64, authKey string) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminVolShrink)
 	request.addParam("name", volName)
@@ -354,6 +410,112 @@ func (api *AdminAPI) SetDeleteParas(batchCount, markDeleteRate, deleteWorkerSlee
 	return
 }
 
+func (api *AdminAPI) SetRebalance(enable, threshold, maxConcurrency string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetRebalance)
+	request.addParam("enable", enable)
+	request.addParam("threshold", threshold)
+	request.addParam("maxConcurrency", maxConcurrency)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetRebalance() (view *proto.RebalanceView, err error) {
+	var buf []byte
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetRebalance)
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.RebalanceView{}
+	if err = json.Unmarshal(buf, &view); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetAutoDecommission(enable, errorThreshold, maxConcurrency, badDiskLimit string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetAutoDecommission)
+	request.addParam("enable", enable)
+	request.addParam("errorThreshold", errorThreshold)
+	request.addParam("maxConcurrency", maxConcurrency)
+	request.addParam("badDiskLimit", badDiskLimit)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetAutoDecommission() (view *proto.AutoDecommissionView, err error) {
+	var buf []byte
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetAutoDecommission)
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	view = &proto.AutoDecommissionView{}
+	if err = json.Unmarshal(buf, &view); err != nil {
+		return
+	}
+	return
+}
+
+// BackupMetadata writes the backup of the metadata of the cluster, to restore a new master group from.
+func (api *AdminAPI) BackupMetadata(w io.Writer) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminBackupMetadata)
+	return api.mc.serveStream(request, w)
+}
+
+func (api *AdminAPI) ListAuditRecords(user, op string, since int64, limit int) (records []*proto.AuditRecord, err error) {
+	var buf []byte
+	var request = newAPIRequest(http.MethodGet, proto.AdminListAuditRecords)
+	request.addParam("user", user)
+	request.addParam("op", op)
+	request.addParam("since", strconv.FormatInt(since, 10))
+	request.addParam("limit", strconv.Itoa(limit))
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(buf, &records); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListDecommissionJobs() (jobs []*proto.DecommissionJobView, err error) {
+	var buf []byte
+	var request = newAPIRequest(http.MethodGet, proto.AdminListJobs)
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	if err = json.Unmarshal(buf, &jobs); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetDecommissionJob(id uint64) (job *proto.DecommissionJob, err error) {
+	var buf []byte
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetJob)
+	request.addParam("id", strconv.FormatUint(id, 10))
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	job = &proto.DecommissionJob{}
+	if err = json.Unmarshal(buf, &job); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) CancelDecommissionJob(id uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminCancelJob)
+	request.addParam("id", strconv.FormatUint(id, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) GetDeleteParas() (delParas map[string]string, err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminGetNodeInfo)
 	if _, err = api.mc.serveRequest(request); err != nil {
@@ -369,3 +531,23 @@ func (api *AdminAPI) GetDeleteParas() (delParas map[string]string, err error) {
 	}
 	return
 }
+
+// SetNodeInfo sets the parameters of the nodes, such as the delete and the repair rates.
+func (api *AdminAPI) SetNodeInfo(params map[string]uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetNodeInfo)
+	for key, value := range params {
+		request.addParam(key, strconv.FormatUint(value, 10))
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+// noneIfEmpty clears the option of the volume by "none", since the options not specified are left as they are.
+func noneIfEmpty(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
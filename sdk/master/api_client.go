@@ -153,3 +153,12 @@ func (api *ClientAPI) GetDataPartitions(volName string) (view *proto.DataPartiti
 	}
 	return
 }
+
+// ReportChecksumMismatch reports the data read from the replica of the address mismatching the checksum.
+func (api *ClientAPI) ReportChecksumMismatch(partitionID uint64, addr string) (err error) {
+	var request = newAPIRequest(http.MethodPost, proto.ClientChecksumReport)
+	request.addParam("id", strconv.FormatUint(partitionID, 10))
+	request.addParam("addr", addr)
+	_, err = api.mc.serveRequest(request)
+	return
+}
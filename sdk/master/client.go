@@ -19,8 +19,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,6 +46,8 @@ type MasterClient struct {
 	useSSL     bool
 	leaderAddr string
 	timeout    time.Duration
+	accessKey  string // credential signing the admin API requests
+	secretKey  string
 
 	adminAPI  *AdminAPI
 	clientAPI *ClientAPI
@@ -96,6 +100,39 @@ func (c *MasterClient) SetTimeout(timeout uint16) {
 	c.Unlock()
 }
 
+// SetCredential sets the access key and the secret key of the user signing the requests, which are required by
+// the admin APIs if the admin authentication is enabled on the master.
+func (c *MasterClient) SetCredential(accessKey, secretKey string) {
+	c.Lock()
+	c.accessKey, c.secretKey = accessKey, secretKey
+	c.Unlock()
+}
+
+// signRequest returns the headers of the request along with the signature, if the credential is set.
+func (c *MasterClient) signRequest(r *request) map[string]string {
+	c.RLock()
+	accessKey, secretKey := c.accessKey, c.secretKey
+	c.RUnlock()
+	if accessKey == "" {
+		return r.header
+	}
+	query := make(url.Values, len(r.params))
+	for k, v := range r.params {
+		query.Set(k, v)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := proto.NewAdminAuthNonce()
+	header := make(map[string]string, len(r.header)+4)
+	for k, v := range r.header {
+		header[k] = v
+	}
+	header[proto.AdminAuthAccessKey] = accessKey
+	header[proto.AdminAuthTimestamp] = timestamp
+	header[proto.AdminAuthNonce] = nonce
+	header[proto.AdminAuthSignature] = proto.SignAdminRequest(secretKey, r.method, r.path, query, r.body, timestamp, nonce)
+	return header
+}
+
 func (c *MasterClient) serveRequest(r *request) (repsData []byte, err error) {
 	leaderAddr, nodes := c.prepareRequest()
 	host := leaderAddr
@@ -116,7 +153,7 @@ func (c *MasterClient) serveRequest(r *request) (repsData []byte, err error) {
 		}
 		var url = fmt.Sprintf("%s://%s%s", schema, host,
 			r.path)
-		resp, err = c.httpRequest(r.method, url, r.params, r.header, r.body)
+		resp, err = c.httpRequest(r.method, url, r.params, c.signRequest(r), r.body)
 		if err != nil {
 			log.LogErrorf("serveRequest: send http request fail: method(%v) url(%v) err(%v)", r.method, url, err)
 			continue
@@ -170,6 +207,55 @@ func (c *MasterClient) serveRequest(r *request) (repsData []byte, err error) {
 	return
 }
 
+// serveStream copies the reply of the request to the writer, for the APIs streaming the replies not wrapped in the
+// HTTPReply, without the timeout. The errors are still replied in the HTTPReply.
+func (c *MasterClient) serveStream(r *request, w io.Writer) (err error) {
+	leaderAddr, nodes := c.prepareRequest()
+	schema := "http"
+	if c.useSSL {
+		schema = "https"
+	}
+	for _, host := range append([]string{leaderAddr}, nodes...) {
+		if host == "" {
+			continue
+		}
+		var req *http.Request
+		var url = c.mergeRequestUrl(fmt.Sprintf("%s://%s%s", schema, host, r.path), r.params)
+		if req, err = http.NewRequest(r.method, url, bytes.NewReader(r.body)); err != nil {
+			return
+		}
+		for k, v := range c.signRequest(r) {
+			req.Header.Set(k, v)
+		}
+		var resp *http.Response
+		if resp, err = (&http.Client{}).Do(req); err != nil {
+			log.LogErrorf("serveStream: send http request fail: method(%v) url(%v) err(%v)", r.method, url, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			log.LogErrorf("serveStream: unknown status: host(%v) uri(%v) status(%v)", host, url, resp.StatusCode)
+			_ = resp.Body.Close()
+			continue
+		}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			reply := &proto.HTTPReply{}
+			err = json.NewDecoder(resp.Body).Decode(reply)
+			_ = resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("unmarshal response body err:%v", err)
+			}
+			if reply.Code != 0 {
+				return proto.ParseErrorCode(reply.Code)
+			}
+			return fmt.Errorf("unexpected reply: %v", reply.Msg)
+		}
+		_, err = io.Copy(w, resp.Body)
+		_ = resp.Body.Close()
+		return
+	}
+	return ErrNoValidMaster
+}
+
 // Nodes returns all master addresses.
 func (c *MasterClient) Nodes() (nodes []string) {
 	c.RLock()
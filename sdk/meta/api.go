@@ -72,6 +72,10 @@ func (mw *MetaWrapper) Statfs() (total, used uint64) {
 }
 
 func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
+	return mw.createWithCred(mw.userCred, parentID, name, mode, uid, gid, target)
+}
+
+func (mw *MetaWrapper) createWithCred(cred *proto.UserCred, parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
 	var (
 		status       int
 		err          error
@@ -86,6 +90,16 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 		return nil, syscall.ENOENT
 	}
 
+	// Inherit the default ACL of the parent directory if there is one.
+	var defaultACL string
+	if mw.enablePosixACL {
+		defaultACL, status, err = mw.getXAttr(parentMP, parentID, proto.XAttrKeyPosixACLDefault)
+		if err != nil || status != statusOK {
+			log.LogErrorf("Create_ll: get default acl failed, parentID(%v) status(%v) err(%v)", parentID, status, err)
+			return nil, statusToErrno(status)
+		}
+	}
+
 	// Create Inode
 
 	//	mp = mw.getLatestPartition()
@@ -114,7 +128,7 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, defaultACL)
 		if err == nil && status == statusOK {
 			goto create_dentry
 		}
@@ -122,7 +136,7 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 	return nil, syscall.ENOMEM
 
 create_dentry:
-	status, err = mw.dcreate(parentMP, parentID, name, info.Inode, mode)
+	status, err = mw.dcreate(parentMP, cred, parentID, name, info.Inode, mode)
 	if err != nil {
 		return nil, statusToErrno(status)
 	} else if status != statusOK {
@@ -136,13 +150,17 @@ create_dentry:
 }
 
 func (mw *MetaWrapper) Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error) {
+	return mw.lookupWithCred(mw.userCred, parentID, name)
+}
+
+func (mw *MetaWrapper) lookupWithCred(cred *proto.UserCred, parentID uint64, name string) (inode uint64, mode uint32, err error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		log.LogErrorf("Lookup_ll: No parent partition, parentID(%v) name(%v)", parentID, name)
 		return 0, 0, syscall.ENOENT
 	}
 
-	status, inode, mode, err := mw.lookup(parentMP, parentID, name)
+	status, inode, mode, err := mw.lookup(parentMP, cred, parentID, name)
 	if err != nil || status != statusOK {
 		return 0, 0, statusToErrno(status)
 	}
@@ -302,6 +320,10 @@ func (mw *MetaWrapper) BatchGetXAttr(inodes []uint64, keys []string) ([]*proto.X
  * and the caller should make sure InodeInfo is valid before using it.
  */
 func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
+	return mw.deleteWithCred(mw.userCred, parentID, name, isDir)
+}
+
+func (mw *MetaWrapper) deleteWithCred(cred *proto.UserCred, parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
 	var (
 		status int
 		inode  uint64
@@ -318,7 +340,7 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 	}
 
 	if isDir {
-		status, inode, mode, err = mw.lookup(parentMP, parentID, name)
+		status, inode, mode, err = mw.lookup(parentMP, cred, parentID, name)
 		if err != nil || status != statusOK {
 			return nil, statusToErrno(status)
 		}
@@ -339,7 +361,7 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 		}
 	}
 
-	status, inode, err = mw.ddelete(parentMP, parentID, name)
+	status, inode, err = mw.ddelete(parentMP, cred, parentID, name)
 	if err != nil || status != statusOK {
 		if status == statusNoent {
 			return nil, nil
@@ -362,6 +384,10 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 }
 
 func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
+	return mw.renameWithCred(mw.userCred, srcParentID, srcName, dstParentID, dstName)
+}
+
+func (mw *MetaWrapper) renameWithCred(cred *proto.UserCred, srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
 	var oldInode uint64
 
 	srcParentMP := mw.getPartitionByInode(srcParentID)
@@ -374,7 +400,7 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	}
 
 	// look up for the src ino
-	status, inode, mode, err := mw.lookup(srcParentMP, srcParentID, srcName)
+	status, inode, mode, err := mw.lookup(srcParentMP, cred, srcParentID, srcName)
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
@@ -389,14 +415,14 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	}
 
 	// create dentry in dst parent
-	status, err = mw.dcreate(dstParentMP, dstParentID, dstName, inode, mode)
+	status, err = mw.dcreate(dstParentMP, cred, dstParentID, dstName, inode, mode)
 	if err != nil {
 		return syscall.EAGAIN
 	}
 
 	// Note that only regular files are allowed to be overwritten.
 	if status == statusExist && proto.IsRegular(mode) {
-		status, oldInode, err = mw.dupdate(dstParentMP, dstParentID, dstName, inode)
+		status, oldInode, err = mw.dupdate(dstParentMP, cred, dstParentID, dstName, inode)
 		if err != nil {
 			return syscall.EAGAIN
 		}
@@ -408,7 +434,7 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	}
 
 	// delete dentry from src parent
-	status, _, err = mw.ddelete(srcParentMP, srcParentID, srcName)
+	status, _, err = mw.ddelete(srcParentMP, cred, srcParentID, srcName)
 	if err != nil {
 		return statusToErrno(status)
 	} else if status != statusOK {
@@ -417,9 +443,9 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 			e   error
 		)
 		if oldInode == 0 {
-			sts, _, e = mw.ddelete(dstParentMP, dstParentID, dstName)
+			sts, _, e = mw.ddelete(dstParentMP, cred, dstParentID, dstName)
 		} else {
-			sts, _, e = mw.dupdate(dstParentMP, dstParentID, dstName, oldInode)
+			sts, _, e = mw.dupdate(dstParentMP, cred, dstParentID, dstName, oldInode)
 		}
 		if e == nil && sts == statusOK {
 			mw.iunlink(srcMP, inode)
@@ -442,12 +468,16 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 }
 
 func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
+	return mw.readDirWithCred(mw.userCred, parentID)
+}
+
+func (mw *MetaWrapper) readDirWithCred(cred *proto.UserCred, parentID uint64) ([]proto.Dentry, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		return nil, syscall.ENOENT
 	}
 
-	status, children, err := mw.readdir(parentMP, parentID)
+	status, children, err := mw.readdir(parentMP, cred, parentID)
 	if err != nil || status != statusOK {
 		return nil, statusToErrno(status)
 	}
@@ -455,26 +485,34 @@ func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
 }
 
 func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
+	return mw.dentryCreateWithCred(mw.userCred, parentID, name, inode, mode)
+}
+
+func (mw *MetaWrapper) dentryCreateWithCred(cred *proto.UserCred, parentID uint64, name string, inode uint64, mode uint32) error {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		return syscall.ENOENT
 	}
 	var err error
 	var status int
-	if status, err = mw.dcreate(parentMP, parentID, name, inode, mode); err != nil || status != statusOK {
+	if status, err = mw.dcreate(parentMP, cred, parentID, name, inode, mode); err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
 	return nil
 }
 
 func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
+	return mw.dentryUpdateWithCred(mw.userCred, parentID, name, inode)
+}
+
+func (mw *MetaWrapper) dentryUpdateWithCred(cred *proto.UserCred, parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		err = syscall.ENOENT
 		return
 	}
 	var status int
-	status, oldInode, err = mw.dupdate(parentMP, parentID, name, inode)
+	status, oldInode, err = mw.dupdate(parentMP, cred, parentID, name, inode)
 	if err != nil || status != statusOK {
 		err = statusToErrno(status)
 		return
@@ -514,19 +552,79 @@ func (mw *MetaWrapper) AppendExtentKeys(inode uint64, eks []proto.ExtentKey) err
 	return nil
 }
 
+// ReplaceExtentKeys atomically replaces the contiguous extent keys of the inode
+// with a single extent key which holds the same data. It fails if the extent
+// keys have been changed since they were read, in which case the extent of the
+// new key is deleted.
+func (mw *MetaWrapper) ReplaceExtentKeys(inode uint64, oldEks []proto.ExtentKey, ek proto.ExtentKey) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		return syscall.ENOENT
+	}
+
+	status, err := mw.replaceExtentKeys(mp, inode, oldEks, ek, false, false)
+	if err != nil || status != statusOK {
+		log.LogErrorf("ReplaceExtentKeys: inode(%v) oldEks(%v) ek(%v) err(%v) status(%v)", inode, oldEks, ek, err, status)
+		return statusToErrno(status)
+	}
+	log.LogDebugf("ReplaceExtentKeys: ino(%v) oldEks(%v) ek(%v)", inode, oldEks, ek)
+	return nil
+}
+
+// PrepareExtentKey records the extent allocated to replace the extent keys of the inode,
+// so that it can be discarded if the replacement is abandoned.
+func (mw *MetaWrapper) PrepareExtentKey(inode uint64, ek proto.ExtentKey) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		return syscall.ENOENT
+	}
+
+	status, err := mw.replaceExtentKeys(mp, inode, nil, ek, true, false)
+	if err != nil || status != statusOK {
+		log.LogErrorf("PrepareExtentKey: inode(%v) ek(%v) err(%v) status(%v)", inode, ek, err, status)
+		return statusToErrno(status)
+	}
+	log.LogDebugf("PrepareExtentKey: ino(%v) ek(%v)", inode, ek)
+	return nil
+}
+
+// DiscardExtentKey deletes the extent written to replace the extent keys of the inode,
+// if the replacement is abandoned before it is committed.
+func (mw *MetaWrapper) DiscardExtentKey(inode uint64, ek proto.ExtentKey) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		return syscall.ENOENT
+	}
+
+	status, err := mw.replaceExtentKeys(mp, inode, nil, ek, false, true)
+	if err != nil || status != statusOK {
+		log.LogErrorf("DiscardExtentKey: inode(%v) ek(%v) err(%v) status(%v)", inode, ek, err, status)
+		return statusToErrno(status)
+	}
+	log.LogDebugf("DiscardExtentKey: ino(%v) ek(%v)", inode, ek)
+	return nil
+}
+
 func (mw *MetaWrapper) GetExtents(inode uint64) (gen uint64, size uint64, extents []proto.ExtentKey, err error) {
+	gen, size, extents, _, err = mw.GetTieredExtents(inode)
+	return
+}
+
+// GetTieredExtents returns the extent keys of the inode, along with the locations
+// of those migrated to the tier store.
+func (mw *MetaWrapper) GetTieredExtents(inode uint64) (gen uint64, size uint64, extents []proto.ExtentKey, tiers []proto.TierExtent, err error) {
 	mp := mw.getPartitionByInode(inode)
 	if mp == nil {
-		return 0, 0, nil, syscall.ENOENT
+		return 0, 0, nil, nil, syscall.ENOENT
 	}
 
-	status, gen, size, extents, err := mw.getExtents(mp, inode)
+	status, gen, size, extents, tiers, err := mw.getExtents(mp, inode)
 	if err != nil || status != statusOK {
 		log.LogErrorf("GetExtents: ino(%v) err(%v) status(%v)", inode, err, status)
-		return 0, 0, nil, statusToErrno(status)
+		return 0, 0, nil, nil, statusToErrno(status)
 	}
-	log.LogDebugf("GetExtents: ino(%v) gen(%v) size(%v)", inode, gen, size)
-	return gen, size, extents, nil
+	log.LogDebugf("GetExtents: ino(%v) gen(%v) size(%v) tiers(%v)", inode, gen, size, len(tiers))
+	return gen, size, extents, tiers, nil
 }
 
 func (mw *MetaWrapper) Truncate(inode, size uint64) error {
@@ -545,6 +643,10 @@ func (mw *MetaWrapper) Truncate(inode, size uint64) error {
 }
 
 func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
+	return mw.linkWithCred(mw.userCred, parentID, name, ino)
+}
+
+func (mw *MetaWrapper) linkWithCred(cred *proto.UserCred, parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		log.LogErrorf("Link: No parent partition, parentID(%v)", parentID)
@@ -564,7 +666,7 @@ func (mw *MetaWrapper) Link(parentID uint64, name string, ino uint64) (*proto.In
 	}
 
 	// create new dentry and refer to the inode
-	status, err = mw.dcreate(parentMP, parentID, name, ino, info.Mode)
+	status, err = mw.dcreate(parentMP, cred, parentID, name, ino, info.Mode)
 	if err != nil {
 		return nil, statusToErrno(status)
 	} else if status != statusOK {
@@ -592,13 +694,17 @@ func (mw *MetaWrapper) Evict(inode uint64) error {
 }
 
 func (mw *MetaWrapper) Setattr(inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) error {
+	return mw.setattrWithCred(mw.userCred, inode, valid, mode, uid, gid, atime, mtime)
+}
+
+func (mw *MetaWrapper) setattrWithCred(cred *proto.UserCred, inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) error {
 	mp := mw.getPartitionByInode(inode)
 	if mp == nil {
 		log.LogErrorf("Setattr: No such partition, ino(%v)", inode)
 		return syscall.EINVAL
 	}
 
-	status, err := mw.setattr(mp, inode, valid, mode, uid, gid, atime, mtime)
+	status, err := mw.setattr(mp, cred, inode, valid, mode, uid, gid, atime, mtime)
 	if err != nil || status != statusOK {
 		log.LogErrorf("Setattr: ino(%v) err(%v) status(%v)", inode, err, status)
 		return statusToErrno(status)
@@ -622,7 +728,7 @@ func (mw *MetaWrapper) InodeCreate_ll(mode, uid, gid uint32, target []byte) (*pr
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, "")
 		if err == nil && status == statusOK {
 			return info, nil
 		}
@@ -830,6 +936,10 @@ func (mw *MetaWrapper) ListMultipart_ll(prefix, delimiter, keyMarker string, mul
 }
 
 func (mw *MetaWrapper) XAttrSet_ll(inode uint64, name, value []byte) error {
+	return mw.xattrSetWithCred(mw.userCred, inode, name, value)
+}
+
+func (mw *MetaWrapper) xattrSetWithCred(cred *proto.UserCred, inode uint64, name, value []byte) error {
 	var err error
 	mp := mw.getPartitionByInode(inode)
 	if mp == nil {
@@ -837,7 +947,7 @@ func (mw *MetaWrapper) XAttrSet_ll(inode uint64, name, value []byte) error {
 		return syscall.ENOENT
 	}
 	var status int
-	status, err = mw.setXAttr(mp, inode, name, value)
+	status, err = mw.setXAttr(mp, cred, inode, name, value)
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
@@ -873,6 +983,10 @@ func (mw *MetaWrapper) XAttrGet_ll(inode uint64, name string) (*proto.XAttrInfo,
 
 // XAttrDel_ll is a low-level meta api that deletes specified xattr.
 func (mw *MetaWrapper) XAttrDel_ll(inode uint64, name string) error {
+	return mw.xattrDelWithCred(mw.userCred, inode, name)
+}
+
+func (mw *MetaWrapper) xattrDelWithCred(cred *proto.UserCred, inode uint64, name string) error {
 	var err error
 	mp := mw.getPartitionByInode(inode)
 	if mp == nil {
@@ -880,7 +994,7 @@ func (mw *MetaWrapper) XAttrDel_ll(inode uint64, name string) error {
 		return syscall.ENOENT
 	}
 	var status int
-	status, err = mw.removeXAttr(mp, inode, name)
+	status, err = mw.removeXAttr(mp, cred, inode, name)
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
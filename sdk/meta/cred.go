@@ -0,0 +1,79 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// CredWrapper issues the metadata operations subject to the permission checks of the meta nodes with the
+// credential of a caller, instead of the one the meta wrapper is configured with.
+type CredWrapper struct {
+	mw   *MetaWrapper
+	cred *proto.UserCred
+}
+
+// WithCred returns the wrapper issuing the operations with the credential, or the one the meta wrapper
+// is configured with if nil.
+func (mw *MetaWrapper) WithCred(cred *proto.UserCred) *CredWrapper {
+	if cred == nil {
+		cred = mw.userCred
+	}
+	return &CredWrapper{mw: mw, cred: cred}
+}
+
+func (cw *CredWrapper) Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
+	return cw.mw.createWithCred(cw.cred, parentID, name, mode, uid, gid, target)
+}
+
+func (cw *CredWrapper) Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error) {
+	return cw.mw.lookupWithCred(cw.cred, parentID, name)
+}
+
+func (cw *CredWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*proto.InodeInfo, error) {
+	return cw.mw.deleteWithCred(cw.cred, parentID, name, isDir)
+}
+
+func (cw *CredWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
+	return cw.mw.renameWithCred(cw.cred, srcParentID, srcName, dstParentID, dstName)
+}
+
+func (cw *CredWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
+	return cw.mw.readDirWithCred(cw.cred, parentID)
+}
+
+func (cw *CredWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
+	return cw.mw.dentryCreateWithCred(cw.cred, parentID, name, inode, mode)
+}
+
+func (cw *CredWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
+	return cw.mw.dentryUpdateWithCred(cw.cred, parentID, name, inode)
+}
+
+func (cw *CredWrapper) Link(parentID uint64, name string, ino uint64) (*proto.InodeInfo, error) {
+	return cw.mw.linkWithCred(cw.cred, parentID, name, ino)
+}
+
+func (cw *CredWrapper) Setattr(inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) error {
+	return cw.mw.setattrWithCred(cw.cred, inode, valid, mode, uid, gid, atime, mtime)
+}
+
+func (cw *CredWrapper) XAttrSet_ll(inode uint64, name, value []byte) error {
+	return cw.mw.xattrSetWithCred(cw.cred, inode, name, value)
+}
+
+func (cw *CredWrapper) XAttrDel_ll(inode uint64, name string) error {
+	return cw.mw.xattrDelWithCred(cw.cred, inode, name)
+}
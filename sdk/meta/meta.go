@@ -75,6 +75,10 @@ type MetaConfig struct {
 	TicketMess       auth.TicketMess
 	ValidateOwner    bool
 	OnAsyncTaskError AsyncTaskErrorFunc
+	EnablePosixACL   bool
+	// Identity carried by the requests which are subject to the permission
+	// checks of meta nodes.
+	UserCred *proto.UserCred
 }
 
 type MetaWrapper struct {
@@ -83,9 +87,12 @@ type MetaWrapper struct {
 	localIP         string
 	volname         string
 	ossSecure       *OSSSecure
+	tierSecure      *OSSSecure
 	volCreateTime   int64
 	owner           string
 	ownerValidation bool
+	enablePosixACL  bool
+	userCred        *proto.UserCred
 	mc              *masterSDK.MasterClient
 	ac              *authSDK.AuthClient
 	conns           *util.ConnectPool
@@ -158,6 +165,8 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 	mw.volname = config.Volume
 	mw.owner = config.Owner
 	mw.ownerValidation = config.ValidateOwner
+	mw.enablePosixACL = config.EnablePosixACL
+	mw.userCred = config.UserCred
 	mw.mc = masterSDK.NewMasterClient(config.Masters, false)
 	mw.onAsyncTaskError = config.OnAsyncTaskError
 	mw.conns = util.NewConnectPool()
@@ -217,6 +226,14 @@ func (mw *MetaWrapper) OSSSecure() (accessKey, secretKey string) {
 	return mw.ossSecure.AccessKey, mw.ossSecure.SecretKey
 }
 
+// TierSecure returns the keys of the tier store of the volume, which are empty if it has none.
+func (mw *MetaWrapper) TierSecure() (accessKey, secretKey string) {
+	if secure := mw.tierSecure; secure != nil {
+		return secure.AccessKey, secure.SecretKey
+	}
+	return
+}
+
 func (mw *MetaWrapper) VolCreateTime() int64 {
 	return mw.volCreateTime
 }
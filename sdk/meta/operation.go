@@ -28,7 +28,7 @@ import (
 // API implementations
 //
 
-func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte) (status int, info *proto.InodeInfo, err error) {
+func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, defaultACL string) (status int, info *proto.InodeInfo, err error) {
 	req := &proto.CreateInodeRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -36,6 +36,7 @@ func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target
 		Uid:         uid,
 		Gid:         gid,
 		Target:      target,
+		DefaultACL:  defaultACL,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -151,7 +152,7 @@ func (mw *MetaWrapper) ievict(mp *MetaPartition, inode uint64) (status int, err
 	return statusOK, nil
 }
 
-func (mw *MetaWrapper) dcreate(mp *MetaPartition, parentID uint64, name string, inode uint64, mode uint32) (status int, err error) {
+func (mw *MetaWrapper) dcreate(mp *MetaPartition, cred *proto.UserCred, parentID uint64, name string, inode uint64, mode uint32) (status int, err error) {
 	if parentID == inode {
 		return statusExist, nil
 	}
@@ -163,6 +164,7 @@ func (mw *MetaWrapper) dcreate(mp *MetaPartition, parentID uint64, name string,
 		Inode:       inode,
 		Name:        name,
 		Mode:        mode,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -192,7 +194,7 @@ func (mw *MetaWrapper) dcreate(mp *MetaPartition, parentID uint64, name string,
 	return
 }
 
-func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string, newInode uint64) (status int, oldInode uint64, err error) {
+func (mw *MetaWrapper) dupdate(mp *MetaPartition, cred *proto.UserCred, parentID uint64, name string, newInode uint64) (status int, oldInode uint64, err error) {
 	if parentID == newInode {
 		return statusExist, 0, nil
 	}
@@ -203,6 +205,7 @@ func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string,
 		ParentID:    parentID,
 		Name:        name,
 		Inode:       newInode,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -238,12 +241,13 @@ func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string,
 	return statusOK, resp.Inode, nil
 }
 
-func (mw *MetaWrapper) ddelete(mp *MetaPartition, parentID uint64, name string) (status int, inode uint64, err error) {
+func (mw *MetaWrapper) ddelete(mp *MetaPartition, cred *proto.UserCred, parentID uint64, name string) (status int, inode uint64, err error) {
 	req := &proto.DeleteDentryRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
 		ParentID:    parentID,
 		Name:        name,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -279,12 +283,13 @@ func (mw *MetaWrapper) ddelete(mp *MetaPartition, parentID uint64, name string)
 	return statusOK, resp.Inode, nil
 }
 
-func (mw *MetaWrapper) lookup(mp *MetaPartition, parentID uint64, name string) (status int, inode uint64, mode uint32, err error) {
+func (mw *MetaWrapper) lookup(mp *MetaPartition, cred *proto.UserCred, parentID uint64, name string) (status int, inode uint64, mode uint32, err error) {
 	req := &proto.LookupRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
 		ParentID:    parentID,
 		Name:        name,
+		Cred:        cred,
 	}
 	packet := proto.NewPacketReqID()
 	packet.Opcode = proto.OpMetaLookup
@@ -414,11 +419,12 @@ func (mw *MetaWrapper) batchIget(wg *sync.WaitGroup, mp *MetaPartition, inodes [
 	}
 }
 
-func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int, children []proto.Dentry, err error) {
+func (mw *MetaWrapper) readdir(mp *MetaPartition, cred *proto.UserCred, parentID uint64) (status int, children []proto.Dentry, err error) {
 	req := &proto.ReadDirRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
 		ParentID:    parentID,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -461,6 +467,7 @@ func (mw *MetaWrapper) appendExtentKey(mp *MetaPartition, inode uint64, extent p
 		PartitionID: mp.PartitionID,
 		Inode:       inode,
 		Extent:      extent,
+		Cred:        mw.userCred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -487,7 +494,43 @@ func (mw *MetaWrapper) appendExtentKey(mp *MetaPartition, inode uint64, extent p
 	return status, nil
 }
 
-func (mw *MetaWrapper) getExtents(mp *MetaPartition, inode uint64) (status int, gen, size uint64, extents []proto.ExtentKey, err error) {
+func (mw *MetaWrapper) replaceExtentKeys(mp *MetaPartition, inode uint64, oldEks []proto.ExtentKey, ek proto.ExtentKey, prepare, discard bool) (status int, err error) {
+	req := &proto.ReplaceExtentKeysRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Inode:       inode,
+		OldExtents:  oldEks,
+		Extent:      ek,
+		Prepare:     prepare,
+		Discard:     discard,
+		Cred:        mw.userCred,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaExtentsReplace
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("replaceExtentKeys: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer metric.Set(err)
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("replaceExtentKeys: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("replaceExtentKeys: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	}
+	return status, nil
+}
+
+func (mw *MetaWrapper) getExtents(mp *MetaPartition, inode uint64) (status int, gen, size uint64, extents []proto.ExtentKey, tiers []proto.TierExtent, err error) {
 	req := &proto.GetExtentsRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -524,7 +567,7 @@ func (mw *MetaWrapper) getExtents(mp *MetaPartition, inode uint64) (status int,
 		log.LogErrorf("getExtents: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
 		return
 	}
-	return statusOK, resp.Generation, resp.Size, resp.Extents, nil
+	return statusOK, resp.Generation, resp.Size, resp.Extents, resp.Tiers, nil
 }
 
 func (mw *MetaWrapper) truncate(mp *MetaPartition, inode, size uint64) (status int, err error) {
@@ -533,6 +576,7 @@ func (mw *MetaWrapper) truncate(mp *MetaPartition, inode, size uint64) (status i
 		PartitionID: mp.PartitionID,
 		Inode:       inode,
 		Size:        size,
+		Cred:        mw.userCred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -611,7 +655,7 @@ func (mw *MetaWrapper) ilink(mp *MetaPartition, inode uint64) (status int, info
 	return statusOK, resp.Info, nil
 }
 
-func (mw *MetaWrapper) setattr(mp *MetaPartition, inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) (status int, err error) {
+func (mw *MetaWrapper) setattr(mp *MetaPartition, cred *proto.UserCred, inode uint64, valid, mode, uid, gid uint32, atime, mtime int64) (status int, err error) {
 	req := &proto.SetAttrRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -622,6 +666,7 @@ func (mw *MetaWrapper) setattr(mp *MetaPartition, inode uint64, valid, mode, uid
 		Gid:         gid,
 		AccessTime:  atime,
 		ModifyTime:  mtime,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -853,6 +898,7 @@ func (mw *MetaWrapper) appendExtentKeys(mp *MetaPartition, inode uint64, extents
 		PartitionId: mp.PartitionID,
 		Inode:       inode,
 		Extents:     extents,
+		Cred:        mw.userCred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -883,13 +929,14 @@ func (mw *MetaWrapper) appendExtentKeys(mp *MetaPartition, inode uint64, extents
 	return
 }
 
-func (mw *MetaWrapper) setXAttr(mp *MetaPartition, inode uint64, name []byte, value []byte) (status int, err error) {
+func (mw *MetaWrapper) setXAttr(mp *MetaPartition, cred *proto.UserCred, inode uint64, name []byte, value []byte) (status int, err error) {
 	req := &proto.SetXAttrRequest{
 		VolName:     mw.volname,
 		PartitionId: mp.PartitionID,
 		Inode:       inode,
 		Key:         string(name),
 		Value:       string(value),
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -964,12 +1011,13 @@ func (mw *MetaWrapper) getXAttr(mp *MetaPartition, inode uint64, name string) (v
 	return
 }
 
-func (mw *MetaWrapper) removeXAttr(mp *MetaPartition, inode uint64, name string) (status int, err error) {
+func (mw *MetaWrapper) removeXAttr(mp *MetaPartition, cred *proto.UserCred, inode uint64, name string) (status int, err error) {
 	req := &proto.RemoveXAttrRequest{
 		VolName:     mw.volname,
 		PartitionId: mp.PartitionID,
 		Inode:       inode,
 		Key:         name,
+		Cred:        cred,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -42,6 +42,7 @@ type VolumeView struct {
 	Owner          string
 	MetaPartitions []*MetaPartition
 	OSSSecure      *OSSSecure
+	TierSecure     *OSSSecure
 	CreateTime     int64
 }
 
@@ -97,6 +98,9 @@ func (mw *MetaWrapper) fetchVolumeView() (view *VolumeView, err error) {
 			result.OSSSecure.AccessKey = volView.OSSSecure.AccessKey
 			result.OSSSecure.SecretKey = volView.OSSSecure.SecretKey
 		}
+		if volView.TierSecure != nil {
+			result.TierSecure = &OSSSecure{AccessKey: volView.TierSecure.AccessKey, SecretKey: volView.TierSecure.SecretKey}
+		}
 		for i, mp := range volView.MetaPartitions {
 			result.MetaPartitions[i] = &MetaPartition{
 				PartitionID: mp.PartitionID,
@@ -173,6 +177,7 @@ func (mw *MetaWrapper) updateMetaPartitions() error {
 		}
 	}
 	mw.ossSecure = view.OSSSecure
+	mw.tierSecure = view.TierSecure
 	mw.volCreateTime = view.CreateTime
 
 	if len(rwPartitions) == 0 {
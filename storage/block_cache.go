@@ -0,0 +1,295 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"container/list"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+const (
+	BlockCacheFileName      = "BLOCK_CACHE"
+	BlockCacheAdmitCount    = 2 // a block is cached when it is missed for the times recently
+	blockCacheEpochCount    = 1024
+	blockCacheGhostMultiple = 2 // the number of the recently missed blocks remembered, relative to the cache slots
+)
+
+type blockKey struct {
+	partitionID uint64
+	extentID    uint64
+	blockNo     int
+}
+
+type extentKey struct {
+	partitionID uint64
+	extentID    uint64
+}
+
+type cacheEntry struct {
+	key  blockKey
+	slot int64
+	size int
+	crc  uint32
+}
+
+type ghostEntry struct {
+	key   blockKey
+	count int
+}
+
+// BlockCache caches the hot blocks of the normal extents in a file on a fast local device such as SSD.
+// The blocks are evicted in the LRU order, and a block is admitted only if it is missed for BlockCacheAdmitCount times
+// recently, so that the blocks read once do not pollute the cache. The cache is emptied when the data node restarts.
+type BlockCache struct {
+	sync.Mutex
+	file    *os.File
+	slots   int64
+	free    []int64
+	lru     *list.List // the most recently used block at the front
+	entries map[blockKey]*list.Element
+	extents map[extentKey]int // number of the cached blocks of each extent
+	ghost   *list.List        // the recently missed blocks that are not cached
+	ghosts  map[blockKey]*list.Element
+
+	// epochs are increased when the extents are modified, so that the data read before the modification
+	// is not cached after the invalidation.
+	epochs [blockCacheEpochCount]uint64
+	hits   uint64
+	misses uint64
+}
+
+// NewBlockCache creates a block cache of the capacity in bytes under the directory.
+func NewBlockCache(dir string, capacity uint64) (c *BlockCache, err error) {
+	slots := int64(capacity / util.BlockSize)
+	if slots == 0 {
+		return nil, fmt.Errorf("block cache capacity(%v) is less than a block", capacity)
+	}
+	if err = MkdirAll(dir); err != nil {
+		return
+	}
+	c = &BlockCache{
+		slots:   slots,
+		lru:     list.New(),
+		entries: make(map[blockKey]*list.Element),
+		extents: make(map[extentKey]int),
+		ghost:   list.New(),
+		ghosts:  make(map[blockKey]*list.Element),
+	}
+	if c.file, err = os.OpenFile(path.Join(dir, BlockCacheFileName), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666); err != nil {
+		return nil, err
+	}
+	for slot := slots - 1; slot >= 0; slot-- {
+		c.free = append(c.free, slot)
+	}
+	return
+}
+
+// Close closes the cache file.
+func (c *BlockCache) Close() {
+	c.Lock()
+	defer c.Unlock()
+	c.file.Close()
+}
+
+// Stat returns the number of the hits and the misses, and the number of the cached blocks and the slots.
+func (c *BlockCache) Stat() (hits, misses uint64, blocks, slots int64) {
+	c.Lock()
+	defer c.Unlock()
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses), int64(c.lru.Len()), c.slots
+}
+
+func (c *BlockCache) epoch(partitionID, extentID uint64) *uint64 {
+	return &c.epochs[(partitionID*31+extentID)%blockCacheEpochCount]
+}
+
+// get reads the cached block into the buffer. If the block is missed, admit tells whether the block should be cached.
+func (c *BlockCache) get(key blockKey, buf []byte) (n int, hit, admit bool) {
+	c.Lock()
+	elem, ok := c.entries[key]
+	if !ok {
+		admit = c.recordMiss(key)
+		c.Unlock()
+		atomic.AddUint64(&c.misses, 1)
+		return
+	}
+	c.lru.MoveToFront(elem)
+	entry := *elem.Value.(*cacheEntry)
+	c.Unlock()
+
+	// the slot may be reused by another block during the read, which is detected by the crc
+	if _, err := c.file.ReadAt(buf[:entry.size], entry.slot*util.BlockSize); err != nil ||
+		crc32.ChecksumIEEE(buf[:entry.size]) != entry.crc {
+		atomic.AddUint64(&c.misses, 1)
+		return
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return entry.size, true, false
+}
+
+func (c *BlockCache) recordMiss(key blockKey) (admit bool) {
+	if elem, ok := c.ghosts[key]; ok {
+		g := elem.Value.(*ghostEntry)
+		g.count++
+		c.ghost.MoveToFront(elem)
+		return g.count >= BlockCacheAdmitCount
+	}
+	c.ghosts[key] = c.ghost.PushFront(&ghostEntry{key: key, count: 1})
+	if int64(c.ghost.Len()) > c.slots*blockCacheGhostMultiple {
+		c.removeGhost(c.ghost.Back())
+	}
+	return BlockCacheAdmitCount <= 1
+}
+
+func (c *BlockCache) removeGhost(elem *list.Element) {
+	c.ghost.Remove(elem)
+	delete(c.ghosts, elem.Value.(*ghostEntry).key)
+}
+
+// put caches the block read when the epoch of the extent was the given one.
+func (c *BlockCache) put(key blockKey, data []byte, epoch uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if atomic.LoadUint64(c.epoch(key.partitionID, key.extentID)) != epoch {
+		return
+	}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	if len(c.free) == 0 {
+		c.remove(c.lru.Back())
+	}
+	slot := c.free[len(c.free)-1]
+	c.free = c.free[:len(c.free)-1]
+	if _, err := c.file.WriteAt(data, slot*util.BlockSize); err != nil {
+		c.free = append(c.free, slot)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, slot: slot, size: len(data), crc: crc32.ChecksumIEEE(data)})
+	c.extents[extentKey{key.partitionID, key.extentID}]++
+	if elem, ok := c.ghosts[key]; ok {
+		c.removeGhost(elem)
+	}
+}
+
+func (c *BlockCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	ek := extentKey{entry.key.partitionID, entry.key.extentID}
+	if c.extents[ek]--; c.extents[ek] <= 0 {
+		delete(c.extents, ek)
+	}
+	c.free = append(c.free, entry.slot)
+}
+
+// Invalidate removes the cached blocks of the extent overlapping the range. All the blocks from the offset
+// are removed if the size is negative.
+func (c *BlockCache) Invalidate(partitionID, extentID uint64, offset, size int64) {
+	atomic.AddUint64(c.epoch(partitionID, extentID), 1)
+	c.Lock()
+	defer c.Unlock()
+	if c.extents[extentKey{partitionID, extentID}] == 0 {
+		return
+	}
+	last := util.BlockCount - 1
+	if size >= 0 {
+		last = int((offset + size - 1) / util.BlockSize)
+	}
+	for blockNo := int(offset / util.BlockSize); blockNo <= last; blockNo++ {
+		if elem, ok := c.entries[blockKey{partitionID, extentID, blockNo}]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// InvalidatePartition removes all the cached blocks of the partition.
+func (c *BlockCache) InvalidatePartition(partitionID uint64) {
+	for i := range c.epochs {
+		atomic.AddUint64(&c.epochs[i], 1)
+	}
+	c.Lock()
+	defer c.Unlock()
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cacheEntry).key.partitionID == partitionID {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// SetBlockCache sets the block cache shared by the extent stores of the data node.
+func (s *ExtentStore) SetBlockCache(c *BlockCache) {
+	s.blockCache = c
+}
+
+func (s *ExtentStore) invalidateCache(extentID uint64, offset, size int64) {
+	if s.blockCache != nil && !IsTinyExtent(extentID) {
+		s.blockCache.Invalidate(s.partitionID, extentID, offset, size)
+	}
+}
+
+// readWithCache reads the normal extent block by block, which reads the cached blocks from the block cache,
+// and reads the whole block from the extent if it is admitted to the cache.
+func (s *ExtentStore) readWithCache(e *Extent, data []byte, offset, size int64) (crc uint32, err error) {
+	if err = e.checkOffsetAndSize(offset, size); err != nil {
+		return
+	}
+	dataSize := e.Size()
+	if offset+size > dataSize {
+		return e.Read(data, offset, size, false)
+	}
+	buf, err := proto.Buffers.Get(util.BlockSize)
+	if err != nil {
+		return
+	}
+	defer proto.Buffers.Put(buf)
+
+	c := s.blockCache
+	for pos := offset; pos < offset+size; {
+		blockNo := int(pos / util.BlockSize)
+		blockOffset := int64(blockNo) * util.BlockSize
+		blockSize := util.Min(util.BlockSize, int(dataSize-blockOffset))
+		end := util.Min(int(offset+size), int(blockOffset)+blockSize)
+		key := blockKey{s.partitionID, e.extentID, blockNo}
+		n, hit, admit := c.get(key, buf)
+		switch {
+		case hit && n >= end-int(blockOffset):
+			copy(data[pos-offset:], buf[pos-blockOffset:int64(end)-blockOffset])
+		case admit:
+			epoch := atomic.LoadUint64(c.epoch(s.partitionID, e.extentID))
+			if _, err = e.readAt(buf[:blockSize], blockOffset); err != nil {
+				return
+			}
+			c.put(key, buf[:blockSize], epoch)
+			copy(data[pos-offset:], buf[pos-blockOffset:int64(end)-blockOffset])
+		default:
+			if _, err = e.readAt(data[pos-offset:int64(end)-offset], pos); err != nil {
+				return
+			}
+		}
+		pos = int64(end)
+	}
+	crc = proto.Checksum(s.checksum, data[:size])
+	return
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestBlockCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "block_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewExtentStore(path.Join(dir, "store"), 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBlockCache(path.Join(dir, "cache"), 2*util.BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetBlockCache(c)
+	extentID, _ := s.NextExtentID()
+	if err = s.Create(extentID); err != nil {
+		t.Fatal(err)
+	}
+	size := 3 * util.BlockSize
+	data := bytes.Repeat([]byte("0123456789"), size/10+1)[:size]
+	for offset := 0; offset < size; offset += util.BlockSize {
+		block := data[offset : offset+util.BlockSize]
+		if err = s.Write(extentID, int64(offset), int64(len(block)), block, crc32.ChecksumIEEE(block), AppendWriteType, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(offset int) {
+		buf := make([]byte, util.BlockSize)
+		if _, err := s.Read(extentID, int64(offset), util.BlockSize, buf, false); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, data[offset:offset+util.BlockSize]) {
+			t.Fatalf("data read at offset(%v) mismatches", offset)
+		}
+	}
+	// the blocks are admitted when they are missed twice
+	read(10)
+	if _, _, blocks, _ := c.Stat(); blocks != 0 {
+		t.Fatalf("blocks(%v) are cached after the first read", blocks)
+	}
+	read(10)
+	read(10)
+	if hits, misses, blocks, _ := c.Stat(); hits != 2 || misses != 4 || blocks != 2 {
+		t.Fatalf("hits(%v) misses(%v) blocks(%v)", hits, misses, blocks)
+	}
+
+	// the written blocks are invalidated
+	copy(data[util.BlockSize:], "new data")
+	if err = s.Write(extentID, util.BlockSize, 8, data[util.BlockSize:util.BlockSize+8], crc32.ChecksumIEEE(data[util.BlockSize:util.BlockSize+8]), RandomWriteType, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, blocks, _ := c.Stat(); blocks != 1 {
+		t.Fatalf("blocks(%v) after the write", blocks)
+	}
+	read(10)
+	read(10)
+
+	// the least recently used block is evicted
+	read(2 * util.BlockSize)
+	read(2 * util.BlockSize)
+	if _, ok := c.entries[blockKey{1, extentID, 0}]; ok {
+		t.Fatalf("the least recently used block is not evicted")
+	}
+
+	if err = s.MarkDelete(extentID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, blocks, _ := c.Stat(); blocks != 0 {
+		t.Fatalf("blocks(%v) after the extent is deleted", blocks)
+	}
+}
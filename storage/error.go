@@ -33,6 +33,7 @@ var (
 	ExtentIsFullError         = errors.New("extent is full")
 	BrokenExtentError         = errors.New("extent has been broken")
 	BrokenDiskError           = errors.New("disk has broken")
+	ExtentSharedError         = errors.New("extent is shared by the cloned volumes")
 )
 
 func NewParameterMismatchErr(msg string) (err error) {
@@ -27,6 +27,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/log"
 )
@@ -45,6 +46,8 @@ type ExtentInfo struct {
 	IsDeleted  bool   `json:"deleted"`
 	ModifyTime int64  `json:"modTime"`
 	Source     string `json:"src"`
+
+	CompressedSize uint64 `json:"csize,omitempty"` // size of the file of the compressed extent, 0 if not compressed
 }
 
 func (ei *ExtentInfo) String() (m string) {
@@ -67,6 +70,10 @@ type Extent struct {
 	hasClose   int32
 	header     []byte
 	sync.Mutex
+
+	compressed *compressedIndex // index of the blocks if the extent is compressed
+	fileLock   sync.RWMutex     // protects the file against the compression and the decompression
+	checksum   string           // checksum type of the data
 }
 
 // NewExtentInCore create and returns a new extent instance.
@@ -130,6 +137,14 @@ func (e *Extent) InitToFS() (err error) {
 // RestoreFromFS restores the entity data and status from the file stored on the filesystem.
 func (e *Extent) RestoreFromFS() (err error) {
 	if e.file, err = os.OpenFile(e.filePath, os.O_RDWR, 0666); err != nil {
+		if os.IsNotExist(err) && !IsTinyExtent(e.extentID) {
+			if e.file, err = os.OpenFile(e.filePath+CompressedExtentSuffix, os.O_RDWR, 0666); err == nil {
+				if err = e.restoreCompressed(); err != nil {
+					e.file.Close()
+				}
+				return
+			}
+		}
 		if strings.Contains(err.Error(), syscall.ENOENT.Error()) {
 			err = ExtentNotFoundError
 		}
@@ -216,6 +231,10 @@ func (e *Extent) Write(data []byte, offset, size int64, crc uint32, writeType in
 	if err = e.checkOffsetAndSize(offset, size); err != nil {
 		return
 	}
+	if err = e.lockForWrite(); err != nil {
+		return
+	}
+	defer e.fileLock.RUnlock()
 	if _, err = e.file.WriteAt(data[:size], int64(offset)); err != nil {
 		return
 	}
@@ -255,10 +274,10 @@ func (e *Extent) Read(data []byte, offset, size int64, isRepairRead bool) (crc u
 	if err = e.checkOffsetAndSize(offset, size); err != nil {
 		return
 	}
-	if _, err = e.file.ReadAt(data[:size], offset); err != nil {
+	if _, err = e.readAt(data[:size], offset); err != nil {
 		return
 	}
-	crc = crc32.ChecksumIEEE(data)
+	crc = proto.Checksum(e.checksum, data[:size])
 	return
 }
 
@@ -268,7 +287,7 @@ func (e *Extent) ReadTiny(data []byte, offset, size int64, isRepairRead bool) (c
 	if isRepairRead && err == io.EOF {
 		err = nil
 	}
-	crc = crc32.ChecksumIEEE(data[:size])
+	crc = proto.Checksum(e.checksum, data[:size])
 
 	return
 }
@@ -308,11 +327,11 @@ func (e *Extent) autoComputeExtentCrc(crcFunc UpdateCrcFunc) (crc uint32, err er
 		}
 		bdata := make([]byte, util.BlockSize)
 		offset := int64(blockNo * util.BlockSize)
-		readN, err := e.file.ReadAt(bdata[:util.BlockSize], offset)
+		readN, err := e.readAt(bdata[:util.BlockSize], offset)
 		if readN == 0 && err != nil {
 			break
 		}
-		blockCrc = crc32.ChecksumIEEE(bdata[:readN])
+		blockCrc = proto.Checksum(e.checksum, bdata[:readN])
 		err = crcFunc(e, blockNo, blockCrc)
 		if err != nil {
 			return 0, nil
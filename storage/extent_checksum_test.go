@@ -0,0 +1,121 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestReadVerifyChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extent_checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewExtentStore(dir, 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetChecksum(proto.ChecksumCRC32C)
+	extentID, _ := s.NextExtentID()
+	if err = s.Create(extentID); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("checksum"), util.BlockSize/8)
+	crc := proto.Checksum(proto.ChecksumCRC32C, data)
+	if err = s.Write(extentID, 0, util.BlockSize, data, crc, AppendWriteType, false); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, util.BlockSize)
+	if readCrc, err := s.Read(extentID, 0, util.BlockSize, buf, false); err != nil || readCrc != crc {
+		t.Fatalf("read block: crc(%v) expected(%v) err(%v)", readCrc, crc, err)
+	}
+
+	// corrupt the block on the disk
+	file, err := os.OpenFile(path.Join(dir, strconv.FormatUint(extentID, 10)), os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err = file.WriteAt([]byte("corrupted"), 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Read(extentID, 0, util.BlockSize, buf, false); err == nil || !strings.Contains(err.Error(), proto.ErrChecksumMismatch.Error()) {
+		t.Fatalf("corrupted block is read without error, err(%v)", err)
+	}
+	// the partial reads are verified by the whole block covering them
+	if _, err = s.Read(extentID, 0, 10, buf, false); err == nil || !strings.Contains(err.Error(), proto.ErrChecksumMismatch.Error()) {
+		t.Fatalf("partial read of the corrupted block is read without error, err(%v)", err)
+	}
+}
+
+func TestScrubRepairBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extent_scrub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewExtentStore(dir, 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extentID, _ := s.NextExtentID()
+	if err = s.Create(extentID); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("scrubbed"), util.BlockSize/8)
+	crc := proto.Checksum(proto.ChecksumCRC32, data)
+	for blockNo := 0; blockNo < 2; blockNo++ {
+		if err = s.Write(extentID, int64(blockNo*util.BlockSize), util.BlockSize, data, crc, AppendWriteType, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.OpenFile(path.Join(dir, strconv.FormatUint(extentID, 10)), os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err = file.WriteAt([]byte("corrupted"), util.BlockSize+100); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, badBlocks, err := s.ScrubExtent(extentID, nil); err != nil || len(badBlocks) != 1 || badBlocks[0] != 1 {
+		t.Fatalf("scrub: bad blocks(%v) err(%v)", badBlocks, err)
+	}
+
+	if err = s.RepairBlock(extentID, 1, []byte("corrupted"), crc); err != CrcMismatchError {
+		t.Fatalf("repair with the mismatched data: expect %v, actual %v", CrcMismatchError, err)
+	}
+	if err = s.RepairBlock(extentID, 1, data[:100], proto.Checksum(proto.ChecksumCRC32, data[:100])); err != ParameterMismatchError {
+		t.Fatalf("repair with the partial block: expect %v, actual %v", ParameterMismatchError, err)
+	}
+	if err = s.RepairBlock(extentID, 1, data, crc); err != nil {
+		t.Fatal(err)
+	}
+	size, _, badBlocks, err := s.ScrubExtent(extentID, nil)
+	if err != nil || len(badBlocks) != 0 || size != 2*util.BlockSize {
+		t.Fatalf("scrub after repair: size(%v) bad blocks(%v) err(%v)", size, badBlocks, err)
+	}
+}
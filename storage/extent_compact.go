@@ -0,0 +1,234 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync/atomic"
+	"syscall"
+)
+
+// A tiny extent is compacted by relocating its live data to the other tiny extents, which is done by the meta nodes
+// since only they know the extent keys referring to the data. The tiny extent is not written while it is compacting,
+// and once all of its data has been deleted, it is truncated to empty together with its tiny delete records.
+const (
+	tinyDeleteTmpFileName = TinyExtDeletedFileName + ".tmp"
+	tinyDeleteScanRecords = 4096 // number of the records read at a time by scanning the tiny delete record file
+)
+
+type tinyRange struct {
+	offset int64
+	end    int64
+}
+
+type tinyRanges []tinyRange
+
+func (r tinyRanges) Len() int           { return len(r) }
+func (r tinyRanges) Less(i, j int) bool { return r[i].offset < r[j].offset }
+func (r tinyRanges) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// size returns the size of the union of the ranges.
+func (r tinyRanges) size() (size int64) {
+	sort.Sort(r)
+	var end int64
+	for _, tr := range r {
+		if tr.end <= end {
+			continue
+		}
+		if tr.offset > end {
+			size += tr.end - tr.offset
+		} else {
+			size += tr.end - end
+		}
+		end = tr.end
+	}
+	return
+}
+
+// IsTinyExtentCompacting returns if the live data of the tiny extent is being relocated.
+func (s *ExtentStore) IsTinyExtentCompacting(extentID uint64) bool {
+	_, ok := s.compactingTinyExtents.Load(extentID)
+	return ok
+}
+
+// MarkTinyExtentCompacting stops the tiny extent from being allocated to the writes, so that
+// its live data can be relocated.
+func (s *ExtentStore) MarkTinyExtentCompacting(extentID uint64) {
+	if IsTinyExtent(extentID) {
+		s.compactingTinyExtents.Store(extentID, true)
+	}
+}
+
+// CompactingTinyExtents returns the tiny extents being compacted in ascending order.
+func (s *ExtentStore) CompactingTinyExtents() (extents []uint64) {
+	extents = make([]uint64, 0)
+	s.compactingTinyExtents.Range(func(key, value interface{}) bool {
+		extents = append(extents, key.(uint64))
+		return true
+	})
+	sort.Slice(extents, func(i, j int) bool { return extents[i] < extents[j] })
+	return
+}
+
+// TinyReclaimableSize returns the size of the deleted data of the tiny extents computed by the last scan.
+func (s *ExtentStore) TinyReclaimableSize() uint64 {
+	return atomic.LoadUint64(&s.tinyReclaimableSize)
+}
+
+// TinyDeletedSizes scans the tiny delete records and returns the size of the deleted data of each tiny extent.
+// Since the writes to a tiny extent are aligned to pages, a deleted range covers the padding up to the next page,
+// and the deleted size equals to the size of the extent once all of its data has been deleted.
+func (s *ExtentStore) TinyDeletedSizes() (deleted map[uint64]int64, err error) {
+	ranges := make(map[uint64]tinyRanges)
+	err = s.scanTinyDeleteRecords(func(extentID uint64, offset, size int64) {
+		if !IsTinyExtent(extentID) || size <= 0 {
+			return
+		}
+		end := offset + size
+		if end%PageSize != 0 {
+			end = end + (PageSize - end%PageSize)
+		}
+		ranges[extentID] = append(ranges[extentID], tinyRange{offset: offset, end: end})
+	})
+	if err != nil {
+		return
+	}
+	var total int64
+	deleted = make(map[uint64]int64, len(ranges))
+	for extentID, r := range ranges {
+		deleted[extentID] = r.size()
+		total += deleted[extentID]
+	}
+	atomic.StoreUint64(&s.tinyReclaimableSize, uint64(total))
+	return
+}
+
+func (s *ExtentStore) scanTinyDeleteRecords(fn func(extentID uint64, offset, size int64)) (err error) {
+	s.tinyDeleteLock.Lock()
+	defer s.tinyDeleteLock.Unlock()
+	data := make([]byte, tinyDeleteScanRecords*DeleteTinyRecordSize)
+	var offset int64
+	for {
+		n, readErr := s.tinyExtentDeleteFp.ReadAt(data, offset)
+		for index := 0; (index+1)*DeleteTinyRecordSize <= n; index++ {
+			extentID, extentOffset, size := UnMarshalTinyExtent(data[index*DeleteTinyRecordSize : (index+1)*DeleteTinyRecordSize])
+			fn(extentID, int64(extentOffset), int64(size))
+		}
+		offset += int64(n - n%DeleteTinyRecordSize)
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// ResetTinyExtent truncates the tiny extent, all the data of which has been deleted, to empty and removes
+// its tiny delete records. The extent is rejected if it is larger than the given size, in which case
+// some data may have been written after it was checked.
+func (s *ExtentStore) ResetTinyExtent(extentID uint64, size int64) (err error) {
+	if !IsTinyExtent(extentID) {
+		return fmt.Errorf("extent %v not tinyExtent", extentID)
+	}
+	s.eiMutex.RLock()
+	ei := s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	e, err := s.extentWithHeader(ei)
+	if err != nil {
+		return
+	}
+	if err = e.truncateTiny(size); err != nil {
+		return
+	}
+	ei.UpdateExtentInfo(e, 0)
+	if err = s.removeTinyDeleteRecords(extentID); err != nil {
+		return
+	}
+	s.compactingTinyExtents.Delete(extentID)
+	s.SendToBrokenTinyExtentC(extentID)
+	return
+}
+
+func (e *Extent) truncateTiny(size int64) (err error) {
+	e.Lock()
+	defer e.Unlock()
+	if e.dataSize > size {
+		return NewParameterMismatchErr(fmt.Sprintf("extent %v size(%v) larger than %v", e.extentID, e.dataSize, size))
+	}
+	if err = syscall.Ftruncate(int(e.file.Fd()), 0); err != nil {
+		return
+	}
+	e.dataSize = 0
+	return
+}
+
+// removeTinyDeleteRecords rewrites the tiny delete record file without the records of the tiny extent.
+func (s *ExtentStore) removeTinyDeleteRecords(extentID uint64) (err error) {
+	s.tinyDeleteLock.Lock()
+	defer s.tinyDeleteLock.Unlock()
+	tmpName := path.Join(s.dataPath, tinyDeleteTmpFileName)
+	tmpFp, err := os.OpenFile(tmpName, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return
+	}
+	defer func() {
+		tmpFp.Close()
+		if err != nil {
+			os.Remove(tmpName)
+		}
+	}()
+
+	data := make([]byte, tinyDeleteScanRecords*DeleteTinyRecordSize)
+	var offset int64
+	for {
+		n, readErr := s.tinyExtentDeleteFp.ReadAt(data, offset)
+		kept := data[:0]
+		for index := 0; (index+1)*DeleteTinyRecordSize <= n; index++ {
+			record := data[index*DeleteTinyRecordSize : (index+1)*DeleteTinyRecordSize]
+			if id, _, _ := UnMarshalTinyExtent(record); id != extentID {
+				kept = append(kept, record...)
+			}
+		}
+		if _, err = tmpFp.Write(kept); err != nil {
+			return
+		}
+		offset += int64(n - n%DeleteTinyRecordSize)
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if err = tmpFp.Sync(); err != nil {
+		return
+	}
+	name := path.Join(s.dataPath, TinyExtDeletedFileName)
+	if err = os.Rename(tmpName, name); err != nil {
+		return
+	}
+	fp, err := os.OpenFile(name, TinyDeleteFileOpt, 0666)
+	if err != nil {
+		return
+	}
+	s.tinyExtentDeleteFp.Close()
+	s.tinyExtentDeleteFp = fp
+	return
+}
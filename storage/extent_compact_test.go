@@ -0,0 +1,98 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestCompactTinyExtent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extent_compact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewExtentStore(dir, 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extentID, otherID uint64 = TinyExtentStartID, TinyExtentStartID + 1
+
+	write := func(id uint64, size int) (offset int64) {
+		data := bytes.Repeat([]byte("t"), size)
+		offset, _ = s.GetTinyExtentOffset(id)
+		if err := s.Write(id, offset, int64(size), data, crc32.ChecksumIEEE(data), AppendWriteType, false); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	first := write(extentID, 100)
+	second := write(extentID, PageSize+1)
+	other := write(otherID, 100)
+
+	if err = s.MarkDelete(extentID, first, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.MarkDelete(otherID, other, 100); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := s.TinyDeletedSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ei, _ := s.Watermark(extentID)
+	if deleted[extentID] != PageSize || int64(ei.Size) == deleted[extentID] {
+		t.Fatalf("deleted size(%v) of the extent with size(%v)", deleted[extentID], ei.Size)
+	}
+
+	s.MarkTinyExtentCompacting(extentID)
+	for i := 0; i < TinyExtentCount; i++ {
+		id, err := s.GetBrokenTinyExtent()
+		if err != nil {
+			break
+		}
+		if id == extentID {
+			t.Fatalf("compacting extent is allocated")
+		}
+	}
+	if err = s.MarkDelete(extentID, second, PageSize+1); err != nil {
+		t.Fatal(err)
+	}
+	// the extent may be written after checked
+	if err = s.ResetTinyExtent(extentID, PageSize); err == nil {
+		t.Fatalf("reset the extent larger than the checked size")
+	}
+	if deleted, _ = s.TinyDeletedSizes(); deleted[extentID] != int64(ei.Size) {
+		t.Fatalf("deleted size(%v) of the extent with size(%v)", deleted[extentID], ei.Size)
+	}
+	if err = s.ResetTinyExtent(extentID, int64(ei.Size)); err != nil {
+		t.Fatal(err)
+	}
+	if ei.Size != 0 || s.IsTinyExtentCompacting(extentID) {
+		t.Fatalf("extent size(%v) compacting(%v) after reset", ei.Size, s.IsTinyExtentCompacting(extentID))
+	}
+	if deleted, _ = s.TinyDeletedSizes(); deleted[extentID] != 0 || deleted[otherID] != PageSize {
+		t.Fatalf("delete records after reset: %v", deleted)
+	}
+	if offset := write(extentID, 10); offset != 0 {
+		t.Fatalf("write to the reset extent at offset(%v)", offset)
+	}
+}
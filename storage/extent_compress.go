@@ -0,0 +1,383 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// A compressed normal extent is stored in the file "<extentID>.z", which starts with a header and the offsets of
+// the blocks in the file, followed by the blocks compressed independently, so that any block can be read without
+// decompressing the others. A block is stored raw if the compression saves no space.
+const (
+	CompressedExtentSuffix = ".z"
+
+	compressedExtentMagic      = "CFSZ"
+	compressedExtentHeaderSize = 24 // magic(4) codec(1) reserved(3) dataSize(8) blockCnt(4) reserved(4)
+)
+
+// Codecs of the compressed extents
+const (
+	codecFlate uint8 = 1
+)
+
+var compressionCodecs = map[string]uint8{
+	proto.CompressionFlate: codecFlate,
+}
+
+// compressedIndex describes the blocks of a compressed extent.
+type compressedIndex struct {
+	codec   uint8
+	offsets []int64 // offsets of the blocks in the file, with the file size appended
+}
+
+func (idx *compressedIndex) fileSize() int64 {
+	return idx.offsets[len(idx.offsets)-1]
+}
+
+func compressBlock(codec uint8, data []byte) (compressed []byte, err error) {
+	if codec != codecFlate {
+		return nil, fmt.Errorf("unknown codec %v", codec)
+	}
+	var (
+		buf bytes.Buffer
+		w   *flate.Writer
+	)
+	if w, err = flate.NewWriter(&buf, flate.BestSpeed); err != nil {
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressBlock(codec uint8, data []byte, rawSize int) (raw []byte, err error) {
+	if codec != codecFlate {
+		return nil, fmt.Errorf("unknown codec %v", codec)
+	}
+	raw = make([]byte, rawSize)
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	if _, err = io.ReadFull(r, raw); err != nil {
+		err = fmt.Errorf("decompress block: %v", err)
+	}
+	return
+}
+
+func blockCount(size int64) int {
+	return int((size + util.BlockSize - 1) / util.BlockSize)
+}
+
+func (e *Extent) blockRawSize(blockNo int) int {
+	return util.Min(util.BlockSize, int(e.dataSize-int64(blockNo)*util.BlockSize))
+}
+
+// IsCompressed returns if the extent is stored compressed.
+func (e *Extent) IsCompressed() bool {
+	e.fileLock.RLock()
+	defer e.fileLock.RUnlock()
+	return e.compressed != nil
+}
+
+// CompressedSize returns the size of the file of the compressed extent, 0 if the extent is not compressed.
+func (e *Extent) CompressedSize() int64 {
+	e.fileLock.RLock()
+	defer e.fileLock.RUnlock()
+	if e.compressed == nil {
+		return 0
+	}
+	return e.compressed.fileSize()
+}
+
+// restoreCompressed loads the index of the compressed extent from the opened file.
+func (e *Extent) restoreCompressed() (err error) {
+	header := make([]byte, compressedExtentHeaderSize)
+	if _, err = e.file.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("read header of %v: %v", e.file.Name(), err)
+	}
+	if string(header[:4]) != compressedExtentMagic {
+		return fmt.Errorf("invalid compressed extent %v", e.file.Name())
+	}
+	idx := &compressedIndex{codec: header[4]}
+	dataSize := int64(binary.BigEndian.Uint64(header[8:16]))
+	blockCnt := int(binary.BigEndian.Uint32(header[16:20]))
+	data := make([]byte, (blockCnt+1)*8)
+	if _, err = e.file.ReadAt(data, compressedExtentHeaderSize); err != nil {
+		return fmt.Errorf("read index of %v: %v", e.file.Name(), err)
+	}
+	idx.offsets = make([]int64, blockCnt+1)
+	for i := range idx.offsets {
+		idx.offsets[i] = int64(binary.BigEndian.Uint64(data[i*8 : (i+1)*8]))
+	}
+	info, err := e.file.Stat()
+	if err != nil {
+		return
+	}
+	e.compressed = idx
+	e.dataSize = dataSize
+	atomic.StoreInt64(&e.modifyTime, info.ModTime().Unix())
+	return
+}
+
+func (e *Extent) readCompressedBlock(blockNo int) (block []byte, err error) {
+	start, end := e.compressed.offsets[blockNo], e.compressed.offsets[blockNo+1]
+	data := make([]byte, end-start)
+	if _, err = e.file.ReadAt(data, start); err != nil {
+		return
+	}
+	rawSize := e.blockRawSize(blockNo)
+	if len(data) == rawSize {
+		return data, nil
+	}
+	return decompressBlock(e.compressed.codec, data, rawSize)
+}
+
+// readAt reads the data of the extent at the offset, which decompresses the blocks if the extent is compressed.
+func (e *Extent) readAt(data []byte, offset int64) (n int, err error) {
+	e.fileLock.RLock()
+	defer e.fileLock.RUnlock()
+	if e.compressed == nil {
+		return e.file.ReadAt(data, offset)
+	}
+	var block []byte
+	for n < len(data) {
+		pos := offset + int64(n)
+		if pos >= e.dataSize {
+			return n, io.EOF
+		}
+		if block, err = e.readCompressedBlock(int(pos / util.BlockSize)); err != nil {
+			return
+		}
+		n += copy(data[n:], block[pos%util.BlockSize:])
+	}
+	return
+}
+
+// lockForWrite locks the file of the extent for writing, which decompresses the extent at first if it is compressed.
+// The caller must call e.fileLock.RUnlock when it finishes writing.
+func (e *Extent) lockForWrite() (err error) {
+	for {
+		e.fileLock.RLock()
+		if e.compressed == nil {
+			return
+		}
+		e.fileLock.RUnlock()
+		if err = e.decompress(); err != nil {
+			return
+		}
+	}
+}
+
+// replaceFile replaces the file of the extent with the file at the temporary path. The caller must hold the fileLock.
+func (e *Extent) replaceFile(tmpPath, newPath, oldPath string, idx *compressedIndex) (err error) {
+	modifyTime := time.Unix(e.ModifyTime(), 0)
+	if err = os.Rename(tmpPath, newPath); err != nil {
+		return
+	}
+	os.Chtimes(newPath, modifyTime, modifyTime)
+	file, err := os.OpenFile(newPath, os.O_RDWR, 0666)
+	if err != nil {
+		return
+	}
+	e.file.Close()
+	e.file = file
+	e.compressed = idx
+	return os.Remove(oldPath)
+}
+
+// decompress rewrites the compressed extent into the raw file, so that it can be written.
+func (e *Extent) decompress() (err error) {
+	e.fileLock.Lock()
+	defer e.fileLock.Unlock()
+	if e.compressed == nil {
+		return
+	}
+	tmpPath := e.filePath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmpPath)
+		}
+	}()
+	var block []byte
+	for blockNo := 0; blockNo < blockCount(e.dataSize); blockNo++ {
+		if block, err = e.readCompressedBlock(blockNo); err != nil {
+			return
+		}
+		if _, err = tmp.WriteAt(block, int64(blockNo)*util.BlockSize); err != nil {
+			return
+		}
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	return e.replaceFile(tmpPath, e.filePath, e.filePath+CompressedExtentSuffix, nil)
+}
+
+// CompressExtent rewrites the sealed normal extent into compressed blocks with the codec of the compression mode.
+// The extent is not compressed if the crc of the extent has not been computed yet, or the compression saves no space.
+// The crc of each block is verified before compressing, and remains the crc of the uncompressed data.
+func (s *ExtentStore) CompressExtent(extentID uint64, mode string) (compressed bool, err error) {
+	codec, ok := compressionCodecs[mode]
+	if IsTinyExtent(extentID) || !ok {
+		return false, ParameterMismatchError
+	}
+	if _, incompressible := s.incompressibleExtents.Load(extentID); incompressible {
+		return
+	}
+	s.eiMutex.RLock()
+	ei := s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	e, err := s.extentWithHeader(ei)
+	if err != nil || e.IsCompressed() || atomic.LoadUint32(&ei.Crc) == 0 {
+		return
+	}
+
+	e.fileLock.RLock()
+	size, modifyTime := e.dataSize, e.ModifyTime()
+	tmpPath := e.filePath + CompressedExtentSuffix + ".tmp"
+	idx, err := e.writeCompressedFile(tmpPath, codec, size)
+	e.fileLock.RUnlock()
+	if err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+	if idx.fileSize() >= size {
+		os.Remove(tmpPath)
+		s.incompressibleExtents.Store(extentID, true)
+		return
+	}
+
+	e.fileLock.Lock()
+	defer e.fileLock.Unlock()
+	if e.compressed != nil || e.dataSize != size || e.ModifyTime() != modifyTime {
+		os.Remove(tmpPath)
+		return
+	}
+	if err = e.replaceFile(tmpPath, e.filePath+CompressedExtentSuffix, e.filePath, idx); err != nil {
+		return
+	}
+	atomic.StoreUint64(&ei.CompressedSize, uint64(idx.fileSize()))
+	return true, nil
+}
+
+func (e *Extent) writeCompressedFile(name string, codec uint8, size int64) (idx *compressedIndex, err error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	blockCnt := blockCount(size)
+	idx = &compressedIndex{codec: codec, offsets: make([]int64, blockCnt+1)}
+	offset := int64(compressedExtentHeaderSize + (blockCnt+1)*8)
+	data := make([]byte, util.BlockSize)
+	for blockNo := 0; blockNo < blockCnt; blockNo++ {
+		readSize := util.Min(util.BlockSize, int(size-int64(blockNo)*util.BlockSize))
+		if _, err = e.file.ReadAt(data[:readSize], int64(blockNo)*util.BlockSize); err != nil {
+			return
+		}
+		recorded := binary.BigEndian.Uint32(e.header[blockNo*util.PerBlockCrcSize : (blockNo+1)*util.PerBlockCrcSize])
+		if crc := proto.Checksum(e.checksum, data[:readSize]); recorded != 0 && recorded != crc {
+			err = fmt.Errorf("block(%v) crc(%v) mismatches the recorded crc(%v): %v", blockNo, crc, recorded, CrcMismatchError)
+			return
+		}
+		var block []byte
+		if block, err = compressBlock(codec, data[:readSize]); err != nil {
+			return
+		}
+		if len(block) >= readSize {
+			block = data[:readSize]
+		}
+		if _, err = file.WriteAt(block, offset); err != nil {
+			return
+		}
+		idx.offsets[blockNo] = offset
+		offset += int64(len(block))
+	}
+	idx.offsets[blockCnt] = offset
+
+	meta := make([]byte, compressedExtentHeaderSize+(blockCnt+1)*8)
+	copy(meta, compressedExtentMagic)
+	meta[4] = codec
+	binary.BigEndian.PutUint64(meta[8:16], uint64(size))
+	binary.BigEndian.PutUint32(meta[16:20], uint32(blockCnt))
+	for i, off := range idx.offsets {
+		pos := compressedExtentHeaderSize + i*8
+		binary.BigEndian.PutUint64(meta[pos:pos+8], uint64(off))
+	}
+	if _, err = file.WriteAt(meta, 0); err != nil {
+		return
+	}
+	err = file.Sync()
+	return
+}
+
+// CompressionStat returns the total size of the data of the compressed extents and that of their files.
+func (s *ExtentStore) CompressionStat() (rawSize, compressedSize uint64) {
+	s.eiMutex.RLock()
+	defer s.eiMutex.RUnlock()
+	for _, ei := range s.extentInfoMap {
+		if size := atomic.LoadUint64(&ei.CompressedSize); size > 0 && !ei.IsDeleted {
+			rawSize += ei.Size
+			compressedSize += size
+		}
+	}
+	return
+}
+
+// removeExtentFiles removes the raw file and the compressed file of the normal extent.
+func (s *ExtentStore) removeExtentFiles(name string) (err error) {
+	err = os.Remove(name)
+	if errCompressed := os.Remove(name + CompressedExtentSuffix); os.IsNotExist(err) && errCompressed == nil {
+		err = nil
+	}
+	return
+}
+
+// cleanCompressTmpFiles removes the temporary files left by the interrupted compression or decompression.
+func (s *ExtentStore) cleanCompressTmpFiles() {
+	files, err := ioutil.ReadDir(s.dataPath)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(strings.TrimSuffix(f.Name(), ".tmp"), CompressedExtentSuffix)
+		if _, isExtent := s.ExtentID(name); isExtent && strings.HasSuffix(f.Name(), ".tmp") {
+			os.Remove(path.Join(s.dataPath, f.Name()))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestCompressExtent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extent_compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewExtentStore(dir, 1, util.GB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extentID, _ := s.NextExtentID()
+	if err = s.Create(extentID); err != nil {
+		t.Fatal(err)
+	}
+	size := 2*util.BlockSize + 100
+	data := bytes.Repeat([]byte("compressible text "), size/18+1)[:size]
+	for offset := 0; offset < size; offset += util.BlockSize {
+		block := data[offset:util.Min(offset+util.BlockSize, size)]
+		if err = s.Write(extentID, int64(offset), int64(len(block)), block, crc32.ChecksumIEEE(block), AppendWriteType, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ei, _ := s.Watermark(extentID)
+	ei.Crc = 1
+
+	read := func() []byte {
+		buf := make([]byte, util.BlockSize)
+		if _, err := s.Read(extentID, util.BlockSize-10, util.BlockSize, buf, false); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+	if compressed, err := s.CompressExtent(extentID, proto.CompressionFlate); err != nil || !compressed {
+		t.Fatalf("compress extent: compressed(%v) err(%v)", compressed, err)
+	}
+	if raw, compressed := s.CompressionStat(); raw != uint64(size) || compressed == 0 || compressed >= raw {
+		t.Fatalf("compression stat: raw(%v) compressed(%v)", raw, compressed)
+	}
+	if !bytes.Equal(read(), data[util.BlockSize-10:2*util.BlockSize-10]) {
+		t.Fatalf("data read from the compressed extent mismatches")
+	}
+	// load the compressed extent from the disk again
+	s.cache.Del(extentID)
+	if !bytes.Equal(read(), data[util.BlockSize-10:2*util.BlockSize-10]) {
+		t.Fatalf("data read from the reloaded compressed extent mismatches")
+	}
+
+	// the compressed extent is decompressed before written
+	if err = s.Write(extentID, 0, 4, []byte("head"), crc32.ChecksumIEEE([]byte("head")), RandomWriteType, false); err != nil {
+		t.Fatal(err)
+	}
+	if ei.CompressedSize != 0 {
+		t.Fatalf("extent is still compressed after written")
+	}
+	s.cache.Del(extentID)
+	if !bytes.Equal(read(), data[util.BlockSize-10:2*util.BlockSize-10]) {
+		t.Fatalf("data read from the decompressed extent mismatches")
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalExtentWithLaggingFilter(t *testing.T) {
+	now := time.Now().Unix()
+	filter := NormalExtentWithLaggingFilter([]uint64{MinExtentID + 1, MinExtentID + 2, MinExtentID + 3})
+	cases := []struct {
+		ei     *ExtentInfo
+		passed bool
+	}{
+		{&ExtentInfo{FileID: MinExtentID, Size: 1, ModifyTime: now - RepairInterval - 1}, true},
+		{&ExtentInfo{FileID: MinExtentID, Size: 1, ModifyTime: now - LaggingRepairInterval - 1}, false},
+		{&ExtentInfo{FileID: MinExtentID + 1, Size: 1, ModifyTime: now - LaggingRepairInterval - 1}, true},
+		{&ExtentInfo{FileID: MinExtentID + 2, Size: 1, ModifyTime: now}, false},
+		{&ExtentInfo{FileID: MinExtentID + 3, Size: 1, ModifyTime: now - LaggingRepairInterval - 1, IsDeleted: true}, false},
+		{&ExtentInfo{FileID: TinyExtentStartID, Size: 1, ModifyTime: now - RepairInterval - 1}, false},
+	}
+	for i, c := range cases {
+		if filter(c.ei) != c.passed {
+			t.Fatalf("case %v: extent(%v) passed(%v) expected(%v)", i, c.ei.FileID, !c.passed, c.passed)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// ScrubExtent re-reads every block of the normal extent, and returns the crc of the data of each block
+// on the disk, along with the blocks mismatching the recorded crc. The blocks whose crc has not been
+// computed yet are not verified. The function wait is called before reading each block to limit the rate.
+func (s *ExtentStore) ScrubExtent(extentID uint64, wait func(size int)) (size int64, crcs []uint32, badBlocks []int, err error) {
+	if IsTinyExtent(extentID) {
+		err = ParameterMismatchError
+		return
+	}
+	s.eiMutex.RLock()
+	ei := s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	e, err := s.extentWithHeader(ei)
+	if err != nil {
+		return
+	}
+
+	size = e.Size()
+	blockCnt := int(size / util.BlockSize)
+	if size%util.BlockSize != 0 {
+		blockCnt += 1
+	}
+	crcs = make([]uint32, 0, blockCnt)
+	badBlocks = make([]int, 0)
+	data := make([]byte, util.BlockSize)
+	for blockNo := 0; blockNo < blockCnt; blockNo++ {
+		offset := int64(blockNo * util.BlockSize)
+		readSize := util.Min(util.BlockSize, int(size-offset))
+		if wait != nil {
+			wait(readSize)
+		}
+		if _, err = e.readAt(data[:readSize], offset); err != nil {
+			return
+		}
+		crc := proto.Checksum(s.checksum, data[:readSize])
+		crcs = append(crcs, crc)
+		recorded := binary.BigEndian.Uint32(e.header[blockNo*util.PerBlockCrcSize : (blockNo+1)*util.PerBlockCrcSize])
+		if recorded != 0 && recorded != crc {
+			badBlocks = append(badBlocks, blockNo)
+		}
+	}
+	return
+}
+
+// RepairBlock overwrites the bad block of the normal extent with the data of a healthy replica, which must be
+// the whole block and match the crc of the block on that replica.
+func (s *ExtentStore) RepairBlock(extentID uint64, blockNo int, data []byte, crc uint32) (err error) {
+	if IsTinyExtent(extentID) || len(data) == 0 || len(data) > util.BlockSize {
+		return ParameterMismatchError
+	}
+	if proto.Checksum(s.checksum, data) != crc {
+		return CrcMismatchError
+	}
+	s.eiMutex.RLock()
+	ei := s.extentInfoMap[extentID]
+	s.eiMutex.RUnlock()
+	e, err := s.extentWithHeader(ei)
+	if err != nil {
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	offset := int64(blockNo * util.BlockSize)
+	if offset >= e.dataSize || int64(len(data)) != int64(util.Min(util.BlockSize, int(e.dataSize-offset))) {
+		return ParameterMismatchError
+	}
+	if err = e.lockForWrite(); err != nil {
+		return
+	}
+	defer e.fileLock.RUnlock()
+	defer s.invalidateCache(extentID, offset, int64(len(data)))
+	if _, err = e.file.WriteAt(data, offset); err != nil {
+		return
+	}
+	if err = e.file.Sync(); err != nil {
+		return
+	}
+	if err = s.PersistenceBlockCrc(e, blockNo, crc); err != nil {
+		return
+	}
+	atomic.StoreUint32(&ei.Crc, 0)
+	return
+}
@@ -52,6 +52,7 @@ const (
 	DeleteTinyRecordSize         = 24
 	UpdateCrcInterval            = 600
 	RepairInterval               = 60
+	LaggingRepairInterval        = 5 // the lagging extents written with quorum are repaired once idle for the interval
 	RandomWriteType              = 2
 	AppendWriteType              = 1
 	NormalExtentDeleteRetainTime = 3600 * 4
@@ -84,6 +85,24 @@ var (
 		}
 	}
 
+	// NormalExtentWithLaggingFilter also passes the lagging extents idle for LaggingRepairInterval,
+	// so that the replicas lagging behind the quorum writes are caught up sooner.
+	NormalExtentWithLaggingFilter = func(lagging []uint64) ExtentFilter {
+		now := time.Now()
+		normalFilter := NormalExtentFilter()
+		laggingExtents := make(map[uint64]bool, len(lagging))
+		for _, extentID := range lagging {
+			laggingExtents[extentID] = true
+		}
+		return func(ei *ExtentInfo) bool {
+			if normalFilter(ei) {
+				return true
+			}
+			return laggingExtents[ei.FileID] && !IsTinyExtent(ei.FileID) && now.Unix()-ei.ModifyTime > LaggingRepairInterval &&
+				ei.IsDeleted == false && ei.Size > 0
+		}
+	}
+
 	TinyExtentFilter = func(filters []uint64) ExtentFilter {
 		return func(ei *ExtentInfo) bool {
 			if !IsTinyExtent(ei.FileID) {
@@ -127,6 +146,12 @@ type ExtentStore struct {
 	verifyExtentFp                    *os.File
 	hasAllocSpaceExtentIDOnVerfiyFile uint64
 	hasDeleteNormalExtentsCache       sync.Map
+	incompressibleExtents             sync.Map // normal extents which the compression saves no space of
+	blockCache                        *BlockCache
+	tinyDeleteLock                    sync.Mutex // protects the tiny delete record file against the compaction
+	compactingTinyExtents             sync.Map   // tiny extents whose live data is being relocated
+	tinyReclaimableSize               uint64     // size of the deleted data of the tiny extents
+	checksum                          string     // checksum type of the data, see proto.Checksum
 }
 
 func MkdirAll(name string) (err error) {
@@ -189,6 +214,7 @@ func (ei *ExtentInfo) UpdateExtentInfo(extent *Extent, crc uint32) {
 	if !IsTinyExtent(ei.FileID) {
 		atomic.StoreUint32(&ei.Crc, crc)
 		ei.ModifyTime = extent.ModifyTime()
+		atomic.StoreUint64(&ei.CompressedSize, uint64(extent.CompressedSize()))
 	}
 }
 
@@ -234,6 +260,7 @@ func (s *ExtentStore) Create(extentID uint64) (err error) {
 		return err
 	}
 	e = NewExtentInCore(name, extentID)
+	e.checksum = s.checksum
 	e.header = make([]byte, util.BlockHeaderSize)
 	err = e.InitToFS()
 	if err != nil {
@@ -250,11 +277,23 @@ func (s *ExtentStore) Create(extentID uint64) (err error) {
 	return
 }
 
+// Recreate creates the normal extent deleted before, such as the one restored from the erasure coded shards.
+func (s *ExtentStore) Recreate(extentID uint64) (err error) {
+	s.eiMutex.Lock()
+	if ei, ok := s.extentInfoMap[extentID]; ok && ei.IsDeleted {
+		delete(s.extentInfoMap, extentID)
+	}
+	s.eiMutex.Unlock()
+	s.ForgetDeletedNormalExtent(extentID)
+	return s.Create(extentID)
+}
+
 func (s *ExtentStore) initBaseFileID() (err error) {
 	var (
 		baseFileID uint64
 	)
 	baseFileID, _ = s.GetPersistenceBaseExtentID()
+	s.cleanCompressTmpFiles()
 	files, err := ioutil.ReadDir(s.dataPath)
 	if err != nil {
 		return err
@@ -268,7 +307,7 @@ func (s *ExtentStore) initBaseFileID() (err error) {
 		loadErr  error
 	)
 	for _, f := range files {
-		if extentID, isExtent = s.ExtentID(f.Name()); !isExtent {
+		if extentID, isExtent = s.ExtentID(strings.TrimSuffix(f.Name(), CompressedExtentSuffix)); !isExtent {
 			continue
 		}
 		if e, loadErr = s.extent(extentID); loadErr != nil {
@@ -311,6 +350,7 @@ func (s *ExtentStore) Write(extentID uint64, offset, size int64, data []byte, cr
 		return err
 	}
 	err = e.Write(data, offset, size, crc, writeType, isSync, s.PersistenceBlockCrc, ei)
+	s.invalidateCache(extentID, offset, size)
 	if err != nil {
 		return err
 	}
@@ -353,11 +393,67 @@ func (s *ExtentStore) Read(extentID uint64, offset, size int64, nbuf []byte, isR
 	if err = s.checkOffsetAndSize(extentID, offset, size); err != nil {
 		return
 	}
-	crc, err = e.Read(nbuf, offset, size, isRepairRead)
+	if s.blockCache != nil && !IsTinyExtent(extentID) && !isRepairRead {
+		crc, err = s.readWithCache(e, nbuf, offset, size)
+	} else {
+		crc, err = e.Read(nbuf, offset, size, isRepairRead)
+	}
+	if err == nil && !IsTinyExtent(extentID) {
+		err = s.verifyBlock(e, offset, size, crc)
+	}
 
 	return
 }
 
+// SetChecksum sets the checksum type of the data of the extent store, see proto.Checksum.
+func (s *ExtentStore) SetChecksum(checksum string) {
+	s.checksum = checksum
+}
+
+// verifyBlock verifies the data read against the checksums stored along with the blocks, so that the data
+// corrupted on the disk is never returned. The data read of a part of the blocks is verified by re-reading
+// the whole blocks covering it.
+func (s *ExtentStore) verifyBlock(e *Extent, offset, size int64, crc uint32) (err error) {
+	if offset%util.BlockSize == 0 && (size == util.BlockSize || offset+size == e.Size()) {
+		return s.verifyWholeBlock(e, int(offset/util.BlockSize), size, crc, true)
+	}
+	for blockNo := int(offset / util.BlockSize); int64(blockNo*util.BlockSize) < offset+size; blockNo++ {
+		blockSize := e.Size() - int64(blockNo*util.BlockSize)
+		if blockSize <= 0 {
+			return
+		}
+		if blockSize > util.BlockSize {
+			blockSize = util.BlockSize
+		}
+		if err = s.verifyWholeBlock(e, blockNo, blockSize, 0, false); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// verifyWholeBlock verifies the data of the block against the checksum stored, which is the crc given if read.
+// The block is read again on mismatch in case it is overwritten in the meantime.
+func (s *ExtentStore) verifyWholeBlock(e *Extent, blockNo int, size int64, crc uint32, read bool) (err error) {
+	if (blockNo+1)*util.PerBlockCrcSize > len(e.header) {
+		return
+	}
+	stored := binary.BigEndian.Uint32(e.header[blockNo*util.PerBlockCrcSize : (blockNo+1)*util.PerBlockCrcSize])
+	if stored == 0 || (read && stored == crc) {
+		return
+	}
+	offset := int64(blockNo * util.BlockSize)
+	data := make([]byte, size)
+	if _, err = e.readAt(data, offset); err != nil {
+		return
+	}
+	stored = binary.BigEndian.Uint32(e.header[blockNo*util.PerBlockCrcSize : (blockNo+1)*util.PerBlockCrcSize])
+	if crc = proto.Checksum(s.checksum, data); stored != 0 && stored != crc {
+		return fmt.Errorf("extent(%v) block(%v) crc(%v) stored(%v): %v", e.extentID, blockNo, crc, stored, proto.ErrChecksumMismatch)
+	}
+	return
+}
+
 func (s *ExtentStore) tinyDelete(extentID uint64, offset, size int64) (err error) {
 	e, err := s.extentWithHeaderByExtentID(extentID)
 	if err != nil {
@@ -370,7 +466,11 @@ func (s *ExtentStore) tinyDelete(extentID uint64, offset, size int64) (err error
 		hasDelete bool
 	)
 	if hasDelete, err = e.DeleteTiny(offset, size); err != nil {
-		return
+		if err != syscall.EOPNOTSUPP {
+			return
+		}
+		// the space is reclaimed by the compaction of the tiny extent instead
+		err = nil
 	}
 	if hasDelete {
 		return
@@ -398,9 +498,10 @@ func (s *ExtentStore) MarkDelete(extentID uint64, offset, size int64) (err error
 		return
 	}
 	extentFilePath := path.Join(s.dataPath, strconv.FormatUint(extentID, 10))
-	if err = os.Remove(extentFilePath); err != nil {
+	if err = s.removeExtentFiles(extentFilePath); err != nil {
 		return
 	}
+	s.invalidateCache(extentID, 0, -1)
 	s.PersistenceHasDeleteExtent(extentID)
 	ei.IsDeleted = true
 	ei.ModifyTime = time.Now().Unix()
@@ -424,6 +525,11 @@ func (s *ExtentStore) IsDeletedNormalExtent(extentID uint64) (ok bool) {
 	return
 }
 
+// ForgetDeletedNormalExtent allows the deleted normal extent to be repaired from the other replicas.
+func (s *ExtentStore) ForgetDeletedNormalExtent(extentID uint64) {
+	s.hasDeleteNormalExtentsCache.Delete(extentID)
+}
+
 // Close closes the extent store.
 func (s *ExtentStore) Close() {
 	s.mutex.Lock()
@@ -435,12 +541,17 @@ func (s *ExtentStore) Close() {
 	// Release cache
 	s.cache.Flush()
 	s.cache.Clear()
+	s.tinyDeleteLock.Lock()
 	s.tinyExtentDeleteFp.Sync()
 	s.tinyExtentDeleteFp.Close()
+	s.tinyDeleteLock.Unlock()
 	s.normalExtentDeleteFp.Sync()
 	s.normalExtentDeleteFp.Close()
 	s.verifyExtentFp.Sync()
 	s.verifyExtentFp.Close()
+	if s.blockCache != nil {
+		s.blockCache.InvalidatePartition(s.partitionID)
+	}
 	s.closed = true
 }
 
@@ -580,18 +691,25 @@ func (s *ExtentStore) initTinyExtent() (err error) {
 
 // GetAvailableTinyExtent returns the available tiny extent from the channel.
 func (s *ExtentStore) GetAvailableTinyExtent() (extentID uint64, err error) {
-	select {
-	case extentID = <-s.availableTinyExtentC:
-		s.availableTinyExtentMap.Delete(extentID)
-		return
-	default:
-		return 0, NoAvailableExtentError
-
+	for {
+		select {
+		case extentID = <-s.availableTinyExtentC:
+			s.availableTinyExtentMap.Delete(extentID)
+			if s.IsTinyExtentCompacting(extentID) {
+				continue
+			}
+			return
+		default:
+			return 0, NoAvailableExtentError
+		}
 	}
 }
 
 // SendToAvailableTinyExtentC sends the extent to the channel that stores the available tiny extents.
 func (s *ExtentStore) SendToAvailableTinyExtentC(extentID uint64) {
+	if s.IsTinyExtentCompacting(extentID) {
+		return
+	}
 	if _, ok := s.availableTinyExtentMap.Load(extentID); !ok {
 		s.availableTinyExtentC <- extentID
 		s.availableTinyExtentMap.Store(extentID, true)
@@ -601,6 +719,9 @@ func (s *ExtentStore) SendToAvailableTinyExtentC(extentID uint64) {
 // SendAllToBrokenTinyExtentC sends all the extents to the channel that stores the broken extents.
 func (s *ExtentStore) SendAllToBrokenTinyExtentC(extentIds []uint64) {
 	for _, extentID := range extentIds {
+		if s.IsTinyExtentCompacting(extentID) {
+			continue
+		}
 		if _, ok := s.brokenTinyExtentMap.Load(extentID); !ok {
 			s.brokenTinyExtentC <- extentID
 			s.brokenTinyExtentMap.Store(extentID, true)
@@ -632,6 +753,9 @@ func (s *ExtentStore) MoveAllToBrokenTinyExtentC(cnt int) {
 
 // SendToBrokenTinyExtentC sends the given extent id to the channel.
 func (s *ExtentStore) SendToBrokenTinyExtentC(extentID uint64) {
+	if s.IsTinyExtentCompacting(extentID) {
+		return
+	}
 	if _, ok := s.brokenTinyExtentMap.Load(extentID); !ok {
 		s.brokenTinyExtentC <- extentID
 		s.brokenTinyExtentMap.Store(extentID, true)
@@ -641,13 +765,17 @@ func (s *ExtentStore) SendToBrokenTinyExtentC(extentID uint64) {
 
 // GetBrokenTinyExtent returns the first broken extent in the channel.
 func (s *ExtentStore) GetBrokenTinyExtent() (extentID uint64, err error) {
-	select {
-	case extentID = <-s.brokenTinyExtentC:
-		s.brokenTinyExtentMap.Delete(extentID)
-		return
-	default:
-		return 0, NoBrokenExtentError
-
+	for {
+		select {
+		case extentID = <-s.brokenTinyExtentC:
+			s.brokenTinyExtentMap.Delete(extentID)
+			if s.IsTinyExtentCompacting(extentID) {
+				continue
+			}
+			return
+		default:
+			return 0, NoBrokenExtentError
+		}
 	}
 }
 
@@ -703,6 +831,8 @@ func UnMarshalTinyExtent(data []byte) (extentID, offset, size uint64) {
 
 func (s *ExtentStore) RecordTinyDelete(extentID uint64, offset, size int64) (err error) {
 	record := MarshalTinyExtent(extentID, offset, size)
+	s.tinyDeleteLock.Lock()
+	defer s.tinyDeleteLock.Unlock()
 	stat, err := s.tinyExtentDeleteFp.Stat()
 	if err != nil {
 		return
@@ -721,6 +851,8 @@ func (s *ExtentStore) RecordTinyDelete(extentID uint64, offset, size int64) (err
 }
 
 func (s *ExtentStore) ReadTinyDeleteRecords(offset, size int64, data []byte) (crc uint32, err error) {
+	s.tinyDeleteLock.Lock()
+	defer s.tinyDeleteLock.Unlock()
 	_, err = s.tinyExtentDeleteFp.ReadAt(data[:size], offset)
 	if err == nil || err == io.EOF {
 		err = nil
@@ -739,6 +871,8 @@ func (s *ExtentStore) NextExtentID() (extentID uint64, err error) {
 }
 
 func (s *ExtentStore) LoadTinyDeleteFileOffset() (offset int64, err error) {
+	s.tinyDeleteLock.Lock()
+	defer s.tinyDeleteLock.Unlock()
 	stat, err := s.tinyExtentDeleteFp.Stat()
 	if err == nil {
 		offset = stat.Size()
@@ -816,6 +950,7 @@ func (s *ExtentStore) GetExtentCount() (count int) {
 func (s *ExtentStore) loadExtentFromDisk(extentID uint64, putCache bool) (e *Extent, err error) {
 	name := path.Join(s.dataPath, strconv.Itoa(int(extentID)))
 	e = NewExtentInCore(name, extentID)
+	e.checksum = s.checksum
 	if err = e.RestoreFromFS(); err != nil {
 		err = fmt.Errorf("restore from file %v putCache %v system: %v", name, putCache, err)
 		return
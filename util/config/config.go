@@ -69,6 +69,12 @@ func (c *Config) parse(fileName string) error {
 	return err
 }
 
+// HasKey returns true if the config key is set.
+func (c *Config) HasKey(key string) bool {
+	_, present := c.data[key]
+	return present
+}
+
 // GetString returns a string for the config key.
 func (c *Config) GetString(key string) string {
 	x, present := c.data[key]
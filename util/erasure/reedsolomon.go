@@ -0,0 +1,207 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package erasure implements the systematic Reed-Solomon erasure coding.
+// The data is split into k data shards, and m parity shards are computed from them,
+// so that the data can be rebuilt from any k of the k+m shards.
+package erasure
+
+import (
+	"errors"
+)
+
+const (
+	// MaxTotalShards is the upper limit of data shards plus parity shards.
+	MaxTotalShards = 256
+)
+
+var (
+	ErrInvalidShardNum = errors.New("erasure: invalid number of shards")
+	ErrShardSize       = errors.New("erasure: shards have different sizes")
+	ErrTooFewShards    = errors.New("erasure: too few shards to reconstruct")
+	ErrShortData       = errors.New("erasure: not enough data to join")
+	ErrSingularMatrix  = errors.New("erasure: matrix is singular")
+)
+
+// Encoder encodes and reconstructs the shards with k data shards and m parity shards.
+type Encoder struct {
+	dataNum   int
+	parityNum int
+	// the parity rows of the encoding matrix, the data rows are the identity matrix.
+	parity [][]byte
+}
+
+// NewEncoder returns a new encoder with the given number of data shards and parity shards.
+func NewEncoder(dataNum, parityNum int) (e *Encoder, err error) {
+	if dataNum <= 0 || parityNum <= 0 || dataNum+parityNum > MaxTotalShards {
+		return nil, ErrInvalidShardNum
+	}
+	e = &Encoder{
+		dataNum:   dataNum,
+		parityNum: parityNum,
+		parity:    make([][]byte, parityNum),
+	}
+	// Use the Cauchy matrix 1/(x_i+y_j) with x_i = k+i and y_j = j,
+	// any square sub-matrix of which is invertible.
+	for i := 0; i < parityNum; i++ {
+		e.parity[i] = make([]byte, dataNum)
+		for j := 0; j < dataNum; j++ {
+			e.parity[i][j] = gfInv(byte(dataNum+i) ^ byte(j))
+		}
+	}
+	return
+}
+
+// DataNum returns the number of data shards.
+func (e *Encoder) DataNum() int {
+	return e.dataNum
+}
+
+// ParityNum returns the number of parity shards.
+func (e *Encoder) ParityNum() int {
+	return e.parityNum
+}
+
+// ShardSize returns the size of each shard for the data of the given size.
+func (e *Encoder) ShardSize(size int) int {
+	return (size + e.dataNum - 1) / e.dataNum
+}
+
+// Split splits the data into data shards padded with zero, and allocates the parity shards.
+func (e *Encoder) Split(data []byte) [][]byte {
+	shardSize := e.ShardSize(len(data))
+	buf := make([]byte, shardSize*(e.dataNum+e.parityNum))
+	copy(buf, data)
+	shards := make([][]byte, e.dataNum+e.parityNum)
+	for i := range shards {
+		shards[i] = buf[i*shardSize : (i+1)*shardSize]
+	}
+	return shards
+}
+
+// Encode computes the parity shards from the data shards.
+func (e *Encoder) Encode(shards [][]byte) (err error) {
+	if len(shards) != e.dataNum+e.parityNum {
+		return ErrInvalidShardNum
+	}
+	size := len(shards[0])
+	for _, shard := range shards {
+		if len(shard) != size {
+			return ErrShardSize
+		}
+	}
+	for i := 0; i < e.parityNum; i++ {
+		e.encodeRow(e.parity[i], shards[:e.dataNum], shards[e.dataNum+i])
+	}
+	return
+}
+
+func (e *Encoder) encodeRow(row []byte, inputs [][]byte, output []byte) {
+	for i := range output {
+		output[i] = 0
+	}
+	for j, input := range inputs {
+		gfMulAdd(row[j], input, output)
+	}
+}
+
+// Reconstruct rebuilds the missing shards, which are nil or empty, from the present ones.
+func (e *Encoder) Reconstruct(shards [][]byte) (err error) {
+	if len(shards) != e.dataNum+e.parityNum {
+		return ErrInvalidShardNum
+	}
+
+	size := 0
+	present := make([]int, 0, e.dataNum)
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		if size == 0 {
+			size = len(shard)
+		} else if len(shard) != size {
+			return ErrShardSize
+		}
+		if len(present) < e.dataNum {
+			present = append(present, i)
+		}
+	}
+	if len(present) < e.dataNum {
+		return ErrTooFewShards
+	}
+
+	dataMissing := false
+	for i := 0; i < e.dataNum; i++ {
+		if len(shards[i]) == 0 {
+			dataMissing = true
+			break
+		}
+	}
+
+	if dataMissing {
+		// the rows of the encoding matrix for the present shards
+		matrix := make([][]byte, e.dataNum)
+		inputs := make([][]byte, e.dataNum)
+		for r, index := range present {
+			if index < e.dataNum {
+				matrix[r] = make([]byte, e.dataNum)
+				matrix[r][index] = 1
+			} else {
+				matrix[r] = append([]byte{}, e.parity[index-e.dataNum]...)
+			}
+			inputs[r] = shards[index]
+		}
+		if err = invertMatrix(matrix); err != nil {
+			return
+		}
+		for i := 0; i < e.dataNum; i++ {
+			if len(shards[i]) != 0 {
+				continue
+			}
+			shards[i] = make([]byte, size)
+			e.encodeRow(matrix[i], inputs, shards[i])
+		}
+	}
+
+	for i := 0; i < e.parityNum; i++ {
+		if len(shards[e.dataNum+i]) != 0 {
+			continue
+		}
+		shards[e.dataNum+i] = make([]byte, size)
+		e.encodeRow(e.parity[i], shards[:e.dataNum], shards[e.dataNum+i])
+	}
+	return
+}
+
+// Join concatenates the data shards and returns the first size bytes.
+func (e *Encoder) Join(shards [][]byte, size int) (data []byte, err error) {
+	if len(shards) < e.dataNum {
+		return nil, ErrTooFewShards
+	}
+	data = make([]byte, 0, size)
+	for i := 0; i < e.dataNum && len(data) < size; i++ {
+		if len(shards[i]) == 0 {
+			return nil, ErrTooFewShards
+		}
+		remain := size - len(data)
+		if remain > len(shards[i]) {
+			remain = len(shards[i])
+		}
+		data = append(data, shards[i][:remain]...)
+	}
+	if len(data) < size {
+		return nil, ErrShortData
+	}
+	return
+}
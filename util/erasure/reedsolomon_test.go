@@ -0,0 +1,79 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package erasure
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEncoder_Reconstruct(t *testing.T) {
+	var cases = []struct {
+		dataNum, parityNum int
+	}{
+		{1, 1}, {2, 1}, {4, 2}, {6, 3}, {10, 4},
+	}
+	for _, c := range cases {
+		e, err := NewEncoder(c.dataNum, c.parityNum)
+		if err != nil {
+			t.Fatalf("new encoder(%v+%v) fail cause: %v", c.dataNum, c.parityNum, err)
+		}
+		data := make([]byte, 10000+rand.Intn(1000))
+		rand.Read(data)
+		shards := e.Split(data)
+		if err = e.Encode(shards); err != nil {
+			t.Fatalf("encode(%v+%v) fail cause: %v", c.dataNum, c.parityNum, err)
+		}
+		origin := make([][]byte, len(shards))
+		for i := range shards {
+			origin[i] = append([]byte{}, shards[i]...)
+		}
+
+		// lose as many shards as the parity shards, at random positions
+		for _, i := range rand.Perm(len(shards))[:c.parityNum] {
+			shards[i] = nil
+		}
+		if err = e.Reconstruct(shards); err != nil {
+			t.Fatalf("reconstruct(%v+%v) fail cause: %v", c.dataNum, c.parityNum, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], origin[i]) {
+				t.Fatalf("reconstruct(%v+%v) shard %v mismatch", c.dataNum, c.parityNum, i)
+			}
+		}
+		joined, err := e.Join(shards, len(data))
+		if err != nil || !bytes.Equal(joined, data) {
+			t.Fatalf("join(%v+%v) mismatch, err: %v", c.dataNum, c.parityNum, err)
+		}
+
+		// one more lost shard can not be tolerated
+		for _, i := range rand.Perm(len(shards))[:c.parityNum+1] {
+			shards[i] = nil
+		}
+		if err = e.Reconstruct(shards); err != ErrTooFewShards {
+			t.Fatalf("reconstruct(%v+%v) expect %v, actual %v", c.dataNum, c.parityNum, ErrTooFewShards, err)
+		}
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	if _, err := NewEncoder(0, 2); err != ErrInvalidShardNum {
+		t.Fatalf("expect %v, actual %v", ErrInvalidShardNum, err)
+	}
+	if _, err := NewEncoder(200, 57); err != ErrInvalidShardNum {
+		t.Fatalf("expect %v, actual %v", ErrInvalidShardNum, err)
+	}
+}
@@ -239,6 +239,7 @@ type Log struct {
 	readLogger     *LogObject
 	updateLogger   *LogObject
 	criticalLogger *LogObject
+	auditLogger    *LogObject
 	level          Level
 	msgC           chan string
 	rotate         *LogRotate
@@ -253,6 +254,7 @@ var (
 	ReadLogFileName     = "_read.log"
 	UpdateLogFileName   = "_write.log"
 	CriticalLogFileName = "_critical.log"
+	AuditLogFileName    = "_audit.log"
 )
 
 var gLog *Log = nil
@@ -320,8 +322,8 @@ func (l *Log) initLog(logDir, module string, level Level) error {
 		return
 	}
 	var err error
-	logHandles := [...]**LogObject{&l.debugLogger, &l.infoLogger, &l.warnLogger, &l.errorLogger, &l.readLogger, &l.updateLogger, &l.criticalLogger}
-	logNames := [...]string{DebugLogFileName, InfoLogFileName, WarnLogFileName, ErrLogFileName, ReadLogFileName, UpdateLogFileName, CriticalLogFileName}
+	logHandles := [...]**LogObject{&l.debugLogger, &l.infoLogger, &l.warnLogger, &l.errorLogger, &l.readLogger, &l.updateLogger, &l.criticalLogger, &l.auditLogger}
+	logNames := [...]string{DebugLogFileName, InfoLogFileName, WarnLogFileName, ErrLogFileName, ReadLogFileName, UpdateLogFileName, CriticalLogFileName, AuditLogFileName}
 	for i := range logHandles {
 		if *logHandles[i], err = newLog(logNames[i]); err != nil {
 			return err
@@ -358,6 +360,7 @@ func (l *Log) Flush() {
 		l.readLogger,
 		l.updateLogger,
 		l.criticalLogger,
+		l.auditLogger,
 	}
 	for _, logger := range loggers {
 		if logger != nil {
@@ -631,6 +634,14 @@ func LogWritef(format string, v ...interface{}) {
 	gLog.updateLogger.Output(2, s)
 }
 
+// LogAudit logs the audit records regardless of the log level, to the audit log rotated as the others.
+func LogAudit(v ...interface{}) {
+	if gLog == nil {
+		return
+	}
+	gLog.auditLogger.Output(2, fmt.Sprintln(v...))
+}
+
 // LogFlush flushes the log.
 func LogFlush() {
 	if gLog != nil {
@@ -670,6 +681,7 @@ func (l *Log) checkLogRotation(logDir, module string) {
 		l.readLogger.SetRotation()
 		l.updateLogger.SetRotation()
 		l.criticalLogger.SetRotation()
+		l.auditLogger.SetRotation()
 
 		l.lastRolledTime = now
 	}
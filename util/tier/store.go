@@ -0,0 +1,242 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tier implements the stores of the extents migrated out of the data nodes,
+// which are either an S3-compatible bucket or a local directory standing in for it.
+package tier
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	// LocalScheme is the endpoint prefix of the local directory store.
+	LocalScheme = "file://"
+	// BlockSize is the size of the blocks of an object, each of which has a crc.
+	BlockSize = 1024 * 1024
+)
+
+var (
+	ErrInvalidPolicy = errors.New("tier: invalid policy")
+	ErrNotExist      = errors.New("tier: object does not exist")
+	ErrCrcMismatch   = errors.New("tier: object crc mismatch")
+)
+
+// Store defines the interface of the object store holding the migrated extents.
+type Store interface {
+	// Put writes the whole object.
+	Put(key string, data []byte) error
+	// Get reads size bytes of the object from the offset.
+	Get(key string, offset, size int64) ([]byte, error)
+	// Delete removes the object, it is not an error if the object does not exist.
+	Delete(key string) error
+}
+
+// NewStore returns the store defined by the tier policy.
+func NewStore(policy *proto.TierPolicy) (Store, error) {
+	if policy == nil || policy.Endpoint == "" {
+		return nil, ErrInvalidPolicy
+	}
+	if strings.HasPrefix(policy.Endpoint, LocalScheme) {
+		dir := strings.TrimPrefix(policy.Endpoint, LocalScheme)
+		return newLocalStore(filepath.Join(dir, policy.Bucket))
+	}
+	if policy.Bucket == "" {
+		return nil, ErrInvalidPolicy
+	}
+	return newS3Store(policy)
+}
+
+// ObjectKey returns the key of the object holding the data of the extent key of the inode.
+func ObjectKey(volName string, ino uint64, ek *proto.ExtentKey) string {
+	return path.Join(volName, fmt.Sprintf("%v", ino), fmt.Sprintf("%v_%v_%v", ek.PartitionId, ek.ExtentId, ek.ExtentOffset))
+}
+
+// BlockCrcs returns the crcs of the blocks of the object data.
+func BlockCrcs(data []byte) (crcs []uint32) {
+	crcs = make([]uint32, 0, (len(data)+BlockSize-1)/BlockSize)
+	for offset := 0; offset < len(data); offset += BlockSize {
+		end := offset + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		crcs = append(crcs, crc32.ChecksumIEEE(data[offset:end]))
+	}
+	return
+}
+
+// GetVerified reads size bytes of the object of the tier extent from the offset, and verifies
+// the blocks covering them against the crcs of the tier extent, which are absent for the objects
+// migrated before the crcs are recorded.
+func GetVerified(s Store, t *proto.TierExtent, offset, size int64) (data []byte, err error) {
+	if len(t.Crcs) == 0 {
+		return s.Get(t.Key, offset, size)
+	}
+	if offset < 0 || size < 0 || offset+size > int64(t.Size) {
+		return nil, fmt.Errorf("tier: read [%v, %v) beyond the object %v of size %v", offset, offset+size, t.Key, t.Size)
+	}
+	start := offset / BlockSize * BlockSize
+	end := (offset + size + BlockSize - 1) / BlockSize * BlockSize
+	if end > int64(t.Size) {
+		end = int64(t.Size)
+	}
+	if data, err = s.Get(t.Key, start, end-start); err != nil {
+		return
+	}
+	if int64(len(data)) != end-start {
+		return nil, ErrCrcMismatch
+	}
+	for blockOffset := start; blockOffset < end; blockOffset += BlockSize {
+		index := blockOffset / BlockSize
+		blockEnd := blockOffset + BlockSize
+		if blockEnd > end {
+			blockEnd = end
+		}
+		if index >= int64(len(t.Crcs)) || crc32.ChecksumIEEE(data[blockOffset-start:blockEnd-start]) != t.Crcs[index] {
+			return nil, ErrCrcMismatch
+		}
+	}
+	return data[offset-start : offset-start+size], nil
+}
+
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(dir string) (s *localStore, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	return &localStore{dir: dir}, nil
+}
+
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s *localStore) Put(key string, data []byte) (err error) {
+	name := s.path(key)
+	if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return
+	}
+	// write to a temp file first so that a partial object is never visible
+	tmp := name + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmp, name)
+}
+
+func (s *localStore) Get(key string, offset, size int64) (data []byte, err error) {
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	data = make([]byte, size)
+	n, err := f.ReadAt(data, offset)
+	if err == io.EOF && int64(n) == size {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *localStore) Delete(key string) (err error) {
+	if err = os.Remove(s.path(key)); os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+type s3Store struct {
+	bucket string
+	client *s3.S3
+}
+
+func newS3Store(policy *proto.TierPolicy) (s *s3Store, err error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return
+	}
+	var ac = aws.NewConfig()
+	ac.Endpoint = aws.String(policy.Endpoint)
+	ac.DisableSSL = aws.Bool(strings.HasPrefix(policy.Endpoint, "http://"))
+	region := policy.Region
+	if region == "" {
+		region = "default"
+	}
+	ac.Region = aws.String(region)
+	ac.Credentials = credentials.NewStaticCredentials(policy.AccessKey, policy.SecretKey, "")
+	ac.S3ForcePathStyle = aws.Bool(true)
+	return &s3Store{bucket: policy.Bucket, client: s3.New(sess, ac)}, nil
+}
+
+func (s *s3Store) Put(key string, data []byte) (err error) {
+	_, err = s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return
+}
+
+func (s *s3Store) Get(key string, offset, size int64) (data []byte, err error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%v-%v", offset, offset+size-1)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return
+	}
+	defer output.Body.Close()
+	data = make([]byte, size)
+	if _, err = io.ReadFull(output.Body, data); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (s *s3Store) Delete(key string) (err error) {
+	_, err = s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return
+}
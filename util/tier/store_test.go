@@ -0,0 +1,104 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tier
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewStore(&proto.TierPolicy{ColdDays: 1, Endpoint: LocalScheme + dir, Bucket: "bucket"})
+	if err != nil {
+		t.Fatalf("new store fail cause: %v", err)
+	}
+	key := ObjectKey("vol", 1, &proto.ExtentKey{PartitionId: 2, ExtentId: 3, ExtentOffset: 4096})
+	if key != "vol/1/2_3_4096" {
+		t.Fatalf("unexpected object key %v", key)
+	}
+	data := []byte("0123456789")
+	if err = store.Put(key, data); err != nil {
+		t.Fatalf("put fail cause: %v", err)
+	}
+	read, err := store.Get(key, 2, 5)
+	if err != nil || !bytes.Equal(read, data[2:7]) {
+		t.Fatalf("get mismatch, read(%s) err(%v)", read, err)
+	}
+	if _, err = store.Get(key, 8, 5); err == nil {
+		t.Fatalf("expect error when reading beyond the object")
+	}
+	if err = store.Delete(key); err != nil {
+		t.Fatalf("delete fail cause: %v", err)
+	}
+	if _, err = store.Get(key, 0, 1); err != ErrNotExist {
+		t.Fatalf("expect %v, actual %v", ErrNotExist, err)
+	}
+	if err = store.Delete(key); err != nil {
+		t.Fatalf("delete missing object fail cause: %v", err)
+	}
+}
+
+func TestGetVerified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewStore(&proto.TierPolicy{ColdDays: 1, Endpoint: LocalScheme + dir, Bucket: "bucket"})
+	if err != nil {
+		t.Fatalf("new store fail cause: %v", err)
+	}
+	data := make([]byte, 2*BlockSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	te := &proto.TierExtent{Size: uint32(len(data)), Key: "vol/1/2_3_0", Crcs: BlockCrcs(data)}
+	if len(te.Crcs) != 3 {
+		t.Fatalf("expect 3 block crcs, actual %v", len(te.Crcs))
+	}
+	if err = store.Put(te.Key, data); err != nil {
+		t.Fatalf("put fail cause: %v", err)
+	}
+	read, err := GetVerified(store, te, BlockSize-10, BlockSize+50)
+	if err != nil || !bytes.Equal(read, data[BlockSize-10:2*BlockSize+40]) {
+		t.Fatalf("get mismatch, err(%v)", err)
+	}
+	if _, err = GetVerified(store, te, 2*BlockSize, 200); err == nil {
+		t.Fatalf("expect error when reading beyond the object")
+	}
+
+	// corrupt the last block
+	data[2*BlockSize+1]++
+	if err = store.Put(te.Key, data); err != nil {
+		t.Fatalf("put fail cause: %v", err)
+	}
+	if _, err = GetVerified(store, te, 0, BlockSize); err != nil {
+		t.Fatalf("get the intact block fail cause: %v", err)
+	}
+	if _, err = GetVerified(store, te, 2*BlockSize, 10); err != ErrCrcMismatch {
+		t.Fatalf("expect %v, actual %v", ErrCrcMismatch, err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// The packet protocol between the clients and the nodes, and among the nodes, runs over TLS with mutual
+// authentication once the certificate of the role is loaded by InitPacketTLS. The certificate is presented
+// to the peers both on accepting and on dialing the connections, so it is issued for both the server and
+// the client authentication, and the certificates of the peers are verified by the CA of the cluster.
+var packetTLSConfig *tls.Config
+
+// InitPacketTLS loads the certificate, the private key and the CA of the role. The packet protocol stays
+// on the plain TCP if none of the files is configured. The server name, if set, is verified against the
+// certificates of the nodes dialed instead of their addresses.
+func InitPacketTLS(certFile, keyFile, caFile, serverName string) (err error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		packetTLSConfig = nil
+		return
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return errors.New("the certificate, the key and the CA files are all required by TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("load the certificate(%v) and the key(%v) err(%v)", certFile, keyFile, err)
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("load the CA(%v) err(%v)", caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("no certificate found in the CA(%v)", caFile)
+	}
+	packetTLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return
+}
+
+// PacketTLSEnabled returns if the packet protocol runs over TLS.
+func PacketTLSEnabled() bool {
+	return packetTLSConfig != nil
+}
+
+// Listen listens on the address for the packet protocol. The connections accepted keep alive without delay,
+// and are served over TLS if enabled.
+func Listen(network, addr string) (ln net.Listener, err error) {
+	if ln, err = net.Listen(network, addr); err != nil {
+		return
+	}
+	ln = tcpListener{ln.(*net.TCPListener)}
+	if packetTLSConfig != nil {
+		ln = tls.NewListener(ln, packetTLSConfig)
+	}
+	return
+}
+
+type tcpListener struct {
+	*net.TCPListener
+}
+
+func (ln tcpListener) Accept() (net.Conn, error) {
+	c, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	c.SetKeepAlive(true)
+	c.SetNoDelay(true)
+	return c, nil
+}
+
+// DailTimeOut dials the target for the packet protocol, and finishes the TLS handshake within the timeout
+// if enabled.
+func DailTimeOut(target string, timeout time.Duration) (c net.Conn, err error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 15 * time.Second}
+	if packetTLSConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", target, packetTLSConfig)
+	}
+	if c, err = dialer.Dial("tcp", target); err != nil {
+		return
+	}
+	c.(*net.TCPConn).SetNoDelay(true)
+	return
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// handshake dials the listener with the config, and returns the error of the handshake on the server side.
+func handshake(t *testing.T, ln net.Listener, config *tls.Config) error {
+	result := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		result <- c.(*tls.Conn).Handshake()
+	}()
+	c, err := tls.Dial("tcp", ln.Addr().String(), config)
+	if err == nil {
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+		// the server verifies the client certificate after the client finishes the handshake of TLS 1.3
+		c.Read(make([]byte, 1))
+		c.Close()
+	}
+	return <-result
+}
+
+func TestPacketTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer InitPacketTLS("", "", "", "")
+
+	ca := newTestCert(t, "ca", nil)
+	node := newTestCert(t, "node", ca)
+	files := map[string][]byte{"ca.pem": ca.certPEM, "node.pem": node.certPEM, "node.key": node.keyPEM}
+	for name, data := range files {
+		if err = ioutil.WriteFile(path.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = InitPacketTLS(path.Join(dir, "node.pem"), path.Join(dir, "node.key"), "", ""); err == nil {
+		t.Fatal("expect the CA required")
+	}
+	if err = InitPacketTLS(path.Join(dir, "node.pem"), path.Join(dir, "node.key"), path.Join(dir, "ca.pem"), ""); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// the nodes with the certificates issued by the CA of the cluster
+	result := make(chan error, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			err = c.(*tls.Conn).Handshake()
+			c.Close()
+		}
+		result <- err
+	}()
+	c, err := DailTimeOut(ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("dial err(%v)", err)
+	}
+	c.Close()
+	if err = <-result; err != nil {
+		t.Fatalf("handshake err(%v)", err)
+	}
+
+	// the certificate issued by another CA
+	otherCA := newTestCert(t, "other", nil)
+	other := newTestCert(t, "other-node", otherCA)
+	otherCert, err := tls.X509KeyPair(other.certPEM, other.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{otherCert},
+		RootCAs:      packetTLSConfig.RootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if err = handshake(t, ln, config); err == nil {
+		t.Fatal("expect the certificate of another CA rejected")
+	}
+
+	// no client certificate
+	config = &tls.Config{RootCAs: packetTLSConfig.RootCAs, MinVersion: tls.VersionTLS12}
+	if err = handshake(t, ln, config); err == nil {
+		t.Fatal("expect the client without the certificate rejected")
+	}
+}